@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"sync"
 
 	shell "github.com/ipfs/go-ipfs-api"
@@ -124,13 +123,10 @@ func (sw *streamWrapper) Seek(offset int64, whence int) (int64, error) {
 	}
 
 	if sw.ReadCloser != nil {
-		// Not sure if that is even needed...
-		// TODO: measure memory consumption and see if we can do
-		//       without discarding left over bytes.
-		go func(rc io.ReadCloser) {
-			io.Copy(ioutil.Discard, rc)
-			rc.Close()
-		}(sw.ReadCloser)
+		// NOTE: Do not drain the left over bytes before closing.
+		// For big files this would download the whole rest of the
+		// file on every seek, which hurts a lot with read-ahead.
+		sw.ReadCloser.Close()
 	}
 
 	sw.off = absOffset
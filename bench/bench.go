@@ -1,6 +1,7 @@
 package bench
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -18,6 +20,8 @@ import (
 	"github.com/pkg/xattr"
 	"github.com/sahib/brig/backend/httpipfs"
 	"github.com/sahib/brig/catfs/mio"
+	"github.com/sahib/brig/catfs/mio/pagecache"
+	"github.com/sahib/brig/catfs/mio/pagecache/mdcache"
 	"github.com/sahib/brig/client"
 	"github.com/sahib/brig/client/clienttest"
 	"github.com/sahib/brig/fuse/fusetest"
@@ -170,7 +174,7 @@ func (s *serverStageBench) Bench(hint hints.Hint, size int64, r io.Reader, verif
 
 	c := string(hint.CompressionAlgo)
 	e := string(hint.EncryptionAlgo)
-	if err := s.common.client.HintSet(path, &c, &e); err != nil {
+	if err := s.common.client.HintSet(path, &c, &e, nil); err != nil {
 		return nil, err
 	}
 
@@ -186,6 +190,95 @@ func (s *serverStageBench) Close() error {
 	return s.common.Close()
 }
 
+// serverStageBatchBench stages all files of a tar archive in one batch.
+// It is meant to be used with the "small-files" input.
+type serverStageBatchBench struct {
+	common *serverCommon
+}
+
+func newServerStageBatchBench(ipfsPath string, _ bool) (Bench, error) {
+	common, err := newServerCommon(ipfsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &serverStageBatchBench{common: common}, nil
+}
+
+func (s *serverStageBatchBench) SupportHints() bool { return true }
+
+func (s *serverStageBatchBench) CanBeVerified() bool { return false }
+
+func (s *serverStageBatchBench) Bench(hint hints.Hint, size int64, r io.Reader, verifier io.Writer) (*Run, error) {
+	root := fmt.Sprintf("/batch_%d", rand.Int31())
+
+	tmpDir, err := ioutil.TempDir("", "brig-bench-batch-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	// Unpacking is not part of the measurement:
+	entries := []client.StageEntry{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("small-files input is required: %v", err)
+		}
+
+		localPath := filepath.Join(tmpDir, hdr.Name)
+		if err := os.MkdirAll(filepath.Dir(localPath), 0700); err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ioutil.WriteFile(localPath, data, 0600); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, client.StageEntry{
+			LocalPath: localPath,
+			RepoPath:  path.Join(root, hdr.Name),
+		})
+	}
+
+	c := string(hint.CompressionAlgo)
+	e := string(hint.EncryptionAlgo)
+	if err := s.common.client.HintSet(root, &c, &e, nil); err != nil {
+		return nil, err
+	}
+
+	// That's just for cleaning up after each test.
+	defer s.common.client.Remove(root)
+
+	return withRunStats(size, func() (int64, error) {
+		failures, err := s.common.client.StageBatch(entries)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(failures) > 0 {
+			return 0, fmt.Errorf("failed to stage %s: %s", failures[0].RepoPath, failures[0].Error)
+		}
+
+		return size, nil
+	})
+}
+
+func (s *serverStageBatchBench) Close() error {
+	return s.common.Close()
+}
+
 type serverCatBench struct {
 	common *serverCommon
 }
@@ -208,7 +301,7 @@ func (s *serverCatBench) Bench(hint hints.Hint, size int64, r io.Reader, verifie
 	c := string(hint.CompressionAlgo)
 	e := string(hint.EncryptionAlgo)
 
-	if err := s.common.client.HintSet(path, &c, &e); err != nil {
+	if err := s.common.client.HintSet(path, &c, &e, nil); err != nil {
 		return nil, err
 	}
 
@@ -314,6 +407,136 @@ func (m *mioReaderBench) Close() error {
 
 //////////
 
+// latencyReader simulates a backend that needs some time for every read.
+type latencyReader struct {
+	*bytes.Reader
+	latency time.Duration
+}
+
+func (lr latencyReader) Read(buf []byte) (int, error) {
+	time.Sleep(lr.latency)
+	return lr.Reader.Read(buf)
+}
+
+type mioReadAheadBench struct {
+	readAhead bool
+}
+
+func newMioReadAheadBench(readAhead bool) func(string, bool) (Bench, error) {
+	return func(_ string, _ bool) (Bench, error) {
+		return &mioReadAheadBench{readAhead: readAhead}, nil
+	}
+}
+
+func (m *mioReadAheadBench) SupportHints() bool { return true }
+
+func (m *mioReadAheadBench) CanBeVerified() bool { return true }
+
+func (m *mioReadAheadBench) Bench(hint hints.Hint, size int64, r io.Reader, verifier io.Writer) (*Run, error) {
+	inStream, _, err := mio.NewInStream(r, "", dummyKey, hint)
+	if err != nil {
+		return nil, err
+	}
+
+	defer inStream.Close()
+
+	streamData, err := ioutil.ReadAll(inStream)
+	if err != nil {
+		return nil, err
+	}
+
+	open := func() (mio.Stream, error) {
+		br := bytes.NewReader(streamData)
+		return mio.NewOutStream(
+			struct {
+				io.Reader
+				io.Seeker
+			}{latencyReader{br, time.Millisecond}, br},
+			hint.IsRaw(),
+			dummyKey,
+		)
+	}
+
+	return withRunStats(size, func() (int64, error) {
+		cache, err := mdcache.New(mdcache.Options{
+			MaxMemoryUsage: 64 * 1024 * 1024,
+		})
+		if err != nil {
+			return -1, err
+		}
+
+		defer cache.Close()
+
+		outStream, err := open()
+		if err != nil {
+			return -1, err
+		}
+
+		defer outStream.Close()
+
+		// Read like a mount would: through the page cache,
+		// in the block size that fuse uses.
+		layer, err := pagecache.NewLayer(outStream, cache, 1, size)
+		if err != nil {
+			return -1, err
+		}
+
+		var ra *mio.ReadAhead
+		if m.readAhead {
+			ra, err = mio.NewReadAhead(open, size, layerSink{layer}, mio.DefaultReadAheadOptions())
+			if err != nil {
+				return -1, err
+			}
+
+			defer ra.Close()
+		}
+
+		buf := make([]byte, 128*1024)
+		for off := int64(0); off < size; {
+			if ra != nil {
+				if err := ra.Access(off, int64(len(buf))); err != nil {
+					return -1, err
+				}
+			}
+
+			n, err := layer.ReadAt(buf, off)
+			if err != nil && err != io.EOF {
+				return -1, err
+			}
+
+			if _, err := verifier.Write(buf[:n]); err != nil {
+				return -1, err
+			}
+
+			off += int64(n)
+			if err == io.EOF || n == 0 {
+				break
+			}
+		}
+
+		return size, nil
+	})
+}
+
+// layerSink puts blocks fetched by the read-ahead into the page cache.
+type layerSink struct {
+	layer *pagecache.Layer
+}
+
+func (ls layerSink) Fill(off int64, data []byte) error {
+	return ls.layer.Underlay(off, data)
+}
+
+func (ls layerSink) Forget(off, size int64) error {
+	return ls.layer.EvictRange(off, size)
+}
+
+func (m *mioReadAheadBench) Close() error {
+	return nil
+}
+
+//////////
+
 type ipfsAddOrCatBench struct {
 	ipfsPath string
 	isAdd    bool
@@ -495,19 +718,23 @@ var (
 	// - If it's using ipfs, put it in the name.
 	// - If it's writing things, put that in the name too as "write".
 	benchMap = map[string]func(string, bool) (Bench, error){
-		"memcpy":          newMemcpyBench,
-		"brig-write-mem":  newServerStageBench,
-		"brig-read-mem":   newServerCatBench,
-		"brig-write-ipfs": newServerStageBench,
-		"brig-read-ipfs":  newServerCatBench,
-		"mio-write":       newMioWriterBench,
-		"mio-read":        newMioReaderBench,
-		"ipfs-write":      newIPFSAddBench,
-		"ipfs-read":       newIPFSAddBench,
-		"fuse-write-mem":  newFuseWriteOrReadBench,
-		"fuse-write-ipfs": newFuseWriteOrReadBench,
-		"fuse-read-mem":   newFuseWriteOrReadBench,
-		"fuse-read-ipfs":  newFuseWriteOrReadBench,
+		"memcpy":                newMemcpyBench,
+		"brig-write-mem":        newServerStageBench,
+		"brig-read-mem":         newServerCatBench,
+		"brig-write-ipfs":       newServerStageBench,
+		"brig-write-batch-mem":  newServerStageBatchBench,
+		"brig-write-batch-ipfs": newServerStageBatchBench,
+		"brig-read-ipfs":        newServerCatBench,
+		"mio-write":             newMioWriterBench,
+		"mio-read":              newMioReaderBench,
+		"mio-read-slow":         newMioReadAheadBench(false),
+		"mio-read-ahead":        newMioReadAheadBench(true),
+		"ipfs-write":            newIPFSAddBench,
+		"ipfs-read":             newIPFSAddBench,
+		"fuse-write-mem":        newFuseWriteOrReadBench,
+		"fuse-write-ipfs":       newFuseWriteOrReadBench,
+		"fuse-read-mem":         newFuseWriteOrReadBench,
+		"fuse-read-ipfs":        newFuseWriteOrReadBench,
 	}
 )
 
@@ -522,6 +749,17 @@ func ByName(name, ipfsPath string) (Bench, error) {
 	return newBench(ipfsPath, strings.Contains(name, "write"))
 }
 
+// InputIsSupported returns false if the benchmark `benchName`
+// cannot work with the input `inputName`.
+func InputIsSupported(benchName, inputName string) bool {
+	if strings.Contains(benchName, "-batch-") {
+		// Batches need several files to stage:
+		return inputName == "small-files"
+	}
+
+	return true
+}
+
 // BenchmarkNames returns all possible benchmark names
 // in an defined & stable sorting.
 func BenchmarkNames() []string {
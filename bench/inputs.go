@@ -1,6 +1,7 @@
 package bench
 
 import (
+	"archive/tar"
 	"bytes"
 	"encoding/binary"
 	"fmt"
@@ -105,6 +106,76 @@ func (ni *memInput) Close() error {
 
 //////////
 
+// smallFileSize is the size of a single file in the "small-files" input.
+const smallFileSize = 4 * 1024
+
+// smallFilesInput produces a tar archive with many small files.
+// It is meant for benchmarks that stage whole directories.
+type smallFilesInput struct {
+	nFiles int
+	buf    []byte
+}
+
+func newSmallFilesInput(size uint64) Input {
+	nFiles := int(size / smallFileSize)
+	if nFiles == 0 {
+		nFiles = 1
+	}
+
+	return &smallFilesInput{nFiles: nFiles}
+}
+
+func (si *smallFilesInput) Reader(seed uint64) (io.Reader, error) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	data := benchData(smallFileSize, "mixed")
+
+	for idx := 0; idx < si.nFiles; idx++ {
+		// Make every file different, also between the seeds:
+		binary.LittleEndian.PutUint64(data, seed)
+		binary.LittleEndian.PutUint64(data[8:], uint64(idx))
+
+		hdr := &tar.Header{
+			Name: fmt.Sprintf("dir-%d/file-%d", idx/1000, idx),
+			Mode: 0600,
+			Size: int64(len(data)),
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	si.buf = buf.Bytes()
+	return bytes.NewReader(si.buf), nil
+}
+
+func (si *smallFilesInput) Verifier() (Verifier, error) {
+	return &memVerifier{
+		expect:  si.buf,
+		counter: 0,
+	}, nil
+}
+
+// Size returns the size of all files, without the tar overhead.
+func (si *smallFilesInput) Size() int64 {
+	return int64(si.nFiles * smallFileSize)
+}
+
+func (si *smallFilesInput) Close() error {
+	return nil
+}
+
+//////////
+
 var (
 	inputMap = map[string]func(size uint64) (Input, error){
 		"ten": func(size uint64) (Input, error) {
@@ -116,6 +187,9 @@ var (
 		"mixed": func(size uint64) (Input, error) {
 			return newMemInput(size, "mixed"), nil
 		},
+		"small-files": func(size uint64) (Input, error) {
+			return newSmallFilesInput(size), nil
+		},
 	}
 )
 
@@ -0,0 +1,153 @@
+package catfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	e "github.com/pkg/errors"
+	c "github.com/sahib/brig/catfs/core"
+	n "github.com/sahib/brig/catfs/nodes"
+)
+
+// Files can carry arbitrary user defined key/value attributes (e.g. tags
+// like project=holiday). They are stored in the node, so changing them is
+// versioned and synced like a change of the content.
+
+const (
+	// MaxAttrKeyLen is the maximum length of an attribute key.
+	MaxAttrKeyLen = 255
+
+	// MaxAttrValueLen is the maximum size of an attribute value.
+	MaxAttrValueLen = 64 * 1024
+)
+
+// ErrNoSuchAttr is returned when an attribute does not exist.
+var ErrNoSuchAttr = errors.New("no such attribute")
+
+// IsNoSuchAttrError returns true if `err` is (or wraps) ErrNoSuchAttr.
+func IsNoSuchAttrError(err error) bool {
+	return e.Cause(err) == ErrNoSuchAttr
+}
+
+// ValidateAttrKey checks if `key` can be used as attribute key.
+// Keys starting with "brig." are reserved for internal use.
+func ValidateAttrKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("attribute key may not be empty")
+	}
+
+	if len(key) > MaxAttrKeyLen {
+		return fmt.Errorf("attribute key may be at most %d bytes long", MaxAttrKeyLen)
+	}
+
+	if strings.ContainsRune(key, 0) {
+		return fmt.Errorf("attribute key may not contain null bytes")
+	}
+
+	if key == "brig" || strings.HasPrefix(key, "brig.") {
+		return fmt.Errorf("attribute keys starting with `brig.` are reserved")
+	}
+
+	return nil
+}
+
+func (fs *FS) lookupAttrFile(path string) (*n.File, error) {
+	nd, err := lookupFileOrDir(fs.lkr, path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, ok := nd.(*n.File)
+	if !ok {
+		return nil, fmt.Errorf("attributes are only supported on files: %s", path)
+	}
+
+	return file, nil
+}
+
+// modifyAttrs calls `fn` with the attributes of the file at `path`
+// and stages the modified attributes afterwards.
+func (fs *FS) modifyAttrs(path string, fn func(attrs map[string][]byte) error) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.readOnly {
+		return ErrReadOnly
+	}
+
+	path = prefixSlash(path)
+	file, err := fs.lookupAttrFile(path)
+	if err != nil {
+		return err
+	}
+
+	attrs := file.Attributes()
+	if err := fn(attrs); err != nil {
+		return err
+	}
+
+	if err := c.StageAttributes(fs.lkr, file, attrs); err != nil {
+		return err
+	}
+
+	fs.notifyChange(path)
+	return nil
+}
+
+// SetAttr sets the attribute `key` of the file at `path` to `value`.
+func (fs *FS) SetAttr(path, key string, value []byte) error {
+	if err := ValidateAttrKey(key); err != nil {
+		return err
+	}
+
+	if len(value) > MaxAttrValueLen {
+		return fmt.Errorf("attribute value may be at most %d bytes long", MaxAttrValueLen)
+	}
+
+	return fs.modifyAttrs(path, func(attrs map[string][]byte) error {
+		attrs[key] = value
+		return nil
+	})
+}
+
+// RemoveAttr removes the attribute `key` from the file at `path`.
+func (fs *FS) RemoveAttr(path, key string) error {
+	return fs.modifyAttrs(path, func(attrs map[string][]byte) error {
+		if _, ok := attrs[key]; !ok {
+			return e.Wrapf(ErrNoSuchAttr, "%s", key)
+		}
+
+		delete(attrs, key)
+		return nil
+	})
+}
+
+// GetAttr returns the value of the attribute `key` of the node at `path`.
+func (fs *FS) GetAttr(path, key string) ([]byte, error) {
+	attrs, err := fs.Attrs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := attrs[key]
+	if !ok {
+		return nil, e.Wrapf(ErrNoSuchAttr, "%s", key)
+	}
+
+	return value, nil
+}
+
+// Attrs returns all attributes of the node at `path`.
+// Directories have no attributes; an empty map is returned for them.
+func (fs *FS) Attrs(path string) (map[string][]byte, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	nd, err := lookupFileOrDir(fs.lkr, prefixSlash(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return nd.Attributes(), nil
+}
@@ -0,0 +1,120 @@
+package catfs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttrs(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		require.NoError(t, fs.Stage("/photo.jpg", bytes.NewReader([]byte{1, 2, 3})))
+		require.NoError(t, fs.Mkdir("/dir", false))
+
+		require.Error(t, fs.SetAttr("/photo.jpg", "", []byte("x")))
+		require.Error(t, fs.SetAttr("/photo.jpg", "brig.pinned", []byte("x")))
+		require.Error(t, fs.SetAttr("/dir", "project", []byte("x")))
+
+		require.NoError(t, fs.SetAttr("/photo.jpg", "project", []byte("holiday")))
+		require.NoError(t, fs.SetAttr("/photo.jpg", "client", []byte("acme")))
+
+		value, err := fs.GetAttr("/photo.jpg", "project")
+		require.NoError(t, err)
+		require.Equal(t, []byte("holiday"), value)
+
+		_, err = fs.GetAttr("/photo.jpg", "nope")
+		require.True(t, IsNoSuchAttrError(err))
+
+		info, err := fs.Stat("/photo.jpg")
+		require.NoError(t, err)
+		require.Len(t, info.Attrs, 2)
+
+		// Modifying the content keeps the attributes:
+		require.NoError(t, fs.Stage("/photo.jpg", bytes.NewReader([]byte{4, 5, 6})))
+		attrs, err := fs.Attrs("/photo.jpg")
+		require.NoError(t, err)
+		require.Equal(t, []byte("acme"), attrs["client"])
+
+		require.NoError(t, fs.RemoveAttr("/photo.jpg", "client"))
+		require.True(t, IsNoSuchAttrError(fs.RemoveAttr("/photo.jpg", "client")))
+
+		attrs, err = fs.Attrs("/photo.jpg")
+		require.NoError(t, err)
+		require.Len(t, attrs, 1)
+	})
+}
+
+func TestAttrsHistory(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		require.NoError(t, fs.Stage("/x", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.MakeCommit("add x"))
+
+		haveChanges, err := fs.HaveStagedChanges()
+		require.NoError(t, err)
+		require.False(t, haveChanges)
+
+		require.NoError(t, fs.SetAttr("/x", "project", []byte("holiday")))
+
+		haveChanges, err = fs.HaveStagedChanges()
+		require.NoError(t, err)
+		require.True(t, haveChanges)
+		require.NoError(t, fs.MakeCommit("tag x"))
+
+		hist, err := fs.History("/x")
+		require.NoError(t, err)
+		require.True(t, len(hist) >= 2)
+		require.Equal(t, "none", hist[0].Change)
+		require.Equal(t, "modified", hist[1].Change)
+
+		// Old versions still have the old attributes:
+		info, err := fs.StatAt("HEAD^", "/x")
+		require.NoError(t, err)
+		require.Empty(t, info.Attrs)
+	})
+}
+
+func TestSyncAttrs(t *testing.T) {
+	withDummyFS(t, func(fsa *FS) {
+		require.NoError(t, fsa.MakeCommit("hello a"))
+		withDummyFS(t, func(fsb *FS) {
+			require.NoError(t, fsb.lkr.SetOwner("bob"))
+			require.NoError(t, fsb.MakeCommit("hello b"))
+
+			// Attributes are transferred with the file:
+			require.NoError(t, fsb.Stage("/x", bytes.NewReader([]byte{1})))
+			require.NoError(t, fsb.SetAttr("/x", "project", []byte("holiday")))
+			require.NoError(t, fsa.Sync(fsb))
+
+			value, err := fsa.GetAttr("/x", "project")
+			require.NoError(t, err)
+			require.Equal(t, []byte("holiday"), value)
+
+		})
+	})
+}
+
+func TestPatchAttrs(t *testing.T) {
+	withDummyFS(t, func(srcFs *FS) {
+		withDummyFS(t, func(dstFs *FS) {
+			require.NoError(t, srcFs.MakeCommit("init"))
+			require.NoError(t, srcFs.Touch("/x"))
+			require.NoError(t, srcFs.MakeCommit("added x"))
+
+			patch, err := srcFs.MakePatch("commit[0]", nil, "")
+			require.NoError(t, err)
+			require.NoError(t, dstFs.ApplyPatch(patch))
+
+			require.NoError(t, srcFs.SetAttr("/x", "project", []byte("holiday")))
+			require.NoError(t, srcFs.MakeCommit("tag x"))
+
+			patch, err = srcFs.MakePatch("commit[1]", nil, "")
+			require.NoError(t, err)
+			require.NoError(t, dstFs.ApplyPatch(patch))
+
+			value, err := dstFs.GetAttr("/x", "project")
+			require.NoError(t, err)
+			require.Equal(t, []byte("holiday"), value)
+		})
+	})
+}
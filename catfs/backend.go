@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sync"
 
 	"github.com/sahib/brig/catfs/mio"
 	"github.com/sahib/brig/catfs/mio/chunkbuf"
@@ -54,7 +55,9 @@ type FsBackend interface {
 }
 
 // MemFsBackend is a mock structure that implements FsBackend.
+// It is safe to use from several go routines.
 type MemFsBackend struct {
+	mu   sync.Mutex
 	data map[string][]byte
 	pins map[string]bool
 }
@@ -69,6 +72,9 @@ func NewMemFsBackend() *MemFsBackend {
 
 // Cat implements FsBackend.Cat by querying memory.
 func (mb *MemFsBackend) Cat(hash h.Hash) (mio.Stream, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
 	data, ok := mb.data[hash.B58String()]
 	if !ok {
 		return nil, ErrNoSuchHash{hash}
@@ -98,24 +104,36 @@ func (mb *MemFsBackend) Add(r io.Reader) (h.Hash, error) {
 	}
 
 	hash := h.SumWithBackendHash(data)
+
+	mb.mu.Lock()
 	mb.data[hash.B58String()] = data
+	mb.mu.Unlock()
 	return hash, nil
 }
 
 // Pin implements FsBackend.Pin by storing a marker in memory.
 func (mb *MemFsBackend) Pin(hash h.Hash) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
 	mb.pins[hash.B58String()] = true
 	return nil
 }
 
 // Unpin implements FsBackend.Unpin by removing a marker in memory.
 func (mb *MemFsBackend) Unpin(hash h.Hash) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
 	mb.pins[hash.B58String()] = false
 	return nil
 }
 
 // IsPinned implements FsBackend.IsPinned by querying a marker in memory.
 func (mb *MemFsBackend) IsPinned(hash h.Hash) (bool, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
 	isPinned, ok := mb.pins[hash.B58String()]
 	if !ok {
 		return false, nil
@@ -127,6 +145,9 @@ func (mb *MemFsBackend) IsPinned(hash h.Hash) (bool, error) {
 // IsCached implements FsBackend.IsCached by checking if the file exists.
 // If hash found, the file is always cached.
 func (mb *MemFsBackend) IsCached(hash h.Hash) (bool, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
 	_, ok := mb.data[hash.B58String()]
 	return ok, nil
 }
@@ -134,6 +155,9 @@ func (mb *MemFsBackend) IsCached(hash h.Hash) (bool, error) {
 // CachedSize implements FsBackend.CachedSize by returnig data size
 // If hash found, the file is always cached.
 func (mb *MemFsBackend) CachedSize(hash h.Hash) (int64, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
 	data, ok := mb.data[hash.B58String()]
 	if !ok {
 		return -1, nil // negative indicates unknown size
@@ -0,0 +1,83 @@
+package catfs
+
+import (
+	ie "github.com/sahib/brig/catfs/errors"
+	n "github.com/sahib/brig/catfs/nodes"
+)
+
+// compressionUnknown is used for files that were staged
+// before the compression was remembered per file.
+const compressionUnknown = "unknown"
+
+// CompressionStats summarizes how well the files below a folder compress.
+// The sizes are only summed up for files whose backend size is known.
+type CompressionStats struct {
+	// Files is the number of files below the folder.
+	Files int
+	// Size is the sum of the sizes of the counted files.
+	Size uint64
+	// StoredSize is the sum of the backend sizes of the counted files.
+	StoredSize int64
+	// ByCompression maps the used compression to the number of files.
+	ByCompression map[string]int
+}
+
+// Ratio returns the stored size in relation to the actual size.
+// Smaller is better; 1 means that nothing was saved.
+func (cs *CompressionStats) Ratio() float64 {
+	if cs.Size == 0 {
+		return 1
+	}
+
+	return float64(cs.StoredSize) / float64(cs.Size)
+}
+
+// CompressionStats reports which compression the files below `root` use
+// and how much space it saves. `root` may also be a single file.
+func (fs *FS) CompressionStats(root string) (*CompressionStats, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	rootNd, err := fs.lkr.LookupNode(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if rootNd.Type() == n.NodeTypeGhost {
+		return nil, ie.NoSuchFile(root)
+	}
+
+	stats := &CompressionStats{
+		ByCompression: make(map[string]int),
+	}
+
+	err = n.Walk(fs.lkr, rootNd, false, func(child n.Node) error {
+		file, ok := child.(*n.File)
+		if !ok {
+			return nil
+		}
+
+		compression := file.Compression()
+		if compression == "" {
+			compression = compressionUnknown
+		}
+
+		stats.Files++
+		stats.ByCompression[compression]++
+
+		// Files that were never cached have no backend size:
+		if file.CachedSize() < 0 {
+			return nil
+		}
+
+		stats.Size += file.Size()
+		stats.StoredSize += file.CachedSize()
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,46 @@
+package catfs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sahib/brig/util/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionStats(t *testing.T) {
+	t.Parallel()
+
+	withDummyFS(t, func(fs *FS) {
+		text := []byte(strings.Repeat("brig is a distributed file system. ", 1000))
+		noise := testutil.CreateRandomDummyBuf(128*1024, 23)
+
+		require.NoError(t, fs.Mkdir("/docs", true))
+		require.NoError(t, fs.Stage("/docs/text.txt", bytes.NewReader(text)))
+
+		// Random data should not be compressed, no matter what the name says:
+		require.NoError(t, fs.Stage("/docs/noise.txt", bytes.NewReader(noise)))
+
+		info, err := fs.Stat("/docs/text.txt")
+		require.NoError(t, err)
+		require.NotEqual(t, "none", info.Compression)
+		require.NotEqual(t, "", info.Compression)
+
+		info, err = fs.Stat("/docs/noise.txt")
+		require.NoError(t, err)
+		require.Equal(t, "none", info.Compression)
+
+		stats, err := fs.CompressionStats("/docs")
+		require.NoError(t, err)
+		require.Equal(t, 2, stats.Files)
+		require.Equal(t, uint64(len(text)+len(noise)), stats.Size)
+		require.Equal(t, 1, stats.ByCompression["none"])
+		require.True(t, stats.Ratio() < 1)
+
+		stats, err = fs.CompressionStats("/docs/text.txt")
+		require.NoError(t, err)
+		require.Equal(t, 1, stats.Files)
+		require.True(t, stats.Ratio() < 0.1)
+	})
+}
@@ -366,6 +366,7 @@ func StageFromFileNode(lkr *Linker, f *n.File) (*n.File, error) {
 		f.Key(),
 		f.ModTime(),
 		f.IsRaw(),
+		f.Compression(),
 	)
 }
 
@@ -383,6 +384,7 @@ func Stage(
 	key []byte,
 	modTime time.Time,
 	isRaw bool,
+	compression string,
 ) (file *n.File, err error) {
 	node, lerr := lkr.LookupNode(repoPath)
 	if lerr != nil && !ie.IsNoSuchFileError(lerr) {
@@ -465,6 +467,7 @@ func Stage(
 		file.SetKey(key)
 		file.SetUser(lkr.owner)
 		file.SetIsRaw(isRaw)
+		file.SetCompression(compression)
 
 		// Add it again when the hash was changed.
 		log.Debugf("adding %s (%v)", file.Path(), file.BackendHash())
@@ -482,6 +485,39 @@ func Stage(
 	return
 }
 
+// StageAttributes replaces the user attributes of `file` with `attrs`
+// and stages the change. The content of the file is not touched.
+func StageAttributes(lkr *Linker, file *n.File, attrs map[string][]byte) error {
+	return lkr.Atomic(func() (bool, error) {
+		parentDir, err := n.ParentDirectory(lkr, file)
+		if err != nil {
+			return true, err
+		}
+
+		if parentDir == nil {
+			return true, fmt.Errorf("%s has no parent yet (BUG)", file.Path())
+		}
+
+		// Remove the child before changing the hash:
+		if err := parentDir.RemoveChild(lkr, file); err != nil {
+			return true, err
+		}
+
+		file.SetAttributes(lkr, attrs)
+		file.SetUser(lkr.owner)
+
+		if err := parentDir.Add(lkr, file); err != nil {
+			return true, err
+		}
+
+		if err := lkr.StageNode(file); err != nil {
+			return true, err
+		}
+
+		return false, nil
+	})
+}
+
 // Log will call `fn` on every commit we currently have, starting
 // with the most current one (CURR, then HEAD, ...).
 // If `fn` will return an error, the iteration is being stopped.
@@ -499,6 +499,7 @@ func TestStage(t *testing.T) {
 			key,
 			time.Now(),
 			false,
+			"",
 		)
 		if err != nil {
 			t.Fatalf("Adding of /photos/moose.png failed: %v", err)
@@ -516,6 +517,7 @@ func TestStage(t *testing.T) {
 			key,
 			time.Now(),
 			false,
+			"",
 		)
 		if err != nil {
 			t.Fatalf("Adding of /photos/moose.png failed: %v", err)
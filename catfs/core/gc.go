@@ -16,6 +16,7 @@ type GarbageCollector struct {
 	kv       db.Database
 	notifier func(nd n.Node) bool
 	markMap  map[string]struct{}
+	shardMap map[string]struct{}
 }
 
 // NewGarbageCollector will return a new GC, operating on `lkr` and `kv`.
@@ -47,12 +48,32 @@ func (gc *GarbageCollector) markMoveMap(key []string) error {
 
 		if node != nil {
 			gc.markMap[node.TreeHash().B58String()] = struct{}{}
+			if err := gc.markShards(node); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+func (gc *GarbageCollector) markShards(nd n.Node) error {
+	dir := shardedDirectory(nd)
+	if dir == nil {
+		return nil
+	}
+
+	return dir.VisitShards(gc.lkr, func(key h.Hash, data []byte) (bool, error) {
+		b58Key := key.B58String()
+		if _, ok := gc.shardMap[b58Key]; ok {
+			return false, nil
+		}
+
+		gc.shardMap[b58Key] = struct{}{}
+		return true, nil
+	})
+}
+
 func (gc *GarbageCollector) mark(cmt *n.Commit, recursive bool) error {
 	if cmt == nil {
 		return nil
@@ -66,7 +87,7 @@ func (gc *GarbageCollector) mark(cmt *n.Commit, recursive bool) error {
 	gc.markMap[cmt.TreeHash().B58String()] = struct{}{}
 	err = n.Walk(gc.lkr, root, true, func(child n.Node) error {
 		gc.markMap[child.TreeHash().B58String()] = struct{}{}
-		return nil
+		return gc.markShards(child)
 	})
 
 	if err != nil {
@@ -136,6 +157,28 @@ func (gc *GarbageCollector) sweep(prefix []string) (int, error) {
 	})
 }
 
+func (gc *GarbageCollector) sweepShards(prefix []string) (int, error) {
+	removed := 0
+
+	return removed, gc.lkr.AtomicWithBatch(func(batch db.Batch) (bool, error) {
+		keys, err := gc.kv.Keys(prefix...)
+		if err != nil {
+			return hintRollback(err)
+		}
+
+		for _, key := range keys {
+			if _, ok := gc.shardMap[key[len(key)-1]]; ok {
+				continue
+			}
+
+			batch.Erase(key...)
+			removed++
+		}
+
+		return false, nil
+	})
+}
+
 func (gc *GarbageCollector) findAllMoveLocations(head *n.Commit) ([][]string, error) {
 	locations := [][]string{
 		{"stage", "moves"},
@@ -169,6 +212,7 @@ func (gc *GarbageCollector) findAllMoveLocations(head *n.Commit) ([][]string, er
 // all objects in the key value store.
 func (gc *GarbageCollector) Run(allObjects bool) error {
 	gc.markMap = make(map[string]struct{})
+	gc.shardMap = make(map[string]struct{})
 	head, err := gc.lkr.Status()
 	if err != nil {
 		return err
@@ -203,6 +247,10 @@ func (gc *GarbageCollector) Run(allObjects bool) error {
 		log.Debugf("removed %d unreachable staging objects.", removed)
 	}
 
+	if _, err := gc.sweepShards([]string{"stage", "shards"}); err != nil {
+		return err
+	}
+
 	if allObjects {
 		removed, err = gc.sweep([]string{"objects"})
 		if err != nil {
@@ -213,6 +261,10 @@ func (gc *GarbageCollector) Run(allObjects bool) error {
 			log.Warningf("removed %d unreachable permanent objects.", removed)
 			log.Warningf("this might indiciate a bug in catfs somewhere.")
 		}
+
+		if _, err := gc.sweepShards([]string{"shards"}); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -3,6 +3,7 @@ package core
 // Layout of the key/value store:
 //
 // objects/<NODE_HASH>                   => NODE_METADATA
+// shards/<SHARD_HASH>                   => DIRECTORY_SHARD
 // tree/<FULL_NODE_PATH>                 => NODE_HASH
 // index/<CMT_INDEX>                     => COMMIT_HASH
 // inode/<INODE>                         => NODE_HASH
@@ -10,6 +11,7 @@ package core
 // moves/overlay/<INODE>                 => MOVE_INFO
 //
 // stage/objects/<NODE_HASH>             => NODE_METADATA
+// stage/shards/<SHARD_HASH>             => DIRECTORY_SHARD
 // stage/tree/<FULL_NODE_PATH>           => NODE_HASH
 // stage/STATUS                          => COMMIT_METADATA
 // stage/moves/<INODE>                   => MOVE_INFO
@@ -278,6 +280,46 @@ func (lkr *Linker) NodeByHash(hash h.Hash) (n.Node, error) {
 	return nd, nil
 }
 
+// ShardByHash returns the directory shard stored under `key`.
+func (lkr *Linker) ShardByHash(key h.Hash) ([]byte, error) {
+	b58Key := key.B58String()
+	for _, bucketPath := range [][]string{
+		{"stage", "shards", b58Key},
+		{"shards", b58Key},
+	} {
+		data, err := lkr.kv.Get(bucketPath...)
+		if err != nil && err != db.ErrNoSuchKey {
+			return nil, err
+		}
+
+		if data != nil {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no such directory shard: %s", b58Key)
+}
+
+// shardedDirectory returns the directory of `nd` (or of the ghost `nd`)
+// if its children are stored in shards. Otherwise nil is returned.
+func shardedDirectory(nd n.Node) *n.Directory {
+	var dir *n.Directory
+	switch nd.Type() {
+	case n.NodeTypeDirectory:
+		dir, _ = nd.(*n.Directory)
+	case n.NodeTypeGhost:
+		if ghost, ok := nd.(*n.Ghost); ok {
+			dir, _ = ghost.OldDirectory()
+		}
+	}
+
+	if dir == nil || !dir.IsSharded() {
+		return nil
+	}
+
+	return dir
+}
+
 func appendDot(path string) string {
 	// path.Join() calls path.Clean() which in turn
 	// removes the '.' at the end when trying to join that.
@@ -427,6 +469,18 @@ func (lkr *Linker) stageNodeRecursive(batch db.Batch, nd n.Node) error {
 	b58Hash := nd.TreeHash().B58String()
 	batch.Put(data, "stage", "objects", b58Hash)
 
+	if dir := shardedDirectory(nd); dir != nil {
+		// Only the shards that changed need to be written:
+		err := dir.UnsavedShards(func(key h.Hash, data []byte) error {
+			batch.Put(data, "stage", "shards", key.B58String())
+			return nil
+		})
+
+		if err != nil {
+			return e.Wrapf(err, "shards")
+		}
+	}
+
 	uidKey := strconv.FormatUint(nd.Inode(), 10)
 	batch.Put([]byte(nd.TreeHash().B58String()), "inode", uidKey)
 
@@ -494,8 +548,15 @@ func (lkr *Linker) SetMergeMarker(with string, remoteHead h.Hash) error {
 // If nothing changed since the last call to MakeCommit, it will
 // return ErrNoChange, which can be reacted upon.
 func (lkr *Linker) MakeCommit(author string, message string) error {
+	return lkr.MakeCommitAt(author, message, time.Time{})
+}
+
+// MakeCommitAt works like MakeCommit, but records `date` as the time
+// of the commit instead of the current time. This is useful when
+// importing history from other systems. A zero `date` means "now".
+func (lkr *Linker) MakeCommitAt(author, message string, date time.Time) error {
 	return lkr.AtomicWithBatch(func(batch db.Batch) (bool, error) {
-		switch err := lkr.makeCommit(batch, author, message); err {
+		switch err := lkr.makeCommit(batch, author, message, date); err {
 		case ie.ErrNoChange:
 			return false, err
 		case nil:
@@ -518,6 +579,10 @@ func (lkr *Linker) makeCommitPutCurrToPersistent(batch db.Batch, rootDir *n.Dire
 		batch.Put(data, "objects", b58Hash)
 		exportedInodes[child.Inode()] = true
 
+		if err := lkr.makeCommitPutShards(batch, child); err != nil {
+			return err
+		}
+
 		childPath := child.Path()
 		if child.Type() == n.NodeTypeDirectory {
 			childPath = appendDot(childPath)
@@ -528,7 +593,27 @@ func (lkr *Linker) makeCommitPutCurrToPersistent(batch db.Batch, rootDir *n.Dire
 	})
 }
 
-func (lkr *Linker) makeCommit(batch db.Batch, author string, message string) error {
+// makeCommitPutShards copies the shards of `nd` to the persistent store.
+func (lkr *Linker) makeCommitPutShards(batch db.Batch, nd n.Node) error {
+	dir := shardedDirectory(nd)
+	if dir == nil {
+		return nil
+	}
+
+	return dir.VisitShards(lkr, func(key h.Hash, data []byte) (bool, error) {
+		b58Key := key.B58String()
+		if _, err := lkr.kv.Get("shards", b58Key); err != db.ErrNoSuchKey {
+			// Shards are content addressed; if this one was persisted
+			// already, all shards below it were too.
+			return false, err
+		}
+
+		batch.Put(data, "shards", b58Key)
+		return true, nil
+	})
+}
+
+func (lkr *Linker) makeCommit(batch db.Batch, author string, message string, date time.Time) error {
 	head, err := lkr.Head()
 	if err != nil && !ie.IsErrNoSuchRef(err) {
 		return err
@@ -567,6 +652,10 @@ func (lkr *Linker) makeCommit(batch db.Batch, author string, message string) err
 		}
 	}
 
+	if !date.IsZero() {
+		status.SetModTime(date)
+	}
+
 	if err := status.BoxCommit(author, message); err != nil {
 		return err
 	}
@@ -625,6 +714,7 @@ func (lkr *Linker) clearStage(batch db.Batch) error {
 	// Clear the staging area.
 	toClear := [][]string{
 		{"stage", "objects"},
+		{"stage", "shards"},
 		{"stage", "tree"},
 		{"stage", "moves"},
 	}
@@ -1435,6 +1525,13 @@ func (lkr *Linker) commitMoveMappingKey(
 		}
 	}
 
+	// The same goes for the shards of huge directories:
+	for _, nd := range []n.Node{dstNode, srcNode} {
+		if err := lkr.makeCommitPutShards(batch, nd); err != nil {
+			return err
+		}
+	}
+
 	// We already have a bidir mapping for this node, no need to mention
 	// them further.  (would not hurt, but would be duplicated work)
 	delete(exported, srcNode.Inode())
@@ -620,3 +620,59 @@ func TestLookupNodeAt(t *testing.T) {
 		require.Nil(t, last)
 	})
 }
+
+func TestLinkerShardedDirectory(t *testing.T) {
+	const nFiles = 2100
+
+	WithReloadingLinker(t, func(lkr *Linker) {
+		dir := MustMkdir(t, lkr, "/huge")
+
+		// Staging every single file would write every intermediate
+		// version of the directory and is too slow for a test.
+		for idx := 0; idx < nFiles; idx++ {
+			file := n.NewEmptyFile(dir, fmt.Sprintf("%04d", idx), lkr.owner, lkr.NextInode())
+			file.SetContent(lkr, h.TestDummy(t, byte(idx)))
+			require.Nil(t, dir.Add(lkr, file))
+			lkr.MemIndexAdd(file, true)
+		}
+
+		require.Nil(t, lkr.StageNode(dir))
+		require.True(t, dir.IsSharded())
+		require.Equal(t, nFiles, dir.NChildren())
+
+		gc := NewGarbageCollector(lkr, lkr.kv, nil)
+		require.Nil(t, gc.Run(false))
+		MustCommit(t, lkr, "add huge")
+
+		keys, err := lkr.kv.Keys("stage", "shards")
+		require.Nil(t, err)
+		require.Len(t, keys, 0)
+
+		file, err := lkr.LookupFile("/huge/0042")
+		require.Nil(t, err)
+		MustRemove(t, lkr, file)
+		require.Nil(t, gc.Run(false))
+		MustCommit(t, lkr, "remove one")
+	}, func(lkr *Linker) {
+		dir := MustLookupDirectory(t, lkr, "/huge")
+		require.True(t, dir.IsSharded())
+
+		// The ghost of the removed file is still a child:
+		require.Equal(t, nFiles, dir.NChildren())
+
+		file, err := lkr.LookupFile("/huge/0043")
+		require.Nil(t, err)
+		require.Equal(t, h.TestDummy(t, 43), file.ContentHash())
+
+		_, err = lkr.LookupGhost("/huge/0042")
+		require.Nil(t, err)
+
+		// The older version is still reachable too:
+		cmt, err := lkr.CommitByIndex(1)
+		require.Nil(t, err)
+
+		nd, err := lkr.LookupNodeAt(cmt, "/huge/0042")
+		require.Nil(t, err)
+		require.Equal(t, n.NodeTypeFile, nd.Type())
+	})
+}
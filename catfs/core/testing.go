@@ -208,6 +208,7 @@ func MustTouchAndCommit(t *testing.T, lkr *Linker, path string, seed byte) (*n.F
 		nil,
 		time.Now(),
 		false,
+		"",
 	)
 	if err != nil {
 		t.Fatalf("Failed to stage %s at %d: %v", path, seed, err)
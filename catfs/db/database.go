@@ -2,7 +2,11 @@ package db
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 )
 
 var (
@@ -80,3 +84,47 @@ func CopyKey(db Database, src, dst []string) error {
 	batch.Put(data, dst...)
 	return batch.Flush()
 }
+
+const (
+	// TypeBadger is the name of the default, badger based database.
+	TypeBadger = "badger"
+	// TypeDisk is the name of the file-per-key database.
+	TypeDisk = "disk"
+	// TypeMemory is the name of the in-memory database (mostly for tests)
+	TypeMemory = "memory"
+)
+
+// Open opens a database of type `dbType` (one of the Type* constants) at `path`.
+func Open(dbType, path string) (Database, error) {
+	switch dbType {
+	case TypeBadger:
+		return NewBadgerDatabase(path)
+	case TypeDisk:
+		return NewDiskDatabase(path)
+	case TypeMemory:
+		return NewMemoryDatabase(), nil
+	default:
+		return nil, fmt.Errorf("unknown database type: %s", dbType)
+	}
+}
+
+// DetectType checks what kind of database is stored at `path`.
+// Badger always writes a MANIFEST file, every other non-empty
+// directory is assumed to be a disk database. Empty or non-existing
+// directories will be opened as badger database, since that's the default.
+func DetectType(path string) (string, error) {
+	if _, err := os.Stat(filepath.Join(path, "MANIFEST")); err == nil {
+		return TypeBadger, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if len(entries) == 0 {
+		return TypeBadger, nil
+	}
+
+	return TypeDisk, nil
+}
@@ -15,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/sahib/config"
 	log "github.com/sirupsen/logrus"
 	capnp "zombiezen.com/go/capnproto2"
@@ -24,6 +25,7 @@ import (
 	"github.com/sahib/brig/catfs/db"
 	ie "github.com/sahib/brig/catfs/errors"
 	"github.com/sahib/brig/catfs/mio"
+	"github.com/sahib/brig/catfs/mio/compress"
 	"github.com/sahib/brig/catfs/mio/pagecache"
 	n "github.com/sahib/brig/catfs/nodes"
 	"github.com/sahib/brig/catfs/vcs"
@@ -63,6 +65,13 @@ func (dhm defaultHintManager) Set(path string, hint hints.Hint) error {
 	return fmt.Errorf("no hint manager, cannot remember hints")
 }
 
+// DictStore is the API for looking up compression dictionaries.
+type DictStore interface {
+	// Dict should return the dictionary with `id` or an error
+	// wrapping compress.ErrDictNotFound if there is none.
+	Dict(id compress.DictID) (*compress.Dict, error)
+}
+
 // FS (short for Filesystem) is the central API entry for everything related to
 // paths.  It exposes a POSIX-like interface where path are mapped to the
 // actual underlying hashes and the associated metadata.
@@ -77,6 +86,9 @@ type FS struct {
 	// underlying key/value store
 	kv db.Database
 
+	// type of kv (see db.DetectType)
+	dbType string
+
 	// linker (holds all nodes together)
 	lkr *c.Linker
 
@@ -92,6 +104,29 @@ type FS struct {
 	// channel to schedule repins and quit the loop
 	repinControl chan string
 
+	// channel to quit the snapshot loop
+	snapshotControl chan bool
+
+	// channel to deliver change notifications and quit the loop
+	changeControl chan bool
+
+	// paths that were changed, but not yet delivered to changeCallbacks
+	changeMu        sync.Mutex
+	pendingChanges  []string
+	changeCallbacks []func(paths []string)
+
+	// called when our own locks changed
+	lockCallbacks []func()
+
+	// channel to schedule prefetch runs and quit the loop
+	prefetchControl chan bool
+
+	// progress of the prefetcher
+	prefetch prefetcher
+
+	// set once Close() was called; background tasks check it.
+	closed bool
+
 	// Actual storage backend (e.g. ipfs or memory)
 	bk FsBackend
 
@@ -108,6 +143,9 @@ type FS struct {
 	// interface to load stream hints
 	hintManager HintManager
 
+	// interface to load compression dictionaries (may be nil)
+	dicts DictStore
+
 	// cache for storing pages written to catfs.Handle
 	// (may be nil if not used, e.g. for tests)
 	pageCache pagecache.Cache
@@ -117,6 +155,10 @@ type FS struct {
 // and a modifying operation was called on it.
 var ErrReadOnly = errors.New("fs is read only")
 
+// ErrFsClosed is returned by long running operations
+// when the file system was closed in the meantime.
+var ErrFsClosed = errors.New("fs was closed")
+
 // StatInfo describes the metadata of a single node.
 // The concept is comparable to the POSIX stat() call.
 type StatInfo struct {
@@ -158,6 +200,13 @@ type StatInfo struct {
 
 	// Key is the encryption key for the file.
 	Key []byte
+
+	// Compression is the compression used for the stream of the file,
+	// e.g. "zstd-fastest" or "none". Empty for directories or if unknown.
+	Compression string
+
+	// Attrs are the user defined attributes of the file.
+	Attrs map[string][]byte
 }
 
 // DiffPair is a pair of nodes.
@@ -199,6 +248,8 @@ type Commit struct {
 	Hash h.Hash
 	// Msg describes the committed contents
 	Msg string
+	// Author is the person that made the commit
+	Author string
 	// Tags is a user defined list of tags
 	// (tags like HEAD, CURR and INIT are assigned dynamically as exception)
 	Tags []string
@@ -256,6 +307,9 @@ func (fs *FS) nodeToStat(nd n.Node) *StatInfo {
 	var isDir bool
 	var isRaw bool
 	var key []byte
+	var compression string
+
+	modTime := nd.ModTime()
 
 	switch nd.Type() {
 	case n.NodeTypeFile:
@@ -266,19 +320,28 @@ func (fs *FS) nodeToStat(nd n.Node) *StatInfo {
 		}
 
 		isRaw = file.IsRaw()
+		compression = file.Compression()
 	case n.NodeTypeDirectory:
 		isDir = true
 	case n.NodeTypeGhost:
 		ghost, ok := nd.(*n.Ghost)
 		if ok {
 			isDir = (ghost.OldNode().Type() == n.NodeTypeDirectory)
+
+			// For deleted nodes it's more interesting when they were
+			// deleted and if their content still takes up space.
+			modTime = ghost.DeletedAt()
+			isPinned, isExplicit, err = fs.pinner.IsNodePinned(ghost.OldNode())
+			if err != nil {
+				log.Warningf("stat: failed to acquire pin state of ghost: %v", err)
+			}
 		}
 	}
 
 	return &StatInfo{
 		Path:        nd.Path(),
 		User:        nd.User(),
-		ModTime:     nd.ModTime(),
+		ModTime:     modTime,
 		IsDir:       isDir,
 		Inode:       nd.Inode(),
 		Size:        nd.Size(),
@@ -291,6 +354,8 @@ func (fs *FS) nodeToStat(nd n.Node) *StatInfo {
 		BackendHash: nd.BackendHash().Clone(),
 		TreeHash:    nd.TreeHash().Clone(),
 		Key:         key,
+		Compression: compression,
+		Attrs:       nd.Attributes(),
 	}
 }
 
@@ -364,9 +429,17 @@ func NewFilesystem(
 	readOnly bool,
 	fsCfg *config.Config,
 	hintManager HintManager,
+	dicts DictStore,
 	pageCache pagecache.Cache,
 ) (*FS, error) {
-	kv, err := db.NewBadgerDatabase(dbPath)
+	// The database type can be changed by MigrateDatabase(),
+	// so we cannot simply assume that badger is used.
+	dbType, err := db.DetectType(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := db.Open(dbType, dbPath)
 	if err != nil {
 		return nil, err
 	}
@@ -396,6 +469,7 @@ func NewFilesystem(
 
 	fs := &FS{
 		kv:                kv,
+		dbType:            dbType,
 		lkr:               lkr,
 		bk:                backend,
 		cfg:               fsCfg,
@@ -403,8 +477,12 @@ func NewFilesystem(
 		gcControl:         make(chan bool, 1),
 		autoCommitControl: make(chan bool, 1),
 		repinControl:      make(chan string, 1),
+		snapshotControl:   make(chan bool, 1),
+		changeControl:     make(chan bool, 1),
+		prefetchControl:   make(chan bool, 1),
 		pinner:            pinCache,
 		hintManager:       hintManager,
+		dicts:             dicts,
 		pageCache:         pageCache,
 	}
 
@@ -416,6 +494,13 @@ func NewFilesystem(
 	go fs.gcLoop()
 	go fs.autoCommitLoop()
 	go fs.repinLoop()
+	go fs.snapshotLoop()
+	go fs.changeLoop()
+	go fs.prefetchLoop()
+
+	// New content below offline marks should be fetched as soon as it appears:
+	fs.OnChange(fs.prefetchOnChange)
+	fs.schedulePrefetch()
 
 	return fs, nil
 }
@@ -471,7 +556,7 @@ func (fs *FS) repinLoop() {
 		return
 	}
 
-	lastCheck := time.Now()
+	lastCheck, lastPurge := time.Now(), time.Now()
 	checkTicker := time.NewTicker(1 * time.Second)
 	defer checkTicker.Stop()
 
@@ -491,6 +576,15 @@ func (fs *FS) repinLoop() {
 
 			lastCheck = time.Now()
 		case <-checkTicker.C:
+			// Unpin old content in the trash, so repin has a clear view:
+			retention := fs.cfg.Duration("trash.retention")
+			if retention > 0 && time.Since(lastPurge) >= fs.cfg.Duration("repin.interval") {
+				lastPurge = time.Now()
+				if _, err := fs.PurgeTrash("/", retention); err != nil {
+					log.Warningf("trash purge failed: %v", err)
+				}
+			}
+
 			isEnabled := fs.cfg.Bool("repin.enabled")
 			if !isEnabled {
 				continue
@@ -512,9 +606,14 @@ func (fs *FS) Close() error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
+	fs.closed = true
+
 	go func() { fs.gcControl <- false }()
 	go func() { fs.autoCommitControl <- false }()
 	go func() { fs.repinControl <- "" }()
+	go func() { fs.snapshotControl <- false }()
+	go func() { fs.changeControl <- false }()
+	go func() { fs.prefetchControl <- false }()
 
 	if err := fs.pinner.Close(); err != nil {
 		log.Warnf("Failed to close pin cache: %v", err)
@@ -542,6 +641,7 @@ func (fs *FS) Import(r io.Reader) error {
 
 	// disk (probably) changed, delete memcache:
 	fs.lkr.MemIndexClear()
+	fs.notifyChange("/")
 	return nil
 }
 
@@ -564,7 +664,12 @@ func (fs *FS) Move(src, dst string) error {
 		return err
 	}
 
-	return c.Move(fs.lkr, srcNd, dst)
+	if err := c.Move(fs.lkr, srcNd, dst); err != nil {
+		return err
+	}
+
+	fs.notifyChange(src, dst)
+	return nil
 }
 
 // Copy will copy the file or directory at `src` to `dst`.
@@ -582,8 +687,12 @@ func (fs *FS) Copy(src, dst string) error {
 		return err
 	}
 
-	_, err = c.Copy(fs.lkr, srcNd, dst)
-	return err
+	if _, err := c.Copy(fs.lkr, srcNd, dst); err != nil {
+		return err
+	}
+
+	fs.notifyChange(dst)
+	return nil
 }
 
 // Mkdir creates a new empty directory at `dir`, possibly creating
@@ -598,8 +707,12 @@ func (fs *FS) Mkdir(dir string, createParents bool) error {
 
 	// "brig mkdir ." somehow is able to overwrite everything:
 	dir = strings.TrimLeft(path.Clean(dir), ".")
-	_, err := c.Mkdir(fs.lkr, dir, createParents)
-	return err
+	if _, err := c.Mkdir(fs.lkr, dir, createParents); err != nil {
+		return err
+	}
+
+	fs.notifyChange(dir)
+	return nil
 }
 
 // Remove removes the file or directory at `path`.
@@ -617,8 +730,12 @@ func (fs *FS) Remove(path string) error {
 	}
 
 	// TODO: What should remove do with the pin state?
-	_, _, err = c.Remove(fs.lkr, nd, true, true)
-	return err
+	if _, _, err := c.Remove(fs.lkr, nd, true, true); err != nil {
+		return err
+	}
+
+	fs.notifyChange(path)
+	return nil
 }
 
 // Stat delivers detailed information about the node at `path`.
@@ -638,6 +755,41 @@ func (fs *FS) Stat(path string) (*StatInfo, error) {
 	return fs.nodeToStat(nd), nil
 }
 
+// lookupNodeAt resolves `path` at the state of the commit `rev`.
+// Unknown revisions are reported as non-existing files, since there
+// is no way to tell them apart from typos in a path. fs.mu needs to be locked.
+func (fs *FS) lookupNodeAt(rev, path string) (n.Node, error) {
+	cmt, err := parseRev(fs.lkr, rev)
+	if err != nil {
+		log.Debugf("lookup-at: failed to parse rev %s: %v", rev, err)
+		return nil, ie.NoSuchFile(path)
+	}
+
+	nd, err := fs.lkr.LookupNodeAt(cmt, prefixSlash(path))
+	if err != nil {
+		return nil, err
+	}
+
+	if nd == nil || nd.Type() == n.NodeTypeGhost {
+		return nil, ie.NoSuchFile(path)
+	}
+
+	return nd, nil
+}
+
+// StatAt works like Stat, but returns the state of `path` at `rev`.
+func (fs *FS) StatAt(rev, path string) (*StatInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	nd, err := fs.lookupNodeAt(rev, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.nodeToStat(nd), nil
+}
+
 // Filter implements a quick and easy way to search over all files
 // by using a query that checks if it is part of the path.
 func (fs *FS) Filter(root, query string) ([]*StatInfo, error) {
@@ -796,6 +948,108 @@ func (fs *FS) Pin(path, rev string, explicit bool) error {
 	return fs.doPin(path, rev, fs.pinner.PinNode, explicit)
 }
 
+// PinProgress describes how far PinWithProgress got.
+type PinProgress struct {
+	// Current is the file that was pinned last.
+	Current    string
+	DoneFiles  int64
+	TotalFiles int64
+	DoneBytes  uint64
+	TotalBytes uint64
+}
+
+// pinItem is a file that PinWithProgress still has to pin.
+type pinItem struct {
+	path        string
+	inode       uint64
+	backendHash h.Hash
+	size        uint64
+}
+
+// PinWithProgress works like Pin, but pins directories file by file.
+// After each file, `progress` is called. Other operations may run
+// in between, since the lock is not held while pinning a directory.
+func (fs *FS) PinWithProgress(path, rev string, explicit bool, progress func(PinProgress)) error {
+	fs.mu.Lock()
+
+	cmt, err := parseRev(fs.lkr, rev)
+	if err != nil {
+		fs.mu.Unlock()
+		return err
+	}
+
+	root, err := fs.lkr.DirectoryByHash(cmt.Root())
+	if err != nil {
+		fs.mu.Unlock()
+		return err
+	}
+
+	nd, err := root.Lookup(fs.lkr, path)
+	if err != nil {
+		fs.mu.Unlock()
+		return err
+	}
+
+	if nd == nil || nd.Type() == n.NodeTypeGhost {
+		fs.mu.Unlock()
+		return ie.NoSuchFile(path)
+	}
+
+	items := []pinItem{}
+	status := PinProgress{}
+	err = n.Walk(fs.lkr, nd, true, func(child n.Node) error {
+		file, ok := child.(*n.File)
+		if !ok {
+			return nil
+		}
+
+		items = append(items, pinItem{
+			path:        file.Path(),
+			inode:       file.Inode(),
+			backendHash: file.BackendHash().Clone(),
+			size:        file.Size(),
+		})
+
+		status.TotalBytes += file.Size()
+		return nil
+	})
+
+	fs.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	status.TotalFiles = int64(len(items))
+	progress(status)
+
+	for _, item := range items {
+		fs.mu.Lock()
+		if fs.closed {
+			fs.mu.Unlock()
+			return ErrFsClosed
+		}
+
+		err := fs.pinner.Pin(item.inode, item.backendHash, explicit)
+		fs.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		status.Current = item.path
+		status.DoneFiles++
+		status.DoneBytes += item.size
+		progress(status)
+	}
+
+	// Make sure the data is available (if requested):
+	if nd.Type() == n.NodeTypeFile {
+		fs.preCacheInBackground(nd.BackendHash())
+	}
+
+	return nil
+}
+
 // Unpin will unpin the file or directory at `path` explicitly.
 func (fs *FS) Unpin(path, rev string, explicit bool) error {
 	return fs.doPin(path, rev, fs.pinner.UnpinNode, explicit)
@@ -888,6 +1142,7 @@ func (fs *FS) Touch(path string) error {
 		}
 		if modNd.Type() != n.NodeTypeGhost {
 			modNd.SetModTime(time.Now())
+			fs.notifyChange(path)
 			fs.mu.Unlock()
 			return nil
 		}
@@ -928,7 +1183,12 @@ func (fs *FS) Truncate(path string, size uint64) error {
 	}
 
 	nd.SetSize(size)
-	return fs.lkr.StageNode(nd)
+	if err := fs.lkr.StageNode(nd); err != nil {
+		return err
+	}
+
+	fs.notifyChange(path)
+	return nil
 }
 
 func (fs *FS) renewPins(oldFile, newFile *n.File) error {
@@ -998,7 +1258,11 @@ func (fs *FS) preStageKeyGen(path string) ([]byte, error) {
 	}
 
 	// only create a new key for new files.
-	// The key depends on the content hash and the size.
+	return newEncryptionKey()
+}
+
+// newEncryptionKey generates a new random key for a file.
+func newEncryptionKey() ([]byte, error) {
 	key := make([]byte, defaultEncryptionKeyLength)
 	if _, err := rand.Read(key); err != nil {
 		return nil, e.Wrapf(err, "failed to generate random key")
@@ -1021,6 +1285,22 @@ func (fs *FS) Stage(path string, r io.Reader) error {
 	return fs.stageWithKey(path, r, key)
 }
 
+// StageWithNewKey works like Stage, but always encrypts the content with a
+// freshly generated key, even if `path` already exists. This is used for
+// re-keying: whoever knew the old key can not read the new content.
+func (fs *FS) StageWithNewKey(path string, r io.Reader) error {
+	if fs.readOnly {
+		return ErrReadOnly
+	}
+
+	key, err := newEncryptionKey()
+	if err != nil {
+		return err
+	}
+
+	return fs.stageWithKey(path, r, key)
+}
+
 // stageWithKey reads all data from `r` and stores as content of the node at `path`.
 // It uses provided encryption key
 // If `path` already exists, it will be updated.
@@ -1032,7 +1312,40 @@ func (fs *FS) stageWithKey(path string, r io.Reader, key []byte) error {
 
 	// NOTE: fs.mu is not locked here since I/O can be done in parallel.
 	//       If you need locking, you can do it at the bottom of this method.
+	content, err := fs.addContent(path, r, key)
+	if err != nil {
+		return err
+	}
+
+	// Lock it again for the metadata staging:
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	newFile, err := fs.stageContent(content)
+	if err != nil {
+		return err
+	}
+
+	fs.notifyChange(path)
+	return fs.pinner.PinNode(newFile, false)
+}
+
+// stagedContent describes content that was added to the backend,
+// but whose metadata was not staged yet.
+type stagedContent struct {
+	path        string
+	contentHash h.Hash
+	backendHash h.Hash
+	size        uint64
+	cachedSize  int64
+	key         []byte
+	isRaw       bool
+	compression string
+}
 
+// addContent reads all of `r` and adds it to the backend.
+// It does not need fs.mu to be locked.
+func (fs *FS) addContent(path string, r io.Reader, key []byte) (*stagedContent, error) {
 	// Branch off a part of the stream and pipe it through
 	// a hash writer to compute the hash while reading the stream:
 	hashWriter := h.NewHashWriter()
@@ -1045,47 +1358,77 @@ func (fs *FS) stageWithKey(path string, r io.Reader, key []byte) error {
 	sizeReader := io.TeeReader(hashReader, sizeAcc)
 
 	hint := fs.hintManager.Lookup(path)
-	stream, isRaw, err := mio.NewInStream(sizeReader, path, key, hint)
+	dict, err := fs.hintDict(hint)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	stream, enc, err := mio.NewInStreamWithDict(sizeReader, path, key, hint, dict)
+	if err != nil {
+		return nil, err
 	}
 
 	backendHash, err := fs.bk.Add(stream)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	cachedSize, err := fs.bk.CachedSize(backendHash)
+	if err != nil {
+		return nil, err
 	}
 
 	// The stream was consumed, we now know those attrs:
-	size := sizeAcc.Size()
-	contentHash := hashWriter.Finalize()
+	return &stagedContent{
+		path:        path,
+		contentHash: hashWriter.Finalize(),
+		backendHash: backendHash,
+		size:        sizeAcc.Size(),
+		cachedSize:  cachedSize,
+		key:         key,
+		isRaw:       enc.IsRaw,
+		compression: enc.Compression,
+	}, nil
+}
 
-	// Lock it again for the metadata staging:
-	fs.mu.Lock()
-	defer fs.mu.Unlock()
+// hintDict returns the compression dictionary set in `hint` or nil.
+func (fs *FS) hintDict(hint hints.Hint) (*compress.Dict, error) {
+	if hint.ZstdDict == "" || hint.CompressionAlgo != hints.CompressionZstd {
+		return nil, nil
+	}
 
-	cachedSize, err := fs.bk.CachedSize(backendHash)
+	id, err := compress.ParseDictID(hint.ZstdDict)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Remember the metadata:
-	newFile, err := c.Stage(
-		fs.lkr,
-		path,
-		contentHash,
-		backendHash,
-		size,
-		cachedSize,
-		key,
-		time.Now(),
-		isRaw,
-	)
+	return fs.lookupDict(id)
+}
 
-	if err != nil {
-		return err
+// lookupDict is used to decode streams that were compressed with a dictionary.
+func (fs *FS) lookupDict(id compress.DictID) (*compress.Dict, error) {
+	if fs.dicts == nil {
+		return nil, e.Wrapf(compress.ErrDictNotFound, "%s", id)
 	}
 
-	return fs.pinner.PinNode(newFile, false)
+	return fs.dicts.Dict(id)
+}
+
+// stageContent remembers the metadata of `content`.
+// fs.mu needs to be locked by the caller.
+func (fs *FS) stageContent(content *stagedContent) (*n.File, error) {
+	return c.Stage(
+		fs.lkr,
+		content.path,
+		content.contentHash,
+		content.backendHash,
+		content.size,
+		content.cachedSize,
+		content.key,
+		time.Now(),
+		content.isRaw,
+		content.compression,
+	)
 }
 
 ////////////////////
@@ -1209,6 +1552,40 @@ func (fs *FS) Tar(root string, w io.Writer, filter func(node *StatInfo) bool) er
 	return tw.Close()
 }
 
+// ListAt returns stat info for the direct children of the
+// directory `root` at the state of `rev`.
+func (fs *FS) ListAt(rev, root string) ([]*StatInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	nd, err := fs.lookupNodeAt(rev, root)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, ok := nd.(*n.Directory)
+	if !ok {
+		return nil, ie.ErrBadNode
+	}
+
+	children, err := dir.ChildrenSorted(fs.lkr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*StatInfo{}
+	for _, child := range children {
+		// Ghost nodes should not be visible to the outside.
+		if child.Type() == n.NodeTypeGhost {
+			continue
+		}
+
+		result = append(result, fs.nodeToStat(child))
+	}
+
+	return result, nil
+}
+
 // Cat will open a file read-only and expose it's underlying data as stream.
 // If no such path is known or it was deleted, nil is returned as stream.
 func (fs *FS) Cat(path string) (mio.Stream, error) {
@@ -1237,14 +1614,83 @@ func (fs *FS) Cat(path string) (mio.Stream, error) {
 	return fs.catHash(backendHash, key, size, isRaw)
 }
 
+// CatAt works like Cat, but returns the content `path` had at `rev`.
+func (fs *FS) CatAt(rev, path string) (mio.Stream, error) {
+	fs.mu.Lock()
+
+	nd, err := fs.lookupNodeAt(rev, path)
+	if err != nil {
+		fs.mu.Unlock()
+		return nil, err
+	}
+
+	file, ok := nd.(*n.File)
+	if !ok {
+		fs.mu.Unlock()
+		return nil, ie.ErrBadNode
+	}
+
+	size := file.Size()
+	backendHash := file.BackendHash().Clone()
+	key := make([]byte, len(file.Key()))
+	isRaw := file.IsRaw()
+	copy(key, file.Key())
+
+	fs.mu.Unlock()
+
+	return fs.catHash(backendHash, key, size, isRaw)
+}
+
+// readAheadOptions returns the read-ahead settings from the config.
+// The second return value is false if read-ahead is disabled.
+func (fs *FS) readAheadOptions() (mio.ReadAheadOptions, bool) {
+	opts := mio.DefaultReadAheadOptions()
+	if !fs.cfg.Bool("readahead.enabled") {
+		return opts, false
+	}
+
+	maxWindow, err := humanize.ParseBytes(fs.cfg.String("readahead.max_window"))
+	if err != nil {
+		log.Warningf("bad readahead.max_window; disabling read-ahead: %v", err)
+		return opts, false
+	}
+
+	opts.MaxWindow = int64(maxWindow)
+	opts.Workers = int(fs.cfg.Int("readahead.workers"))
+	return opts, opts.MaxWindow > 0 && opts.Workers > 0
+}
+
+// newOutStream returns the decoded content of `backendHash`.
 // NOTE: This method can be called without locking fs.mu!
-func (fs *FS) catHash(backendHash h.Hash, key []byte, size uint64, isRaw bool) (mio.Stream, error) {
+func (fs *FS) newOutStream(backendHash h.Hash, key []byte, isRaw bool) (mio.Stream, error) {
 	rawStream, err := fs.bk.Cat(backendHash)
 	if err != nil {
 		return nil, err
 	}
 
-	stream, err := mio.NewOutStream(rawStream, isRaw, key)
+	return mio.NewOutStreamWithDicts(rawStream, isRaw, key, fs.lookupDict)
+}
+
+// newReadAhead returns a read-ahead for the content of `backendHash`,
+// which passes the prefetched blocks to `sink`. If read-ahead is disabled
+// or the content is too small to benefit from it, nil is returned.
+// NOTE: This method can be called without locking fs.mu!
+func (fs *FS) newReadAhead(backendHash h.Hash, key []byte, size uint64, isRaw bool, sink mio.BlockSink) (*mio.ReadAhead, error) {
+	opts, ok := fs.readAheadOptions()
+	if !ok || int64(size) <= opts.BlockSize {
+		return nil, nil
+	}
+
+	open := func() (mio.Stream, error) {
+		return fs.newOutStream(backendHash, key, isRaw)
+	}
+
+	return mio.NewReadAhead(open, int64(size), sink, opts)
+}
+
+// NOTE: This method can be called without locking fs.mu!
+func (fs *FS) catHash(backendHash h.Hash, key []byte, size uint64, isRaw bool) (mio.Stream, error) {
+	stream, err := fs.newOutStream(backendHash, key, isRaw)
 	if err != nil {
 		return nil, err
 	}
@@ -1324,36 +1770,14 @@ func (fs *FS) DeletedNodes(root string) ([]*StatInfo, error) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
-	rootNd, err := fs.lkr.LookupNode(root)
+	ghosts, err := fs.deletedGhosts(root)
 	if err != nil {
 		return nil, err
 	}
 
 	nodes := []*StatInfo{}
-	err = n.Walk(fs.lkr, rootNd, false, func(child n.Node) error {
-		if child.Type() != n.NodeTypeGhost {
-			return nil
-		}
-
-		modNd, ok := child.(n.ModNode)
-		if !ok {
-			return ie.ErrBadNode
-		}
-
-		isMove, err := fs.isMove(modNd)
-		if err != nil {
-			return err
-		}
-
-		if !isMove {
-			nodes = append(nodes, fs.nodeToStat(modNd))
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
+	for _, ghost := range ghosts {
+		nodes = append(nodes, fs.nodeToStat(ghost))
 	}
 
 	return nodes, nil
@@ -1374,6 +1798,8 @@ func (fs *FS) Undelete(root string) error {
 		return err
 	}
 
+	fs.notifyChange(root)
+
 	nd, err := fs.lkr.LookupModNode(root)
 	if err != nil {
 		return err
@@ -1415,11 +1841,12 @@ func commitToExternal(cmt *n.Commit, hashToRef map[string][]string) *Commit {
 	}
 
 	return &Commit{
-		Hash:  cmt.TreeHash().Clone(),
-		Msg:   cmt.Message(),
-		Tags:  tags,
-		Date:  cmt.ModTime(),
-		Index: cmt.Index(),
+		Hash:   cmt.TreeHash().Clone(),
+		Msg:    cmt.Message(),
+		Author: cmt.Author(),
+		Tags:   tags,
+		Date:   cmt.ModTime(),
+		Index:  cmt.Index(),
 	}
 }
 
@@ -1506,8 +1933,14 @@ func (fs *FS) buildSyncCfg() (*vcs.SyncOptions, error) {
 		return nil, fmt.Errorf("unknown conflict strategy: %v", conflictStrategy)
 	}
 
+	lockPolicy := vcs.LockPolicyFromString(fs.cfg.String("sync.lock_policy"))
+	if lockPolicy == vcs.LockPolicyUnknown {
+		return nil, fmt.Errorf("unknown lock policy: %v", fs.cfg.String("sync.lock_policy"))
+	}
+
 	return &vcs.SyncOptions{
 		ConflictStrategy: conflictStrategy,
+		LockPolicy:       lockPolicy,
 		IgnoreDeletes:    fs.cfg.Bool("sync.ignore_removed"),
 		IgnoreMoves:      fs.cfg.Bool("sync.ignore_moved"),
 		OnAdd: func(newNd n.ModNode) bool {
@@ -1575,6 +2008,14 @@ func SyncOptMessage(msg string) SyncOption {
 	}
 }
 
+// SyncOptProgress sets a function that is called after
+// every path that the sync looked at.
+func SyncOptProgress(fn func(nodePath string, done, total int)) SyncOption {
+	return func(cfg *vcs.SyncOptions) {
+		cfg.OnProgress = fn
+	}
+}
+
 // SyncOptConflictStrategy overwrites the conflict strategy
 // (see also fs.sync.conflict_strategy which acts as default)
 func SyncOptConflictStrategy(strategy string) SyncOption {
@@ -1621,6 +2062,30 @@ func SyncOptConflictgStrategyPerFolder(strategies map[string]string) SyncOption
 	}
 }
 
+// SyncOptLockPolicy sets what to do with changes to paths that are locked
+// by somebody else (see also fs.sync.lock_policy which acts as default).
+// The keys of `perFolder` are folders, the values lock policy names.
+func SyncOptLockPolicy(policy string, perFolder map[string]string) SyncOption {
+	return func(cfg *vcs.SyncOptions) {
+		if lp := vcs.LockPolicyFromString(policy); lp != vcs.LockPolicyUnknown {
+			cfg.LockPolicy = lp
+		}
+
+		if cfg.LockPolicyPerFolder == nil {
+			cfg.LockPolicyPerFolder = make(map[string]vcs.LockPolicy)
+		}
+
+		for folder, folderPolicy := range perFolder {
+			lp := vcs.LockPolicyFromString(folderPolicy)
+			if lp == vcs.LockPolicyUnknown {
+				continue
+			}
+
+			cfg.LockPolicyPerFolder[folder] = lp
+		}
+	}
+}
+
 // Sync will synchronize the state of two filesystems.
 // If one of filesystems have unstaged changes, they will be committted first.
 // If our filesystem was changed by Sync(), a new merge commit will also be created.
@@ -1642,7 +2107,35 @@ func (fs *FS) Sync(remote *FS, options ...SyncOption) error {
 		option(syncCfg)
 	}
 
-	return vcs.Sync(remote.lkr, fs.lkr, syncCfg)
+	// Changes of the remote are fine for paths that the remote locked
+	// itself, but not for paths that somebody else (e.g. we) locked.
+	remoteOwner, err := remote.lkr.Owner()
+	if err != nil {
+		return err
+	}
+
+	locks, err := fs.locks()
+	if err != nil {
+		return err
+	}
+
+	if len(locks) > 0 {
+		syncCfg.LockedBy = func(nodePath string) string {
+			if lock := lockedBy(locks, nodePath, remoteOwner); lock != nil {
+				return lock.Owner
+			}
+
+			return ""
+		}
+	}
+
+	if err := vcs.Sync(remote.lkr, fs.lkr, syncCfg); err != nil {
+		return err
+	}
+
+	// A sync might touch any part of the tree:
+	fs.notifyChange("/")
+	return nil
 }
 
 // MakeDiff will return a diff between `headRevOwn` and `headRevRemote`.
@@ -1797,6 +2290,8 @@ func (fs *FS) Reset(path, rev string) error {
 		return err
 	}
 
+	fs.notifyChange(path)
+
 	// The old node does not necessarily exist:
 	if oldNode != nil {
 		if err := fs.pinner.UnpinNode(oldNode, false); err != nil {
@@ -1832,7 +2327,12 @@ func (fs *FS) checkout(rev string, force bool) error {
 		return err
 	}
 
-	return fs.lkr.CheckoutCommit(cmt, force)
+	if err := fs.lkr.CheckoutCommit(cmt, force); err != nil {
+		return err
+	}
+
+	fs.notifyChange("/")
+	return nil
 }
 
 // Tag saves a human readable name for the revision pointed to by `rev`.
@@ -2035,6 +2535,13 @@ func (fs *FS) applyPatches(patches vcs.Patches) error {
 			return err
 		}
 
+		for _, change := range patch.Changes {
+			fs.notifyChange(change.Curr.Path())
+			if change.WasPreviouslyAt != "" {
+				fs.notifyChange(change.WasPreviouslyAt)
+			}
+		}
+
 		if idx := patch.CurrIndex; highestIndex < idx {
 			highestIndex = idx
 		}
@@ -2077,6 +2584,30 @@ func (fs *FS) LastPatchIndex() (int64, error) {
 	return strconv.ParseInt(string(fromIndexData), 10, 64)
 }
 
+// FetchSource returns the id of the device our patches came from,
+// as set by SetFetchSource. It is empty if it was never set.
+func (fs *FS) FetchSource() (string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := fs.lkr.MetadataGet("fs.fetch-source")
+	if err == db.ErrNoSuchKey {
+		return "", nil
+	}
+
+	return string(data), err
+}
+
+// SetFetchSource remembers the device that our patches come from.
+// Patch indices are only meaningful for a single device, so a remote with
+// several devices needs to be fetched from scratch when the device changes.
+func (fs *FS) SetFetchSource(source string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.lkr.MetadataPut("fs.fetch-source", []byte(source))
+}
+
 // CommitInfo returns detailed info about a certain commit.
 func (fs *FS) CommitInfo(rev string) (*Commit, error) {
 	fs.mu.Lock()
@@ -63,6 +63,7 @@ func withDummyFSReadOnly(t *testing.T, readOnly bool, fn func(fs *FS)) {
 		readOnly,
 		fsCfg,
 		nil,
+		nil,
 		mdc,
 	)
 	if err != nil {
@@ -200,6 +201,7 @@ func TestCat(t *testing.T) {
 			TestKey,
 			time.Now(),
 			isRaw,
+			"",
 		)
 		require.Nil(t, err)
 
@@ -574,6 +576,39 @@ func TestPin(t *testing.T) {
 	})
 }
 
+func TestPinWithProgress(t *testing.T) {
+	t.Parallel()
+
+	withDummyFS(t, func(fs *FS) {
+		require.Nil(t, fs.Stage("/dir/x", bytes.NewReader([]byte{1, 2})))
+		require.Nil(t, fs.Stage("/dir/sub/y", bytes.NewReader([]byte{3, 4, 5})))
+		require.Nil(t, fs.Unpin("/dir", "curr", true))
+
+		reports := []PinProgress{}
+		err := fs.PinWithProgress("/dir", "curr", true, func(status PinProgress) {
+			reports = append(reports, status)
+		})
+		require.Nil(t, err)
+
+		// One report before the first file and one after each file:
+		require.Len(t, reports, 3)
+		require.Equal(t, PinProgress{TotalFiles: 2, TotalBytes: 5}, reports[0])
+
+		last := reports[len(reports)-1]
+		require.Equal(t, int64(2), last.DoneFiles)
+		require.Equal(t, uint64(5), last.DoneBytes)
+		require.Contains(t, []string{"/dir/x", "/dir/sub/y"}, last.Current)
+
+		isPinned, isExplicit, err := fs.IsPinned("/dir")
+		require.Nil(t, err)
+		require.True(t, isPinned)
+		require.True(t, isExplicit)
+
+		err = fs.PinWithProgress("/nope", "curr", true, func(PinProgress) {})
+		require.True(t, ie.IsNoSuchFileError(err))
+	})
+}
+
 func TestMkdir(t *testing.T) {
 	t.Parallel()
 
@@ -722,6 +757,53 @@ func TestList(t *testing.T) {
 	})
 }
 
+func TestStatListCatAt(t *testing.T) {
+	t.Parallel()
+
+	withDummyFS(t, func(fs *FS) {
+		require.Nil(t, fs.Stage("/dir/x", bytes.NewReader([]byte{1})))
+		require.Nil(t, fs.Stage("/dir/y", bytes.NewReader([]byte{2})))
+		require.Nil(t, fs.MakeCommit("first"))
+		require.Nil(t, fs.Tag("head", "first"))
+
+		require.Nil(t, fs.Stage("/dir/x", bytes.NewReader([]byte{3, 4})))
+		require.Nil(t, fs.Remove("/dir/y"))
+		require.Nil(t, fs.MakeCommit("second"))
+
+		info, err := fs.StatAt("first", "/dir/x")
+		require.Nil(t, err)
+		require.Equal(t, uint64(1), info.Size)
+
+		info, err = fs.StatAt("head", "/dir/x")
+		require.Nil(t, err)
+		require.Equal(t, uint64(2), info.Size)
+
+		entries, err := fs.ListAt("head^", "/dir")
+		require.Nil(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, "/dir/x", entries[0].Path)
+		require.Equal(t, "/dir/y", entries[1].Path)
+
+		// Removed nodes and unknown revs do not exist:
+		entries, err = fs.ListAt("head", "/dir")
+		require.Nil(t, err)
+		require.Len(t, entries, 1)
+
+		_, err = fs.StatAt("head", "/dir/y")
+		require.True(t, ie.IsNoSuchFileError(err))
+
+		_, err = fs.StatAt("nosuchtag", "/dir/x")
+		require.True(t, ie.IsNoSuchFileError(err))
+
+		stream, err := fs.CatAt("first", "/dir/y")
+		require.Nil(t, err)
+
+		data, err := ioutil.ReadAll(stream)
+		require.Nil(t, err)
+		require.Equal(t, []byte{2}, data)
+	})
+}
+
 func TestTag(t *testing.T) {
 	t.Parallel()
 
@@ -1002,3 +1084,115 @@ func TestUndeleteDirectory(t *testing.T) {
 		}, paths)
 	})
 }
+
+type testDictStore map[compress.DictID]*compress.Dict
+
+func (tds testDictStore) Dict(id compress.DictID) (*compress.Dict, error) {
+	dict, ok := tds[id]
+	if !ok {
+		return nil, compress.ErrDictNotFound
+	}
+
+	return dict, nil
+}
+
+func TestStageWithDict(t *testing.T) {
+	t.Parallel()
+
+	withDummyFS(t, func(fs *FS) {
+		samples := [][]byte{}
+		for idx := 0; idx < 100; idx++ {
+			samples = append(samples, []byte(fmt.Sprintf(
+				`{"id": %d, "kind": "log-entry", "message": "service started on port %d"}`,
+				idx, 8000+idx,
+			)))
+		}
+
+		dict, err := compress.TrainDict(samples, compress.DefaultDictSize)
+		require.NoError(t, err)
+
+		hintMgr, err := hints.NewManager(nil)
+		require.NoError(t, err)
+		require.NoError(t, hintMgr.Set("/logs", hints.Hint{
+			CompressionAlgo: hints.CompressionZstd,
+			EncryptionAlgo:  hints.EncryptionNone,
+			ZstdDict:        dict.ID.String(),
+		}))
+
+		dicts := testDictStore{}
+		fs.hintManager = hintMgr
+		fs.dicts = dicts
+
+		data := []byte(`{"id": 4711, "kind": "log-entry", "message": "service started on port 4711"}`)
+
+		// Staging needs the dictionary:
+		err = fs.Stage("/logs/x.json", bytes.NewReader(data))
+		require.True(t, compress.IsDictNotFoundError(err))
+
+		dicts[dict.ID] = dict
+		require.NoError(t, fs.Stage("/logs/x.json", bytes.NewReader(data)))
+
+		stream, err := fs.Cat("/logs/x.json")
+		require.NoError(t, err)
+
+		result, err := ioutil.ReadAll(stream)
+		require.NoError(t, err)
+		require.Equal(t, data, result)
+
+		// Reading without the dictionary fails:
+		delete(dicts, dict.ID)
+		stream, err = fs.Cat("/logs/x.json")
+		if err == nil {
+			_, err = ioutil.ReadAll(stream)
+		}
+
+		require.True(t, compress.IsDictNotFoundError(err))
+	})
+}
+
+func TestStageWithNewKey(t *testing.T) {
+	t.Parallel()
+
+	withDummyFS(t, func(fs *FS) {
+		data := testutil.CreateDummyBuf(4096)
+		require.NoError(t, fs.Stage("/x", bytes.NewReader(data)))
+
+		oldInfo, err := fs.Stat("/x")
+		require.NoError(t, err)
+
+		// Modifying the file keeps the key:
+		require.NoError(t, fs.Stage("/x", bytes.NewReader(data[:2048])))
+		info, err := fs.Stat("/x")
+		require.NoError(t, err)
+		require.Equal(t, oldInfo.Key, info.Key)
+
+		require.NoError(t, fs.StageWithNewKey("/x", bytes.NewReader(data)))
+		newInfo, err := fs.Stat("/x")
+		require.NoError(t, err)
+		require.NotEqual(t, oldInfo.Key, newInfo.Key)
+		require.Equal(t, oldInfo.ContentHash, newInfo.ContentHash)
+		require.False(t, oldInfo.BackendHash.Equal(newInfo.BackendHash))
+
+		stream, err := fs.Cat("/x")
+		require.NoError(t, err)
+
+		result, err := ioutil.ReadAll(stream)
+		require.NoError(t, err)
+		require.Equal(t, data, result)
+	})
+}
+
+func TestFetchSource(t *testing.T) {
+	t.Parallel()
+
+	withDummyFS(t, func(fs *FS) {
+		source, err := fs.FetchSource()
+		require.NoError(t, err)
+		require.Equal(t, "", source)
+
+		require.NoError(t, fs.SetFetchSource("W1laptop"))
+		source, err = fs.FetchSource()
+		require.NoError(t, err)
+		require.Equal(t, "W1laptop", source)
+	})
+}
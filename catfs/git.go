@@ -0,0 +1,833 @@
+package catfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	e "github.com/pkg/errors"
+	c "github.com/sahib/brig/catfs/core"
+	ie "github.com/sahib/brig/catfs/errors"
+	n "github.com/sahib/brig/catfs/nodes"
+	h "github.com/sahib/brig/util/hashlib"
+	log "github.com/sirupsen/logrus"
+)
+
+// This file implements a bridge between brig's history and git.
+// The format used for both directions is the one of `git fast-export`
+// and `git fast-import`, documented in git-fast-import(1).
+// Only a subset of the format is understood: a linear history of
+// commits with regular files. Git has no concept of empty directories,
+// brig has no concept of file modes, symbolic links or submodules.
+
+// GitImportStats is returned by ImportGit and describes what was imported.
+type GitImportStats struct {
+	// Commits is the number of brig commits that were created.
+	Commits int
+	// Skipped is the number of git commits that did not change anything.
+	Skipped int
+}
+
+// gitReader is a line based reader for fast-export streams that allows
+// to push back a single line when a command ends without terminator.
+type gitReader struct {
+	rd      *bufio.Reader
+	pending *string
+}
+
+func (gr *gitReader) readLine() (string, error) {
+	if gr.pending != nil {
+		line := *gr.pending
+		gr.pending = nil
+		return line, nil
+	}
+
+	line, err := gr.rd.ReadString('\n')
+	if err == io.EOF && line != "" {
+		return line, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+func (gr *gitReader) unreadLine(line string) {
+	gr.pending = &line
+}
+
+// readData reads a `data <count>` command and the data following it.
+func (gr *gitReader) readData(line string) ([]byte, error) {
+	if !strings.HasPrefix(line, "data ") {
+		return nil, fmt.Errorf("expected data command, got: %s", line)
+	}
+
+	// Sizes have to fit into an int64; negative ones are rejected.
+	size, err := strconv.ParseUint(line[len("data "):], 10, 63)
+	if err != nil {
+		return nil, e.Wrapf(err, "unsupported data command: %s", line)
+	}
+
+	// Do not trust the size for allocating the buffer;
+	// a broken stream would make us run out of memory.
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, gr.rd, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	data := buf.Bytes()
+
+	// The data might be followed by an optional newline:
+	next, err := gr.rd.Peek(1)
+	if err == nil && next[0] == '\n' {
+		if _, err := gr.rd.Discard(1); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// parseGitPath parses a (possibly quoted) path at the start of `s`
+// and returns it together with the rest of the line.
+// If `last` is true, the path is expected to span the rest of the line.
+func parseGitPath(s string, last bool) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		if last {
+			return s, "", nil
+		}
+
+		idx := strings.IndexByte(s, ' ')
+		if idx < 0 {
+			return s, "", nil
+		}
+
+		return s[:idx], s[idx+1:], nil
+	}
+
+	// Find the closing quote that is not escaped:
+	for idx := 1; idx < len(s); idx++ {
+		switch s[idx] {
+		case '\\':
+			idx++
+		case '"':
+			unquoted, err := strconv.Unquote(s[:idx+1])
+			if err != nil {
+				return "", "", e.Wrapf(err, "bad quoted path: %s", s)
+			}
+
+			return unquoted, strings.TrimPrefix(s[idx+1:], " "), nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated path: %s", s)
+}
+
+// quoteGitPath quotes `p` in the C-style way git expects,
+// but only if it contains characters that would confuse the parser.
+func quoteGitPath(p string) string {
+	needsQuote := strings.HasPrefix(p, `"`)
+	for idx := 0; idx < len(p) && !needsQuote; idx++ {
+		if p[idx] < 0x20 || p[idx] == '\\' || p[idx] == 0x7f {
+			needsQuote = true
+		}
+	}
+
+	if !needsQuote {
+		return p
+	}
+
+	buf := &strings.Builder{}
+	buf.WriteByte('"')
+	for idx := 0; idx < len(p); idx++ {
+		switch ch := p[idx]; {
+		case ch == '"' || ch == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(ch)
+		case ch == '\n':
+			buf.WriteString(`\n`)
+		case ch == '\t':
+			buf.WriteString(`\t`)
+		case ch < 0x20 || ch == 0x7f:
+			fmt.Fprintf(buf, `\%03o`, ch)
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// parseGitIdent splits a line like "Bob <bob@x.org> 1234567890 +0100"
+// into the identity and the time it describes.
+func parseGitIdent(ident string) (string, time.Time, error) {
+	fields := strings.Split(ident, " ")
+	if len(fields) < 3 {
+		return "", time.Time{}, fmt.Errorf("bad ident: %s", ident)
+	}
+
+	tz := fields[len(fields)-1]
+	stamp, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return "", time.Time{}, e.Wrapf(err, "bad timestamp in ident: %s", ident)
+	}
+
+	offset := 0
+	if len(tz) == 5 {
+		hours, herr := strconv.Atoi(tz[1:3])
+		minutes, merr := strconv.Atoi(tz[3:5])
+		if herr != nil || merr != nil {
+			return "", time.Time{}, fmt.Errorf("bad timezone in ident: %s", ident)
+		}
+
+		offset = hours*3600 + minutes*60
+		if tz[0] == '-' {
+			offset = -offset
+		}
+	}
+
+	who := strings.Join(fields[:len(fields)-2], " ")
+	return who, time.Unix(stamp, 0).In(time.FixedZone("", offset)), nil
+}
+
+// formatGitIdent is the reverse of parseGitIdent.
+// brig authors usually have no mail address, git requires one though.
+func formatGitIdent(author string, date time.Time) string {
+	if !strings.HasSuffix(author, ">") {
+		author += " <>"
+	}
+
+	return fmt.Sprintf("%s %d %s", author, date.Unix(), date.Format("-0700"))
+}
+
+type gitImporter struct {
+	fs      *FS
+	gr      *gitReader
+	prefix  string
+	blobDir string
+	stats   GitImportStats
+}
+
+func (gi *gitImporter) repoPath(gitPath string) string {
+	return prefixSlash(path.Join(gi.prefix, gitPath))
+}
+
+// blobPath returns where the blob with `mark` is stored while importing.
+// Marks come from the stream and are not trusted; only »:<number>« is
+// accepted, so they can not point outside of the blob directory.
+func (gi *gitImporter) blobPath(mark string) (string, error) {
+	if !strings.HasPrefix(mark, ":") {
+		return "", fmt.Errorf("unsupported data reference: %s", mark)
+	}
+
+	num, err := strconv.ParseUint(mark[1:], 10, 64)
+	if err != nil || num == 0 {
+		return "", fmt.Errorf("bad mark: %s", mark)
+	}
+
+	return filepath.Join(gi.blobDir, strconv.FormatUint(num, 10)), nil
+}
+
+func (gi *gitImporter) stageBlob(repoPath, mark string) error {
+	blobPath, err := gi.blobPath(mark)
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.Open(blobPath)
+	if err != nil {
+		return e.Wrapf(err, "unknown blob %s", mark)
+	}
+
+	defer fd.Close()
+	return gi.fs.Stage(repoPath, fd)
+}
+
+// remove deletes `repoPath` and all parent directories
+// that became empty by this, since git does not track them.
+func (gi *gitImporter) remove(repoPath string) error {
+	if err := gi.fs.Remove(repoPath); err != nil && !ie.IsNoSuchFileError(err) {
+		return err
+	}
+
+	root := prefixSlash(gi.prefix)
+	for dir := path.Dir(repoPath); dir != root && dir != "/"; dir = path.Dir(dir) {
+		info, err := gi.fs.Stat(dir)
+		if err != nil || !info.IsDir {
+			break
+		}
+
+		children, err := gi.fs.List(dir, 1)
+		if err != nil {
+			return err
+		}
+
+		if len(children) > 0 {
+			break
+		}
+
+		if err := gi.fs.Remove(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gi *gitImporter) removeAll() error {
+	root := prefixSlash(gi.prefix)
+	if _, err := gi.fs.Stat(root); ie.IsNoSuchFileError(err) {
+		return nil
+	}
+
+	children, err := gi.fs.List(root, 1)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if child.Path == root {
+			continue
+		}
+
+		if err := gi.fs.Remove(child.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gi *gitImporter) readBlob() error {
+	var mark string
+	for {
+		line, err := gi.gr.readLine()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case strings.HasPrefix(line, "mark "):
+			mark = line[len("mark "):]
+		case strings.HasPrefix(line, "original-oid "):
+			// Not needed.
+		case strings.HasPrefix(line, "data "):
+			data, err := gi.gr.readData(line)
+			if err != nil {
+				return err
+			}
+
+			if mark == "" {
+				// Unreferenced blob; nobody can use it.
+				return nil
+			}
+
+			blobPath, err := gi.blobPath(mark)
+			if err != nil {
+				return err
+			}
+
+			return ioutil.WriteFile(blobPath, data, 0600)
+		default:
+			return fmt.Errorf("unexpected line in blob: %s", line)
+		}
+	}
+}
+
+func (gi *gitImporter) applyFileCommand(line string) error {
+	switch {
+	case line == "deleteall":
+		return gi.removeAll()
+	case strings.HasPrefix(line, "M "):
+		fields := strings.SplitN(line[len("M "):], " ", 3)
+		if len(fields) != 3 {
+			return fmt.Errorf("bad filemodify command: %s", line)
+		}
+
+		mode, dataRef := fields[0], fields[1]
+		gitPath, _, err := parseGitPath(fields[2], true)
+		if err != nil {
+			return err
+		}
+
+		repoPath := gi.repoPath(gitPath)
+		if dataRef == "inline" {
+			dataLine, err := gi.gr.readLine()
+			if err != nil {
+				return err
+			}
+
+			data, err := gi.gr.readData(dataLine)
+			if err != nil {
+				return err
+			}
+
+			if mode != "100644" && mode != "100755" && mode != "644" && mode != "755" {
+				return nil
+			}
+
+			return gi.fs.Stage(repoPath, strings.NewReader(string(data)))
+		}
+
+		switch mode {
+		case "100644", "100755", "644", "755":
+			return gi.stageBlob(repoPath, dataRef)
+		default:
+			log.Warnf("git import: skipping %s with unsupported mode %s", gitPath, mode)
+			return nil
+		}
+	case strings.HasPrefix(line, "D "):
+		gitPath, _, err := parseGitPath(line[len("D "):], true)
+		if err != nil {
+			return err
+		}
+
+		return gi.remove(gi.repoPath(gitPath))
+	case strings.HasPrefix(line, "R "), strings.HasPrefix(line, "C "):
+		src, rest, err := parseGitPath(line[len("R "):], false)
+		if err != nil {
+			return err
+		}
+
+		dst, _, err := parseGitPath(rest, true)
+		if err != nil {
+			return err
+		}
+
+		if line[0] == 'C' {
+			return gi.fs.Copy(gi.repoPath(src), gi.repoPath(dst))
+		}
+
+		if err := gi.fs.Move(gi.repoPath(src), gi.repoPath(dst)); err != nil {
+			return err
+		}
+
+		return gi.remove(gi.repoPath(src))
+	case strings.HasPrefix(line, "N "):
+		// Notes are not supported and silently ignored.
+		return nil
+	default:
+		return fmt.Errorf("unsupported file command: %s", line)
+	}
+}
+
+func (gi *gitImporter) readCommit(ref string) error {
+	var (
+		author, committer     string
+		authorAt, committerAt time.Time
+		msg                   []byte
+	)
+
+	for {
+		line, err := gi.gr.readLine()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if line == "" {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, "mark "),
+			strings.HasPrefix(line, "original-oid "),
+			strings.HasPrefix(line, "encoding "),
+			strings.HasPrefix(line, "from "),
+			strings.HasPrefix(line, "merge "):
+			// Merges are flattened, the stream order is our history.
+		case strings.HasPrefix(line, "author "):
+			author, authorAt, err = parseGitIdent(line[len("author "):])
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "committer "):
+			committer, committerAt, err = parseGitIdent(line[len("committer "):])
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data "):
+			msg, err = gi.gr.readData(line)
+			if err != nil {
+				return err
+			}
+		case line == "deleteall" || (len(line) > 2 && line[1] == ' ' && strings.ContainsRune("MDRCN", rune(line[0]))):
+			if err := gi.applyFileCommand(line); err != nil {
+				return err
+			}
+		default:
+			// Some other command; the commit ended without newline.
+			gi.gr.unreadLine(line)
+			return gi.finishCommit(ref, author, authorAt, committer, committerAt, msg)
+		}
+	}
+
+	return gi.finishCommit(ref, author, authorAt, committer, committerAt, msg)
+}
+
+func (gi *gitImporter) finishCommit(ref, author string, authorAt time.Time, committer string, committerAt time.Time, msg []byte) error {
+	if author == "" {
+		author, authorAt = committer, committerAt
+	}
+
+	message := strings.TrimRight(string(msg), "\n")
+	switch err := gi.fs.makeCommitAt(author, message, authorAt); err {
+	case ie.ErrNoChange:
+		log.Debugf("git import: skipping empty commit on %s: %s", ref, message)
+		gi.stats.Skipped++
+		return nil
+	case nil:
+		gi.stats.Commits++
+		return nil
+	default:
+		return err
+	}
+}
+
+func (gi *gitImporter) skipUntilData() error {
+	for {
+		line, err := gi.gr.readLine()
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(line, "data ") {
+			_, err := gi.gr.readData(line)
+			return err
+		}
+	}
+}
+
+func (gi *gitImporter) run() error {
+	for {
+		line, err := gi.gr.readLine()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "", line == "done":
+			continue
+		case line == "blob":
+			err = gi.readBlob()
+		case strings.HasPrefix(line, "commit "):
+			err = gi.readCommit(line[len("commit "):])
+		case strings.HasPrefix(line, "tag "):
+			// Tags of git are not brig tags; names may not be valid refs.
+			err = gi.skipUntilData()
+		case strings.HasPrefix(line, "reset "),
+			strings.HasPrefix(line, "from "),
+			strings.HasPrefix(line, "feature "),
+			strings.HasPrefix(line, "option "),
+			strings.HasPrefix(line, "progress "):
+			continue
+		default:
+			return fmt.Errorf("unsupported command in git stream: %s", line)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ImportGit reads a stream as produced by `git fast-export` from `r`
+// and replays every commit in it as brig commit, keeping the author,
+// the message and the date of each commit. All paths are put below
+// `prefix`. Merges are flattened into a linear history in stream order,
+// so `git fast-export --first-parent` is recommended.
+// The stage has to be clean before importing.
+func (fs *FS) ImportGit(r io.Reader, prefix string) (*GitImportStats, error) {
+	if fs.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	haveChanges, err := fs.haveUncommittedChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	if haveChanges {
+		return nil, fmt.Errorf("there are uncommitted changes; commit them first")
+	}
+
+	blobDir, err := ioutil.TempDir("", "brig-git-import")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(blobDir)
+
+	gi := &gitImporter{
+		fs:      fs,
+		gr:      &gitReader{rd: bufio.NewReader(r)},
+		prefix:  prefixSlash(prefix),
+		blobDir: blobDir,
+	}
+
+	if err := gi.run(); err != nil {
+		return &gi.stats, err
+	}
+
+	return &gi.stats, nil
+}
+
+// haveUncommittedChanges is like HaveStagedChanges,
+// but an empty filesystem without any commit counts as unchanged.
+func (fs *FS) haveUncommittedChanges() (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, err := fs.lkr.Head(); ie.IsErrNoSuchRef(err) {
+		root, err := fs.lkr.Root()
+		if err != nil {
+			return false, err
+		}
+
+		return root.NChildren() > 0, nil
+	}
+
+	return fs.lkr.HaveStagedChanges()
+}
+
+func (fs *FS) makeCommitAt(author, msg string, date time.Time) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.lkr.MakeCommitAt(author, msg, date)
+}
+
+type gitExportFile struct {
+	path        string
+	contentHash string
+	backendHash h.Hash
+	key         []byte
+	size        uint64
+	isRaw       bool
+}
+
+type gitExportCommit struct {
+	cmt   *Commit
+	files []gitExportFile
+}
+
+// gitExportHistory collects everything that is needed to write the history,
+// so that fs.mu does not need to be held while reading the file contents.
+func (fs *FS) gitExportHistory() ([]gitExportCommit, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	head, err := fs.lkr.Head()
+	if err != nil {
+		if ie.IsErrNoSuchRef(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	hashToRef, err := fs.buildCommitHashToRefTable()
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []gitExportCommit{}
+	err = c.Log(fs.lkr, head, func(cmt *n.Commit) error {
+		root, err := fs.lkr.DirectoryByHash(cmt.Root())
+		if err != nil {
+			return err
+		}
+
+		files := []gitExportFile{}
+		err = n.Walk(fs.lkr, root, true, func(child n.Node) error {
+			file, ok := child.(*n.File)
+			if !ok {
+				return nil
+			}
+
+			files = append(files, gitExportFile{
+				path:        child.Path(),
+				contentHash: file.ContentHash().B58String(),
+				backendHash: file.BackendHash().Clone(),
+				key:         append([]byte{}, file.Key()...),
+				size:        file.Size(),
+				isRaw:       file.IsRaw(),
+			})
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		commits = append(commits, gitExportCommit{
+			cmt:   commitToExternal(cmt, hashToRef),
+			files: files,
+		})
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Log() goes from newest to oldest, git wants it the other way round.
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+
+	return commits, nil
+}
+
+func (fs *FS) writeGitBlob(w io.Writer, mark int, file gitExportFile) error {
+	stream, err := fs.catHash(file.backendHash, file.key, file.size, file.isRaw)
+	if err != nil {
+		return e.Wrapf(err, "failed to read %s", file.path)
+	}
+
+	defer stream.Close()
+
+	if _, err := fmt.Fprintf(w, "blob\nmark :%d\ndata %d\n", mark, file.size); err != nil {
+		return err
+	}
+
+	copied, err := io.Copy(w, stream)
+	if err != nil {
+		return err
+	}
+
+	if uint64(copied) != file.size {
+		return fmt.Errorf("short read on %s: %d of %d bytes", file.path, copied, file.size)
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// checkGitRefName checks if `name` can be used as branch or tag name. The
+// rules are the ones of `git check-ref-format --branch`. The name is written
+// into the fast-import stream as is, so this also prevents injecting commands.
+func checkGitRefName(name string) error {
+	switch {
+	case name == "" || name == "@":
+		return fmt.Errorf("invalid ref name: %q", name)
+	case strings.HasPrefix(name, "-"):
+		return fmt.Errorf("ref name may not start with a dash: %q", name)
+	case strings.Contains(name, "..") || strings.Contains(name, "@{") || strings.Contains(name, "//"):
+		return fmt.Errorf("ref name may not contain »..«, »@{« or »//«: %q", name)
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.HasSuffix(name, "."):
+		return fmt.Errorf("ref name may not start or end with a slash or end with a dot: %q", name)
+	}
+
+	for _, char := range name {
+		if char <= ' ' || char == 0x7f || strings.ContainsRune("~^:?*[\\", char) {
+			return fmt.Errorf("ref name contains invalid character %q: %q", char, name)
+		}
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("ref name component may not start with a dot or end with ».lock«: %q", name)
+		}
+	}
+
+	return nil
+}
+
+// ExportGit writes the history of HEAD as a stream that can be read by
+// `git fast-import` to `w`. Every brig commit is exported as git commit on
+// `branch`. Tags are exported as lightweight git tags. Since git does not
+// know about empty directories, they are not exported.
+func (fs *FS) ExportGit(w io.Writer, branch string) error {
+	if err := checkGitRefName(branch); err != nil {
+		return e.Wrapf(err, "bad branch")
+	}
+
+	commits, err := fs.gitExportHistory()
+	if err != nil {
+		return err
+	}
+
+	// Marks are shared between blobs and commits.
+	nextMark, lastCommitMark := 1, 0
+	blobMarks := make(map[string]int)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "feature done\n")
+	for _, export := range commits {
+		for _, file := range export.files {
+			if _, ok := blobMarks[file.contentHash]; ok {
+				continue
+			}
+
+			if err := fs.writeGitBlob(bw, nextMark, file); err != nil {
+				return err
+			}
+
+			blobMarks[file.contentHash] = nextMark
+			nextMark++
+		}
+
+		cmt := export.cmt
+		ident := formatGitIdent(cmt.Author, cmt.Date)
+		fmt.Fprintf(bw, "commit refs/heads/%s\nmark :%d\n", branch, nextMark)
+		fmt.Fprintf(bw, "author %s\ncommitter %s\n", ident, ident)
+		fmt.Fprintf(bw, "data %d\n%s\n", len(cmt.Msg), cmt.Msg)
+
+		if lastCommitMark > 0 {
+			fmt.Fprintf(bw, "from :%d\n", lastCommitMark)
+		}
+
+		// Just list the full tree; git figures out the changes itself.
+		fmt.Fprintf(bw, "deleteall\n")
+		for _, file := range export.files {
+			gitPath := quoteGitPath(strings.TrimPrefix(file.path, "/"))
+			fmt.Fprintf(bw, "M 100644 :%d %s\n", blobMarks[file.contentHash], gitPath)
+		}
+
+		fmt.Fprintf(bw, "\n")
+		lastCommitMark = nextMark
+
+		for _, tag := range cmt.Tags {
+			switch tag {
+			case "head", "curr", "init":
+				// Those are managed by brig itself.
+				continue
+			}
+
+			if err := checkGitRefName(tag); err != nil {
+				log.Warnf("git export: skipping tag: %v", err)
+				continue
+			}
+
+			fmt.Fprintf(bw, "reset refs/tags/%s\nfrom :%d\n\n", tag, nextMark)
+		}
+
+		nextMark++
+	}
+
+	fmt.Fprintf(bw, "done\n")
+	return bw.Flush()
+}
@@ -0,0 +1,204 @@
+package catfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testGitStream = `blob
+mark :1
+data 5
+hello
+blob
+mark :2
+data 5
+world
+reset refs/heads/master
+commit refs/heads/master
+mark :3
+author Bob <bob@example.org> 1500000000 +0200
+committer Bob <bob@example.org> 1500000100 +0200
+data 14
+first commit
+
+M 100644 :1 a.txt
+M 100644 :2 "sub dir/\303\244.txt"
+
+commit refs/heads/master
+mark :4
+author Eve <eve@example.org> 1600000000 -0100
+committer Eve <eve@example.org> 1600000000 -0100
+data 6
+second
+from :3
+D "sub dir/\303\244.txt"
+M 120000 :1 link
+M 100644 inline b.txt
+data 3
+abc
+
+commit refs/heads/master
+mark :5
+author Eve <eve@example.org> 1600000001 -0100
+committer Eve <eve@example.org> 1600000001 -0100
+data 5
+empty
+from :4
+
+done
+`
+
+func readAll(t *testing.T, fs *FS, path string) string {
+	stream, err := fs.Cat(path)
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+	return string(data)
+}
+
+func TestGitImportExport(t *testing.T) {
+	t.Parallel()
+
+	exported := &bytes.Buffer{}
+	withDummyFS(t, func(fs *FS) {
+		stats, err := fs.ImportGit(strings.NewReader(testGitStream), "/assets")
+		require.NoError(t, err)
+		require.Equal(t, 2, stats.Commits)
+		require.Equal(t, 1, stats.Skipped)
+
+		require.Equal(t, "hello", readAll(t, fs, "/assets/a.txt"))
+		require.Equal(t, "abc", readAll(t, fs, "/assets/b.txt"))
+
+		// Symbolic links are not supported:
+		_, err = fs.Stat("/assets/link")
+		require.Error(t, err)
+
+		// Directories that became empty should vanish like in git:
+		_, err = fs.Stat("/assets/sub dir")
+		require.Error(t, err)
+
+		cmts := []*Commit{}
+		require.NoError(t, fs.Log("head", func(cmt *Commit) error {
+			cmts = append(cmts, cmt)
+			return nil
+		}))
+
+		require.True(t, len(cmts) >= 2)
+		require.Equal(t, "second", cmts[0].Msg)
+		require.Equal(t, "Eve <eve@example.org>", cmts[0].Author)
+		require.True(t, cmts[0].Date.Equal(time.Unix(1600000000, 0)))
+		require.Equal(t, "first commit", cmts[1].Msg)
+		require.Equal(t, "Bob <bob@example.org>", cmts[1].Author)
+		require.True(t, cmts[1].Date.Equal(time.Unix(1500000000, 0)))
+
+		require.NoError(t, fs.Tag("head", "release"))
+		require.NoError(t, fs.ExportGit(exported, "main"))
+	})
+
+	stream := exported.String()
+	require.Contains(t, stream, "commit refs/heads/main\n")
+	require.Contains(t, stream, "author Eve <eve@example.org> 1600000000 -0100\n")
+	require.Contains(t, stream, "M 100644 :1 assets/a.txt\n")
+	require.Contains(t, stream, "reset refs/tags/release\n")
+
+	// Importing the export again should yield the same history:
+	withDummyFS(t, func(fs *FS) {
+		_, err := fs.ImportGit(exported, "/")
+		require.NoError(t, err)
+
+		require.Equal(t, "hello", readAll(t, fs, "/assets/a.txt"))
+		require.Equal(t, "abc", readAll(t, fs, "/assets/b.txt"))
+
+		cmt, err := fs.CommitInfo("head")
+		require.NoError(t, err)
+		require.Equal(t, "second", cmt.Msg)
+		require.Equal(t, "Eve <eve@example.org>", cmt.Author)
+
+		cmt, err = fs.CommitInfo("head^")
+		require.NoError(t, err)
+		require.Equal(t, "first commit", cmt.Msg)
+	})
+}
+
+func TestGitPathQuoting(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []string{"a.txt", "with space", "x\ny", `back\slash`, `"quoted"`} {
+		quoted := quoteGitPath(p)
+		parsed, rest, err := parseGitPath(quoted, true)
+		require.NoError(t, err)
+		require.Equal(t, p, parsed)
+		require.Empty(t, rest)
+	}
+}
+
+func TestGitImportBadMarks(t *testing.T) {
+	t.Parallel()
+
+	streams := []string{
+		"blob\nmark :../../escape\ndata 3\nabc\n",
+		"blob\nmark :/tmp/escape\ndata 3\nabc\n",
+		"commit refs/heads/master\nmark :1\n" +
+			"author Bob <bob@example.org> 1500000000 +0200\n" +
+			"committer Bob <bob@example.org> 1500000000 +0200\n" +
+			"data 2\nhi\nM 100644 ../escape a.txt\n\n",
+	}
+
+	for _, stream := range streams {
+		withDummyFS(t, func(fs *FS) {
+			_, err := fs.ImportGit(strings.NewReader(stream), "/")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGitImportBadData(t *testing.T) {
+	t.Parallel()
+
+	streams := []string{
+		"blob\nmark :1\ndata -1\n",
+		"blob\nmark :1\ndata 99999999999999\nabc\n",
+		"blob\nmark :1\ndata 18446744073709551615\n",
+	}
+
+	for _, stream := range streams {
+		withDummyFS(t, func(fs *FS) {
+			_, err := fs.ImportGit(strings.NewReader(stream), "/")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGitRefNames(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"master", "feature/x", "v1.0", "brig-export"} {
+		require.NoError(t, checkGitRefName(name), name)
+	}
+
+	bad := []string{
+		"", "@", "-master", "a..b", "a b", "a\tb", "a@{1}", "/a", "a/", "a//b",
+		"a.", ".a", "a/.b", "a.lock", "a~1", "a^", "a:b", "a?", "a*", "a[b", "a\\b",
+		"master\nreset refs/heads/evil",
+	}
+
+	for _, name := range bad {
+		require.Error(t, checkGitRefName(name), name)
+	}
+
+	withDummyFS(t, func(fs *FS) {
+		require.NoError(t, fs.Stage("/x", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.MakeCommit("add x"))
+
+		buf := &bytes.Buffer{}
+		require.Error(t, fs.ExportGit(buf, "master\nreset refs/heads/evil"))
+		require.NoError(t, fs.ExportGit(buf, "master"))
+	})
+}
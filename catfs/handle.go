@@ -9,6 +9,7 @@ import (
 	"github.com/sahib/brig/catfs/mio"
 	"github.com/sahib/brig/catfs/mio/pagecache"
 	n "github.com/sahib/brig/catfs/nodes"
+	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -26,6 +27,7 @@ type Handle struct {
 	lock        sync.Mutex
 	layer       *pagecache.Layer
 	stream      mio.Stream
+	readAhead   *mio.ReadAhead
 	wasModified bool
 	isClosed    bool
 	readOnly    bool
@@ -49,16 +51,11 @@ func (hdl *Handle) initStreamIfNeeded() error {
 	}
 
 	// Initialize the stream lazily to avoid I/O on open()
-	rawStream, err := hdl.fs.bk.Cat(hdl.file.BackendHash())
-	if err != nil {
-		return err
-	}
-
-	// Stack the mio stack on top:
-	hdl.stream, err = mio.NewOutStream(
-		rawStream,
-		hdl.file.IsRaw(),
+	var err error
+	hdl.stream, err = hdl.fs.newOutStream(
+		hdl.file.BackendHash(),
 		hdl.file.Key(),
+		hdl.file.IsRaw(),
 	)
 	if err != nil {
 		return err
@@ -71,9 +68,61 @@ func (hdl *Handle) initStreamIfNeeded() error {
 		int64(hdl.file.Size()),
 	)
 
+	if err != nil {
+		return err
+	}
+
+	// Prefetched blocks go to the page cache, where reads are served from.
+	hdl.readAhead, err = hdl.fs.newReadAhead(
+		hdl.file.BackendHash(),
+		hdl.file.Key(),
+		hdl.file.Size(),
+		hdl.file.IsRaw(),
+		readAheadSink{layer: hdl.layer},
+	)
+
 	return err
 }
 
+// readAheadSink puts blocks prefetched by the read-ahead into the page cache.
+type readAheadSink struct {
+	layer *pagecache.Layer
+}
+
+func (ras readAheadSink) Fill(off int64, data []byte) error {
+	return ras.layer.Underlay(off, data)
+}
+
+func (ras readAheadSink) Forget(off, size int64) error {
+	return ras.layer.EvictRange(off, size)
+}
+
+// prefetch lets the read-ahead know that `length` bytes at `off` are read next.
+func (hdl *Handle) prefetch(off int64, length int) {
+	if hdl.readAhead == nil {
+		return
+	}
+
+	if err := hdl.readAhead.Access(off, int64(length)); err != nil {
+		log.Warningf("read-ahead failed for %s: %v", hdl.file.Path(), err)
+	}
+}
+
+// stopReadAhead has to be called before the content is modified:
+// Prefetched pages are dropped when the read-ahead stops, which would
+// drop the modifications too.
+func (hdl *Handle) stopReadAhead() {
+	if hdl.readAhead == nil {
+		return
+	}
+
+	if err := hdl.readAhead.Close(); err != nil {
+		log.Warningf("failed to stop read-ahead for %s: %v", hdl.file.Path(), err)
+	}
+
+	hdl.readAhead = nil
+}
+
 // Read will try to fill `buf` as much as possible.
 // The seek pointer will be advanced by the number of bytes written.
 // Take care, `buf` might still have contents, even if io.EOF was returned.
@@ -89,6 +138,12 @@ func (hdl *Handle) Read(buf []byte) (int, error) {
 		return 0, err
 	}
 
+	off, err := hdl.layer.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	hdl.prefetch(off, len(buf))
 	return hdl.layer.Read(buf)
 }
 
@@ -105,6 +160,7 @@ func (hdl *Handle) ReadAt(buf []byte, off int64) (int, error) {
 		return 0, err
 	}
 
+	hdl.prefetch(off, len(buf))
 	return hdl.layer.ReadAt(buf, off)
 }
 
@@ -126,6 +182,7 @@ func (hdl *Handle) Write(buf []byte) (int, error) {
 		return 0, err
 	}
 
+	hdl.stopReadAhead()
 	hdl.wasModified = true
 	return hdl.layer.Write(buf)
 }
@@ -148,6 +205,7 @@ func (hdl *Handle) WriteAt(buf []byte, off int64) (n int, err error) {
 		return 0, err
 	}
 
+	hdl.stopReadAhead()
 	hdl.wasModified = true
 	return hdl.layer.WriteAt(buf, off)
 }
@@ -191,6 +249,8 @@ func (hdl *Handle) Truncate(size uint64) error {
 		return err
 	}
 
+	hdl.stopReadAhead()
+
 	hdl.fs.mu.Lock()
 	hdl.file.SetSize(size)
 	hdl.fs.mu.Unlock()
@@ -266,6 +326,7 @@ func (hdl *Handle) Close() error {
 	}
 
 	hdl.isClosed = true
+	hdl.stopReadAhead()
 	return hdl.flush()
 }
 
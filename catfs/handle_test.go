@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/sahib/brig/catfs/mio/compress"
+	"github.com/sahib/brig/catfs/mio/pagecache/page"
 	"github.com/sahib/brig/util/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -167,6 +168,8 @@ func TestHandleFuseLikeRead(t *testing.T) {
 		blockSize int
 	}{
 		{2048, 400},
+		// Big enough to use read-ahead:
+		{1024*1024 + 17, 128 * 1024},
 	}
 
 	for _, tc := range tcs {
@@ -218,6 +221,69 @@ func testHandleFuseLikeRead(t *testing.T, fileSize, blockSize int) {
 	})
 }
 
+func TestHandleReadAhead(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		rawData := testutil.CreateDummyBuf(2*1024*1024 + 3)
+		require.Nil(t, fs.Stage("/x", bytes.NewReader(rawData)))
+
+		for _, enabled := range []bool{true, false} {
+			fs.cfg.SetBool("readahead.enabled", enabled)
+
+			fd, err := fs.Open("/x")
+			require.Nil(t, err)
+
+			// Block 0 of the read-ahead is waited for, so it has to
+			// be in the page cache, even if it was not read yet:
+			inode := int64(fd.file.Inode())
+			_, err = fd.Read(make([]byte, 128*1024))
+			require.Nil(t, err)
+
+			_, err = fs.pageCache.Lookup(inode, 2)
+			if enabled {
+				require.Nil(t, err)
+			} else {
+				require.Equal(t, page.ErrCacheMiss, err)
+			}
+
+			_, err = fd.Seek(0, io.SeekStart)
+			require.Nil(t, err)
+
+			data, err := ioutil.ReadAll(fd)
+			require.Nil(t, err)
+			require.Equal(t, rawData, data)
+			require.Nil(t, fd.Close())
+
+			// Prefetched pages are gone with the handle:
+			_, err = fs.pageCache.Lookup(inode, 31)
+			require.Equal(t, page.ErrCacheMiss, err)
+
+			// Writes in the middle of a sequential read
+			// should show up in the read data:
+			fd, err = fs.Open("/x")
+			require.Nil(t, err)
+
+			buf := make([]byte, 1024*1024)
+			_, err = io.ReadFull(fd, buf)
+			require.Nil(t, err)
+			require.Equal(t, rawData[:len(buf)], buf)
+
+			_, err = fd.WriteAt([]byte{0xFF, 0xFF}, 1024*1024+10)
+			require.Nil(t, err)
+
+			_, err = io.ReadFull(fd, buf)
+			require.Nil(t, err)
+
+			expected := append([]byte{}, rawData[len(buf):2*len(buf)]...)
+			expected[10], expected[11] = 0xFF, 0xFF
+			require.Equal(t, expected, buf)
+			require.Nil(t, fd.Close())
+
+			// Restore the original content for the next run:
+			require.Nil(t, fs.Stage("/x", bytes.NewReader(rawData)))
+		}
+	})
+}
+
 func TestHandleChangeCompression(t *testing.T) {
 	withDummyFS(t, func(fs *FS) {
 		// Create a file which will not be compressed.
@@ -0,0 +1,314 @@
+package catfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs/db"
+	ie "github.com/sahib/brig/catfs/errors"
+	n "github.com/sahib/brig/catfs/nodes"
+)
+
+// Paths can be locked by a user to tell others that they are working on it.
+// Locks are advisory: they do not prevent any modification, but they are
+// shown to other users, honored by flock/fcntl in the fuse layer and the
+// sync can warn about (or refuse) changes to paths that somebody else locked.
+// Our own locks are stored alongside the locks we fetched from remotes.
+
+const locksMetadataKey = "fs.locks"
+
+// ErrLocked is returned when a path is locked by another user.
+var ErrLocked = errors.New("path is locked by another user")
+
+// Lock is an advisory lock on a file or directory.
+// A lock on a directory covers everything below it.
+type Lock struct {
+	// Path is the locked path.
+	Path string `json:"path"`
+	// Owner is the name of the user holding the lock.
+	Owner string `json:"owner"`
+	// Since is the time the lock was taken.
+	Since time.Time `json:"since"`
+}
+
+// covers returns true if `lock` applies to `nodePath`.
+func (lock Lock) covers(nodePath string) bool {
+	return lock.Path == "/" || lock.Path == nodePath || strings.HasPrefix(nodePath, lock.Path+"/")
+}
+
+// locks returns all lock records. fs.mu needs to be locked.
+func (fs *FS) locks() ([]Lock, error) {
+	data, err := fs.lkr.MetadataGet(locksMetadataKey)
+	if err == db.ErrNoSuchKey || len(data) == 0 {
+		return []Lock{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	locks := []Lock{}
+	if err := json.Unmarshal(data, &locks); err != nil {
+		return nil, e.Wrapf(err, "failed to decode locks")
+	}
+
+	return locks, nil
+}
+
+func (fs *FS) setLocks(locks []Lock) error {
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].Path == locks[j].Path {
+			return locks[i].Owner < locks[j].Owner
+		}
+
+		return locks[i].Path < locks[j].Path
+	})
+
+	data, err := json.Marshal(locks)
+	if err != nil {
+		return err
+	}
+
+	return fs.lkr.MetadataPut(locksMetadataKey, data)
+}
+
+// lockedBy returns the lock covering `nodePath` that is held by somebody
+// else than `user`. If there are several, the one nearest to `nodePath` wins.
+func lockedBy(locks []Lock, nodePath, user string) *Lock {
+	var found *Lock
+	for idx := range locks {
+		lock := &locks[idx]
+		if lock.Owner == user || !lock.covers(nodePath) {
+			continue
+		}
+
+		if found == nil || len(lock.Path) > len(found.Path) {
+			found = lock
+		}
+	}
+
+	return found
+}
+
+// OnLockChange registers `fn` to be called when one of our own locks was
+// taken or released. The callback runs in a separate go routine.
+// Can be called several times.
+func (fs *FS) OnLockChange(fn func()) {
+	fs.changeMu.Lock()
+	defer fs.changeMu.Unlock()
+
+	fs.lockCallbacks = append(fs.lockCallbacks, fn)
+}
+
+func (fs *FS) notifyLockChange() {
+	fs.changeMu.Lock()
+	defer fs.changeMu.Unlock()
+
+	for _, fn := range fs.lockCallbacks {
+		go fn()
+	}
+}
+
+// Lock takes an advisory lock on `nodePath` for the owner of this filesystem.
+// If the path (or a parent or child of it) is locked by another user,
+// an error wrapping ErrLocked is returned. Locking a path twice is fine.
+func (fs *FS) Lock(nodePath string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.readOnly {
+		return ErrReadOnly
+	}
+
+	nodePath = prefixSlash(path.Clean(nodePath))
+	nd, err := fs.lkr.LookupNode(nodePath)
+	if err != nil {
+		return err
+	}
+
+	if nd.Type() == n.NodeTypeGhost {
+		return ie.NoSuchFile(nodePath)
+	}
+
+	owner, err := fs.lkr.Owner()
+	if err != nil {
+		return err
+	}
+
+	locks, err := fs.locks()
+	if err != nil {
+		return err
+	}
+
+	for _, lock := range locks {
+		if lock.Owner == owner {
+			if lock.Path == nodePath {
+				// Already locked by us.
+				return nil
+			}
+
+			continue
+		}
+
+		// Children of the path are affected by the new lock too:
+		if lock.covers(nodePath) || strings.HasPrefix(lock.Path, nodePath+"/") || nodePath == "/" {
+			return e.Wrapf(ErrLocked, "%s is locked by %s", lock.Path, lock.Owner)
+		}
+	}
+
+	locks = append(locks, Lock{
+		Path:  nodePath,
+		Owner: owner,
+		Since: time.Now(),
+	})
+
+	if err := fs.setLocks(locks); err != nil {
+		return err
+	}
+
+	fs.notifyLockChange()
+	return nil
+}
+
+// Unlock releases our own lock on `nodePath`.
+// Locks of other users can not be released.
+func (fs *FS) Unlock(nodePath string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.readOnly {
+		return ErrReadOnly
+	}
+
+	nodePath = prefixSlash(path.Clean(nodePath))
+	owner, err := fs.lkr.Owner()
+	if err != nil {
+		return err
+	}
+
+	locks, err := fs.locks()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	newLocks := []Lock{}
+	for _, lock := range locks {
+		if lock.Path != nodePath {
+			newLocks = append(newLocks, lock)
+			continue
+		}
+
+		if lock.Owner != owner {
+			return e.Wrapf(ErrLocked, "%s is locked by %s", lock.Path, lock.Owner)
+		}
+
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("%s is not locked", nodePath)
+	}
+
+	if err := fs.setLocks(newLocks); err != nil {
+		return err
+	}
+
+	fs.notifyLockChange()
+	return nil
+}
+
+// Locks returns all known locks, our own and the ones of remotes.
+func (fs *FS) Locks() ([]Lock, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.locks()
+}
+
+// OwnLocks returns only the locks held by the owner of this filesystem.
+func (fs *FS) OwnLocks() ([]Lock, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	owner, err := fs.lkr.Owner()
+	if err != nil {
+		return nil, err
+	}
+
+	locks, err := fs.locks()
+	if err != nil {
+		return nil, err
+	}
+
+	ownLocks := []Lock{}
+	for _, lock := range locks {
+		if lock.Owner == owner {
+			ownLocks = append(ownLocks, lock)
+		}
+	}
+
+	return ownLocks, nil
+}
+
+// LockedBy returns the lock of another user that covers `nodePath`.
+// If the path is not locked (or only by us), nil is returned.
+func (fs *FS) LockedBy(nodePath string) (*Lock, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	owner, err := fs.lkr.Owner()
+	if err != nil {
+		return nil, err
+	}
+
+	locks, err := fs.locks()
+	if err != nil {
+		return nil, err
+	}
+
+	return lockedBy(locks, prefixSlash(nodePath), owner), nil
+}
+
+// SetRemoteLocks replaces all locks of `remoteOwner` with `remoteLocks`.
+// Every user can only announce their own locks, so the owner of all
+// records in `remoteLocks` is set to `remoteOwner`.
+func (fs *FS) SetRemoteLocks(remoteOwner string, remoteLocks []Lock) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	owner, err := fs.lkr.Owner()
+	if err != nil {
+		return err
+	}
+
+	if remoteOwner == owner {
+		return fmt.Errorf("refusing to overwrite own locks")
+	}
+
+	locks, err := fs.locks()
+	if err != nil {
+		return err
+	}
+
+	newLocks := []Lock{}
+	for _, lock := range locks {
+		if lock.Owner != remoteOwner {
+			newLocks = append(newLocks, lock)
+		}
+	}
+
+	for _, lock := range remoteLocks {
+		lock.Owner = remoteOwner
+		lock.Path = prefixSlash(path.Clean(lock.Path))
+		newLocks = append(newLocks, lock)
+	}
+
+	return fs.setLocks(newLocks)
+}
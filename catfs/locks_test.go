@@ -0,0 +1,96 @@
+package catfs
+
+import (
+	"bytes"
+	"testing"
+
+	e "github.com/pkg/errors"
+	ie "github.com/sahib/brig/catfs/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockUnlock(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		require.NoError(t, fs.Stage("/dir/a", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.Stage("/b", bytes.NewReader([]byte{2})))
+
+		changes := make(chan bool, 10)
+		fs.OnLockChange(func() { changes <- true })
+
+		require.True(t, ie.IsNoSuchFileError(fs.Lock("/nope")))
+		require.NoError(t, fs.Lock("/dir"))
+		require.NoError(t, fs.Lock("/dir"))
+		<-changes
+
+		locks, err := fs.Locks()
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+		require.Equal(t, "/dir", locks[0].Path)
+		require.Equal(t, "alice", locks[0].Owner)
+
+		// Own locks do not count as "locked by":
+		lock, err := fs.LockedBy("/dir/a")
+		require.NoError(t, err)
+		require.Nil(t, lock)
+
+		// bob locked /b and /dir/a (which conflicts with our lock,
+		// but that's fine since we cannot control what others do)
+		require.NoError(t, fs.SetRemoteLocks("bob", []Lock{
+			{Path: "/b", Owner: "bob"},
+			{Path: "/dir/a", Owner: "mallory"},
+		}))
+
+		lock, err = fs.LockedBy("/dir/a")
+		require.NoError(t, err)
+		require.NotNil(t, lock)
+		require.Equal(t, "bob", lock.Owner)
+
+		require.Equal(t, ErrLocked, e.Cause(fs.Lock("/b")))
+		require.Equal(t, ErrLocked, e.Cause(fs.Lock("/")))
+		require.Equal(t, ErrLocked, e.Cause(fs.Unlock("/b")))
+
+		ownLocks, err := fs.OwnLocks()
+		require.NoError(t, err)
+		require.Len(t, ownLocks, 1)
+
+		require.NoError(t, fs.Unlock("/dir"))
+		<-changes
+		require.Error(t, fs.Unlock("/dir"))
+
+		// bob released all of his locks:
+		require.NoError(t, fs.SetRemoteLocks("bob", nil))
+		locks, err = fs.Locks()
+		require.NoError(t, err)
+		require.Empty(t, locks)
+	})
+}
+
+func TestSyncLockPolicy(t *testing.T) {
+	withDummyFS(t, func(fsa *FS) {
+		require.NoError(t, fsa.MakeCommit("hello a"))
+		withDummyFS(t, func(fsb *FS) {
+			require.NoError(t, fsb.lkr.SetOwner("bob"))
+			require.NoError(t, fsb.MakeCommit("hello b"))
+			require.NoError(t, fsb.Mkdir("/locked", false))
+			require.NoError(t, fsb.Mkdir("/free", false))
+			require.NoError(t, fsa.Sync(fsb))
+
+			// alice locks /locked, but bob adds something anyways:
+			require.NoError(t, fsa.Lock("/locked"))
+			require.NoError(t, fsb.Stage("/locked/x", bytes.NewReader([]byte{1})))
+			require.NoError(t, fsb.Stage("/free/y", bytes.NewReader([]byte{2})))
+			require.NoError(t, fsa.Sync(fsb, SyncOptLockPolicy("refuse", nil)))
+
+			_, err := fsa.Stat("/locked/x")
+			require.True(t, ie.IsNoSuchFileError(err))
+			_, err = fsa.Stat("/free/y")
+			require.NoError(t, err)
+
+			// With the default policy the change is applied with a warning:
+			require.NoError(t, fsb.Stage("/locked/z", bytes.NewReader([]byte{3})))
+			require.NoError(t, fsa.Sync(fsb))
+			_, err = fsa.Stat("/locked/z")
+			require.NoError(t, err)
+		})
+	})
+}
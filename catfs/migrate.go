@@ -0,0 +1,240 @@
+package catfs
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// MigrateStats is returned by MigrateDatabase and describes
+// what was copied from one database to the other.
+type MigrateStats struct {
+	SrcType  string
+	DstType  string
+	Keys     int
+	Checksum string
+}
+
+// CanonicalKey converts a key as returned by Keys() of a database of type
+// `dbType` back to the key that was originally passed to Put().
+//
+// This is necessary, since the backends do not preserve the segmentation of
+// the key: badger joins all parts with a dot and the disk database uses
+// the path separator. Both are used freely in paths and metadata keys.
+// Luckily only the last part of the linker's keys may contain them,
+// so we can reconstruct them by knowing the key layout:
+//
+// - stage.tree.<path>[.]: staged nodes; directories get an extra "." part.
+// - tree.<path>[/.]: committed nodes; directories end in "/.".
+// - metadata.<key> and refs.<name>: arbitrary names.
+//
+// All other keys consist of parts without dots or slashes.
+func CanonicalKey(dbType string, key []string) []string {
+	if dbType == db.TypeDisk {
+		return canonicalDiskKey(key)
+	}
+
+	return canonicalBadgerKey(key)
+}
+
+func canonicalBadgerKey(key []string) []string {
+	fullKey := strings.Join(key, ".")
+	if strings.HasPrefix(fullKey, "stage.tree.") {
+		// Directories have an extra "." part, which results in "/dir..".
+		nodePath := fullKey[len("stage.tree."):]
+		if strings.HasSuffix(nodePath, "..") {
+			return []string{"stage", "tree", nodePath[:len(nodePath)-2], "."}
+		}
+
+		return []string{"stage", "tree", nodePath}
+	}
+
+	for _, bucket := range []string{"tree", "metadata", "refs"} {
+		if strings.HasPrefix(fullKey, bucket+".") {
+			return []string{bucket, fullKey[len(bucket)+1:]}
+		}
+	}
+
+	return strings.Split(fullKey, ".")
+}
+
+func canonicalDiskKey(key []string) []string {
+	if len(key) > 2 && key[0] == "stage" && key[1] == "tree" {
+		parts := key[2:]
+		if parts[len(parts)-1] == "." {
+			nodePath := "/" + path.Join(parts[:len(parts)-1]...)
+			return []string{"stage", "tree", nodePath, "."}
+		}
+
+		return []string{"stage", "tree", "/" + path.Join(parts...)}
+	}
+
+	if len(key) > 1 && key[0] == "tree" {
+		parts := key[1:]
+		if parts[len(parts)-1] == "." {
+			nodePath := "/" + path.Join(parts[:len(parts)-1]...)
+			if nodePath != "/" {
+				nodePath += "/"
+			}
+
+			return []string{"tree", nodePath + "."}
+		}
+
+		return []string{"tree", "/" + path.Join(parts...)}
+	}
+
+	if len(key) > 1 && (key[0] == "metadata" || key[0] == "refs") {
+		return []string{key[0], strings.Join(key[1:], "/")}
+	}
+
+	return key
+}
+
+// databaseChecksum computes the number of keys and a checksum over all
+// canonical keys and their values in `kv`. The result does not depend
+// on the type of the database, so it can be used to verify a migration.
+func databaseChecksum(kv db.Database, dbType string) (int, string, error) {
+	keys, err := kv.Keys()
+	if err != nil {
+		return 0, "", err
+	}
+
+	type entry struct {
+		key    string
+		rawKey []string
+	}
+
+	entries := make([]entry, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, entry{
+			key:    strings.Join(CanonicalKey(dbType, key), "\x00"),
+			rawKey: key,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	sizeBuf := make([]byte, binary.MaxVarintLen64)
+	hash := sha256.New()
+	for _, entry := range entries {
+		val, err := kv.Get(entry.rawKey...)
+		if err != nil {
+			return 0, "", e.Wrapf(err, "failed to read key %v", entry.rawKey)
+		}
+
+		n := binary.PutUvarint(sizeBuf, uint64(len(entry.key)))
+		hash.Write(sizeBuf[:n])
+		hash.Write([]byte(entry.key))
+
+		n = binary.PutUvarint(sizeBuf, uint64(len(val)))
+		hash.Write(sizeBuf[:n])
+		hash.Write(val)
+	}
+
+	return len(entries), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// MigrateDatabase copies all keys of the filesystem metadata at `srcPath`
+// into a new database of type `dstType` at `dstPath`. The type of the source
+// database is detected automatically. Afterwards the number of keys and
+// a checksum over all keys and values are compared. The source database
+// is never modified. No filesystem may be opened on either path.
+func MigrateDatabase(srcPath, dstPath, dstType string) (*MigrateStats, error) {
+	srcType, err := db.DetectType(srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if dstType == db.TypeMemory {
+		return nil, fmt.Errorf("cannot migrate to a memory database")
+	}
+
+	// Make sure we don't merge into existing data by accident:
+	entries, err := ioutil.ReadDir(dstPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("destination %s is not empty", dstPath)
+	}
+
+	src, err := db.Open(srcType, srcPath)
+	if err != nil {
+		return nil, e.Wrapf(err, "failed to open source database")
+	}
+
+	defer src.Close()
+
+	dst, err := db.Open(dstType, dstPath)
+	if err != nil {
+		return nil, e.Wrapf(err, "failed to open destination database")
+	}
+
+	defer dst.Close()
+
+	keys, err := src.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	batch := dst.Batch()
+	for _, key := range keys {
+		val, err := src.Get(key...)
+		if err != nil {
+			batch.Rollback()
+			return nil, e.Wrapf(err, "failed to read key %v", key)
+		}
+
+		batch.Put(val, CanonicalKey(srcType, key)...)
+	}
+
+	if err := batch.Flush(); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("copied %d keys from %s to %s", len(keys), srcPath, dstPath)
+
+	srcCount, srcSum, err := databaseChecksum(src, srcType)
+	if err != nil {
+		return nil, e.Wrapf(err, "failed to checksum source")
+	}
+
+	dstCount, dstSum, err := databaseChecksum(dst, dstType)
+	if err != nil {
+		return nil, e.Wrapf(err, "failed to checksum destination")
+	}
+
+	if srcCount != dstCount {
+		return nil, fmt.Errorf(
+			"key count differs after migration: %d (src) != %d (dst)",
+			srcCount, dstCount,
+		)
+	}
+
+	if srcSum != dstSum {
+		return nil, fmt.Errorf(
+			"checksum differs after migration: %s (src) != %s (dst)",
+			srcSum, dstSum,
+		)
+	}
+
+	return &MigrateStats{
+		SrcType:  srcType,
+		DstType:  dstType,
+		Keys:     dstCount,
+		Checksum: dstSum,
+	}, nil
+}
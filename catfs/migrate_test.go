@@ -0,0 +1,102 @@
+package catfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sahib/brig/catfs/db"
+	"github.com/sahib/brig/catfs/mio/pagecache/mdcache"
+	"github.com/sahib/brig/defaults"
+	"github.com/sahib/config"
+	"github.com/stretchr/testify/require"
+)
+
+func withFSAt(t *testing.T, backend FsBackend, dbPath string, fn func(fs *FS)) {
+	cfg, err := config.Open(nil, defaults.Defaults, config.StrictnessPanic)
+	require.Nil(t, err)
+
+	mdc, err := mdcache.New(mdcache.Options{
+		MaxMemoryUsage: 1024 * 1024,
+	})
+	require.NoError(t, err)
+
+	fs, err := NewFilesystem(backend, dbPath, "alice", false, cfg.Section("fs"), nil, nil, mdc)
+	require.NoError(t, err)
+
+	fn(fs)
+	require.NoError(t, fs.Close())
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	t.Parallel()
+
+	baseDir, err := ioutil.TempDir("", "brig-migrate-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(baseDir)
+
+	backend := NewMemFsBackend()
+	badgerPath := filepath.Join(baseDir, "badger")
+	diskPath := filepath.Join(baseDir, "disk")
+	badgerAgainPath := filepath.Join(baseDir, "badger-again")
+
+	withFSAt(t, backend, badgerPath, func(fs *FS) {
+		require.NoError(t, fs.Mkdir("/sub.dir/x", true))
+		require.NoError(t, fs.Stage("/sub.dir/x/a.txt", bytes.NewReader([]byte("hello"))))
+		require.NoError(t, fs.MakeCommit("first"))
+		require.NoError(t, fs.Tag("head", "release"))
+		require.NoError(t, fs.Stage("/b.tar.gz", bytes.NewReader([]byte("world"))))
+		require.NoError(t, fs.Move("/sub.dir/x/a.txt", "/sub.dir/c.txt"))
+	})
+
+	typ, err := db.DetectType(badgerPath)
+	require.NoError(t, err)
+	require.Equal(t, db.TypeBadger, typ)
+
+	stats, err := MigrateDatabase(badgerPath, diskPath, db.TypeDisk)
+	require.NoError(t, err)
+	require.Equal(t, db.TypeBadger, stats.SrcType)
+	require.Equal(t, db.TypeDisk, stats.DstType)
+	require.True(t, stats.Keys > 0)
+
+	typ, err = db.DetectType(diskPath)
+	require.NoError(t, err)
+	require.Equal(t, db.TypeDisk, typ)
+
+	// Going back should yield the very same checksum:
+	againStats, err := MigrateDatabase(diskPath, badgerAgainPath, db.TypeBadger)
+	require.NoError(t, err)
+	require.Equal(t, stats.Keys, againStats.Keys)
+	require.Equal(t, stats.Checksum, againStats.Checksum)
+
+	// Refuse to overwrite existing data:
+	_, err = MigrateDatabase(badgerPath, diskPath, db.TypeDisk)
+	require.Error(t, err)
+
+	for _, dbPath := range []string{diskPath, badgerAgainPath} {
+		withFSAt(t, backend, dbPath, func(fs *FS) {
+			info, err := fs.Stat("/sub.dir/c.txt")
+			require.NoError(t, err)
+			require.Equal(t, uint64(5), info.Size)
+
+			_, err = fs.Stat("/sub.dir/x/a.txt")
+			require.Error(t, err)
+
+			stream, err := fs.Cat("/b.tar.gz")
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(stream)
+			require.NoError(t, err)
+			require.Equal(t, []byte("world"), data)
+
+			cmt, err := fs.CommitInfo("release")
+			require.NoError(t, err)
+			require.NotNil(t, cmt)
+			require.Equal(t, "first", cmt.Msg)
+
+			// Check that the migrated filesystem is still writable:
+			require.NoError(t, fs.MakeCommit("second"))
+		})
+	}
+}
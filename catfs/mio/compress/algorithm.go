@@ -77,34 +77,70 @@ type lz4Algo struct {
 	compressor *lz4.Compressor
 }
 
-type zstdAlgo struct{}
+type zstdAlgo struct {
+	encoder *zstd.Encoder
+}
+
+// Level controls how hard an algorithm tries to compress.
+// Only zstd knows several levels. The level is not stored
+// in the stream, since it is not needed to decompress it.
+type Level int
+
+const (
+	// LevelDefault is a good tradeoff between speed and ratio.
+	LevelDefault = Level(iota)
+
+	// LevelFastest compresses as fast as possible.
+	LevelFastest
+
+	// LevelBetter compresses better, but takes more time.
+	LevelBetter
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelFastest:
+		return "fastest"
+	case LevelBetter:
+		return "better"
+	default:
+		return "default"
+	}
+}
 
 var (
-	zstdWriter *zstd.Encoder
-	zstdReader *zstd.Decoder
+	zstdWriters = make(map[Level]*zstd.Encoder)
+	zstdReader  *zstd.Decoder
 )
 
 func init() {
-	var err error
-
 	// NOTE: zstd package allows us to use the same writer and reader
 	//       stateless if we just use block encoding/decoding.
 	//       This saves us some extra allocations.
+	zstdLevels := map[Level]zstd.EncoderLevel{
+		LevelFastest: zstd.SpeedFastest,
+		LevelDefault: zstd.SpeedDefault,
+		LevelBetter:  zstd.SpeedBetterCompression,
+	}
 
-	// TODO: configure compression level?
-	zstdWriter, err = zstd.NewWriter(
-		nil,
-		zstd.WithEncoderLevel(zstd.SpeedDefault),
-	)
+	for level, zstdLevel := range zstdLevels {
+		zstdWriter, err := zstd.NewWriter(
+			nil,
+			zstd.WithEncoderLevel(zstdLevel),
+		)
 
-	if err != nil {
-		// configuring the writer wrong is a programmer error.
-		panic(err)
+		if err != nil {
+			// configuring the writer wrong is a programmer error.
+			panic(err)
+		}
+
+		zstdWriters[level] = zstdWriter
 	}
 
 	// NOTE: reader should set max memory bound with WithDecoderMaxMemory.
 	//       we can deduce it from maxChunkSize and protect against
 	//       malicious inputs.
+	var err error
 	zstdReader, err = zstd.NewReader(
 		nil,
 		zstd.WithDecoderMaxMemory(32*maxChunkSize),
@@ -117,18 +153,23 @@ func init() {
 
 var (
 	// AlgoMap is a map of available algorithms.
-	algoMap = map[AlgorithmType]func() Algorithm{
-		AlgoSnappy: func() Algorithm {
+	algoMap = map[AlgorithmType]func(level Level) Algorithm{
+		AlgoSnappy: func(level Level) Algorithm {
 			return snappyAlgo{}
 		},
-		AlgoLZ4: func() Algorithm {
+		AlgoLZ4: func(level Level) Algorithm {
 			// TODO: we could configure compression level here.
 			return &lz4Algo{
 				compressor: &lz4.Compressor{},
 			}
 		},
-		AlgoZstd: func() Algorithm {
-			return zstdAlgo{}
+		AlgoZstd: func(level Level) Algorithm {
+			encoder, ok := zstdWriters[level]
+			if !ok {
+				encoder = zstdWriters[LevelDefault]
+			}
+
+			return zstdAlgo{encoder: encoder}
 		},
 	}
 
@@ -177,7 +218,7 @@ func (a *lz4Algo) MaxEncodeBufferSize() int {
 /////////////////////////
 
 func (a zstdAlgo) Encode(dst, src []byte) ([]byte, error) {
-	return zstdWriter.EncodeAll(src, dst[:0]), nil
+	return a.encoder.EncodeAll(src, dst[:0]), nil
 }
 
 func (a zstdAlgo) Decode(dst, src []byte) ([]byte, error) {
@@ -190,10 +231,14 @@ func (a zstdAlgo) MaxEncodeBufferSize() int {
 }
 
 func algorithmFromType(a AlgorithmType) (Algorithm, error) {
+	return algorithmFromTypeLevel(a, LevelDefault)
+}
+
+func algorithmFromTypeLevel(a AlgorithmType, level Level) (Algorithm, error) {
 	newAlgoFn, ok := algoMap[a]
 	if !ok {
 		return nil, ErrBadAlgo
 	}
 
-	return newAlgoFn(), nil
+	return newAlgoFn(level), nil
 }
@@ -0,0 +1,495 @@
+package compress
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/klauspost/compress/huff0"
+	"github.com/klauspost/compress/zstd"
+	e "github.com/pkg/errors"
+)
+
+// Collections of small, similar files (json, logs, configs) compress badly
+// when every file is compressed on its own, since each stream has to learn
+// the repeating parts again. A dictionary that was trained on a sample of
+// those files gives zstd this knowledge upfront. Streams that were compressed
+// with a dictionary store its id in the header and need the same dictionary
+// to be decoded again.
+
+const (
+	// DictIDSize is the size of a DictID in bytes.
+	DictIDSize = sha256.Size
+
+	// DefaultDictSize is the maximum size of a dictionary built by TrainDict.
+	DefaultDictSize = 64 * 1024
+
+	// Size of the segments that are copied from the samples into the dict.
+	dictSegmentSize = 64
+
+	// Size of the substrings whose frequency is counted in the samples.
+	dictDmerSize = 8
+
+	// Number of buckets for counting the substrings.
+	dictHashLog = 20
+)
+
+var (
+	// ErrDictNotSupported is returned when a dictionary is used
+	// with another algorithm than zstd.
+	ErrDictNotSupported = errors.New("dictionaries are only supported by zstd")
+
+	// ErrDictNotFound is returned when a stream was compressed with a
+	// dictionary that is not known.
+	ErrDictNotFound = errors.New("compression dictionary not found")
+
+	// ErrDictUseless is returned by TrainDict when the samples
+	// have nothing in common that a dictionary could help with.
+	ErrDictUseless = errors.New("samples have too little in common for a dictionary")
+
+	dictMagic = []byte{0x37, 0xa4, 0x30, 0xec}
+)
+
+// DictID identifies a dictionary by the hash of its data.
+type DictID [DictIDSize]byte
+
+func (id DictID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// ParseDictID parses the output of DictID.String().
+func ParseDictID(s string) (DictID, error) {
+	id := DictID{}
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return id, err
+	}
+
+	if len(data) != DictIDSize {
+		return id, fmt.Errorf("dictionary id has bad length: %d", len(data))
+	}
+
+	copy(id[:], data)
+	return id, nil
+}
+
+// DictLookup should return the dictionary with `id`. If there is no such
+// dictionary, it should return an error that wraps ErrDictNotFound.
+type DictLookup func(id DictID) (*Dict, error)
+
+// IsDictNotFoundError returns true if `err` is (or wraps) ErrDictNotFound.
+func IsDictNotFoundError(err error) bool {
+	return e.Cause(err) == ErrDictNotFound
+}
+
+// Dict is a zstd dictionary (in the format of `zstd --train`).
+type Dict struct {
+	// ID is the hash of Data.
+	ID DictID
+
+	// Data is the dictionary itself.
+	Data []byte
+
+	once sync.Once
+	algo *zstdDictAlgo
+	err  error
+}
+
+// NewDict checks if `data` is a valid dictionary and returns it.
+func NewDict(data []byte) (*Dict, error) {
+	dict := &Dict{
+		ID:   sha256.Sum256(data),
+		Data: data,
+	}
+
+	if _, err := dict.algorithm(); err != nil {
+		return nil, e.Wrap(err, "bad dictionary")
+	}
+
+	return dict, nil
+}
+
+// algorithm returns a zstd Algorithm that uses the dictionary.
+// Creating the encoder and decoder is not cheap, so it is done only once.
+func (d *Dict) algorithm() (Algorithm, error) {
+	d.once.Do(func() {
+		enc, err := zstd.NewWriter(
+			nil,
+			zstd.WithEncoderLevel(zstd.SpeedDefault),
+			zstd.WithEncoderDict(d.Data),
+		)
+
+		if err != nil {
+			d.err = err
+			return
+		}
+
+		dec, err := zstd.NewReader(
+			nil,
+			zstd.WithDecoderMaxMemory(32*maxChunkSize),
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderDicts(d.Data),
+		)
+
+		if err != nil {
+			d.err = err
+			return
+		}
+
+		d.algo = &zstdDictAlgo{enc: enc, dec: dec}
+	})
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.algo, nil
+}
+
+type zstdDictAlgo struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func (a *zstdDictAlgo) Encode(dst, src []byte) ([]byte, error) {
+	return a.enc.EncodeAll(src, dst[:0]), nil
+}
+
+func (a *zstdDictAlgo) Decode(dst, src []byte) ([]byte, error) {
+	return a.dec.DecodeAll(src, dst[:0])
+}
+
+func (a *zstdDictAlgo) MaxEncodeBufferSize() int {
+	return maxChunkSize * 2
+}
+
+/////////////////////////
+
+// TrainDict builds a dictionary of at most `maxSize` bytes from `samples`.
+// Each sample should be (the start of) one of the files the dictionary is
+// meant for. The dictionary contains the parts that most samples share.
+func TrainDict(samples [][]byte, maxSize int) (*Dict, error) {
+	// Leave some room for the entropy tables in front of the content.
+	contentSize := maxSize - 1024
+	if contentSize < dictSegmentSize {
+		return nil, fmt.Errorf("dictionary size is too small: %d", maxSize)
+	}
+
+	content := selectDictContent(samples, contentSize)
+	if len(content) < dictSegmentSize {
+		return nil, ErrDictUseless
+	}
+
+	data, err := buildDict(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDict(data)
+}
+
+func dmerHash(data []byte) uint32 {
+	const prime = 0x9E3779B185EBCA87
+	return uint32((binary.LittleEndian.Uint64(data) * prime) >> (64 - dictHashLog))
+}
+
+type dictSegment struct {
+	data  []byte
+	score uint64
+}
+
+// selectDictContent picks the segments of `samples` that share most of their
+// substrings with other samples. This is a simplified version of the COVER
+// algorithm that `zstd --train` uses: the samples are split into epochs and
+// the best segment of each epoch is taken.
+func selectDictContent(samples [][]byte, contentSize int) []byte {
+	// Count in how many samples each substring occurs:
+	counts := make([]uint32, 1<<dictHashLog)
+	lastSeen := make([]int32, 1<<dictHashLog)
+	for idx, sample := range samples {
+		for pos := 0; pos+dictDmerSize <= len(sample); pos++ {
+			bucket := dmerHash(sample[pos:])
+			if lastSeen[bucket] == int32(idx+1) {
+				continue
+			}
+
+			lastSeen[bucket] = int32(idx + 1)
+			counts[bucket]++
+		}
+	}
+
+	// Substrings that only occur in a single sample are of no use:
+	for bucket, count := range counts {
+		if count < 2 {
+			counts[bucket] = 0
+		}
+	}
+
+	totalSize := 0
+	for _, sample := range samples {
+		totalSize += len(sample)
+	}
+
+	// Every sample gets a share of the epochs that matches its size,
+	// but at least one. Big samples get split into several epochs.
+	nEpochs := contentSize / dictSegmentSize
+	segments := []dictSegment{}
+	for _, sample := range samples {
+		// Samples smaller than a segment are taken as a whole segment.
+		if len(sample) < dictDmerSize {
+			continue
+		}
+
+		sampleEpochs := nEpochs * len(sample) / totalSize
+		if sampleEpochs < 1 {
+			sampleEpochs = 1
+		}
+
+		epochSize := len(sample) / sampleEpochs
+		if epochSize < dictSegmentSize {
+			epochSize = dictSegmentSize
+		}
+
+		for start := 0; start < len(sample); start += epochSize {
+			end := start + epochSize
+			if end > len(sample) {
+				end = len(sample)
+			}
+
+			if seg, ok := bestSegment(sample[start:end], counts); ok {
+				segments = append(segments, seg)
+			}
+		}
+	}
+
+	// Keep the best segments and put the most useful ones at the end,
+	// since zstd can reference data near the end of the dictionary cheaper.
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].score > segments[j].score
+	})
+
+	if len(segments) > nEpochs {
+		segments = segments[:nEpochs]
+	}
+
+	content := make([]byte, 0, contentSize)
+	for idx := len(segments) - 1; idx >= 0; idx-- {
+		content = append(content, segments[idx].data...)
+	}
+
+	return content
+}
+
+// bestSegment returns the segment of `epoch` with the highest score.
+// Substrings of the chosen segment do not count anymore afterwards,
+// so the same content is not picked twice.
+func bestSegment(epoch []byte, counts []uint32) (dictSegment, bool) {
+	segmentSize := dictSegmentSize
+	if len(epoch) < segmentSize {
+		segmentSize = len(epoch)
+	}
+
+	if segmentSize < dictDmerSize {
+		return dictSegment{}, false
+	}
+
+	nDmers := segmentSize - dictDmerSize + 1
+
+	score := uint64(0)
+	for pos := 0; pos < nDmers; pos++ {
+		score += uint64(counts[dmerHash(epoch[pos:])])
+	}
+
+	bestScore, bestStart := score, 0
+	for start := 1; start+segmentSize <= len(epoch); start++ {
+		score -= uint64(counts[dmerHash(epoch[start-1:])])
+		score += uint64(counts[dmerHash(epoch[start+nDmers-1:])])
+		if score > bestScore {
+			bestScore, bestStart = score, start
+		}
+	}
+
+	if bestScore == 0 {
+		return dictSegment{}, false
+	}
+
+	data := epoch[bestStart : bestStart+segmentSize]
+	for pos := 0; pos < nDmers; pos++ {
+		counts[dmerHash(data[pos:])] = 0
+	}
+
+	return dictSegment{data: data, score: bestScore}, true
+}
+
+/////////////////////////
+
+// Default distributions of the zstd format. A trained dictionary could
+// contain better ones, but the gain is small compared to the content.
+// See: https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md#default-distributions
+var (
+	defaultOffsetsNorm = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+
+	defaultMatchLengthsNorm = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+
+	defaultLiteralLengthsNorm = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+)
+
+// writeNCount appends the fse table header for the normalized counts `norm`
+// to `out`. `norm` may not contain zero counts, which keeps this simpler
+// than the general version in the zstd sources.
+func writeNCount(out []byte, norm []int16, tableLog uint) ([]byte, error) {
+	const minTableLog = 5
+
+	var (
+		tableSize = 1 << tableLog
+		bitStream = uint32(tableLog - minTableLog)
+		bitCount  = uint(4)
+		remaining = int16(tableSize + 1)
+		threshold = int16(tableSize)
+		nbBits    = tableLog + 1
+	)
+
+	flush := func() {
+		out = append(out, byte(bitStream), byte(bitStream>>8))
+		bitStream >>= 16
+		bitCount -= 16
+	}
+
+	for idx := 0; remaining > 1; idx++ {
+		if idx >= len(norm) {
+			return nil, errors.New("normalized counts do not add up")
+		}
+
+		count := norm[idx]
+		if count == 0 {
+			return nil, errors.New("zero counts are not supported")
+		}
+
+		max := (2*threshold - 1) - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+
+		count++
+		if count >= threshold {
+			count += max
+		}
+
+		bitStream += uint32(count) << bitCount
+		bitCount += nbBits
+		if count < max {
+			bitCount--
+		}
+
+		if remaining < 1 {
+			return nil, errors.New("normalized counts are too big")
+		}
+
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+
+		if bitCount > 16 {
+			flush()
+		}
+	}
+
+	out = append(out, byte(bitStream), byte(bitStream>>8))
+	return out[:len(out)-2+int((bitCount+7)/8)], nil
+}
+
+// huffmanTable returns a huffman table for the literals in `content`.
+// Every byte value gets a code, so any literal can be encoded with it.
+func huffmanTable(content []byte) ([]byte, error) {
+	input := make([]byte, 0, len(content)+256)
+	input = append(input, content...)
+	if len(input) > huff0.BlockSizeMax-256 {
+		input = input[:huff0.BlockSizeMax-256]
+	}
+
+	for sym := 0; sym < 256; sym++ {
+		input = append(input, byte(sym))
+	}
+
+	scratch := &huff0.Scratch{}
+	_, _, err := huff0.Compress1X(input, scratch)
+	if err == huff0.ErrIncompressible {
+		// Content is random; the table does not matter much then.
+		// Make up one that favours ascii text.
+		for sym := 0; sym < 128; sym++ {
+			for rep := 0; rep < 16; rep++ {
+				input = append(input, byte(sym))
+			}
+		}
+
+		scratch = &huff0.Scratch{}
+		_, _, err = huff0.Compress1X(input, scratch)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scratch.OutTable, nil
+}
+
+// buildDict builds a dictionary in the zstd format around `content`:
+// https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md#dictionary-format
+func buildDict(content []byte) ([]byte, error) {
+	sum := sha256.Sum256(content)
+
+	// Ids below 32768 are reserved for a public registry.
+	id := binary.LittleEndian.Uint32(sum[:4]) | 0x8000
+
+	data := append([]byte{}, dictMagic...)
+	data = append(data, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[4:], id)
+
+	table, err := huffmanTable(content)
+	if err != nil {
+		return nil, e.Wrap(err, "huffman table")
+	}
+
+	data = append(data, table...)
+
+	for _, fse := range []struct {
+		norm     []int16
+		tableLog uint
+	}{
+		{defaultOffsetsNorm, 5},
+		{defaultMatchLengthsNorm, 6},
+		{defaultLiteralLengthsNorm, 6},
+	} {
+		if data, err = writeNCount(data, fse.norm, fse.tableLog); err != nil {
+			return nil, e.Wrap(err, "fse table")
+		}
+	}
+
+	// Initial repeat offsets:
+	for _, offset := range []uint32{1, 4, 8} {
+		offsetBuf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(offsetBuf, offset)
+		data = append(data, offsetBuf...)
+	}
+
+	return append(data, content...), nil
+}
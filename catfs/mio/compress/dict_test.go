@@ -0,0 +1,111 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func similarSample(idx int) []byte {
+	return []byte(fmt.Sprintf(`{
+	"id": %d,
+	"type": "measurement",
+	"sensor": {"name": "sensor-%d", "location": "greenhouse", "unit": "celsius"},
+	"values": [%d, %d, %d],
+	"comment": "collected automatically by the weather station on the roof"
+}`, idx, idx%7, idx*3, idx*5, idx*7))
+}
+
+func compressWithDict(t *testing.T, data []byte, dict *Dict) []byte {
+	buf := &bytes.Buffer{}
+	w, err := NewWriterWithDict(buf, AlgoZstd, dict)
+	require.NoError(t, err)
+	_, err = w.ReadFrom(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDictTrainAndUse(t *testing.T) {
+	samples := [][]byte{}
+	for idx := 0; idx < 200; idx++ {
+		samples = append(samples, similarSample(idx))
+	}
+
+	dict, err := TrainDict(samples, DefaultDictSize)
+	require.NoError(t, err)
+	require.True(t, len(dict.Data) <= DefaultDictSize)
+
+	// A new dictionary with the same data must have the same id:
+	sameDict, err := NewDict(dict.Data)
+	require.NoError(t, err)
+	require.Equal(t, dict.ID, sameDict.ID)
+
+	parsedID, err := ParseDictID(dict.ID.String())
+	require.NoError(t, err)
+	require.Equal(t, dict.ID, parsedID)
+
+	lookup := func(id DictID) (*Dict, error) {
+		if id != dict.ID {
+			return nil, ErrDictNotFound
+		}
+
+		return dict, nil
+	}
+
+	// Use a file that was not part of the samples:
+	data := similarSample(4242)
+	plainZipped := compressWithDict(t, data, nil)
+	dictZipped := compressWithDict(t, data, dict)
+	require.True(
+		t,
+		len(dictZipped) < len(plainZipped),
+		"%d >= %d", len(dictZipped), len(plainZipped),
+	)
+
+	r := NewReaderWithDicts(bytes.NewReader(dictZipped), lookup)
+	decoded, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, decoded)
+
+	// Seeking should still work with the bigger header:
+	_, err = r.Seek(10, io.SeekStart)
+	require.NoError(t, err)
+	decoded, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data[10:], decoded)
+
+	// Without a dictionary it cannot be decoded:
+	_, err = ioutil.ReadAll(NewReader(bytes.NewReader(dictZipped)))
+	require.True(t, IsDictNotFoundError(err))
+
+	// Old streams are still readable by dict readers:
+	decoded, err = ioutil.ReadAll(NewReaderWithDicts(bytes.NewReader(plainZipped), lookup))
+	require.NoError(t, err)
+	require.Equal(t, data, decoded)
+}
+
+func TestDictOnlyZstd(t *testing.T) {
+	samples := [][]byte{}
+	for idx := 0; idx < 50; idx++ {
+		samples = append(samples, similarSample(idx))
+	}
+
+	dict, err := TrainDict(samples, DefaultDictSize)
+	require.NoError(t, err)
+
+	_, err = NewWriterWithDict(&bytes.Buffer{}, AlgoLZ4, dict)
+	require.Equal(t, ErrDictNotSupported, err)
+}
+
+func TestDictUseless(t *testing.T) {
+	_, err := TrainDict([][]byte{[]byte("a"), []byte("b")}, DefaultDictSize)
+	require.Equal(t, ErrDictUseless, err)
+
+	_, err = NewDict([]byte("this is not a dictionary"))
+	require.Error(t, err)
+}
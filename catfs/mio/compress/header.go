@@ -37,6 +37,11 @@ const (
 	trailerSize    = 12
 	headerSize     = 12
 	currentVersion = 1
+
+	// dictVersion is used for streams that need a dictionary to be decoded.
+	// The header of those is followed by the id of the dictionary.
+	dictVersion    = 2
+	dictHeaderSize = headerSize + DictIDSize
 )
 
 // record structure reprenents a offset mapping {uncompressed offset, compressedOffset}.
@@ -76,9 +81,28 @@ func (rc *record) unmarshal(buf []byte) {
 type header struct {
 	algo    AlgorithmType
 	version uint16
+
+	// dictID is only set for dictVersion.
+	dictID DictID
 }
 
-func makeHeader(algo AlgorithmType, version byte) []byte {
+// size returns the number of bytes the header takes in the stream.
+func (hdr *header) size() int64 {
+	if hdr.version == dictVersion {
+		return dictHeaderSize
+	}
+
+	return headerSize
+}
+
+// makeHeader returns the header of a stream compressed with `algo`.
+// If `dict` is not nil, the stream needs this dictionary to be decoded.
+func makeHeader(algo AlgorithmType, dict *Dict) []byte {
+	version := currentVersion
+	if dict != nil {
+		version = dictVersion
+	}
+
 	algoField := make([]byte, 2)
 	binary.LittleEndian.PutUint16(algoField, uint16(algo))
 
@@ -86,7 +110,12 @@ func makeHeader(algo AlgorithmType, version byte) []byte {
 	binary.LittleEndian.PutUint16(versionField, uint16(version))
 
 	suffix := append(versionField, algoField...)
-	return append(MagicNumber, suffix...)
+	hdr := append(MagicNumber, suffix...)
+	if dict != nil {
+		hdr = append(hdr, dict.ID[:]...)
+	}
+
+	return hdr
 }
 
 func readHeader(bheader []byte) (*header, error) {
@@ -98,9 +127,8 @@ func readHeader(bheader []byte) (*header, error) {
 		return nil, ErrBadMagicNumber
 	}
 
-	// This version only understands itself currently:
 	version := binary.LittleEndian.Uint16(bheader[8:10])
-	if version != currentVersion {
+	if version != currentVersion && version != dictVersion {
 		return nil, ErrUnsupportedVersion
 	}
 
@@ -113,10 +141,18 @@ func readHeader(bheader []byte) (*header, error) {
 		return nil, ErrBadAlgorithm
 	}
 
-	return &header{
+	hdr := &header{
 		algo:    algo,
 		version: version,
-	}, nil
+	}
+
+	// The dictionary id follows the header; callers that only
+	// read headerSize bytes need to read it on their own.
+	if version == dictVersion && len(bheader) >= dictHeaderSize {
+		copy(hdr.dictID[:], bheader[headerSize:dictHeaderSize])
+	}
+
+	return hdr, nil
 }
 
 // Pack compresses `data` with `algo` and returns the resulting data.
@@ -1,82 +1,115 @@
 package compress
 
-import (
-	"mime"
-	"net/http"
-	"path/filepath"
-	"strings"
-
-	"github.com/sdemontfort/go-mimemagic"
-)
-
-var (
-	// TextFileExtensions not covered by mime.TypeByExtension
-	TextFileExtensions = map[string]bool{
-		".go":   true,
-		".json": true,
-		".yaml": true,
-		".xml":  true,
-		".txt":  true,
-	}
-)
+import "github.com/sahib/brig/util"
 
 const (
 	// HeaderSizeThreshold is the number of bytes needed to enable compression at all.
 	HeaderSizeThreshold = 2048
+
+	// SampleSize is the number of bytes that SampleAlgorithm
+	// needs from the start of a stream to make a good choice.
+	SampleSize = 2 * maxChunkSize
+
+	// minSavings is the fraction of the size that compression needs
+	// to save on the sample. Otherwise the data counts as incompressible.
+	minSavings = 0.05
+
+	// sampleTolerance is how much worse than the best candidate a faster
+	// candidate may compress the sample and still be chosen.
+	sampleTolerance = 0.1
 )
 
-func guessMime(path string, buf []byte) string {
-	httpMatch := http.DetectContentType(buf)
-	if httpMatch != "application/octet-stream" {
-		return httpMatch
-	}
+// Choice is a compression algorithm together with its level.
+type Choice struct {
+	Algo  AlgorithmType
+	Level Level
+}
 
-	// try to guess it from the buffer we pass:
-	match := mimemagic.Match("", buf)
-	if match == "" {
-		// try to guess it from the file path:
-		match = mime.TypeByExtension(filepath.Ext(path))
+// ChoiceNone is the choice to not compress at all.
+var ChoiceNone = Choice{Algo: AlgoUnknown}
+
+// String returns a short, human readable description like "zstd-fastest".
+func (c Choice) String() string {
+	if !c.Algo.IsValid() {
+		return "none"
 	}
 
-	// handle a few edge cases:
-	if TextFileExtensions[filepath.Ext(path)] {
-		return "text/plain"
+	if c.Algo != AlgoZstd || c.Level == LevelDefault {
+		return c.Algo.String()
 	}
 
-	return match
+	return c.Algo.String() + "-" + c.Level.String()
+}
+
+// sampleCandidates are tried by SampleAlgorithm, the fastest first.
+var sampleCandidates = []Choice{
+	{Algo: AlgoSnappy, Level: LevelDefault},
+	{Algo: AlgoLZ4, Level: LevelDefault},
+	{Algo: AlgoZstd, Level: LevelFastest},
+	{Algo: AlgoZstd, Level: LevelDefault},
+	{Algo: AlgoZstd, Level: LevelBetter},
 }
 
-func isCompressible(mimetype string) bool {
-	if strings.HasPrefix(mimetype, "text/") {
-		return true
+// sampledSize compresses `sample` like the Writer would
+// and returns the size of the compressed data.
+func sampledSize(choice Choice, sample []byte) (int, error) {
+	algo, err := algorithmFromTypeLevel(choice.Algo, choice.Level)
+	if err != nil {
+		return 0, err
 	}
 
-	return CompressibleMapping[mimetype]
+	encBuf := make([]byte, algo.MaxEncodeBufferSize())
+	size := 0
+
+	for len(sample) > 0 {
+		chunk := sample[:util.Min(len(sample), maxChunkSize)]
+		sample = sample[len(chunk):]
+
+		encData, err := algo.Encode(encBuf, chunk)
+		if err != nil {
+			return 0, err
+		}
+
+		size += len(encData)
+	}
+
+	return size, nil
 }
 
-// GuessAlgorithm takes the path name and the header data of it
-// and tries to guess a suitable compression algorithm.
-func GuessAlgorithm(path string, header []byte) (AlgorithmType, error) {
-	if len(header) < HeaderSizeThreshold {
-		return AlgoUnknown, nil
+// SampleAlgorithm compresses `sample` (the start of a stream) with all
+// algorithms and levels and picks the fastest one that compresses almost as
+// well as the best one. If the data turns out to be incompressible,
+// ChoiceNone is returned.
+// The second return value is the compression ratio achieved on the sample.
+func SampleAlgorithm(sample []byte) (Choice, float64, error) {
+	if len(sample) < HeaderSizeThreshold {
+		return ChoiceNone, 1, nil
 	}
 
-	mime := guessMime(path, header)
-	if mime == "" {
-		// the guesses below work only when mime is known
-		return AlgoSnappy, nil
+	sizes := make([]int, len(sampleCandidates))
+	bestSize := len(sample)
+	for idx, candidate := range sampleCandidates {
+		size, err := sampledSize(candidate, sample)
+		if err != nil {
+			return ChoiceNone, 1, err
+		}
+
+		sizes[idx] = size
+		if size < bestSize {
+			bestSize = size
+		}
 	}
 
-	compressible := isCompressible(mime)
-	if !compressible {
-		return AlgoUnknown, nil
+	if float64(bestSize) > float64(len(sample))*(1-minSavings) {
+		return ChoiceNone, 1, nil
 	}
 
-	// text like files probably deserve some thorough compression:
-	if strings.HasPrefix(mime, "text/") {
-		return AlgoLZ4, nil
+	for idx, candidate := range sampleCandidates {
+		if float64(sizes[idx]) <= float64(bestSize)*(1+sampleTolerance) {
+			return candidate, float64(sizes[idx]) / float64(len(sample)), nil
+		}
 	}
 
-	// fallback to snappy for generic files:
-	return AlgoSnappy, nil
+	// Not reachable, the best candidate always matches.
+	return ChoiceNone, 1, nil
 }
@@ -1,60 +1,52 @@
 package compress
 
 import (
+	"bytes"
+	"io/ioutil"
 	"testing"
 
 	"github.com/sahib/brig/util/testutil"
+	"github.com/stretchr/testify/require"
 )
 
-type testCase struct {
-	path         string
-	header       []byte
-	expectedAlgo AlgorithmType
-}
+func TestSampleAlgorithm(t *testing.T) {
+	t.Parallel()
 
-var (
-	testCases = []testCase{
-		{
-			"1.txt",
-			testutil.CreateDummyBuf(HeaderSizeThreshold - 1),
-			AlgoUnknown,
-		}, {
-			"2.txt",
-			testutil.CreateDummyBuf(HeaderSizeThreshold),
-			AlgoLZ4,
-		}, {
-			"3.opus",
-			append(
-				[]byte{0x4f, 0x67, 0x67, 0x53},
-				testutil.CreateDummyBuf(HeaderSizeThreshold)...,
-			),
-			AlgoUnknown,
-		}, {
-			"4.zip",
-			append(
-				[]byte{0x50, 0x4b, 0x3, 0x4},
-				testutil.CreateDummyBuf(HeaderSizeThreshold)...,
-			),
-			AlgoUnknown,
-		},
-	}
-)
+	// Too small to be worth it:
+	choice, _, err := SampleAlgorithm([]byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, ChoiceNone, choice)
 
-func TestChooseCompressAlgo(t *testing.T) {
-	t.Parallel()
+	// Random data is not compressible:
+	noise := testutil.CreateRandomDummyBuf(SampleSize, 42)
+	choice, ratio, err := SampleAlgorithm(noise)
+	require.NoError(t, err)
+	require.Equal(t, ChoiceNone, choice)
+	require.Equal(t, float64(1), ratio)
+
+	// Text should be compressed:
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog\n"), 2000)
+	choice, ratio, err = SampleAlgorithm(text)
+	require.NoError(t, err)
+	require.True(t, choice.Algo.IsValid())
+	require.True(t, ratio < 0.5)
+
+	// The compressed stream must be readable again with a normal reader:
+	buf := &bytes.Buffer{}
+	w, err := NewWriterLevel(buf, choice.Algo, choice.Level)
+	require.NoError(t, err)
+	_, err = w.Write(text)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	decoded, err := ioutil.ReadAll(NewReader(bytes.NewReader(buf.Bytes())))
+	require.NoError(t, err)
+	require.Equal(t, text, decoded)
+}
 
-	for _, tc := range testCases {
-		t.Run(tc.path, func(t *testing.T) {
-			if algo, err := GuessAlgorithm(tc.path, tc.header); err != nil {
-				t.Errorf("Error: %v", err)
-			} else if algo != tc.expectedAlgo {
-				t.Errorf(
-					"For path '%s' expected '%s', got '%s'",
-					tc.path,
-					algoToString[tc.expectedAlgo],
-					algoToString[algo],
-				)
-			}
-		})
-	}
+func TestChoiceString(t *testing.T) {
+	require.Equal(t, "none", ChoiceNone.String())
+	require.Equal(t, "lz4", Choice{Algo: AlgoLZ4, Level: LevelBetter}.String())
+	require.Equal(t, "zstd", Choice{Algo: AlgoZstd}.String())
+	require.Equal(t, "zstd-fastest", Choice{Algo: AlgoZstd, Level: LevelFastest}.String())
 }
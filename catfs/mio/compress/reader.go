@@ -5,6 +5,7 @@ import (
 	"io"
 	"sort"
 
+	e "github.com/pkg/errors"
 	"github.com/sahib/brig/catfs/mio/chunkbuf"
 )
 
@@ -34,6 +35,12 @@ type Reader struct {
 	// Holds algorithm interface.
 	algo Algorithm
 
+	// Used to find the dictionary of streams that were compressed with one.
+	dictLookup DictLookup
+
+	// Size of the header in front of the stream.
+	headerSize int64
+
 	// buffer for reading in the raw stream for decoding.
 	rawBuf []byte
 
@@ -128,6 +135,19 @@ func (r *Reader) parseTrailerIfNeeded() error {
 		return err
 	}
 
+	algo, err := algorithmFromType(header.algo)
+	if err != nil {
+		return err
+	}
+
+	if header.version == dictVersion {
+		if algo, err = r.readDict(header); err != nil {
+			return err
+		}
+	}
+
+	r.headerSize = header.size()
+
 	// Goto end of file and read trailer buffer.
 	if _, err := r.rawR.Seek(-trailerSize, io.SeekEnd); err != nil {
 		return err
@@ -146,11 +166,6 @@ func (r *Reader) parseTrailerIfNeeded() error {
 	r.trailer = &trailer{}
 	r.trailer.unmarshal(buf[:])
 
-	algo, err := algorithmFromType(header.algo)
-	if err != nil {
-		return err
-	}
-
 	// Allocate the rawBuf depending on the algorithm that was used.
 	// Every compression algorithm might produce data that is bigger
 	// than the original data in edge cases. `rawBuf` has to be big
@@ -190,12 +205,11 @@ func (r *Reader) parseTrailerIfNeeded() error {
 	}
 
 	// Set Reader to beginning of file
-	if _, err := r.rawR.Seek(headerSize, io.SeekStart); err != nil {
-
+	if _, err := r.rawR.Seek(r.headerSize, io.SeekStart); err != nil {
 		return err
 	}
 
-	r.rawSeekOffset = headerSize
+	r.rawSeekOffset = r.headerSize
 	r.zipSeekOffset = 0
 	return nil
 }
@@ -322,13 +336,43 @@ func (r *Reader) readZipChunk() ([]byte, error) {
 	return decData, nil
 }
 
+// readDict reads the dictionary id following `header`
+// and returns an algorithm that decodes with this dictionary.
+func (r *Reader) readDict(header *header) (Algorithm, error) {
+	if _, err := io.ReadFull(r.rawR, header.dictID[:]); err != nil {
+		return nil, err
+	}
+
+	if header.algo != AlgoZstd {
+		return nil, ErrDictNotSupported
+	}
+
+	if r.dictLookup == nil {
+		return nil, e.Wrapf(ErrDictNotFound, "%s", header.dictID)
+	}
+
+	dict, err := r.dictLookup(header.dictID)
+	if err != nil {
+		return nil, err
+	}
+
+	return dict.algorithm()
+}
+
 // NewReader returns a new ReadSeeker with compression support. As random access
 // is the purpose of this layer, a ReadSeeker is required as parameter. The used
 // compression algorithm is chosen based on trailer information.
 func NewReader(r io.ReadSeeker) *Reader {
+	return NewReaderWithDicts(r, nil)
+}
+
+// NewReaderWithDicts is like NewReader, but can also decode streams that
+// were compressed with a dictionary. It is searched with `dictLookup`.
+func NewReaderWithDicts(r io.ReadSeeker, dictLookup DictLookup) *Reader {
 	return &Reader{
-		rawR:     r,
-		chunkBuf: chunkbuf.NewChunkBuffer([]byte{}),
-		decBuf:   make([]byte, maxChunkSize),
+		rawR:       r,
+		dictLookup: dictLookup,
+		chunkBuf:   chunkbuf.NewChunkBuffer([]byte{}),
+		decBuf:     make([]byte, maxChunkSize),
 	}
 }
@@ -33,6 +33,9 @@ type Writer struct {
 	// Type of the algorithm
 	algoType AlgorithmType
 
+	// Dictionary that is used for compression (might be nil).
+	dict *Dict
+
 	// Becomes true after the first write.
 	headerWritten bool
 
@@ -74,12 +77,13 @@ func (w *Writer) writeHeaderIfNeeded() error {
 		return nil
 	}
 
-	if _, err := w.rawW.Write(makeHeader(w.algoType, currentVersion)); err != nil {
+	hdr := makeHeader(w.algoType, w.dict)
+	if _, err := w.rawW.Write(hdr); err != nil {
 		return err
 	}
 
 	w.headerWritten = true
-	w.zipOff += headerSize
+	w.zipOff += int64(len(hdr))
 	return nil
 }
 
@@ -136,15 +140,43 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 
 // NewWriter returns a WriteCloser with compression support.
 func NewWriter(w io.Writer, algoType AlgorithmType) (*Writer, error) {
-	algo, err := algorithmFromType(algoType)
+	return newWriter(w, algoType, LevelDefault, nil)
+}
+
+// NewWriterLevel is like NewWriter, but compresses with `level`.
+// Algorithms that do not know about levels ignore it.
+func NewWriterLevel(w io.Writer, algoType AlgorithmType, level Level) (*Writer, error) {
+	return newWriter(w, algoType, level, nil)
+}
+
+// NewWriterWithDict is like NewWriter, but compresses with `dict`.
+// Only zstd supports dictionaries. If `dict` is nil, no dictionary is used.
+// Readers need the same dictionary to decode the stream again.
+func NewWriterWithDict(w io.Writer, algoType AlgorithmType, dict *Dict) (*Writer, error) {
+	return newWriter(w, algoType, LevelDefault, dict)
+}
+
+func newWriter(w io.Writer, algoType AlgorithmType, level Level, dict *Dict) (*Writer, error) {
+	algo, err := algorithmFromTypeLevel(algoType, level)
 	if err != nil {
 		return nil, err
 	}
 
+	if dict != nil {
+		if algoType != AlgoZstd {
+			return nil, ErrDictNotSupported
+		}
+
+		if algo, err = dict.algorithm(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Writer{
 		rawW:     w,
 		algo:     algo,
 		algoType: algoType,
+		dict:     dict,
 		encBuf:   make([]byte, algo.MaxEncodeBufferSize()),
 		chunkBuf: &bytes.Buffer{},
 		trailer:  &trailer{},
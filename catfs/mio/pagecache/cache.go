@@ -15,10 +15,18 @@ type Cache interface {
 	// to `pageID`, starting at `pageOff` and with the contents of `buf`.
 	Merge(inode int64, pageID, pageOff uint32, buf []byte) error
 
+	// Underlay is like Merge, but `buf` is data of the underlying stream.
+	// Parts of the page that were already written to are not changed.
+	Underlay(inode int64, pageID, pageOff uint32, buf []byte) error
+
 	// Evict clears cached pages for `inode`. `size` can be used
 	// to clear only up to a certain size.
 	Evict(inode, size int64) error
 
+	// EvictRange clears the cached pages of `inode` that are
+	// touched by the range starting at `off` with `size` bytes.
+	EvictRange(inode, off, size int64) error
+
 	// Close the cache and free up all resources.
 	Close() error
 }
@@ -145,19 +145,55 @@ func (dc *MDCache) Merge(inode int64, pageIdx, off uint32, write []byte) error {
 	return dc.l1.Set(pk, p)
 }
 
+// Underlay implements pagecache.Cache
+func (dc *MDCache) Underlay(inode int64, pageIdx, off uint32, write []byte) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if len(write) == 0 {
+		return nil
+	}
+
+	if off+uint32(len(write)) > page.Size {
+		return fmt.Errorf("underlay: write overflows page bounds")
+	}
+
+	pk := pageKey{inode: inode, pageIdx: pageIdx}
+	p, err := dc.get(pk)
+	if err != nil && err != page.ErrCacheMiss {
+		return err
+	}
+
+	if p == nil {
+		// Nothing was written to this page yet,
+		// so there is nothing we could hide.
+		p = page.New(off, write)
+	} else {
+		p.Underlay(off, write)
+	}
+
+	return dc.l1.Set(pk, p)
+}
+
 // Evict implements pagecache.Cache
 func (dc *MDCache) Evict(inode, size int64) error {
+	return dc.EvictRange(inode, 0, size)
+}
+
+// EvictRange implements pagecache.Cache
+func (dc *MDCache) EvictRange(inode, off, size int64) error {
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 
-	// Figure out all possible indices from size:
+	// Figure out all possible indices from the range:
 	pks := []pageKey{}
-	pageHi := uint32(size / page.Size)
-	if size%page.Size > 0 {
+	pageLo := uint32(off / page.Size)
+	pageHi := uint32((off + size) / page.Size)
+	if (off+size)%page.Size > 0 {
 		pageHi++
 	}
 
-	for pageIdx := uint32(0); pageIdx < pageHi; pageIdx++ {
+	for pageIdx := pageLo; pageIdx < pageHi; pageIdx++ {
 		pks = append(pks, pageKey{inode: inode, pageIdx: pageIdx})
 	}
 
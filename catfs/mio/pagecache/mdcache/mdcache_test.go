@@ -49,3 +49,33 @@ func TestMDBasic(t *testing.T) {
 		require.NoError(t, mdc.Evict(1, 8*page.Size))
 	})
 }
+
+func TestMDUnderlayAndEvictRange(t *testing.T) {
+	withMDCache(t, func(mdc *MDCache) {
+		write := []byte{1, 2, 3}
+		require.NoError(t, mdc.Merge(1, 0, 10, write))
+
+		// Underlaying must not hide the write:
+		data := make([]byte, page.Size)
+		for idx := 0; idx < 4; idx++ {
+			require.NoError(t, mdc.Underlay(1, uint32(idx), 0, data))
+		}
+
+		p, err := mdc.Lookup(1, 0)
+		require.NoError(t, err)
+		require.Equal(t, write, p.Data[10:13])
+		require.Equal(t, byte(0), p.Data[13])
+		require.True(t, p.OccludesStream(0, page.Size))
+
+		// Only the pages in the range go:
+		require.NoError(t, mdc.EvictRange(1, page.Size+1, page.Size))
+		for idx, isCached := range []bool{true, false, false, true} {
+			_, err := mdc.Lookup(1, uint32(idx))
+			if isCached {
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, page.ErrCacheMiss, err)
+			}
+		}
+	})
+}
@@ -143,6 +143,46 @@ func (l *Layer) WriteAt(buf []byte, off int64) (n int, err error) {
 	return len(buf), nil
 }
 
+// Underlay caches `buf`, which is the content of the underlying stream
+// starting at `off`. Reads of this range are then served from the cache
+// without touching the stream. Data that was written to the layer before
+// or after stays visible.
+func (l *Layer) Underlay(off int64, buf []byte) error {
+	// Data beyond the stream size is not part of the stream.
+	if off >= l.size {
+		return nil
+	}
+
+	if rest := l.size - off; int64(len(buf)) > rest {
+		buf = buf[:rest]
+	}
+
+	pageOff := off % page.Size
+	for pageIdx := off / page.Size; len(buf) > 0; pageIdx++ {
+		mayWrite := util.Min64(page.Size-pageOff, int64(len(buf)))
+		if err := l.cache.Underlay(
+			l.inode,
+			uint32(pageIdx),
+			uint32(pageOff),
+			buf[:mayWrite],
+		); err != nil {
+			return err
+		}
+
+		pageOff = 0
+		buf = buf[mayWrite:]
+	}
+
+	return nil
+}
+
+// EvictRange drops the cached pages touched by the range at `off` with
+// `size` bytes. Writes to those pages are lost, so this should be only
+// used for pages that were filled by Underlay().
+func (l *Layer) EvictRange(off, size int64) error {
+	return l.cache.EvictRange(l.inode, off, size)
+}
+
 var (
 	copyBufPool = &sync.Pool{
 		New: func() interface{} {
@@ -293,3 +293,47 @@ func TestReadRandomOffset(t *testing.T) {
 		})
 	}
 }
+
+type failingReadSeeker struct{}
+
+func (failingReadSeeker) Read(buf []byte) (int, error) {
+	return 0, fmt.Errorf("stream should not be read")
+}
+
+func (failingReadSeeker) Seek(off int64, whence int) (int64, error) {
+	return 0, fmt.Errorf("stream should not be seeked")
+}
+
+func TestUnderlay(t *testing.T) {
+	for _, testSize := range testSizes {
+		if testSize == 0 {
+			continue
+		}
+
+		t.Run(fmt.Sprintf("%d", testSize), func(t *testing.T) {
+			withLayer(t, testSize, func(expected []byte, p *Layer) {
+				write := []byte{0xFF}
+				_, err := p.WriteAt(write, testSize/2)
+				require.NoError(t, err)
+				expected[testSize/2] = write[0]
+
+				// Underlay everything with the original data; the
+				// write has to stay visible. Afterwards the stream
+				// is not needed anymore.
+				require.NoError(t, p.Underlay(0, testutil.CreateDummyBuf(testSize)))
+				p.rs = failingReadSeeker{}
+
+				got := make([]byte, testSize)
+				n, err := p.ReadAt(got, 0)
+				require.NoError(t, err)
+				require.Equal(t, int(testSize), n)
+				require.Equal(t, expected, got)
+
+				// Evicting the pages makes reads go to the stream again:
+				require.NoError(t, p.EvictRange(0, testSize))
+				_, err = p.ReadAt(got, 0)
+				require.Error(t, err)
+			})
+		})
+	}
+}
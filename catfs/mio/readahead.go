@@ -0,0 +1,374 @@
+package mio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrReadAheadClosed is returned when using a closed ReadAhead.
+	ErrReadAheadClosed = errors.New("read-ahead is closed")
+)
+
+// ReadAheadOptions configure the behavior of NewReadAhead.
+type ReadAheadOptions struct {
+	// BlockSize is the number of bytes that are fetched as one unit.
+	// It should be a multiple of the encryption block size (64k), so
+	// that blocks can be decrypted independently without re-reads.
+	// This is also the page size of the pagecache.
+	BlockSize int64
+
+	// MaxWindow is the number of bytes that may be prefetched ahead of the
+	// current read position. The actual window starts small and doubles
+	// with every sequential read until it reaches this size.
+	MaxWindow int64
+
+	// Workers is the number of blocks that are fetched in parallel.
+	// Every worker uses a separate stream.
+	Workers int
+}
+
+// DefaultReadAheadOptions returns sane options for most cases.
+func DefaultReadAheadOptions() ReadAheadOptions {
+	return ReadAheadOptions{
+		BlockSize: 256 * 1024,
+		MaxWindow: 8 * 1024 * 1024,
+		Workers:   4,
+	}
+}
+
+// StreamOpener opens a new stream of the same content that can be used
+// independently of all other streams opened by it.
+type StreamOpener func() (Stream, error)
+
+// BlockSink is where ReadAhead puts the blocks it prefetched.
+// Usually this is the pagecache, where the actual reads are served from.
+type BlockSink interface {
+	// Fill is called with `data`, the content of the stream at `off`.
+	Fill(off int64, data []byte) error
+
+	// Forget is called for a range that was passed to Fill before,
+	// once it is not needed anymore.
+	Forget(off, size int64) error
+}
+
+// idleStream is a worker stream that is currently not in use.
+// Seeking can be expensive, so we remember where it stopped.
+type idleStream struct {
+	stream Stream
+	off    int64
+}
+
+// readAheadBlock is a single block that is fetched in the background.
+type readAheadBlock struct {
+	done chan struct{}
+	data []byte
+	err  error
+
+	// filled is true once the block was passed to the sink.
+	filled bool
+}
+
+// ReadAhead detects sequential reads of a stream and prefetches the
+// upcoming blocks in parallel, before they are actually read. This helps to
+// hide the latency of the backend. It does not serve any reads itself; the
+// blocks are handed to a BlockSink instead. Random access is left alone.
+//
+// ReadAhead is not safe for concurrent use.
+type ReadAhead struct {
+	open StreamOpener
+	size int64
+	sink BlockSink
+	opts ReadAheadOptions
+
+	// lastEnd is where the last read stopped.
+	// A read starting there is considered sequential.
+	lastEnd int64
+
+	// window is the number of bytes that are currently prefetched.
+	window int64
+
+	// blocks that are fetched, being fetched or filled, by block index.
+	blocks map[int64]*readAheadBlock
+
+	// workerSem limits the number of parallel fetches.
+	workerSem chan struct{}
+
+	// mu protects the attributes below; they are shared with the workers.
+	mu       sync.Mutex
+	idle     []idleStream
+	isClosed bool
+}
+
+// NewReadAhead returns a ReadAhead for the content of the streams returned
+// by `open`. `size` is the number of bytes that can be read from them.
+// Prefetched blocks are passed to `sink`. The memory used by this is bounded
+// by `opts.MaxWindow` plus one block; blocks are only kept until they were
+// passed to the sink.
+func NewReadAhead(open StreamOpener, size int64, sink BlockSink, opts ReadAheadOptions) (*ReadAhead, error) {
+	if opts.BlockSize <= 0 || opts.Workers <= 0 {
+		return nil, fmt.Errorf("read-ahead: invalid options: %+v", opts)
+	}
+
+	return &ReadAhead{
+		open:      open,
+		size:      size,
+		sink:      sink,
+		opts:      opts,
+		blocks:    make(map[int64]*readAheadBlock),
+		workerSem: make(chan struct{}, opts.Workers),
+	}, nil
+}
+
+func (ra *ReadAhead) blockRange(blockIdx int64) (int64, int64) {
+	start := blockIdx * ra.opts.BlockSize
+	end := start + ra.opts.BlockSize
+	if end > ra.size {
+		end = ra.size
+	}
+
+	return start, end
+}
+
+// acquireStream returns an idle stream, preferably one that stopped at `off`.
+// If there is none, a new one is opened.
+func (ra *ReadAhead) acquireStream(off int64) (idleStream, error) {
+	ra.mu.Lock()
+	if len(ra.idle) > 0 {
+		pick := len(ra.idle) - 1
+		for idx, idle := range ra.idle {
+			if idle.off == off {
+				pick = idx
+				break
+			}
+		}
+
+		idle := ra.idle[pick]
+		ra.idle = append(ra.idle[:pick], ra.idle[pick+1:]...)
+		ra.mu.Unlock()
+		return idle, nil
+	}
+	ra.mu.Unlock()
+
+	stream, err := ra.open()
+	return idleStream{stream: stream}, err
+}
+
+func (ra *ReadAhead) releaseStream(idle idleStream) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if ra.isClosed {
+		idle.stream.Close()
+		return
+	}
+
+	ra.idle = append(ra.idle, idle)
+}
+
+func (ra *ReadAhead) fetchBlock(blockIdx int64, block *readAheadBlock) {
+	defer close(block.done)
+
+	ra.workerSem <- struct{}{}
+	defer func() { <-ra.workerSem }()
+
+	ra.mu.Lock()
+	isClosed := ra.isClosed
+	ra.mu.Unlock()
+
+	if isClosed {
+		block.err = ErrReadAheadClosed
+		return
+	}
+
+	start, end := ra.blockRange(blockIdx)
+	idle, err := ra.acquireStream(start)
+	if err != nil {
+		block.err = err
+		return
+	}
+
+	if idle.off != start {
+		if _, err := idle.stream.Seek(start, io.SeekStart); err != nil {
+			idle.stream.Close()
+			block.err = err
+			return
+		}
+	}
+
+	block.data = make([]byte, end-start)
+	if _, err := io.ReadFull(idle.stream, block.data); err != nil {
+		// Do not reuse streams in an unknown state.
+		idle.stream.Close()
+		block.data = nil
+		block.err = err
+		return
+	}
+
+	idle.off = end
+	ra.releaseStream(idle)
+}
+
+// forget drops all blocks that are not touched by the range [lo, hi).
+// Blocks that are still being fetched finish in the background.
+func (ra *ReadAhead) forget(lo, hi int64) error {
+	var lastErr error
+	for blockIdx, block := range ra.blocks {
+		start, end := ra.blockRange(blockIdx)
+		if end > lo && start < hi {
+			continue
+		}
+
+		if block.filled {
+			if err := ra.sink.Forget(start, end-start); err != nil {
+				lastErr = err
+			}
+		}
+
+		delete(ra.blocks, blockIdx)
+	}
+
+	return lastErr
+}
+
+// schedule makes sure that the blocks touched by [lo, hi) are fetched.
+func (ra *ReadAhead) schedule(lo, hi int64) {
+	for blockIdx := lo / ra.opts.BlockSize; ; blockIdx++ {
+		if start, _ := ra.blockRange(blockIdx); start >= hi || start >= ra.size {
+			break
+		}
+
+		if _, ok := ra.blocks[blockIdx]; ok {
+			continue
+		}
+
+		block := &readAheadBlock{done: make(chan struct{})}
+		ra.blocks[blockIdx] = block
+		go ra.fetchBlock(blockIdx, block)
+	}
+}
+
+// fill passes a fetched block to the sink.
+func (ra *ReadAhead) fill(blockIdx int64, block *readAheadBlock) error {
+	if block.err != nil {
+		// The reader will go to the stream itself; no need to fail.
+		log.Debugf("read-ahead: block %d failed: %v", blockIdx, block.err)
+		delete(ra.blocks, blockIdx)
+		return nil
+	}
+
+	start, _ := ra.blockRange(blockIdx)
+	if err := ra.sink.Fill(start, block.data); err != nil {
+		delete(ra.blocks, blockIdx)
+		return err
+	}
+
+	block.data = nil
+	block.filled = true
+	return nil
+}
+
+// Access tells ReadAhead that `length` bytes at `off` are about to be read.
+// If the reads so far were sequential, the read-ahead window grows and the
+// blocks in it are fetched in the background. Once Access returns, all
+// blocks that were fetched (including the ones touching the requested range)
+// were passed to the sink. Blocks that were fetched too late are not waited
+// for; the reader has to go to the stream for them.
+func (ra *ReadAhead) Access(off, length int64) error {
+	ra.mu.Lock()
+	isClosed := ra.isClosed
+	ra.mu.Unlock()
+
+	if isClosed {
+		return ErrReadAheadClosed
+	}
+
+	if off >= ra.size {
+		return nil
+	}
+
+	if rest := ra.size - off; length > rest {
+		length = rest
+	}
+
+	if off == ra.lastEnd {
+		if ra.window == 0 {
+			ra.window = ra.opts.BlockSize
+		} else {
+			ra.window *= 2
+		}
+
+		if ra.window > ra.opts.MaxWindow {
+			ra.window = ra.opts.MaxWindow
+		}
+	} else {
+		// Random access; prefetching would only waste bandwidth.
+		ra.window = 0
+	}
+
+	ra.lastEnd = off + length
+	lo, hi := off, off+length+ra.window
+	if err := ra.forget(lo, hi); err != nil {
+		return err
+	}
+
+	if ra.window > 0 {
+		ra.schedule(lo, hi)
+	}
+
+	for blockIdx, block := range ra.blocks {
+		if block.filled {
+			continue
+		}
+
+		start, end := ra.blockRange(blockIdx)
+		if end > off && start < off+length {
+			<-block.done
+		} else {
+			select {
+			case <-block.done:
+			default:
+				continue
+			}
+		}
+
+		if err := ra.fill(blockIdx, block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close stops the read-ahead and frees all resources.
+// The blocks that were passed to the sink are forgotten.
+func (ra *ReadAhead) Close() error {
+	ra.mu.Lock()
+	if ra.isClosed {
+		ra.mu.Unlock()
+		return nil
+	}
+
+	ra.isClosed = true
+	idle := ra.idle
+	ra.idle = nil
+	ra.mu.Unlock()
+
+	var lastErr error
+	for _, idle := range idle {
+		if err := idle.stream.Close(); err != nil {
+			lastErr = err
+		}
+	}
+
+	// Forget everything:
+	if err := ra.forget(0, 0); err != nil {
+		lastErr = err
+	}
+
+	return lastErr
+}
@@ -0,0 +1,226 @@
+package mio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sahib/brig/util/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// slowOpener opens streams over `data` that sleep on every read,
+// like a backend with high latency would do.
+type slowOpener struct {
+	data  []byte
+	delay time.Duration
+
+	reading  int32
+	maxReads int32
+}
+
+func (so *slowOpener) Open() (Stream, error) {
+	r := bytes.NewReader(so.data)
+	sr := &slowReader{r: r, so: so}
+	return stream{
+		Reader:   sr,
+		Seeker:   r,
+		WriterTo: dumbWriterTo{r: sr},
+		Closer:   ioutil.NopCloser(nil),
+	}, nil
+}
+
+type slowReader struct {
+	r  io.Reader
+	so *slowOpener
+}
+
+func (sr *slowReader) Read(buf []byte) (int, error) {
+	reading := atomic.AddInt32(&sr.so.reading, 1)
+	defer atomic.AddInt32(&sr.so.reading, -1)
+
+	for {
+		max := atomic.LoadInt32(&sr.so.maxReads)
+		if reading <= max || atomic.CompareAndSwapInt32(&sr.so.maxReads, max, reading) {
+			break
+		}
+	}
+
+	time.Sleep(sr.so.delay)
+	return sr.r.Read(buf)
+}
+
+// memorySink remembers what was filled in and not forgotten yet.
+type memorySink struct {
+	blocks map[int64][]byte
+
+	// maxBytes is the max. number of bytes that were held at once.
+	maxBytes int64
+}
+
+func newMemorySink() *memorySink {
+	return &memorySink{blocks: make(map[int64][]byte)}
+}
+
+func (ms *memorySink) Fill(off int64, data []byte) error {
+	ms.blocks[off] = append([]byte{}, data...)
+	if held := ms.heldBytes(); held > ms.maxBytes {
+		ms.maxBytes = held
+	}
+
+	return nil
+}
+
+func (ms *memorySink) Forget(off, size int64) error {
+	if int64(len(ms.blocks[off])) != size {
+		return errors.New("forgot a block that was not filled")
+	}
+
+	delete(ms.blocks, off)
+	return nil
+}
+
+func (ms *memorySink) heldBytes() int64 {
+	held := int64(0)
+	for _, data := range ms.blocks {
+		held += int64(len(data))
+	}
+
+	return held
+}
+
+// readAt reads like a pagecache on top of the stream would:
+// filled blocks are used, everything else comes from `data`.
+func (ms *memorySink) readAt(data []byte, buf []byte, off int64) (fromSink int) {
+	for idx := range buf {
+		pos := off + int64(idx)
+		blockOff := pos - pos%4096
+		if block, ok := ms.blocks[blockOff]; ok {
+			buf[idx] = block[pos-blockOff]
+			fromSink++
+			continue
+		}
+
+		buf[idx] = data[pos]
+	}
+
+	return fromSink
+}
+
+func testReadAheadOpts() ReadAheadOptions {
+	return ReadAheadOptions{
+		BlockSize: 4096,
+		MaxWindow: 4 * 4096,
+		Workers:   4,
+	}
+}
+
+func TestReadAheadSequential(t *testing.T) {
+	for _, size := range []int{0, 1, 4095, 4096, 4097, 64*1024 + 17} {
+		data := testutil.CreateDummyBuf(int64(size))
+		so := &slowOpener{data: data}
+		sink := newMemorySink()
+
+		ra, err := NewReadAhead(so.Open, int64(len(data)), sink, testReadAheadOpts())
+		require.NoError(t, err)
+
+		// Use an odd buffer size to not be aligned to the blocks:
+		out := &bytes.Buffer{}
+		fromSink := 0
+		for off := 0; off < size; off += 1000 {
+			buf := make([]byte, 1000)
+			if rest := size - off; rest < len(buf) {
+				buf = buf[:rest]
+			}
+
+			require.NoError(t, ra.Access(int64(off), int64(len(buf))))
+			fromSink += sink.readAt(data, buf, int64(off))
+			out.Write(buf)
+		}
+
+		require.True(t, bytes.Equal(data, out.Bytes()), "size %d", size)
+
+		// Everything the reader needed was waited for:
+		require.Equal(t, size, fromSink, "size %d", size)
+
+		// Blocks that were passed are forgotten:
+		require.True(t, sink.maxBytes <= 6*4096, "sink held too much: %d", sink.maxBytes)
+
+		require.NoError(t, ra.Close())
+		require.Empty(t, sink.blocks)
+		require.Equal(t, ErrReadAheadClosed, ra.Access(0, 1))
+	}
+}
+
+func TestReadAheadParallel(t *testing.T) {
+	data := testutil.CreateDummyBuf(64 * 4096)
+	so := &slowOpener{data: data, delay: 5 * time.Millisecond}
+	sink := newMemorySink()
+
+	ra, err := NewReadAhead(so.Open, int64(len(data)), sink, testReadAheadOpts())
+	require.NoError(t, err)
+
+	buf := make([]byte, 4096)
+	for off := int64(0); off < int64(len(data)); off += int64(len(buf)) {
+		require.NoError(t, ra.Access(off, int64(len(buf))))
+		require.Equal(t, len(buf), sink.readAt(data, buf, off))
+		require.Equal(t, data[off:off+int64(len(buf))], buf)
+	}
+
+	require.NoError(t, ra.Close())
+
+	// Several blocks should have been fetched at the same time,
+	// but never more than there are workers.
+	maxReads := atomic.LoadInt32(&so.maxReads)
+	require.True(t, maxReads > 1, "no parallel reads happened")
+	require.True(t, maxReads <= 4, "too many parallel reads: %d", maxReads)
+}
+
+func TestReadAheadRandomAccess(t *testing.T) {
+	data := testutil.CreateDummyBuf(16 * 4096)
+	so := &slowOpener{data: data}
+	sink := newMemorySink()
+
+	ra, err := NewReadAhead(so.Open, int64(len(data)), sink, testReadAheadOpts())
+	require.NoError(t, err)
+
+	// Nothing should be prefetched for random reads:
+	for _, off := range []int64{3000, 50000, 10, 65535, 4096, 7000} {
+		require.NoError(t, ra.Access(off, 3000))
+		require.Empty(t, sink.blocks)
+	}
+
+	// ...until it gets sequential again:
+	require.NoError(t, ra.Access(10000, 3000))
+	buf := make([]byte, 3000)
+	require.Equal(t, len(buf), sink.readAt(data, buf, 10000))
+	require.Equal(t, data[10000:13000], buf)
+
+	// Jumping back forgets the blocks:
+	require.NoError(t, ra.Access(0, 10))
+	require.Empty(t, sink.blocks)
+	require.NoError(t, ra.Close())
+}
+
+func TestReadAheadFailingBlocks(t *testing.T) {
+	data := testutil.CreateDummyBuf(8 * 4096)
+	open := func() (Stream, error) {
+		return nil, errors.New("backend is down")
+	}
+
+	sink := newMemorySink()
+	ra, err := NewReadAhead(open, int64(len(data)), sink, testReadAheadOpts())
+	require.NoError(t, err)
+
+	// The reader has to go to the stream itself then:
+	for off := int64(0); off < int64(len(data)); off += 1000 {
+		require.NoError(t, ra.Access(off, 1000))
+		require.Empty(t, sink.blocks)
+	}
+
+	require.NoError(t, ra.Close())
+}
@@ -39,6 +39,12 @@ func (d dumbWriterTo) WriteTo(w io.Writer) (n int64, err error) {
 // `key` is used to decrypt the data. The compression algorithm is read
 // from the stream header.
 func NewOutStream(r io.ReadSeeker, isRaw bool, key []byte) (Stream, error) {
+	return NewOutStreamWithDicts(r, isRaw, key, nil)
+}
+
+// NewOutStreamWithDicts works like NewOutStream, but can also decode streams
+// that were compressed with a zstd dictionary. `lookup` is used to find it.
+func NewOutStreamWithDicts(r io.ReadSeeker, isRaw bool, key []byte, lookup compress.DictLookup) (Stream, error) {
 	s := stream{
 		Reader:   r,
 		Seeker:   r,
@@ -109,24 +115,27 @@ func NewOutStream(r io.ReadSeeker, isRaw bool, key []byte) (Stream, error) {
 
 	// if compression is used inside, than wrap in decompressor:
 	// (s might contain decryptor or is raw stream)
-	rZip := compress.NewReader(s)
+	rZip := compress.NewReaderWithDicts(s, lookup)
 	s.Reader = rZip
 	s.Seeker = rZip
 	s.WriterTo = rZip
 	return s, nil
 }
 
-func guessCompression(path string, r io.Reader, hint *hints.Hint) (io.Reader, error) {
-	// Keep the header of the file in memory, so we can do some guessing
-	// of e.g. the compression algorithm we should use.
-	headerReader := util.NewHeaderReader(r, 2048)
+// guessCompression samples the start of the stream and replaces the
+// "guess" compression of `hint` by the most suitable algorithm.
+// It returns the level to use with the algorithm.
+func guessCompression(path string, r io.Reader, hint *hints.Hint) (io.Reader, compress.Level, error) {
+	// Keep the start of the file in memory, so we can
+	// try how well the different algorithms do on it.
+	headerReader := util.NewHeaderReader(r, compress.SampleSize)
 	headerBuf, err := headerReader.Peek()
 	if err != nil {
 		log.WithError(err).Warnf("failed to peek stream header")
-		return nil, err
+		return nil, compress.LevelDefault, err
 	}
 
-	compressAlgo, err := compress.GuessAlgorithm(path, headerBuf)
+	choice, ratio, err := compress.SampleAlgorithm(headerBuf)
 	if err != nil {
 		// NOTE: don't error out here. That just means we don't
 		// guessed the perfect settings.
@@ -135,29 +144,58 @@ func guessCompression(path string, r io.Reader, hint *hints.Hint) (io.Reader, er
 			Warnf("failed to guess suitable zip algorithm")
 	}
 
-	log.Debugf("guessed '%s' compression for file %s", compressAlgo, path)
-	hint.CompressionAlgo = hints.CompressAlgorithmTypeToCompressionHint(compressAlgo)
-	return headerReader, nil
+	log.Debugf("guessed '%s' compression for file %s (ratio %.2f)", choice, path, ratio)
+	hint.CompressionAlgo = hints.CompressAlgorithmTypeToCompressionHint(choice.Algo)
+	return headerReader, choice.Level, nil
+}
+
+// Encoding describes how NewInStreamWithDict encodes a stream.
+type Encoding struct {
+	// IsRaw is true if the stream is neither compressed nor encrypted.
+	IsRaw bool
+
+	// Compression is the used compression, e.g. "zstd-fastest" or "none".
+	// If the hint said "guess", this is the guessed compression.
+	Compression string
 }
 
 // NewInStream creates a new stream that pipes data into ipfs.
 // The data is read from `r`, encrypted with `key` and encoded based on the
-// settings given by `hint`. `path` is only used to better guess the compression
-// algorithm - if desired by `hint`. `path` can be empty.
+// settings given by `hint`. `path` is only used for log messages when the
+// compression is guessed. `path` can be empty.
 //
 // It returns a reader that will produce the encoded stream.
 // If no actual encoding will be done, the second return param will be true
 func NewInStream(r io.Reader, path string, key []byte, hint hints.Hint) (io.ReadCloser, bool, error) {
+	stream, enc, err := NewInStreamWithDict(r, path, key, hint, nil)
+	return stream, enc.IsRaw, err
+}
+
+// NewInStreamWithDict works like NewInStream, but uses `dict` to compress
+// the data if the hint resolves to zstd compression. `dict` may be nil.
+// It also returns the encoding that is used for the stream.
+func NewInStreamWithDict(r io.Reader, path string, key []byte, hint hints.Hint, dict *compress.Dict) (io.ReadCloser, Encoding, error) {
 	var err error
 
+	level := compress.LevelDefault
 	if hint.CompressionAlgo == hints.CompressionGuess {
 		// replace "guess" to an actual compression algorithm.
-		r, err = guessCompression(path, r, &hint)
+		r, level, err = guessCompression(path, r, &hint)
 		if err != nil {
-			return nil, false, err
+			return nil, Encoding{}, err
 		}
 	}
 
+	zipAlgo := hint.CompressionAlgo.ToCompressAlgorithmType()
+	if zipAlgo != compress.AlgoZstd {
+		dict = nil
+	}
+
+	enc := Encoding{
+		IsRaw:       hint.IsRaw(),
+		Compression: compress.Choice{Algo: zipAlgo, Level: level}.String(),
+	}
+
 	// use a pipe to redirect `r` to encoding writers without copying:
 	pr, pw := io.Pipe()
 
@@ -170,18 +208,25 @@ func NewInStream(r io.Reader, path string, key []byte, hint hints.Hint) (io.Read
 	if hint.EncryptionAlgo != hints.EncryptionNone {
 		wEnc, err := encrypt.NewWriter(w, key, hint.EncryptFlags())
 		if err != nil {
-			return nil, false, err
+			return nil, Encoding{}, err
 		}
 
 		closers = append(closers, wEnc)
 		w = wEnc
 	}
 
-	// Only add compression if desired or mime type is suitable:
+	// Only add compression if the hint asks for it. A "guess" hint was
+	// already resolved above by sampling the start of the stream:
 	if hint.CompressionAlgo != hints.CompressionNone {
-		wZip, err := compress.NewWriter(w, hint.CompressionAlgo.ToCompressAlgorithmType())
+		var wZip *compress.Writer
+		if dict != nil {
+			wZip, err = compress.NewWriterWithDict(w, zipAlgo, dict)
+		} else {
+			wZip, err = compress.NewWriterLevel(w, zipAlgo, level)
+		}
+
 		if err != nil {
-			return nil, false, err
+			return nil, Encoding{}, err
 		}
 
 		closers = append(closers, wZip)
@@ -205,7 +250,7 @@ func NewInStream(r io.Reader, path string, key []byte, hint hints.Hint) (io.Read
 		}
 	}()
 
-	return pr, hint.IsRaw(), nil
+	return pr, enc, nil
 }
 
 // limitedStream is a small wrapper around Stream,
@@ -1,7 +1,9 @@
 package nodes
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -38,6 +40,9 @@ type Base struct {
 
 	// Unique identifier for this node
 	inode uint64
+
+	// User defined key/value attributes (might be nil)
+	attrs map[string][]byte
 }
 
 // copyBase will copy all attributes from the base.
@@ -51,7 +56,21 @@ func (b *Base) copyBase(inode uint64) Base {
 		modTime:  b.modTime,
 		nodeType: b.nodeType,
 		inode:    inode,
+		attrs:    copyAttributes(b.attrs),
+	}
+}
+
+func copyAttributes(attrs map[string][]byte) map[string][]byte {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	copied := make(map[string][]byte, len(attrs))
+	for key, value := range attrs {
+		copied[key] = append([]byte{}, value...)
 	}
+
+	return copied
 }
 
 // User returns the user that last modified this node.
@@ -96,6 +115,41 @@ func (b *Base) Inode() uint64 {
 	return b.inode
 }
 
+// Attributes returns a copy of the user defined attributes of this node.
+func (b *Base) Attributes() map[string][]byte {
+	attrs := copyAttributes(b.attrs)
+	if attrs == nil {
+		return map[string][]byte{}
+	}
+
+	return attrs
+}
+
+// attrsDigest returns a string that changes whenever the attributes change.
+// It is empty if there are no attributes, so that nodes without attributes
+// keep the same tree hash as before.
+func (b *Base) attrsDigest() string {
+	if len(b.attrs) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(b.attrs))
+	for key := range b.attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	for _, key := range keys {
+		value := b.attrs[key]
+		fmt.Fprintf(buf, "%d:%s%d:", len(key), key, len(value))
+		buf.Write(value)
+	}
+
+	return h.Sum(buf.Bytes()).B58String()
+}
+
 /////// UTILS /////////
 
 func (b *Base) setBaseAttrsToNode(capnode capnp_model.Node) error {
@@ -124,6 +178,70 @@ func (b *Base) setBaseAttrsToNode(capnode capnp_model.Node) error {
 	}
 
 	capnode.SetInode(b.inode)
+	return b.setAttributesToNode(capnode)
+}
+
+func (b *Base) setAttributesToNode(capnode capnp_model.Node) error {
+	if len(b.attrs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(b.attrs))
+	for key := range b.attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	capAttrs, err := capnode.NewAttributes(int32(len(keys)))
+	if err != nil {
+		return err
+	}
+
+	for idx, key := range keys {
+		capAttr := capAttrs.At(idx)
+		if err := capAttr.SetKey(key); err != nil {
+			return err
+		}
+
+		if err := capAttr.SetValue(b.attrs[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Base) parseAttributesFromNode(capnode capnp_model.Node) error {
+	b.attrs = nil
+	if !capnode.HasAttributes() {
+		return nil
+	}
+
+	capAttrs, err := capnode.Attributes()
+	if err != nil {
+		return err
+	}
+
+	for idx := 0; idx < capAttrs.Len(); idx++ {
+		capAttr := capAttrs.At(idx)
+		key, err := capAttr.Key()
+		if err != nil {
+			return err
+		}
+
+		value, err := capAttr.Value()
+		if err != nil {
+			return err
+		}
+
+		if b.attrs == nil {
+			b.attrs = make(map[string][]byte)
+		}
+
+		b.attrs[key] = append([]byte{}, value...)
+	}
+
 	return nil
 }
 
@@ -178,7 +296,7 @@ func (b *Base) parseBaseAttrsFromNode(capnode capnp_model.Node) error {
 	}
 
 	b.inode = capnode.Inode()
-	return nil
+	return b.parseAttributesFromNode(capnode)
 }
 
 func prefixSlash(s string) string {
@@ -303,6 +421,23 @@ func ParentDirectory(lkr Linker, nd Node) (*Directory, error) {
 	return parDir, nil
 }
 
+// AttributesEqual returns true if `a` and `b` have the same user attributes.
+func AttributesEqual(a, b Node) bool {
+	aAttrs, bAttrs := a.Attributes(), b.Attributes()
+	if len(aAttrs) != len(bAttrs) {
+		return false
+	}
+
+	for key, aValue := range aAttrs {
+		bValue, ok := bAttrs[key]
+		if !ok || !bytes.Equal(aValue, bValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // ContentHash returns the correct content hash for `nd`.
 // This also works for ghosts where the content hash is taken from the
 // underlying node (ghosts themselve have no content).
@@ -266,6 +266,346 @@ func (p DirEntry_Promise) Struct() (DirEntry, error) {
 	return DirEntry{s}, err
 }
 
+// Reference to a shard of a huge directory
+type DirShardRef struct{ capnp.Struct }
+
+// DirShardRef_TypeID is the unique identifier for the type DirShardRef.
+const DirShardRef_TypeID = 0xe4df85cb4e227fe7
+
+func NewDirShardRef(s *capnp.Segment) (DirShardRef, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 3})
+	return DirShardRef{st}, err
+}
+
+func NewRootDirShardRef(s *capnp.Segment) (DirShardRef, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 3})
+	return DirShardRef{st}, err
+}
+
+func ReadRootDirShardRef(msg *capnp.Message) (DirShardRef, error) {
+	root, err := msg.RootPtr()
+	return DirShardRef{root.Struct()}, err
+}
+
+func (s DirShardRef) String() string {
+	str, _ := text.Marshal(0xe4df85cb4e227fe7, s.Struct)
+	return str
+}
+
+func (s DirShardRef) Key() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return []byte(p.Data()), err
+}
+
+func (s DirShardRef) HasKey() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShardRef) SetKey(v []byte) error {
+	return s.Struct.SetData(0, v)
+}
+
+func (s DirShardRef) Tree() ([]byte, error) {
+	p, err := s.Struct.Ptr(1)
+	return []byte(p.Data()), err
+}
+
+func (s DirShardRef) HasTree() bool {
+	p, err := s.Struct.Ptr(1)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShardRef) SetTree(v []byte) error {
+	return s.Struct.SetData(1, v)
+}
+
+func (s DirShardRef) Content() ([]byte, error) {
+	p, err := s.Struct.Ptr(2)
+	return []byte(p.Data()), err
+}
+
+func (s DirShardRef) HasContent() bool {
+	p, err := s.Struct.Ptr(2)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShardRef) SetContent(v []byte) error {
+	return s.Struct.SetData(2, v)
+}
+
+func (s DirShardRef) Count() uint64 {
+	return s.Struct.Uint64(0)
+}
+
+func (s DirShardRef) SetCount(v uint64) {
+	s.Struct.SetUint64(0, v)
+}
+
+// DirShardRef_List is a list of DirShardRef.
+type DirShardRef_List struct{ capnp.List }
+
+// NewDirShardRef creates a new list of DirShardRef.
+func NewDirShardRef_List(s *capnp.Segment, sz int32) (DirShardRef_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 3}, sz)
+	return DirShardRef_List{l}, err
+}
+
+func (s DirShardRef_List) At(i int) DirShardRef { return DirShardRef{s.List.Struct(i)} }
+
+func (s DirShardRef_List) Set(i int, v DirShardRef) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s DirShardRef_List) String() string {
+	str, _ := text.MarshalList(0xe4df85cb4e227fe7, s.List)
+	return str
+}
+
+// DirShardRef_Promise is a wrapper for a DirShardRef promised by a client call.
+type DirShardRef_Promise struct{ *capnp.Pipeline }
+
+func (p DirShardRef_Promise) Struct() (DirShardRef, error) {
+	s, err := p.Pipeline.Struct()
+	return DirShardRef{s}, err
+}
+
+// A single entry in a leaf shard
+type DirShardEntry struct{ capnp.Struct }
+
+// DirShardEntry_TypeID is the unique identifier for the type DirShardEntry.
+const DirShardEntry_TypeID = 0xffc9a4e3687580b7
+
+func NewDirShardEntry(s *capnp.Segment) (DirShardEntry, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 3})
+	return DirShardEntry{st}, err
+}
+
+func NewRootDirShardEntry(s *capnp.Segment) (DirShardEntry, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 3})
+	return DirShardEntry{st}, err
+}
+
+func ReadRootDirShardEntry(msg *capnp.Message) (DirShardEntry, error) {
+	root, err := msg.RootPtr()
+	return DirShardEntry{root.Struct()}, err
+}
+
+func (s DirShardEntry) String() string {
+	str, _ := text.Marshal(0xffc9a4e3687580b7, s.Struct)
+	return str
+}
+
+func (s DirShardEntry) Name() (string, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.Text(), err
+}
+
+func (s DirShardEntry) HasName() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShardEntry) NameBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s DirShardEntry) SetName(v string) error {
+	return s.Struct.SetText(0, v)
+}
+
+func (s DirShardEntry) Tree() ([]byte, error) {
+	p, err := s.Struct.Ptr(1)
+	return []byte(p.Data()), err
+}
+
+func (s DirShardEntry) HasTree() bool {
+	p, err := s.Struct.Ptr(1)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShardEntry) SetTree(v []byte) error {
+	return s.Struct.SetData(1, v)
+}
+
+func (s DirShardEntry) Content() ([]byte, error) {
+	p, err := s.Struct.Ptr(2)
+	return []byte(p.Data()), err
+}
+
+func (s DirShardEntry) HasContent() bool {
+	p, err := s.Struct.Ptr(2)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShardEntry) SetContent(v []byte) error {
+	return s.Struct.SetData(2, v)
+}
+
+// DirShardEntry_List is a list of DirShardEntry.
+type DirShardEntry_List struct{ capnp.List }
+
+// NewDirShardEntry creates a new list of DirShardEntry.
+func NewDirShardEntry_List(s *capnp.Segment, sz int32) (DirShardEntry_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 3}, sz)
+	return DirShardEntry_List{l}, err
+}
+
+func (s DirShardEntry_List) At(i int) DirShardEntry { return DirShardEntry{s.List.Struct(i)} }
+
+func (s DirShardEntry_List) Set(i int, v DirShardEntry) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s DirShardEntry_List) String() string {
+	str, _ := text.MarshalList(0xffc9a4e3687580b7, s.List)
+	return str
+}
+
+// DirShardEntry_Promise is a wrapper for a DirShardEntry promised by a client call.
+type DirShardEntry_Promise struct{ *capnp.Pipeline }
+
+func (p DirShardEntry_Promise) Struct() (DirShardEntry, error) {
+	s, err := p.Pipeline.Struct()
+	return DirShardEntry{s}, err
+}
+
+// A node in the hash trie of a sharded directory
+type DirShard struct{ capnp.Struct }
+type DirShard_Which uint16
+
+const (
+	DirShard_Which_leaf  DirShard_Which = 0
+	DirShard_Which_inner DirShard_Which = 1
+)
+
+func (w DirShard_Which) String() string {
+	const s = "leafinner"
+	switch w {
+	case DirShard_Which_leaf:
+		return s[0:4]
+	case DirShard_Which_inner:
+		return s[4:9]
+
+	}
+	return "DirShard_Which(" + strconv.FormatUint(uint64(w), 10) + ")"
+}
+
+// DirShard_TypeID is the unique identifier for the type DirShard.
+const DirShard_TypeID = 0x8c6c48f65f5b54e4
+
+func NewDirShard(s *capnp.Segment) (DirShard, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
+	return DirShard{st}, err
+}
+
+func NewRootDirShard(s *capnp.Segment) (DirShard, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
+	return DirShard{st}, err
+}
+
+func ReadRootDirShard(msg *capnp.Message) (DirShard, error) {
+	root, err := msg.RootPtr()
+	return DirShard{root.Struct()}, err
+}
+
+func (s DirShard) String() string {
+	str, _ := text.Marshal(0x8c6c48f65f5b54e4, s.Struct)
+	return str
+}
+
+func (s DirShard) Which() DirShard_Which {
+	return DirShard_Which(s.Struct.Uint16(0))
+}
+func (s DirShard) Leaf() (DirShardEntry_List, error) {
+	if s.Struct.Uint16(0) != 0 {
+		panic("Which() != leaf")
+	}
+	p, err := s.Struct.Ptr(0)
+	return DirShardEntry_List{List: p.List()}, err
+}
+
+func (s DirShard) HasLeaf() bool {
+	if s.Struct.Uint16(0) != 0 {
+		return false
+	}
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShard) SetLeaf(v DirShardEntry_List) error {
+	s.Struct.SetUint16(0, 0)
+	return s.Struct.SetPtr(0, v.List.ToPtr())
+}
+
+// NewLeaf sets the leaf field to a newly
+// allocated DirShardEntry_List, preferring placement in s's segment.
+func (s DirShard) NewLeaf(n int32) (DirShardEntry_List, error) {
+	s.Struct.SetUint16(0, 0)
+	l, err := NewDirShardEntry_List(s.Struct.Segment(), n)
+	if err != nil {
+		return DirShardEntry_List{}, err
+	}
+	err = s.Struct.SetPtr(0, l.List.ToPtr())
+	return l, err
+}
+
+func (s DirShard) Inner() (DirShardRef_List, error) {
+	if s.Struct.Uint16(0) != 1 {
+		panic("Which() != inner")
+	}
+	p, err := s.Struct.Ptr(0)
+	return DirShardRef_List{List: p.List()}, err
+}
+
+func (s DirShard) HasInner() bool {
+	if s.Struct.Uint16(0) != 1 {
+		return false
+	}
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s DirShard) SetInner(v DirShardRef_List) error {
+	s.Struct.SetUint16(0, 1)
+	return s.Struct.SetPtr(0, v.List.ToPtr())
+}
+
+// NewInner sets the inner field to a newly
+// allocated DirShardRef_List, preferring placement in s's segment.
+func (s DirShard) NewInner(n int32) (DirShardRef_List, error) {
+	s.Struct.SetUint16(0, 1)
+	l, err := NewDirShardRef_List(s.Struct.Segment(), n)
+	if err != nil {
+		return DirShardRef_List{}, err
+	}
+	err = s.Struct.SetPtr(0, l.List.ToPtr())
+	return l, err
+}
+
+// DirShard_List is a list of DirShard.
+type DirShard_List struct{ capnp.List }
+
+// NewDirShard creates a new list of DirShard.
+func NewDirShard_List(s *capnp.Segment, sz int32) (DirShard_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1}, sz)
+	return DirShard_List{l}, err
+}
+
+func (s DirShard_List) At(i int) DirShard { return DirShard{s.List.Struct(i)} }
+
+func (s DirShard_List) Set(i int, v DirShard) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s DirShard_List) String() string {
+	str, _ := text.MarshalList(0x8c6c48f65f5b54e4, s.List)
+	return str
+}
+
+// DirShard_Promise is a wrapper for a DirShard promised by a client call.
+type DirShard_Promise struct{ *capnp.Pipeline }
+
+func (p DirShard_Promise) Struct() (DirShard, error) {
+	s, err := p.Pipeline.Struct()
+	return DirShard{s}, err
+}
+
 // Directory contains one or more directories or files
 type Directory struct{ capnp.Struct }
 
@@ -273,12 +613,12 @@ type Directory struct{ capnp.Struct }
 const Directory_TypeID = 0xe24c59306c829c01
 
 func NewDirectory(s *capnp.Segment) (Directory, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 3})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 4})
 	return Directory{st}, err
 }
 
 func NewRootDirectory(s *capnp.Segment) (Directory, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 3})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 4})
 	return Directory{st}, err
 }
 
@@ -377,12 +717,37 @@ func (s Directory) NewContents(n int32) (DirEntry_List, error) {
 	return l, err
 }
 
+func (s Directory) Shard() (DirShardRef, error) {
+	p, err := s.Struct.Ptr(3)
+	return DirShardRef{Struct: p.Struct()}, err
+}
+
+func (s Directory) HasShard() bool {
+	p, err := s.Struct.Ptr(3)
+	return p.IsValid() || err != nil
+}
+
+func (s Directory) SetShard(v DirShardRef) error {
+	return s.Struct.SetPtr(3, v.Struct.ToPtr())
+}
+
+// NewShard sets the shard field to a newly
+// allocated DirShardRef struct, preferring placement in s's segment.
+func (s Directory) NewShard() (DirShardRef, error) {
+	ss, err := NewDirShardRef(s.Struct.Segment())
+	if err != nil {
+		return DirShardRef{}, err
+	}
+	err = s.Struct.SetPtr(3, ss.Struct.ToPtr())
+	return ss, err
+}
+
 // Directory_List is a list of Directory.
 type Directory_List struct{ capnp.List }
 
 // NewDirectory creates a new list of Directory.
 func NewDirectory_List(s *capnp.Segment, sz int32) (Directory_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 3}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 4}, sz)
 	return Directory_List{l}, err
 }
 
@@ -403,6 +768,10 @@ func (p Directory_Promise) Struct() (Directory, error) {
 	return Directory{s}, err
 }
 
+func (p Directory_Promise) Shard() DirShardRef_Promise {
+	return DirShardRef_Promise{Pipeline: p.Pipeline.GetPipeline(3)}
+}
+
 // A leaf node in the MDAG
 type File struct{ capnp.Struct }
 
@@ -410,12 +779,12 @@ type File struct{ capnp.Struct }
 const File_TypeID = 0x8ea7393d37893155
 
 func NewFile(s *capnp.Segment) (File, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 2})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 3})
 	return File{st}, err
 }
 
 func NewRootFile(s *capnp.Segment) (File, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 2})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 3})
 	return File{st}, err
 }
 
@@ -486,12 +855,31 @@ func (s File) SetIsRaw(v bool) {
 	s.Struct.SetBit(128, v)
 }
 
+func (s File) Compression() (string, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.Text(), err
+}
+
+func (s File) HasCompression() bool {
+	p, err := s.Struct.Ptr(2)
+	return p.IsValid() || err != nil
+}
+
+func (s File) CompressionBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.TextBytes(), err
+}
+
+func (s File) SetCompression(v string) error {
+	return s.Struct.SetText(2, v)
+}
+
 // File_List is a list of File.
 type File_List struct{ capnp.List }
 
 // NewFile creates a new list of File.
 func NewFile_List(s *capnp.Segment, sz int32) (File_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 24, PointerCount: 2}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 24, PointerCount: 3}, sz)
 	return File_List{l}, err
 }
 
@@ -540,12 +928,12 @@ func (w Ghost_Which) String() string {
 const Ghost_TypeID = 0x80c828d7e89c12ea
 
 func NewGhost(s *capnp.Segment) (Ghost, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 2})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 3})
 	return Ghost{st}, err
 }
 
 func NewRootGhost(s *capnp.Segment) (Ghost, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 2})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 3})
 	return Ghost{st}, err
 }
 
@@ -589,6 +977,25 @@ func (s Ghost) SetGhostPath(v string) error {
 	return s.Struct.SetText(0, v)
 }
 
+func (s Ghost) DeletedAt() (string, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.Text(), err
+}
+
+func (s Ghost) HasDeletedAt() bool {
+	p, err := s.Struct.Ptr(2)
+	return p.IsValid() || err != nil
+}
+
+func (s Ghost) DeletedAtBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.TextBytes(), err
+}
+
+func (s Ghost) SetDeletedAt(v string) error {
+	return s.Struct.SetText(2, v)
+}
+
 func (s Ghost) Commit() (Commit, error) {
 	if s.Struct.Uint16(8) != 0 {
 		panic("Which() != commit")
@@ -693,7 +1100,7 @@ type Ghost_List struct{ capnp.List }
 
 // NewGhost creates a new list of Ghost.
 func NewGhost_List(s *capnp.Segment, sz int32) (Ghost_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 2}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 3}, sz)
 	return Ghost_List{l}, err
 }
 
@@ -726,6 +1133,91 @@ func (p Ghost_Promise) File() File_Promise {
 	return File_Promise{Pipeline: p.Pipeline.GetPipeline(1)}
 }
 
+// A user defined key/value pair on a node
+type Attribute struct{ capnp.Struct }
+
+// Attribute_TypeID is the unique identifier for the type Attribute.
+const Attribute_TypeID = 0xd79f108fb9204dfd
+
+func NewAttribute(s *capnp.Segment) (Attribute, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return Attribute{st}, err
+}
+
+func NewRootAttribute(s *capnp.Segment) (Attribute, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return Attribute{st}, err
+}
+
+func ReadRootAttribute(msg *capnp.Message) (Attribute, error) {
+	root, err := msg.RootPtr()
+	return Attribute{root.Struct()}, err
+}
+
+func (s Attribute) String() string {
+	str, _ := text.Marshal(0xd79f108fb9204dfd, s.Struct)
+	return str
+}
+
+func (s Attribute) Key() (string, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.Text(), err
+}
+
+func (s Attribute) HasKey() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s Attribute) KeyBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s Attribute) SetKey(v string) error {
+	return s.Struct.SetText(0, v)
+}
+
+func (s Attribute) Value() ([]byte, error) {
+	p, err := s.Struct.Ptr(1)
+	return []byte(p.Data()), err
+}
+
+func (s Attribute) HasValue() bool {
+	p, err := s.Struct.Ptr(1)
+	return p.IsValid() || err != nil
+}
+
+func (s Attribute) SetValue(v []byte) error {
+	return s.Struct.SetData(1, v)
+}
+
+// Attribute_List is a list of Attribute.
+type Attribute_List struct{ capnp.List }
+
+// NewAttribute creates a new list of Attribute.
+func NewAttribute_List(s *capnp.Segment, sz int32) (Attribute_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2}, sz)
+	return Attribute_List{l}, err
+}
+
+func (s Attribute_List) At(i int) Attribute { return Attribute{s.List.Struct(i)} }
+
+func (s Attribute_List) Set(i int, v Attribute) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s Attribute_List) String() string {
+	str, _ := text.MarshalList(0xd79f108fb9204dfd, s.List)
+	return str
+}
+
+// Attribute_Promise is a wrapper for a Attribute promised by a client call.
+type Attribute_Promise struct{ *capnp.Pipeline }
+
+func (p Attribute_Promise) Struct() (Attribute, error) {
+	s, err := p.Pipeline.Struct()
+	return Attribute{s}, err
+}
+
 // Node is a node in the merkle dag of brig
 type Node struct{ capnp.Struct }
 type Node_Which uint16
@@ -757,12 +1249,12 @@ func (w Node_Which) String() string {
 const Node_TypeID = 0xa629eb7f7066fae3
 
 func NewNode(s *capnp.Segment) (Node, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 7})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 8})
 	return Node{st}, err
 }
 
 func NewRootNode(s *capnp.Segment) (Node, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 7})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 8})
 	return Node{st}, err
 }
 
@@ -1018,12 +1510,37 @@ func (s Node) SetBackendHash(v []byte) error {
 	return s.Struct.SetData(6, v)
 }
 
+func (s Node) Attributes() (Attribute_List, error) {
+	p, err := s.Struct.Ptr(7)
+	return Attribute_List{List: p.List()}, err
+}
+
+func (s Node) HasAttributes() bool {
+	p, err := s.Struct.Ptr(7)
+	return p.IsValid() || err != nil
+}
+
+func (s Node) SetAttributes(v Attribute_List) error {
+	return s.Struct.SetPtr(7, v.List.ToPtr())
+}
+
+// NewAttributes sets the attributes field to a newly
+// allocated Attribute_List, preferring placement in s's segment.
+func (s Node) NewAttributes(n int32) (Attribute_List, error) {
+	l, err := NewAttribute_List(s.Struct.Segment(), n)
+	if err != nil {
+		return Attribute_List{}, err
+	}
+	err = s.Struct.SetPtr(7, l.List.ToPtr())
+	return l, err
+}
+
 // Node_List is a list of Node.
 type Node_List struct{ capnp.List }
 
 // NewNode creates a new list of Node.
 func NewNode_List(s *capnp.Segment, sz int32) (Node_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 7}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 8}, sz)
 	return Node_List{l}, err
 }
 
@@ -1060,96 +1577,131 @@ func (p Node_Promise) Ghost() Ghost_Promise {
 	return Ghost_Promise{Pipeline: p.Pipeline.GetPipeline(5)}
 }
 
-const schema_9195d073cb5c5953 = "x\xda\xb4\x96\xefk\x14\xd7\x1a\xc7\x9f\xef9\xb3;Y" +
-	"\xd1\xbb\xd9;\x11D\\\xf7 \x0aQ\x12M\\/W" +
-	"\x83\x17\x8d\xc6k\xcc\xcd\x95\x1cW\xb9*za\xdc=" +
-	"\xd9\x1d\xdc\x9d\x093cc\xa4E[\x14\xb45E\xa9" +
-	"\x82BBm\x89\xfd\x01-m\xff\x80\xd2\x1f`)\xed" +
-	"\x9b\xd2\x17-\xed\xcb\xb6/j\x0b}]\xda\xea\x94\xb3" +
-	"\xbf&\x86\xb4Ji\xdfe\x9e\xe7\xfc\xf8\x9e\xcf\xf3<" +
-	"\xdfl\xdf\xcfl'\xebOx\x8cH\xaeJ$\xa3\xef" +
-	"\xfe>\xf3\xed\xe7\xdd\x1f\x9e#\xb9\x1a,*\x1c9\xf6" +
-	"q\xf0\xc9\xf5\xab\xb4\x87\x99\x1cF>\x8b5\xb0za" +
-	"Z\xbd\xc8\xe5\x1d\xe4@\x88fs\xff\x99|\xec\x87\xe5" +
-	"\xcfPf5\xe2\x0d\x09f\x12\xe5/\xb0\x01X\xd7\x99" +
-	"i]g9\xeb=6I\x88\xde8~\xd0\xfd\xc0\xba" +
-	"5\xad/\x98\xbf>\xa9\xd7g\xf9\x06X\xbd\xdc\xb4z" +
-	"y.\x7f\x9c\xffO\x9f\x7f\xa8\xff\xd2?\xff\xb5\xed\xe5" +
-	"g\xf5\x06\xbe\xf0\x82ic%\xacY\xc3\xb4f\x8d\\" +
-	"\xfe\x8eQ\x17\xf4\xf5O\xe3\x13g\xbf_\xff\xd2\xc2'" +
-	"\x98\xa6\x01#\xffEb%\xac\xbb\x09\xd3\xba\x9b\xc8\xe5" +
-	"\xb3I\x8f\x11\xa2\xb9oF\xbfL\xcf\xfd\xf8\x0e\xc9u" +
-	"\x98\xa7py\xd2\x04Q\xfev\xc7Q\x10\xac\xb7:\xb4" +
-	"|\xcc<U\xed;2\xfa\xd5\x82\xc3\x13\\\xabY\x96" +
-	"\xda\x05+\x9b2\xadl*\x97?\x94\xaa\xab)\xda\xe1" +
-	"x\xb0\xc9\xf5xI\x05\x9b\x8a\xf6\x84;\xb1\xc9\xf5J" +
-	"*\xd8X\xff{`o\xc5\xf4\x82p\x0c\x90\x06X\xf4" +
-	"\xff\xe7\x9e\x97o\x7f\xf6\xf4\x1d\x92\x06\xc3`\x0f\xb0\x94" +
-	"\xa8\x1f\x9f\"\xda[\xf1\x82P8n\xb2\xe4\x14\xedP" +
-	"\x05\"\xac\xd8\xa1\xb0EQ\xf9\xa1\xed\xb8B\x1f)&" +
-	"\xed@\xd8\xa1\x08+N &\xec\xb0\"<\xb7\x08E" +
-	"$\xbb\xb8Ad\x80(\xf3\xc4Q\"\xf98\x87\xbc\xc8" +
-	"\x00tA\xc7.\x1c \x92\xe79\xe4\x15\x86,\x8b\"" +
-	"t\x81\x11e\xa6\x07\x88\xe4E\x0ey\x8d!\xcb\xef\xeb" +
-	"0'\xca\\\xd5\xab\xafp\xc8\x19\x86\xacqO\x87\x0d" +
-	"\xa2\xcc\xcd\x0dD\xf2\x1a\x87\xbc\xc5\x10\x95\xb5\xda}\xae" +
-	"G\xbc\xa4\x90\"\x86\x145\x83cvH\xa8`)1" +
-	",%\xec(z\xb5\x9a\x13\xa23fN@'!*" +
-	"9\xbe*\x86\x9eO\x98Bg\x0c\xbd\x91M\x8f;U" +
-	"\x85\xce\xb83\x9a\x9b\x1e\x82z\xc8\xd9\xe1\xefqC\x7f" +
-	"jq\xda\xab\xea\xb43\xf8(\x1a\x14\x81\xe3\x96\xab\x8a" +
-	"\x89\x96\x8c)\xa1\xf4F\x82\xech\xa3\\\xaf_\xbc\x96" +
-	"C\xf61dZ,{u\xb0\x9bCnaH\xbbv" +
-	"M\xb5\x9e\x9a\xae\xd8A\x05\xcb\x88a\xd9\xc3\x95\xee\xf6" +
-	"\xd2\x9a\xcb\xe2:E\xb3+\xd6 \xda]\xc7'\x1c\x1e" +
-	"\x08[\x04*\x14\xde\xb8(Vl\xb7\xac\x1b\xc4\x13\xae" +
-	"g\x96T@$W\xb4E\xdf\xdc\x15\x97\xa9-zV" +
-	"W\xfa\x06\x87\x9cc\xc80\xd6(\xff\x0b:8\xc3!" +
-	"_a\xc8p\xde(\xfem\xfd\xbc[\x1c\xf25\x06\x18" +
-	"\x8d\xca\xbf\xba\x99H\xceq\xc87\x19\x90\xc0\xbci\xca" +
-	"\xbc\xbe\x99\xd8\xd9\x9a\x0a\x02\xbb\xdc\x06\xb1\xc3>\x15V" +
-	"<\xbf\xfd9a\xfb\xca\x0d[d\xd2\xbe\xe7\xb5?r" +
-	"\x8e[R\xa7\x91 \x86\x04!WS~Y=\x0c\xdd" +
-	"\xbf\x1d^U\x8b\x83[\xd1,\xf0\xfb\xd1\xa0\xa8*{" +
-	"\\\xb8LO\x8d\xe3\x8a\xb0\xa2\xc4\x7f\x87\x06\xf7\xd2\x83" +
-	"\xb3\xa2\x1f{\x9aC\x9e\x9f\xc7\xeaI=@\xe78\xe4" +
-	"e\x064Q]\x1a\x88\x07(\xc3\x9bs2\xbd&\x1e" +
-	"\x9f\x8cq\xae\xc1\xea\xaafu\x99C\xde`H\x07\xce" +
-	"\x99x:\x8av\xb1\xa2J\x05\x87\xf8\x19\xd5zq\x8b" +
-	"M\x13\x95yRM\xc5h\x82\x03\xf6$@\x0c\x8f`" +
-	"2\xfbubq(k\x9b\xdd4\x82h\x7f\x9dF " +
-	"\x0c\xbba'M05\xe5\x9f\xac*Q\xb2\xcb\xba\xbd" +
-	"N\xf8N\x99 {Z\x94\xacu\xd8@T\x10\xe0(" +
-	"\xf4 \x06e\xad\xc7\x08Q\xa1[\xc7\xb7 \xee+\xab" +
-	"\x1f\xbb\x88\x0a=:\xbe\x15\x0cht\x96\xf5\x0fl&" +
-	"*\xf4\xe9\xf0v\xbd\xdc\xe0ub\xd66\x9c *l" +
-	"\xd5\xf1!\x1dO\x18]H\x10Y\x83\xf5k\xb7\xeb\xf8" +
-	"0\x18\xb2\xc9(Jt!Id\xed\xc1\x00Qa\xa7" +
-	"\xce\x8c\xea\x8cy_gL\"k\x1f\x0e\x10\x15\x86u" +
-	"\xe6\xa0\xcet\xdc\xd3\x99\x0e\"K\xd6O\x1b\xd5\x99\xc3" +
-	":\x93\xfaEgRD\xd6\xa1\xba\xae1\x9d9\xa6\xef" +
-	"_\x92\xec\xc2\x12\"\xebH]\xd7a\x1d/a\xc1\xac" +
-	"G\xa1\xaf\xd4\xb0\x1dT\x88\xa8U\xb0\xb35\xaft\xd0" +
-	"\x89\xd7\xe4\x1c\xcd8.\xbf\xe7\x86\xca\x0d\x87\xc9\x9cg" +
-	"\x13\xe9S\x81\xf2\xff\x1a\xaf\xcc\xd5\xdd\x18\x9d\xf1\xff\xfb" +
-	"\xe6a'\xec\xe2I\xe5\x96\x1e\x14\xd2\xee/\xe3\xb7\xfc" +
-	"JK\xdbXS>/+m\x91\x9d\x8d*-\xf0\xc8" +
-	"F\x81\x1e\xf4\xc8I'\xac\xc4\x1e\xa9\xec\xd2\xa3\xde9" +
-	"\xd4\xb2fZ\xbc\xb1\xbb\x9b\x8d\xfd\"\xa2\xd6\xd2\xc4\x94" +
-	"\xd0\x9cm\xc7\x0d\x84\xe7*\xe1\xf9\xa2\xe6\xf9\xaa\xed\xf2" +
-	"\x8e\x0atl\xdc1\xabu\xdb\xfc\xd3\xac`\xa49\xf5" +
-	"\xdaJ\x0d\xd6\xb0\x82\xdb#M\xdb|\xf7\x0fXAT" +
-	"\xac8\xd5\x92\xaf\\\xdda\x7f#\x8cq\xa03\xfe%" +
-	"F\xd0\xc1VS\x05\xbf\xb7\xe8\xd7\x00\x00\x00\xff\xff*" +
-	"\xb6Z]"
+const schema_9195d073cb5c5953 = "x\xda\xb4W\x7f\x88\x1cw\x15\x7fo\xbe\xb3;I\xee" +
+	"6\xbb\xeb\xf7\x0a\xb5x\xdd/G\x85\xf4\xa8Mr+" +
+	"\xa8\x87\xe1r\xe9\x9d\xb9\xc6\\\xb8\xef\xed\x15\xdb\x98h" +
+	"\xe7v\xbe\xbb3fwf\x9d\x99\xed\xf5\x82\x92 \xe4" +
+	"\x0f\x7fT\x11\x15*\xa44\x95\xabm\xa0\xa1-(\xb6" +
+	"`\xf0\x07\xadXKQD,'\x06\xc5F\xea\x0f\x10" +
+	"\xfa\x8f\xd8b2\xf2fvw\xf6\xd6K\xeeP\xfa\xdf" +
+	"\xed\xfb1\xdf\xf7>\xef\xbd\xcf{\xb7\xef3\xfaAm" +
+	"\x7f\xe6\x97\x0c@\xde\x95\xc9F\x7f{\xcf\xf9\xbf\xbc\xbe" +
+	"\xe7\xe7gA\xde\x8eZTy\xe0\xc4\xab\xc1\xaf\xbe\xf5" +
+	"u\x98e\x06C\xbd\xfc\x1a\x8e!\xbf\x82\x06\xbf\x82\xa5" +
+	"\xf2{\xb5O `\xf4X\xe9\xe3+\x0f\xfd\xe3\x96/" +
+	"C\xf1vL\x1d2\x9a\x01Pn\xb3I\xe4\xe7\x98\xc1" +
+	"\xcf\xb1\x12\x7f\x9e\xad\x00FW\x97>\xf9\xe9\x7f\xce5" +
+	"\xbeB\x0f\xf4\xd9\xcf\xa2\xa1\x01\x94s\xfa$\xf2Q\xdd" +
+	"\xe0\xa3z\x89K\x9d\x1c\x9e=\xb9\xe4\xfe\x8c_xd" +
+	"\xc0!\x93\xa5\x07^\xd3\xc7\x91_\xd1\x0d~E/\x95" +
+	"s\x998\xa0\xfb\xf6\x7f\xf1C\x07>\xf2\xd4W\xc9\x81" +
+	"\xf590r8\x90\xbd\x0d\xf9|\xd6\xe0\xf3\xd9R\xb9" +
+	"\x9d\x8d\x1d\xdex\xa7\xd6:\xf3\xf7;\xbf;\x98\xf3\x0e" +
+	"CG\xbd\xfc\x8aq\x1b\xf2u\xc3\xe0\xebF\xa9\x9c\xdb" +
+	"\xf1\x94\x06\x18\xad]=\xfa\xbb\xfc\xda\xbf~\x04\xf2\xfd" +
+	"\xd8\x17\xe1-Y\x03\x01\xca\x7f\xdeu\x1c\x01\xf9[\xbb" +
+	"(\xfck\xf3\xe2\xc5\xaf\x15\x1e\x7f}3x\xe6\x87\x0e" +
+	"!?9d\xf0\x93C%\xfe\xc8\x10\x99\xe3\xf9/4" +
+	"\xf6=p\xf4O\x03\xb1dt\xb2\xff+\xd9\xbf=d" +
+	"\xf0\xb7\x87J\xe5\xfd\xc3q\xf0o\x9e\x19;\xf6\xea\xb9" +
+	"?\\\x1d\x84'\xce\xf6bn\x11\xf9\xe5\x9c\xc1/\xe7" +
+	"J\xfc\xad\xdc\x9b\x80\xd1\x0f\xce\xb6\xed7\xd6^\x89\x06" +
+	"\xe2\x89\xcd_\xde\xbd\x8c|}\xb7\xc1\xd7w\x97x&" +
+	"\xff,`T5\xc3Z\xb0\xd7\xf5\x98\xa5\x82\xbdU\xb3" +
+	"\xe5\xb6\xf6\xba\x9e\xa5\x82\xbb\xe3\xbf'\x0f\xdb\x86\x17\x84" +
+	"\x0b\x88RG-\xfa\xd47\x1e\x97\x97\x7f\xfb\xa5\x97A" +
+	"\xea\x1aN\xdf\x858\x0c\xb0\x1f\x7f\x8d\xd1a\xdb\x0bB" +
+	"\xe1\xb8Y\xcb\xa9\x9a\xa1\x0aDh\x9b\xa10EU\xf9" +
+	"\xa1\xe9\xb8\x82>)V\xcc@\x98\xa1\x08m'\x10-" +
+	"3\xb4\x85\xe7VQ\x01\xc8[\x99\x0e\xa0#@\xf1\xdb" +
+	"\xc7\x01\xe4\xa3\x0c\xe5\x9a\x86\x88#H\xb2'\x16\x01\xe4" +
+	"\x05\x86\xf2\x19\x0dGY\x14\xe1\x08j\x00\xc5\x8b\x93\x00" +
+	"r\x8d\xa1|N\xc3Q\xfd:\x89\x19@\xf1\x12Y?" +
+	"\xc3P\xbe\xa0\xe1h\xe6\x1a\x89u\x80\xe2\xf7\xc7\x01\xe4" +
+	"s\x0c\xe5\x0f5,j\xda\x08f\x00\x8a/\x92\xed\x0b" +
+	"\x0c\xe5K\x1aFuJ\xe1^\xd7\x03f)\xdc\x09\x1a" +
+	"\xee\x84\x8ep\xc1\x0c\x01m\x1c\x06\x0d\x87\x01\xa7\xaa^" +
+	"\xb3\xe9\x84XH\xdb\x02\x10\x0b\x80\x91\xe5\xf8\xaa\x1az" +
+	">\xe0*\x16\xd2B'\xda|\xcdi(,\xa4\xcd\xdb" +
+	"uR\x0d\x15*k\x1a0\xec\xbe\xb0UMf\x9c)" +
+	"\x7f\xd6\x0d\xfd\xd5\xcd\xcb\xf2\xbe\xb8,E\xfcE4-" +
+	"\x02\xc7\xad7\x94&\xba\xa1\xad\x0aE\x8e\x80rG\x0f" +
+	"\xf3;\x09\x9a;\x18\xca}\x1a\x16\xbb\xa0\x7f\x80\x84{" +
+	"\x18\xca\x0fj\x98w\xcd\xa6\xea\x06\x97\xb7\xcd\xc0\xc6\x1c" +
+	"h\x98\xdb^\xa4\x15\xdb\xf4\xad\xcd#\xbd\xa3\xd3@\xa7" +
+	"1\x9aNZ\xc4\xd1]\x11\xdaJ\xd0#\"\xf4\x1d%" +
+	"\xbc\x9a0E@\xdfP\x16\xa51\x95\xe4\x01@\x19\x0c" +
+	"G\xd1\xc6\x14\x0ej\x98\xc3\xebQ\x92\xc3\x81\x09\x00\xf9" +
+	"a\x86rI\xc3|C\x995\xdc\x0d\xb8\xc0\x10\x0b\xe9" +
+	"\x90\x00\x92\xb0\xe4\xb8\xae\xf2Suo\xe6\x12\xf5Vi" +
+	"\xde\xe3\xe5\xa9%6ORt\x92\x1c\xc3\xe8\x9e\xb8s" +
+	"\x84\xc3\x02\xcaI\x85\x94\\\xd56\xdd:\x0d\x8c'\\" +
+	"\xcf\xb0T\xb0q\x1e\x0e\x01\xc8o2\x94\x17\xfaj\xf3" +
+	"\xd8d:$q/\xd38<A\xc2\xf3\x0c\xe5\xd3\x1a" +
+	"\x16\x19K\x86\xe1\xc9\xf1ttPO&\xe1\xe2D:" +
+	"7\x98\xc1>\xae+^\x9a\x00\xedLS\x05\x81Y\xef" +
+	"\xd5{\xcal\x87\xb6\xe7\xf7~\xb6L_\xb9a\xb7\x01" +
+	"\xf2\xbe\xe7\xf5~\x94\x1c\xd7R\x0fc\x064\xcc\x00\x96" +
+	"\x9a\xca\xaf\xab\xad\xa0\xfb\x98\xc3\x1ajs\xe0n\xed\xf4" +
+	"\xf1O\xa3iA\xd5\x13\xae\x16\xb7H\xd2!\xf33\xd3" +
+	"\x87a#V\xe3\x9bau<\x85\x05;P=9\x99" +
+	"\xa2Rd\x1d\xde\xb88\x96\xc2R\xd4\xcf&X]\"" +
+	"\xac\x9ef(\xbf\xa7a1\xd3a\x8d\xe7\x97S*\xc9" +
+	"\x07\xce\xe9\x94-\xaaf\xd5VV\xc5\x01vZua" +
+	"\xe8\x02\xd6\xc1\xcf8\xa5VS\xbc\x82Es\x05\x114" +
+	"D\xf2\xf6\x9a-_\x05\x01\x18\x8e\xe7n\x97\x0b\x8e\x91" +
+	"\xe2\xe6\xd3u\x04\xa3c1p\x81\xd0M\xe1\xf6a\xd8" +
+	"T\xfe\xa9\x86\x12\x96Y\xa7N\\\xf6\x9d:\xa0\xdc\xd7" +
+	"\x05\x94O\xe38@\xe5\xa3\xc8\xb02\x87)\xa6|\x16" +
+	"\x8f\x00TfH\xbe\x80i\x0b\xf2y<\x04P\x99#" +
+	"\xf9\x12j\x88I\x13r\x89\x13\x00\x95\xa3$\xbe\x9f\xcc" +
+	"u\x16\x83\xcb\xef\xc3e\x80\xca\x12\xc9\x1f$yF\x8f" +
+	"\xf1\xe5'\xe3g\xef'\xb9\x85\x1a\x8ef\xa3(3\x82" +
+	"Y\x00n\xe2$@\xe5\x04il\xd2\x18\xd7Ic\x00" +
+	"p\x85\x8b\x00\x15\x8b4-\xd2\xec\xb8F\x9a\x1d\x00\xbc" +
+	"\x19\x7f\xcd&MH\x9a\x9d\xff&\xcdN\x00\xfe\xd98" +
+	"\xae\x06i\x1e\xa6\xf7weGp\x17\x00o\xc7q\x85" +
+	"$?K\xf2!c\x04\x87\x00\xf8\xe7\xf18@\xe5s" +
+	"$\x7f\x14\x07X1\x0a}\xa5\xe6\xcc\xc0\x06\x80ny" +
+	"\xcf4=k\xc9ImJ\x0ea\x9f6\x8b\xe7\x86\xca" +
+	"\x0d\xe7\xc0\xe8#\xd4|;P\xfe\xbb\xb3iJ\xf1." +
+	"\xc3Bz\x01v>\xb6lVO)\xd7\xda\x18Hd" +
+	"\x86\xa1\xef,\xb7C`*H\x89\xb1w\xec\x0c\x10\xa3" +
+	"~#b\xa4\x04\xeen*\x9f\xd5\x15\x11v!\xa9\xf1" +
+	"\xc0\xceI\xca\xbbq\xe7\xac8\xa1\x9d\xee\x1ceZ\xff" +
+	"\xb5sn\xf0\xe6t'p\x057\xe7\xe3C\xb4t\x08" +
+	"na\xe9\xaa\xe6\xb8\xca\x12\xa7\xd4\xea\xde\x87\xccF[" +
+	"\x89\x96\xe9\xf8\xc2sE2/\x90l\x9b\xee\xbe\x1c\xdb" +
+	"l_N\xa4\xb1\xc73\xde-z\xfc\xbd\xed\xc6>\xd3" +
+	"]\xd37\x88}O'\xf6\xef`\xd45\xcd\xac\x0a\xea" +
+	"$\xd3q\x03\xe1\xb9Jx\xbehz\xbe\xeam|G" +
+	"\x05$\xab9Fcp\xb7\xfc\x7f|y\xa4C\x8d/" +
+	"\xd1Hk\x09_\xfe\x84\x84?f(\x7fO\xf3\xcc\x92" +
+	"Z\xaf\x134\xbfa(\xff\xf8?\xf0eT\xb5\x9d\x86" +
+	"\xe5+\x17\x00\xd26\xec\xfdK\xd2m\xc3d\x96\x82\x9b" +
+	"\x19\x95\xe2#b`\xb9\x17\xb6U\x93\xf8\x84YTX" +
+	"\xdb\x92h\x17UM\xf9\xca\xad\xea\x8a\x16z\xe7pI" +
+	"n\x18\xbb]O\xabB\x17X\xa1W\x09\x93:\xea\x04" +
+	"Ci\xf7UBQy\x1ed(\x1b}[\xde\xa1{" +
+	"\xc0b([=~-6\x09_\x9b\xa1\x0c\xb5\x0d\xeb" +
+	"%O\x9c\xd4#\xa3\x0eD\xbd\xddS\xf5\xdan\xd8W" +
+	"\x89ma0\xeb\x1a[\x9e\x9d\xef\xf4\x9d\x9d\xf1\xb1I" +
+	"\xdb\xc6\x8c7\xf8T\x82\x07\x80\x1c\xee\xe5>Ki\x1e" +
+	"d(\x8f\xf6\xe5~/\x09g\x18\xca\x85\xbe\xdc\xe7)" +
+	"\xf7\xb9\xce9\xb7\xe1$\xbdY\xa2\xff\x19\x00\xbc\xd8\xac" +
+	"\xca"
 
 func init() {
 	schemas.Register(schema_9195d073cb5c5953,
 		0x80c828d7e89c12ea,
 		0x8b15ee76774b1f9d,
+		0x8c6c48f65f5b54e4,
 		0x8da013c66e545daf,
 		0x8ea7393d37893155,
 		0xa629eb7f7066fae3,
 		0xbff8a40fda4ce4a4,
-		0xe24c59306c829c01)
+		0xd79f108fb9204dfd,
+		0xe24c59306c829c01,
+		0xe4df85cb4e227fe7,
+		0xffc9a4e3687580b7)
 }
@@ -274,6 +274,11 @@ func (c *Commit) Message() string {
 	return c.message
 }
 
+// Author will return the person that made this commit.
+func (c *Commit) Author() string {
+	return c.author
+}
+
 // Path will return the path of the commit, which will
 func (c *Commit) Path() string {
 	return prefixSlash(path.Join(".snapshots", c.Name()))
@@ -25,6 +25,10 @@ type Directory struct {
 	children   map[string]h.Hash
 	contents   map[string]h.Hash
 	order      []string
+
+	// shard is the root of the shard trie for huge directories.
+	// If it is set, children, contents and order are empty.
+	shard *shardRef
 }
 
 // NewEmptyDirectory creates a new empty directory that does not exist yet.
@@ -41,7 +45,7 @@ func NewEmptyDirectory(
 			inode:    inode,
 			user:     user,
 			tree:     h.Sum([]byte(absPath)),
-			content:  h.EmptyInternalHash.Clone(),
+			content:  h.EmptyInternalHash().Clone(),
 			backend:  h.EmptyBackendHash.Clone(),
 			name:     name,
 			nodeType: NodeTypeDirectory,
@@ -158,6 +162,21 @@ func (d *Directory) setDirectoryAttrs(seg *capnp.Segment) (*capnp_model.Director
 		return nil, err
 	}
 
+	if d.shard != nil {
+		if err := d.shard.seal(); err != nil {
+			return nil, err
+		}
+
+		capShard, err := capDir.NewShard()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := setShardRef(capShard, d.shard); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := capDir.SetParent(d.parentName); err != nil {
 		return nil, err
 	}
@@ -244,6 +263,19 @@ func (d *Directory) readDirectoryAttr(capDir capnp_model.Directory) error {
 		d.contents[name] = hash
 	}
 
+	d.shard = nil
+	if capDir.HasShard() {
+		capShard, err := capDir.Shard()
+		if err != nil {
+			return err
+		}
+
+		// The shards themselves are loaded once they are needed.
+		if d.shard, err = readShardRef(capShard); err != nil {
+			return err
+		}
+	}
+
 	sort.Strings(d.order)
 	d.nodeType = NodeTypeDirectory
 	return nil
@@ -274,14 +306,18 @@ func (d *Directory) Path() string {
 
 // NChildren returns the number of children the directory has.
 func (d *Directory) NChildren() int {
+	if d.shard != nil {
+		return d.shard.count
+	}
+
 	return len(d.children)
 }
 
 // Child returns a specific child with `name` or nil, if it was not found.
 func (d *Directory) Child(lkr Linker, name string) (Node, error) {
-	childHash, ok := d.children[name]
-	if !ok {
-		return nil, nil
+	childHash, ok, err := d.childTree(lkr, name)
+	if err != nil || !ok {
+		return nil, err
 	}
 
 	return lkr.NodeByHash(childHash)
@@ -317,8 +353,7 @@ func (d *Directory) SetParent(lkr Linker, nd Node) error {
 // VisitChildren will call `fn` for each of it's direct children.
 // The order of visits is lexicographical based on the child name.
 func (d *Directory) VisitChildren(lkr Linker, fn func(nd Node) error) error {
-	for _, name := range d.order {
-		hash := d.children[name]
+	return d.visitEntries(lkr, func(name string, hash h.Hash) error {
 		child, err := lkr.NodeByHash(hash)
 		if err != nil {
 			return err
@@ -328,12 +363,8 @@ func (d *Directory) VisitChildren(lkr Linker, fn func(nd Node) error) error {
 			return fmt.Errorf("BUG: dead link in tree: %s => %s", name, hash.B58String())
 		}
 
-		if err := fn(child); err != nil {
-			return err
-		}
-	}
-
-	return nil
+		return fn(child)
+	})
 }
 
 // ChildrenSorted returns a list of children node objects, sorted lexically by
@@ -370,7 +401,11 @@ func (d *Directory) Up(lkr Linker, visit func(par *Directory) error) error {
 			continue
 		}
 
-		childHash, ok := curr.children[elem]
+		childHash, ok, err := curr.childTree(lkr, elem)
+		if err != nil {
+			return err
+		}
+
 		if !ok {
 			// This usually means that some link is missing.
 			return fmt.Errorf("bug: cannot reach self from root in up()")
@@ -446,8 +481,7 @@ func Walk(lkr Linker, node Node, dfs bool, visit func(child Node) error) error {
 		}
 	}
 
-	for _, name := range d.order {
-		hash := d.children[name]
+	err := d.visitEntries(lkr, func(name string, hash h.Hash) error {
 		child, err := lkr.NodeByHash(hash)
 		if err != nil {
 			return err
@@ -457,9 +491,11 @@ func Walk(lkr Linker, node Node, dfs bool, visit func(child Node) error) error {
 			return fmt.Errorf("walk: could not resolve %s (%s)", name, hash.B58String())
 		}
 
-		if err := Walk(lkr, child, dfs, visit); err != nil {
-			return err
-		}
+		return Walk(lkr, child, dfs, visit)
+	})
+
+	if err != nil {
+		return err
 	}
 
 	if dfs {
@@ -545,6 +581,11 @@ func (d *Directory) Copy(inode uint64) ModNode {
 	order := make([]string, len(d.order))
 	copy(order, d.order)
 
+	var shard *shardRef
+	if d.shard != nil {
+		shard = d.shard.clone()
+	}
+
 	return &Directory{
 		Base:       d.Base.copyBase(inode),
 		size:       d.size,
@@ -552,21 +593,12 @@ func (d *Directory) Copy(inode uint64) ModNode {
 		children:   children,
 		contents:   contents,
 		order:      order,
+		shard:      shard,
 	}
 }
 
 func (d *Directory) rehash(lkr Linker, updateContentHash bool) error {
-	newTreeHash := h.Sum([]byte(path.Join(d.parentName, d.name)))
-	newContentHash := h.EmptyInternalHash.Clone()
-	for _, name := range d.order {
-		newTreeHash = newTreeHash.Mix(d.children[name])
-
-		if childContent := d.contents[name]; updateContentHash && childContent != nil {
-			// The child content might be nil in case of ghost.
-			// Those should not add to the content calculation.
-			newContentHash = newContentHash.Mix(childContent)
-		}
-	}
+	newTreeHash, newContentHash := d.hashes()
 
 	oldHash := d.tree.Clone()
 	d.tree = newTreeHash
@@ -579,13 +611,44 @@ func (d *Directory) rehash(lkr Linker, updateContentHash bool) error {
 	return nil
 }
 
+// hashes computes the tree and content hash of the directory from its children.
+// Sharded directories mix the digests of their shard trie, all others mix the
+// hashes of their children in order, like before shards existed. Whether a
+// directory is sharded only depends on its number of children (see
+// updateSharding), so the hash does not depend on how it got there.
+func (d *Directory) hashes() (h.Hash, h.Hash) {
+	treeHash := h.Sum([]byte(path.Join(d.parentName, d.name)))
+	if d.shard != nil {
+		// The shards already mixed the hashes of their entries:
+		return treeHash.Mix(d.shard.tree), d.shard.content.Clone()
+	}
+
+	contentHash := h.EmptyInternalHash().Clone()
+	for _, name := range d.order {
+		treeHash = treeHash.Mix(d.children[name])
+
+		if childContent := d.contents[name]; childContent != nil {
+			// The child content might be nil in case of ghost.
+			// Those should not add to the content calculation.
+			contentHash = contentHash.Mix(childContent)
+		}
+	}
+
+	return treeHash, contentHash
+}
+
 // Add `nd` to this directory using `lkr`.
 func (d *Directory) Add(lkr Linker, nd Node) error {
 	if nd == d {
 		return fmt.Errorf("bug: attempting to add `%s` to itself", nd.Path())
 	}
 
-	if _, ok := d.children[nd.Name()]; ok {
+	_, exists, err := d.childTree(lkr, nd.Name())
+	if err != nil {
+		return err
+	}
+
+	if exists {
 		twin, err := d.Child(lkr, nd.Name())
 		if err != nil {
 			return ie.ErrExists
@@ -606,17 +669,17 @@ func (d *Directory) Add(lkr Linker, nd Node) error {
 	nodeHash := nd.TreeHash()
 	nodeContent := nd.ContentHash()
 
-	d.children[nd.Name()] = nodeHash
-	if nd.Type() != NodeTypeGhost {
-		d.contents[nd.Name()] = nodeContent
+	isGhost := nd.Type() == NodeTypeGhost
+	if err := d.setChild(lkr, nd.Name(), nodeHash, nodeContent, !isGhost); err != nil {
+		return err
 	}
 
-	nameIdx := sort.SearchStrings(d.order, nd.Name())
-	suffix := append([]string{nd.Name()}, d.order[nameIdx:]...)
-	d.order = append(d.order[:nameIdx], suffix...)
+	if err := d.updateSharding(lkr); err != nil {
+		return err
+	}
 
 	var lastNd Node
-	err := d.Up(lkr, func(parent *Directory) error {
+	err = d.Up(lkr, func(parent *Directory) error {
 		if nd.Type() != NodeTypeGhost {
 			// Only add to the size if it's not a ghost.
 			// They do not really count as size.
@@ -627,10 +690,10 @@ func (d *Directory) Add(lkr Linker, nd Node) error {
 		}
 
 		if lastNd != nil {
-			parent.children[lastNd.Name()] = lastNd.TreeHash()
-
-			if nd.Type() != NodeTypeGhost {
-				parent.contents[lastNd.Name()] = lastNd.ContentHash()
+			isGhost := nd.Type() == NodeTypeGhost
+			err := parent.setChild(lkr, lastNd.Name(), lastNd.TreeHash(), lastNd.ContentHash(), !isGhost)
+			if err != nil {
+				return err
 			}
 		}
 
@@ -654,7 +717,12 @@ func (d *Directory) Add(lkr Linker, nd Node) error {
 // There is no way to remove the root node.
 func (d *Directory) RemoveChild(lkr Linker, nd Node) error {
 	name := nd.Name()
-	if _, ok := d.children[name]; !ok {
+	_, exists, err := d.childTree(lkr, name)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
 		return ie.NoSuchFile(name)
 	}
 
@@ -665,11 +733,13 @@ func (d *Directory) RemoveChild(lkr Linker, nd Node) error {
 
 	// Delete it from orders and children.
 	// This assumes that it definitely was part of orders before.
-	delete(d.children, name)
-	delete(d.contents, name)
+	if err := d.deleteChild(lkr, name); err != nil {
+		return err
+	}
 
-	nameIdx := sort.SearchStrings(d.order, name)
-	d.order = append(d.order[:nameIdx], d.order[nameIdx+1:]...)
+	if err := d.updateSharding(lkr); err != nil {
+		return err
+	}
 
 	var lastNd Node
 	nodeSize := nd.Size()
@@ -681,10 +751,10 @@ func (d *Directory) RemoveChild(lkr Linker, nd Node) error {
 		}
 
 		if lastNd != nil {
-			parent.children[lastNd.Name()] = lastNd.TreeHash()
-
-			if nd.Type() != NodeTypeGhost {
-				parent.contents[lastNd.Name()] = lastNd.ContentHash()
+			isGhost := nd.Type() == NodeTypeGhost
+			err := parent.setChild(lkr, lastNd.Name(), lastNd.TreeHash(), lastNd.ContentHash(), !isGhost)
+			if err != nil {
+				return err
 			}
 		}
 
@@ -698,6 +768,10 @@ func (d *Directory) RemoveChild(lkr Linker, nd Node) error {
 }
 
 func (d *Directory) rebuildOrderCache() {
+	if d.shard != nil {
+		return
+	}
+
 	d.order = []string{}
 	for name := range d.children {
 		d.order = append(d.order, name)
@@ -722,9 +796,22 @@ func (d *Directory) NotifyMove(lkr Linker, newParent *Directory, newPath string)
 				return ie.ErrBadNode
 			}
 
-			for name := range childDir.children {
+			names := []string{}
+			err := childDir.visitEntries(lkr, func(name string, _ h.Hash) error {
+				names = append(names, name)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+
+			for _, name := range names {
 				movedChildPath := path.Join(newChildPath, name)
-				childDir.children[name] = visited[movedChildPath].TreeHash()
+				movedTree := visited[movedChildPath].TreeHash()
+				if err := childDir.setChild(lkr, name, movedTree, nil, false); err != nil {
+					return err
+				}
 			}
 
 			if err := childDir.rehash(lkr, false); err != nil {
@@ -766,7 +853,10 @@ func (d *Directory) NotifyMove(lkr Linker, newParent *Directory, newPath string)
 	for nodePath, node := range visited {
 		if parent, ok := visited[path.Dir(nodePath)]; ok {
 			parentDir := parent.(*Directory)
-			parentDir.children[path.Base(nodePath)] = node.TreeHash()
+			if err := parentDir.setChild(lkr, path.Base(nodePath), node.TreeHash(), nil, false); err != nil {
+				return err
+			}
+
 			parentDir.rebuildOrderCache()
 		}
 	}
@@ -779,6 +869,173 @@ func (d *Directory) NotifyMove(lkr Linker, newParent *Directory, newPath string)
 	return nil
 }
 
+// childTree returns the tree hash of the child `name`.
+func (d *Directory) childTree(lkr Linker, name string) (h.Hash, bool, error) {
+	if d.shard == nil {
+		hash, ok := d.children[name]
+		return hash, ok, nil
+	}
+
+	entry, ok, err := d.shard.get(lkr, name, shardNameHash(name), 0)
+	return entry.tree, ok, err
+}
+
+// setChild adds or updates the child `name`.
+// The content hash is only updated if `setContent` is true.
+func (d *Directory) setChild(lkr Linker, name string, tree, content h.Hash, setContent bool) error {
+	if d.shard != nil {
+		return d.shard.set(lkr, name, shardNameHash(name), 0, tree, content, setContent)
+	}
+
+	if _, ok := d.children[name]; !ok {
+		nameIdx := sort.SearchStrings(d.order, name)
+		suffix := append([]string{name}, d.order[nameIdx:]...)
+		d.order = append(d.order[:nameIdx], suffix...)
+	}
+
+	d.children[name] = tree
+	if setContent {
+		d.contents[name] = content
+	}
+
+	return nil
+}
+
+// deleteChild removes the child `name`, if it exists.
+func (d *Directory) deleteChild(lkr Linker, name string) error {
+	if d.shard != nil {
+		return d.shard.remove(lkr, name, shardNameHash(name), 0)
+	}
+
+	if _, ok := d.children[name]; !ok {
+		return nil
+	}
+
+	delete(d.children, name)
+	delete(d.contents, name)
+
+	nameIdx := sort.SearchStrings(d.order, name)
+	d.order = append(d.order[:nameIdx], d.order[nameIdx+1:]...)
+	return nil
+}
+
+// visitEntries calls `fn` with the name and tree hash of each child,
+// sorted by the name.
+func (d *Directory) visitEntries(lkr Linker, fn func(name string, hash h.Hash) error) error {
+	if d.shard == nil {
+		for _, name := range d.order {
+			if err := fn(name, d.children[name]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	names := make([]string, 0, d.shard.count)
+	hashes := make(map[string]h.Hash, d.shard.count)
+	err := d.shard.visitEntries(lkr, func(name string, entry shardEntry) error {
+		names = append(names, name)
+		hashes[name] = entry.tree
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if err := fn(name, hashes[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateSharding switches between the flat and the sharded representation
+// when the directory grew above or shrunk to shardThreshold children. There
+// is deliberately no hysteresis: both representations hash differently, so
+// the representation must only depend on the number of children.
+func (d *Directory) updateSharding(lkr Linker) error {
+	switch {
+	case d.shard == nil && len(d.children) > shardThreshold:
+		root := &shardRef{node: newLeafShard()}
+		for _, name := range d.order {
+			content := d.contents[name]
+			if err := root.set(lkr, name, shardNameHash(name), 0, d.children[name], content, true); err != nil {
+				return err
+			}
+		}
+
+		d.shard = root
+		d.children = make(map[string]h.Hash)
+		d.contents = make(map[string]h.Hash)
+		d.order = []string{}
+	case d.shard != nil && d.shard.count <= shardThreshold:
+		children := make(map[string]h.Hash)
+		contents := make(map[string]h.Hash)
+		order := []string{}
+		err := d.shard.visitEntries(lkr, func(name string, entry shardEntry) error {
+			children[name] = entry.tree
+			if entry.content != nil {
+				contents[name] = entry.content
+			}
+
+			order = append(order, name)
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		sort.Strings(order)
+		d.shard = nil
+		d.children = children
+		d.contents = contents
+		d.order = order
+	}
+
+	return nil
+}
+
+// IsSharded returns true if the children of this directory are stored
+// in separate shards. This is done for huge directories.
+func (d *Directory) IsSharded() bool {
+	return d.shard != nil
+}
+
+// UnsavedShards calls `fn` for every shard that was modified since the
+// last call. The linker should store `data` under `key` and return it
+// in ShardByHash.
+func (d *Directory) UnsavedShards(fn func(key h.Hash, data []byte) error) error {
+	if d.shard == nil {
+		return nil
+	}
+
+	if err := d.shard.seal(); err != nil {
+		return err
+	}
+
+	return d.shard.unsaved(fn)
+}
+
+// VisitShards calls `fn` for every shard of the directory, parents first.
+// If `fn` returns false, the shards below the current one are skipped.
+func (d *Directory) VisitShards(lkr Linker, fn func(key h.Hash, data []byte) (bool, error)) error {
+	if d.shard == nil {
+		return nil
+	}
+
+	if err := d.shard.seal(); err != nil {
+		return err
+	}
+
+	return d.shard.visit(lkr, fn)
+}
+
 // SetUser sets the user that last modified the directory.
 func (d *Directory) SetUser(user string) {
 	d.Base.user = user
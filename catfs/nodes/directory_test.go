@@ -1,9 +1,11 @@
 package nodes
 
 import (
+	"fmt"
 	"testing"
 
 	ie "github.com/sahib/brig/catfs/errors"
+	h "github.com/sahib/brig/util/hashlib"
 	"github.com/stretchr/testify/require"
 	capnp "zombiezen.com/go/capnproto2"
 )
@@ -75,3 +77,218 @@ func TestDirectoryBasics(t *testing.T) {
 	empty.modTime = repoDir.modTime
 	require.Equal(t, empty, repoDir)
 }
+
+func withSmallShards(fn func()) {
+	oldThreshold, oldLeafSize := shardThreshold, shardLeafSize
+	shardThreshold, shardLeafSize = 16, 4
+	defer func() {
+		shardThreshold, shardLeafSize = oldThreshold, oldLeafSize
+	}()
+
+	fn()
+}
+
+func TestDirectorySharded(t *testing.T) {
+	withSmallShards(func() {
+		lkr := NewMockLinker()
+		root, err := lkr.Root()
+		require.NoError(t, err)
+		lkr.AddNode(root, true)
+
+		dir, err := NewEmptyDirectory(lkr, root, "huge", "a", 2)
+		require.NoError(t, err)
+		lkr.AddNode(dir, true)
+
+		names := []string{}
+		for idx := 0; idx < 100; idx++ {
+			name := fmt.Sprintf("%03d", idx)
+			sub, err := NewEmptyDirectory(lkr, dir, name, "a", uint64(idx+3))
+			require.NoError(t, err)
+			lkr.AddNode(sub, true)
+			names = append(names, name)
+		}
+
+		require.True(t, dir.IsSharded())
+		require.Equal(t, 100, dir.NChildren())
+		require.Empty(t, dir.children)
+
+		for _, name := range names {
+			child, err := dir.Child(lkr, name)
+			require.NoError(t, err)
+			require.NotNil(t, child)
+			require.Equal(t, name, child.Name())
+		}
+
+		children, err := dir.ChildrenSorted(lkr)
+		require.NoError(t, err)
+		require.Len(t, children, 100)
+		for idx, child := range children {
+			require.Equal(t, names[idx], child.Name())
+		}
+
+		// Hand the shards to the linker and load the directory again:
+		require.NoError(t, dir.UnsavedShards(lkr.AddShard))
+		msg, err := dir.ToCapnp()
+		require.NoError(t, err)
+
+		loaded := &Directory{}
+		require.NoError(t, loaded.FromCapnp(msg))
+		require.True(t, loaded.IsSharded())
+		require.Equal(t, 100, loaded.NChildren())
+		require.Equal(t, dir.TreeHash(), loaded.TreeHash())
+
+		child, err := loaded.Child(lkr, "042")
+		require.NoError(t, err)
+		require.Equal(t, "042", child.Name())
+
+		// Copies are independent from the original:
+		copied := dir.Copy(1000).(*Directory)
+		require.NoError(t, copied.RemoveChild(lkr, children[0]))
+		require.Equal(t, 99, copied.NChildren())
+		require.Equal(t, 100, dir.NChildren())
+
+		// Removing enough children switches back to the flat representation:
+		for _, child := range children[:95] {
+			require.NoError(t, dir.RemoveChild(lkr, child))
+		}
+
+		require.False(t, dir.IsSharded())
+		require.Equal(t, 5, dir.NChildren())
+		require.Equal(t, names[95:], dir.order)
+	})
+}
+
+func TestDirectoryShardedHash(t *testing.T) {
+	withSmallShards(func() {
+		// The hash should only depend on the children, not on
+		// the order in which they were added or removed.
+		hashes := []string{}
+		for _, reverse := range []bool{false, true} {
+			lkr := NewMockLinker()
+			root, err := lkr.Root()
+			require.NoError(t, err)
+			lkr.AddNode(root, true)
+
+			for idx := 0; idx < 50; idx++ {
+				num := idx
+				if reverse {
+					num = 49 - idx
+				}
+
+				sub, err := NewEmptyDirectory(lkr, root, fmt.Sprintf("%d", num), "a", uint64(num+2))
+				require.NoError(t, err)
+				lkr.AddNode(sub, true)
+			}
+
+			require.True(t, root.IsSharded())
+			hashes = append(hashes, root.TreeHash().B58String())
+		}
+
+		require.Equal(t, hashes[0], hashes[1])
+	})
+}
+
+func TestDirectoryHashIndependentOfHistory(t *testing.T) {
+	withSmallShards(func() {
+		// Fills a directory with `add` files and removes them again
+		// until only `keep` are left. Files are created the same way
+		// in every directory, so only the history differs.
+		build := func(add, keep int) *Directory {
+			lkr := NewMockLinker()
+			root, err := lkr.Root()
+			require.NoError(t, err)
+			lkr.AddNode(root, true)
+
+			files := []*File{}
+			for idx := 0; idx < add; idx++ {
+				file := NewEmptyFile(root, fmt.Sprintf("file-%d", idx), "a", uint64(idx+2))
+				require.NoError(t, root.Add(lkr, file))
+				files = append(files, file)
+			}
+
+			for _, file := range files[keep:] {
+				require.NoError(t, root.RemoveChild(lkr, file))
+			}
+
+			return root
+		}
+
+		for _, keep := range []int{3, 10, shardThreshold} {
+			flat := build(keep, keep)
+			shrunk := build(30, keep)
+
+			// Directories that shrunk are flat again:
+			require.False(t, flat.IsSharded())
+			require.False(t, shrunk.IsSharded())
+			require.Equal(t, keep, shrunk.NChildren())
+
+			require.Equal(t, flat.TreeHash(), shrunk.TreeHash(), "tree hash with %d children", keep)
+			require.Equal(t, flat.ContentHash(), shrunk.ContentHash(), "content hash with %d children", keep)
+		}
+
+		require.True(t, build(shardThreshold+1, shardThreshold+1).IsSharded())
+	})
+}
+
+func TestDirectoryFlatHash(t *testing.T) {
+	withSmallShards(func() {
+		// Flat directories hash like they did before shards existed,
+		// also if they have more children than fit into a single shard:
+		lkr := NewMockLinker()
+		root, err := lkr.Root()
+		require.NoError(t, err)
+		lkr.AddNode(root, true)
+
+		dir, err := NewEmptyDirectory(lkr, root, "dir", "a", 2)
+		require.NoError(t, err)
+		lkr.AddNode(dir, true)
+
+		files := []*File{}
+		for idx := 0; idx < shardThreshold; idx++ {
+			file := NewEmptyFile(dir, fmt.Sprintf("file-%02d", idx), "a", uint64(idx+3))
+			require.NoError(t, dir.Add(lkr, file))
+			files = append(files, file)
+		}
+
+		require.False(t, dir.IsSharded())
+		require.True(t, dir.NChildren() > shardLeafSize)
+
+		treeHash := h.Sum([]byte("/dir"))
+		for _, file := range files {
+			treeHash = treeHash.Mix(file.TreeHash())
+		}
+
+		require.Equal(t, treeHash, dir.TreeHash())
+	})
+}
+
+// Staging a child means adding it and serializing the directory with all
+// modified shards. This should not get more expensive with more siblings.
+func BenchmarkDirectoryStageChild(b *testing.B) {
+	for _, siblings := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("siblings-%d", siblings), func(b *testing.B) {
+			lkr := NewMockLinker()
+			root, err := lkr.Root()
+			require.NoError(b, err)
+
+			for idx := 0; idx < siblings; idx++ {
+				file := NewEmptyFile(root, fmt.Sprintf("sibling-%d", idx), "a", uint64(idx+1))
+				require.NoError(b, root.Add(lkr, file))
+			}
+
+			require.NoError(b, root.UnsavedShards(lkr.AddShard))
+
+			b.ResetTimer()
+			for idx := 0; idx < b.N; idx++ {
+				file := NewEmptyFile(root, fmt.Sprintf("new-%d", idx), "a", uint64(siblings+idx+1))
+				require.NoError(b, root.Add(lkr, file))
+
+				if _, err := MarshalNode(root); err != nil {
+					b.Fatal(err)
+				}
+
+				require.NoError(b, root.UnsavedShards(lkr.AddShard))
+			}
+		})
+	}
+}
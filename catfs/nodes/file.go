@@ -20,6 +20,9 @@ type File struct {
 	parent     string
 	key        []byte
 	isRaw      bool
+
+	// compression used for the stream; empty if unknown.
+	compression string
 }
 
 // NewEmptyFile returns a newly created file under `parent`, named `name`.
@@ -79,6 +82,10 @@ func (f *File) setFileAttrs(seg *capnp.Segment) (*capnp_model.File, error) {
 		return nil, err
 	}
 
+	if err := capFile.SetCompression(f.compression); err != nil {
+		return nil, err
+	}
+
 	capFile.SetSize(f.size)
 	capFile.SetCachedSize(f.cachedSize)
 	capFile.SetIsRaw(f.isRaw)
@@ -117,6 +124,11 @@ func (f *File) readFileAttrs(capFile capnp_model.File) error {
 		return err
 	}
 
+	f.compression, err = capFile.Compression()
+	if err != nil {
+		return err
+	}
+
 	f.isRaw = capFile.IsRaw()
 	f.nodeType = NodeTypeFile
 	f.size = capFile.Size()
@@ -146,6 +158,13 @@ func (f *File) SetName(n string) { f.name = n }
 // SetIsRaw sets the isRaw attribute
 func (f *File) SetIsRaw(isRaw bool) { f.isRaw = isRaw }
 
+// SetCompression sets the compression that was used for the stream.
+func (f *File) SetCompression(compression string) { f.compression = compression }
+
+// Compression returns the compression used for the stream of this file,
+// e.g. "zstd-fastest" or "none". It is empty if it is not known.
+func (f *File) Compression() string { return f.compression }
+
 // SetKey updates the key to a new value, taking ownership of the value.
 func (f *File) SetKey(k []byte) { f.key = k }
 
@@ -174,25 +193,35 @@ func (f *File) Copy(inode uint64) ModNode {
 	}
 
 	return &File{
-		Base:       f.Base.copyBase(inode),
-		size:       f.size,
-		cachedSize: f.cachedSize,
-		parent:     f.parent,
-		key:        copyKey,
+		Base:        f.Base.copyBase(inode),
+		size:        f.size,
+		cachedSize:  f.cachedSize,
+		parent:      f.parent,
+		key:         copyKey,
+		compression: f.compression,
 	}
 }
 
 func (f *File) rehash(lkr Linker, newPath string) {
 	oldHash := f.tree.Clone()
+	f.tree = f.treeHash(newPath)
+	lkr.MemIndexSwap(f, oldHash, true)
+}
+
+// treeHash computes the tree hash of the file as if it was located at `nodePath`.
+func (f *File) treeHash(nodePath string) h.Hash {
 	var contentHash h.Hash
 	if f.Base.content != nil {
 		contentHash = f.Base.content.Clone()
 	} else {
-		contentHash = h.EmptyInternalHash.Clone()
+		contentHash = h.EmptyInternalHash().Clone()
 	}
 
-	f.tree = h.Sum([]byte(fmt.Sprintf("%s|%s", newPath, contentHash)))
-	lkr.MemIndexSwap(f, oldHash, true)
+	if digest := f.attrsDigest(); digest != "" {
+		return h.Sum([]byte(fmt.Sprintf("%s|%s|%s", nodePath, contentHash, digest)))
+	}
+
+	return h.Sum([]byte(fmt.Sprintf("%s|%s", nodePath, contentHash)))
 }
 
 // NotifyMove should be called when the node moved parents.
@@ -220,6 +249,12 @@ func (f *File) SetContent(lkr Linker, content h.Hash) {
 	f.SetModTime(time.Now())
 }
 
+// SetAttributes replaces all user attributes with `attrs`.
+func (f *File) SetAttributes(lkr Linker, attrs map[string][]byte) {
+	f.attrs = copyAttributes(attrs)
+	f.rehash(lkr, f.Path())
+}
+
 // SetBackend will update the hash of the file (and also the mod time)
 func (f *File) SetBackend(lkr Linker, backend h.Hash) {
 	f.Base.backend = backend
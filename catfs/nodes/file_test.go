@@ -86,3 +86,51 @@ func TestFile(t *testing.T) {
 	empty.modTime = file.modTime
 	require.Equal(t, empty, file)
 }
+
+func TestFileAttributes(t *testing.T) {
+	lkr := NewMockLinker()
+	root, err := NewEmptyDirectory(lkr, nil, "", "a", 2)
+	require.NoError(t, err)
+	lkr.AddNode(root, true)
+	lkr.MemSetRoot(root)
+
+	file := NewEmptyFile(root, "photo.jpg", "a", 3)
+	lkr.AddNode(file, true)
+	file.SetContent(lkr, []byte{4, 5, 6})
+	hashWithoutAttrs := file.TreeHash().Clone()
+
+	attrs := map[string][]byte{
+		"project": []byte("holiday"),
+		"client":  []byte("acme"),
+	}
+
+	file.SetAttributes(lkr, attrs)
+	require.NotEqual(t, hashWithoutAttrs, file.TreeHash())
+
+	// Modifying the passed map should not modify the node:
+	attrs["project"] = []byte("work")
+	require.Equal(t, []byte("holiday"), file.Attributes()["project"])
+
+	// Modifying the returned map should not modify the node:
+	attrs = file.Attributes()
+	attrs["project"] = []byte("work")
+	require.Equal(t, []byte("holiday"), file.Attributes()["project"])
+
+	data, err := MarshalNode(file)
+	require.NoError(t, err)
+
+	nd, err := UnmarshalNode(data)
+	require.NoError(t, err)
+	require.True(t, AttributesEqual(file, nd))
+	require.Equal(t, file.TreeHash(), nd.TreeHash())
+
+	copied := file.Copy(4)
+	require.True(t, AttributesEqual(file, copied))
+
+	// Removing all attributes gives the old hash:
+	file.SetAttributes(lkr, map[string][]byte{"client": []byte("acme")})
+	require.False(t, AttributesEqual(file, nd))
+	file.SetAttributes(lkr, nil)
+	require.Equal(t, hashWithoutAttrs, file.TreeHash())
+	require.Empty(t, file.Attributes())
+}
@@ -2,6 +2,7 @@ package nodes
 
 import (
 	"fmt"
+	"time"
 
 	ie "github.com/sahib/brig/catfs/errors"
 	capnp_model "github.com/sahib/brig/catfs/nodes/capnp"
@@ -19,6 +20,7 @@ type Ghost struct {
 	ghostPath  string
 	ghostInode uint64
 	oldType    NodeType
+	deletedAt  time.Time
 }
 
 // MakeGhost takes an existing node and converts it to a ghost.
@@ -35,6 +37,7 @@ func MakeGhost(nd ModNode, inode uint64) (*Ghost, error) {
 		oldType:    nd.Type(),
 		ghostInode: inode,
 		ghostPath:  nd.Path(),
+		deletedAt:  time.Now(),
 	}, nil
 }
 
@@ -74,6 +77,17 @@ func (g *Ghost) String() string {
 	return fmt.Sprintf("<ghost: %s %v>", g.TreeHash(), g.ModNode)
 }
 
+// DeletedAt returns the time when the node was turned into a ghost.
+// Ghosts that were created before this was recorded report
+// the modification time of their old node instead.
+func (g *Ghost) DeletedAt() time.Time {
+	if g.deletedAt.IsZero() {
+		return g.ModNode.ModTime()
+	}
+
+	return g.deletedAt
+}
+
 // Path returns the path of the node.
 func (g *Ghost) Path() string {
 	return g.ghostPath
@@ -122,6 +136,17 @@ func (g *Ghost) ToCapnpNode(seg *capnp.Segment, capNd capnp_model.Node) error {
 		return err
 	}
 
+	if !g.deletedAt.IsZero() {
+		deletedAt, err := g.deletedAt.MarshalText()
+		if err != nil {
+			return err
+		}
+
+		if err := capghost.SetDeletedAt(string(deletedAt)); err != nil {
+			return err
+		}
+	}
+
 	switch g.oldType {
 	case NodeTypeFile:
 		file, ok := g.ModNode.(*File)
@@ -197,6 +222,17 @@ func (g *Ghost) FromCapnpNode(capNd capnp_model.Node) error {
 		return err
 	}
 
+	deletedAt, err := capghost.DeletedAt()
+	if err != nil {
+		return err
+	}
+
+	if deletedAt != "" {
+		if err := g.deletedAt.UnmarshalText([]byte(deletedAt)); err != nil {
+			return err
+		}
+	}
+
 	var base *Base
 
 	switch typ := capghost.Which(); typ {
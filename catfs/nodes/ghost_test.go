@@ -65,6 +65,10 @@ func TestGhost(t *testing.T) {
 		t.Fatalf("Ghost path was not unmarshaled: %v", empty.Path())
 	}
 
+	if !empty.DeletedAt().Equal(ghost.DeletedAt()) {
+		t.Fatalf("Ghost deletion time was not unmarshaled: %v", empty.DeletedAt())
+	}
+
 	if !bytes.Equal(ghost.OldNode().TreeHash(), file.TreeHash()) {
 		t.Fatalf("Ghost and real hash differ (%v - %v)", ghost.TreeHash(), root.TreeHash())
 	}
@@ -30,6 +30,10 @@ type Linker interface {
 
 	// MemSetRoot should be called when the current root directory changed.
 	MemSetRoot(root *Directory)
+
+	// ShardByHash returns the serialized directory shard stored under `key`.
+	// Shards are handed to the linker via Directory.UnsavedShards.
+	ShardByHash(key h.Hash) ([]byte, error)
 }
 
 ////////////////////////////
@@ -42,6 +46,7 @@ type MockLinker struct {
 	root   *Directory
 	paths  map[string]Node
 	hashes map[string]Node
+	shards map[string][]byte
 }
 
 // NewMockLinker returns a Linker that can be easily used for testing.
@@ -49,6 +54,7 @@ func NewMockLinker() *MockLinker {
 	return &MockLinker{
 		paths:  make(map[string]Node),
 		hashes: make(map[string]Node),
+		shards: make(map[string][]byte),
 	}
 }
 
@@ -106,3 +112,19 @@ func (ml *MockLinker) AddNode(nd Node, updatePathIndex bool) {
 		ml.paths[nd.Path()] = nd
 	}
 }
+
+// ShardByHash returns a shard previously added via AddShard.
+func (ml *MockLinker) ShardByHash(key h.Hash) ([]byte, error) {
+	if data, ok := ml.shards[key.B58String()]; ok {
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("No such shard")
+}
+
+// AddShard will add a directory shard to the memory index.
+// This is not part of the linker interface.
+func (ml *MockLinker) AddShard(key h.Hash, data []byte) error {
+	ml.shards[key.B58String()] = data
+	return nil
+}
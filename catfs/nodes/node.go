@@ -83,6 +83,10 @@ type Metadatable interface {
 	// can be read from the backend.
 	// It is valid to return nil if the file is empty.
 	BackendHash() h.Hash
+
+	// Attributes returns the user defined key/value attributes of the node.
+	// The returned map is a copy and may be modified.
+	Attributes() map[string][]byte
 }
 
 // Serializable is a thing that can be converted to a capnproto message.
@@ -0,0 +1,145 @@
+package nodes
+
+import (
+	"fmt"
+
+	h "github.com/sahib/brig/util/hashlib"
+)
+
+// Rehasher is used by Rehash to find out the new hashes
+// of everything a node refers to.
+type Rehasher interface {
+	// NodeHashes returns the new tree and content hash of the node
+	// that was stored under the tree hash `old`.
+	NodeHashes(old h.Hash) (tree h.Hash, content h.Hash, err error)
+
+	// FileContent returns the new content hash of `file`.
+	FileContent(file *File) (h.Hash, error)
+
+	// Shard returns the directory shard stored under the (old) key `key`.
+	Shard(key h.Hash) ([]byte, error)
+}
+
+// Rehash recomputes all hashes of `nd` with the current hash algorithm
+// (see hashlib.SetInternalAlgorithm). Hashes of other nodes are mapped
+// via `rh`. The node is modified in place; the new shards of directories
+// can be retrieved afterwards via Directory.UnsavedShards.
+//
+// Backend hashes are not touched, since they are chosen by the backend.
+// The same goes for the merge marker of commits, which refers to the
+// history of another repository.
+func Rehash(nd Node, rh Rehasher) error {
+	switch nd := nd.(type) {
+	case *File:
+		content, err := rh.FileContent(nd)
+		if err != nil {
+			return err
+		}
+
+		nd.content = content
+		nd.tree = nd.treeHash(nd.Path())
+		return nil
+	case *Directory:
+		return nd.rehashWith(rh)
+	case *Ghost:
+		// The tree hash of the ghost is derived from the old node:
+		return Rehash(nd.ModNode, rh)
+	case *Commit:
+		return nd.rehashWith(rh)
+	default:
+		return fmt.Errorf("rehash: unsupported node type: %T", nd)
+	}
+}
+
+func (d *Directory) rehashWith(rh Rehasher) error {
+	for name, child := range d.children {
+		tree, content, err := rh.NodeHashes(child)
+		if err != nil {
+			return err
+		}
+
+		d.children[name] = tree
+		if d.contents[name] != nil {
+			d.contents[name] = content
+		}
+	}
+
+	if d.shard != nil {
+		if err := d.shard.rehash(rh); err != nil {
+			return err
+		}
+	}
+
+	d.tree, d.content = d.hashes()
+	return nil
+}
+
+func (ref *shardRef) rehash(rh Rehasher) error {
+	data, err := rh.Shard(ref.key)
+	if err != nil {
+		return err
+	}
+
+	sh, err := unmarshalShard(data)
+	if err != nil {
+		return err
+	}
+
+	ref.node = sh
+	if sh.isLeaf() {
+		for _, name := range sh.order {
+			entry := sh.entries[name]
+			tree, content, err := rh.NodeHashes(entry.tree)
+			if err != nil {
+				return err
+			}
+
+			entry.tree = tree
+			if entry.content != nil {
+				entry.content = content
+			}
+
+			sh.entries[name] = entry
+		}
+	} else {
+		for _, child := range sh.slots {
+			if child == nil {
+				continue
+			}
+
+			if err := child.rehash(rh); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Drops the old key; seal() computes the new one.
+	ref.update()
+	return nil
+}
+
+func (c *Commit) rehashWith(rh Rehasher) error {
+	if c.root != nil {
+		root, _, err := rh.NodeHashes(c.root)
+		if err != nil {
+			return err
+		}
+
+		c.root = root
+	}
+
+	if c.parent != nil {
+		parent, _, err := rh.NodeHashes(c.parent)
+		if err != nil {
+			return err
+		}
+
+		c.parent = parent
+	}
+
+	if !c.IsBoxed() {
+		return nil
+	}
+
+	return c.BoxCommit(c.author, c.message)
+}
@@ -0,0 +1,136 @@
+package nodes
+
+import (
+	"fmt"
+	"testing"
+
+	h "github.com/sahib/brig/util/hashlib"
+	"github.com/stretchr/testify/require"
+)
+
+func buildRehashTree(t *testing.T, algo h.Algorithm) (*MockLinker, *Commit) {
+	require.NoError(t, h.SetInternalAlgorithm(algo))
+
+	lkr := NewMockLinker()
+	root, err := lkr.Root()
+	require.NoError(t, err)
+	lkr.AddNode(root, true)
+
+	dir, err := NewEmptyDirectory(lkr, root, "huge", "a", 2)
+	require.NoError(t, err)
+	lkr.AddNode(dir, true)
+
+	for idx := 0; idx < 40; idx++ {
+		name := fmt.Sprintf("%03d", idx)
+		file := NewEmptyFile(dir, name, "a", uint64(idx+3))
+		file.SetContent(lkr, h.SumWith(algo, []byte(name)))
+		require.NoError(t, dir.Add(lkr, file))
+		lkr.AddNode(file, true)
+	}
+
+	require.True(t, dir.IsSharded())
+	require.NoError(t, dir.UnsavedShards(lkr.AddShard))
+
+	cmt, err := NewEmptyCommit(100, 0)
+	require.NoError(t, err)
+	cmt.SetRoot(root.TreeHash())
+	require.NoError(t, cmt.BoxCommit("a", "init"))
+	lkr.AddNode(cmt, false)
+	return lkr, cmt
+}
+
+type mockRehasher struct {
+	lkr  *MockLinker
+	algo h.Algorithm
+	done map[string]Node
+}
+
+func (mr *mockRehasher) rehash(old h.Hash) (Node, error) {
+	if nd, ok := mr.done[old.B58String()]; ok {
+		return nd, nil
+	}
+
+	oldNd, ok := mr.lkr.hashes[old.B58String()]
+	if !ok {
+		return nil, fmt.Errorf("no such node: %s", old)
+	}
+
+	// Go over the serialized form, like a real database would:
+	data, err := MarshalNode(oldNd)
+	if err != nil {
+		return nil, err
+	}
+
+	nd, err := UnmarshalNode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Rehash(nd, mr); err != nil {
+		return nil, err
+	}
+
+	mr.done[old.B58String()] = nd
+	return nd, nil
+}
+
+func (mr *mockRehasher) NodeHashes(old h.Hash) (h.Hash, h.Hash, error) {
+	nd, err := mr.rehash(old)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nd.TreeHash(), nd.ContentHash(), nil
+}
+
+func (mr *mockRehasher) FileContent(file *File) (h.Hash, error) {
+	return h.SumWith(mr.algo, []byte(file.Name())), nil
+}
+
+func (mr *mockRehasher) Shard(key h.Hash) ([]byte, error) {
+	return mr.lkr.ShardByHash(key)
+}
+
+func TestRehash(t *testing.T) {
+	defer h.SetInternalAlgorithm(h.DefaultAlgorithm)
+
+	withSmallShards(func() {
+		oldLkr, oldCmt := buildRehashTree(t, h.DefaultAlgorithm)
+		expectLkr, expectCmt := buildRehashTree(t, h.AlgoSHA256)
+		require.NotEqual(t, oldCmt.TreeHash(), expectCmt.TreeHash())
+
+		rh := &mockRehasher{
+			lkr:  oldLkr,
+			algo: h.AlgoSHA256,
+			done: make(map[string]Node),
+		}
+
+		nd, err := rh.rehash(oldCmt.TreeHash())
+		require.NoError(t, err)
+
+		cmt := nd.(*Commit)
+		require.Equal(t, expectCmt.Root(), cmt.Root())
+		require.Equal(t, expectCmt.TreeHash(), cmt.TreeHash())
+		require.Equal(t, "init", cmt.Message())
+
+		algo, err := cmt.TreeHash().Algorithm()
+		require.NoError(t, err)
+		require.Equal(t, h.AlgoSHA256, algo)
+
+		// The new shards must be the same as if the tree was built from scratch:
+		dirNd, err := rh.rehash(oldLkr.paths["/huge"].TreeHash())
+		require.NoError(t, err)
+
+		shards := map[string][]byte{}
+		require.NoError(t, dirNd.(*Directory).UnsavedShards(func(key h.Hash, data []byte) error {
+			shards[key.B58String()] = data
+			return nil
+		}))
+
+		require.NotEmpty(t, shards)
+		for b58 := range shards {
+			_, ok := expectLkr.shards[b58]
+			require.True(t, ok, "unexpected shard %s", b58)
+		}
+	})
+}
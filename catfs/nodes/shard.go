@@ -0,0 +1,637 @@
+package nodes
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	capnp_model "github.com/sahib/brig/catfs/nodes/capnp"
+	h "github.com/sahib/brig/util/hashlib"
+	capnp "zombiezen.com/go/capnproto2"
+)
+
+// Directories with many entries are stored as a hash trie of shards.
+// Each entry is sorted into a slot by the hash of its name; leaves hold
+// the actual entries and are split once they grow too big. Modifying a
+// single entry then only touches the shards on the way from the directory
+// to the entry instead of all entries, and shards are only loaded when
+// they are needed. The shards are stored separately by the linker
+// (see Linker.ShardByHash) under a key that is the hash of their content.
+//
+// The trie is canonical: a shard is an inner node exactly if it holds more
+// than shardLeafSize entries (unless it sits at the maximum depth). Its
+// digests therefore only depend on its entries, not on the order in which
+// they were added or removed.
+
+var (
+	// shardThreshold is the number of entries above which a directory
+	// switches to shards. It switches back once it has no more than that.
+	shardThreshold = 2048
+
+	// shardLeafSize is the number of entries above which a leaf is split.
+	shardLeafSize = 128
+)
+
+const (
+	shardBits     = 5
+	shardFanout   = 1 << shardBits
+	shardMaxDepth = 64 / shardBits
+)
+
+func shardNameHash(name string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(name))
+	return hasher.Sum64()
+}
+
+func shardSlot(nameHash uint64, depth int) int {
+	return int((nameHash >> (uint(depth) * shardBits)) & (shardFanout - 1))
+}
+
+// shardEntry is a single directory entry in a leaf shard.
+type shardEntry struct {
+	tree    h.Hash
+	content h.Hash // nil for ghosts
+}
+
+// shardRef points to a shard that might not be loaded yet.
+type shardRef struct {
+	// key is the hash of the serialized shard.
+	// It is nil if the shard was modified and not serialized yet.
+	key     h.Hash
+	tree    h.Hash
+	content h.Hash
+	count   int
+
+	// node is nil until the shard was loaded.
+	node *shard
+}
+
+// shard is either a leaf with entries or an inner node with slots.
+type shard struct {
+	// Leaf:
+	entries map[string]shardEntry
+	order   []string
+
+	// Inner node; nil for leaves. Unused slots are nil.
+	slots []*shardRef
+
+	// data is the serialized shard. It is only valid if the key of
+	// the reference is not nil. saved is true once the linker stored it.
+	data  []byte
+	saved bool
+}
+
+func newLeafShard() *shard {
+	return &shard{entries: make(map[string]shardEntry)}
+}
+
+func (sh *shard) isLeaf() bool {
+	return sh.slots == nil
+}
+
+func (ref *shardRef) load(lkr Linker) (*shard, error) {
+	if ref.node != nil {
+		return ref.node, nil
+	}
+
+	data, err := lkr.ShardByHash(ref.key)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := unmarshalShard(data)
+	if err != nil {
+		return nil, err
+	}
+
+	node.data = data
+	node.saved = true
+	ref.node = node
+	return node, nil
+}
+
+// update recalculates the digests of `ref` after its shard was modified.
+func (ref *shardRef) update() {
+	sh := ref.node
+	tree := h.EmptyInternalHash().Clone()
+	content := h.EmptyInternalHash().Clone()
+	count := 0
+
+	if sh.isLeaf() {
+		for _, name := range sh.order {
+			entry := sh.entries[name]
+			tree = tree.Mix(entry.tree)
+			if entry.content != nil {
+				content = content.Mix(entry.content)
+			}
+		}
+
+		count = len(sh.order)
+	} else {
+		for _, slot := range sh.slots {
+			if slot == nil {
+				continue
+			}
+
+			tree = tree.Mix(slot.tree)
+			content = content.Mix(slot.content)
+			count += slot.count
+		}
+	}
+
+	ref.tree = tree
+	ref.content = content
+	ref.count = count
+	ref.key = nil
+	sh.data = nil
+	sh.saved = false
+}
+
+func (ref *shardRef) get(lkr Linker, name string, nameHash uint64, depth int) (shardEntry, bool, error) {
+	sh, err := ref.load(lkr)
+	if err != nil {
+		return shardEntry{}, false, err
+	}
+
+	if sh.isLeaf() {
+		entry, ok := sh.entries[name]
+		return entry, ok, nil
+	}
+
+	child := sh.slots[shardSlot(nameHash, depth)]
+	if child == nil {
+		return shardEntry{}, false, nil
+	}
+
+	return child.get(lkr, name, nameHash, depth+1)
+}
+
+// set updates the entry `name`. The content is only changed if `setContent` is true.
+func (ref *shardRef) set(lkr Linker, name string, nameHash uint64, depth int, tree, content h.Hash, setContent bool) error {
+	sh, err := ref.load(lkr)
+	if err != nil {
+		return err
+	}
+
+	if sh.isLeaf() {
+		entry, ok := sh.entries[name]
+		if !ok {
+			idx := sort.SearchStrings(sh.order, name)
+			sh.order = append(sh.order, "")
+			copy(sh.order[idx+1:], sh.order[idx:])
+			sh.order[idx] = name
+		}
+
+		entry.tree = tree
+		if setContent {
+			entry.content = content
+		}
+
+		sh.entries[name] = entry
+		if len(sh.order) > shardLeafSize && depth < shardMaxDepth {
+			if err := ref.split(depth); err != nil {
+				return err
+			}
+		}
+
+		ref.update()
+		return nil
+	}
+
+	slot := shardSlot(nameHash, depth)
+	child := sh.slots[slot]
+	if child == nil {
+		child = &shardRef{node: newLeafShard()}
+		sh.slots[slot] = child
+	}
+
+	if err := child.set(lkr, name, nameHash, depth+1, tree, content, setContent); err != nil {
+		return err
+	}
+
+	ref.update()
+	return nil
+}
+
+// split converts the leaf of `ref` to an inner node.
+func (ref *shardRef) split(depth int) error {
+	leaf := ref.node
+	inner := &shard{slots: make([]*shardRef, shardFanout)}
+	for _, name := range leaf.order {
+		slot := shardSlot(shardNameHash(name), depth)
+		child := inner.slots[slot]
+		if child == nil {
+			child = &shardRef{node: newLeafShard()}
+			inner.slots[slot] = child
+		}
+
+		// Names are visited in order, so appending keeps the order sorted:
+		child.node.order = append(child.node.order, name)
+		child.node.entries[name] = leaf.entries[name]
+	}
+
+	for _, child := range inner.slots {
+		if child == nil {
+			continue
+		}
+
+		// All entries might have ended up in the same slot:
+		if len(child.node.order) > shardLeafSize && depth+1 < shardMaxDepth {
+			if err := child.split(depth + 1); err != nil {
+				return err
+			}
+		}
+
+		child.update()
+	}
+
+	ref.node = inner
+	return nil
+}
+
+// remove deletes the entry `name`. Small inner nodes are merged back to a leaf.
+func (ref *shardRef) remove(lkr Linker, name string, nameHash uint64, depth int) error {
+	sh, err := ref.load(lkr)
+	if err != nil {
+		return err
+	}
+
+	if sh.isLeaf() {
+		if _, ok := sh.entries[name]; !ok {
+			return nil
+		}
+
+		delete(sh.entries, name)
+		idx := sort.SearchStrings(sh.order, name)
+		sh.order = append(sh.order[:idx], sh.order[idx+1:]...)
+		ref.update()
+		return nil
+	}
+
+	slot := shardSlot(nameHash, depth)
+	child := sh.slots[slot]
+	if child == nil {
+		return nil
+	}
+
+	if err := child.remove(lkr, name, nameHash, depth+1); err != nil {
+		return err
+	}
+
+	if child.count == 0 {
+		sh.slots[slot] = nil
+	}
+
+	ref.update()
+	if ref.count <= shardLeafSize {
+		return ref.merge(lkr)
+	}
+
+	return nil
+}
+
+// merge converts the inner node of `ref` back to a leaf.
+func (ref *shardRef) merge(lkr Linker) error {
+	leaf := newLeafShard()
+	err := ref.visitEntries(lkr, func(name string, entry shardEntry) error {
+		leaf.entries[name] = entry
+		leaf.order = append(leaf.order, name)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(leaf.order)
+	ref.node = leaf
+	ref.update()
+	return nil
+}
+
+// visitEntries calls `fn` for all entries below `ref` in no specific order.
+func (ref *shardRef) visitEntries(lkr Linker, fn func(name string, entry shardEntry) error) error {
+	sh, err := ref.load(lkr)
+	if err != nil {
+		return err
+	}
+
+	if sh.isLeaf() {
+		for _, name := range sh.order {
+			if err := fn(name, sh.entries[name]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, child := range sh.slots {
+		if child == nil {
+			continue
+		}
+
+		if err := child.visitEntries(lkr, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seal serializes all modified shards and calculates their keys.
+func (ref *shardRef) seal() error {
+	if ref.key != nil {
+		return nil
+	}
+
+	sh := ref.node
+	for _, child := range sh.slots {
+		if child == nil {
+			continue
+		}
+
+		if err := child.seal(); err != nil {
+			return err
+		}
+	}
+
+	data, err := sh.marshal()
+	if err != nil {
+		return err
+	}
+
+	sh.data = data
+	sh.saved = false
+	ref.key = h.Sum(data)
+	return nil
+}
+
+func (ref *shardRef) unsaved(fn func(key h.Hash, data []byte) error) error {
+	sh := ref.node
+	if sh == nil {
+		// Not loaded, so it's saved already.
+		return nil
+	}
+
+	if !sh.saved {
+		if err := fn(ref.key, sh.data); err != nil {
+			return err
+		}
+
+		sh.saved = true
+	}
+
+	for _, child := range sh.slots {
+		if child == nil {
+			continue
+		}
+
+		if err := child.unsaved(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ref *shardRef) visit(lkr Linker, fn func(key h.Hash, data []byte) (bool, error)) error {
+	sh, err := ref.load(lkr)
+	if err != nil {
+		return err
+	}
+
+	descend, err := fn(ref.key, sh.data)
+	if err != nil || !descend {
+		return err
+	}
+
+	for _, child := range sh.slots {
+		if child == nil {
+			continue
+		}
+
+		if err := child.visit(lkr, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clone returns a deep copy of all loaded shards below `ref`.
+func (ref *shardRef) clone() *shardRef {
+	copied := &shardRef{
+		key:     ref.key.Clone(),
+		tree:    ref.tree.Clone(),
+		content: ref.content.Clone(),
+		count:   ref.count,
+	}
+
+	if ref.node == nil {
+		return copied
+	}
+
+	sh := ref.node
+	copiedShard := &shard{
+		data:  sh.data,
+		saved: sh.saved,
+	}
+
+	if sh.isLeaf() {
+		copiedShard.entries = make(map[string]shardEntry, len(sh.entries))
+		for name, entry := range sh.entries {
+			copiedShard.entries[name] = shardEntry{
+				tree:    entry.tree.Clone(),
+				content: entry.content.Clone(),
+			}
+		}
+
+		copiedShard.order = make([]string, len(sh.order))
+		copy(copiedShard.order, sh.order)
+	} else {
+		copiedShard.slots = make([]*shardRef, shardFanout)
+		for idx, child := range sh.slots {
+			if child != nil {
+				copiedShard.slots[idx] = child.clone()
+			}
+		}
+	}
+
+	copied.node = copiedShard
+	return copied
+}
+
+/////// SERIALIZATION ///////
+
+func setShardRef(capRef capnp_model.DirShardRef, ref *shardRef) error {
+	if err := capRef.SetKey(ref.key); err != nil {
+		return err
+	}
+
+	if err := capRef.SetTree(ref.tree); err != nil {
+		return err
+	}
+
+	if err := capRef.SetContent(ref.content); err != nil {
+		return err
+	}
+
+	capRef.SetCount(uint64(ref.count))
+	return nil
+}
+
+func readShardRef(capRef capnp_model.DirShardRef) (*shardRef, error) {
+	key, err := capRef.Key()
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := capRef.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := capRef.Content()
+	if err != nil {
+		return nil, err
+	}
+
+	return &shardRef{
+		key:     h.Hash(key).Clone(),
+		tree:    h.Hash(tree).Clone(),
+		content: h.Hash(content).Clone(),
+		count:   int(capRef.Count()),
+	}, nil
+}
+
+func (sh *shard) marshal() ([]byte, error) {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	capShard, err := capnp_model.NewRootDirShard(seg)
+	if err != nil {
+		return nil, err
+	}
+
+	if sh.isLeaf() {
+		capEntries, err := capShard.NewLeaf(int32(len(sh.order)))
+		if err != nil {
+			return nil, err
+		}
+
+		for idx, name := range sh.order {
+			entry := sh.entries[name]
+			capEntry := capEntries.At(idx)
+			if err := capEntry.SetName(name); err != nil {
+				return nil, err
+			}
+
+			if err := capEntry.SetTree(entry.tree); err != nil {
+				return nil, err
+			}
+
+			if err := capEntry.SetContent(entry.content); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		capSlots, err := capShard.NewInner(int32(len(sh.slots)))
+		if err != nil {
+			return nil, err
+		}
+
+		for idx, child := range sh.slots {
+			if child == nil {
+				continue
+			}
+
+			if err := setShardRef(capSlots.At(idx), child); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return msg.Marshal()
+}
+
+func unmarshalShard(data []byte) (*shard, error) {
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	capShard, err := capnp_model.ReadRootDirShard(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch capShard.Which() {
+	case capnp_model.DirShard_Which_leaf:
+		capEntries, err := capShard.Leaf()
+		if err != nil {
+			return nil, err
+		}
+
+		sh := newLeafShard()
+		for idx := 0; idx < capEntries.Len(); idx++ {
+			capEntry := capEntries.At(idx)
+			name, err := capEntry.Name()
+			if err != nil {
+				return nil, err
+			}
+
+			tree, err := capEntry.Tree()
+			if err != nil {
+				return nil, err
+			}
+
+			content, err := capEntry.Content()
+			if err != nil {
+				return nil, err
+			}
+
+			entry := shardEntry{tree: h.Hash(tree).Clone()}
+			if len(content) > 0 {
+				entry.content = h.Hash(content).Clone()
+			}
+
+			sh.entries[name] = entry
+			sh.order = append(sh.order, name)
+		}
+
+		sort.Strings(sh.order)
+		return sh, nil
+	case capnp_model.DirShard_Which_inner:
+		capSlots, err := capShard.Inner()
+		if err != nil {
+			return nil, err
+		}
+
+		if capSlots.Len() != shardFanout {
+			return nil, fmt.Errorf("bad number of shard slots: %d", capSlots.Len())
+		}
+
+		sh := &shard{slots: make([]*shardRef, shardFanout)}
+		for idx := 0; idx < capSlots.Len(); idx++ {
+			capSlot := capSlots.At(idx)
+			if !capSlot.HasKey() {
+				continue
+			}
+
+			child, err := readShardRef(capSlot)
+			if err != nil {
+				return nil, err
+			}
+
+			sh.slots[idx] = child
+		}
+
+		return sh, nil
+	default:
+		return nil, fmt.Errorf("bad shard type: %d", capShard.Which())
+	}
+}
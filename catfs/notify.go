@@ -0,0 +1,89 @@
+package catfs
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OnChange registers `fn` to be called with the paths of all nodes that were
+// modified, e.g. by staging, removing, a sync or a reset. A path means that
+// the node itself and everything below it might have changed. The callbacks
+// run in a separate go routine, so they may call back into the filesystem.
+// Changes that happen in quick succession are delivered in one batch.
+// Can be called several times.
+func (fs *FS) OnChange(fn func(paths []string)) {
+	fs.changeMu.Lock()
+	defer fs.changeMu.Unlock()
+
+	fs.changeCallbacks = append(fs.changeCallbacks, fn)
+}
+
+// notifyChange queues `paths` for the change callbacks.
+// It never blocks, so it is fine to call it with fs.mu locked.
+func (fs *FS) notifyChange(paths ...string) {
+	fs.changeMu.Lock()
+	for _, path := range paths {
+		fs.pendingChanges = append(fs.pendingChanges, prefixSlash(path))
+	}
+	fs.changeMu.Unlock()
+
+	select {
+	case fs.changeControl <- true:
+	default:
+		// There is already a notification pending;
+		// it will pick up our paths too.
+	}
+}
+
+// compactChangePaths sorts `paths` and removes duplicates and
+// all paths that are already covered by one of their parents.
+func compactChangePaths(paths []string) []string {
+	sort.Strings(paths)
+
+	result := []string{}
+	for _, path := range paths {
+		isCovered := false
+		for _, prev := range result {
+			if prev == "/" || prev == path || strings.HasPrefix(path, prev+"/") {
+				isCovered = true
+				break
+			}
+		}
+
+		if !isCovered {
+			result = append(result, path)
+		}
+	}
+
+	return result
+}
+
+func (fs *FS) deliverChanges() {
+	fs.changeMu.Lock()
+	paths := compactChangePaths(fs.pendingChanges)
+	callbacks := fs.changeCallbacks
+	fs.pendingChanges = nil
+	fs.changeMu.Unlock()
+
+	if len(paths) == 0 {
+		return
+	}
+
+	log.Debugf("notifying about changes: %v", paths)
+	for _, fn := range callbacks {
+		fn(paths)
+	}
+}
+
+func (fs *FS) changeLoop() {
+	for state := range fs.changeControl {
+		if !state {
+			log.Debugf("quitting the change loop")
+			return
+		}
+
+		fs.deliverChanges()
+	}
+}
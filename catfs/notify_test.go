@@ -0,0 +1,67 @@
+package catfs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactChangePaths(t *testing.T) {
+	require.Equal(t, []string{"/a", "/b/c"}, compactChangePaths([]string{
+		"/b/c", "/a/x", "/a", "/b/c", "/a/y/z",
+	}))
+
+	require.Equal(t, []string{"/"}, compactChangePaths([]string{
+		"/x", "/", "/y",
+	}))
+
+	// Only real parents cover a path, not prefixes of the name:
+	require.Equal(t, []string{"/a", "/ab"}, compactChangePaths([]string{
+		"/ab", "/a",
+	}))
+}
+
+func TestOnChange(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		changes := make(chan []string, 10)
+		fs.OnChange(func(paths []string) {
+			changes <- paths
+		})
+
+		waitFor := func(expected ...string) map[string]bool {
+			seen := map[string]bool{}
+			timeout := time.After(5 * time.Second)
+			for _, path := range expected {
+				for !seen[path] {
+					select {
+					case paths := <-changes:
+						for _, path := range paths {
+							seen[path] = true
+						}
+					case <-timeout:
+						t.Fatalf("did not get notified about %v (got %v)", expected, seen)
+					}
+				}
+			}
+
+			return seen
+		}
+
+		require.NoError(t, fs.Stage("/dir/x", bytes.NewReader([]byte{1})))
+		waitFor("/dir/x")
+
+		require.NoError(t, fs.MakeCommit("x"))
+		require.NoError(t, fs.Move("/dir/x", "/y"))
+		waitFor("/dir/x", "/y")
+
+		require.NoError(t, fs.Checkout("head", true))
+		waitFor("/")
+
+		// Failed operations do not notify:
+		require.Error(t, fs.Remove("/nope"))
+		require.NoError(t, fs.Remove("/dir"))
+		require.False(t, waitFor("/dir")["/nope"])
+	})
+}
@@ -0,0 +1,404 @@
+package catfs
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sahib/brig/catfs/db"
+	ie "github.com/sahib/brig/catfs/errors"
+	n "github.com/sahib/brig/catfs/nodes"
+	h "github.com/sahib/brig/util/hashlib"
+	log "github.com/sirupsen/logrus"
+)
+
+// Folders can be marked as "always available offline". The marks are a local
+// preference and are not synchronized. The prefetcher makes sure that all
+// files below a mark are cached and explicitly pinned, also when new files
+// arrive later by a sync.
+
+const offlineMetadataKey = "fs.offline-paths"
+
+// PrefetchStatus describes what the prefetcher is currently doing.
+type PrefetchStatus struct {
+	// Running is true while files are being downloaded.
+	Running bool
+	// TotalFiles is the number of files that needed fetching in this run.
+	TotalFiles int64
+	// DoneFiles is the number of files that are fetched already.
+	DoneFiles int64
+	// TotalBytes is the size of all files that needed fetching.
+	TotalBytes uint64
+	// DoneBytes is the size of all fetched files.
+	DoneBytes uint64
+	// Current is the path of the file that is being fetched.
+	Current string
+	// LastError is the last error that happened during fetching.
+	LastError string
+}
+
+// prefetcher holds the state shared between the prefetch loop and the API.
+type prefetcher struct {
+	// runMu makes sure that only one run is active at a time.
+	runMu sync.Mutex
+
+	mu     sync.Mutex
+	status PrefetchStatus
+}
+
+func (pf *prefetcher) update(fn func(status *PrefetchStatus)) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	fn(&pf.status)
+}
+
+// prefetchItem is a file that needs to be fetched.
+// All attributes are copied, so it can be used without fs.mu.
+type prefetchItem struct {
+	path        string
+	inode       uint64
+	backendHash h.Hash
+	key         []byte
+	size        uint64
+	isRaw       bool
+}
+
+// offlinePaths returns all marked paths. fs.mu needs to be locked.
+func (fs *FS) offlinePaths() ([]string, error) {
+	data, err := fs.lkr.MetadataGet(offlineMetadataKey)
+	if err == db.ErrNoSuchKey || len(data) == 0 {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(string(data), "\n"), nil
+}
+
+func (fs *FS) setOfflinePaths(paths []string) error {
+	sort.Strings(paths)
+	return fs.lkr.MetadataPut(offlineMetadataKey, []byte(strings.Join(paths, "\n")))
+}
+
+// isBelowOfflinePath checks if `path` is inside of one of `offline`.
+func isBelowOfflinePath(path string, offline []string) bool {
+	for _, root := range offline {
+		if root == "/" || path == root || strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OfflinePaths returns all paths that were marked with SetOffline.
+func (fs *FS) OfflinePaths() ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.offlinePaths()
+}
+
+// IsOffline checks if `path` or one of its parents is marked
+// as "always available offline".
+func (fs *FS) IsOffline(path string) (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	offline, err := fs.offlinePaths()
+	if err != nil {
+		return false, err
+	}
+
+	return isBelowOfflinePath(prefixSlash(path), offline), nil
+}
+
+// SetOffline marks the file or directory at `path` as "always available
+// offline" if `enable` is true. The prefetcher will then download and pin
+// everything below it in the background. If `enable` is false, the mark is
+// removed again; the content stays pinned until it is unpinned explicitly.
+func (fs *FS) SetOffline(path string, enable bool) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.readOnly {
+		return ErrReadOnly
+	}
+
+	path = prefixSlash(path)
+	nd, err := fs.lkr.LookupNode(path)
+	if err != nil {
+		return err
+	}
+
+	if nd.Type() == n.NodeTypeGhost {
+		return ie.NoSuchFile(path)
+	}
+
+	offline, err := fs.offlinePaths()
+	if err != nil {
+		return err
+	}
+
+	newOffline := []string{}
+	for _, offlinePath := range offline {
+		if offlinePath != path {
+			newOffline = append(newOffline, offlinePath)
+		}
+	}
+
+	if enable {
+		newOffline = append(newOffline, path)
+	}
+
+	if err := fs.setOfflinePaths(newOffline); err != nil {
+		return err
+	}
+
+	if enable {
+		fs.schedulePrefetch()
+	}
+
+	return nil
+}
+
+// PrefetchStatus returns the progress of the current (or last) prefetch run.
+// A run that is scheduled but did not start yet counts as running.
+func (fs *FS) PrefetchStatus() PrefetchStatus {
+	fs.prefetch.mu.Lock()
+	defer fs.prefetch.mu.Unlock()
+
+	status := fs.prefetch.status
+	if !fs.readOnly && len(fs.prefetchControl) > 0 {
+		status.Running = true
+	}
+
+	return status
+}
+
+// prefetchItems collects all files below the offline marks that are either
+// not cached or not explicitly pinned yet. fs.mu needs to be locked.
+func (fs *FS) prefetchItems() ([]prefetchItem, error) {
+	offline, err := fs.offlinePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []prefetchItem{}
+	for _, root := range offline {
+		rootNd, err := fs.lkr.LookupNode(root)
+		if ie.IsNoSuchFileError(err) {
+			// Marked paths might have been removed since.
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = n.Walk(fs.lkr, rootNd, false, func(child n.Node) error {
+			file, ok := child.(*n.File)
+			if !ok {
+				return nil
+			}
+
+			isCached, err := fs.bk.IsCached(file.BackendHash())
+			if err != nil {
+				return err
+			}
+
+			_, isExplicit, err := fs.pinner.IsPinned(file.Inode(), file.BackendHash())
+			if err != nil {
+				return err
+			}
+
+			if isCached && isExplicit {
+				return nil
+			}
+
+			key := make([]byte, len(file.Key()))
+			copy(key, file.Key())
+
+			items = append(items, prefetchItem{
+				path:        file.Path(),
+				inode:       file.Inode(),
+				backendHash: file.BackendHash().Clone(),
+				key:         key,
+				size:        file.Size(),
+				isRaw:       file.IsRaw(),
+			})
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// fetchItem downloads the content of `item` by reading it once.
+// NOTE: This method can be called without locking fs.mu!
+func (fs *FS) fetchItem(item prefetchItem) error {
+	stream, err := fs.catHash(item.backendHash, item.key, item.size, item.isRaw)
+	if err != nil {
+		return err
+	}
+
+	defer stream.Close()
+
+	_, err = io.Copy(ioutil.Discard, stream)
+	return err
+}
+
+// Prefetch downloads and explicitly pins all files below the offline marks
+// that are not available yet. It blocks until all of them are processed.
+// Files that fail to download are skipped and retried on the next run.
+func (fs *FS) Prefetch() error {
+	fs.prefetch.runMu.Lock()
+	defer fs.prefetch.runMu.Unlock()
+
+	defer fs.prefetch.update(func(status *PrefetchStatus) {
+		status.Running = false
+		status.Current = ""
+	})
+
+	fs.mu.Lock()
+	if fs.closed {
+		fs.mu.Unlock()
+		return nil
+	}
+
+	items, err := fs.prefetchItems()
+	fs.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	totalBytes := uint64(0)
+	for _, item := range items {
+		totalBytes += item.size
+	}
+
+	fs.prefetch.update(func(status *PrefetchStatus) {
+		*status = PrefetchStatus{
+			Running:    len(items) > 0,
+			TotalFiles: int64(len(items)),
+			TotalBytes: totalBytes,
+		}
+	})
+
+	for _, item := range items {
+		fs.prefetch.update(func(status *PrefetchStatus) {
+			status.Current = item.path
+		})
+
+		if err := fs.fetchItem(item); err != nil {
+			log.Warningf("prefetch: failed to fetch %s: %v", item.path, err)
+			fs.prefetch.update(func(status *PrefetchStatus) {
+				status.LastError = err.Error()
+			})
+			continue
+		}
+
+		fs.mu.Lock()
+		if fs.closed {
+			fs.mu.Unlock()
+			return nil
+		}
+
+		err := fs.pinner.Pin(item.inode, item.backendHash, true)
+		fs.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		fs.prefetch.update(func(status *PrefetchStatus) {
+			status.DoneFiles++
+			status.DoneBytes += item.size
+		})
+	}
+
+	if len(items) > 0 {
+		log.Infof("prefetch: made %d files available offline", len(items))
+	}
+
+	return nil
+}
+
+// schedulePrefetch makes the prefetch loop run soon.
+func (fs *FS) schedulePrefetch() {
+	select {
+	case fs.prefetchControl <- true:
+	default:
+		// A run is already scheduled.
+	}
+}
+
+// prefetchOnChange schedules a prefetch run when new content
+// might have appeared below an offline mark.
+func (fs *FS) prefetchOnChange(paths []string) {
+	fs.mu.Lock()
+	if fs.closed {
+		fs.mu.Unlock()
+		return
+	}
+
+	offline, err := fs.offlinePaths()
+	fs.mu.Unlock()
+
+	if err != nil {
+		log.Warningf("prefetch: failed to read offline paths: %v", err)
+		return
+	}
+
+	for _, path := range paths {
+		for _, root := range offline {
+			// A change of a parent (e.g. "/" after a sync) counts too:
+			if isBelowOfflinePath(path, []string{root}) || isBelowOfflinePath(root, []string{path}) {
+				fs.schedulePrefetch()
+				return
+			}
+		}
+	}
+}
+
+func (fs *FS) prefetchLoop() {
+	if fs.readOnly {
+		return
+	}
+
+	// Also retry failed downloads once in a while:
+	retryTicker := time.NewTicker(5 * time.Minute)
+	defer retryTicker.Stop()
+
+	for {
+		select {
+		case state := <-fs.prefetchControl:
+			if !state {
+				log.Debugf("quitting the prefetch loop")
+				return
+			}
+		case <-retryTicker.C:
+		}
+
+		// Prefetch() corrects this once it knows if there is anything to do.
+		fs.prefetch.update(func(status *PrefetchStatus) {
+			status.Running = true
+		})
+
+		if err := fs.Prefetch(); err != nil {
+			log.Warningf("prefetch failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package catfs
+
+import (
+	"bytes"
+	"testing"
+
+	ie "github.com/sahib/brig/catfs/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineMarks(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		require.NoError(t, fs.Stage("/dir/a", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.Stage("/dir/sub/b", bytes.NewReader([]byte{2})))
+		require.NoError(t, fs.Stage("/c", bytes.NewReader([]byte{3})))
+
+		require.True(t, ie.IsNoSuchFileError(fs.SetOffline("/nope", true)))
+		require.NoError(t, fs.SetOffline("/dir", true))
+
+		paths, err := fs.OfflinePaths()
+		require.NoError(t, err)
+		require.Equal(t, []string{"/dir"}, paths)
+
+		for path, expected := range map[string]bool{
+			"/dir":       true,
+			"/dir/sub/b": true,
+			"/c":         false,
+			"/":          false,
+		} {
+			isOffline, err := fs.IsOffline(path)
+			require.NoError(t, err)
+			require.Equal(t, expected, isOffline, path)
+		}
+
+		require.NoError(t, fs.Prefetch())
+
+		status := fs.PrefetchStatus()
+		require.False(t, status.Running)
+		require.Equal(t, status.TotalFiles, status.DoneFiles)
+		require.Empty(t, status.LastError)
+
+		for path, expected := range map[string]bool{
+			"/dir/a":     true,
+			"/dir/sub/b": true,
+			"/c":         false,
+		} {
+			_, isExplicit, err := fs.IsPinned(path)
+			require.NoError(t, err)
+			require.Equal(t, expected, isExplicit, path)
+		}
+
+		// Nothing left to do:
+		fs.mu.Lock()
+		items, err := fs.prefetchItems()
+		fs.mu.Unlock()
+		require.NoError(t, err)
+		require.Empty(t, items)
+
+		// New files below a mark are picked up:
+		require.NoError(t, fs.Stage("/dir/d", bytes.NewReader([]byte{4})))
+		fs.mu.Lock()
+		items, err = fs.prefetchItems()
+		fs.mu.Unlock()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.Equal(t, "/dir/d", items[0].path)
+
+		require.NoError(t, fs.SetOffline("/dir", false))
+		paths, err = fs.OfflinePaths()
+		require.NoError(t, err)
+		require.Empty(t, paths)
+	})
+}
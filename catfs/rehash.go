@@ -0,0 +1,384 @@
+package catfs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs/db"
+	n "github.com/sahib/brig/catfs/nodes"
+	h "github.com/sahib/brig/util/hashlib"
+	log "github.com/sirupsen/logrus"
+)
+
+// RehashStats is returned by Rehash and describes what was rewritten.
+type RehashStats struct {
+	SrcAlgorithm string
+	DstAlgorithm string
+	Nodes        int
+	Files        int
+	Keys         int
+
+	// Unreadable is the number of files whose content could not be read.
+	// Their new content hash is derived from the old one.
+	Unreadable int
+}
+
+// rehasher implements n.Rehasher on top of the raw keys of a database.
+type rehasher struct {
+	fs   *FS
+	algo h.Algorithm
+
+	// old b58 hash -> serialized node or shard
+	nodes  map[string][]byte
+	shards map[string][]byte
+
+	// old b58 hash -> rehashed node
+	done map[string]n.Node
+
+	// backend and old content hash -> new content hash
+	contents map[string]h.Hash
+
+	stats *RehashStats
+}
+
+func (rh *rehasher) rehash(b58 string) (n.Node, error) {
+	if nd, ok := rh.done[b58]; ok {
+		return nd, nil
+	}
+
+	data, ok := rh.nodes[b58]
+	if !ok {
+		return nil, nil
+	}
+
+	nd, err := n.UnmarshalNode(data)
+	if err != nil {
+		return nil, e.Wrapf(err, "failed to load node %s", b58)
+	}
+
+	if err := n.Rehash(nd, rh); err != nil {
+		return nil, e.Wrapf(err, "failed to rehash %s", nd.Path())
+	}
+
+	rh.done[b58] = nd
+	rh.stats.Nodes++
+	return nd, nil
+}
+
+// NodeHashes implements n.Rehasher.
+func (rh *rehasher) NodeHashes(old h.Hash) (h.Hash, h.Hash, error) {
+	nd, err := rh.rehash(old.B58String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if nd == nil {
+		// Might happen for references to nodes that were already
+		// garbage collected. Give them a stable, new-style hash.
+		log.Warningf("rehash: no node with hash %s; deriving new hash", old.B58String())
+		derived := h.SumWith(rh.algo, old)
+		return derived, derived, nil
+	}
+
+	return nd.TreeHash(), nd.ContentHash(), nil
+}
+
+// FileContent implements n.Rehasher.
+func (rh *rehasher) FileContent(file *n.File) (h.Hash, error) {
+	oldContent := file.ContentHash()
+	if oldContent == nil {
+		return nil, nil
+	}
+
+	cacheKey := file.BackendHash().B58String() + "|" + oldContent.B58String()
+	if content, ok := rh.contents[cacheKey]; ok {
+		return content, nil
+	}
+
+	content, err := rh.hashContent(file)
+	if err != nil {
+		log.WithError(err).Warningf(
+			"rehash: failed to read %s; deriving new content hash",
+			file.Path(),
+		)
+
+		content = h.SumWith(rh.algo, oldContent)
+		rh.stats.Unreadable++
+	}
+
+	rh.contents[cacheKey] = content
+	rh.stats.Files++
+	return content, nil
+}
+
+func (rh *rehasher) hashContent(file *n.File) (h.Hash, error) {
+	stream, err := rh.fs.catHash(file.BackendHash(), file.Key(), file.Size(), file.IsRaw())
+	if err != nil {
+		return nil, err
+	}
+
+	defer stream.Close()
+
+	newWriter, err := h.NewHashWriterWith(rh.algo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Also check if we read the content that we think we read:
+	oldWriter, err := h.NewHashWriterLike(file.ContentHash())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(io.MultiWriter(newWriter, oldWriter), stream); err != nil {
+		return nil, err
+	}
+
+	if !oldWriter.Finalize().Equal(file.ContentHash()) {
+		return nil, fmt.Errorf("content does not match its hash")
+	}
+
+	return newWriter.Finalize(), nil
+}
+
+// Shard implements n.Rehasher.
+func (rh *rehasher) Shard(key h.Hash) ([]byte, error) {
+	data, ok := rh.shards[key.B58String()]
+	if !ok {
+		return nil, fmt.Errorf("no such shard: %s", key.B58String())
+	}
+
+	return data, nil
+}
+
+func (rh *rehasher) mapB58(b58 string) string {
+	if nd, ok := rh.done[b58]; ok {
+		return nd.TreeHash().B58String()
+	}
+
+	return b58
+}
+
+// mapValue replaces all known hashes in a value like "> hash <b58>".
+func (rh *rehasher) mapValue(val []byte) []byte {
+	parts := strings.Split(string(val), " ")
+	for idx, part := range parts {
+		parts[idx] = rh.mapB58(part)
+	}
+
+	return []byte(strings.Join(parts, " "))
+}
+
+func unsavedShards(nd n.Node, fn func(key h.Hash, data []byte) error) error {
+	if ghost, ok := nd.(*n.Ghost); ok {
+		nd = ghost.OldNode()
+	}
+
+	dir, ok := nd.(*n.Directory)
+	if !ok {
+		return nil
+	}
+
+	return dir.UnsavedShards(fn)
+}
+
+// Rehash writes a copy of the metadata to a new database at `dstPath`, in
+// which all content and tree hashes (and therefore all commit hashes) are
+// computed with `algo`. The content of all files is read from the backend
+// for this. The database of `fs` is not modified.
+//
+// Rehash temporarily changes the global hash algorithm and should
+// therefore only be called when nothing else computes hashes.
+func (fs *FS) Rehash(dstPath string, algo h.Algorithm) (*RehashStats, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if !algo.IsValid() {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+
+	// Make sure we don't merge into existing data by accident:
+	entries, err := ioutil.ReadDir(dstPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("destination %s is not empty", dstPath)
+	}
+
+	keys, err := fs.kv.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &RehashStats{
+		SrcAlgorithm: h.InternalAlgorithm().String(),
+		DstAlgorithm: algo.String(),
+	}
+
+	rh := &rehasher{
+		fs:       fs,
+		algo:     algo,
+		nodes:    make(map[string][]byte),
+		shards:   make(map[string][]byte),
+		done:     make(map[string]n.Node),
+		contents: make(map[string]h.Hash),
+		stats:    stats,
+	}
+
+	type entry struct {
+		key []string
+		val []byte
+	}
+
+	objects := []entry{}
+	others := []entry{}
+	var statusData []byte
+
+	for _, rawKey := range keys {
+		key := CanonicalKey(fs.dbType, rawKey)
+		val, err := fs.kv.Get(rawKey...)
+		if err != nil {
+			return nil, e.Wrapf(err, "failed to read key %v", key)
+		}
+
+		fullKey := strings.Join(key, ".")
+		switch {
+		case strings.HasPrefix(fullKey, "objects."),
+			strings.HasPrefix(fullKey, "stage.objects."):
+			rh.nodes[key[len(key)-1]] = val
+			objects = append(objects, entry{key: key, val: val})
+		case strings.HasPrefix(fullKey, "shards."),
+			strings.HasPrefix(fullKey, "stage.shards."):
+			// Shards are written again by the directories referencing them.
+			rh.shards[key[len(key)-1]] = val
+		case fullKey == "stage.STATUS":
+			statusData = val
+		default:
+			others = append(others, entry{key: key, val: val})
+		}
+	}
+
+	oldAlgo := h.InternalAlgorithm()
+	if err := h.SetInternalAlgorithm(algo); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := h.SetInternalAlgorithm(oldAlgo); err != nil {
+			log.WithError(err).Warningf("failed to restore hash algorithm")
+		}
+	}()
+
+	// Sort for a deterministic order of log messages:
+	sort.Slice(objects, func(i, j int) bool {
+		return strings.Join(objects[i].key, ".") < strings.Join(objects[j].key, ".")
+	})
+
+	for _, object := range objects {
+		if _, err := rh.rehash(object.key[len(object.key)-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	var status n.Node
+	if statusData != nil {
+		// The status commit is not stored as object,
+		// but other keys (like refs/CURR) refer to it:
+		status, err = n.UnmarshalNode(statusData)
+		if err != nil {
+			return nil, e.Wrapf(err, "failed to load status")
+		}
+
+		oldB58 := status.TreeHash().B58String()
+		if err := n.Rehash(status, rh); err != nil {
+			return nil, e.Wrapf(err, "failed to rehash status")
+		}
+
+		rh.done[oldB58] = status
+	}
+
+	dst, err := db.Open(fs.dbType, dstPath)
+	if err != nil {
+		return nil, e.Wrapf(err, "failed to open destination database")
+	}
+
+	defer dst.Close()
+
+	batch := dst.Batch()
+	put := func(val []byte, key ...string) {
+		batch.Put(val, key...)
+		stats.Keys++
+	}
+
+	for _, object := range objects {
+		prefix := object.key[:len(object.key)-1]
+		nd := rh.done[object.key[len(object.key)-1]]
+
+		err := unsavedShards(nd, func(key h.Hash, data []byte) error {
+			shardPrefix := append([]string{}, prefix...)
+			shardPrefix[len(shardPrefix)-1] = "shards"
+			put(data, append(shardPrefix, key.B58String())...)
+			return nil
+		})
+
+		if err != nil {
+			batch.Rollback()
+			return nil, err
+		}
+
+		data, err := n.MarshalNode(nd)
+		if err != nil {
+			batch.Rollback()
+			return nil, err
+		}
+
+		put(data, append(append([]string{}, prefix...), nd.TreeHash().B58String())...)
+	}
+
+	if status != nil {
+		data, err := n.MarshalNode(status)
+		if err != nil {
+			batch.Rollback()
+			return nil, err
+		}
+
+		put(data, "stage", "STATUS")
+	}
+
+	for _, other := range others {
+		key, val := other.key, other.val
+		switch key[0] {
+		case "metadata", "stats":
+			// Those do not contain any hashes.
+		case "moves":
+			key = append([]string{}, key...)
+			for idx := range key {
+				key[idx] = rh.mapB58(key[idx])
+			}
+
+			val = rh.mapValue(val)
+		default:
+			val = rh.mapValue(val)
+		}
+
+		put(val, key...)
+	}
+
+	if err := batch.Flush(); err != nil {
+		return nil, err
+	}
+
+	log.Infof(
+		"rehashed %d nodes and %d files from %s to %s",
+		stats.Nodes, stats.Files, stats.SrcAlgorithm, stats.DstAlgorithm,
+	)
+
+	return stats, nil
+}
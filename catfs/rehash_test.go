@@ -0,0 +1,116 @@
+package catfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	h "github.com/sahib/brig/util/hashlib"
+	"github.com/stretchr/testify/require"
+)
+
+func logMessages(t *testing.T, fs *FS) ([]string, []h.Hash) {
+	msgs, hashes := []string{}, []h.Hash{}
+	require.NoError(t, fs.Log("", func(c *Commit) error {
+		msgs = append(msgs, c.Msg)
+		hashes = append(hashes, c.Hash)
+		return nil
+	}))
+
+	return msgs, hashes
+}
+
+// NOTE: Not parallel, since it changes the global hash algorithm.
+func TestRehash(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "brig-rehash-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(baseDir)
+
+	defer h.SetInternalAlgorithm(h.DefaultAlgorithm)
+
+	backend := NewMemFsBackend()
+	srcPath := filepath.Join(baseDir, "src")
+	dstPath := filepath.Join(baseDir, "dst")
+
+	var oldMsgs []string
+	var oldHashes []h.Hash
+	var oldContent h.Hash
+
+	withFSAt(t, backend, srcPath, func(fs *FS) {
+		require.NoError(t, fs.Mkdir("/sub/x", true))
+		require.NoError(t, fs.Stage("/sub/x/a.txt", bytes.NewReader([]byte("hello"))))
+		require.NoError(t, fs.MakeCommit("first"))
+		require.NoError(t, fs.Stage("/b.txt", bytes.NewReader([]byte("world"))))
+		require.NoError(t, fs.Move("/sub/x/a.txt", "/sub/c.txt"))
+		require.NoError(t, fs.MakeCommit("second"))
+		require.NoError(t, fs.Tag("head", "release"))
+		require.NoError(t, fs.Stage("/b.txt", bytes.NewReader([]byte("world!"))))
+
+		info, err := fs.Stat("/sub/c.txt")
+		require.NoError(t, err)
+		oldContent = info.ContentHash
+
+		oldMsgs, oldHashes = logMessages(t, fs)
+
+		stats, err := fs.Rehash(dstPath, h.AlgoSHA256)
+		require.NoError(t, err)
+		require.Equal(t, "blake2s", stats.SrcAlgorithm)
+		require.Equal(t, "sha2-256", stats.DstAlgorithm)
+		require.Equal(t, 0, stats.Unreadable)
+		require.True(t, stats.Files > 0)
+
+		// The algorithm is only changed during the rehash:
+		require.Equal(t, h.DefaultAlgorithm, h.InternalAlgorithm())
+
+		// Refuse to overwrite existing data:
+		_, err = fs.Rehash(dstPath, h.AlgoSHA256)
+		require.Error(t, err)
+	})
+
+	require.NoError(t, h.SetInternalAlgorithm(h.AlgoSHA256))
+	withFSAt(t, backend, dstPath, func(fs *FS) {
+		info, err := fs.Stat("/sub/c.txt")
+		require.NoError(t, err)
+		require.Equal(t, h.SumWith(h.AlgoSHA256, []byte("hello")), info.ContentHash)
+		require.NotEqual(t, oldContent, info.ContentHash)
+
+		algo, err := info.TreeHash.Algorithm()
+		require.NoError(t, err)
+		require.Equal(t, h.AlgoSHA256, algo)
+
+		stream, err := fs.Cat("/b.txt")
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(stream)
+		require.NoError(t, err)
+		require.Equal(t, []byte("world!"), data)
+
+		msgs, hashes := logMessages(t, fs)
+		require.Equal(t, oldMsgs, msgs)
+		require.Len(t, hashes, len(oldHashes))
+		for idx := range hashes {
+			require.NotEqual(t, oldHashes[idx], hashes[idx])
+		}
+
+		// Tags and the move history survived:
+		releaseMsgs := []string{}
+		require.NoError(t, fs.Log("release", func(c *Commit) error {
+			releaseMsgs = append(releaseMsgs, c.Msg)
+			return nil
+		}))
+		require.Equal(t, "second", releaseMsgs[0])
+
+		changes, err := fs.History("/sub/c.txt")
+		require.NoError(t, err)
+		require.True(t, len(changes) > 1)
+		require.Equal(t, "/sub/x/a.txt", changes[len(changes)-1].Path)
+
+		// The new filesystem can be used normally:
+		require.NoError(t, fs.Stage("/sub/d.txt", bytes.NewReader([]byte("new"))))
+		require.NoError(t, fs.MakeCommit("third"))
+
+		msgs, _ = logMessages(t, fs)
+		require.Contains(t, msgs, "third")
+	})
+}
@@ -29,8 +29,9 @@ type partition struct {
 }
 
 // partitionNodeHashes takes all hashes of a node and sorts them into the
-// buckets described in the partition docs.
-func (fs *FS) partitionNodeHashes(nd n.ModNode, minDepth, maxDepth int64) (*partition, error) {
+// buckets described in the partition docs. Versions whose backend hash is
+// in `protected` are always sorted into ShouldPin.
+func (fs *FS) partitionNodeHashes(nd n.ModNode, minDepth, maxDepth int64, protected map[string]bool) (*partition, error) {
 	currDepth := int64(0)
 	part := &partition{}
 
@@ -59,7 +60,7 @@ func (fs *FS) partitionNodeHashes(nd n.ModNode, minDepth, maxDepth int64) (*part
 		}
 
 		// Sort the entry into the right bucket:
-		if currDepth < minDepth {
+		if currDepth < minDepth || protected[curr.BackendHash().B58String()] {
 			part.ShouldPin = append(part.ShouldPin, curr)
 			part.PinSize += nd.Size()
 		} else if currDepth >= minDepth && currDepth < maxDepth {
@@ -247,6 +248,15 @@ func (fs *FS) repin(root string) error {
 	savedStorage := uint64(0)
 	parts := []*partition{}
 
+	// Versions that are part of a snapshot should stay available:
+	protected := make(map[string]bool)
+	if fs.cfg.Bool("snapshots.pin") {
+		protected, err = fs.snapshotBackendHashes()
+		if err != nil {
+			return e.Wrapf(err, "repin: snapshots")
+		}
+	}
+
 	log.Infof("repin started (min=%d max=%d quota=%s)", minDepth, maxDepth, quotaSrc)
 
 	err = n.Walk(fs.lkr, rootNd, true, func(child n.Node) error {
@@ -259,7 +269,7 @@ func (fs *FS) repin(root string) error {
 			return e.Wrapf(ie.ErrBadNode, "repin")
 		}
 
-		part, err := fs.partitionNodeHashes(modChild, minDepth, maxDepth)
+		part, err := fs.partitionNodeHashes(modChild, minDepth, maxDepth, protected)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,227 @@
+package catfs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	ie "github.com/sahib/brig/catfs/errors"
+	n "github.com/sahib/brig/catfs/nodes"
+	log "github.com/sirupsen/logrus"
+)
+
+const snapshotTagPrefix = "snap"
+
+// snapshotPeriod describes one kind of snapshot and how its tags are named.
+// The generated names sort in chronological order and only consist of
+// letters and numbers, so they are valid refs.
+type snapshotPeriod struct {
+	name   string
+	format func(t time.Time) string
+
+	// pattern matches exactly the tags generated by format.
+	pattern *regexp.Regexp
+}
+
+var snapshotPeriods = []snapshotPeriod{
+	{
+		name:    "hourly",
+		format:  func(t time.Time) string { return t.Format("2006010215") },
+		pattern: regexp.MustCompile(`^snaphourly[0-9]{10}$`),
+	}, {
+		name:    "daily",
+		format:  func(t time.Time) string { return t.Format("20060102") },
+		pattern: regexp.MustCompile(`^snapdaily[0-9]{8}$`),
+	}, {
+		name: "weekly",
+		format: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%04dw%02d", year, week)
+		},
+		pattern: regexp.MustCompile(`^snapweekly[0-9]{4}w[0-9]{2}$`),
+	}, {
+		name:    "monthly",
+		format:  func(t time.Time) string { return t.Format("200601") },
+		pattern: regexp.MustCompile(`^snapmonthly[0-9]{6}$`),
+	},
+}
+
+func (sp snapshotPeriod) tagName(t time.Time) string {
+	return snapshotTagPrefix + sp.name + sp.format(t)
+}
+
+func (sp snapshotPeriod) isTag(name string) bool {
+	return sp.pattern.MatchString(name)
+}
+
+// isSnapshotTag checks if `name` was generated by the snapshot scheduler.
+func isSnapshotTag(name string) bool {
+	for _, period := range snapshotPeriods {
+		if period.isTag(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SnapshotResult describes what TakeSnapshots did.
+type SnapshotResult struct {
+	// Created are the names of the newly created snapshot tags.
+	Created []string
+	// Pruned are the names of the snapshot tags that were removed.
+	Pruned []string
+}
+
+// TakeSnapshots tags HEAD for every snapshot period (hourly, daily, weekly,
+// monthly) that has no snapshot for the period `now` falls into yet.
+// Afterwards, only the newest fs.snapshots.keep_<period> tags of each period
+// are kept, older ones are removed. Uncommitted changes are not part of
+// a snapshot; the auto commit loop is supposed to take care of them.
+func (fs *FS) TakeSnapshots(now time.Time) (*SnapshotResult, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	result := &SnapshotResult{}
+	head, err := fs.lkr.Head()
+	if err != nil {
+		if ie.IsErrNoSuchRef(err) {
+			// Nothing committed yet, nothing to snapshot.
+			return result, nil
+		}
+
+		return nil, err
+	}
+
+	refs, err := fs.lkr.ListRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for _, ref := range refs {
+		existing[ref] = true
+	}
+
+	for _, period := range snapshotPeriods {
+		keep := fs.cfg.Int("snapshots.keep_" + period.name)
+		tagName := period.tagName(now)
+
+		if keep > 0 && !existing[tagName] {
+			if err := fs.lkr.SaveRef(tagName, head); err != nil {
+				return nil, err
+			}
+
+			log.Infof("created snapshot %s", tagName)
+			result.Created = append(result.Created, tagName)
+			refs = append(refs, tagName)
+		}
+
+		periodTags := []string{}
+		for _, ref := range refs {
+			if period.isTag(ref) {
+				periodTags = append(periodTags, ref)
+			}
+		}
+
+		if int64(len(periodTags)) <= keep {
+			continue
+		}
+
+		// Newest first; everything after the first `keep` tags goes.
+		sort.Sort(sort.Reverse(sort.StringSlice(periodTags)))
+		for _, tagName := range periodTags[keep:] {
+			if err := fs.lkr.RemoveRef(tagName); err != nil {
+				return nil, err
+			}
+
+			log.Infof("pruned snapshot %s", tagName)
+			result.Pruned = append(result.Pruned, tagName)
+		}
+	}
+
+	return result, nil
+}
+
+// snapshotBackendHashes returns the backend hashes of all files
+// that are part of any snapshot. Needs fs.mu to be locked.
+func (fs *FS) snapshotBackendHashes() (map[string]bool, error) {
+	refs, err := fs.lkr.ListRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]bool)
+	seenRoots := make(map[string]bool)
+	for _, ref := range refs {
+		if !isSnapshotTag(ref) {
+			continue
+		}
+
+		nd, err := fs.lkr.ResolveRef(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		cmt, ok := nd.(*n.Commit)
+		if !ok {
+			return nil, ie.ErrBadNode
+		}
+
+		// Snapshots of different periods often share the same commit:
+		if seenRoots[cmt.Root().B58String()] {
+			continue
+		}
+
+		seenRoots[cmt.Root().B58String()] = true
+
+		root, err := fs.lkr.DirectoryByHash(cmt.Root())
+		if err != nil {
+			return nil, err
+		}
+
+		err = n.Walk(fs.lkr, root, true, func(child n.Node) error {
+			if child.Type() == n.NodeTypeFile {
+				hashes[child.BackendHash().B58String()] = true
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hashes, nil
+}
+
+func (fs *FS) snapshotLoop() {
+	if fs.readOnly {
+		return
+	}
+
+	checkTicker := time.NewTicker(1 * time.Minute)
+	defer checkTicker.Stop()
+
+	for {
+		select {
+		case <-fs.snapshotControl:
+			log.Debugf("quitting the snapshot loop")
+			return
+		case <-checkTicker.C:
+			if !fs.cfg.Bool("snapshots.enabled") {
+				continue
+			}
+
+			if _, err := fs.TakeSnapshots(time.Now()); err != nil {
+				log.Warningf("failed to take snapshots: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package catfs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotTags(t *testing.T, fs *FS) []string {
+	refs, err := fs.lkr.ListRefs()
+	require.NoError(t, err)
+
+	tags := []string{}
+	for _, ref := range refs {
+		if isSnapshotTag(ref) {
+			tags = append(tags, ref)
+		}
+	}
+
+	return tags
+}
+
+func TestSnapshotsRetention(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		fs.cfg.SetInt("snapshots.keep_hourly", 3)
+		fs.cfg.SetInt("snapshots.keep_daily", 2)
+		fs.cfg.SetInt("snapshots.keep_weekly", 1)
+		fs.cfg.SetInt("snapshots.keep_monthly", 0)
+
+		// Without any commit there is nothing to snapshot:
+		now := time.Date(2020, 1, 31, 10, 30, 0, 0, time.UTC)
+		result, err := fs.TakeSnapshots(now)
+		require.NoError(t, err)
+		require.Empty(t, result.Created)
+
+		require.NoError(t, fs.Stage("/x", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.MakeCommit("first"))
+
+		result, err = fs.TakeSnapshots(now)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"snaphourly2020013110",
+			"snapdaily20200131",
+			"snapweekly2020w05",
+		}, result.Created)
+
+		// Same period, nothing new:
+		result, err = fs.TakeSnapshots(now.Add(10 * time.Minute))
+		require.NoError(t, err)
+		require.Empty(t, result.Created)
+		require.Empty(t, result.Pruned)
+
+		for hour := 1; hour <= 48; hour++ {
+			_, err := fs.TakeSnapshots(now.Add(time.Duration(hour) * time.Hour))
+			require.NoError(t, err)
+		}
+
+		tags := snapshotTags(t, fs)
+		hourly, daily, weekly := 0, 0, 0
+		for _, tag := range tags {
+			switch {
+			case strings.HasPrefix(tag, "snaphourly"):
+				hourly++
+			case strings.HasPrefix(tag, "snapdaily"):
+				daily++
+			case strings.HasPrefix(tag, "snapweekly"):
+				weekly++
+			default:
+				t.Fatalf("unexpected snapshot tag: %s", tag)
+			}
+		}
+
+		require.Equal(t, 3, hourly)
+		require.Equal(t, 2, daily)
+		require.Equal(t, 1, weekly)
+
+		// The newest ones should have survived:
+		require.Contains(t, tags, "snaphourly2020020210")
+		require.Contains(t, tags, "snapdaily20200202")
+
+		// Snapshots can be used like every other tag:
+		cmt, err := fs.CommitInfo("snapdaily20200202")
+		require.NoError(t, err)
+		require.Equal(t, "first", cmt.Msg)
+
+		// Disabling a period removes its snapshots:
+		fs.cfg.SetInt("snapshots.keep_hourly", 0)
+		result, err = fs.TakeSnapshots(now.Add(49 * time.Hour))
+		require.NoError(t, err)
+		require.Len(t, result.Pruned, 3)
+	})
+}
+
+func TestSnapshotsKeepUserTags(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		fs.cfg.SetInt("snapshots.keep_hourly", 0)
+		fs.cfg.SetInt("snapshots.keep_daily", 1)
+		fs.cfg.SetInt("snapshots.keep_weekly", 0)
+		fs.cfg.SetInt("snapshots.keep_monthly", 0)
+
+		require.NoError(t, fs.Stage("/x", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.MakeCommit("first"))
+
+		// Those only look like snapshot tags:
+		userTags := []string{"snapdailyrelease", "snapdaily2020", "snaphourlyx"}
+		for _, tag := range userTags {
+			require.NoError(t, fs.Tag("HEAD", tag))
+			require.False(t, isSnapshotTag(tag))
+		}
+
+		now := time.Date(2020, 1, 31, 10, 30, 0, 0, time.UTC)
+		for day := 0; day < 3; day++ {
+			_, err := fs.TakeSnapshots(now.Add(time.Duration(day) * 24 * time.Hour))
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []string{"snapdaily20200202"}, snapshotTags(t, fs))
+		for _, tag := range userTags {
+			_, err := fs.CommitInfo(tag)
+			require.NoError(t, err)
+		}
+	})
+}
+
+func TestRepinKeepsSnapshots(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		fs.cfg.SetBool("repin.enabled", true)
+		fs.cfg.SetString("repin.quota", "10G")
+		fs.cfg.SetInt("repin.min_depth", 1)
+		fs.cfg.SetInt("repin.max_depth", 1)
+		fs.cfg.SetBool("snapshots.pin", true)
+
+		now := time.Date(2020, 1, 31, 10, 30, 0, 0, time.UTC)
+		for idx := 0; idx < 5; idx++ {
+			require.NoError(t, fs.Stage("/dir/a", bytes.NewReader([]byte{byte(idx)})))
+			require.NoError(t, fs.MakeCommit(fmt.Sprintf("state: %d", idx)))
+
+			// Only the very first state gets a snapshot:
+			if idx == 0 {
+				_, err := fs.TakeSnapshots(now)
+				require.NoError(t, err)
+			}
+		}
+
+		for idx := 0; idx < 5; idx++ {
+			require.NoError(t, fs.Pin("/dir/a", "HEAD"+strings.Repeat("^", idx), false))
+		}
+
+		require.NoError(t, fs.repin("/"))
+
+		histA, err := fs.History("/dir/a")
+		require.NoError(t, err)
+
+		require.True(t, histA[0].IsPinned)
+		require.False(t, histA[3].IsPinned)
+
+		// The snapshotted version is the oldest one:
+		require.True(t, histA[len(histA)-1].IsPinned)
+	})
+}
@@ -0,0 +1,152 @@
+package catfs
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	e "github.com/pkg/errors"
+	n "github.com/sahib/brig/catfs/nodes"
+)
+
+// ErrStageIncomplete is returned by StageBatch when some entries failed.
+var ErrStageIncomplete = errors.New("some files could not be staged")
+
+// StageEntry is a single file that should be staged by StageBatch.
+type StageEntry struct {
+	// Path is the path of the file inside the repository.
+	Path string
+
+	// Open should return the content of the file. It is only called
+	// once a worker is free, so not all files are opened at the same time.
+	Open func() (io.ReadCloser, error)
+}
+
+type stageBatchResult struct {
+	path    string
+	content *stagedContent
+	err     error
+}
+
+// StageBatch stages all files in `entries` at once. This is a lot faster
+// than calling Stage for each file when staging many small files:
+//
+// - The content of the files is hashed, compressed, encrypted and added to
+//   the backend by several workers in parallel (see fs.stage.workers).
+// - The metadata of all files is staged afterwards in a single database
+//   transaction, instead of one per file.
+//
+// If `progress` is not nil, it is called once the content of an entry was
+// added (or failed to be added). Entries that failed are skipped; an error
+// wrapping ErrStageIncomplete is returned after the other entries were staged.
+// If staging the metadata fails, none of the entries are staged.
+func (fs *FS) StageBatch(entries []StageEntry, progress func(path string, err error)) error {
+	if fs.readOnly {
+		return ErrReadOnly
+	}
+
+	if progress == nil {
+		progress = func(path string, err error) {}
+	}
+
+	nWorkers := int(fs.cfg.Int("stage.workers"))
+	if nWorkers < 1 {
+		nWorkers = 1
+	}
+
+	jobs := make(chan StageEntry)
+	results := make(chan stageBatchResult)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(nWorkers)
+	for idx := 0; idx < nWorkers; idx++ {
+		go func() {
+			defer wg.Done()
+			for entry := range jobs {
+				path := prefixSlash(entry.Path)
+				content, err := fs.addEntryContent(path, entry)
+				results <- stageBatchResult{path: path, content: content, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, entry := range entries {
+			jobs <- entry
+		}
+
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	nFailed := 0
+	contents := []*stagedContent{}
+	for result := range results {
+		progress(result.path, result.err)
+		if result.err != nil {
+			nFailed++
+			continue
+		}
+
+		contents = append(contents, result.content)
+	}
+
+	if err := fs.stageContents(contents); err != nil {
+		return err
+	}
+
+	if nFailed > 0 {
+		return e.Wrapf(ErrStageIncomplete, "%d of %d failed", nFailed, len(entries))
+	}
+
+	return nil
+}
+
+func (fs *FS) addEntryContent(path string, entry StageEntry) (*stagedContent, error) {
+	key, err := fs.preStageKeyGen(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+	return fs.addContent(path, fd, key)
+}
+
+// stageContents stages the metadata of all `contents` in one transaction.
+func (fs *FS) stageContents(contents []*stagedContent) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	files := []*n.File{}
+	err := fs.lkr.Atomic(func() (bool, error) {
+		for _, content := range contents {
+			file, err := fs.stageContent(content)
+			if err != nil {
+				return true, e.Wrapf(err, "stage %s", content.path)
+			}
+
+			files = append(files, file)
+		}
+
+		return false, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		fs.notifyChange(file.Path())
+		if err := fs.pinner.PinNode(file, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package catfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	e "github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageBatch(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		require.NoError(t, fs.Mkdir("/dir", false))
+
+		entries := []StageEntry{}
+		for idx := 0; idx < 100; idx++ {
+			data := []byte(fmt.Sprintf("content %d", idx))
+			entries = append(entries, StageEntry{
+				Path: fmt.Sprintf("/sub/%d", idx),
+				Open: func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(data)), nil
+				},
+			})
+		}
+
+		// Two entries that fail; the rest should be staged anyways:
+		entries = append(entries, StageEntry{
+			Path: "/dir",
+			Open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(nil)), nil
+			},
+		})
+
+		entries = append(entries, StageEntry{
+			Path: "/broken",
+			Open: func() (io.ReadCloser, error) {
+				return nil, e.New("cannot open")
+			},
+		})
+
+		mu := sync.Mutex{}
+		failed := []string{}
+		nCalls := 0
+		err := fs.StageBatch(entries, func(path string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			nCalls++
+			if err != nil {
+				failed = append(failed, path)
+			}
+		})
+
+		require.Equal(t, ErrStageIncomplete, e.Cause(err))
+		require.Equal(t, len(entries), nCalls)
+		require.ElementsMatch(t, []string{"/dir", "/broken"}, failed)
+
+		for idx := 0; idx < 100; idx++ {
+			stream, err := fs.Cat(fmt.Sprintf("/sub/%d", idx))
+			require.NoError(t, err)
+
+			data, err := ioutil.ReadAll(stream)
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("content %d", idx), string(data))
+			require.NoError(t, stream.Close())
+		}
+
+		_, err = fs.Stat("/broken")
+		require.Error(t, err)
+
+		// Staging the same content again does not change anything:
+		require.NoError(t, fs.MakeCommit("batch"))
+		require.NoError(t, fs.StageBatch(entries[:100], nil))
+
+		haveChanges, err := fs.HaveStagedChanges()
+		require.NoError(t, err)
+		require.False(t, haveChanges)
+	})
+}
@@ -0,0 +1,205 @@
+package catfs
+
+import (
+	"time"
+
+	ie "github.com/sahib/brig/catfs/errors"
+	n "github.com/sahib/brig/catfs/nodes"
+	"github.com/sahib/brig/catfs/vcs"
+	log "github.com/sirupsen/logrus"
+)
+
+// deletedGhosts returns all ghosts below `root` that stem from a removal.
+// Ghosts of moved nodes are not included. fs.mu needs to be locked.
+func (fs *FS) deletedGhosts(root string) ([]*n.Ghost, error) {
+	rootNd, err := fs.lkr.LookupNode(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ghosts := []*n.Ghost{}
+	err = n.Walk(fs.lkr, rootNd, false, func(child n.Node) error {
+		if child.Type() != n.NodeTypeGhost {
+			return nil
+		}
+
+		ghost, ok := child.(*n.Ghost)
+		if !ok {
+			return ie.ErrBadNode
+		}
+
+		isMove, err := fs.isMove(ghost)
+		if err != nil {
+			return err
+		}
+
+		if !isMove {
+			ghosts = append(ghosts, ghost)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ghosts, nil
+}
+
+// liveBackendHashes returns the backend hashes of all files that
+// are currently reachable. fs.mu needs to be locked.
+func (fs *FS) liveBackendHashes() (map[string]bool, error) {
+	root, err := fs.lkr.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]bool)
+	return hashes, n.Walk(fs.lkr, root, true, func(child n.Node) error {
+		if child.Type() == n.NodeTypeFile {
+			hashes[child.BackendHash().B58String()] = true
+		}
+
+		return nil
+	})
+}
+
+// purgeGhost unpins the content of `ghost` and of all older versions of it.
+// Content that is still used by a living file or a snapshot (given by `keep`)
+// stays. Returns true if anything was unpinned.
+func (fs *FS) purgeGhost(status *n.Commit, ghost *n.Ghost, keep map[string]bool) (bool, error) {
+	purged := false
+	unpin := func(nd n.ModNode) error {
+		if nd.Type() != n.NodeTypeFile || keep[nd.BackendHash().B58String()] {
+			return nil
+		}
+
+		isPinned, _, err := fs.pinner.IsNodePinned(nd)
+		if err != nil {
+			return err
+		}
+
+		if !isPinned {
+			return nil
+		}
+
+		purged = true
+		return fs.pinner.UnpinNode(nd, true)
+	}
+
+	// The walker for a file needs to start at the ghost to find the
+	// versions before the removal. Files in removed directories are
+	// not ghosts themselves, so we start with the file there.
+	starts := []n.ModNode{}
+	if ghost.OldNode().Type() == n.NodeTypeFile {
+		starts = append(starts, ghost)
+	}
+
+	err := n.Walk(fs.lkr, ghost.OldNode(), true, func(child n.Node) error {
+		file, ok := child.(*n.File)
+		if !ok {
+			return nil
+		}
+
+		if err := unpin(file); err != nil {
+			return err
+		}
+
+		if ghost.OldNode().Type() == n.NodeTypeDirectory {
+			starts = append(starts, file)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, start := range starts {
+		walker := vcs.NewHistoryWalker(fs.lkr, status, start)
+		for walker.Next() {
+			if err := unpin(walker.State().Curr); err != nil {
+				return false, err
+			}
+		}
+
+		if err := walker.Err(); err != nil {
+			return false, err
+		}
+	}
+
+	return purged, nil
+}
+
+func (fs *FS) purgeTrash(root string, olderThan time.Duration, now time.Time) ([]*StatInfo, error) {
+	if fs.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	ghosts, err := fs.deletedGhosts(root)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := fs.lkr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	keep, err := fs.liveBackendHashes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions that are part of a snapshot should stay available,
+	// just like repin keeps them:
+	if fs.cfg.Bool("snapshots.pin") {
+		protected, err := fs.snapshotBackendHashes()
+		if err != nil {
+			return nil, err
+		}
+
+		for hash := range protected {
+			keep[hash] = true
+		}
+	}
+
+	purged := []*StatInfo{}
+	for _, ghost := range ghosts {
+		if now.Sub(ghost.DeletedAt()) < olderThan {
+			continue
+		}
+
+		// Remember the state before purging; it contains the size
+		// that was freed by this.
+		info := fs.nodeToStat(ghost)
+
+		didPurge, err := fs.purgeGhost(status, ghost, keep)
+		if err != nil {
+			return nil, err
+		}
+
+		if didPurge {
+			log.Infof("purged %s from trash", ghost.Path())
+			purged = append(purged, info)
+		}
+	}
+
+	return purged, nil
+}
+
+// PurgeTrash unpins the content of all nodes below `root` that were deleted
+// at least `olderThan` ago, so that the backend can collect them on its next
+// garbage collection run. All older versions of the deleted nodes are
+// unpinned too. Content that is still used by another file, or by a snapshot
+// if snapshots.pin is set, stays pinned.
+// Purged nodes can still be undeleted, but their content might need to be
+// fetched again from other peers. It returns the purged nodes.
+func (fs *FS) PurgeTrash(root string, olderThan time.Duration) ([]*StatInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.purgeTrash(prefixSlash(root), olderThan, time.Now())
+}
@@ -0,0 +1,104 @@
+package catfs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashPurge(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		require.NoError(t, fs.Stage("/dir/a", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.MakeCommit("a v1"))
+		require.NoError(t, fs.Stage("/dir/a", bytes.NewReader([]byte{2})))
+		require.NoError(t, fs.Stage("/dir/b", bytes.NewReader([]byte{3})))
+		require.NoError(t, fs.Copy("/dir/b", "/c"))
+		require.NoError(t, fs.MakeCommit("a v2 and b"))
+
+		// Pin the old version explicitly, purge should not care:
+		require.NoError(t, fs.Pin("/dir/a", "HEAD^", true))
+
+		require.NoError(t, fs.Remove("/dir/a"))
+		require.NoError(t, fs.Remove("/dir/b"))
+		require.NoError(t, fs.MakeCommit("remove"))
+
+		before := time.Now()
+		deleted, err := fs.DeletedNodes("/")
+		require.NoError(t, err)
+		require.Len(t, deleted, 2)
+		for _, info := range deleted {
+			require.True(t, info.IsPinned)
+			require.True(t, before.Sub(info.ModTime) < time.Minute)
+		}
+
+		// Nothing is old enough yet:
+		purged, err := fs.PurgeTrash("/", time.Hour)
+		require.NoError(t, err)
+		require.Empty(t, purged)
+
+		fs.mu.Lock()
+		purged, err = fs.purgeTrash("/", time.Hour, time.Now().Add(2*time.Hour))
+		fs.mu.Unlock()
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		require.Equal(t, "/dir/a", purged[0].Path)
+
+		deleted, err = fs.DeletedNodes("/")
+		require.NoError(t, err)
+		for _, info := range deleted {
+			switch info.Path {
+			case "/dir/a":
+				require.False(t, info.IsPinned)
+			case "/dir/b":
+				// Shares its content with /c, which is still alive:
+				require.True(t, info.IsPinned)
+			}
+		}
+
+		histA, err := fs.History("/dir/a")
+		require.NoError(t, err)
+		for _, change := range histA {
+			require.False(t, change.IsPinned, change.Head.Msg)
+		}
+
+		// Purged items can still be restored:
+		require.NoError(t, fs.Undelete("/dir/a"))
+		info, err := fs.Stat("/dir/a")
+		require.NoError(t, err)
+		require.True(t, info.IsPinned)
+	})
+}
+
+func TestTrashPurgeKeepsSnapshots(t *testing.T) {
+	withDummyFS(t, func(fs *FS) {
+		fs.cfg.SetBool("snapshots.pin", true)
+
+		require.NoError(t, fs.Stage("/dir/a", bytes.NewReader([]byte{1})))
+		require.NoError(t, fs.MakeCommit("a v1"))
+
+		_, err := fs.TakeSnapshots(time.Date(2020, 1, 31, 10, 30, 0, 0, time.UTC))
+		require.NoError(t, err)
+
+		require.NoError(t, fs.Stage("/dir/a", bytes.NewReader([]byte{2})))
+		require.NoError(t, fs.MakeCommit("a v2"))
+		require.NoError(t, fs.Pin("/dir/a", "HEAD^", true))
+
+		require.NoError(t, fs.Remove("/dir/a"))
+		require.NoError(t, fs.MakeCommit("remove"))
+
+		fs.mu.Lock()
+		purged, err := fs.purgeTrash("/", time.Hour, time.Now().Add(2*time.Hour))
+		fs.mu.Unlock()
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+
+		// Only the snapshotted version stays pinned:
+		histA, err := fs.History("/dir/a")
+		require.NoError(t, err)
+		for _, change := range histA {
+			require.Equal(t, change.Head.Msg == "a v1", change.IsPinned, change.Head.Msg)
+		}
+	})
+}
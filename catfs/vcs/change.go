@@ -150,9 +150,18 @@ func replayAdd(lkr *c.Linker, currNd n.ModNode) error {
 			return e.Wrapf(err, "replay: mkdir")
 		}
 
-		if _, err := c.StageFromFileNode(lkr, currNd.(*n.File)); err != nil {
+		currFile := currNd.(*n.File)
+		file, err := c.StageFromFileNode(lkr, currFile)
+		if err != nil {
 			return e.Wrapf(err, "replay: stage")
 		}
+
+		// Stage() does not know about attributes:
+		if !n.AttributesEqual(file, currFile) {
+			if err := c.StageAttributes(lkr, file, currFile.Attributes()); err != nil {
+				return e.Wrapf(err, "replay: attributes")
+			}
+		}
 	case *n.Directory:
 		if _, err := c.Mkdir(lkr, currNd.Path(), true); err != nil {
 			return e.Wrapf(err, "replay: mkdir")
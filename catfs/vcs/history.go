@@ -76,6 +76,11 @@ func (hw *HistoryWalker) maskFromState(curr, next n.ModNode) ChangeType {
 		mask |= ChangeTypeModify
 	}
 
+	// Changed user attributes count as modification too:
+	if !n.AttributesEqual(curr, next) {
+		mask |= ChangeTypeModify
+	}
+
 	if next.Path() != curr.Path() {
 		mask |= ChangeTypeMove
 	} else {
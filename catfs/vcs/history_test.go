@@ -503,6 +503,7 @@ func setupHistoryMultipleMovesPerCommit(t *testing.T, lkr *c.Linker) *historySet
 		nil,
 		time.Now(),
 		false,
+		"",
 	)
 	require.Nil(t, err)
 
@@ -158,7 +158,7 @@ func (ma *Mapper) reportByType(src, dst n.ModNode) error {
 		return ma.report(src, dst, isTypeMismatch, false, false)
 	}
 
-	if src.ContentHash().Equal(dst.ContentHash()) {
+	if src.ContentHash().Equal(dst.ContentHash()) && n.AttributesEqual(src, dst) {
 		// If the files are equal, but the location changed,
 		// the file were moved.
 		if src.Path() != dst.Path() {
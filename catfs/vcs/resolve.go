@@ -95,6 +95,9 @@ type resolver struct {
 
 	// actual executor based on the decision
 	exec executor
+
+	// onProgress is called after each decided pair, if set.
+	onProgress func(nodePath string, done, total int)
 }
 
 func newResolver(lkrSrc, lkrDst *c.Linker, srcHead, dstHead *n.Commit, exec executor) (*resolver, error) {
@@ -147,15 +150,33 @@ func (rv *resolver) resolve() error {
 		return err
 	}
 
-	for _, pair := range mappings {
+	for idx, pair := range mappings {
 		if err := rv.decide(pair); err != nil {
 			return err
 		}
+
+		if rv.onProgress != nil {
+			rv.onProgress(pairPath(pair), idx+1, len(mappings))
+		}
 	}
 
 	return nil
 }
 
+// pairPath returns the path of the source node of `pair`,
+// or the one of the destination if there is no source.
+func pairPath(pair MapPair) string {
+	if pair.Src != nil {
+		return pair.Src.Path()
+	}
+
+	if pair.Dst != nil {
+		return pair.Dst.Path()
+	}
+
+	return ""
+}
+
 func (rv *resolver) cacheLastCommonMerge() error {
 	srcOwner, err := rv.lkrSrc.Owner()
 	if err != nil {
@@ -260,7 +281,8 @@ func (rv *resolver) hasConflicts(src, dst n.ModNode) (bool, ChangeType, ChangeTy
 		for dstIdx := 0; dstIdx < len(dstHist) && !commonRootFound; dstIdx++ {
 			srcChange, dstChange := srcHist[srcIdx], dstHist[dstIdx]
 
-			if srcChange.Curr.ContentHash().Equal(dstChange.Curr.ContentHash()) {
+			srcCurr, dstCurr := srcChange.Curr, dstChange.Curr
+			if srcCurr.ContentHash().Equal(dstCurr.ContentHash()) && n.AttributesEqual(srcCurr, dstCurr) {
 				srcRoot, dstRoot = srcIdx, dstIdx
 				commonRootFound = true
 			}
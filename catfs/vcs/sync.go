@@ -57,6 +57,52 @@ func ConflictStrategyFromString(spec string) ConflictStrategy {
 	}
 }
 
+const (
+	// LockPolicyWarn applies changes to locked paths, but logs a warning.
+	LockPolicyWarn = iota
+
+	// LockPolicyRefuse does not apply changes to locked paths.
+	LockPolicyRefuse
+
+	// LockPolicyIgnore does not care about locks at all.
+	LockPolicyIgnore
+
+	// LockPolicyUnknown should be used when the policy is not clear.
+	LockPolicyUnknown
+)
+
+// LockPolicy defines what to do with changes to paths
+// that are locked by another user.
+type LockPolicy int
+
+func (lp LockPolicy) String() string {
+	switch lp {
+	case LockPolicyWarn:
+		return "warn"
+	case LockPolicyRefuse:
+		return "refuse"
+	case LockPolicyIgnore:
+		return "ignore"
+	default:
+		return "unknown"
+	}
+}
+
+// LockPolicyFromString converts a string to a LockPolicy.
+// It it is not valid, LockPolicyUnknown is returned.
+func LockPolicyFromString(spec string) LockPolicy {
+	switch spec {
+	case "warn":
+		return LockPolicyWarn
+	case "refuse":
+		return LockPolicyRefuse
+	case "ignore":
+		return LockPolicyIgnore
+	default:
+		return LockPolicyUnknown
+	}
+}
+
 // PinStats Handy structure to use during handleMerge to store the node info in which we merge
 type PinStats struct {
 	Pinned, Explicit bool
@@ -70,6 +116,17 @@ type SyncOptions struct {
 	Message                   string
 	ReadOnlyFolders           map[string]bool
 	ConflictStrategyPerFolder map[string]ConflictStrategy
+	LockPolicy                LockPolicy
+	LockPolicyPerFolder       map[string]LockPolicy
+
+	// LockedBy is called for every path that the sync wants to modify.
+	// It should return the name of the user that locked the path,
+	// or an empty string if the path may be modified freely.
+	LockedBy func(nodePath string) string
+
+	// OnProgress is called after each path the sync looked at,
+	// together with the number of paths done so far and overall.
+	OnProgress func(nodePath string, done, total int)
 
 	OnAdd      func(newNd n.ModNode) bool
 	OnRemove   func(oldNd n.ModNode) bool
@@ -151,6 +208,7 @@ func (sy *syncer) add(src n.ModNode, srcParent, srcName string) error {
 			newDstFile.SetSize(srcFile.Size())
 			newDstFile.SetCachedSize(srcFile.CachedSize())
 			newDstFile.SetKey(srcFile.Key())
+			newDstFile.SetAttributes(sy.lkrDst, srcFile.Attributes())
 		}
 
 		if sy.cfg.OnAdd != nil {
@@ -193,11 +251,63 @@ func isReadOnly(folders map[string]bool, nodePaths ...string) bool {
 	return false
 }
 
+func (sy *syncer) getLockPolicy(nodePath string) LockPolicy {
+	if len(sy.cfg.LockPolicyPerFolder) == 0 {
+		return sy.cfg.LockPolicy
+	}
+
+	curr := nodePath
+	for {
+		lp, ok := sy.cfg.LockPolicyPerFolder[curr]
+		if ok {
+			return lp
+		}
+
+		parent := path.Dir(curr)
+		if parent == curr {
+			break
+		}
+
+		curr = parent
+	}
+
+	return sy.cfg.LockPolicy
+}
+
+// isLocked checks if the change to `nodePaths` should be skipped
+// because one of them is locked by somebody else.
+func (sy *syncer) isLocked(nodePaths ...string) bool {
+	if sy.cfg.LockedBy == nil {
+		return false
+	}
+
+	for _, nodePath := range nodePaths {
+		holder := sy.cfg.LockedBy(nodePath)
+		if holder == "" {
+			continue
+		}
+
+		switch sy.getLockPolicy(nodePath) {
+		case LockPolicyRefuse:
+			log.Warningf("sync: not applying change to %s: locked by %s", nodePath, holder)
+			return true
+		case LockPolicyWarn:
+			log.Warningf("sync: applying change to %s, although it is locked by %s", nodePath, holder)
+		}
+	}
+
+	return false
+}
+
 func (sy *syncer) handleAdd(src n.ModNode) error {
 	if isReadOnly(sy.cfg.ReadOnlyFolders, src.Path()) {
 		return nil
 	}
 
+	if sy.isLocked(src.Path()) {
+		return nil
+	}
+
 	log.Debugf("handling add: %s", src.Path())
 	return sy.add(src, path.Dir(src.Path()), src.Name())
 }
@@ -211,6 +321,10 @@ func (sy *syncer) handleMove(src, dst n.ModNode) error {
 		return nil
 	}
 
+	if sy.isLocked(src.Path(), dst.Path()) {
+		return nil
+	}
+
 	log.Debugf("handling move: %s -> %s", dst.Path(), src.Path())
 	if _, err := c.Mkdir(sy.lkrDst, path.Dir(src.Path()), true); err != nil {
 		return err
@@ -236,6 +350,10 @@ func (sy *syncer) handleRemove(dst n.ModNode) error {
 		return nil
 	}
 
+	if sy.isLocked(dst.Path()) {
+		return nil
+	}
+
 	log.Debugf("handling remove: %s", dst.Path())
 
 	if sy.cfg.OnRemove != nil {
@@ -328,6 +446,10 @@ func (sy *syncer) handleMerge(src, dst n.ModNode, srcMask, dstMask ChangeType) e
 		return nil
 	}
 
+	if sy.isLocked(src.Path(), dst.Path()) {
+		return nil
+	}
+
 	if src.Path() != dst.Path() {
 		// Only move the file if it was only moved on the remote side.
 		if srcMask&ChangeTypeMove != 0 && dstMask&ChangeTypeMove == 0 {
@@ -381,6 +503,7 @@ func (sy *syncer) handleMerge(src, dst n.ModNode, srcMask, dstMask ChangeType) e
 	dstFile.SetSize(srcFile.Size())
 	dstFile.SetCachedSize(srcFile.CachedSize())
 	dstFile.SetKey(srcFile.Key())
+	dstFile.SetAttributes(sy.lkrDst, srcFile.Attributes())
 
 	if err := dstParent.Add(sy.lkrDst, dstFile); err != nil {
 		return err
@@ -429,6 +552,8 @@ func Sync(lkrSrc, lkrDst *c.Linker, cfg *SyncOptions) error {
 		return err
 	}
 
+	resolver.onProgress = cfg.OnProgress
+
 	// Make sure the complete sync goes through in one disk transaction.
 	return lkrDst.Atomic(func() (bool, error) {
 		// This calls all the handleXXX() callbacks above.
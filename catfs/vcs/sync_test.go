@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	c "github.com/sahib/brig/catfs/core"
+	ie "github.com/sahib/brig/catfs/errors"
 	h "github.com/sahib/brig/util/hashlib"
 	"github.com/stretchr/testify/require"
 )
@@ -380,3 +381,106 @@ func TestSyncReadOnlyFolders(t *testing.T) {
 		require.Equal(t, srcX.ContentHash(), h.TestDummy(t, byte(1)))
 	})
 }
+
+func TestSyncLockedPaths(t *testing.T) {
+	c.WithLinkerPair(t, func(lkrSrc, lkrDst *c.Linker) {
+		c.MustTouchAndCommit(t, lkrSrc, "/locked/x.png", 1)
+		c.MustTouchAndCommit(t, lkrSrc, "/free/y.png", 2)
+		require.Nil(t, Sync(lkrSrc, lkrDst, nil))
+
+		// Modify both files on the remote side:
+		srcX, err := lkrSrc.LookupFile("/locked/x.png")
+		require.Nil(t, err)
+		c.MustModify(t, lkrSrc, srcX, 3)
+		srcY, err := lkrSrc.LookupFile("/free/y.png")
+		require.Nil(t, err)
+		c.MustModify(t, lkrSrc, srcY, 4)
+		c.MustCommit(t, lkrSrc, "modify")
+
+		lockedBy := func(nodePath string) string {
+			if nodePath == "/locked/x.png" {
+				return "charlie"
+			}
+
+			return ""
+		}
+
+		// Per default changes are applied with a warning; for /locked
+		// we configure that changes should be refused instead.
+		cfg := &SyncOptions{
+			LockPolicy: LockPolicyWarn,
+			LockPolicyPerFolder: map[string]LockPolicy{
+				"/locked": LockPolicyRefuse,
+			},
+			LockedBy: lockedBy,
+		}
+
+		require.Nil(t, Sync(lkrSrc, lkrDst, cfg))
+
+		dstX, err := lkrDst.LookupFile("/locked/x.png")
+		require.Nil(t, err)
+		require.Equal(t, h.TestDummy(t, byte(1)), dstX.ContentHash())
+
+		dstY, err := lkrDst.LookupFile("/free/y.png")
+		require.Nil(t, err)
+		require.Equal(t, h.TestDummy(t, byte(4)), dstY.ContentHash())
+	})
+}
+
+func TestSyncAttributes(t *testing.T) {
+	c.WithLinkerPair(t, func(lkrSrc, lkrDst *c.Linker) {
+		srcX := c.MustTouch(t, lkrSrc, "/x.png", 1)
+		require.NoError(t, c.StageAttributes(lkrSrc, srcX, map[string][]byte{
+			"project": []byte("holiday"),
+		}))
+		c.MustCommit(t, lkrSrc, "tag x")
+		c.MustCommitIfPossible(t, lkrDst, "init dst")
+
+		// Attributes are synced with new files:
+		require.NoError(t, Sync(lkrSrc, lkrDst, nil))
+		dstX, err := lkrDst.LookupFile("/x.png")
+		require.NoError(t, err)
+		require.Equal(t, []byte("holiday"), dstX.Attributes()["project"])
+
+		// ...and if only the attributes were modified:
+		srcX, err = lkrSrc.LookupFile("/x.png")
+		require.NoError(t, err)
+		require.NoError(t, c.StageAttributes(lkrSrc, srcX, map[string][]byte{
+			"project": []byte("holiday"),
+			"client":  []byte("acme"),
+		}))
+		c.MustCommit(t, lkrSrc, "tag x again")
+
+		require.NoError(t, Sync(lkrSrc, lkrDst, nil))
+		dstX, err = lkrDst.LookupFile("/x.png")
+		require.NoError(t, err)
+		require.Equal(t, []byte("acme"), dstX.Attributes()["client"])
+
+		_, err = lkrDst.LookupNode("/x.png.conflict.0")
+		require.True(t, ie.IsNoSuchFileError(err))
+	})
+}
+
+func TestSyncProgress(t *testing.T) {
+	c.WithLinkerPair(t, func(lkrSrc, lkrDst *c.Linker) {
+		c.MustTouchAndCommit(t, lkrSrc, "/a.png", 1)
+		c.MustTouchAndCommit(t, lkrSrc, "/b.png", 2)
+		c.MustTouchAndCommit(t, lkrSrc, "/c.png", 3)
+
+		paths := []string{}
+		lastDone, lastTotal := 0, 0
+		cfg := &SyncOptions{
+			OnProgress: func(nodePath string, done, total int) {
+				paths = append(paths, nodePath)
+				require.Equal(t, lastDone+1, done)
+				lastDone, lastTotal = done, total
+			},
+		}
+
+		require.Nil(t, Sync(lkrSrc, lkrDst, cfg))
+		require.Equal(t, lastTotal, lastDone)
+		require.Equal(t, len(paths), lastDone)
+		require.Contains(t, paths, "/a.png")
+		require.Contains(t, paths, "/c.png")
+	})
+}
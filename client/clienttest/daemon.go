@@ -13,14 +13,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// StartDaemon starts a new daemon with user `name`, using backend defined by
-// `backendName` and, if the backend is IPFS, uses the IPFS repository at
-// `ipfsPath`.  The resulting server should be closed after use and the
-// temporary directory where all data resides should be removed.
-func StartDaemon(name, backendName, ipfsPath string) (*server.Server, error) {
+func initRepo(name, backendName, ipfsPath string) (string, error) {
 	repoPath, err := ioutil.TempDir("", "brig-client-repo")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	daemonURL := "unix:" + filepath.Join(repoPath, "brig.socket")
@@ -30,7 +26,7 @@ func StartDaemon(name, backendName, ipfsPath string) (*server.Server, error) {
 		BackendName: backendName,
 		DaemonURL:   daemonURL,
 	}); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	if backendName == "httpipfs" {
@@ -39,11 +35,40 @@ func StartDaemon(name, backendName, ipfsPath string) (*server.Server, error) {
 			"daemon.ipfs_path_or_url",
 			ipfsPath,
 		); err != nil {
+			return "", err
+		}
+	}
+
+	return repoPath, nil
+}
+
+// StartDaemon starts a new daemon with user `name`, using backend defined by
+// `backendName` and, if the backend is IPFS, uses the IPFS repository at
+// `ipfsPath`.  The resulting server should be closed after use and the
+// temporary directory where all data resides should be removed.
+// For every name in `extraNames` another repository is created and served
+// by the same daemon under this name.
+func StartDaemon(name, backendName, ipfsPath string, extraNames ...string) (*server.Server, error) {
+	repoPath, err := initRepo(name, backendName, ipfsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	extraRepos := []server.RepoSpec{}
+	for _, extraName := range extraNames {
+		extraPath, err := initRepo(extraName, backendName, ipfsPath)
+		if err != nil {
 			return nil, err
 		}
+
+		extraRepos = append(extraRepos, server.RepoSpec{
+			Name: extraName,
+			Path: extraPath,
+		})
 	}
 
-	srv, err := server.BootServer(repoPath, daemonURL)
+	daemonURL := "unix:" + filepath.Join(repoPath, "brig.socket")
+	srv, err := server.BootServer(repoPath, daemonURL, extraRepos...)
 	if err != nil {
 		return nil, err
 	}
@@ -60,8 +85,9 @@ func StartDaemon(name, backendName, ipfsPath string) (*server.Server, error) {
 }
 
 // WithDaemon calls `fn` with a readily setup daemon client. `name` is the user.
-func WithDaemon(name string, fn func(ctl *client.Client) error) error {
-	srv, err := StartDaemon(name, "mock", "")
+// The daemon also serves a repository for every name in `extraNames`.
+func WithDaemon(name string, fn func(ctl *client.Client) error, extraNames ...string) error {
+	srv, err := StartDaemon(name, "mock", "", extraNames...)
 	if err != nil {
 		return err
 	}
@@ -76,7 +102,9 @@ func WithDaemon(name string, fn func(ctl *client.Client) error) error {
 		// and I see complains in log about DB.Close
 		// I introduce this time delay as a crude hack
 		time.Sleep(100 * time.Millisecond)
-		os.RemoveAll(srv.RepoPath())
+		for _, repoPath := range srv.RepoPaths() {
+			os.RemoveAll(repoPath)
+		}
 	}()
 	defer srv.Close()
 
@@ -9,8 +9,10 @@ import (
 
 	"github.com/sahib/brig/backend/httpipfs"
 	"github.com/sahib/brig/catfs/mio"
+	"github.com/sahib/brig/catfs/mio/compress"
 	"github.com/sahib/brig/server/capnp"
 	h "github.com/sahib/brig/util/hashlib"
+	capnplib "zombiezen.com/go/capnproto2"
 )
 
 // StatInfo gives information about a file or directory
@@ -32,6 +34,8 @@ type StatInfo struct {
 	BackendHash h.Hash
 	Key         []byte
 	Hint        Hint
+	Attrs       map[string][]byte
+	Compression string
 }
 
 func convertHash(hashBytes []byte, err error) (h.Hash, error) {
@@ -94,6 +98,11 @@ func convertCapStatInfo(capInfo *capnp.StatInfo) (*StatInfo, error) {
 		return nil, err
 	}
 
+	compression, err := capInfo.Compression()
+	if err != nil {
+		return nil, err
+	}
+
 	info.Path = path
 	info.User = user
 	info.Size = capInfo.Size()
@@ -110,6 +119,31 @@ func convertCapStatInfo(capInfo *capnp.StatInfo) (*StatInfo, error) {
 	info.BackendHash = backendHash
 	info.Key = key
 	info.Hint = *hint
+	info.Compression = compression
+
+	info.Attrs = make(map[string][]byte)
+	if capInfo.HasAttrs() {
+		capAttrs, err := capInfo.Attrs()
+		if err != nil {
+			return nil, err
+		}
+
+		for idx := 0; idx < capAttrs.Len(); idx++ {
+			capAttr := capAttrs.At(idx)
+			attrKey, err := capAttr.Key()
+			if err != nil {
+				return nil, err
+			}
+
+			attrValue, err := capAttr.Value()
+			if err != nil {
+				return nil, err
+			}
+
+			info.Attrs[attrKey] = append([]byte{}, attrValue...)
+		}
+	}
+
 	return info, nil
 }
 
@@ -158,6 +192,75 @@ func (cl *Client) Stage(localPath, repoPath string) error {
 	return err
 }
 
+// StageEntry is a single file to stage with StageBatch.
+type StageEntry struct {
+	LocalPath string
+	RepoPath  string
+}
+
+// StageFailure describes a file of StageBatch that could not be staged.
+type StageFailure struct {
+	RepoPath string
+	Error    string
+}
+
+// StageBatch stages all `entries` at once. The daemon processes the files
+// in parallel and stages all of them in one transaction, which is a lot
+// faster than calling Stage for every file. Files that could not be staged
+// are returned; the others are staged anyways.
+func (cl *Client) StageBatch(entries []StageEntry) ([]StageFailure, error) {
+	call := cl.api.StageBatch(cl.ctx, func(p capnp.FS_stageBatch_Params) error {
+		capEntries, err := capnp.NewStageEntry_List(p.Segment(), int32(len(entries)))
+		if err != nil {
+			return err
+		}
+
+		for idx, entry := range entries {
+			capEntry := capEntries.At(idx)
+			if err := capEntry.SetLocalPath(entry.LocalPath); err != nil {
+				return err
+			}
+
+			if err := capEntry.SetRepoPath(entry.RepoPath); err != nil {
+				return err
+			}
+		}
+
+		return p.SetEntries(capEntries)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capFailures, err := result.Failures()
+	if err != nil {
+		return nil, err
+	}
+
+	failures := []StageFailure{}
+	for idx := 0; idx < capFailures.Len(); idx++ {
+		capFailure := capFailures.At(idx)
+		repoPath, err := capFailure.RepoPath()
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := capFailure.Error()
+		if err != nil {
+			return nil, err
+		}
+
+		failures = append(failures, StageFailure{
+			RepoPath: repoPath,
+			Error:    msg,
+		})
+	}
+
+	return failures, nil
+}
+
 // StageFromReader will create a new node at `repoPath` from the contents of `r`.
 func (cl *Client) StageFromReader(repoPath string, r io.Reader) error {
 	call := cl.api.StageFromStream(cl.ctx, func(p capnp.FS_stageFromStream_Params) error {
@@ -290,6 +393,24 @@ func (cl *Client) CatOnClient(path string, offline bool, w io.Writer) error {
 	}
 
 	_, err = io.Copy(os.Stdout, stream)
+	if compress.IsDictNotFoundError(err) {
+		// The stream was compressed with a dictionary, which only the
+		// daemon knows. The header is read first, so nothing was written yet.
+		return cl.catOnDaemon(path, offline, w)
+	}
+
+	return err
+}
+
+func (cl *Client) catOnDaemon(path string, offline bool, w io.Writer) error {
+	stream, err := cl.Cat(path, offline)
+	if err != nil {
+		return err
+	}
+
+	defer stream.Close()
+
+	_, err = io.Copy(w, stream)
 	return err
 }
 
@@ -479,6 +600,38 @@ func (cl *Client) DeletedNodes(root string) ([]StatInfo, error) {
 	return results, err
 }
 
+// PurgeTrash unpins the content of all nodes under `root` that were
+// deleted at least `olderThan` ago. It returns the purged nodes.
+func (cl *Client) PurgeTrash(root string, olderThan time.Duration) ([]StatInfo, error) {
+	call := cl.api.PurgeTrash(cl.ctx, func(p capnp.FS_purgeTrash_Params) error {
+		p.SetOlderThanNs(int64(olderThan))
+		return p.SetRoot(root)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capNodes, err := result.Nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []StatInfo{}
+	for idx := 0; idx < capNodes.Len(); idx++ {
+		capInfo := capNodes.At(idx)
+		info, err := convertCapStatInfo(&capInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, *info)
+	}
+
+	return results, err
+}
+
 // IsCached checks if file or directory at `path` is cached.
 func (cl *Client) IsCached(path string) (bool, error) {
 	call := cl.api.IsCached(cl.ctx, func(p capnp.FS_isCached_Params) error {
@@ -503,3 +656,383 @@ func (cl *Client) RecodeStream(path string) error {
 	_, err := call.Struct()
 	return err
 }
+
+// SetOffline marks (or unmarks) `path` as "always available offline".
+// Marked content is downloaded and pinned in the background.
+func (cl *Client) SetOffline(path string, enable bool) error {
+	call := cl.api.SetOffline(cl.ctx, func(p capnp.FS_setOffline_Params) error {
+		p.SetEnable(enable)
+		return p.SetPath(path)
+	})
+
+	_, err := call.Struct()
+	return err
+}
+
+// PrefetchStatus describes the progress of downloading offline marked files.
+type PrefetchStatus struct {
+	Running    bool
+	TotalFiles int64
+	DoneFiles  int64
+	TotalBytes uint64
+	DoneBytes  uint64
+	Current    string
+	LastError  string
+	Paths      []string
+}
+
+// PrefetchStatus returns all offline marks and the progress of downloading them.
+func (cl *Client) PrefetchStatus() (*PrefetchStatus, error) {
+	call := cl.api.PrefetchStatus(cl.ctx, func(p capnp.FS_prefetchStatus_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capStatus, err := result.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := capStatus.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	lastError, err := capStatus.LastError()
+	if err != nil {
+		return nil, err
+	}
+
+	capPaths, err := capStatus.Paths()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for idx := 0; idx < capPaths.Len(); idx++ {
+		path, err := capPaths.At(idx)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return &PrefetchStatus{
+		Running:    capStatus.Running(),
+		TotalFiles: capStatus.TotalFiles(),
+		DoneFiles:  capStatus.DoneFiles(),
+		TotalBytes: capStatus.TotalBytes(),
+		DoneBytes:  capStatus.DoneBytes(),
+		Current:    current,
+		LastError:  lastError,
+		Paths:      paths,
+	}, nil
+}
+
+// Lock takes an advisory lock on `path` that is visible to other remotes.
+func (cl *Client) Lock(path string) error {
+	call := cl.api.Lock(cl.ctx, func(p capnp.FS_lock_Params) error {
+		return p.SetPath(path)
+	})
+
+	_, err := call.Struct()
+	return err
+}
+
+// Unlock releases a lock previously taken by Lock.
+func (cl *Client) Unlock(path string) error {
+	call := cl.api.Unlock(cl.ctx, func(p capnp.FS_unlock_Params) error {
+		return p.SetPath(path)
+	})
+
+	_, err := call.Struct()
+	return err
+}
+
+// LockInfo describes a single advisory lock.
+type LockInfo struct {
+	Path  string
+	Owner string
+	Since time.Time
+}
+
+// Locks returns all locks that we know of, our own and those of remotes.
+func (cl *Client) Locks() ([]LockInfo, error) {
+	call := cl.api.Locks(cl.ctx, func(p capnp.FS_locks_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capLocks, err := result.Locks()
+	if err != nil {
+		return nil, err
+	}
+
+	locks := []LockInfo{}
+	for idx := 0; idx < capLocks.Len(); idx++ {
+		capLock := capLocks.At(idx)
+		path, err := capLock.Path()
+		if err != nil {
+			return nil, err
+		}
+
+		owner, err := capLock.Owner()
+		if err != nil {
+			return nil, err
+		}
+
+		sinceStamp, err := capLock.Since()
+		if err != nil {
+			return nil, err
+		}
+
+		since, err := time.Parse(time.RFC3339, sinceStamp)
+		if err != nil {
+			return nil, err
+		}
+
+		locks = append(locks, LockInfo{
+			Path:  path,
+			Owner: owner,
+			Since: since,
+		})
+	}
+
+	return locks, nil
+}
+
+// SetAttr sets the user attribute `key` of the file at `path` to `value`.
+func (cl *Client) SetAttr(path, key string, value []byte) error {
+	call := cl.api.SetAttr(cl.ctx, func(p capnp.FS_setAttr_Params) error {
+		if err := p.SetPath(path); err != nil {
+			return err
+		}
+
+		if err := p.SetKey(key); err != nil {
+			return err
+		}
+
+		return p.SetValue(value)
+	})
+
+	_, err := call.Struct()
+	return err
+}
+
+// GetAttr returns the value of the user attribute `key` of `path`.
+func (cl *Client) GetAttr(path, key string) ([]byte, error) {
+	call := cl.api.GetAttr(cl.ctx, func(p capnp.FS_getAttr_Params) error {
+		if err := p.SetPath(path); err != nil {
+			return err
+		}
+
+		return p.SetKey(key)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := result.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{}, value...), nil
+}
+
+// RemoveAttr removes the user attribute `key` from `path`.
+func (cl *Client) RemoveAttr(path, key string) error {
+	call := cl.api.RemoveAttr(cl.ctx, func(p capnp.FS_removeAttr_Params) error {
+		if err := p.SetPath(path); err != nil {
+			return err
+		}
+
+		return p.SetKey(key)
+	})
+
+	_, err := call.Struct()
+	return err
+}
+
+// CompressionStats describes how well the files below a folder compress.
+type CompressionStats struct {
+	// Files is the number of files below the folder.
+	Files int64
+	// Size is the summed size of all files with a known backend size.
+	Size uint64
+	// StoredSize is the summed backend size of those files.
+	StoredSize int64
+	// ByCompression maps the used compression to the number of files.
+	ByCompression map[string]int64
+}
+
+// Ratio returns the stored size in relation to the actual size.
+func (cs *CompressionStats) Ratio() float64 {
+	if cs.Size == 0 {
+		return 1
+	}
+
+	return float64(cs.StoredSize) / float64(cs.Size)
+}
+
+// CompressionStats returns how well the files below `path` compress.
+func (cl *Client) CompressionStats(path string) (*CompressionStats, error) {
+	call := cl.api.CompressionStats(cl.ctx, func(p capnp.FS_compressionStats_Params) error {
+		return p.SetPath(path)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capStats, err := result.Stats()
+	if err != nil {
+		return nil, err
+	}
+
+	capCounts, err := capStats.ByCompression()
+	if err != nil {
+		return nil, err
+	}
+
+	byCompression := make(map[string]int64)
+	for idx := 0; idx < capCounts.Len(); idx++ {
+		capCount := capCounts.At(idx)
+		compression, err := capCount.Compression()
+		if err != nil {
+			return nil, err
+		}
+
+		byCompression[compression] = capCount.Files()
+	}
+
+	return &CompressionStats{
+		Files:         capStats.Files(),
+		Size:          capStats.Size(),
+		StoredSize:    capStats.StoredSize(),
+		ByCompression: byCompression,
+	}, nil
+}
+
+// RekeyStatus describes the progress of re-keying a folder.
+type RekeyStatus struct {
+	Running     bool
+	Folders     []string
+	TotalFiles  int64
+	DoneFiles   int64
+	FailedFiles int64
+	Current     string
+	LastError   string
+
+	// Remotes are the remotes that receive the new keys.
+	Remotes []string
+}
+
+func convertCapTextList(capList capnplib.TextList) ([]string, error) {
+	list := []string{}
+	for idx := 0; idx < capList.Len(); idx++ {
+		text, err := capList.At(idx)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, text)
+	}
+
+	return list, nil
+}
+
+func convertCapRekeyStatus(capStatus capnp.RekeyStatus) (*RekeyStatus, error) {
+	current, err := capStatus.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	lastError, err := capStatus.LastError()
+	if err != nil {
+		return nil, err
+	}
+
+	capFolders, err := capStatus.Folders()
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := convertCapTextList(capFolders)
+	if err != nil {
+		return nil, err
+	}
+
+	capRemotes, err := capStatus.Remotes()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes, err := convertCapTextList(capRemotes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RekeyStatus{
+		Running:     capStatus.Running(),
+		Folders:     folders,
+		TotalFiles:  capStatus.TotalFiles(),
+		DoneFiles:   capStatus.DoneFiles(),
+		FailedFiles: capStatus.FailedFiles(),
+		Current:     current,
+		LastError:   lastError,
+		Remotes:     remotes,
+	}, nil
+}
+
+// Rekey re-encrypts the current version of all files in `path` (or in its
+// key domain) with new keys. It returns once all files were processed;
+// use RekeyStatus to watch the progress in the meantime.
+func (cl *Client) Rekey(path string) (*RekeyStatus, error) {
+	call := cl.api.Rekey(cl.ctx, func(p capnp.FS_rekey_Params) error {
+		return p.SetPath(path)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capStatus, err := result.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCapRekeyStatus(capStatus)
+}
+
+// RekeyStatus returns the progress of the running (or the last) rekey.
+func (cl *Client) RekeyStatus() (*RekeyStatus, error) {
+	call := cl.api.RekeyStatus(cl.ctx, func(p capnp.FS_rekeyStatus_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capStatus, err := result.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCapRekeyStatus(capStatus)
+}
@@ -2,10 +2,13 @@ package client_test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/sahib/brig/client"
@@ -94,6 +97,44 @@ func TestStageAndCatStream(t *testing.T) {
 	})
 }
 
+func TestStageBatch(t *testing.T) {
+	withDaemon(t, "ali", func(ctl *client.Client) {
+		dir, err := ioutil.TempDir("", "brig-batch-data")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		entries := []client.StageEntry{}
+		for idx := 0; idx < 10; idx++ {
+			localPath := filepath.Join(dir, fmt.Sprintf("%d", idx))
+			require.NoError(t, ioutil.WriteFile(localPath, []byte{byte(idx)}, 0600))
+			entries = append(entries, client.StageEntry{
+				LocalPath: localPath,
+				RepoPath:  fmt.Sprintf("/batch/%d", idx),
+			})
+		}
+
+		entries = append(entries, client.StageEntry{
+			LocalPath: filepath.Join(dir, "does-not-exist"),
+			RepoPath:  "/batch/missing",
+		})
+
+		failures, err := ctl.StageBatch(entries)
+		require.NoError(t, err)
+		require.Len(t, failures, 1)
+		require.Equal(t, "/batch/missing", failures[0].RepoPath)
+
+		for idx := 0; idx < 10; idx++ {
+			rw, err := ctl.Cat(fmt.Sprintf("/batch/%d", idx), false)
+			require.NoError(t, err)
+
+			data, err := ioutil.ReadAll(rw)
+			require.NoError(t, err)
+			require.Equal(t, []byte{byte(idx)}, data)
+			require.NoError(t, rw.Close())
+		}
+	})
+}
+
 func TestMkdir(t *testing.T) {
 	withDaemon(t, "ali", func(ctl *client.Client) {
 		// Create something nested with -p...
@@ -438,7 +479,7 @@ func TestHints(t *testing.T) {
 		require.Equal(t, false, info.IsRaw)
 
 		none := "none"
-		require.NoError(t, ctl.HintSet("/public", &none, &none))
+		require.NoError(t, ctl.HintSet("/public", &none, &none, nil))
 
 		info, err = ctl.Stat(path)
 		require.NoError(t, err)
@@ -464,3 +505,182 @@ func TestHints(t *testing.T) {
 		require.Equal(t, expected, got)
 	})
 }
+
+func TestCompressionStats(t *testing.T) {
+	withDaemon(t, "ali", func(ctl *client.Client) {
+		text := strings.Repeat("a very compressible line of text\n", 1000)
+		require.NoError(t, ctl.StageFromReader("/docs/a.txt", strings.NewReader(text)))
+
+		info, err := ctl.Stat("/docs/a.txt")
+		require.NoError(t, err)
+		require.NotEqual(t, "", info.Compression)
+		require.NotEqual(t, "none", info.Compression)
+
+		stats, err := ctl.CompressionStats("/docs")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), stats.Files)
+		require.Equal(t, uint64(len(text)), stats.Size)
+		require.Equal(t, int64(1), stats.ByCompression[info.Compression])
+		require.True(t, stats.Ratio() < 0.5)
+	})
+}
+
+func TestHintTrainDict(t *testing.T) {
+	withDaemon(t, "ali", func(ctl *client.Client) {
+		require.NoError(t, ctl.Mkdir("/logs", true))
+
+		for idx := 0; idx < 50; idx++ {
+			data := fmt.Sprintf(`{"id": %d, "level": "info", "msg": "request served"}`, idx)
+			path := fmt.Sprintf("/logs/%d.json", idx)
+			require.NoError(t, ctl.StageFromReader(path, strings.NewReader(data)))
+		}
+
+		id, size, err := ctl.HintTrainDict("/logs", 0)
+		require.NoError(t, err)
+		require.True(t, size > 0)
+
+		info, err := ctl.Stat("/logs/1.json")
+		require.NoError(t, err)
+		require.Equal(t, "zstd", info.Hint.CompressionAlgo)
+		require.Equal(t, id, info.Hint.ZstdDict)
+
+		require.NoError(t, ctl.RecodeStream("/logs"))
+
+		stream, err := ctl.Cat("/logs/1.json", true)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(stream)
+		require.NoError(t, err)
+		require.Equal(t, `{"id": 1, "level": "info", "msg": "request served"}`, string(got))
+	})
+}
+
+func TestMultipleRepos(t *testing.T) {
+	err := clienttest.WithDaemon("ali", func(ctl *client.Client) error {
+		repos, err := ctl.DaemonRepos()
+		require.NoError(t, err)
+		require.Len(t, repos, 2)
+
+		var bobRepo client.DaemonRepo
+		for _, repo := range repos {
+			if repo.Owner == "bob" {
+				bobRepo = repo
+			} else {
+				require.True(t, repo.IsDefault)
+			}
+		}
+
+		require.Equal(t, "bob", bobRepo.Name)
+		require.False(t, bobRepo.IsDefault)
+
+		require.NoError(t, ctl.Touch("/ali-file"))
+
+		// Repositories can be selected by name or by path:
+		require.Error(t, ctl.SelectRepo("charlie"))
+		require.NoError(t, ctl.SelectRepo(bobRepo.Path))
+		require.NoError(t, ctl.SelectRepo("bob"))
+
+		self, err := ctl.Whoami()
+		require.NoError(t, err)
+		require.Equal(t, "bob", self.Owner)
+
+		_, err = ctl.Stat("/ali-file")
+		require.Error(t, err)
+		require.NoError(t, ctl.Touch("/bob-file"))
+		_, err = ctl.Stat("/bob-file")
+		require.NoError(t, err)
+		return nil
+	}, "bob")
+
+	require.NoError(t, err)
+}
+
+func TestRekey(t *testing.T) {
+	withDaemon(t, "ali", func(ctl *client.Client) {
+		team := "team"
+		require.NoError(t, ctl.Mkdir("/team", true))
+		require.NoError(t, ctl.Mkdir("/shared", true))
+		require.NoError(t, ctl.HintSet("/team", nil, nil, &team))
+		require.NoError(t, ctl.HintSet("/shared", nil, nil, &team))
+
+		paths := []string{"/team/a", "/team/sub/b", "/shared/c", "/private/d"}
+		for _, path := range paths {
+			require.NoError(t, ctl.StageFromReader(path, strings.NewReader(path)))
+		}
+
+		oldKeys := make(map[string][]byte)
+		for _, path := range paths {
+			info, err := ctl.Stat(path)
+			require.NoError(t, err)
+			oldKeys[path] = info.Key
+		}
+
+		require.NoError(t, ctl.RemoteAddOrUpdate(client.Remote{
+			Name:        "bob",
+			Fingerprint: "QmBob:SEfXUDeJA1toVnP3yvBmDYUAAsMbqF6QzsAuGDrnXi8ctTjdcZgu2gAjVZHBk8Nu6XJcjxoRhF8cvoVZRmf4HQxDjTSXi",
+			Folders:     []client.RemoteFolder{{Folder: "/private"}},
+		}))
+
+		require.NoError(t, ctl.RemoteAddOrUpdate(client.Remote{
+			Name:        "charlie",
+			Fingerprint: "QmCharlie:SEfXUDeJA1toVnP3yvBmDYUAAsMbqF6QzsAuGDrnXi8ctTjdcZgu2gAjVZHBk8Nu6XJcjxoRhF8cvoVZRmf4HQxDjTSXi",
+			Folders:     []client.RemoteFolder{{Folder: "/shared"}},
+		}))
+
+		// Rekeying a subfolder rekeys the whole domain:
+		status, err := ctl.Rekey("/team/sub")
+		require.NoError(t, err)
+		require.False(t, status.Running)
+		require.Equal(t, []string{"/shared", "/team"}, status.Folders)
+		require.Equal(t, int64(3), status.TotalFiles)
+		require.Equal(t, int64(3), status.DoneFiles)
+		require.Equal(t, int64(0), status.FailedFiles)
+		require.Equal(t, []string{"charlie"}, status.Remotes)
+
+		for _, path := range paths {
+			info, err := ctl.Stat(path)
+			require.NoError(t, err)
+
+			if path == "/private/d" {
+				require.Equal(t, oldKeys[path], info.Key)
+			} else {
+				require.NotEqual(t, oldKeys[path], info.Key)
+			}
+
+			stream, err := ctl.Cat(path, false)
+			require.NoError(t, err)
+
+			data, err := ioutil.ReadAll(stream)
+			require.NoError(t, err)
+			require.Equal(t, path, string(data))
+			require.NoError(t, stream.Close())
+		}
+
+		lastStatus, err := ctl.RekeyStatus()
+		require.NoError(t, err)
+		require.Equal(t, status, lastStatus)
+	})
+}
+
+func TestJobList(t *testing.T) {
+	withDaemonPair(t, "ali", "bob", func(aliCtl, bobCtl *client.Client) {
+		jobs, err := aliCtl.JobList()
+		require.NoError(t, err)
+		require.Empty(t, jobs)
+
+		for _, path := range []string{"/dir/a", "/dir/b", "/dir/sub/c"} {
+			err := aliCtl.StageFromReader(path, bytes.NewReader([]byte(path)))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, aliCtl.Pin("/dir"))
+		_, err = bobCtl.Sync("ali", true)
+		require.NoError(t, err)
+
+		// Finished jobs are not listed anymore:
+		for _, ctl := range []*client.Client{aliCtl, bobCtl} {
+			jobs, err := ctl.JobList()
+			require.NoError(t, err)
+			require.Empty(t, jobs)
+		}
+	})
+}
@@ -18,6 +18,7 @@ type RemoteFolder struct {
 	Folder           string `yaml:"Folder"`
 	ReadOnly         bool   `yaml:"ReadOnly"`
 	ConflictStrategy string `yaml:"ConflictStrategy"`
+	LockPolicy       string `yaml:"LockPolicy"`
 }
 
 // Remote describes a single remote in the remote list.
@@ -28,6 +29,10 @@ type Remote struct {
 	AutoUpdate       bool           `yaml:"AutoUpdate"`
 	ConflictStrategy string         `yaml:"ConflictStrategy"`
 	AcceptPush       bool           `yaml:"AcceptPush"`
+	Device           bool           `yaml:"Device"`
+	Devices          []string       `yaml:"Devices,flow"`
+	UploadLimit      string         `yaml:"UploadLimit"`
+	DownloadLimit    string         `yaml:"DownloadLimit"`
 }
 
 func capRemoteToRemote(capRemote capnp.Remote) (*Remote, error) {
@@ -64,13 +69,43 @@ func capRemoteToRemote(capRemote capnp.Remote) (*Remote, error) {
 			return nil, err
 		}
 
+		lp, err := folder.LockPolicy()
+		if err != nil {
+			return nil, err
+		}
+
 		folders = append(folders, RemoteFolder{
 			Folder:           folderName,
 			ReadOnly:         folder.ReadOnly(),
 			ConflictStrategy: cs,
+			LockPolicy:       lp,
 		})
 	}
 
+	capDevices, err := capRemote.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := convertCapTextList(capDevices)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(devices) == 0 {
+		devices = nil
+	}
+
+	uploadLimit, err := capRemote.UploadLimit()
+	if err != nil {
+		return nil, err
+	}
+
+	downloadLimit, err := capRemote.DownloadLimit()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Remote{
 		Name:             remoteName,
 		Fingerprint:      remoteFp,
@@ -78,6 +113,10 @@ func capRemoteToRemote(capRemote capnp.Remote) (*Remote, error) {
 		AutoUpdate:       capRemote.AcceptAutoUpdates(),
 		AcceptPush:       capRemote.AcceptPush(),
 		ConflictStrategy: conflictStrategy,
+		Device:           capRemote.Device(),
+		Devices:          devices,
+		UploadLimit:      uploadLimit,
+		DownloadLimit:    downloadLimit,
 	}, nil
 }
 
@@ -99,38 +138,75 @@ func remoteToCapRemote(remote Remote, seg *capnplib.Segment) (*capnp.Remote, err
 		return nil, err
 	}
 
-	capFolders, err := capnp.NewRemoteFolder_List(seg, int32(len(remote.Folders)))
+	capFolders, err := foldersToCapFolders(remote.Folders, seg)
 	if err != nil {
 		return nil, err
 	}
 
-	for idx, folder := range remote.Folders {
+	if err := capRemote.SetFolders(capFolders); err != nil {
+		return nil, err
+	}
+
+	capDevices, err := capnplib.NewTextList(seg, int32(len(remote.Devices)))
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, device := range remote.Devices {
+		if err := capDevices.Set(idx, device); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := capRemote.SetDevices(capDevices); err != nil {
+		return nil, err
+	}
+
+	if err := capRemote.SetUploadLimit(remote.UploadLimit); err != nil {
+		return nil, err
+	}
+
+	if err := capRemote.SetDownloadLimit(remote.DownloadLimit); err != nil {
+		return nil, err
+	}
+
+	capRemote.SetAcceptAutoUpdates(remote.AutoUpdate)
+	capRemote.SetAcceptPush(remote.AcceptPush)
+	capRemote.SetDevice(remote.Device)
+	return &capRemote, nil
+}
+
+func foldersToCapFolders(folders []RemoteFolder, seg *capnplib.Segment) (capnp.RemoteFolder_List, error) {
+	capFolders, err := capnp.NewRemoteFolder_List(seg, int32(len(folders)))
+	if err != nil {
+		return capFolders, err
+	}
+
+	for idx, folder := range folders {
 		capFolder, err := capnp.NewRemoteFolder(seg)
 		if err != nil {
-			return nil, err
+			return capFolders, err
 		}
 
 		capFolder.SetReadOnly(folder.ReadOnly)
 		if err := capFolder.SetFolder(folder.Folder); err != nil {
-			return nil, err
+			return capFolders, err
 		}
 
 		if err := capFolder.SetConflictStrategy(folder.ConflictStrategy); err != nil {
-			return nil, err
+			return capFolders, err
 		}
 
-		if err := capFolders.Set(idx, capFolder); err != nil {
-			return nil, err
+		if err := capFolder.SetLockPolicy(folder.LockPolicy); err != nil {
+			return capFolders, err
 		}
-	}
 
-	if err := capRemote.SetFolders(capFolders); err != nil {
-		return nil, err
+		if err := capFolders.Set(idx, capFolder); err != nil {
+			return capFolders, err
+		}
 	}
 
-	capRemote.SetAcceptAutoUpdates(remote.AutoUpdate)
-	capRemote.SetAcceptPush(remote.AcceptPush)
-	return &capRemote, nil
+	return capFolders, nil
 }
 
 // RemoteAddOrUpdate adds a new remote described in `remote`.
@@ -149,6 +225,275 @@ func (cl *Client) RemoteAddOrUpdate(remote Remote) error {
 	return err
 }
 
+// RemoteInvite creates a token that can be passed to a not yet known peer.
+// Until `validFor` passed, it can use the token once to add itself as
+// remote that may see `folders`. See also RemoteAcceptInvite.
+func (cl *Client) RemoteInvite(folders []RemoteFolder, acceptPush bool, validFor time.Duration) (string, error) {
+	call := cl.api.RemoteInvite(cl.ctx, func(p capnp.Net_remoteInvite_Params) error {
+		capFolders, err := foldersToCapFolders(folders, p.Segment())
+		if err != nil {
+			return err
+		}
+
+		p.SetAcceptPush(acceptPush)
+		p.SetValidSec(int64(validFor / time.Second))
+		return p.SetFolders(capFolders)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return "", err
+	}
+
+	return result.Token()
+}
+
+// DeviceInvite creates a token that links another device of our user.
+// Accept it on the other device with RemoteAcceptInvite.
+func (cl *Client) DeviceInvite(validFor time.Duration) (string, error) {
+	call := cl.api.DeviceInvite(cl.ctx, func(p capnp.Net_deviceInvite_Params) error {
+		p.SetValidSec(int64(validFor / time.Second))
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return "", err
+	}
+
+	return result.Token()
+}
+
+// RemoteAcceptInvite accepts a token made by RemoteInvite. The inviter adds
+// us as remote and we add the inviter as remote called `name`.
+// If `name` is empty, the name of the inviter is used.
+func (cl *Client) RemoteAcceptInvite(token, name string) (*Remote, error) {
+	call := cl.api.RemoteAcceptInvite(cl.ctx, func(p capnp.Net_remoteAcceptInvite_Params) error {
+		if err := p.SetToken(token); err != nil {
+			return err
+		}
+
+		return p.SetName(name)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capRemote, err := result.Remote()
+	if err != nil {
+		return nil, err
+	}
+
+	return capRemoteToRemote(capRemote)
+}
+
+// RemoteRevoke revokes the key of the remote `name` and removes it.
+// The revocation is sent to all other remotes; the names of
+// the ones that could not be reached are returned.
+func (cl *Client) RemoteRevoke(name, reason string) ([]string, error) {
+	call := cl.api.RemoteRevoke(cl.ctx, func(p capnp.Net_remoteRevoke_Params) error {
+		if err := p.SetName(name); err != nil {
+			return err
+		}
+
+		return p.SetReason(reason)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capNotReached, err := result.NotReached()
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCapTextList(capNotReached)
+}
+
+// RotateKey replaces our key by a new one and tells our remotes about it.
+// It returns our new fingerprint and the remotes that could not be reached.
+func (cl *Client) RotateKey() (string, []string, error) {
+	call := cl.api.RotateKey(cl.ctx, func(p capnp.Net_rotateKey_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return "", nil, err
+	}
+
+	fingerprint, err := result.Fingerprint()
+	if err != nil {
+		return "", nil, err
+	}
+
+	capNotReached, err := result.NotReached()
+	if err != nil {
+		return "", nil, err
+	}
+
+	notReached, err := convertCapTextList(capNotReached)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fingerprint, notReached, nil
+}
+
+// Revocation is a key revocation (or rotation) that we trust.
+type Revocation struct {
+	Revoked    string
+	Successor  string
+	Reason     string
+	Created    time.Time
+	Issuer     string
+	SelfSigned bool
+}
+
+func capRevocationToRevocation(capRv capnp.Revocation) (*Revocation, error) {
+	revoked, err := capRv.Revoked()
+	if err != nil {
+		return nil, err
+	}
+
+	successor, err := capRv.Successor()
+	if err != nil {
+		return nil, err
+	}
+
+	reason, err := capRv.Reason()
+	if err != nil {
+		return nil, err
+	}
+
+	createdStamp, err := capRv.Created()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := time.Parse(time.RFC3339, createdStamp)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := capRv.Issuer()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revocation{
+		Revoked:    revoked,
+		Successor:  successor,
+		Reason:     reason,
+		Created:    created,
+		Issuer:     issuer,
+		SelfSigned: capRv.SelfSigned(),
+	}, nil
+}
+
+// RevocationList returns all revocations that we know of.
+func (cl *Client) RevocationList() ([]Revocation, error) {
+	call := cl.api.RevocationList(cl.ctx, func(p capnp.Net_revocationList_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capRevs, err := result.Revocations()
+	if err != nil {
+		return nil, err
+	}
+
+	revs := []Revocation{}
+	for idx := 0; idx < capRevs.Len(); idx++ {
+		rv, err := capRevocationToRevocation(capRevs.At(idx))
+		if err != nil {
+			return nil, err
+		}
+
+		revs = append(revs, *rv)
+	}
+
+	return revs, nil
+}
+
+// BandwidthStats are the limits and throughput of either all traffic
+// or the one of a single remote. All values are in bytes (per second).
+// A limit of zero means unlimited.
+type BandwidthStats struct {
+	Name          string
+	UploadLimit   int64
+	DownloadLimit int64
+	Upload        int64
+	Download      int64
+	UploadTotal   int64
+	DownloadTotal int64
+}
+
+func capStatsToStats(capStats capnp.BandwidthStats) (*BandwidthStats, error) {
+	name, err := capStats.Name()
+	if err != nil {
+		return nil, err
+	}
+
+	return &BandwidthStats{
+		Name:          name,
+		UploadLimit:   capStats.UploadLimit(),
+		DownloadLimit: capStats.DownloadLimit(),
+		Upload:        capStats.Upload(),
+		Download:      capStats.Download(),
+		UploadTotal:   capStats.UploadTotal(),
+		DownloadTotal: capStats.DownloadTotal(),
+	}, nil
+}
+
+// BandwidthStats returns the stats of all traffic and the ones
+// of each remote that had traffic since the daemon started.
+func (cl *Client) BandwidthStats() (*BandwidthStats, []BandwidthStats, error) {
+	call := cl.api.BandwidthStats(cl.ctx, func(p capnp.Net_bandwidthStats_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	capGlobal, err := result.Global()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	global, err := capStatsToStats(capGlobal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	capRemotes, err := result.Remotes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remotes := []BandwidthStats{}
+	for idx := 0; idx < capRemotes.Len(); idx++ {
+		stats, err := capStatsToStats(capRemotes.At(idx))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		remotes = append(remotes, *stats)
+	}
+
+	return global, remotes, nil
+}
+
 // RemoteByName adds a new remote described in `remote`.
 // We thus authenticate this remote.
 func (cl *Client) RemoteByName(name string) (Remote, error) {
@@ -41,3 +41,181 @@ func TestPush(t *testing.T) {
 
 	})
 }
+
+func TestInviteAndAccept(t *testing.T) {
+	withDaemon(t, "ali", func(aliCtl *client.Client) {
+		withDaemon(t, "bob", func(bobCtl *client.Client) {
+			folders := []client.RemoteFolder{{Folder: "/shared"}}
+			token, err := aliCtl.RemoteInvite(folders, false, time.Hour)
+			require.NoError(t, err)
+
+			rmt, err := bobCtl.RemoteAcceptInvite(token, "")
+			require.NoError(t, err)
+			require.Equal(t, "ali", rmt.Name)
+			require.Equal(t, folders, rmt.Folders)
+
+			bobRmt, err := aliCtl.RemoteByName("bob")
+			require.NoError(t, err)
+			require.Equal(t, folders, bobRmt.Folders)
+
+			bobWhoami, err := bobCtl.Whoami()
+			require.NoError(t, err)
+			require.Equal(t, bobWhoami.Fingerprint, bobRmt.Fingerprint)
+
+			// Both sides know each other now and can sync:
+			require.NoError(t, aliCtl.StageFromReader("/shared/x", bytes.NewReader([]byte{1})))
+			require.NoError(t, aliCtl.StageFromReader("/private", bytes.NewReader([]byte{2})))
+			_, err = bobCtl.Sync("ali", true)
+			require.NoError(t, err)
+
+			_, err = bobCtl.Stat("/shared/x")
+			require.NoError(t, err)
+			_, err = bobCtl.Stat("/private")
+			require.Error(t, err)
+
+			// The token can not be used twice:
+			_, err = bobCtl.RemoteAcceptInvite(token, "ali2")
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestRevokeAndRotate(t *testing.T) {
+	withDaemon(t, "ali", func(aliCtl *client.Client) {
+		withDaemon(t, "bob", func(bobCtl *client.Client) {
+			folders := []client.RemoteFolder{{Folder: "/shared"}}
+			token, err := aliCtl.RemoteInvite(folders, false, time.Hour)
+			require.NoError(t, err)
+
+			_, err = bobCtl.RemoteAcceptInvite(token, "")
+			require.NoError(t, err)
+
+			require.NoError(t, aliCtl.StageFromReader("/shared/x", bytes.NewReader([]byte{1})))
+
+			// Bob rotates his key; ali learns the new one:
+			newFp, notReached, err := bobCtl.RotateKey()
+			require.NoError(t, err)
+			require.Empty(t, notReached)
+
+			bobWhoami, err := bobCtl.Whoami()
+			require.NoError(t, err)
+			require.Equal(t, newFp, bobWhoami.Fingerprint)
+
+			bobRmt, err := aliCtl.RemoteByName("bob")
+			require.NoError(t, err)
+			require.Equal(t, newFp, bobRmt.Fingerprint)
+
+			_, err = bobCtl.Sync("ali", true)
+			require.NoError(t, err)
+
+			// Ali revokes bob's (new) key:
+			_, err = aliCtl.RemoteRevoke("bob", "lost laptop")
+			require.NoError(t, err)
+
+			_, err = aliCtl.RemoteByName("bob")
+			require.Error(t, err)
+
+			revs, err := aliCtl.RevocationList()
+			require.NoError(t, err)
+			require.Len(t, revs, 2)
+			require.True(t, revs[0].SelfSigned)
+			require.Equal(t, newFp, revs[0].Successor)
+			require.Equal(t, "lost laptop", revs[1].Reason)
+
+			// Bob can not get in anymore:
+			_, err = bobCtl.Sync("ali", true)
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestLinkDevices(t *testing.T) {
+	withDaemon(t, "ali/laptop", func(laptopCtl *client.Client) {
+		withDaemon(t, "ali/desktop", func(desktopCtl *client.Client) {
+			withDaemon(t, "bob", func(bobCtl *client.Client) {
+				// Bob knows ali only by her laptop:
+				folders := []client.RemoteFolder{{Folder: "/shared"}}
+				token, err := laptopCtl.RemoteInvite(folders, false, time.Hour)
+				require.NoError(t, err)
+				_, err = bobCtl.RemoteAcceptInvite(token, "ali")
+				require.NoError(t, err)
+
+				// Bob can't pretend to be one of ali's devices:
+				token, err = laptopCtl.DeviceInvite(time.Hour)
+				require.NoError(t, err)
+				_, err = bobCtl.RemoteAcceptInvite(token, "")
+				require.Error(t, err)
+
+				laptopRmt, err := desktopCtl.RemoteAcceptInvite(token, "")
+				require.NoError(t, err)
+				require.Equal(t, "ali/laptop", laptopRmt.Name)
+				require.True(t, laptopRmt.Device)
+				require.True(t, laptopRmt.AcceptPush)
+
+				desktopRmt, err := laptopCtl.RemoteByName("ali/desktop")
+				require.NoError(t, err)
+				require.True(t, desktopRmt.Device)
+				require.Empty(t, desktopRmt.Folders)
+
+				// Devices get all folders of each other:
+				require.NoError(t, laptopCtl.StageFromReader("/private", bytes.NewReader([]byte{1})))
+				_, err = desktopCtl.Sync("ali/laptop", true)
+				require.NoError(t, err)
+				_, err = desktopCtl.Stat("/private")
+				require.NoError(t, err)
+
+				// ...and the desktop now knows bob too:
+				bobRmt, err := desktopCtl.RemoteByName("bob")
+				require.NoError(t, err)
+				require.Equal(t, folders, bobRmt.Folders)
+
+				// Bob learns about the desktop on his next sync:
+				desktopWhoami, err := desktopCtl.Whoami()
+				require.NoError(t, err)
+
+				_, err = bobCtl.Sync("ali", true)
+				require.NoError(t, err)
+
+				aliRmt, err := bobCtl.RemoteByName("ali")
+				require.NoError(t, err)
+				require.Equal(t, []string{desktopWhoami.Fingerprint}, aliRmt.Devices)
+			})
+		})
+	})
+}
+
+func TestBandwidthLimits(t *testing.T) {
+	withDaemonPair(t, "ali", "bob", func(aliCtl, bobCtl *client.Client) {
+		bobRmt, err := aliCtl.RemoteByName("bob")
+		require.NoError(t, err)
+
+		bobRmt.UploadLimit = "bogus"
+		require.Error(t, aliCtl.RemoteAddOrUpdate(bobRmt))
+
+		bobRmt.UploadLimit = "1MB"
+		require.NoError(t, aliCtl.RemoteAddOrUpdate(bobRmt))
+
+		bobRmt, err = aliCtl.RemoteByName("bob")
+		require.NoError(t, err)
+		require.Equal(t, "1MB", bobRmt.UploadLimit)
+		require.Equal(t, "", bobRmt.DownloadLimit)
+
+		require.NoError(t, aliCtl.ConfigSet("net.bandwidth.download", "2MB"))
+		require.NoError(t, bobCtl.StageFromReader("/bob_file", bytes.NewReader([]byte{23})))
+
+		_, err = aliCtl.Sync("bob", true)
+		require.NoError(t, err)
+
+		global, remotes, err := aliCtl.BandwidthStats()
+		require.NoError(t, err)
+		require.Equal(t, int64(0), global.UploadLimit)
+		require.Equal(t, int64(2000*1000), global.DownloadLimit)
+		require.True(t, global.DownloadTotal > 0)
+
+		require.Len(t, remotes, 1)
+		require.Equal(t, "bob", remotes[0].Name)
+		require.Equal(t, int64(1000*1000), remotes[0].UploadLimit)
+		require.Equal(t, int64(2000*1000), remotes[0].DownloadLimit)
+		require.Equal(t, global.DownloadTotal, remotes[0].DownloadTotal)
+	})
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"sort"
+	"time"
 
 	gwdb "github.com/sahib/brig/gateway/db"
 	"github.com/sahib/brig/server/capnp"
@@ -36,9 +37,10 @@ func (ctl *Client) Ping() error {
 
 // MountOptions holds the possible option for a single mount.
 type MountOptions struct {
-	ReadOnly bool
-	RootPath string
-	Offline  bool
+	ReadOnly        bool
+	RootPath        string
+	Offline         bool
+	FlagUnavailable bool
 }
 
 func mountOptionsToCapnp(opts MountOptions, seg *capnplib.Segment) (*capnp.MountOptions, error) {
@@ -49,6 +51,7 @@ func mountOptionsToCapnp(opts MountOptions, seg *capnplib.Segment) (*capnp.Mount
 
 	capOpts.SetReadOnly(opts.ReadOnly)
 	capOpts.SetOffline(opts.Offline)
+	capOpts.SetFlagUnavailable(opts.FlagUnavailable)
 	if err := capOpts.SetRootPath(opts.RootPath); err != nil {
 		return nil, err
 	}
@@ -311,12 +314,13 @@ func (ctl *Client) FstabUnmountAll() error {
 
 // FsTabEntry describes a single entry in the filesystem tab
 type FsTabEntry struct {
-	Name     string
-	Path     string
-	Root     string
-	Active   bool
-	ReadOnly bool
-	Offline  bool
+	Name            string
+	Path            string
+	Root            string
+	Active          bool
+	ReadOnly        bool
+	Offline         bool
+	FlagUnavailable bool
 }
 
 func capMountToMount(capEntry capnp.FsTabEntry) (*FsTabEntry, error) {
@@ -336,12 +340,13 @@ func capMountToMount(capEntry capnp.FsTabEntry) (*FsTabEntry, error) {
 	}
 
 	return &FsTabEntry{
-		Path:     path,
-		Name:     name,
-		Root:     root,
-		Active:   capEntry.Active(),
-		ReadOnly: capEntry.ReadOnly(),
-		Offline:  capEntry.Offline(),
+		Path:            path,
+		Name:            name,
+		Root:            root,
+		Active:          capEntry.Active(),
+		ReadOnly:        capEntry.ReadOnly(),
+		Offline:         capEntry.Offline(),
+		FlagUnavailable: capEntry.FlagUnavailable(),
 	}, nil
 }
 
@@ -574,10 +579,17 @@ type Hint struct {
 
 	// EncryptionAlgo must be a valid encryption algorithm.
 	EncryptionAlgo string
+
+	// ZstdDict is the id of the compression dictionary (or empty).
+	ZstdDict string
+
+	// KeyDomain is the name of the key domain (or empty).
+	KeyDomain string
 }
 
-// HintSet remembers the given settings at `path` (and below)
-func (ctl *Client) HintSet(path string, compressionAlgo, encryptionAlgo *string) error {
+// HintSet remembers the given settings at `path` (and below).
+// Pass "none" as `keyDomain` to remove `path` from its key domain.
+func (ctl *Client) HintSet(path string, compressionAlgo, encryptionAlgo, keyDomain *string) error {
 	call := ctl.api.HintSet(ctl.ctx, func(p capnp.Repo_hintSet_Params) error {
 		capHint, err := capnp.NewHint(p.Segment())
 		if err != nil {
@@ -600,6 +612,12 @@ func (ctl *Client) HintSet(path string, compressionAlgo, encryptionAlgo *string)
 			}
 		}
 
+		if keyDomain != nil {
+			if err := capHint.SetKeyDomain(*keyDomain); err != nil {
+				return err
+			}
+		}
+
 		return p.SetHint(capHint)
 	})
 
@@ -633,10 +651,22 @@ func convertCapHint(capHint capnp.Hint) (*Hint, error) {
 		return nil, err
 	}
 
+	zstdDict, err := capHint.ZstdDict()
+	if err != nil {
+		return nil, err
+	}
+
+	keyDomain, err := capHint.KeyDomain()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Hint{
 		Path:            path,
 		EncryptionAlgo:  encryptionAlgo,
 		CompressionAlgo: compressionAlgo,
+		ZstdDict:        zstdDict,
+		KeyDomain:       keyDomain,
 	}, nil
 }
 
@@ -674,3 +704,173 @@ func (ctl *Client) HintList() ([]Hint, error) {
 
 	return hints, nil
 }
+
+// HintTrainDict trains a zstd dictionary of at most `maxSize` bytes
+// (0 for the default) on the files below `path` and uses it for new files
+// there. It returns the id and the size of the dictionary.
+func (ctl *Client) HintTrainDict(path string, maxSize int64) (string, int64, error) {
+	call := ctl.api.HintTrainDict(ctl.ctx, func(p capnp.Repo_hintTrainDict_Params) error {
+		p.SetMaxSize(maxSize)
+		return p.SetPath(path)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return "", 0, err
+	}
+
+	id, err := result.Id()
+	if err != nil {
+		return "", 0, err
+	}
+
+	return id, result.Size(), nil
+}
+
+// SelectRepo makes all further calls of this client operate on the
+// repository `repo` (a name or a path) of a daemon serving several of them.
+func (ctl *Client) SelectRepo(repo string) error {
+	call := ctl.api.SelectRepo(ctl.ctx, func(p capnp.Repo_selectRepo_Params) error {
+		return p.SetRepo(repo)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return err
+	}
+
+	ctl.api = capnp.API{Client: result.Api().Client}
+	return nil
+}
+
+// DaemonRepo is a repository that is served by the daemon.
+type DaemonRepo struct {
+	Name      string
+	Path      string
+	Owner     string
+	IsDefault bool
+}
+
+// DaemonRepos lists all repositories that are served by the daemon.
+func (ctl *Client) DaemonRepos() ([]DaemonRepo, error) {
+	call := ctl.api.DaemonRepos(ctl.ctx, func(p capnp.Repo_daemonRepos_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capRepos, err := result.Repos()
+	if err != nil {
+		return nil, err
+	}
+
+	repos := []DaemonRepo{}
+	for idx := 0; idx < capRepos.Len(); idx++ {
+		capRepo := capRepos.At(idx)
+
+		name, err := capRepo.Name()
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := capRepo.Path()
+		if err != nil {
+			return nil, err
+		}
+
+		owner, err := capRepo.Owner()
+		if err != nil {
+			return nil, err
+		}
+
+		repos = append(repos, DaemonRepo{
+			Name:      name,
+			Path:      path,
+			Owner:     owner,
+			IsDefault: capRepo.IsDefault(),
+		})
+	}
+
+	return repos, nil
+}
+
+// Job is an operation of the daemon that is in progress.
+type Job struct {
+	ID         uint64
+	Kind       string
+	Subject    string
+	Started    time.Time
+	TotalFiles int64
+	DoneFiles  int64
+	TotalBytes uint64
+	DoneBytes  uint64
+	Current    string
+
+	// ETA is the estimated remaining time, negative if unknown.
+	ETA time.Duration
+}
+
+// JobList returns the operations that the daemon is doing right now.
+func (ctl *Client) JobList() ([]Job, error) {
+	call := ctl.api.JobList(ctl.ctx, func(p capnp.Repo_jobList_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capJobs, err := result.Jobs()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []Job{}
+	for idx := 0; idx < capJobs.Len(); idx++ {
+		capJob := capJobs.At(idx)
+
+		kind, err := capJob.Kind()
+		if err != nil {
+			return nil, err
+		}
+
+		subject, err := capJob.Subject()
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := capJob.Current()
+		if err != nil {
+			return nil, err
+		}
+
+		startedStamp, err := capJob.Started()
+		if err != nil {
+			return nil, err
+		}
+
+		started, err := time.Parse(time.RFC3339, startedStamp)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, Job{
+			ID:         capJob.Id(),
+			Kind:       kind,
+			Subject:    subject,
+			Started:    started,
+			TotalFiles: capJob.TotalFiles(),
+			DoneFiles:  capJob.DoneFiles(),
+			TotalBytes: capJob.TotalBytes(),
+			DoneBytes:  capJob.DoneBytes(),
+			Current:    current,
+			ETA:        time.Duration(capJob.EtaSec() * float64(time.Second)),
+		})
+	}
+
+	return jobs, nil
+}
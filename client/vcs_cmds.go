@@ -474,3 +474,42 @@ func (ctl *Client) CommitInfo(rev string) (bool, *Commit, error) {
 
 	return true, cmt, nil
 }
+
+// ImportGit replays the history of the git repository at `gitPath` up to
+// `rev` as brig commits. All files are placed below `prefix`. It returns
+// the number of created commits and the number of skipped empty commits.
+func (ctl *Client) ImportGit(gitPath, rev, prefix string) (int, int, error) {
+	call := ctl.api.ImportGit(ctl.ctx, func(p capnp.VCS_importGit_Params) error {
+		if err := p.SetGitPath(gitPath); err != nil {
+			return err
+		}
+
+		if err := p.SetRev(rev); err != nil {
+			return err
+		}
+
+		return p.SetPrefix(prefix)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(result.Commits()), int(result.Skipped()), nil
+}
+
+// ExportGit writes the history of brig as commits on `branch` into the git
+// repository at `gitPath`. The repository is created if it does not exist.
+func (ctl *Client) ExportGit(gitPath, branch string) error {
+	call := ctl.api.ExportGit(ctl.ctx, func(p capnp.VCS_exportGit_Params) error {
+		if err := p.SetGitPath(gitPath); err != nil {
+			return err
+		}
+
+		return p.SetBranch(branch)
+	})
+
+	_, err := call.Struct()
+	return err
+}
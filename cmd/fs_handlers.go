@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
+	isatty "github.com/mattn/go-isatty"
 	"github.com/sahib/brig/client"
 	"github.com/urfave/cli"
 
@@ -168,6 +170,9 @@ func makeParentDirIfNeeded(ctx *cli.Context, ctl *client.Client, path string) er
 	return fmt.Errorf("Cannot make dir from existing non dir node %s", parent)
 }
 
+// stageBatchSize is the number of files that are sent to the daemon at once.
+const stageBatchSize = 512
+
 func handleStageDirectory(ctx *cli.Context, ctl *client.Client, root, repoRoot string) error {
 	// Links will be reflinked in the `man cp` sense,
 	// i.e. resolved repoPaths will point to the same content and backend hash
@@ -219,68 +224,73 @@ func handleStageDirectory(ctx *cli.Context, ctl *client.Client, root, repoRoot s
 		mpb.AppendDecorators(decor.Percentage()),
 	)
 
-	type stageList struct {
-		local    string
-		repoList []string
+	// Files are staged in batches; the daemon stages the files of
+	// a batch in parallel and commits their metadata all at once.
+	twinsList := []twins{}
+	for _, v := range toBeStaged {
+		twinsList = append(twinsList, v)
 	}
 
-	nWorkers := 20
+	failed := make(map[string]bool)
 	start := time.Now()
-	jobs := make(chan twins, nWorkers)
-
-	// Start a bunch of workers that will do the actual adding:
-	for idx := 0; idx < nWorkers; idx++ {
-		go func() {
-			for {
-				twinsSet, ok := <-jobs
-				if !ok {
-					return
-				}
+	for len(twinsList) > 0 {
+		chunkSize := stageBatchSize
+		if chunkSize > len(twinsList) {
+			chunkSize = len(twinsList)
+		}
 
-				firstToStage := ""
-				for i, repoPath := range twinsSet.repoPaths {
-					if i == 0 {
-						firstToStage = repoPath
-						// First occurrence is staged.
-						// Stage creates all needed parent directories.
-						if err := ctl.Stage(twinsSet.localPath, repoPath); err != nil {
-							fmt.Fprintf(os.Stderr, "failed to stage '%s' as '%s': %v\n", twinsSet.localPath, repoPath, err)
-							break
-						}
-						continue
-					}
-					// Copy does not create parent directories. We take care of it.
-					if err := makeParentDirIfNeeded(ctx, ctl, repoPath); err != nil {
-						fmt.Fprintf(os.Stderr, "failed to make the parent dir for '%s': %v\n", repoPath, err)
-						break
-					}
-					if err := ctl.Copy(firstToStage, repoPath); err != nil {
-						fmt.Fprintf(os.Stderr, "failed copy of '%s' to '%s': %v\n", firstToStage, repoPath, err)
-						break
-					}
-				}
+		chunk := twinsList[:chunkSize]
+		twinsList = twinsList[chunkSize:]
+
+		// First occurrence is staged.
+		// Stage creates all needed parent directories.
+		entries := []client.StageEntry{}
+		for _, twinsSet := range chunk {
+			entries = append(entries, client.StageEntry{
+				LocalPath: twinsSet.localPath,
+				RepoPath:  twinsSet.repoPaths[0],
+			})
+		}
 
-				// Notify the bar. The op time is used for the ETA.
-				// The time is measured by "start" is NOT the time used to
-				// stage a single file.  This would only work in a non-parallel
-				// environment, because the ETA would assume that one file took
-				// 2s, so 1000 files must take 2000s.  Instead it measures the
-				// time between two time recordings, which are in the ideal
-				// case around 1/n_workers * time_to_stage but it measures the
-				// actual amount of parallelism that we achieve.
-				bar.IncrBy(1, time.Since(start))
-				start = time.Now()
-			}
-		}()
-	}
+		failures, err := ctl.StageBatch(entries)
+		if err != nil {
+			pbars.Abort(bar, false)
+			return err
+		}
 
-	// Send the jobs onward:
-	for _, v := range toBeStaged {
-		jobs <- v
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "failed to stage '%s': %v\n", failure.RepoPath, failure.Error)
+			failed[failure.RepoPath] = true
+		}
+
+		// Notify the bar. The op time is used for the ETA.
+		bar.IncrBy(len(chunk), time.Since(start))
+		start = time.Now()
 	}
 
-	close(jobs)
 	pbars.Wait()
+
+	// Copy the content to the other paths it is linked to:
+	for _, twinsSet := range toBeStaged {
+		firstToStage := twinsSet.repoPaths[0]
+		if failed[firstToStage] {
+			continue
+		}
+
+		for _, repoPath := range twinsSet.repoPaths[1:] {
+			// Copy does not create parent directories. We take care of it.
+			if err := makeParentDirIfNeeded(ctx, ctl, repoPath); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to make the parent dir for '%s': %v\n", repoPath, err)
+				break
+			}
+
+			if err := ctl.Copy(firstToStage, repoPath); err != nil {
+				fmt.Fprintf(os.Stderr, "failed copy of '%s' to '%s': %v\n", firstToStage, repoPath, err)
+				break
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -311,7 +321,13 @@ func handleCat(ctx *cli.Context, ctl *client.Client) error {
 	}
 	defer util.Closer(stream)
 
-	if _, err := io.Copy(os.Stdout, stream); err != nil {
+	// Only cats that take a while show up as job:
+	err = withJobProgress(ctl, "cat", path, func() error {
+		_, err := io.Copy(os.Stdout, stream)
+		return err
+	})
+
+	if err != nil {
 		return ExitCode{
 			UnknownError,
 			fmt.Sprintf("cat: %v", err),
@@ -400,6 +416,44 @@ func formatHint(hint client.Hint) string {
 	return fmt.Sprintf("enc:%s-zip:%s", hint.EncryptionAlgo, hint.CompressionAlgo)
 }
 
+// formatCompression describes the compression of a single file.
+func formatCompression(info *client.StatInfo) string {
+	compression := info.Compression
+	if compression == "" {
+		compression = "unknown"
+	}
+
+	if info.Size == 0 || info.CachedSize < 0 {
+		return compression
+	}
+
+	ratio := float64(info.CachedSize) / float64(info.Size)
+	return fmt.Sprintf("%s (stored with %.0f%% of the size)", compression, ratio*100)
+}
+
+// formatCompressionStats describes the compression of the files in a folder.
+func formatCompressionStats(stats *client.CompressionStats) string {
+	compressions := []string{}
+	for compression := range stats.ByCompression {
+		compressions = append(compressions, compression)
+	}
+
+	sort.Strings(compressions)
+
+	counts := []string{}
+	for _, compression := range compressions {
+		counts = append(counts, fmt.Sprintf("%s: %d", compression, stats.ByCompression[compression]))
+	}
+
+	return fmt.Sprintf(
+		"stored with %.0f%% of the size (%s in %d files; %s)",
+		stats.Ratio()*100,
+		humanize.Bytes(uint64(stats.StoredSize)),
+		stats.Files,
+		strings.Join(counts, ", "),
+	)
+}
+
 func handleList(ctx *cli.Context, ctl *client.Client) error {
 	maxDepth := ctx.Int("depth")
 	if ctx.Bool("recursive") {
@@ -642,11 +696,25 @@ func handleShowFileOrDir(ctx *cli.Context, ctl *client.Client, path string) erro
 	printPair("Hint", formatHint(info.Hint))
 
 	if !info.IsDir {
+		printPair("Compression", formatCompression(info))
 		printPair("Backend Hash", info.BackendHash.B58String())
 	} else {
+		stats, err := ctl.CompressionStats(path)
+		if err != nil {
+			return err
+		}
+
+		if stats.Files > 0 {
+			printPair("Compression", formatCompressionStats(stats))
+		}
+
 		printPair("Backend Hash", "-")
 	}
 
+	if len(info.Attrs) > 0 {
+		printPair("Attributes", formatAttrs(info.Attrs))
+	}
+
 	return tabW.Flush()
 }
 
@@ -692,6 +760,139 @@ func handleTouch(ctx *cli.Context, ctl *client.Client) error {
 	return ctl.Touch(repoPath)
 }
 
+func handleLock(ctx *cli.Context, ctl *client.Client) error {
+	for _, path := range ctx.Args() {
+		if err := ctl.Lock(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func handleUnlock(ctx *cli.Context, ctl *client.Client) error {
+	for _, path := range ctx.Args() {
+		if err := ctl.Unlock(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func handleLocks(ctx *cli.Context, ctl *client.Client) error {
+	locks, err := ctl.Locks()
+	if err != nil {
+		return err
+	}
+
+	if len(locks) == 0 {
+		fmt.Println("No locks.")
+		return nil
+	}
+
+	self, err := ctl.Whoami()
+	if err != nil {
+		return err
+	}
+
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	fmt.Fprintln(tabW, "PATH\tOWNER\tSINCE\t")
+	for _, lock := range locks {
+		owner := lock.Owner
+		if owner == self.Owner {
+			owner = color.GreenString(owner)
+		} else {
+			owner = color.YellowString(owner)
+		}
+
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t\n",
+			lock.Path,
+			owner,
+			humanize.Time(lock.Since),
+		)
+	}
+
+	return tabW.Flush()
+}
+
+func handleAttrList(ctx *cli.Context, ctl *client.Client) error {
+	info, err := ctl.Stat(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if len(info.Attrs) == 0 {
+		fmt.Println("No attributes.")
+		return nil
+	}
+
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	fmt.Fprintln(tabW, "KEY\tVALUE\t")
+	for _, key := range sortedAttrKeys(info.Attrs) {
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t\n",
+			color.WhiteString(key),
+			formatAttrValue(info.Attrs[key]),
+		)
+	}
+
+	return tabW.Flush()
+}
+
+func handleAttrGet(ctx *cli.Context, ctl *client.Client) error {
+	value, err := ctl.GetAttr(ctx.Args().Get(0), ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	// Print the raw value; it might be binary.
+	if _, err := os.Stdout.Write(value); err != nil {
+		return err
+	}
+
+	if isatty.IsTerminal(os.Stdout.Fd()) {
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func handleAttrSet(ctx *cli.Context, ctl *client.Client) error {
+	var value []byte
+	if ctx.Bool("stdin") {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		value = data
+	} else {
+		if ctx.NArg() < 3 {
+			return ExitCode{BadArgs, "attr set: need a value or --stdin"}
+		}
+
+		value = []byte(ctx.Args().Get(2))
+	}
+
+	return ctl.SetAttr(ctx.Args().Get(0), ctx.Args().Get(1), value)
+}
+
+func handleAttrRemove(ctx *cli.Context, ctl *client.Client) error {
+	return ctl.RemoveAttr(ctx.Args().Get(0), ctx.Args().Get(1))
+}
+
 func handleTrashList(ctx *cli.Context, ctl *client.Client) error {
 	root := "/"
 	if firstArg := ctx.Args().First(); firstArg != "" {
@@ -703,13 +904,73 @@ func handleTrashList(ctx *cli.Context, ctl *client.Client) error {
 		return err
 	}
 
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	if len(nodes) > 0 {
+		fmt.Fprintln(tabW, "SIZE\tDELETED\tPATH\t")
+	}
+
+	totalSize, totalCount := uint64(0), 0
 	for _, node := range nodes {
-		fmt.Println(node.Path)
+		size := color.CyanString("purged")
+		if node.IsPinned {
+			size = colorForSize(node.Size)(humanize.Bytes(node.Size))
+			totalSize += node.Size
+			totalCount++
+		}
+
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t\n",
+			size,
+			node.ModTime.Format(time.Stamp),
+			color.RedString(node.Path),
+		)
+	}
+
+	if err := tabW.Flush(); err != nil {
+		return err
 	}
 
+	fmt.Printf(
+		"\nThe trash holds %s in %d of %d items.\n",
+		humanize.Bytes(totalSize),
+		totalCount,
+		len(nodes),
+	)
 	return nil
 }
 
 func handleTrashRemove(ctx *cli.Context, ctl *client.Client) error {
 	return ctl.Undelete(ctx.Args().First())
 }
+
+func handleTrashPurge(ctx *cli.Context, ctl *client.Client) error {
+	root := "/"
+	if firstArg := ctx.Args().First(); firstArg != "" {
+		root = firstArg
+	}
+
+	olderThanSec, err := parseDuration(ctx.String("older-than"))
+	if err != nil {
+		return ExitCode{BadArgs, fmt.Sprintf("bad duration: %v", err)}
+	}
+
+	olderThan := time.Duration(olderThanSec * float64(time.Second))
+	purged, err := ctl.PurgeTrash(root, olderThan)
+	if err != nil {
+		return ExitCode{UnknownError, fmt.Sprintf("purge: %v", err)}
+	}
+
+	freed := uint64(0)
+	for _, node := range purged {
+		fmt.Printf("%s %s\n", color.RedString("purged"), node.Path)
+		freed += node.Size
+	}
+
+	fmt.Printf("Unpinned %s in %d items.\n", humanize.Bytes(freed), len(purged))
+	return nil
+}
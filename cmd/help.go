@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/sahib/brig/repo/hints"
+	h "github.com/sahib/brig/util/hashlib"
 	"github.com/toqueteos/webbrowser"
 	"github.com/urfave/cli"
 )
@@ -73,6 +74,11 @@ var helpTexts = map[string]helpEntry{
 				Value: "httpipfs",
 				Usage: "What data backend to use for the new repo. One of  `mock`, `httpipfs`. This cannot be changed later!",
 			},
+			cli.StringFlag{
+				Name:  "hash-algorithm",
+				Value: h.DefaultAlgorithm.String(),
+				Usage: fmt.Sprintf("Algorithm for content and tree hashes. One of %s. Can be changed with »brig repo rehash«.", strings.Join(h.Algorithms(), ", ")),
+			},
 			cli.BoolFlag{
 				Name:  "empty,e",
 				Usage: "Do not create an initial README and no initial commit.",
@@ -139,16 +145,46 @@ EXAMPLES:
 				Name:  "key,k",
 				Usage: "Only print the key portion of the fingerprint",
 			},
+			cli.BoolFlag{
+				Name:  "qr,q",
+				Usage: "Print name and fingerprint as QR code on the terminal",
+			},
+			cli.BoolFlag{
+				Name:  "qr-invert",
+				Usage: "Draw the dark modules of the QR code (for terminals with light background)",
+			},
+			cli.StringFlag{
+				Name:  "qr-png",
+				Usage: "Write the QR code as PNG image to this path",
+			},
+			cli.BoolFlag{
+				Name:  "rotate-key",
+				Usage: "Replace the own key by a new one and tell all remotes about it",
+			},
 		},
 		Description: `This command prints your name, fingerprint and what store
    you are looking at. When you initialized your repository, you chose
    the name and a fingerprint (two longer hash values) was created for you.
 
+   Since the fingerprint is tedious to copy, it can also be shown as QR code
+   with »--qr« (or saved as image with »--qr-png«). The code contains the
+   name and fingerprint exactly like »brig remote add« expects them. The
+   gateway shows the same code under /qr, which is handy for phones.
+
+   With »--rotate-key« a new key is made and the old one is retired. The
+   change is signed with the old key and sent to all remotes, which update
+   your fingerprint on their side. Remotes that are offline learn about it
+   from other remotes that know you both. If you use an external IPFS daemon,
+   restart brig's daemon afterwards.
+
 EXAMPLES:
 
    # Show the fingerprint only:
    $ brig whoami -f
    QmUYz9dbqnYPyHCLUi7ghtiwFbdU93MQKFH4qg8iXHWcPV:W1q4vzbvLPUVwDUUXxjQfnuYJxq2CYqbeqXPSv7pUr5NcP
+
+   # Show a QR code for the other side to scan:
+   $ brig whoami --qr
 `,
 	},
 	"remote": {
@@ -174,11 +210,27 @@ EXAMPLES:
 `,
 	},
 	"remote.add": {
-		Usage:       "Add/Update a remote under a handy name with their fingerprint.",
-		ArgsUsage:   "<name> <fingerprint>",
-		Complete:    completeArgsUsage,
-		Description: "",
+		Usage:     "Add/Update a remote under a handy name with their fingerprint.",
+		ArgsUsage: "<name> <fingerprint>",
+		Complete:  completeArgsUsage,
+		Description: `
+   Add the remote with the fingerprint as shown by their »brig whoami«.
+
+   If the fingerprint was not exchanged over a secure channel, use »--verify«:
+   both sides add each other with it and 24 symbols (and words) derived
+   from both fingerprints are shown in four lines. Compare all of them in
+   person or over the phone; the remote is only added if you confirm that
+   they are the same. Comparing only a part of them does not protect you.
+
+EXAMPLES:
+
+   $ brig remote add --verify bob QmUYz9dbqnYPyHCLUi7ghtiwFbdU93MQKFH4qg8iXHWcPV:W1q4vzbvLPUVwDUUXxjQfnuYJxq2CYqbeqXPSv7pUr5NcP
+`,
 		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "verify,v",
+				Usage: "Compare a short authentication string with the remote before adding it",
+			},
 			cli.BoolFlag{
 				Name:  "auto-update,a",
 				Usage: "Take automatic updates from this node.",
@@ -198,11 +250,165 @@ EXAMPLES:
 			},
 		},
 	},
+	"remote.invite": {
+		Usage:    "Create a token that lets another user pair with us in one step.",
+		Complete: completeArgsUsage,
+		Description: `
+   Print a signed token with our name, fingerprint and the offered folders.
+   Give it to the other user, who passes it to »brig remote accept«. This adds
+   us as remote on their side, and them as remote on our side, with the folders
+   given here. Both daemons need to be online for this.
+
+   A token can be used only once and only until it expires. Send it over a
+   channel you trust; whoever has it can add itself as remote.
+
+EXAMPLES:
+
+   $ brig remote invite --folder /shared --expires 1h
+   $ brig remote invite --folder /shared --folder -/public --accept-push
+`,
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{
+				Name:  "folder,f",
+				Usage: "Folder the new remote may see. Can be given more than once. If the first letter of the folder is »-« it is added as read-only.",
+			},
+			cli.StringFlag{
+				Name:  "expires,e",
+				Usage: "How long the token may be used (e.g. »1h« or »30m«).",
+				Value: "24h",
+			},
+			cli.BoolFlag{
+				Name:  "accept-push,p",
+				Usage: "Allow the new remote to push to our state.",
+			},
+		},
+	},
+	"remote.accept": {
+		Usage:     "Accept a token made by »brig remote invite« and pair with the inviter.",
+		ArgsUsage: "<token>",
+		Complete:  completeArgsUsage,
+		Description: `
+   Contact the inviter, check the signature of the token and tell the inviter
+   our name and fingerprint, so it adds us as remote. The inviter is then added
+   as remote on our side as well, with the same folders it offered us.
+
+EXAMPLES:
+
+   $ brig remote accept eyJuYW1lIjoiYWxpY2UiLC...
+   $ brig remote accept --name alice-laptop eyJuYW1lIjoiYWxpY2UiLC...
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "name,n",
+				Usage: "Name of the new remote. Defaults to the name of the inviter.",
+			},
+		},
+	},
 	"remote.remove": {
-		Usage:       "Remove a remote by name.",
-		ArgsUsage:   "<name>",
+		Usage:     "Remove a remote by name.",
+		ArgsUsage: "<name>",
+		Complete:  completeArgsUsage,
+		Description: `Remove a remote by name.
+
+   The remote's key stays valid for other peers. If the key was lost
+   or stolen, use »brig remote revoke« instead.`,
+	},
+	"remote.revoke": {
+		Usage:     "Revoke the key of a remote and remove it.",
+		ArgsUsage: "<name>",
+		Complete:  completeArgsUsage,
+		Description: `
+   Sign a revocation of the remote's key and send it to all other remotes.
+   Use this when a device of the remote was lost or stolen. The remote is
+   removed and its key is not accepted anymore, neither by us nor by our
+   remotes (unless they disabled »net.accept_remote_revocations«).
+
+   Revocations can not be undone. The owner of the key needs to make a new
+   one with »brig whoami --rotate-key« from a device that still has it, or
+   pair with us again using a fresh repository.
+
+EXAMPLES:
+
+   $ brig remote revoke bob --reason "laptop was stolen"
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "reason,r",
+				Usage: "Why the key was revoked. Shown to other peers.",
+			},
+		},
+	},
+	"device": {
+		Usage:    "Link other devices of your user.",
+		Complete: completeSubcommands,
+		Description: `
+   Devices are several repositories of the same user, for example on your
+   laptop and your desktop. Their names differ only in the resource part
+   (»ali@woods.org/laptop« and »ali@woods.org/desktop«).
+
+   Linked devices are remotes that see all folders and may always push to
+   each other. They also share their remotes: if a device knows a peer, the
+   other devices add it on their next sync with it. Other peers learn about
+   all devices of a user and try them in order if one is not reachable. Each
+   device signs that it belongs to the user; devices without such a proof, or
+   with the key of somebody else, are ignored.
+
+   Without a subcommand, all linked devices are listed.
+
+EXAMPLES:
+
+   # On the laptop:
+   $ brig device link
+   eyJuYW1lIjoiYWxpQHdvb2RzLm9yZy9sYXB0b3AiLC...
+
+   # On the desktop:
+   $ brig device accept eyJuYW1lIjoiYWxpQHdvb2RzLm9yZy9sYXB0b3AiLC...
+`,
+	},
+	"device.link": {
+		Usage:    "Create a token that links another device of your user.",
+		Complete: completeArgsUsage,
+		Description: `
+   Print a signed token that the other device passes to »brig device accept«.
+   Both devices need to be online for this. The token can be used only once.
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "expires,e",
+				Usage: "How long the token may be used (e.g. »1h« or »30m«).",
+				Value: "1h",
+			},
+		},
+	},
+	"device.accept": {
+		Usage:       "Accept a token made by »brig device link« on another device.",
+		ArgsUsage:   "<token>",
+		Complete:    completeArgsUsage,
+		Description: "Link the device that made the token. Its user name must be the same as ours.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "name,n",
+				Usage: "Name of the device's remote. Defaults to the name of the device.",
+			},
+		},
+	},
+	"device.list": {
+		Usage:       "List all linked devices.",
+		Complete:    completeArgsUsage,
+		Description: "List all linked devices.",
+	},
+	"device.unlink": {
+		Usage:     "Unlink a device.",
+		ArgsUsage: "<name>",
+		Complete:  completeArgsUsage,
+		Description: `Remove the device from our remotes.
+
+   The device keeps its key. If it was lost or stolen, use »brig remote revoke«.`,
+	},
+	"remote.revocations": {
+		Usage:       "List all key revocations and rotations we know of.",
 		Complete:    completeArgsUsage,
-		Description: "Remove a remote by name.",
+		Description: "List all key revocations and rotations we know of, oldest first.",
 	},
 	"remote.list": {
 		Usage:    "List all remotes and their online status",
@@ -329,6 +535,28 @@ EXAMPLES:
 
    # or shorter to prevent you from RSI:
    brig rmt cs embrace bob charlie
+`,
+	},
+	"remote.bandwidth": {
+		Usage:     "Set own bandwidth limits for the connections with a remote.",
+		ArgsUsage: "<upload>/<download>|default <remote-name> [<remote-name>...]",
+		Complete:  completeArgsUsage,
+		Description: `By default, the limits of »net.bandwidth.upload« and
+   »net.bandwidth.download« (and their »net.bandwidth.schedule«) apply to
+   all remotes together. A remote with own limits is only limited by those.
+   Rates are given per second, a rate of 0 is unlimited. Upload limits only
+   apply to sync metadata; content is served by IPFS and is not limited.
+
+   Passing »default« removes the own limits of a remote again.
+   The current throughput can be seen with »brig net status«.
+
+EXAMPLES:
+
+   # Never send more than 500KB/s to bob, but receive as fast as possible:
+   $ brig remote bandwidth 500KB/0 bob
+
+   # Use the global limits for bob again:
+   $ brig rmt bw default bob
 `,
 	},
 	"remote.folder": {
@@ -354,12 +582,20 @@ EXAMPLES:
 				Usage: "What conflict strategy to use for this specific folder. Overwrites per-remote conflict strategy.",
 				Value: "",
 			},
+			cli.StringFlag{
+				Name:  "lock-policy,l",
+				Usage: "What to do with changes to paths locked by others: warn, refuse or ignore. Overwrites fs.sync.lock_policy.",
+				Value: "",
+			},
 		},
 		Description: `If a folder is added as read-only, we do not accept changes when syncing from remotes.
+The lock policy decides what happens when the remote changes a path that is locked
+by somebody else (see »brig lock«). If not given, fs.sync.lock_policy is used.
 
 EXAMPLES:
 
    $ brig remote folder add bob /public --read-only
+   $ brig remote folder add bob /design --lock-policy refuse
 `,
 	},
 	"remote.folder.set": {
@@ -379,6 +615,11 @@ EXAMPLES:
 				Usage: "What conflict strategy to use for this specific folder. Overwrites per-remote conflict strategy.",
 				Value: "",
 			},
+			cli.StringFlag{
+				Name:  "lock-policy,l",
+				Usage: "What to do with changes to paths locked by others: warn, refuse or ignore. Overwrites fs.sync.lock_policy.",
+				Value: "",
+			},
 		},
 		Description: `This works exactly like »add« but overwrites an existing folder.
 
@@ -414,14 +655,24 @@ EXAMPLES:
    is currently not invoked automatically, but can be activated via »brig gc«.
 
    Note that you can also pin files that you do not have cached locally. The
-   pin does not download a file automatically currently. Until we have a
-   proper way to do this, you can use »brig cat <file> > /dev/null«.
+   pin does not download a file automatically. If you want that, use the
+   »--offline« flag: It marks the path as "always available offline" and
+   brig will download and pin everything below it in the background, also
+   files that arrive later on by a sync. Use »brig pin offline« to see the
+   progress. Inside a mount the mark can be also set by writing "yes" or "no"
+   to the »user.brig.offline« extended attribute.
 
    This command contains the subcommand 'add', but for usability reasons, »brig
    pin add <path>« is the same as »brig pin <path>«.
 
    See also the »gc« command as counterpart of pinning.
 `,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "offline,o",
+				Usage: "Also keep everything below this path available offline",
+			},
+		},
 	},
 	"pin.add": {
 		Usage:     "Pin a file or directory to local storage",
@@ -429,6 +680,12 @@ EXAMPLES:
 		Complete:  completeBrigPath(true, true),
 		Description: `A node that is pinned to local storage will not be
    deleted by the garbage collector.`,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "offline,o",
+				Usage: "Also keep everything below this path available offline",
+			},
+		},
 	},
 	"pin.remove": {
 		Usage:     "Remove a pin",
@@ -436,6 +693,34 @@ EXAMPLES:
 		Complete:  completeBrigPath(true, true),
 		Description: `A node that is pinned to local storage will not be
    deleted by the garbage collector.`,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "offline,o",
+				Usage: "Also remove the offline mark of this path",
+			},
+		},
+	},
+	"pin.offline": {
+		Usage:    "Show offline marks and the download progress",
+		Complete: completeArgsUsage,
+		Description: `Lists all paths that were marked with »brig pin --offline«
+   and shows if brig is currently downloading files below them.
+
+   Failed downloads are retried every few minutes. Files that are not available
+   locally yet are flagged as such inside of offline mounts (see »brig mount
+   --offline«); opening them fails right away with EREMOTE instead of hanging.
+
+EXAMPLES:
+
+   $ brig pin --offline /music   # Mark /music as offline available.
+   $ brig pin offline --wait     # Wait until everything is downloaded.
+`,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "wait,w",
+				Usage: "Wait until all marked files were downloaded",
+			},
+		},
 	},
 	"pin.repin": {
 		Usage:     "Recaculate pinning based on fs.repin.{quota,min_depth,max_depth}",
@@ -484,9 +769,28 @@ EXAMPLES:
    Opposite of »brig net offline«. This is the default state whenever the daemon starts.`,
 	},
 	"net.status": {
-		Usage:       "Check if you're connected to the global network.",
-		Complete:    completeArgsUsage,
-		Description: `This will either print the string »online« or »offline«.`,
+		Usage:    "Check if you're connected to the global network.",
+		Complete: completeArgsUsage,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "w,watch",
+				Usage: "Print the throughput every second until interrupted",
+			},
+		},
+		Description: `This will print either »online« or »offline«, followed by the
+   current throughput and bandwidth limits of all traffic and of each remote
+   that we talked to since the daemon started.
+
+   Downloads include fetched content. Uploads only include the sync
+   connections with other remotes, which exchange metadata: content that
+   others fetch from us is served by IPFS and neither counted nor limited.
+
+   Limits are set with »brig config set net.bandwidth.upload 1MB« (and
+   ».download«), optionally depending on the time of day:
+
+   $ brig config set net.bandwidth.schedule "08:00-18:00 1MB/4MB" "22:00-06:00 0/0"
+
+   See »brig config doc net.bandwidth.schedule« and »brig help remote bandwidth«.`,
 	},
 	"net.locate": {
 		Usage:     "Try to locate a remote by their name or by a part of it.",
@@ -802,6 +1106,65 @@ EXAMPLES:
    - moved & modified: The file was moved and modified.
    - add & modified: The file was removed before and now re-added with different content.
    - moved & removed: The file was moved to another location.
+`,
+	},
+	"import-git": {
+		Usage:     "Replay the history of a git repository as brig commits",
+		ArgsUsage: "<git-repo>",
+		Complete:  completeLocalPath,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "rev,r",
+				Value: "HEAD",
+				Usage: "Import the history up to this git revision",
+			},
+			cli.StringFlag{
+				Name:  "prefix,p",
+				Value: "/",
+				Usage: "Put all imported files below this brig directory",
+			},
+		},
+		Description: `Import the history of the git repository at »git-repo«.
+
+   Every git commit on the mainline of »--rev« is replayed as brig commit.
+   The author, message and date of each commit are kept. Merges are
+   flattened, since brig has a linear history. Git commits that do not
+   change anything for brig (e.g. because they only change file modes)
+   are skipped. Symbolic links and submodules are not imported.
+
+   There may not be any uncommitted changes when importing.
+   The »git« executable needs to be installed.
+
+EXAMPLES:
+
+   $ brig import-git ~/code/assets --prefix /assets
+`,
+	},
+	"export-git": {
+		Usage:     "Write the history of brig into a git repository",
+		ArgsUsage: "<dir>",
+		Complete:  completeLocalPath,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "branch,b",
+				Value: "master",
+				Usage: "Name of the git branch to write the commits to",
+			},
+		},
+		Description: `Export all commits up to HEAD into the git repository at »dir«.
+
+   If »dir« is no git repository yet, it will be created. The history is fed
+   to »git fast-import«, so the resulting repository can be inspected with
+   the usual git tools. Each brig commit becomes one git commit with the same
+   author, message and date. Tags are exported as lightweight git tags.
+   Empty directories are not exported, since git does not know them.
+
+   Exporting to a branch that already has a different history will fail.
+
+EXAMPLES:
+
+   $ brig export-git /tmp/brig-history
+   $ git -C /tmp/brig-history log --stat
 `,
 	},
 	"stage": {
@@ -833,6 +1196,10 @@ EXAMPLES:
    Additionally you can read the file from standard input if you pass »--stdin«.
    In this case you pass only one path: The path where the stream is stored.
 
+   If »local-path« is a directory, all files in it are staged recursively.
+   The daemon stages them in batches and processes several files in parallel
+   (see »fs.stage.workers«), so staging many small files is reasonably fast.
+
 EXAMPLES:
 
    $ brig stage file.png                   # gets added as /file.png
@@ -945,7 +1312,8 @@ EXAMPLES:
    If no »<path>« is given, the root directory is assumed. Every line of »ls«
    shows a human readable size of each entry, the last modified time stamp, the
    user that last modified the entry (if there's more than one) and if the
-   entry if pinned.
+   entry if pinned. User defined attributes can be printed with »--format«,
+   see »brig attr«.
 `,
 	},
 	"tree": {
@@ -1013,6 +1381,112 @@ EXAMPLES:
 
 `,
 	},
+	"lock": {
+		Usage:     "Lock files or directories, so others know you are working on them",
+		ArgsUsage: "<path> [<path>...]",
+		Complete:  completeBrigPath(true, true),
+		Description: `Take an advisory lock on the given paths.
+
+   A lock on a directory covers everything below it. Locks are shown to all
+   remotes that may access the path and are announced to them immediately if
+   they are online. Locks do not prevent anybody from changing a file, but:
+
+   * Other users see your lock in »brig locks«.
+   * flock(2) and fcntl(2) locks on a fuse mount take a brig lock and fail if
+     somebody else holds a lock on the file.
+   * When syncing, changes of others to your locked paths cause a warning or
+     are refused, depending on fs.sync.lock_policy and the per-folder lock
+     policy (see »brig remote folder add --lock-policy«).
+
+   Locking fails if somebody else already holds a lock on the path.
+
+EXAMPLES:
+
+   $ brig lock /design/logo.psd
+   $ brig unlock /design/logo.psd
+`,
+	},
+	"unlock": {
+		Usage:     "Release locks taken by »brig lock«",
+		ArgsUsage: "<path> [<path>...]",
+		Complete:  completeBrigPath(true, true),
+		Description: `Release your own locks on the given paths.
+
+   Locks of other users can not be released; they have to do it themselves.
+`,
+	},
+	"locks": {
+		Usage:    "List all known locks",
+		Complete: completeArgsUsage,
+		Description: `List your own locks and the locks of remotes.
+
+   Locks of remotes are updated whenever they announce a change and on every
+   fetch or sync with them.
+`,
+	},
+	"attr": {
+		Usage:     "Manage user defined attributes of files",
+		ArgsUsage: "<path>",
+		Complete:  completeBrigPath(true, false),
+		Description: `Files can carry arbitrary key/value attributes, e.g. to tag photos
+   with the project and client they belong to. Without a subcommand, the
+   attributes of »path« are listed.
+
+   Attributes are part of the file's metadata: Changing them is recorded in
+   the next commit and synced to other remotes like a change of the content.
+   On a fuse mount they are available as extended attributes prefixed with
+   »user.« (see getfattr(1) and setfattr(1)). Keys starting with »brig.« are
+   reserved.
+
+   All attributes can also be shown by »brig ls« and »brig info« using
+   the »attr« and »attrs« template functions:
+
+   $ brig ls --format '{{ .Path }} {{ attr .Attrs "project" }}'
+   $ brig ls --format '{{ .Path }} {{ attrs .Attrs }}'
+
+EXAMPLES:
+
+   $ brig attr set /photos/beach.jpg project holiday
+   $ brig attr set /photos/beach.jpg client acme
+   $ brig attr ls /photos/beach.jpg
+   KEY      VALUE
+   client   acme
+   project  holiday
+   $ brig attr get /photos/beach.jpg project
+   holiday
+   $ brig attr rm /photos/beach.jpg client
+`,
+	},
+	"attr.list": {
+		Usage:       "List all attributes of a file",
+		ArgsUsage:   "<path>",
+		Complete:    completeBrigPath(true, false),
+		Description: "See help of »brig attr«",
+	},
+	"attr.get": {
+		Usage:       "Print the value of a single attribute",
+		ArgsUsage:   "<path> <key>",
+		Complete:    completeBrigPath(true, false),
+		Description: "See help of »brig attr«",
+	},
+	"attr.set": {
+		Usage:     "Set an attribute to a value",
+		ArgsUsage: "<path> <key> [<value>]",
+		Complete:  completeBrigPath(true, false),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "stdin,s",
+				Usage: "Read the value from stdin",
+			},
+		},
+		Description: "See help of »brig attr«",
+	},
+	"attr.remove": {
+		Usage:       "Remove an attribute",
+		ArgsUsage:   "<path> <key>",
+		Complete:    completeBrigPath(true, false),
+		Description: "See help of »brig attr«",
+	},
 	"daemon": {
 		Usage:    "Daemon management commands.",
 		Complete: completeSubcommands,
@@ -1022,6 +1496,15 @@ EXAMPLES:
    (like »brig init« or later on a »brig ls«). This command will start it for
    you in the background. Therefore it is seldom useful to use any of those
    commands - unless you are debugging brig.
+
+   If »daemon.url« is a unix socket (the default on Linux), only the user
+   running the daemon (and root) may talk to it.  Other users can be given
+   full access with »daemon.allowed_uids« or read-only access (listing, cat,
+   log and similar) with »daemon.readonly_uids«.  A socket file is created
+   with the permissions in »daemon.socket_mode«.  Over tcp everyone that can
+   connect has full access.  If the daemon serves several repositories, the
+   config of the default one decides who may connect at all; selecting another
+   one (»brig --repo«) applies the lists of that repository.
 `,
 	},
 	"daemon.launch": {
@@ -1036,14 +1519,25 @@ EXAMPLES:
 				Name:  "s,log-to-stdout",
 				Usage: "Log all messages to stdout instead of syslog",
 			},
+			cli.StringSliceFlag{
+				Name:  "a,add-repo",
+				Usage: "Also serve the repository at [NAME=]PATH (can be given several times)",
+			},
 		},
 		Description: `Start the dameon process in the foreground.
 
+   By default the daemon serves only the repository given by »--repo«.  With
+   »--add-repo« it serves other repositories over the same socket as well.
+   Each of them gets its own backend, event listener and gateway.  Commands
+   select one of them with »brig --repo <name or path>«; without »--repo« the
+   default repository is used.  The name defaults to the name of the folder.
 
 EXAMPLES:
 
    $ brig daemon quit        # Shut down any previous daemon.
    $ brig daemon launch -s   # Start in foreground and log to stdout.
+   $ brig --repo ~/work daemon launch --add-repo private=$HOME/private
+   $ brig --repo private ls  # List the contents of the second repository.
 `,
 	},
 	"daemon.quit": {
@@ -1068,6 +1562,14 @@ EXAMPLES:
 			},
 		},
 	},
+	"daemon.repos": {
+		Usage:    "List the repositories served by the daemon",
+		Complete: completeArgsUsage,
+		Description: `List all repositories that the daemon serves, with their name,
+   owner and path. Use the name (or the path) with »brig --repo« to select one.
+   The default repository is used if »--repo« is not given.
+`,
+	},
 	"config": {
 		Usage:    "View and modify config options.",
 		Complete: completeSubcommands,
@@ -1130,6 +1632,10 @@ if the service needs a restart when setting the value.
 				Name:  "offline,o",
 				Usage: "Error out on files that are only remotely available.",
 			},
+			cli.BoolFlag{
+				Name:  "flag-unavailable",
+				Usage: "List files that are only remotely available without permissions.",
+			},
 			cli.StringFlag{
 				Name:  "x,root",
 				Usage: "Specify a root directory other than »/«.",
@@ -1170,6 +1676,37 @@ if the service needs a restart when setting the value.
 
    It is possible to have more than one mount. They will show the same content.
 
+OLD VERSIONS
+
+   Every mount has a hidden, read-only directory ».brig« in its root. It is
+   not listed, but can be entered directly and gives access to older states:
+
+   - ».brig/snapshots/<rev>/<path>« shows <path> as it was at <rev>. <rev> can
+     be a tag, a commit hash or something like »head^«.
+   - ».brig/history/<path>/<commit>« shows every committed version of the
+     file at <path>, named after the commit that introduced it.
+
+   Restoring an old version is therefore just a matter of copying it:
+
+   $ cp /mnt/.brig/history/photo.png/<commit> /mnt/photo.png
+
+OFFLINE MODE
+
+   With »--offline« all files are still listed, but files that are not cached
+   locally show up as dangling symlinks and fail with EREMOTE when opened,
+   instead of blocking until they were fetched from the network. Scripts can
+   check the »user.brig.available« extended attribute ("yes" or "no").
+
+   With »--flag-unavailable« those files are listed as normal files, but
+   without any permissions (»----------« in »ls -l«). Opening them fails with
+   EREMOTE as well. Once they were fetched (e.g. by »brig pin --offline«),
+   they show up with their normal permissions again.
+
+   Setting »user.brig.offline« to "yes" on a file or directory has the same
+   effect as »brig pin --offline«:
+
+   $ setfattr -n user.brig.offline -v yes /mnt/music
+
 CAVEATS
 
    Editing large files will currently eat big amounts of memory, proportional
@@ -1193,6 +1730,10 @@ CAVEATS
 				Name:  "offline,o",
 				Usage: "Error out on files that are only remotely available.",
 			},
+			cli.BoolFlag{
+				Name:  "flag-unavailable",
+				Usage: "List files that are only remotely available without permissions.",
+			},
 			cli.StringFlag{
 				Name:  "x,root",
 				Usage: "Create the filesystem as readonly",
@@ -1262,11 +1803,48 @@ CAVEATS
         `,
 	},
 	"trash.list": {
-		Usage: "List all items in the trash bin.",
+		Usage:     "List all items in the trash bin.",
+		ArgsUsage: "[<root>]",
+		Complete:  completeBrigPath(true, true),
+		Description: `List all deleted items below »root« (or »/«).
+
+   Each item is printed with its size and the time it was deleted. Items whose
+   content is not pinned anymore (e.g. because they were purged) are shown as
+   »purged«. The total size of all items that still take up space is printed
+   at the end.
+`,
 	},
 	"trash.undelete": {
 		Usage: "Restore a path from the trashbin.",
 	},
+	"trash.purge": {
+		Usage:     "Free the space used by items in the trash bin.",
+		ArgsUsage: "[<root>]",
+		Complete:  completeBrigPath(true, true),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "older-than,o",
+				Value: "0s",
+				Usage: "Only purge items that were deleted at least this long ago",
+			},
+		},
+		Description: `Unpin the content of deleted items below »root« (or »/«).
+
+   The content of the deleted items and of all their older versions is
+   unpinned, so the backend can reclaim the space on its next garbage
+   collection. Content that is still used by other files is kept.
+   Purged items can still be undeleted, but their content might need
+   to be fetched from other peers then.
+
+   Setting »fs.trash.retention« will do this automatically for items that
+   were deleted longer ago than the retention period.
+
+EXAMPLES:
+
+   $ brig trash purge                     # Purge everything.
+   $ brig trash purge /photos -o 168h     # Purge items deleted over a week ago.
+`,
+	},
 	"gateway": {
 		Usage: "Control the HTTP/S gateway service.",
 		Description: `The gateway serves a UI and download endpoints over a browser.
@@ -1409,6 +1987,162 @@ EXAMPLES:
 			},
 		},
 	},
+	"repo": {
+		Usage: "Maintenance commands that operate on the repository itself.",
+	},
+	"repo.migrate-db": {
+		Usage: "Convert the metadata store to another database backend.",
+		Description: `
+    Copy all metadata of the repository into a database of another type.
+    The daemon must not be running while doing this. Currently supported
+    types are »badger« (the default) and »disk«. The latter stores every key
+    in a separate file, which is slow, but easy to inspect and debug.
+
+    After copying, the number of keys and a checksum over all keys and values
+    are compared between the old and new store. The old store is kept as rollback
+    until you either confirm the migration with »--confirm« or restore it
+    with »--rollback«. No other migration can be started before that.
+
+EXAMPLES:
+
+    $ brig daemon quit
+    $ brig repo migrate-db --to disk
+    # Check that everything works as expected:
+    $ brig ls
+    $ brig repo migrate-db --confirm
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "to,t",
+				Usage: "The database type to migrate to (»badger« or »disk«)",
+			},
+			cli.BoolFlag{
+				Name:  "confirm,c",
+				Usage: "Delete the old store kept by a previous migration",
+			},
+			cli.BoolFlag{
+				Name:  "rollback,r",
+				Usage: "Restore the old store kept by a previous migration",
+			},
+		},
+	},
+	"repo.rehash": {
+		Usage: "Recompute all hashes of the repository with another algorithm.",
+		Description: `
+    Rewrite the content, tree and commit hashes of the whole history using
+    another hash algorithm and record it as the algorithm of the repository.
+    The daemon must not be running while doing this. The content of all files
+    is read from the backend, so this may take a while for big repositories.
+    Files that cannot be read get a hash derived from their old one.
+
+    Remotes can only sync with each other when they use the same algorithm.
+    The metadata of remotes is dropped by the rehash and fetched again on the
+    next sync. Commit hashes change, so old references to them become invalid.
+
+    Like with »brig repo migrate-db«, the old metadata is kept as rollback until
+    you either confirm the rehash with »--confirm« or restore it with »--rollback«.
+
+EXAMPLES:
+
+    $ brig daemon quit
+    $ brig repo rehash --to sha2-256
+    # Check that everything works as expected:
+    $ brig log
+    $ brig repo rehash --confirm
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "to,t",
+				Usage: fmt.Sprintf("The algorithm to switch to (%s)", strings.Join(h.Algorithms(), ", ")),
+			},
+			cli.BoolFlag{
+				Name:  "confirm,c",
+				Usage: "Delete the old metadata kept by a previous rehash",
+			},
+			cli.BoolFlag{
+				Name:  "rollback,r",
+				Usage: "Restore the old metadata kept by a previous rehash",
+			},
+		},
+	},
+	"backup": {
+		Usage: "Create and restore incremental, encrypted backups of the metadata.",
+		Description: `
+    Backups contain the metadata of all filesystems (i.e. the history and
+    the file tree of you and your remotes), the config and the keyring.
+    They do not contain the actual file content, which is stored in the backend.
+
+    All backups of a directory form a chain: The first backup is a full one,
+    all others only contain the keys that were changed since the previous one.
+    Every backup is encrypted with a key derived from a password. The same
+    password has to be used for all backups in one directory.
+
+    The daemon must not run while creating a backup.
+
+EXAMPLES:
+
+    # Nightly job that creates a new backup on an external drive:
+    $ brig daemon quit
+    $ brig backup create /mnt/usb/brig-backups --password-command "pass brig/backup"
+    $ brig backup list /mnt/usb/brig-backups
+    # Restore the latest state to a new repository:
+    $ brig --repo /tmp/restored backup restore /mnt/usb/brig-backups
+`,
+	},
+	"backup.create": {
+		Usage:     "Write a new backup to <dir>.",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "password-command,p",
+				Usage: "Execute this command to get the password from its stdout.",
+			},
+			cli.StringFlag{
+				Name:  "password-file,P",
+				Usage: "Read this file to get the password.",
+			},
+			cli.BoolFlag{
+				Name:  "full,f",
+				Usage: "Create a full backup, even if there are previous backups.",
+			},
+		},
+	},
+	"backup.list": {
+		Usage:     "List all backups in <dir>.",
+		ArgsUsage: "<dir>",
+	},
+	"backup.verify": {
+		Usage:     "Check that all backups in <dir> can be read and are consistent.",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "password-command,p",
+				Usage: "Execute this command to get the password from its stdout.",
+			},
+			cli.StringFlag{
+				Name:  "password-file,P",
+				Usage: "Read this file to get the password.",
+			},
+		},
+	},
+	"backup.restore": {
+		Usage:     "Restore a backup from <dir> to a new repository (given by --repo).",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "password-command,p",
+				Usage: "Execute this command to get the password from its stdout.",
+			},
+			cli.StringFlag{
+				Name:  "password-file,P",
+				Usage: "Read this file to get the password.",
+			},
+			cli.IntFlag{
+				Name:  "index,i",
+				Usage: "Index of the backup to restore (default: latest)",
+			},
+		},
+	},
 	"unpack-repo": {
 		ArgsUsage: "<archive-path>",
 		Description: `
@@ -1626,6 +2360,22 @@ EXAMPLES:
    an immediate effect you should use »brig hints set --recode <path>«, or,
    if you want to do it a later point, »brig hints recode <path>«.
 
+   Folders with many small, similar files (logs, JSON, source code) compress
+   badly, since every file is compressed on its own. »brig hints train-dict«
+   trains a zstd dictionary on such a folder, which is then used for all new
+   files in it. Dictionaries are synced to remotes, so they can read the files.
+
+   With the »guess« compression, brig compresses the first 128 KB of each file
+   with every algorithm and level. It takes the fastest one that compresses
+   nearly as well as the best one. Files that hardly compress (like most
+   media files) are stored uncompressed, whatever their file type is. The
+   compression that was chosen for a file is shown by »brig info«. For
+   directories, it shows how much space the compression saves in total.
+
+   A hint can also put a folder into a key domain (»--key-domain«). All folders
+   of a domain are shared with the same people and are re-keyed together by
+   »brig rekey«. Use »--key-domain none« to remove a folder from its domain.
+
    The available compression algorithms are:
 
 %s
@@ -1640,9 +2390,9 @@ EXAMPLES:
    $ echo "meow" | brig stage --stdin /public/cat-meme.png
    $ brig hints set /public --compression none --encryption none
    $ brig hints
-   PATH     ENCRYPTION  COMPRESSION
-   /        aes256gcm   guess
-   /public  none        none
+   PATH     ENCRYPTION  COMPRESSION  DICT  KEY DOMAIN
+   /        aes256gcm   guess        -     -
+   /public  none        none         -     -
    # If a file could be streamed by »ipfs cat« alone,
    # then the »IsRaw« attribute is true.
    $ brig info --format '{{ .IsRaw }}' /public/cat-meme.png
@@ -1665,6 +2415,10 @@ EXAMPLES:
 				Name:  "encryption,e",
 				Usage: "What encryption algorithm to use for this hint",
 			},
+			cli.StringFlag{
+				Name:  "key-domain,k",
+				Usage: "Put the path into this key domain (»none« to remove it from its domain)",
+			},
 			cli.BoolFlag{
 				Name:  "force,f",
 				Usage: "Also create hint if there is no such file or directory",
@@ -1681,7 +2435,88 @@ EXAMPLES:
 	},
 	"hints.recode": {
 		ArgsUsage: "[<path>]",
-		Usage:     "Recode the streams in <path>. If no path given all files are recoded.",
+		Usage:     "Recode the streams in <path>. If no path given all files are recoded. Prints the new compression ratio.",
+	},
+	"hints.train-dict": {
+		ArgsUsage: "<folder>",
+		Usage:     "Train a zstd dictionary for the files in <folder>",
+		Description: `Train a compression dictionary on the files below <folder> and use it for them.
+
+   The hint of <folder> is changed to use zstd compression with the new dictionary.
+   The encryption setting of the hint is kept. Like with other hints, only new or
+   changed files use the dictionary. Pass --recode to also recode existing files.
+
+   Dictionaries never change once trained; training again creates a new one.
+   Files compressed with the old dictionary stay readable.
+
+EXAMPLES:
+
+   $ brig hints train-dict /logs --recode
+   trained dictionary 6f9c3a1e2b4d (64 KB) for /logs
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "max-size,s",
+				Value: "64K",
+				Usage: "Maximum size of the dictionary",
+			},
+			cli.BoolFlag{
+				Name:  "recode,r",
+				Usage: "Recode the existing files in <folder> immediately.",
+			},
+		},
+	},
+	"rekey": {
+		ArgsUsage: "<folder>",
+		Usage:     "Re-encrypt the files in <folder> with new keys",
+		Description: `Re-encrypt the current version of all files in <folder> with new keys.
+
+   Every file is encrypted with its own key, which is part of the metadata and
+   therefore known to every remote that synced the folder. Removing a remote (or
+   one of its folders) does not take this knowledge away: it could still read
+   any new version of the files, since modified files keep their key.
+
+   »brig rekey« generates new keys for the current versions and makes a commit.
+   The new keys are only synced to remotes that still have access to the folder;
+   they are listed when the rekey is done. Old versions stay readable with the
+   old keys.
+
+   If <folder> is part of a key domain (see »brig hints set --key-domain«), all
+   folders of the domain are re-keyed together.
+
+EXAMPLES:
+
+   $ brig remote rm bob
+   $ brig rekey /team
+   rekeyed 12/12 files in /team
+   The new keys are synced to: alice
+`,
+	},
+	"jobs": {
+		Usage:    "Show the progress of long running operations",
+		Complete: completeArgsUsage,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "w,watch",
+				Usage: "Update the list every second until interrupted",
+			},
+		},
+		Description: `List what the daemon is busy with right now: syncs, pins of
+   directories and cats that take a while, usually because the content needs to
+   be fetched first. For every job the number of files and bytes done so far is
+   shown, together with the file that is being worked on and an estimate of the
+   remaining time.
+
+   »brig sync«, »brig pin add« and »brig cat« show the same progress on stderr
+   while they run, if stderr is a terminal. The gateway sends it to its clients
+   over the »/events« websocket.
+
+EXAMPLES:
+
+   $ brig jobs
+   pin /photos: [=======             ]  35%  120/340 files  1.2 GB/3.4 GB  ETA 2m10s
+     /photos/2019/beach.jpg
+`,
 	},
 	"bug": {
 		Usage: "Print a template for bug reports.",
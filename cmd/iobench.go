@@ -18,6 +18,10 @@ func allBenchmarks() []string {
 
 	for _, benchName := range bench.BenchmarkNames() {
 		for _, inputName := range bench.InputNames() {
+			if !bench.InputIsSupported(benchName, inputName) {
+				continue
+			}
+
 			names = append(names, fmt.Sprintf("%s:%s", benchName, inputName))
 		}
 	}
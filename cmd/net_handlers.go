@@ -7,10 +7,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/sahib/brig/cmd/tabwriter"
 
 	"github.com/sahib/brig/client"
+	"github.com/sahib/brig/net/peer"
+	"github.com/sahib/brig/util/qrcode"
 	"github.com/urfave/cli"
 	yml "gopkg.in/yaml.v2"
 )
@@ -35,6 +38,97 @@ func handleIsOnline(ctx *cli.Context, ctl *client.Client) error {
 		fmt.Println(color.RedString("offline"))
 	}
 
+	for {
+		global, remotes, err := ctl.BandwidthStats()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+		if err := printBandwidthStats(global, remotes); err != nil {
+			return err
+		}
+
+		if !ctx.Bool("watch") {
+			return nil
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+func formatRate(rate, limit int64) string {
+	formatted := humanize.Bytes(uint64(rate)) + "/s"
+	if limit > 0 {
+		formatted += fmt.Sprintf(" (max %s/s)", humanize.Bytes(uint64(limit)))
+	}
+
+	return formatted
+}
+
+func printBandwidthStats(global *client.BandwidthStats, remotes []client.BandwidthStats) error {
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	fmt.Fprintln(tabW, "TRAFFIC\tUPLOAD\tDOWNLOAD\tUPLOADED\tDOWNLOADED\t")
+
+	printRow := func(name string, stats client.BandwidthStats) {
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t%s\t%s\t\n",
+			name,
+			formatRate(stats.Upload, stats.UploadLimit),
+			formatRate(stats.Download, stats.DownloadLimit),
+			humanize.Bytes(uint64(stats.UploadTotal)),
+			humanize.Bytes(uint64(stats.DownloadTotal)),
+		)
+	}
+
+	hasUploadLimit := global.UploadLimit > 0
+	printRow(color.CyanString("all"), *global)
+	for _, stats := range remotes {
+		printRow(stats.Name, stats)
+		hasUploadLimit = hasUploadLimit || stats.UploadLimit > 0
+	}
+
+	if err := tabW.Flush(); err != nil {
+		return err
+	}
+
+	if hasUploadLimit {
+		fmt.Println()
+		fmt.Println("Upload limits only apply to sync metadata; content is served by IPFS and not limited.")
+	}
+
+	return nil
+}
+
+func handleRemoteBandwidth(ctx *cli.Context, ctl *client.Client) error {
+	uploadLimit, downloadLimit := "", ""
+	if spec := ctx.Args().First(); spec != "default" {
+		split := strings.Split(spec, "/")
+		if len(split) != 2 {
+			return fmt.Errorf("please specify »<upload>/<download>« or »default« as first argument")
+		}
+
+		uploadLimit, downloadLimit = split[0], split[1]
+	}
+
+	for _, remoteName := range ctx.Args()[1:] {
+		rmt, err := ctl.RemoteByName(remoteName)
+		if err != nil {
+			return err
+		}
+
+		rmt.UploadLimit = uploadLimit
+		rmt.DownloadLimit = downloadLimit
+		if err := ctl.RemoteAddOrUpdate(rmt); err != nil {
+			return fmt.Errorf("remote update: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -254,6 +348,12 @@ func handleRemoteAdd(ctx *cli.Context, ctl *client.Client) error {
 		})
 	}
 
+	if ctx.Bool("verify") {
+		if err := verifyFingerprint(ctl, remote.Name, remote.Fingerprint); err != nil {
+			return err
+		}
+	}
+
 	if err := ctl.RemoteAddOrUpdate(remote); err != nil {
 		return fmt.Errorf("remote add: %v", err)
 	}
@@ -261,6 +361,169 @@ func handleRemoteAdd(ctx *cli.Context, ctl *client.Client) error {
 	return nil
 }
 
+func handleRemoteInvite(ctx *cli.Context, ctl *client.Client) error {
+	folders := []client.RemoteFolder{}
+	for _, folder := range ctx.StringSlice("folder") {
+		isReadOnly := false
+		if strings.HasPrefix(folder, "-") {
+			isReadOnly = true
+			folder = folder[1:]
+		}
+
+		folders = append(folders, client.RemoteFolder{
+			Folder:   folder,
+			ReadOnly: isReadOnly,
+		})
+	}
+
+	expiresSec, err := parseDuration(ctx.String("expires"))
+	if err != nil {
+		return err
+	}
+
+	validFor := time.Duration(expiresSec * float64(time.Second))
+	token, err := ctl.RemoteInvite(folders, ctx.Bool("accept-push"), validFor)
+	if err != nil {
+		return fmt.Errorf("remote invite: %v", err)
+	}
+
+	fmt.Println(token)
+	return nil
+}
+
+func handleDeviceLink(ctx *cli.Context, ctl *client.Client) error {
+	expiresSec, err := parseDuration(ctx.String("expires"))
+	if err != nil {
+		return err
+	}
+
+	validFor := time.Duration(expiresSec * float64(time.Second))
+	token, err := ctl.DeviceInvite(validFor)
+	if err != nil {
+		return fmt.Errorf("device link: %v", err)
+	}
+
+	fmt.Println(token)
+	return nil
+}
+
+func handleDeviceList(ctx *cli.Context, ctl *client.Client) error {
+	remotes, err := ctl.RemoteLs()
+	if err != nil {
+		return err
+	}
+
+	devices := []client.Remote{}
+	for _, remote := range remotes {
+		if remote.Device {
+			devices = append(devices, remote)
+		}
+	}
+
+	if len(devices) == 0 {
+		fmt.Println("No other devices yet. Use `brig device link` to add some.")
+		return nil
+	}
+
+	tabW := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.StripEscape)
+	fmt.Fprintln(tabW, "NAME\tFINGERPRINT\tAUTO-UPDATE\t")
+
+	for _, device := range devices {
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t\n",
+			device.Name,
+			device.Fingerprint,
+			yesOrNo(device.AutoUpdate),
+		)
+	}
+
+	return tabW.Flush()
+}
+
+func handleDeviceUnlink(ctx *cli.Context, ctl *client.Client) error {
+	name := ctx.Args().First()
+	remote, err := ctl.RemoteByName(name)
+	if err != nil {
+		return err
+	}
+
+	if !remote.Device {
+		return fmt.Errorf("%s is not a device of ours; use »brig remote rm«", name)
+	}
+
+	if err := ctl.RemoteRm(name); err != nil {
+		return fmt.Errorf("device unlink: %v", err)
+	}
+
+	return nil
+}
+
+func handleRemoteAccept(ctx *cli.Context, ctl *client.Client) error {
+	remote, err := ctl.RemoteAcceptInvite(ctx.Args().First(), ctx.String("name"))
+	if err != nil {
+		return fmt.Errorf("remote accept: %v", err)
+	}
+
+	if remote.Device {
+		fmt.Printf("Linked %s as device of our user.\n", color.GreenString(remote.Name))
+		return nil
+	}
+
+	fmt.Printf("Added %s as remote.\n", color.GreenString(remote.Name))
+	for _, folder := range remote.Folders {
+		fmt.Printf("  Shared folder: %s\n", folder.Folder)
+	}
+
+	return nil
+}
+
+// verifyFingerprint shows the short authentication string of our
+// and the remote's fingerprint and asks the user to compare it.
+func verifyFingerprint(ctl *client.Client, name, fingerprint string) error {
+	remoteFp, err := peer.CastFingerprint(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	self, err := ctl.Whoami()
+	if err != nil {
+		return err
+	}
+
+	symbols := peer.ShortAuthString(peer.Fingerprint(self.Fingerprint), remoteFp)
+
+	fmt.Printf("Ask %s to run »brig remote add --verify« with your fingerprint\n", color.YellowString(name))
+	fmt.Println("and compare the following symbols, in person or over the phone:")
+	fmt.Println()
+	for idx := 0; idx < len(symbols); idx += peer.SASGroupSize {
+		end := idx + peer.SASGroupSize
+		if end > len(symbols) {
+			end = len(symbols)
+		}
+
+		words := []string{}
+		for _, symbol := range symbols[idx:end] {
+			words = append(words, symbol.Emoji+"  "+symbol.Word)
+		}
+
+		fmt.Printf("    %d: %s\n", idx/peer.SASGroupSize+1, strings.Join(words, ", "))
+	}
+
+	fmt.Println()
+
+	same, err := askYesNo("Do you both see the same symbols in the same order, in every line?")
+	if err != nil {
+		return err
+	}
+
+	if !same {
+		return fmt.Errorf("fingerprint of %s was not verified; remote was not added", name)
+	}
+
+	return nil
+}
+
 func handleRemoteAutoUpdate(ctx *cli.Context, ctl *client.Client) error {
 	enable := true
 
@@ -346,6 +609,68 @@ func handleRemoteRemove(ctx *cli.Context, ctl *client.Client) error {
 	return nil
 }
 
+func printNotReached(notReached []string) {
+	if len(notReached) == 0 {
+		return
+	}
+
+	fmt.Printf(
+		"Could not reach %s; they will hear about it later from other remotes.\n",
+		color.YellowString(strings.Join(notReached, ", ")),
+	)
+}
+
+func handleRemoteRevoke(ctx *cli.Context, ctl *client.Client) error {
+	name := ctx.Args().First()
+	notReached, err := ctl.RemoteRevoke(name, ctx.String("reason"))
+	if err != nil {
+		return fmt.Errorf("remote revoke: %v", err)
+	}
+
+	fmt.Printf("Revoked the key of %s and removed it.\n", color.RedString(name))
+	printNotReached(notReached)
+	return nil
+}
+
+func handleRemoteRevocations(ctx *cli.Context, ctl *client.Client) error {
+	revs, err := ctl.RevocationList()
+	if err != nil {
+		return err
+	}
+
+	if len(revs) == 0 {
+		fmt.Println("No revocations known.")
+		return nil
+	}
+
+	tabW := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.StripEscape)
+	fmt.Fprintln(tabW, "REVOKED\tSUCCESSOR\tISSUER\tCREATED\tREASON\t")
+
+	for _, rv := range revs {
+		issuer := rv.Issuer
+		if rv.SelfSigned {
+			issuer = "self"
+		}
+
+		successor := rv.Successor
+		if successor == "" {
+			successor = "-"
+		}
+
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t%s\t%s\t\n",
+			rv.Revoked,
+			successor,
+			issuer,
+			rv.Created.Format(time.RFC3339),
+			rv.Reason,
+		)
+	}
+
+	return tabW.Flush()
+}
+
 func handleRemoteClear(ctx *cli.Context, ctl *client.Client) error {
 	return ctl.RemoteClear()
 }
@@ -416,6 +741,7 @@ func handleRemoteFolderAddOrReplace(ctx *cli.Context, ctl *client.Client, replac
 
 	isReadOnly := ctx.Bool("read-only")
 	conflictStrategy := ctx.String("conflict-strategy")
+	lockPolicy := ctx.String("lock-policy")
 
 	for _, folder := range ctx.Args().Tail() {
 		if _, err := ctl.Stat(folder); err != nil {
@@ -438,6 +764,10 @@ func handleRemoteFolderAddOrReplace(ctx *cli.Context, ctl *client.Client, replac
 					if ctx.IsSet("conflict-strategy") {
 						remote.Folders[idx].ConflictStrategy = conflictStrategy
 					}
+
+					if ctx.IsSet("lock-policy") {
+						remote.Folders[idx].LockPolicy = lockPolicy
+					}
 				}
 
 				folderFound = true
@@ -458,6 +788,7 @@ func handleRemoteFolderAddOrReplace(ctx *cli.Context, ctl *client.Client, replac
 				Folder:           folder,
 				ReadOnly:         isReadOnly,
 				ConflictStrategy: conflictStrategy,
+				LockPolicy:       lockPolicy,
 			})
 		}
 	}
@@ -507,15 +838,16 @@ func handleRemoteFolderList(ctx *cli.Context, ctl *client.Client) error {
 	}
 
 	tabW := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.StripEscape)
-	fmt.Fprintln(tabW, "FOLDER\tREAD ONLY\tCONFLICT STRATEGY\t")
+	fmt.Fprintln(tabW, "FOLDER\tREAD ONLY\tCONFLICT STRATEGY\tLOCK POLICY\t")
 
 	for _, folder := range remote.Folders {
 		fmt.Fprintf(
 			tabW,
-			"%s\t%s\t%s\t\n",
+			"%s\t%s\t%s\t%s\t\n",
 			folder.Folder,
 			yesOrNo(folder.ReadOnly),
 			folder.ConflictStrategy,
+			folder.LockPolicy,
 		)
 	}
 
@@ -529,17 +861,18 @@ func handleRemoteFolderListAll(ctx *cli.Context, ctl *client.Client) error {
 	}
 
 	tabW := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.StripEscape)
-	fmt.Fprintln(tabW, "REMOTE\tFOLDER\tREAD ONLY\tCONFLICT STRATEGY\t")
+	fmt.Fprintln(tabW, "REMOTE\tFOLDER\tREAD ONLY\tCONFLICT STRATEGY\tLOCK POLICY\t")
 
 	for _, remote := range remotes {
 		for _, folder := range remote.Folders {
 			fmt.Fprintf(
 				tabW,
-				"%s\t%s\t%s\t%s\t\n",
+				"%s\t%s\t%s\t%s\t%s\t\n",
 				remote.Name,
 				folder.Folder,
 				yesOrNo(folder.ReadOnly),
 				folder.ConflictStrategy,
+				folder.LockPolicy,
 			)
 		}
 	}
@@ -622,14 +955,86 @@ func handleRemotePing(ctx *cli.Context, ctl *client.Client) error {
 
 func handlePin(ctx *cli.Context, ctl *client.Client) error {
 	path := ctx.Args().First()
-	return ctl.Pin(path)
+	err := withJobProgress(ctl, "pin", path, func() error {
+		return ctl.Pin(path)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool("offline") {
+		return ctl.SetOffline(path, true)
+	}
+
+	return nil
 }
 
 func handleUnpin(ctx *cli.Context, ctl *client.Client) error {
 	path := ctx.Args().First()
+	if ctx.Bool("offline") {
+		if err := ctl.SetOffline(path, false); err != nil {
+			return err
+		}
+	}
+
 	return ctl.Unpin(path)
 }
 
+func printPrefetchStatus(status *client.PrefetchStatus) {
+	if status.Running {
+		fmt.Printf(
+			"Fetching %d/%d files (%s/%s)",
+			status.DoneFiles,
+			status.TotalFiles,
+			humanize.Bytes(status.DoneBytes),
+			humanize.Bytes(status.TotalBytes),
+		)
+
+		if status.Current != "" {
+			fmt.Printf(": %s", status.Current)
+		}
+
+		fmt.Println()
+	} else {
+		fmt.Println("All offline files are available.")
+	}
+
+	if status.LastError != "" {
+		fmt.Printf("Last error: %s\n", color.RedString(status.LastError))
+	}
+}
+
+func handlePinOffline(ctx *cli.Context, ctl *client.Client) error {
+	status, err := ctl.PrefetchStatus()
+	if err != nil {
+		return err
+	}
+
+	if len(status.Paths) == 0 {
+		fmt.Println("Nothing is marked as offline available.")
+		return nil
+	}
+
+	for _, path := range status.Paths {
+		fmt.Println(color.GreenString(path))
+	}
+
+	fmt.Println()
+
+	for ctx.Bool("wait") && status.Running {
+		printPrefetchStatus(status)
+		time.Sleep(1 * time.Second)
+
+		if status, err = ctl.PrefetchStatus(); err != nil {
+			return err
+		}
+	}
+
+	printPrefetchStatus(status)
+	return nil
+}
+
 func handleRepin(ctx *cli.Context, ctl *client.Client) error {
 	root := "/"
 	if len(ctx.Args()) > 0 {
@@ -639,6 +1044,45 @@ func handleRepin(ctx *cli.Context, ctl *client.Client) error {
 	return ctl.Repin(root)
 }
 
+func rotateKey(ctl *client.Client) error {
+	fingerprint, notReached, err := ctl.RotateKey()
+	if err != nil {
+		return fmt.Errorf("rotate key: %v", err)
+	}
+
+	fmt.Printf("Your new fingerprint is %s\n", color.GreenString(fingerprint))
+	printNotReached(notReached)
+	return nil
+}
+
+// printWhoamiQR encodes our name and fingerprint as QR code, in the form
+// that »brig remote add« expects its arguments.
+func printWhoamiQR(ctx *cli.Context, self *client.Whoami) error {
+	payload := fmt.Sprintf("%s %s", self.Owner, self.Fingerprint)
+	code, err := qrcode.Encode([]byte(payload), qrcode.LevelM)
+	if err != nil {
+		return err
+	}
+
+	if pngPath := ctx.String("qr-png"); pngPath != "" {
+		fd, err := os.OpenFile(pngPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+
+		if err := code.WritePNG(fd, 8); err != nil {
+			fd.Close()
+			return err
+		}
+
+		return fd.Close()
+	}
+
+	fmt.Print(code.Terminal(ctx.Bool("qr-invert")))
+	fmt.Println(payload)
+	return nil
+}
+
 func handleWhoami(ctx *cli.Context, ctl *client.Client) error {
 	self, err := ctl.Whoami()
 	if err != nil {
@@ -652,6 +1096,14 @@ func handleWhoami(ctx *cli.Context, ctl *client.Client) error {
 	printAddr := ctx.Bool("addr")
 	printKey := ctx.Bool("key")
 
+	if ctx.Bool("rotate-key") {
+		return rotateKey(ctl)
+	}
+
+	if ctx.Bool("qr") || ctx.String("qr-png") != "" {
+		return printWhoamiQR(ctx, self)
+	}
+
 	userName := color.YellowString(self.CurrentUser)
 	ownerName := color.GreenString(self.Owner)
 
@@ -146,7 +146,7 @@ func RunCmdline(args []string) int {
 		},
 		cli.StringFlag{
 			Name:   "repo",
-			Usage:  "Path to the repository. Only has effect for new daemons.",
+			Usage:  "Path or name of the repository. Selects it if the daemon serves several.",
 			Value:  ".",
 			EnvVar: "BRIG_PATH",
 		},
@@ -212,6 +212,22 @@ func RunCmdline(args []string) int {
 					Name:    "conflict-strategy",
 					Aliases: []string{"cs"},
 					Action:  withArgCheck(needAtLeast(2), withDaemon(handleRemoteConflictStrategy, true)),
+				}, {
+					Name:    "bandwidth",
+					Aliases: []string{"bw"},
+					Action:  withArgCheck(needAtLeast(2), withDaemon(handleRemoteBandwidth, true)),
+				}, {
+					Name:   "invite",
+					Action: withDaemon(handleRemoteInvite, true),
+				}, {
+					Name:   "accept",
+					Action: withArgCheck(needAtLeast(1), withDaemon(handleRemoteAccept, true)),
+				}, {
+					Name:   "revoke",
+					Action: withArgCheck(needAtLeast(1), withDaemon(handleRemoteRevoke, true)),
+				}, {
+					Name:   "revocations",
+					Action: withDaemon(handleRemoteRevocations, true),
 				}, {
 					Name:    "folder",
 					Aliases: []string{"fld", "f"},
@@ -240,6 +256,27 @@ func RunCmdline(args []string) int {
 					},
 				},
 			},
+		}, {
+			Name:     "device",
+			Aliases:  []string{"dev"},
+			Category: netwGroup,
+			Action:   withDaemon(handleDeviceList, true),
+			Subcommands: []cli.Command{
+				{
+					Name:   "link",
+					Action: withDaemon(handleDeviceLink, true),
+				}, {
+					Name:   "accept",
+					Action: withArgCheck(needAtLeast(1), withDaemon(handleRemoteAccept, true)),
+				}, {
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Action:  withDaemon(handleDeviceList, true),
+				}, {
+					Name:   "unlink",
+					Action: withArgCheck(needAtLeast(1), withDaemon(handleDeviceUnlink, true)),
+				},
+			},
 		}, {
 			Name:     "pin",
 			Category: vcscGroup,
@@ -255,6 +292,9 @@ func RunCmdline(args []string) int {
 					Name:    "remove",
 					Aliases: []string{"rm"},
 					Action:  withArgCheck(needAtLeast(1), withDaemon(handleUnpin, true)),
+				}, {
+					Name:   "offline",
+					Action: withDaemon(handlePinOffline, true),
 				},
 			},
 		}, {
@@ -324,6 +364,14 @@ func RunCmdline(args []string) int {
 			Aliases:  []string{"hst", "hist"},
 			Category: vcscGroup,
 			Action:   withArgCheck(needAtLeast(1), withDaemon(handleHistory, true)),
+		}, {
+			Name:     "import-git",
+			Category: vcscGroup,
+			Action:   withArgCheck(needAtLeast(1), withDaemon(handleImportGit, true)),
+		}, {
+			Name:     "export-git",
+			Category: vcscGroup,
+			Action:   withArgCheck(needAtLeast(1), withDaemon(handleExportGit, true)),
 		}, {
 			Name:     "stage",
 			Aliases:  []string{"stg", "add", "a"},
@@ -372,6 +420,39 @@ func RunCmdline(args []string) int {
 			Name:     "edit",
 			Category: wdirGroup,
 			Action:   withArgCheck(needAtLeast(1), withDaemon(handleEdit, true)),
+		}, {
+			Name:     "lock",
+			Category: wdirGroup,
+			Action:   withArgCheck(needAtLeast(1), withDaemon(handleLock, true)),
+		}, {
+			Name:     "unlock",
+			Category: wdirGroup,
+			Action:   withArgCheck(needAtLeast(1), withDaemon(handleUnlock, true)),
+		}, {
+			Name:     "locks",
+			Category: wdirGroup,
+			Action:   withDaemon(handleLocks, true),
+		}, {
+			Name:     "attr",
+			Category: wdirGroup,
+			Action:   withArgCheck(needAtLeast(1), withDaemon(handleAttrList, true)),
+			Subcommands: []cli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Action:  withArgCheck(needAtLeast(1), withDaemon(handleAttrList, true)),
+				}, {
+					Name:   "get",
+					Action: withArgCheck(needAtLeast(2), withDaemon(handleAttrGet, true)),
+				}, {
+					Name:   "set",
+					Action: withArgCheck(needAtLeast(2), withDaemon(handleAttrSet, true)),
+				}, {
+					Name:    "remove",
+					Aliases: []string{"rm"},
+					Action:  withArgCheck(needAtLeast(2), withDaemon(handleAttrRemove, true)),
+				},
+			},
 		}, {
 			Name:     "daemon",
 			Category: repoGroup,
@@ -385,6 +466,9 @@ func RunCmdline(args []string) int {
 				}, {
 					Name:   "ping",
 					Action: withDaemon(handleDaemonPing, false),
+				}, {
+					Name:   "repos",
+					Action: withDaemon(handleDaemonRepos, false),
 				},
 			},
 		}, {
@@ -445,6 +529,10 @@ func RunCmdline(args []string) int {
 					Aliases: []string{"rm"},
 					Action:  withArgCheck(needAtLeast(1), withDaemon(handleTrashRemove, true)),
 				},
+				{
+					Name:   "purge",
+					Action: withDaemon(handleTrashPurge, true),
+				},
 			},
 		}, {
 			Name:     "hints",
@@ -468,8 +556,19 @@ func RunCmdline(args []string) int {
 					Name:    "recode",
 					Aliases: []string{"r"},
 					Action:  withDaemon(handleRepoHintsRecode, true),
+				}, {
+					Name:   "train-dict",
+					Action: withArgCheck(needAtLeast(1), withDaemon(handleRepoHintsTrainDict, true)),
 				},
 			},
+		}, {
+			Name:     "rekey",
+			Category: repoGroup,
+			Action:   withArgCheck(needAtLeast(1), withDaemon(handleRekey, true)),
+		}, {
+			Name:     "jobs",
+			Category: repoGroup,
+			Action:   withDaemon(handleJobs, true),
 		}, {
 			Name:     "gateway",
 			Aliases:  []string{"gw"},
@@ -569,6 +668,37 @@ func RunCmdline(args []string) int {
 			Name:     "gc",
 			Category: repoGroup,
 			Action:   withDaemon(handleGc, true),
+		}, {
+			Name:     "repo",
+			Category: repoGroup,
+			Subcommands: []cli.Command{
+				{
+					Name:   "migrate-db",
+					Action: handleRepoMigrateDB,
+				}, {
+					Name:   "rehash",
+					Action: handleRepoRehash,
+				},
+			},
+		}, {
+			Name:     "backup",
+			Category: repoGroup,
+			Subcommands: []cli.Command{
+				{
+					Name:   "create",
+					Action: withArgCheck(needAtLeast(1), handleBackupCreate),
+				}, {
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Action:  withArgCheck(needAtLeast(1), handleBackupList),
+				}, {
+					Name:   "verify",
+					Action: withArgCheck(needAtLeast(1), handleBackupVerify),
+				}, {
+					Name:   "restore",
+					Action: withArgCheck(needAtLeast(1), handleBackupRestore),
+				},
+			},
 		}, {
 			Name:     "pack-repo",
 			Category: repoGroup,
@@ -15,16 +15,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
+	isatty "github.com/mattn/go-isatty"
 	e "github.com/pkg/errors"
+	"github.com/sahib/brig/backend"
+	"github.com/sahib/brig/catfs/db"
 	"github.com/sahib/brig/client"
 	"github.com/sahib/brig/cmd/pwd"
 	"github.com/sahib/brig/cmd/tabwriter"
+	"github.com/sahib/brig/net/peer"
 	"github.com/sahib/brig/repo"
+	"github.com/sahib/brig/repo/backup"
 	"github.com/sahib/brig/repo/repopack"
 	"github.com/sahib/brig/repo/setup"
 	"github.com/sahib/brig/server"
 	"github.com/sahib/brig/util"
+	h "github.com/sahib/brig/util/hashlib"
 	formatter "github.com/sahib/brig/util/log"
 	"github.com/sahib/brig/version"
 	log "github.com/sirupsen/logrus"
@@ -185,10 +192,11 @@ func handleInit(ctx *cli.Context) error {
 		ctx,
 		ipfsPathOrMultiaddr,
 		repo.InitOptions{
-			BaseFolder:  folder,
-			Owner:       owner,
-			BackendName: backend,
-			DaemonURL:   daemonURL,
+			BaseFolder:    folder,
+			Owner:         owner,
+			BackendName:   backend,
+			DaemonURL:     daemonURL,
+			HashAlgorithm: ctx.String("hash-algorithm"),
 		},
 	); err != nil {
 		return ExitCode{UnknownError, fmt.Sprintf("init failed: %v", err)}
@@ -348,6 +356,50 @@ func handleDaemonQuit(ctx *cli.Context, ctl *client.Client) error {
 	return nil
 }
 
+func handleDaemonRepos(ctx *cli.Context, ctl *client.Client) error {
+	repos, err := ctl.DaemonRepos()
+	if err != nil {
+		return ExitCode{UnknownError, fmt.Sprintf("daemon repos: %v", err)}
+	}
+
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	fmt.Fprintln(tabW, "NAME\tOWNER\tPATH\tDEFAULT\t")
+
+	for _, repo := range repos {
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t%s\t\n",
+			color.WhiteString(repo.Name),
+			color.CyanString(repo.Owner),
+			repo.Path,
+			yesify(repo.IsDefault),
+		)
+	}
+
+	return tabW.Flush()
+}
+
+// parseRepoSpecs parses the `[NAME=]PATH` arguments of `brig daemon launch --add-repo`.
+func parseRepoSpecs(args []string) []server.RepoSpec {
+	specs := []server.RepoSpec{}
+	for _, arg := range args {
+		spec := server.RepoSpec{Path: arg}
+		if idx := strings.Index(arg, "="); idx > 0 {
+			spec.Name = arg[:idx]
+			spec.Path = arg[idx+1:]
+		}
+
+		spec.Path = mustAbsPath(spec.Path)
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
 func switchToSyslog() {
 	wSyslog, err := syslog.New(syslog.LOG_NOTICE, "brig")
 	if err != nil {
@@ -436,7 +488,8 @@ func handleDaemonLaunch(ctx *cli.Context) error {
 		log.SetOutput(os.Stdout)
 	}
 
-	server, err := server.BootServer(repoPath, daemonURL)
+	extraRepos := parseRepoSpecs(ctx.StringSlice("add-repo"))
+	server, err := server.BootServer(repoPath, daemonURL, extraRepos...)
 	if err != nil {
 		return ExitCode{
 			UnknownError,
@@ -476,9 +529,10 @@ func handleMount(ctx *cli.Context, ctl *client.Client) error {
 	}
 
 	options := client.MountOptions{
-		ReadOnly: ctx.Bool("readonly"),
-		Offline:  ctx.Bool("offline"),
-		RootPath: ctx.String("root"),
+		ReadOnly:        ctx.Bool("readonly"),
+		Offline:         ctx.Bool("offline"),
+		FlagUnavailable: ctx.Bool("flag-unavailable"),
+		RootPath:        ctx.String("root"),
 	}
 
 	if err := ctl.Mount(absMountPath, options); err != nil {
@@ -566,9 +620,10 @@ func handleFstabAdd(ctx *cli.Context, ctl *client.Client) error {
 	mountPath := ctx.Args().Get(1)
 
 	options := client.MountOptions{
-		ReadOnly: ctx.Bool("readonly"),
-		RootPath: ctx.String("root"),
-		Offline:  ctx.Bool("offline"),
+		ReadOnly:        ctx.Bool("readonly"),
+		RootPath:        ctx.String("root"),
+		Offline:         ctx.Bool("offline"),
+		FlagUnavailable: ctx.Bool("flag-unavailable"),
 	}
 
 	return ctl.FstabAdd(mountName, mountPath, options)
@@ -612,7 +667,7 @@ func handleFstabList(ctx *cli.Context, ctl *client.Client) error {
 	}
 
 	if tmpl == nil && len(mounts) != 0 {
-		fmt.Fprintln(tabW, "NAME\tPATH\tREAD_ONLY\tOFFLINE\tROOT\tACTIVE\t")
+		fmt.Fprintln(tabW, "NAME\tPATH\tREAD_ONLY\tOFFLINE\tFLAG_UNAVAILABLE\tROOT\tACTIVE\t")
 	}
 
 	for _, entry := range mounts {
@@ -626,11 +681,12 @@ func handleFstabList(ctx *cli.Context, ctl *client.Client) error {
 
 		fmt.Fprintf(
 			tabW,
-			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			entry.Name,
 			entry.Path,
 			yesify(entry.ReadOnly),
 			yesify(entry.Offline),
+			yesify(entry.FlagUnavailable),
 			entry.Root,
 			checkmarkify(entry.Active),
 		)
@@ -988,6 +1044,295 @@ func handleRepoUnpack(ctx *cli.Context) error {
 	)
 }
 
+func handleRepoMigrateDB(ctx *cli.Context) error {
+	folder, err := guessRepoFolder(ctx)
+	if err != nil {
+		return err
+	}
+
+	isRunning, err := isDaemonRunning(ctx)
+	if err != nil {
+		return e.Wrap(err, "failed to check if daemon is running")
+	}
+
+	if isRunning {
+		log.Error("daemon is still running for this repo, please quit it first!")
+		log.Errorf("Use »brig --repo %s daemon quit« for this.", folder)
+		return errors.New("refusing to migrate the database while it is in use")
+	}
+
+	switch {
+	case ctx.Bool("confirm"):
+		return repo.ConfirmDatabaseMigration(folder)
+	case ctx.Bool("rollback"):
+		return repo.RollbackDatabaseMigration(folder)
+	}
+
+	dbType := ctx.String("to")
+	if dbType != db.TypeBadger && dbType != db.TypeDisk {
+		return fmt.Errorf("please specify a valid type with --to (badger or disk)")
+	}
+
+	allStats, err := repo.MigrateDatabases(folder, dbType)
+	if err != nil {
+		return err
+	}
+
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	fmt.Fprintln(tabW, "OWNER\tFROM\tTO\tKEYS\tCHECKSUM\t")
+	for owner, stats := range allStats {
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t%d\t%s\t\n",
+			owner,
+			stats.SrcType,
+			stats.DstType,
+			stats.Keys,
+			stats.Checksum,
+		)
+	}
+
+	if err := tabW.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("The old metadata was kept. If everything works as expected use")
+	fmt.Println("»brig repo migrate-db --confirm« to delete it or »--rollback« to restore it.")
+	return nil
+}
+
+func handleRepoRehash(ctx *cli.Context) error {
+	folder, err := guessRepoFolder(ctx)
+	if err != nil {
+		return err
+	}
+
+	isRunning, err := isDaemonRunning(ctx)
+	if err != nil {
+		return e.Wrap(err, "failed to check if daemon is running")
+	}
+
+	if isRunning {
+		log.Error("daemon is still running for this repo, please quit it first!")
+		log.Errorf("Use »brig --repo %s daemon quit« for this.", folder)
+		return errors.New("refusing to rehash the metadata while it is in use")
+	}
+
+	switch {
+	case ctx.Bool("confirm"):
+		return repo.ConfirmDatabaseMigration(folder)
+	case ctx.Bool("rollback"):
+		return repo.RollbackDatabaseMigration(folder)
+	}
+
+	algo, err := h.ParseAlgorithm(ctx.String("to"))
+	if err != nil {
+		return fmt.Errorf(
+			"please specify a valid algorithm with --to (%s)",
+			strings.Join(h.Algorithms(), ", "),
+		)
+	}
+
+	rp, err := repo.Open(folder)
+	if err != nil {
+		return err
+	}
+
+	kr, err := rp.Keyring()
+	if err != nil {
+		return err
+	}
+
+	pubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return err
+	}
+
+	// The content has to be read to compute the new hashes:
+	bk, err := backend.FromName(
+		rp.Immutables.Backend(),
+		rp.Config.String("daemon.ipfs_path_or_url"),
+		peer.BuildFingerprint("", pubKey).PubKeyID(),
+	)
+
+	if err != nil {
+		return e.Wrap(err, "failed to load backend")
+	}
+
+	stats, err := rp.Rehash(bk, algo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Rehashed %d nodes and %d files from %s to %s.\n",
+		stats.Nodes, stats.Files, stats.SrcAlgorithm, stats.DstAlgorithm,
+	)
+
+	if stats.Unreadable > 0 {
+		fmt.Printf(
+			"%s: %d files could not be read; their hashes were derived from the old ones.\n",
+			color.YellowString("WARNING"),
+			stats.Unreadable,
+		)
+	}
+
+	fmt.Println()
+	fmt.Println("Remotes need to use the same algorithm to sync with you.")
+	fmt.Println("The old metadata was kept. If everything works as expected use")
+	fmt.Println("»brig repo rehash --confirm« to delete it or »--rollback« to restore it.")
+	return nil
+}
+
+func handleBackupCreate(ctx *cli.Context) error {
+	folder, err := guessRepoFolder(ctx)
+	if err != nil {
+		return err
+	}
+
+	isRunning, err := isDaemonRunning(ctx)
+	if err != nil {
+		return e.Wrap(err, "failed to check if daemon is running")
+	}
+
+	if isRunning {
+		log.Error("daemon is still running for this repo, please quit it first!")
+		log.Errorf("Use »brig --repo %s daemon quit« for this.", folder)
+		return errors.New("refusing to backup data, there might be inconsistencies")
+	}
+
+	backupDir := ctx.Args().First()
+	infos, err := backup.List(backupDir)
+	if err != nil {
+		return err
+	}
+
+	// Only ask for a new password on the first backup:
+	pass, err := readPassword(ctx, len(infos) == 0)
+	if err != nil {
+		return err
+	}
+
+	info, err := backup.Create(folder, backupDir, string(pass), ctx.Bool("full"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Wrote backup #%d to »%s« (%s, %d changed, %d removed keys)\n",
+		info.Index,
+		info.Path,
+		humanize.Bytes(uint64(info.Size)),
+		info.Changed,
+		info.Removed,
+	)
+
+	return nil
+}
+
+func handleBackupList(ctx *cli.Context) error {
+	infos, err := backup.List(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No backups yet. Create one with »brig backup create <dir>«")
+		return nil
+	}
+
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	fmt.Fprintln(tabW, "INDEX\tCREATED\tTYPE\tCHANGED\tREMOVED\tSIZE\t")
+	for _, info := range infos {
+		typ := "incremental"
+		if info.Full {
+			typ = "full"
+		}
+
+		fmt.Fprintf(
+			tabW,
+			"%d\t%s\t%s\t%d\t%d\t%s\t\n",
+			info.Index,
+			info.CreatedAt.Format(time.Stamp),
+			typ,
+			info.Changed,
+			info.Removed,
+			humanize.Bytes(uint64(info.Size)),
+		)
+	}
+
+	return tabW.Flush()
+}
+
+func handleBackupVerify(ctx *cli.Context) error {
+	pass, err := readPassword(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	results, err := backup.Verify(ctx.Args().First(), string(pass))
+	if err != nil {
+		return err
+	}
+
+	tabW := tabwriter.NewWriter(
+		os.Stdout, 0, 0, 2, ' ',
+		tabwriter.StripEscape,
+	)
+
+	fmt.Fprintln(tabW, "INDEX\tSTATUS\t")
+
+	numBroken := 0
+	for _, result := range results {
+		status := color.GreenString("ok")
+		if result.Err != nil {
+			status = color.RedString(result.Err.Error())
+			numBroken++
+		}
+
+		fmt.Fprintf(tabW, "%d\t%s\t\n", result.Info.Index, status)
+	}
+
+	if err := tabW.Flush(); err != nil {
+		return err
+	}
+
+	if numBroken > 0 {
+		return fmt.Errorf("%d of %d backups are broken", numBroken, len(results))
+	}
+
+	return nil
+}
+
+func handleBackupRestore(ctx *cli.Context) error {
+	if !ctx.GlobalIsSet("repo") {
+		return fmt.Errorf("please specify where to restore to with »--repo«")
+	}
+
+	folder := ctx.GlobalString("repo")
+	pass, err := readPassword(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("restoring to »%s«", folder)
+	return backup.Restore(
+		ctx.Args().First(),
+		folder,
+		string(pass),
+		ctx.Int("index"),
+	)
+}
+
 func optionalStringParamAsPtr(ctx *cli.Context, name string) *string {
 	if v := ctx.String(name); v != "" {
 		return &v
@@ -1007,16 +1352,17 @@ func handleRepoHintsSet(ctx *cli.Context, ctl *client.Client) error {
 
 	zipHint := optionalStringParamAsPtr(ctx, "compression")
 	encHint := optionalStringParamAsPtr(ctx, "encryption")
+	keyDomain := optionalStringParamAsPtr(ctx, "key-domain")
 
 	// TODO: There seems to be a bug in the cli library.
 	// When --recode comes directly after 'set' then
 	// all other arguments are part of 'ctx.Args()' and do not get
 	// parsed. This check at least catches this behavior.
-	if zipHint == nil && encHint == nil {
-		return fmt.Errorf("need at least one of --encryption or --compression")
+	if zipHint == nil && encHint == nil && keyDomain == nil {
+		return fmt.Errorf("need at least one of --encryption, --compression or --key-domain")
 	}
 
-	if err := ctl.HintSet(path, zipHint, encHint); err != nil {
+	if err := ctl.HintSet(path, zipHint, encHint, keyDomain); err != nil {
 		return err
 	}
 
@@ -1042,15 +1388,27 @@ func handleRepoHintsList(ctx *cli.Context, ctl *client.Client) error {
 		tabwriter.StripEscape,
 	)
 
-	fmt.Fprintln(tabW, "PATH\tENCRYPTION\tCOMPRESSION\t")
+	fmt.Fprintln(tabW, "PATH\tENCRYPTION\tCOMPRESSION\tDICT\tKEY DOMAIN\t")
 
 	for _, hint := range hints {
+		zstdDict := "-"
+		if hint.ZstdDict != "" {
+			zstdDict = shortDictID(hint.ZstdDict)
+		}
+
+		keyDomain := "-"
+		if hint.KeyDomain != "" {
+			keyDomain = hint.KeyDomain
+		}
+
 		fmt.Fprintf(
 			tabW,
-			"%s\t%s\t%s\t\n",
+			"%s\t%s\t%s\t%s\t%s\t\n",
 			hint.Path,
 			hint.EncryptionAlgo,
 			hint.CompressionAlgo,
+			zstdDict,
+			keyDomain,
 		)
 	}
 
@@ -1061,6 +1419,57 @@ func handleRepoHintsRemove(ctx *cli.Context, ctl *client.Client) error {
 	return ctl.HintRemove(ctx.Args().First())
 }
 
+func shortDictID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+
+	return id
+}
+
+func handleRepoHintsTrainDict(ctx *cli.Context, ctl *client.Client) error {
+	folder := ctx.Args().First()
+
+	maxSize, err := humanize.ParseBytes(ctx.String("max-size"))
+	if err != nil {
+		return err
+	}
+
+	id, size, err := ctl.HintTrainDict(folder, int64(maxSize))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"trained dictionary %s (%s) for %s\n",
+		shortDictID(id),
+		humanize.Bytes(uint64(size)),
+		folder,
+	)
+
+	if ctx.Bool("recode") {
+		return recodeAndReport(ctl, folder)
+	}
+
+	return nil
+}
+
+// recodeAndReport recodes all files below `path`
+// and tells the user how well they compress now.
+func recodeAndReport(ctl *client.Client, path string) error {
+	if err := ctl.RecodeStream(path); err != nil {
+		return err
+	}
+
+	stats, err := ctl.CompressionStats(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %s\n", path, formatCompressionStats(stats))
+	return nil
+}
+
 func handleRepoHintsRecode(ctx *cli.Context, ctl *client.Client) error {
 	repoPath := ctx.Args().Get(0)
 	if repoPath == "" {
@@ -1068,5 +1477,194 @@ func handleRepoHintsRecode(ctx *cli.Context, ctl *client.Client) error {
 
 	}
 
-	return ctl.RecodeStream(repoPath)
+	return recodeAndReport(ctl, repoPath)
+}
+
+func handleRekey(ctx *cli.Context, ctl *client.Client) error {
+	path := ctx.Args().First()
+
+	// Rekeying might take a while; show the progress in the meantime:
+	progressTicker := time.NewTicker(500 * time.Millisecond)
+	go func() {
+		for range progressTicker.C {
+			status, err := ctl.RekeyStatus()
+			if err != nil || !status.Running {
+				continue
+			}
+
+			fmt.Printf("rekeyed %d/%d files\r", status.DoneFiles+status.FailedFiles, status.TotalFiles)
+		}
+	}()
+
+	status, err := ctl.Rekey(path)
+	progressTicker.Stop()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"rekeyed %d/%d files in %s\n",
+		status.DoneFiles,
+		status.TotalFiles,
+		strings.Join(status.Folders, ", "),
+	)
+
+	if len(status.Remotes) == 0 {
+		fmt.Println("No remote receives the new keys.")
+	} else {
+		fmt.Printf("The new keys are synced to: %s\n", strings.Join(status.Remotes, ", "))
+	}
+
+	if status.FailedFiles > 0 {
+		return fmt.Errorf(
+			"failed to rekey %d files (last error: %s)",
+			status.FailedFiles,
+			color.RedString(status.LastError),
+		)
+	}
+
+	return nil
+}
+
+// progressBar renders `done` of `total` as bar of `width` characters.
+func progressBar(done, total float64, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(float64(width) * done / total)
+	}
+
+	if filled > width {
+		filled = width
+	}
+
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+// formatJobProgress gives a one line summary of how far `job` is.
+func formatJobProgress(job client.Job) string {
+	done, total := float64(job.DoneBytes), float64(job.TotalBytes)
+	if total == 0 {
+		done, total = float64(job.DoneFiles), float64(job.TotalFiles)
+	}
+
+	parts := []string{}
+	if total > 0 {
+		parts = append(parts, fmt.Sprintf("%s %3.0f%%", progressBar(done, total, 20), 100*done/total))
+	}
+
+	if job.TotalFiles > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d files", job.DoneFiles, job.TotalFiles))
+	}
+
+	if job.TotalBytes > 0 {
+		parts = append(parts, fmt.Sprintf(
+			"%s/%s",
+			humanize.Bytes(job.DoneBytes),
+			humanize.Bytes(job.TotalBytes),
+		))
+	}
+
+	if job.ETA >= 0 {
+		parts = append(parts, "ETA "+job.ETA.Round(time.Second).String())
+	}
+
+	if len(parts) == 0 {
+		return "running for " + time.Since(job.Started).Round(time.Second).String()
+	}
+
+	return strings.Join(parts, "  ")
+}
+
+// withJobProgress runs `fn` and shows the progress of the daemon job
+// with `kind` and `subject` on stderr while it runs. Nothing is shown
+// if stderr is not a terminal, so the output can be piped safely.
+func withJobProgress(ctl *client.Client, kind, subject string, fn func() error) error {
+	if !isatty.IsTerminal(os.Stderr.Fd()) {
+		return fn()
+	}
+
+	done := make(chan struct{})
+	drawn := make(chan bool)
+
+	go func() {
+		wasDrawn := false
+		defer func() { drawn <- wasDrawn }()
+
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			jobs, err := ctl.JobList()
+			if err != nil {
+				continue
+			}
+
+			for _, job := range jobs {
+				if job.Kind != kind || job.Subject != subject {
+					continue
+				}
+
+				line := formatJobProgress(job)
+				if job.Current != "" {
+					line += "  " + job.Current
+				}
+
+				// Clear the line first, the previous one might be longer:
+				fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+				wasDrawn = true
+				break
+			}
+		}
+	}()
+
+	err := fn()
+	close(done)
+	if <-drawn {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+
+	return err
+}
+
+func handleJobs(ctx *cli.Context, ctl *client.Client) error {
+	for {
+		jobs, err := ctl.JobList()
+		if err != nil {
+			return err
+		}
+
+		if ctx.Bool("watch") {
+			// Clear the screen and start at the top:
+			fmt.Print("\033[H\033[2J")
+		}
+
+		if len(jobs) == 0 {
+			fmt.Println("No jobs are running.")
+		}
+
+		for _, job := range jobs {
+			fmt.Printf(
+				"%s %s: %s\n",
+				color.CyanString(job.Kind),
+				color.GreenString(job.Subject),
+				formatJobProgress(job),
+			)
+
+			if job.Current != "" {
+				fmt.Printf("  %s\n", job.Current)
+			}
+		}
+
+		if !ctx.Bool("watch") {
+			return nil
+		}
+
+		time.Sleep(time.Second)
+	}
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -12,10 +13,12 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
 	"github.com/sahib/brig/client"
@@ -277,6 +280,10 @@ func withDaemon(handler cmdHandlerWithClient, startNew bool) cli.ActionFunc {
 		ctl, err := client.Dial(context.Background(), daemonURL)
 		if err == nil {
 			defer ctl.Close()
+			if err := selectRepo(ctx, ctl); err != nil {
+				return err
+			}
+
 			return handler(ctx, ctl)
 		}
 
@@ -310,6 +317,29 @@ func withDaemon(handler cmdHandlerWithClient, startNew bool) cli.ActionFunc {
 	}
 }
 
+// selectRepo tells the daemon which repository we want to operate on, in
+// case the user passed --repo explicitly. A daemon might serve several.
+func selectRepo(ctx *cli.Context, ctl *client.Client) error {
+	if !ctx.GlobalIsSet("repo") {
+		return nil
+	}
+
+	repo := ctx.GlobalString("repo")
+	if info, err := os.Stat(repo); err == nil && info.IsDir() {
+		repo = mustAbsPath(repo)
+	}
+
+	logVerbose(ctx, "Selecting repository '%s'.", repo)
+	if err := ctl.SelectRepo(repo); err != nil {
+		return ExitCode{
+			BadArgs,
+			fmt.Sprintf("could not select repository: %v", prettyPrintError(err)),
+		}
+	}
+
+	return nil
+}
+
 type checkFunc func(ctx *cli.Context) int
 
 func withArgCheck(checker checkFunc, handler cli.ActionFunc) cli.ActionFunc {
@@ -481,10 +511,58 @@ func parseDuration(s string) (float64, error) {
 	return float64(dur) / float64(time.Second), nil
 }
 
+// formatFuncs are available in all --format templates.
+var formatFuncs = template.FuncMap{
+	// attr returns a single attribute: {{ attr .Attrs "project" }}
+	"attr": func(attrs map[string][]byte, key string) string {
+		return string(attrs[key])
+	},
+	// attrs returns all attributes as key=value list: {{ attrs .Attrs }}
+	"attrs": formatAttrs,
+}
+
+func sortedAttrKeys(attrs map[string][]byte) []string {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// formatAttrValue prints `value` as-is if it's printable text, quoted otherwise.
+func formatAttrValue(value []byte) string {
+	if utf8.Valid(value) && isPrintable(string(value)) {
+		return string(value)
+	}
+
+	return strconv.Quote(string(value))
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if !strconv.IsPrint(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func formatAttrs(attrs map[string][]byte) string {
+	parts := []string{}
+	for _, key := range sortedAttrKeys(attrs) {
+		parts = append(parts, key+"="+formatAttrValue(attrs[key]))
+	}
+
+	return strings.Join(parts, ",")
+}
+
 func readFormatTemplate(ctx *cli.Context) (*template.Template, error) {
 	if ctx.IsSet("format") {
 		source := ctx.String("format") + "\n"
-		tmpl, err := template.New("format").Parse(source)
+		tmpl, err := template.New("format").Funcs(formatFuncs).Parse(source)
 
 		if err != nil {
 			return nil, err
@@ -517,6 +595,24 @@ func yesOrNo(v bool) string {
 	return color.RedString("no")
 }
 
+// askYesNo asks `question` on the terminal and returns true
+// if the user answered with yes. Anything else counts as no.
+func askYesNo(question string) (bool, error) {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 type logWriter struct{ prefix string }
 
 func (lw *logWriter) Write(buf []byte) (int, error) {
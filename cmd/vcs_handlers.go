@@ -527,7 +527,13 @@ func handleSyncSingle(ctx *cli.Context, ctl *client.Client, remoteName string) e
 		return nil
 	}
 
-	diff, err := ctl.Sync(remoteName, needFetch)
+	var diff *client.Diff
+	err := withJobProgress(ctl, "sync", remoteName, func() error {
+		var err error
+		diff, err = ctl.Sync(remoteName, needFetch)
+		return err
+	})
+
 	if err != nil {
 		return err
 	}
@@ -704,3 +710,31 @@ func handleLog(ctx *cli.Context, ctl *client.Client) error {
 
 	return nil
 }
+
+func handleImportGit(ctx *cli.Context, ctl *client.Client) error {
+	gitPath, err := filepath.Abs(ctx.Args().First())
+	if err != nil {
+		return ExitCode{BadArgs, fmt.Sprintf("bad path: %v", err)}
+	}
+
+	commits, skipped, err := ctl.ImportGit(gitPath, ctx.String("rev"), ctx.String("prefix"))
+	if err != nil {
+		return ExitCode{UnknownError, fmt.Sprintf("import-git: %v", err)}
+	}
+
+	fmt.Printf("Imported %d commits (%d skipped).\n", commits, skipped)
+	return nil
+}
+
+func handleExportGit(ctx *cli.Context, ctl *client.Client) error {
+	gitPath, err := filepath.Abs(ctx.Args().First())
+	if err != nil {
+		return ExitCode{BadArgs, fmt.Sprintf("bad path: %v", err)}
+	}
+
+	if err := ctl.ExportGit(gitPath, ctx.String("branch")); err != nil {
+		return ExitCode{UnknownError, fmt.Sprintf("export-git: %v", err)}
+	}
+
+	return nil
+}
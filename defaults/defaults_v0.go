@@ -2,9 +2,13 @@ package defaults
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"runtime"
+	"strconv"
 
+	"github.com/dustin/go-humanize"
+	"github.com/sahib/brig/net/bandwidth"
 	"github.com/sahib/config"
 )
 
@@ -35,6 +39,44 @@ func urlValidator(val interface{}) error {
 	return err
 }
 
+func sizeValidator(val interface{}) error {
+	s, ok := val.(string)
+	if !ok {
+		return errors.New("size is not an string")
+	}
+
+	_, err := humanize.ParseBytes(s)
+	return err
+}
+
+func scheduleValidator(val interface{}) error {
+	specs, ok := val.([]string)
+	if !ok {
+		return errors.New("schedule is not a list of strings")
+	}
+
+	_, err := bandwidth.ParseSchedule(specs)
+	return err
+}
+
+func socketModeValidator(val interface{}) error {
+	s, ok := val.(string)
+	if !ok {
+		return errors.New("socket mode is not an string")
+	}
+
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return fmt.Errorf("socket mode is not an octal number: %v", err)
+	}
+
+	if mode > 0777 {
+		return errors.New("socket mode may only contain permission bits")
+	}
+
+	return nil
+}
+
 // DefaultsV0 is the default config validation for brig
 var DefaultsV0 = config.DefaultMapping{
 	"daemon": config.DefaultMapping{
@@ -54,6 +96,22 @@ var DefaultsV0 = config.DefaultMapping{
 			NeedsRestart: true,
 			Docs:         "Enable a ppropf profile server on startup (see »brig d p --help«)",
 		},
+		"socket_mode": config.DefaultEntry{
+			Default:      "0600",
+			NeedsRestart: true,
+			Docs:         "File permissions of the socket, if daemon.url is a non-abstract unix socket.",
+			Validator:    socketModeValidator,
+		},
+		"allowed_uids": config.DefaultEntry{
+			Default:      []int64{},
+			NeedsRestart: false,
+			Docs:         "Other users (by uid) that may use all commands on this repository over a unix socket. The daemon's own user and root may always.",
+		},
+		"readonly_uids": config.DefaultEntry{
+			Default:      []int64{},
+			NeedsRestart: false,
+			Docs:         "Users (by uid) that may only use reading commands (like ls, cat or log) on this repository over a unix socket.",
+		},
 	},
 	"events": config.DefaultMapping{
 		"enabled": config.DefaultEntry{
@@ -82,6 +140,35 @@ var DefaultsV0 = config.DefaultMapping{
 			Docs:         "How many outgoing events per second to send out at max",
 		},
 	},
+	"net": config.DefaultMapping{
+		"accept_remote_revocations": config.DefaultEntry{
+			Default:      true,
+			NeedsRestart: false,
+			Docs:         "Accept revocations of keys that were made by one of our remotes (and not by the key's owner).",
+		},
+		"bandwidth": config.DefaultMapping{
+			"upload": config.DefaultEntry{
+				Default:      "0",
+				NeedsRestart: false,
+				Docs:         "Maximum upload rate per second (e.g. »1MB«) of sync connections to other remotes. Content is served by IPFS and not limited by this. 0 is unlimited.",
+				Validator:    sizeValidator,
+			},
+			"download": config.DefaultEntry{
+				Default:      "0",
+				NeedsRestart: false,
+				Docs:         "Maximum download rate per second (e.g. »1MB«) of content and sync connections. 0 is unlimited.",
+				Validator:    sizeValidator,
+			},
+			"schedule": config.DefaultEntry{
+				Default:      []string{},
+				NeedsRestart: false,
+				Docs: `Time windows with other limits than net.bandwidth.upload/download.
+Each entry looks like »HH:MM-HH:MM <upload>/<download>«, e.g. »22:00-06:00 0/0«
+for no limits at night. The first matching window wins.`,
+				Validator: scheduleValidator,
+			},
+		},
+	},
 	"gateway": config.DefaultMapping{
 		"enabled": config.DefaultEntry{
 			Default:      false,
@@ -161,6 +248,19 @@ var DefaultsV0 = config.DefaultMapping{
   * marker: Create a conflict file with the remote's version.
   * ignore: Ignore the remote version completely and keep our version.
   * embrace: Take the remote version and replace ours with it.
+`,
+			},
+			"lock_policy": config.DefaultEntry{
+				Default:      "warn",
+				NeedsRestart: false,
+				Validator: config.EnumValidator(
+					"warn", "refuse", "ignore",
+				),
+				Docs: `What to do with changes to paths that are locked by another user:
+
+  * warn: Apply the change, but log a warning.
+  * refuse: Do not apply the change and keep our version.
+  * ignore: Apply the change silently.
 `,
 			},
 		},
@@ -183,6 +283,39 @@ var DefaultsV0 = config.DefaultMapping{
 				Docs:         "Compress swapped pages over max_memory before going to disk",
 			},
 		},
+		"readahead": config.DefaultMapping{
+			"enabled": config.DefaultEntry{
+				Default:      true,
+				NeedsRestart: false,
+				Docs: `Prefetch upcoming blocks when a file is read sequentially.
+
+  This helps with streaming big files (e.g. videos) over a mount or the
+  gateway, since several blocks are fetched from the backend in parallel.
+  Prefetched blocks go to the page cache and are dropped once they were
+  read. Random access and commands like cat are not affected.
+`,
+			},
+			"max_window": config.DefaultEntry{
+				Default:      "8M",
+				NeedsRestart: false,
+				Docs:         "How much data to prefetch at max. per open file.",
+				Validator:    sizeValidator,
+			},
+			"workers": config.DefaultEntry{
+				Default:      4,
+				NeedsRestart: false,
+				Docs:         "How many blocks to fetch in parallel per open file.",
+				Validator:    config.IntRangeValidator(1, 128),
+			},
+		},
+		"stage": config.DefaultMapping{
+			"workers": config.DefaultEntry{
+				Default:      8,
+				NeedsRestart: false,
+				Docs:         "How many files to hash, compress and encrypt in parallel when staging many files at once.",
+				Validator:    config.IntRangeValidator(1, 128),
+			},
+		},
 		"repin": config.DefaultMapping{
 			"enabled": config.DefaultEntry{
 				Default:      true,
@@ -241,6 +374,61 @@ var DefaultsV0 = config.DefaultMapping{
 				Validator:    config.DurationValidator(),
 			},
 		},
+		"trash": config.DefaultMapping{
+			"retention": config.DefaultEntry{
+				Default:      "0s",
+				NeedsRestart: false,
+				Docs: `How long to keep the content of deleted files pinned.
+
+  After this time the content of deleted files (and their old versions)
+  gets unpinned and can be reclaimed by the backend's garbage collection.
+  The check is done in the same interval as repinning. A value of 0
+  keeps the content until repinning removes it.
+`,
+				Validator: config.DurationValidator(),
+			},
+		},
+		"snapshots": config.DefaultMapping{
+			"enabled": config.DefaultEntry{
+				Default:      false,
+				NeedsRestart: false,
+				Docs: `Wether to tag HEAD regularly as snapshot.
+
+  Snapshot tags are named after their period and time, for example
+  »snapdaily20200131« or »snapweekly2020w05«. They can be used like
+  any other tag, e.g. with »brig reset« or »brig checkout«.
+`,
+			},
+			"keep_hourly": config.DefaultEntry{
+				Default:      24,
+				NeedsRestart: false,
+				Docs:         "How many hourly snapshots to keep. 0 disables them.",
+				Validator:    config.IntRangeValidator(0, 100000),
+			},
+			"keep_daily": config.DefaultEntry{
+				Default:      7,
+				NeedsRestart: false,
+				Docs:         "How many daily snapshots to keep. 0 disables them.",
+				Validator:    config.IntRangeValidator(0, 100000),
+			},
+			"keep_weekly": config.DefaultEntry{
+				Default:      4,
+				NeedsRestart: false,
+				Docs:         "How many weekly snapshots to keep. 0 disables them.",
+				Validator:    config.IntRangeValidator(0, 100000),
+			},
+			"keep_monthly": config.DefaultEntry{
+				Default:      12,
+				NeedsRestart: false,
+				Docs:         "How many monthly snapshots to keep. 0 disables them.",
+				Validator:    config.IntRangeValidator(0, 100000),
+			},
+			"pin": config.DefaultEntry{
+				Default:      false,
+				NeedsRestart: false,
+				Docs:         "Never unpin file versions that are part of a snapshot during repin.",
+			},
+		},
 	},
 	"repo": config.DefaultMapping{
 		"current_user": config.DefaultEntry{
@@ -280,6 +468,11 @@ var DefaultsV0 = config.DefaultMapping{
 				NeedsRestart: true,
 				Docs:         "Error out on remote files early if set true.",
 			},
+			"flag_unavailable": config.DefaultEntry{
+				Default:      false,
+				NeedsRestart: true,
+				Docs:         "List remote files without permissions and error out early when opening them.",
+			},
 			"root": config.DefaultEntry{
 				Default:      "/",
 				NeedsRestart: true,
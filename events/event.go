@@ -14,6 +14,8 @@ const (
 	FsEvent
 	// NetEvent indicates to other peers that our network status changed.
 	NetEvent
+	// LockEvent tells other remotes that we took or released a lock.
+	LockEvent
 )
 
 // EventType is the type of a
@@ -26,6 +28,8 @@ func (ev EventType) String() string {
 		return "fs"
 	case NetEvent:
 		return "net"
+	case LockEvent:
+		return "lock"
 	default:
 		return "unknown"
 	}
@@ -39,6 +43,8 @@ func EventFromString(ev string) (EventType, error) {
 		return FsEvent, nil
 	case "net":
 		return NetEvent, nil
+	case "lock":
+		return LockEvent, nil
 	default:
 		return UnknownEvent, fmt.Errorf("unknown EventType type: %s", ev)
 	}
@@ -51,7 +51,13 @@ func (dir *Directory) Lookup(ctx context.Context, name string) (fs.Node, error)
 	}
 
 	if name == ".." && dir.path != "/" {
-		return &Directory{path: path.Dir(dir.path), m: dir.m}, nil
+		parentPath := path.Dir(dir.path)
+		return dir.m.rememberNode(parentPath, &Directory{path: parentPath, m: dir.m}), nil
+	}
+
+	// The virtual directory shadows any real node with the same name.
+	if name == virtualDirName && dir.path == dir.m.filesys.root {
+		return &brigDir{m: dir.m}, nil
 	}
 
 	var result fs.Node
@@ -68,7 +74,7 @@ func (dir *Directory) Lookup(ctx context.Context, name string) (fs.Node, error)
 		result = &File{path: childPath, m: dir.m}
 	}
 
-	return result, nil
+	return dir.m.rememberNode(childPath, result), nil
 }
 
 // Mkdir is called to create a new directory node inside the receiver.
@@ -88,7 +94,7 @@ func (dir *Directory) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Nod
 	}
 
 	notifyChange(dir.m, 100*time.Millisecond)
-	return &Directory{path: childPath, m: dir.m}, nil
+	return dir.m.rememberNode(childPath, &Directory{path: childPath, m: dir.m}), nil
 }
 
 // Create is called to create an opened file or directory  as child of the receiver.
@@ -121,7 +127,7 @@ func (dir *Directory) Create(ctx context.Context, req *fuse.CreateRequest, resp
 
 	notifyChange(dir.m, 100*time.Millisecond)
 	file := &File{path: childPath, m: dir.m}
-	return file, &Handle{fd: fd, m: dir.m}, nil
+	return dir.m.rememberNode(childPath, file), &Handle{fd: fd, m: dir.m}, nil
 }
 
 // Remove is called when a direct child in the directory needs to be removed.
@@ -246,7 +252,14 @@ func (dir *Directory) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, r
 func (dir *Directory) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
 	defer logPanic("dir: setxattr")
 
-	return setXattr(dir.m.fs, req.Name, dir.path, req.Xattr)
+	return setXattr(dir.m.fs, req.Name, dir.path, req.Xattr, req.Flags)
+}
+
+// Removexattr is called by the removexattr syscall.
+func (dir *Directory) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
+	defer logPanic("dir: removexattr")
+
+	return removeXattr(dir.m.fs, req.Name, dir.path)
 }
 
 // Listxattr is called to list all xattrs of this directory.
@@ -255,9 +268,22 @@ func (dir *Directory) Listxattr(ctx context.Context, req *fuse.ListxattrRequest,
 
 	// Do not worry about req.Size
 	// fuse will cut it to allowed size and report to the caller that buffer need to be larger
-	resp.Xattr = listXattr()
+	xattrs, err := listXattr(dir.m.fs, dir.path)
+	if err != nil {
+		return err
+	}
+
+	resp.Xattr = xattrs
 	return nil
 }
 
+// Forget is called when the kernel does not need the node anymore.
+func (dir *Directory) Forget() {
+	defer logPanic("dir: forget")
+	dir.m.forgetNode(dir.path, dir)
+}
+
+var _ = fs.NodeForgetter(&Directory{})
 var _ = fs.NodeGetxattrer(&Directory{})
 var _ = fs.NodeListxattrer(&Directory{})
+var _ = fs.NodeRemovexattrer(&Directory{})
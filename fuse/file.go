@@ -42,20 +42,7 @@ func (fi *File) Attr(ctx context.Context, attr *fuse.Attr) error {
 	}
 	debugLog("exec file attr: %v", fi.path)
 
-	var filePerm os.FileMode = 0640
-	attr.Mode = filePerm
-	if fi.m.options.Offline {
-		isCached, err := fi.m.fs.IsCached(fi.path)
-		if err != nil || !isCached {
-			if err != nil {
-				log.Errorf("IsCached failed for %s with error : %v", fi.path, err)
-			}
-			// Uncached file will be shown as symlink
-			// We cannot read them in Offline mode,
-			// but we can delete such link and overwrite its content
-			attr.Mode = os.ModeSymlink | filePerm
-		}
-	}
+	attr.Mode = fi.m.fileMode(fi.path)
 	attr.Size = info.Size
 	attr.Mtime = info.ModTime
 	attr.Inode = info.Inode
@@ -83,15 +70,8 @@ func (fi *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.Open
 	log.Debugf("fuse-file-open: %v with request %v", fi.path, req)
 
 	// Check if the file is actually available locally.
-	if fi.m.options.Offline {
-		isCached, err := fi.m.fs.IsCached(fi.path)
-		if err != nil {
-			return nil, errorize("file-is-cached", err)
-		}
-
-		if !isCached {
-			return nil, errorize("file-not-cached", ErrNotCached)
-		}
+	if err := fi.m.checkAvailable(fi.path); err != nil {
+		return nil, err
 	}
 
 	fd, err := fi.m.fs.Open(fi.path)
@@ -163,7 +143,14 @@ func (fi *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *f
 func (fi *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
 	defer logPanic("file: setxattr")
 
-	return setXattr(fi.m.fs, req.Name, fi.path, req.Xattr)
+	return setXattr(fi.m.fs, req.Name, fi.path, req.Xattr, req.Flags)
+}
+
+// Removexattr is called by the removexattr syscall.
+func (fi *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
+	defer logPanic("file: removexattr")
+
+	return removeXattr(fi.m.fs, req.Name, fi.path)
 }
 
 // Listxattr is called to list all xattrs of this file.
@@ -172,7 +159,12 @@ func (fi *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp
 
 	// Do not worry about req.Size
 	// fuse will cut it to allowed size and report to the caller that buffer need to be larger
-	resp.Xattr = listXattr()
+	xattrs, err := listXattr(fi.m.fs, fi.path)
+	if err != nil {
+		return err
+	}
+
+	resp.Xattr = xattrs
 	return nil
 }
 
@@ -187,9 +179,16 @@ func (fi *File) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string
 	return fmt.Sprintf("/brig/backend/ipfs/%s", info.BackendHash), nil
 }
 
+// Forget is called when the kernel does not need the node anymore.
+func (fi *File) Forget() {
+	defer logPanic("file: forget")
+	fi.m.forgetNode(fi.path, fi)
+}
+
 // Compile time checks to see which interfaces we implement:
 // Please update this list when modifying code here.
 var _ = fs.Node(&File{})
+var _ = fs.NodeForgetter(&File{})
 var _ = fs.NodeFsyncer(&File{})
 var _ = fs.NodeGetxattrer(&File{})
 var _ = fs.NodeListxattrer(&File{})
@@ -197,15 +196,14 @@ var _ = fs.NodeOpener(&File{})
 var _ = fs.NodeSetattrer(&File{})
 var _ = fs.NodeReadlinker(&File{})
 var _ = fs.NodeSetxattrer(&File{})
+var _ = fs.NodeRemovexattrer(&File{})
 
 // Other interfaces are available, but currently not needed or make sense:
 // var _ = fs.NodeRenamer(&File{})
 // var _ = fs.NodeReadlinker(&File{})
 // var _ = fs.NodeRemover(&File{})
-// var _ = fs.NodeRemovexattrer(&File{})
 // var _ = fs.NodeRequestLookuper(&File{})
 // var _ = fs.NodeAccesser(&File{})
-// var _ = fs.NodeForgetter(&File{})
 // var _ = fs.NodeGetattrer(&File{})
 // var _ = fs.NodeLinker(&File{})
 // var _ = fs.NodeMkdirer(&File{})
@@ -27,5 +27,5 @@ type Filesystem struct {
 // This depends on what the user choose to select,
 // but usually it's "/".
 func (fs *Filesystem) Root() (fs.Node, error) {
-	return &Directory{path: fs.root, m: fs.m}, nil
+	return fs.m.rememberNode(fs.root, &Directory{path: fs.root, m: fs.m}), nil
 }
@@ -40,6 +40,10 @@ func FsTabAdd(cfg *config.Config, name, path string, opts MountOptions) error {
 		return err
 	}
 
+	if err := cfg.SetBool(name+".flag_unavailable", opts.FlagUnavailable); err != nil {
+		return err
+	}
+
 	if opts.Root == "" {
 		opts.Root = "/"
 	}
@@ -99,6 +103,9 @@ func FsTabApply(cfg *config.Config, mounts *MountTable) error {
 			offlineKey := key[:len(key)-len(".path")] + ".offline"
 			entry.Offline = cfg.Bool(offlineKey)
 
+			flagUnavailableKey := key[:len(key)-len(".path")] + ".flag_unavailable"
+			entry.FlagUnavailable = cfg.Bool(flagUnavailableKey)
+
 			rootPathKey := key[:len(key)-len(".path")] + ".root"
 			entry.Root = cfg.String(rootPathKey)
 			if entry.Root == "" {
@@ -136,12 +143,13 @@ func FsTabApply(cfg *config.Config, mounts *MountTable) error {
 
 // FsTabEntry is a representation of one entry in the filesystem tab.
 type FsTabEntry struct {
-	Name     string
-	Path     string
-	Root     string
-	Active   bool
-	ReadOnly bool
-	Offline  bool
+	Name            string
+	Path            string
+	Root            string
+	Active          bool
+	ReadOnly        bool
+	Offline         bool
+	FlagUnavailable bool
 }
 
 // FsTabList lists all entries in the filesystem tab in a nice way.
@@ -172,6 +180,8 @@ func FsTabList(cfg *config.Config, mounts *MountTable) ([]FsTabEntry, error) {
 			mountMap[mountName].ReadOnly = cfg.Bool(key)
 		case "offline":
 			mountMap[mountName].Offline = cfg.Bool(key)
+		case "flag_unavailable":
+			mountMap[mountName].FlagUnavailable = cfg.Bool(key)
 		case "root":
 			mountMap[mountName].Root = cfg.String(key)
 		}
@@ -18,11 +18,14 @@ import (
 	"github.com/sahib/brig/catfs"
 	"github.com/sahib/brig/catfs/mio/pagecache/mdcache"
 	"github.com/sahib/brig/defaults"
+	h "github.com/sahib/brig/util/hashlib"
 	"github.com/sahib/brig/util/testutil"
 	"github.com/sahib/config"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
 
+	"bazil.org/fuse"
 	"bazil.org/fuse/fs/fstestutil/spawntest"
 	"bazil.org/fuse/fs/fstestutil/spawntest/httpjson"
 )
@@ -68,6 +71,8 @@ func (fch *fuseCatFSHelp) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		httpjson.ServePOST(fch.catfsStage).ServeHTTP(w, req)
 	case "/catfsGetData":
 		httpjson.ServePOST(fch.catfsGetData).ServeHTTP(w, req)
+	case "/catfsCommit":
+		httpjson.ServePOST(fch.catfsCommit).ServeHTTP(w, req)
 	default:
 		http.NotFound(w, req)
 	}
@@ -99,6 +104,7 @@ func makeDummyCatFS(dbPath string) (catfsFuseInfo, error) {
 		false,
 		cfg.Section("fs"),
 		nil,
+		nil,
 		mdc,
 	)
 
@@ -233,6 +239,27 @@ func (fch *fuseCatFSHelp) catfsGetData(ctx context.Context, req catfsPayload) (*
 	return &out, err
 }
 
+type catfsCommitPayload struct {
+	Msg  string
+	Hash string
+}
+
+// Commit the current state of catFS and report the commit hash
+func (fch *fuseCatFSHelp) catfsCommit(ctx context.Context, req catfsCommitPayload) (*catfsCommitPayload, error) {
+	out := catfsCommitPayload{Msg: req.Msg}
+	if err := cfInfo.cfs.MakeCommit(req.Msg); err != nil {
+		return &out, err
+	}
+
+	head, err := cfInfo.cfs.Head()
+	if err != nil {
+		return &out, err
+	}
+
+	out.Hash = head
+	return &out, nil
+}
+
 var helpers spawntest.Registry
 var fuseCatFSHelper = helpers.Register("fuseCatFSHelp", &fuseCatFSHelp{})
 
@@ -555,6 +582,179 @@ func TestWithRoot(t *testing.T) {
 	})
 }
 
+func TestVirtualBrigDir(t *testing.T) {
+	withMount(t, MountOptions{}, func(ctx context.Context, control *spawntest.Control, mount *mountInfo) {
+		oldData := []byte{1, 2, 3}
+		req := catfsPayload{Path: "/dir/x.png", Data: oldData}
+		require.NoError(t, control.JSON("/catfsStage").Call(ctx, req, &nothing{}))
+
+		first := catfsCommitPayload{}
+		commitReq := catfsCommitPayload{Msg: "first"}
+		require.NoError(t, control.JSON("/catfsCommit").Call(ctx, commitReq, &first))
+
+		req = catfsPayload{Path: "/dir/x.png", Data: []byte{4, 5, 6, 7}}
+		require.NoError(t, control.JSON("/catfsStage").Call(ctx, req, &nothing{}))
+
+		second := catfsCommitPayload{}
+		commitReq = catfsCommitPayload{Msg: "second"}
+		require.NoError(t, control.JSON("/catfsCommit").Call(ctx, commitReq, &second))
+
+		// The virtual directory is reachable, but not listed:
+		rootEntries, err := ioutil.ReadDir(mount.Dir)
+		require.NoError(t, err)
+		for _, entry := range rootEntries {
+			require.NotEqual(t, virtualDirName, entry.Name())
+		}
+
+		brigDir := filepath.Join(mount.Dir, virtualDirName)
+		snapPath := filepath.Join(brigDir, "snapshots", "head^", "dir", "x.png")
+		data, err := ioutil.ReadFile(snapPath)
+		require.NoError(t, err)
+		require.Equal(t, oldData, data)
+
+		snapPath = filepath.Join(brigDir, "snapshots", first.Hash, "dir", "x.png")
+		data, err = ioutil.ReadFile(snapPath)
+		require.NoError(t, err)
+		require.Equal(t, oldData, data)
+
+		versions, err := ioutil.ReadDir(filepath.Join(brigDir, "history", "dir", "x.png"))
+		require.NoError(t, err)
+
+		versionNames := []string{}
+		for _, version := range versions {
+			versionNames = append(versionNames, version.Name())
+		}
+
+		require.ElementsMatch(t, []string{first.Hash, second.Hash}, versionNames)
+
+		histPath := filepath.Join(brigDir, "history", "dir", "x.png", first.Hash)
+		data, err = ioutil.ReadFile(histPath)
+		require.NoError(t, err)
+		require.Equal(t, oldData, data)
+
+		// Old versions cannot be modified:
+		require.NotNil(t, ioutil.WriteFile(histPath, []byte{8}, 0644))
+		require.NotNil(t, os.Mkdir(filepath.Join(brigDir, "snapshots", "head", "sub"), 0755))
+	})
+}
+
+// TestLockTable checks the mapping of kernel locks to brig locks.
+// It does not need a real mount, since only the lock table is used.
+func TestLockTable(t *testing.T) {
+	dbPath, err := ioutil.TempDir("", "brig-fuse-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dbPath)
+
+	info, err := makeDummyCatFS(dbPath)
+	require.NoError(t, err)
+	defer info.cfs.Close()
+
+	cfs := info.cfs
+	require.NoError(t, cfs.Stage("/x", bytes.NewReader([]byte{1})))
+	require.NoError(t, cfs.Stage("/y", bytes.NewReader([]byte{2})))
+
+	m := &Mount{fs: cfs, locks: make(map[string]*fileLocks)}
+	eagain := fuse.Errno(syscall.EAGAIN)
+
+	// Read locks are shared and do not take a brig lock:
+	require.NoError(t, m.tryLock("/x", 1, fuse.LockRead, false))
+	require.NoError(t, m.tryLock("/x", 2, fuse.LockRead, false))
+	require.Equal(t, eagain, m.tryLock("/x", 3, fuse.LockWrite, true))
+
+	locks, err := cfs.Locks()
+	require.NoError(t, err)
+	require.Empty(t, locks)
+
+	m.unlock("/x", 1, false)
+	m.unlock("/x", 2, false)
+
+	// Write locks do, and give it back on unlock:
+	require.NoError(t, m.tryLock("/x", 3, fuse.LockWrite, true))
+	require.Equal(t, eagain, m.tryLock("/x", 1, fuse.LockRead, false))
+
+	locks, err = cfs.Locks()
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+	require.Equal(t, "/x", locks[0].Path)
+
+	// Closing a descriptor does not release flock locks:
+	m.unlock("/x", 3, true)
+	locks, err = cfs.Locks()
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+
+	m.unlock("/x", 3, false)
+	locks, err = cfs.Locks()
+	require.NoError(t, err)
+	require.Empty(t, locks)
+
+	// Files locked by other users can not be write-locked:
+	require.NoError(t, cfs.SetRemoteLocks("bob", []catfs.Lock{{Path: "/y"}}))
+	require.Equal(t, eagain, m.tryLock("/y", 1, fuse.LockWrite, false))
+	require.NoError(t, m.tryLock("/y", 1, fuse.LockRead, false))
+
+	// Locks taken by `brig lock` survive unlocking:
+	require.NoError(t, cfs.Lock("/x"))
+	require.NoError(t, m.tryLock("/x", 1, fuse.LockWrite, false))
+	m.unlock("/x", 1, false)
+	locks, err = cfs.OwnLocks()
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+}
+
+// remoteBackend pretends that the content of all files has to be fetched.
+type remoteBackend struct {
+	*catfs.MemFsBackend
+	isCached bool
+}
+
+func (rb *remoteBackend) IsCached(hash h.Hash) (bool, error) {
+	return rb.isCached, nil
+}
+
+// TestFlagUnavailable checks how files are listed and opened, depending on
+// the mount options. Like TestLockTable, it does not need a real mount.
+func TestFlagUnavailable(t *testing.T) {
+	dbPath, err := ioutil.TempDir("", "brig-fuse-available")
+	require.NoError(t, err)
+	defer os.RemoveAll(dbPath)
+
+	cfg, err := config.Open(nil, defaults.Defaults, config.StrictnessPanic)
+	require.NoError(t, err)
+
+	bk := &remoteBackend{MemFsBackend: catfs.NewMemFsBackend(), isCached: true}
+	cfs, err := catfs.NewFilesystem(bk, dbPath, "alice", false, cfg.Section("fs"), nil, nil, nil)
+	require.NoError(t, err)
+	defer cfs.Close()
+
+	require.NoError(t, cfs.Stage("/x", bytes.NewReader([]byte{1})))
+
+	eremote := fuse.Errno(syscall.EREMOTE)
+	tcs := []struct {
+		opts       MountOptions
+		mode       os.FileMode
+		openResult error
+	}{
+		{MountOptions{}, 0640, nil},
+		{MountOptions{Offline: true}, os.ModeSymlink | 0640, eremote},
+		{MountOptions{FlagUnavailable: true}, 0, eremote},
+		{MountOptions{Offline: true, FlagUnavailable: true}, 0, eremote},
+	}
+
+	for _, tc := range tcs {
+		m := &Mount{fs: cfs, options: tc.opts}
+
+		// Available files always look and work the same:
+		bk.isCached = true
+		require.Equal(t, os.FileMode(0640), m.fileMode("/x"))
+		require.NoError(t, m.checkAvailable("/x"))
+
+		bk.isCached = false
+		require.Equal(t, tc.mode, m.fileMode("/x"), "%+v", tc.opts)
+		require.Equal(t, tc.openResult, m.checkAvailable("/x"), "%+v", tc.opts)
+	}
+}
+
 // Benchmarks
 
 var (
@@ -654,3 +854,42 @@ func BenchmarkWrite(b *testing.B) {
 		})
 	}
 }
+
+func TestUserXattrs(t *testing.T) {
+	dbPath, err := ioutil.TempDir("", "brig-fuse-xattr")
+	require.NoError(t, err)
+	defer os.RemoveAll(dbPath)
+
+	info, err := makeDummyCatFS(dbPath)
+	require.NoError(t, err)
+	defer info.cfs.Close()
+
+	cfs := info.cfs
+	require.NoError(t, cfs.Stage("/x", bytes.NewReader([]byte{1})))
+	require.NoError(t, cfs.Mkdir("/dir", false))
+
+	require.NoError(t, setXattr(cfs, "user.project", "/x", []byte("holiday"), 0))
+	require.Equal(t, fuse.EEXIST, setXattr(cfs, "user.project", "/x", []byte("x"), unix.XATTR_CREATE))
+	require.Equal(t, fuse.ErrNoXattr, setXattr(cfs, "user.client", "/x", []byte("x"), unix.XATTR_REPLACE))
+	require.Equal(t, fuse.Errno(syscall.ENOTSUP), setXattr(cfs, "user.project", "/dir", []byte("x"), 0))
+
+	value, err := getXattr(cfs, "user.project", "/x")
+	require.NoError(t, err)
+	require.Equal(t, []byte("holiday"), value)
+
+	// Brig's own attributes still work and can not be shadowed:
+	value, err = getXattr(cfs, "user.brig.pinned", "/x")
+	require.NoError(t, err)
+	require.Equal(t, []byte("yes"), value)
+	require.Equal(t, fuse.ErrNoXattr, setXattr(cfs, "user.brig.pinned", "/x", []byte("no"), 0))
+
+	names, err := listXattr(cfs, "/x")
+	require.NoError(t, err)
+	require.Contains(t, string(names), "user.project\x00")
+
+	require.NoError(t, removeXattr(cfs, "user.project", "/x"))
+	require.Equal(t, fuse.ErrNoXattr, removeXattr(cfs, "user.project", "/x"))
+
+	_, err = getXattr(cfs, "user.project", "/x")
+	require.Equal(t, fuse.ErrNoXattr, err)
+}
@@ -51,6 +51,7 @@ func makeFS(dbPath string, backend catfs.FsBackend) (*catfs.FS, error) {
 		false,
 		cfg.Section("fs"),
 		hintMgr,
+		nil,
 		pageCache,
 	)
 
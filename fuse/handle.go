@@ -93,6 +93,8 @@ func (hd *Handle) writeAt(buf []byte, off int64) (n int, err error) {
 
 // Flush is called to make sure all written contents get synced to disk.
 func (hd *Handle) Flush(ctx context.Context, req *fuse.FlushRequest) error {
+	// Closing any descriptor releases the POSIX locks of this process:
+	hd.m.unlock(hd.fd.Path(), req.LockOwner, true)
 	return hd.flush()
 }
 
@@ -123,6 +125,10 @@ func (hd *Handle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
 	defer logPanic("handle: release")
 	log.Debugf("fuse-release: %v", hd.fd.Path())
 
+	if req.ReleaseFlags&fuse.ReleaseFlockUnlock != 0 {
+		hd.m.unlock(hd.fd.Path(), req.LockOwner, false)
+	}
+
 	if req.Flags.IsReadOnly() {
 		// we don't need to track read-only handles
 		return nil
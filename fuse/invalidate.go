@@ -0,0 +1,93 @@
+// +build !windows
+
+package fuse
+
+import (
+	"path"
+	"strings"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	log "github.com/sirupsen/logrus"
+)
+
+// The kernel caches attributes, directory entries and file contents of a
+// mount. Changes that do not go through the mount itself (a sync, a reset or
+// a `brig stage`) would stay invisible until those caches time out.
+// Therefore each mount remembers the nodes it handed out to the kernel and
+// invalidates them when catfs reports a change of their path.
+
+// rememberNode takes note that the kernel now knows `nd` as `nodePath`.
+func (m *Mount) rememberNode(nodePath string, nd fs.Node) fs.Node {
+	m.nodesMu.Lock()
+	defer m.nodesMu.Unlock()
+
+	m.nodes[nodePath] = nd
+	return nd
+}
+
+// forgetNode is called when the kernel dropped `nd`.
+func (m *Mount) forgetNode(nodePath string, nd fs.Node) {
+	m.nodesMu.Lock()
+	defer m.nodesMu.Unlock()
+
+	// The path might have been looked up again in the meantime:
+	if m.nodes[nodePath] == nd {
+		delete(m.nodes, nodePath)
+	}
+}
+
+// isBelowPath checks if `nodePath` is `root` or inside of it.
+func isBelowPath(nodePath, root string) bool {
+	return root == "/" || nodePath == root || strings.HasPrefix(nodePath, root+"/")
+}
+
+type cachedEntry struct {
+	parent fs.Node
+	name   string
+}
+
+// invalidate drops everything the kernel caches about `changed`
+// and the nodes below them.
+func (m *Mount) invalidate(changed []string) {
+	nodes := make(map[fs.Node]bool)
+	entries := make(map[cachedEntry]bool)
+
+	m.nodesMu.Lock()
+	for _, changedPath := range changed {
+		for nodePath, nd := range m.nodes {
+			if !isBelowPath(nodePath, changedPath) {
+				continue
+			}
+
+			nodes[nd] = true
+			if parent, ok := m.nodes[path.Dir(nodePath)]; ok && nodePath != "/" {
+				entries[cachedEntry{parent, path.Base(nodePath)}] = true
+			}
+		}
+
+		// The changed node might be new or gone,
+		// so the listing of the parent changed as well:
+		if parent, ok := m.nodes[path.Dir(changedPath)]; ok && changedPath != "/" {
+			nodes[parent] = true
+			entries[cachedEntry{parent, path.Base(changedPath)}] = true
+		}
+	}
+	m.nodesMu.Unlock()
+
+	// Do not hold nodesMu while talking to the kernel;
+	// it might answer with forget requests.
+	for entry := range entries {
+		err := m.server.InvalidateEntry(entry.parent, entry.name)
+		if err != nil && err != fuse.ErrNotCached {
+			log.Debugf("fuse: failed to invalidate entry %s: %v", entry.name, err)
+		}
+	}
+
+	for nd := range nodes {
+		err := m.server.InvalidateNodeData(nd)
+		if err != nil && err != fuse.ErrNotCached {
+			log.Debugf("fuse: failed to invalidate node: %v", err)
+		}
+	}
+}
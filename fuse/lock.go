@@ -0,0 +1,248 @@
+// +build !windows
+
+package fuse
+
+import (
+	"context"
+	"math"
+	"syscall"
+	"time"
+
+	"bazil.org/fuse"
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs"
+	log "github.com/sirupsen/logrus"
+)
+
+// flock(2) and fcntl(2) locks are mapped to brig locks, so that other users
+// see that a file is in use, even if it is locked by a program and not by
+// `brig lock`. Byte ranges are not supported; every lock covers the whole
+// file. Read locks are shared and only local, write locks take a brig lock
+// and fail if somebody else locked the file already.
+
+// lockHolder is a single holder of a kernel lock.
+type lockHolder struct {
+	typ     fuse.LockType
+	isFlock bool
+}
+
+// fileLocks are the kernel locks of a single file.
+type fileLocks struct {
+	holders map[fuse.LockOwner]lockHolder
+
+	// tookBrigLock is true if we took the brig lock because of
+	// a write lock and need to release it again.
+	tookBrigLock bool
+}
+
+func (fl *fileLocks) hasWriter() bool {
+	for _, holder := range fl.holders {
+		if holder.typ == fuse.LockWrite {
+			return true
+		}
+	}
+
+	return false
+}
+
+// conflict returns the lock of another owner that conflicts with a
+// lock of `typ` for `owner`, if any.
+func (fl *fileLocks) conflict(owner fuse.LockOwner, typ fuse.LockType) (lockHolder, bool) {
+	for holderOwner, holder := range fl.holders {
+		if holderOwner == owner {
+			continue
+		}
+
+		if typ == fuse.LockWrite || holder.typ == fuse.LockWrite {
+			return holder, true
+		}
+	}
+
+	return lockHolder{}, false
+}
+
+// takeBrigLock locks `path` in brig, unless it was locked by us already.
+func (m *Mount) takeBrigLock(path string, fl *fileLocks) error {
+	if fl.tookBrigLock || m.options.ReadOnly {
+		return nil
+	}
+
+	ownLocks, err := m.fs.OwnLocks()
+	if err != nil {
+		return err
+	}
+
+	for _, lock := range ownLocks {
+		if lock.Path == path {
+			// Locked by `brig lock`; do not release it on unlock.
+			return nil
+		}
+	}
+
+	if err := m.fs.Lock(path); err != nil {
+		if err == catfs.ErrReadOnly {
+			// Mounts of other users can only be locked locally.
+			return nil
+		}
+
+		return err
+	}
+
+	fl.tookBrigLock = true
+	return nil
+}
+
+// tryLock takes a kernel lock of `typ` on `path` for `owner`.
+// syscall.EAGAIN is returned if the lock is held by somebody else.
+func (m *Mount) tryLock(path string, owner fuse.LockOwner, typ fuse.LockType, isFlock bool) error {
+	m.locksMu.Lock()
+	defer m.locksMu.Unlock()
+
+	fl, ok := m.locks[path]
+	if !ok {
+		fl = &fileLocks{holders: make(map[fuse.LockOwner]lockHolder)}
+	}
+
+	if _, ok := fl.conflict(owner, typ); ok {
+		return fuse.Errno(syscall.EAGAIN)
+	}
+
+	if typ == fuse.LockWrite {
+		lock, err := m.fs.LockedBy(path)
+		if err != nil {
+			return errorize("lock", err)
+		}
+
+		if lock != nil {
+			log.Infof("fuse: %s is locked by %s", path, lock.Owner)
+			return fuse.Errno(syscall.EAGAIN)
+		}
+
+		if err := m.takeBrigLock(path, fl); err != nil {
+			if e.Cause(err) == catfs.ErrLocked {
+				return fuse.Errno(syscall.EAGAIN)
+			}
+
+			return errorize("lock", err)
+		}
+	}
+
+	fl.holders[owner] = lockHolder{typ: typ, isFlock: isFlock}
+	m.locks[path] = fl
+	return nil
+}
+
+// unlock releases the kernel lock of `owner` on `path`. If `onlyPOSIX` is
+// true, flock locks are kept, since they survive closing other descriptors.
+func (m *Mount) unlock(path string, owner fuse.LockOwner, onlyPOSIX bool) {
+	m.locksMu.Lock()
+	defer m.locksMu.Unlock()
+
+	fl, ok := m.locks[path]
+	if !ok {
+		return
+	}
+
+	holder, ok := fl.holders[owner]
+	if !ok || (onlyPOSIX && holder.isFlock) {
+		return
+	}
+
+	delete(fl.holders, owner)
+	if fl.tookBrigLock && !fl.hasWriter() {
+		if err := m.fs.Unlock(path); err != nil {
+			log.Warningf("fuse: failed to release brig lock on %s: %v", path, err)
+		}
+
+		fl.tookBrigLock = false
+	}
+
+	if len(fl.holders) == 0 {
+		delete(m.locks, path)
+	}
+}
+
+// Lock tries to acquire a flock or fcntl lock without waiting.
+func (hd *Handle) Lock(ctx context.Context, req *fuse.LockRequest) error {
+	defer logPanic("handle: lock")
+
+	log.Debugf("fuse-lock: %v (%v)", hd.fd.Path(), req.Lock.Type)
+	isFlock := req.LockFlags&fuse.LockFlock != 0
+	return hd.m.tryLock(hd.fd.Path(), req.LockOwner, req.Lock.Type, isFlock)
+}
+
+// LockWait acquires a flock or fcntl lock, waiting until it is available.
+// Locks of other users are only noticed by polling.
+func (hd *Handle) LockWait(ctx context.Context, req *fuse.LockWaitRequest) error {
+	defer logPanic("handle: lock-wait")
+
+	log.Debugf("fuse-lock-wait: %v (%v)", hd.fd.Path(), req.Lock.Type)
+	isFlock := req.LockFlags&fuse.LockFlock != 0
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		err := hd.m.tryLock(hd.fd.Path(), req.LockOwner, req.Lock.Type, isFlock)
+		if err != fuse.Errno(syscall.EAGAIN) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fuse.EINTR
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock releases a lock taken by Lock or LockWait.
+func (hd *Handle) Unlock(ctx context.Context, req *fuse.UnlockRequest) error {
+	defer logPanic("handle: unlock")
+
+	log.Debugf("fuse-unlock: %v", hd.fd.Path())
+	hd.m.unlock(hd.fd.Path(), req.LockOwner, false)
+	return nil
+}
+
+// QueryLock reports a lock that would conflict with the requested one.
+func (hd *Handle) QueryLock(ctx context.Context, req *fuse.QueryLockRequest, resp *fuse.QueryLockResponse) error {
+	defer logPanic("handle: query-lock")
+
+	path := hd.fd.Path()
+	hd.m.locksMu.Lock()
+	defer hd.m.locksMu.Unlock()
+
+	if fl, ok := hd.m.locks[path]; ok {
+		if holder, ok := fl.conflict(req.LockOwner, req.Lock.Type); ok {
+			resp.Lock = fuse.FileLock{
+				Start: 0,
+				End:   math.MaxUint64,
+				Type:  holder.typ,
+				PID:   -1,
+			}
+			return nil
+		}
+	}
+
+	if req.Lock.Type != fuse.LockWrite {
+		return nil
+	}
+
+	lock, err := hd.m.fs.LockedBy(path)
+	if err != nil {
+		return errorize("query-lock", err)
+	}
+
+	if lock != nil {
+		// Held by another user; there is no local process for it.
+		resp.Lock = fuse.FileLock{
+			Start: 0,
+			End:   math.MaxUint64,
+			Type:  fuse.LockWrite,
+			PID:   -1,
+		}
+	}
+
+	return nil
+}
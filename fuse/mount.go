@@ -37,6 +37,9 @@ type MountOptions struct {
 	// Offline tells the mount to error out on files that would need
 	// to be fetched from far.
 	Offline bool
+	// FlagUnavailable lists files that would need to be fetched from far
+	// without any permissions and makes opening them fail right away.
+	FlagUnavailable bool
 }
 
 // This is very similar (and indeed mostly copied) code from:
@@ -58,6 +61,14 @@ type Mount struct {
 	options  MountOptions
 	notifier Notifier
 	fs       *catfs.FS
+
+	// nodes the kernel currently knows about, by path
+	nodesMu sync.Mutex
+	nodes   map[string]fs.Node
+
+	// flock/fcntl locks held by local processes, by path
+	locksMu sync.Mutex
+	locks   map[string]*fileLocks
 }
 
 // NewMount mounts a fuse endpoint at `mountpoint` retrieving data from `store`.
@@ -70,6 +81,9 @@ func NewMount(cfs *catfs.FS, mountpoint string, notifier Notifier, opts MountOpt
 		fuse.MaxReadahead(128 * 1024), // kernel uses at max 128kB = 131072B
 		// enabling WritebackCache doubles write speed to buffer 12MB/s -> 24MB/s
 		fuse.WritebackCache(), // writes will happen in mach large blocks 128kB instead of 8kB
+		// let us see flock and fcntl locks, so they can be mapped to brig locks:
+		fuse.LockingFlock(),
+		fuse.LockingPOSIX(),
 	}
 
 	if opts.ReadOnly {
@@ -103,6 +117,8 @@ func NewMount(cfs *catfs.FS, mountpoint string, notifier Notifier, opts MountOpt
 		options:  opts,
 		notifier: notifier,
 		fs:       cfs,
+		nodes:    make(map[string]fs.Node),
+		locks:    make(map[string]*fileLocks),
 	}
 	filesys := &Filesystem{m: mnt, root: opts.Root}
 	mnt.filesys = filesys
@@ -152,6 +168,10 @@ func (m *Mount) EqualOptions(opts MountOptions) bool {
 		return false
 	}
 
+	if m.options.Offline != opts.Offline || m.options.FlagUnavailable != opts.FlagUnavailable {
+		return false
+	}
+
 	return path.Clean(m.options.Root) == path.Clean(opts.Root)
 }
 
@@ -232,12 +252,29 @@ type MountTable struct {
 }
 
 // NewMountTable returns an empty mount table.
+// All mounts in it are kept up to date with changes to `fs`.
 func NewMountTable(fs *catfs.FS, notifier Notifier) *MountTable {
-	return &MountTable{
+	t := &MountTable{
 		m:        make(map[string]*Mount),
 		fs:       fs,
 		notifier: notifier,
 	}
+
+	fs.OnChange(t.invalidate)
+	return t
+}
+
+func (t *MountTable) invalidate(paths []string) {
+	t.mu.Lock()
+	mounts := make([]*Mount, 0, len(t.m))
+	for _, mount := range t.m {
+		mounts = append(mounts, mount)
+	}
+	t.mu.Unlock()
+
+	for _, mount := range mounts {
+		mount.invalidate(paths)
+	}
 }
 
 // AddMount calls NewMount and adds it to the table at `path`.
@@ -22,8 +22,10 @@ type Notifier interface {
 var ErrCompiledWithoutFuse = errors.New("brig was compiled without fuse support")
 
 type MountOptions struct {
-	ReadOnly bool
-	Root     string
+	ReadOnly        bool
+	Root            string
+	Offline         bool
+	FlagUnavailable bool
 }
 
 type Mount struct {
@@ -4,6 +4,10 @@ package fuse
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"bazil.org/fuse"
@@ -11,6 +15,7 @@ import (
 	ie "github.com/sahib/brig/catfs/errors"
 	"github.com/sahib/brig/repo/hints"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 )
 
 func errorize(name string, err error) error {
@@ -35,6 +40,15 @@ func logPanic(name string) {
 	}
 }
 
+const (
+	// userXattrPrefix is the namespace of the user defined attributes.
+	// The user attribute "project" is exposed as "user.project".
+	userXattrPrefix = "user."
+
+	// brigXattrPrefix is reserved for the attributes in xattrMap.
+	brigXattrPrefix = "user.brig."
+)
+
 type xattrHandler struct {
 	get func(cfs *catfs.FS, info *catfs.StatInfo) ([]byte, error)
 	set func(cfs *catfs.FS, path string, value []byte) error
@@ -72,6 +86,36 @@ var xattrMap = map[string]xattrHandler{
 			return []byte("no"), nil
 		},
 	},
+	"user.brig.offline": {
+		get: func(cfs *catfs.FS, info *catfs.StatInfo) ([]byte, error) {
+			isOffline, err := cfs.IsOffline(info.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			return yesOrNo(isOffline), nil
+		},
+		set: func(cfs *catfs.FS, path string, val []byte) error {
+			switch string(val) {
+			case "yes":
+				return cfs.SetOffline(path, true)
+			case "no":
+				return cfs.SetOffline(path, false)
+			default:
+				return fmt.Errorf("bad offline value: %s (must be yes or no)", string(val))
+			}
+		},
+	},
+	"user.brig.available": {
+		get: func(cfs *catfs.FS, info *catfs.StatInfo) ([]byte, error) {
+			isCached, err := cfs.IsCached(info.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			return yesOrNo(isCached), nil
+		},
+	},
 	"user.brig.hints.encryption": {
 		get: func(cfs *catfs.FS, info *catfs.StatInfo) ([]byte, error) {
 			return []byte(cfs.Hints().Lookup(info.Path).EncryptionAlgo), nil
@@ -102,17 +146,64 @@ var xattrMap = map[string]xattrHandler{
 	},
 }
 
-func listXattr() []byte {
-	resp := []byte{}
+func yesOrNo(val bool) []byte {
+	if val {
+		return []byte("yes")
+	}
+
+	return []byte("no")
+}
+
+// userAttrKey returns the key of the user attribute for the xattr `name`.
+// The second return value is false if `name` is not a user attribute.
+func userAttrKey(name string) (string, bool) {
+	if !strings.HasPrefix(name, userXattrPrefix) || strings.HasPrefix(name, brigXattrPrefix) {
+		return "", false
+	}
+
+	return name[len(userXattrPrefix):], true
+}
+
+func listXattr(cfs *catfs.FS, path string) ([]byte, error) {
+	names := []string{}
 	for k := range xattrMap {
-		resp = append(resp, k...)
+		names = append(names, k)
+	}
+
+	attrs, err := cfs.Attrs(path)
+	if err != nil {
+		return nil, errorize("listxattr", err)
+	}
+
+	for key := range attrs {
+		names = append(names, userXattrPrefix+key)
+	}
+
+	sort.Strings(names)
+
+	resp := []byte{}
+	for _, name := range names {
+		resp = append(resp, name...)
 		resp = append(resp, '\x00')
 	}
 
-	return resp
+	return resp, nil
 }
 
 func getXattr(cfs *catfs.FS, name, path string) ([]byte, error) {
+	if key, ok := userAttrKey(name); ok {
+		value, err := cfs.GetAttr(path, key)
+		if catfs.IsNoSuchAttrError(err) {
+			return nil, fuse.ErrNoXattr
+		}
+
+		if err != nil {
+			return nil, errorize("getxattr", err)
+		}
+
+		return value, nil
+	}
+
 	handler, ok := xattrMap[name]
 	if !ok || handler.get == nil {
 		return nil, fuse.ErrNoXattr
@@ -126,7 +217,52 @@ func getXattr(cfs *catfs.FS, name, path string) ([]byte, error) {
 	return handler.get(cfs, info)
 }
 
-func setXattr(cfs *catfs.FS, name, path string, val []byte) error {
+// checkUserXattr makes sure that `path` can have user attributes.
+func checkUserXattr(cfs *catfs.FS, path string) error {
+	info, err := cfs.Stat(path)
+	if err != nil {
+		return errorize("setxattr", err)
+	}
+
+	if info.IsDir {
+		return fuse.Errno(syscall.ENOTSUP)
+	}
+
+	return nil
+}
+
+func setXattr(cfs *catfs.FS, name, path string, val []byte, flags uint32) error {
+	if key, ok := userAttrKey(name); ok {
+		if err := checkUserXattr(cfs, path); err != nil {
+			return err
+		}
+
+		if err := catfs.ValidateAttrKey(key); err != nil {
+			return fuse.Errno(syscall.EINVAL)
+		}
+
+		if len(val) > catfs.MaxAttrValueLen {
+			return fuse.Errno(syscall.E2BIG)
+		}
+
+		// Honour XATTR_CREATE and XATTR_REPLACE:
+		_, err := cfs.GetAttr(path, key)
+		exists := err == nil
+		if flags&unix.XATTR_CREATE != 0 && exists {
+			return fuse.EEXIST
+		}
+
+		if flags&unix.XATTR_REPLACE != 0 && !exists {
+			return fuse.ErrNoXattr
+		}
+
+		if err := cfs.SetAttr(path, key, val); err != nil {
+			return errorize("setxattr", err)
+		}
+
+		return nil
+	}
+
 	handler, ok := xattrMap[name]
 	if !ok || handler.set == nil {
 		return fuse.ErrNoXattr
@@ -139,6 +275,29 @@ func setXattr(cfs *catfs.FS, name, path string, val []byte) error {
 	return nil
 }
 
+func removeXattr(cfs *catfs.FS, name, path string) error {
+	key, ok := userAttrKey(name)
+	if !ok {
+		// The brig attributes can not be removed.
+		if _, ok := xattrMap[name]; ok {
+			return fuse.EPERM
+		}
+
+		return fuse.ErrNoXattr
+	}
+
+	if err := checkUserXattr(cfs, path); err != nil {
+		return err
+	}
+
+	err := cfs.RemoveAttr(path, key)
+	if catfs.IsNoSuchAttrError(err) {
+		return fuse.ErrNoXattr
+	}
+
+	return errorize("removexattr", err)
+}
+
 func notifyChange(m *Mount, d time.Duration) {
 	if m.notifier == nil {
 		// this can happen in tests.
@@ -147,3 +306,58 @@ func notifyChange(m *Mount, d time.Duration) {
 
 	time.AfterFunc(d, m.notifier.PublishEvent)
 }
+
+const filePerm os.FileMode = 0640
+
+// isAvailable checks if the content of `path` can be read without
+// fetching it from the network first.
+func (m *Mount) isAvailable(path string) bool {
+	isCached, err := m.fs.IsCached(path)
+	if err != nil {
+		log.Errorf("IsCached failed for %s with error : %v", path, err)
+		return false
+	}
+
+	return isCached
+}
+
+// fileMode returns the mode that the file at `path` is listed with.
+// Offline and FlagUnavailable mounts mark files that are not available
+// locally, so users can see them before trying to open them.
+func (m *Mount) fileMode(path string) os.FileMode {
+	if !m.options.Offline && !m.options.FlagUnavailable {
+		return filePerm
+	}
+
+	if m.isAvailable(path) {
+		return filePerm
+	}
+
+	if m.options.FlagUnavailable {
+		// Still listed as normal file with the right size,
+		// but nobody is allowed to do anything with it.
+		return 0
+	}
+
+	// Uncached file will be shown as symlink
+	// We cannot read them in Offline mode,
+	// but we can delete such link and overwrite its content
+	return os.ModeSymlink | filePerm
+}
+
+// checkAvailable returns EREMOTE for files that are not available locally,
+// if the mount was told to not fetch them. This fails fast with a distinct
+// error instead of blocking on the network. Tools can check
+// user.brig.available beforehand.
+func (m *Mount) checkAvailable(path string) error {
+	if !m.options.Offline && !m.options.FlagUnavailable {
+		return nil
+	}
+
+	if !m.isAvailable(path) {
+		log.Infof("fuse: file-not-cached: %s: %v", path, ErrNotCached)
+		return fuse.Errno(syscall.EREMOTE)
+	}
+
+	return nil
+}
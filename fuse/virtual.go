@@ -0,0 +1,457 @@
+// +build !windows
+
+package fuse
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/sahib/brig/catfs"
+	ie "github.com/sahib/brig/catfs/errors"
+	"github.com/sahib/brig/catfs/mio"
+	log "github.com/sirupsen/logrus"
+)
+
+// The virtual directory below the mount root gives read-only access to older
+// states of the repository:
+//
+//     /.brig/snapshots/<tag-or-commit>/<path>
+//     /.brig/history/<path>/<commit>
+//
+// Nothing of it is precomputed; every node is resolved when the kernel asks
+// for it. The directory is not listed in the root, so tools like `du` or
+// `rsync` do not descend into it by accident, but it can be entered directly.
+// All virtual nodes report an inode of 0, which makes fuse assign a dynamic
+// one. Using the inode of the original node would make old versions look
+// like hardlinks of the current ones.
+const virtualDirName = ".brig"
+
+// fillVirtualAttr sets the attributes shared by all virtual nodes.
+func fillVirtualAttr(attr *fuse.Attr, info *catfs.StatInfo) {
+	attr.Uid = uint32(os.Getuid())
+	attr.Gid = uint32(os.Getgid())
+
+	if info.IsDir {
+		attr.Mode = os.ModeDir | 0555
+	} else {
+		attr.Mode = 0444
+	}
+
+	attr.Size = info.Size
+	attr.Mtime = info.ModTime
+	attr.BlockSize = 4096
+	attr.Blocks = info.Size / 512
+	if info.Size%uint64(512) > 0 {
+		attr.Blocks++
+	}
+}
+
+func virtualDirents(names []string) []fuse.Dirent {
+	ents := []fuse.Dirent{
+		{Type: fuse.DT_Dir, Name: "."},
+		{Type: fuse.DT_Dir, Name: ".."},
+	}
+
+	for _, name := range names {
+		ents = append(ents, fuse.Dirent{Type: fuse.DT_Dir, Name: name})
+	}
+
+	return ents
+}
+
+// brigDir is the `.brig` directory itself.
+type brigDir struct {
+	m *Mount
+}
+
+// Attr returns the attributes of the mount root, but read-only.
+func (bd *brigDir) Attr(ctx context.Context, attr *fuse.Attr) error {
+	defer logPanic("brig-dir: attr")
+
+	info, err := bd.m.fs.Stat(bd.m.filesys.root)
+	if err != nil {
+		return errorize("brig-dir-attr", err)
+	}
+
+	fillVirtualAttr(attr, info)
+	return nil
+}
+
+// Lookup returns one of the fixed children.
+func (bd *brigDir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	defer logPanic("brig-dir: lookup")
+
+	switch name {
+	case "snapshots":
+		return &snapshotsDir{m: bd.m}, nil
+	case "history":
+		return &historyDir{path: bd.m.filesys.root, m: bd.m}, nil
+	default:
+		return nil, fuse.ENOENT
+	}
+}
+
+// ReadDirAll lists the fixed children.
+func (bd *brigDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	defer logPanic("brig-dir: readdirall")
+	return virtualDirents([]string{"history", "snapshots"}), nil
+}
+
+// snapshotsDir lists every tag and commit that can be browsed.
+type snapshotsDir struct {
+	m *Mount
+}
+
+// Attr returns the attributes of the mount root, but read-only.
+func (sd *snapshotsDir) Attr(ctx context.Context, attr *fuse.Attr) error {
+	defer logPanic("snapshots-dir: attr")
+
+	info, err := sd.m.fs.Stat(sd.m.filesys.root)
+	if err != nil {
+		return errorize("snapshots-dir-attr", err)
+	}
+
+	fillVirtualAttr(attr, info)
+	return nil
+}
+
+// Lookup accepts every rev that resolves to a commit, not only the listed
+// ones; `HEAD^^` or abbreviated commit hashes work as well.
+func (sd *snapshotsDir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	defer logPanic("snapshots-dir: lookup")
+
+	info, err := sd.m.fs.StatAt(name, sd.m.filesys.root)
+	if err != nil {
+		return nil, errorize("snapshots-dir-lookup", err)
+	}
+
+	if !info.IsDir {
+		return nil, fuse.ENOENT
+	}
+
+	return &snapshotDir{rev: name, path: sd.m.filesys.root, m: sd.m}, nil
+}
+
+// ReadDirAll lists all tags and the hashes of all commits reachable from HEAD.
+func (sd *snapshotsDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	defer logPanic("snapshots-dir: readdirall")
+
+	names := []string{}
+	err := sd.m.fs.Log("head", func(cmt *catfs.Commit) error {
+		names = append(names, cmt.Tags...)
+		names = append(names, cmt.Hash.B58String())
+		return nil
+	})
+
+	if err != nil && !ie.IsErrNoSuchRef(err) {
+		return nil, errorize("snapshots-dir-readdirall", err)
+	}
+
+	return virtualDirents(names), nil
+}
+
+// snapshotDir is a directory as it was at `rev`.
+type snapshotDir struct {
+	rev  string
+	path string
+	m    *Mount
+}
+
+// Attr returns the attributes the directory had at `rev`.
+func (sd *snapshotDir) Attr(ctx context.Context, attr *fuse.Attr) error {
+	defer logPanic("snapshot-dir: attr")
+
+	info, err := sd.m.fs.StatAt(sd.rev, sd.path)
+	if err != nil {
+		return errorize("snapshot-dir-attr", err)
+	}
+
+	fillVirtualAttr(attr, info)
+	return nil
+}
+
+// Lookup returns a direct child as it was at `rev`.
+func (sd *snapshotDir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	defer logPanic("snapshot-dir: lookup")
+
+	childPath := path.Join(sd.path, name)
+	info, err := sd.m.fs.StatAt(sd.rev, childPath)
+	if err != nil {
+		return nil, errorize("snapshot-dir-lookup", err)
+	}
+
+	if info.IsDir {
+		return &snapshotDir{rev: sd.rev, path: childPath, m: sd.m}, nil
+	}
+
+	return &snapshotFile{rev: sd.rev, path: childPath, m: sd.m}, nil
+}
+
+// ReadDirAll lists the children the directory had at `rev`.
+func (sd *snapshotDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	defer logPanic("snapshot-dir: readdirall")
+
+	entries, err := sd.m.fs.ListAt(sd.rev, sd.path)
+	if err != nil {
+		return nil, errorize("snapshot-dir-readdirall", err)
+	}
+
+	fuseEnts := virtualDirents(nil)
+	for _, entry := range entries {
+		childType := fuse.DT_File
+		if entry.IsDir {
+			childType = fuse.DT_Dir
+		}
+
+		fuseEnts = append(fuseEnts, fuse.Dirent{
+			Type: childType,
+			Name: path.Base(entry.Path),
+		})
+	}
+
+	return fuseEnts, nil
+}
+
+// snapshotFile is a file with the content it had at `rev`.
+type snapshotFile struct {
+	rev  string
+	path string
+	m    *Mount
+}
+
+// Attr returns the attributes the file had at `rev`.
+func (sf *snapshotFile) Attr(ctx context.Context, attr *fuse.Attr) error {
+	defer logPanic("snapshot-file: attr")
+
+	info, err := sf.m.fs.StatAt(sf.rev, sf.path)
+	if err != nil {
+		return errorize("snapshot-file-attr", err)
+	}
+
+	fillVirtualAttr(attr, info)
+	return nil
+}
+
+// Open returns a read-only handle to the old content.
+func (sf *snapshotFile) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	defer logPanic("snapshot-file: open")
+
+	if !req.Flags.IsReadOnly() {
+		return nil, fuse.Errno(syscall.EROFS)
+	}
+
+	stream, err := sf.m.fs.CatAt(sf.rev, sf.path)
+	if err != nil {
+		return nil, errorize("snapshot-file-open", err)
+	}
+
+	// Old content never changes, so the kernel may cache it.
+	resp.Flags |= fuse.OpenKeepCache
+	return &streamHandle{path: sf.path, stream: stream}, nil
+}
+
+// historyDir mirrors the directory structure of the current tree.
+// Files in it are directories that contain one entry per version.
+type historyDir struct {
+	path string
+	m    *Mount
+}
+
+// Attr returns the attributes of the current directory, but read-only.
+func (hd *historyDir) Attr(ctx context.Context, attr *fuse.Attr) error {
+	defer logPanic("history-dir: attr")
+
+	info, err := hd.m.fs.Stat(hd.path)
+	if err != nil {
+		return errorize("history-dir-attr", err)
+	}
+
+	fillVirtualAttr(attr, info)
+	return nil
+}
+
+// Lookup returns a nested history directory or the versions of a file.
+func (hd *historyDir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	defer logPanic("history-dir: lookup")
+
+	childPath := path.Join(hd.path, name)
+	info, err := hd.m.fs.Stat(childPath)
+	if err != nil {
+		return nil, errorize("history-dir-lookup", err)
+	}
+
+	if info.IsDir {
+		return &historyDir{path: childPath, m: hd.m}, nil
+	}
+
+	return &versionsDir{path: childPath, m: hd.m}, nil
+}
+
+// ReadDirAll lists the current children, all of them as directories.
+func (hd *historyDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	defer logPanic("history-dir: readdirall")
+
+	entries, err := hd.m.fs.List(hd.path, 1)
+	if err != nil {
+		return nil, errorize("history-dir-readdirall", err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Path == hd.path {
+			continue
+		}
+
+		names = append(names, path.Base(entry.Path))
+	}
+
+	return virtualDirents(names), nil
+}
+
+// versionsDir has one file per committed version of the file at `path`.
+// The entries are named after the commit that introduced the version.
+type versionsDir struct {
+	path string
+	m    *Mount
+}
+
+// versions maps the commit hash of each version to the path
+// the file had back then, since it might have been moved since.
+func (vd *versionsDir) versions() (map[string]string, []string, error) {
+	changes, err := vd.m.fs.History(vd.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := make(map[string]string)
+	names := []string{}
+	for _, change := range changes {
+		if strings.Contains(change.Change, "removed") {
+			// There is nothing to read at this version.
+			continue
+		}
+
+		isStaging := false
+		for _, tag := range change.Head.Tags {
+			if tag == "curr" {
+				isStaging = true
+			}
+		}
+
+		if isStaging {
+			// Not committed yet; it's visible in the normal tree.
+			continue
+		}
+
+		name := change.Head.Hash.B58String()
+		paths[name] = change.Path
+		names = append(names, name)
+	}
+
+	return paths, names, nil
+}
+
+// Attr returns the attributes of the current file, but as directory.
+func (vd *versionsDir) Attr(ctx context.Context, attr *fuse.Attr) error {
+	defer logPanic("versions-dir: attr")
+
+	info, err := vd.m.fs.Stat(vd.path)
+	if err != nil {
+		return errorize("versions-dir-attr", err)
+	}
+
+	fillVirtualAttr(attr, info)
+	attr.Mode = os.ModeDir | 0555
+	return nil
+}
+
+// Lookup returns the version that was committed with `name`.
+func (vd *versionsDir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	defer logPanic("versions-dir: lookup")
+
+	paths, _, err := vd.versions()
+	if err != nil {
+		return nil, errorize("versions-dir-lookup", err)
+	}
+
+	oldPath, ok := paths[name]
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+
+	return &snapshotFile{rev: name, path: oldPath, m: vd.m}, nil
+}
+
+// ReadDirAll lists all versions, newest first.
+func (vd *versionsDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	defer logPanic("versions-dir: readdirall")
+
+	_, names, err := vd.versions()
+	if err != nil {
+		return nil, errorize("versions-dir-readdirall", err)
+	}
+
+	fuseEnts := virtualDirents(nil)
+	for _, name := range names {
+		fuseEnts = append(fuseEnts, fuse.Dirent{Type: fuse.DT_File, Name: name})
+	}
+
+	return fuseEnts, nil
+}
+
+// streamHandle is a read-only handle on old content.
+type streamHandle struct {
+	mu     sync.Mutex
+	path   string
+	stream mio.Stream
+}
+
+// Read is called to read a block of data at a certain offset.
+func (sh *streamHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	defer logPanic("stream-handle: read")
+
+	if _, err := sh.stream.Seek(req.Offset, io.SeekStart); err != nil {
+		return errorize("stream-handle-seek", err)
+	}
+
+	n, err := io.ReadFull(sh.stream, resp.Data[:req.Size])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return errorize("stream-handle-read", err)
+	}
+
+	resp.Data = resp.Data[:n]
+	return nil
+}
+
+// Release is called to close this handle.
+func (sh *streamHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	defer logPanic("stream-handle: release")
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if err := sh.stream.Close(); err != nil {
+		log.Warningf("fuse: failed to close stream of %s: %v", sh.path, err)
+	}
+
+	return nil
+}
+
+// Compile time checks to see which interfaces we implement:
+var _ = fs.NodeStringLookuper(&brigDir{})
+var _ = fs.HandleReadDirAller(&snapshotsDir{})
+var _ = fs.NodeStringLookuper(&snapshotDir{})
+var _ = fs.NodeOpener(&snapshotFile{})
+var _ = fs.NodeStringLookuper(&historyDir{})
+var _ = fs.NodeStringLookuper(&versionsDir{})
+var _ = fs.HandleReader(&streamHandle{})
+var _ = fs.HandleReleaser(&streamHandle{})
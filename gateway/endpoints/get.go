@@ -11,7 +11,6 @@ import (
 
 	"github.com/sahib/brig/catfs"
 	ie "github.com/sahib/brig/catfs/errors"
-	"github.com/sahib/brig/catfs/mio"
 	"github.com/sahib/brig/gateway/db"
 	"github.com/sahib/brig/util"
 	log "github.com/sirupsen/logrus"
@@ -27,7 +26,7 @@ func NewGetHandler(s *State) *GetHandler {
 	return &GetHandler{State: s}
 }
 
-func mimeTypeFromStream(stream mio.Stream) (io.ReadSeeker, string) {
+func mimeTypeFromStream(stream io.ReadSeeker) (io.ReadSeeker, string) {
 	hdr, newStream, err := util.PeekHeader(stream, 512)
 	if err != nil {
 		return stream, "application/octet-stream"
@@ -218,13 +217,17 @@ func (gh *GetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		stream, err := gh.fs.Cat(nodePath)
+		// Go through a file handle, so big files are streamed
+		// with read-ahead over the page cache.
+		stream, err := gh.fs.Open(nodePath)
 		if err != nil {
 			log.Errorf("gateway: failed to stream %s: %v", nodePath, err)
 			http.Error(w, "failed to stream", http.StatusInternalServerError)
 			return
 		}
 
+		defer stream.Close()
+
 		prefixStream, mimeType := mimeTypeFromStream(stream)
 		hdr.Set("Content-Type", mimeType)
 		hdr.Set("Content-Length", strconv.FormatUint(info.Size, 10))
@@ -0,0 +1,83 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/sahib/brig/gateway/db"
+	"github.com/sahib/brig/util/qrcode"
+	log "github.com/sirupsen/logrus"
+)
+
+// qrTemplate is small enough to not warrant a file in templates/.
+var qrTemplate = template.Must(template.New("qr").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>brig: {{.Name}}</title>
+  <style>
+    body { font-family: sans-serif; text-align: center; margin: 1em; }
+    img { width: 100%; max-width: 400px; image-rendering: pixelated; }
+    code { word-break: break-all; }
+  </style>
+</head>
+<body>
+  <h2>{{.Name}}</h2>
+  <img src="data:image/png;base64,{{.Image}}" alt="QR code with name and fingerprint">
+  <p><code>{{.Payload}}</code></p>
+  <p>Scan this code and use its content as arguments for »brig remote add«.</p>
+</body>
+</html>
+`))
+
+// QRHandler implements http.Handler.
+// It serves a page with a QR code of our name and fingerprint.
+type QRHandler struct {
+	*State
+}
+
+// NewQRHandler returns a new QRHandler.
+func NewQRHandler(s *State) *QRHandler {
+	return &QRHandler{State: s}
+}
+
+func (qh *QRHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkRights(w, r, db.RightRemotesView) {
+		return
+	}
+
+	self, err := qh.rapi.Self()
+	if err != nil {
+		jsonifyErrf(w, http.StatusBadRequest, "failed to get self")
+		return
+	}
+
+	// Same format as »brig whoami --qr«:
+	payload := fmt.Sprintf("%s %s", self.Name, self.Fingerprint)
+	code, err := qrcode.Encode([]byte(payload), qrcode.LevelM)
+	if err != nil {
+		jsonifyErrf(w, http.StatusInternalServerError, "failed to encode qr code: %v", err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := code.WritePNG(buf, 8); err != nil {
+		jsonifyErrf(w, http.StatusInternalServerError, "failed to render qr code")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = qrTemplate.Execute(w, map[string]interface{}{
+		"Name":    self.Name,
+		"Payload": payload,
+		"Image":   base64.StdEncoding.EncodeToString(buf.Bytes()),
+	})
+
+	if err != nil {
+		log.Warningf("failed to execute qr template: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package endpoints
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQREndpoint(t *testing.T) {
+	withState(t, func(s *testState) {
+		resp := s.mustRun(
+			t,
+			NewQRHandler(s.State),
+			"GET",
+			"http://localhost:5000/qr",
+			nil,
+		)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+
+		data, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(data), "ali alisfingerprint")
+		require.Contains(t, string(data), "data:image/png;base64,")
+	})
+}
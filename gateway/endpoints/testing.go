@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/mio/pagecache/mdcache"
 	"github.com/sahib/brig/defaults"
 	"github.com/sahib/brig/gateway/db"
 	"github.com/sahib/brig/gateway/remotesapi"
@@ -39,6 +40,11 @@ func withState(t *testing.T, fn func(state *testState)) {
 	cfg, err := config.Open(nil, defaults.Defaults, config.StrictnessPanic)
 	require.Nil(t, err)
 
+	pageCache, err := mdcache.New(mdcache.Options{
+		MaxMemoryUsage: 64 * 1024 * 1024,
+	})
+	require.Nil(t, err)
+
 	fs, err := catfs.NewFilesystem(
 		catfs.NewMemFsBackend(),
 		filepath.Join(tmpDir, "fs"),
@@ -47,6 +53,7 @@ func withState(t *testing.T, fn func(state *testState)) {
 		cfg.Section("fs"),
 		nil,
 		nil,
+		pageCache,
 	)
 	require.Nil(t, err)
 
@@ -14,6 +14,7 @@ type Folder struct {
 	Folder           string `json:"folder"`
 	ReadOnly         bool   `json:"read_only"`
 	ConflictStrategy string `json:"conflict_strategy"`
+	LockPolicy       string `json:"lock_policy"`
 }
 
 // Remote is a the result of List and Get.
@@ -27,6 +28,7 @@ type Remote struct {
 	AcceptPush        bool      `json:"accept_push"`
 	ConflictStrategy  string    `json:"conflict_strategy"`
 	LastSeen          time.Time `json:"last_seen"`
+	Device            bool      `json:"device"`
 }
 
 // Identity describes our own repository identity.
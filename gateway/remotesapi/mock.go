@@ -108,8 +108,8 @@ func (m *Mock) Sync(name string) error {
 func dummyNode(path, user string, isDir bool) catfs.StatInfo {
 	return catfs.StatInfo{
 		BackendHash: h.EmptyBackendHash.Clone(),
-		TreeHash:    h.EmptyInternalHash.Clone(),
-		ContentHash: h.EmptyInternalHash.Clone(),
+		TreeHash:    h.EmptyInternalHash().Clone(),
+		ContentHash: h.EmptyInternalHash().Clone(),
 		Depth:       strings.Count(path, "/") - 1,
 		Inode:       7,
 		IsDir:       isDir,
@@ -203,6 +203,9 @@ func (gw *Gateway) Start() {
 		// The client will probably call /ls then.
 		router.PathPrefix("/events").Handler(needsAuth(gw.evHdl)).Methods("GET")
 
+		// A page with our fingerprint as QR code; meant for phones.
+		router.Handle("/qr", needsAuth(endpoints.NewQRHandler(gw.state))).Methods("GET")
+
 		// Special case: index.html gets a csrf token:
 		idxHdl := endpoints.NewIndexHandler(gw.state)
 		router.Handle("/", idxHdl).Methods("GET")
@@ -263,6 +266,12 @@ func (gw *Gateway) UserDatabase() *db.UserDatabase {
 	return gw.state.UserDatabase()
 }
 
+// Notify sends `msg` to all clients of the /events websocket.
+// The web UI ignores messages it does not know.
+func (gw *Gateway) Notify(ctx context.Context, msg string) error {
+	return gw.evHdl.Notify(ctx, msg)
+}
+
 // Close the gateway and clean up all open resouces.
 func (gw *Gateway) Close() error {
 	return gw.state.UserDatabase().Close()
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/mio/pagecache/mdcache"
 	"github.com/sahib/brig/defaults"
 	"github.com/sahib/brig/gateway/remotesapi"
 	"github.com/sahib/config"
@@ -30,6 +31,11 @@ func withBasicGateway(t *testing.T, fn func(gw *Gateway, fs *catfs.FS)) {
 	cfg, err := config.Open(nil, defaults.Defaults, config.StrictnessPanic)
 	require.Nil(t, err)
 
+	pageCache, err := mdcache.New(mdcache.Options{
+		MaxMemoryUsage: 64 * 1024 * 1024,
+	})
+	require.Nil(t, err)
+
 	fs, err := catfs.NewFilesystem(
 		catfs.NewMemFsBackend(),
 		filepath.Join(tmpDir, "fs"),
@@ -38,6 +44,7 @@ func withBasicGateway(t *testing.T, fn func(gw *Gateway, fs *catfs.FS)) {
 		cfg.Section("fs"),
 		nil,
 		nil,
+		pageCache,
 	)
 
 	require.Nil(t, err)
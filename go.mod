@@ -46,7 +46,6 @@ require (
 	github.com/posener/wstest v0.0.0-20180217133618-28272a7ea048
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/sahib/config v0.2.0
-	github.com/sdemontfort/go-mimemagic v0.0.0-20150708072242-d026a5785116
 	github.com/sirupsen/logrus v1.7.0
 	github.com/stretchr/testify v1.6.1
 	github.com/tinylib/msgp v1.1.5 // indirect
@@ -7,6 +7,7 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 
@@ -38,6 +39,14 @@ type PrivDecrypter interface {
 // against the fingerprint we store of it.
 type RemoteChecker func(remotePubKey []byte) error
 
+// RevocationChecker tells if a public key was revoked.
+type RevocationChecker interface {
+	IsRevoked(pubKey []byte) bool
+}
+
+// ErrRevoked is returned when the remote authenticates with a revoked key.
+var ErrRevoked = errors.New("remote uses a revoked key")
+
 // AuthReadWriter acts as a layer on top of a normal io.ReadWriteCloser
 // that adds authentication of the communication partners.
 // It does this by employing the following protocol:
@@ -82,6 +91,9 @@ type AuthReadWriter struct {
 	// Checker callback to authenticate remote's public key
 	remoteChecker RemoteChecker
 
+	// revocations are checked before remoteChecker, if set.
+	revocations RevocationChecker
+
 	// encrypted read writer
 	cryptedRW io.ReadWriter
 
@@ -117,6 +129,12 @@ func NewAuthReadWriter(
 	}
 }
 
+// SetRevocationChecker makes the authentication fail for remotes that
+// use a key revoked according to `rc`. Must be called before Trigger.
+func (ath *AuthReadWriter) SetRevocationChecker(rc RevocationChecker) {
+	ath.revocations = rc
+}
+
 // IsAuthorised will return true if the partner was successfully authenticated.
 // It will return false if no call to Read() or Write() was made.
 func (ath *AuthReadWriter) IsAuthorised() bool {
@@ -249,6 +267,11 @@ func (ath *AuthReadWriter) runAuth() error {
 		return err
 	}
 
+	// A revoked key is refused, even if we still know the fingerprint:
+	if ath.revocations != nil && ath.revocations.IsRevoked(remotePubKey) {
+		return ErrRevoked
+	}
+
 	// Check if the hash of the remote pub key matches the fingerprint we have.
 	// This is the single most important assertion, because we will accept any
 	// valid keypair otherwise.
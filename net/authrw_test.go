@@ -208,3 +208,40 @@ func TestAuthProcess(t *testing.T) {
 		})
 	}
 }
+
+type dummyRevocations [][]byte
+
+func (dr dummyRevocations) IsRevoked(pubKey []byte) bool {
+	for _, revoked := range dr {
+		if bytes.Equal(revoked, pubKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestAuthRevoked(t *testing.T) {
+	privAli, pubAli := createKeyPair(t, 1024)
+	privBob, pubBob := createKeyPair(t, 1024)
+
+	withLoopbackConnection(t, func(a, b net.Conn) {
+		acceptAll := func(pubKey []byte) error {
+			return nil
+		}
+
+		authAli := NewAuthReadWriter(a, DummyPrivKey(privAli), pubAli, "ali", acceptAll)
+		authBob := NewAuthReadWriter(b, DummyPrivKey(privBob), pubBob, "bob", acceptAll)
+
+		// Bob revoked ali's key:
+		authBob.SetRevocationChecker(dummyRevocations{pubAli})
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- authAli.Trigger()
+		}()
+
+		require.Equal(t, ErrRevoked, authBob.Trigger())
+		require.Error(t, <-errs)
+	})
+}
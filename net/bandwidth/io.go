@@ -0,0 +1,105 @@
+package bandwidth
+
+import (
+	"io"
+	"net"
+)
+
+// stage is one limiter that traffic passes.
+// If throttle is false, the traffic is only measured.
+type stage struct {
+	lim      *Limiter
+	throttle bool
+}
+
+type stages []stage
+
+// chunkSize is the smallest chunk size of all throttling stages,
+// or 0 if none of them limits `dir`.
+func (ss stages) chunkSize(dir direction) int {
+	size := 0
+	for _, s := range ss {
+		if !s.throttle {
+			continue
+		}
+
+		if chunk := s.lim.chunkSize(dir); chunk > 0 && (size == 0 || chunk < size) {
+			size = chunk
+		}
+	}
+
+	return size
+}
+
+func (ss stages) pass(dir direction, n int) {
+	if n <= 0 {
+		return
+	}
+
+	for _, s := range ss {
+		if s.throttle {
+			s.lim.wait(dir, n)
+		}
+
+		s.lim.meter(dir).Add(n)
+	}
+}
+
+// read reads from `r` and waits afterwards until the data may pass.
+// Waiting after reading is the best we can do for the receiving side,
+// since we do not know in advance how much data will arrive.
+func (ss stages) read(r io.Reader, dir direction, p []byte) (int, error) {
+	if chunk := ss.chunkSize(dir); chunk > 0 && len(p) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := r.Read(p)
+	ss.pass(dir, n)
+	return n, err
+}
+
+// write splits `p` into chunks and waits before writing each of them.
+func (ss stages) write(w io.Writer, dir direction, p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := ss.chunkSize(dir)
+		if chunk == 0 || chunk > len(p) {
+			chunk = len(p)
+		}
+
+		ss.pass(dir, chunk)
+		n, err := w.Write(p[:chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[chunk:]
+	}
+
+	return written, nil
+}
+
+type limitedReader struct {
+	r   io.Reader
+	dir direction
+	ss  stages
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	return lr.ss.read(lr.r, lr.dir, p)
+}
+
+// limitedConn limits reading as download and writing as upload.
+type limitedConn struct {
+	net.Conn
+	ss stages
+}
+
+func (lc *limitedConn) Read(p []byte) (int, error) {
+	return lc.ss.read(lc.Conn, download, p)
+}
+
+func (lc *limitedConn) Write(p []byte) (int, error) {
+	return lc.ss.write(lc.Conn, upload, p)
+}
@@ -0,0 +1,223 @@
+package bandwidth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxChunk is the largest amount of bytes we let pass at once.
+	// It is also the burst of the underlying token buckets.
+	maxChunk = 32 * 1024
+
+	// minChunk keeps very low limits from doing many tiny reads.
+	minChunk = 512
+
+	// meterWindow is the number of seconds the throughput is averaged over.
+	meterWindow = 5
+
+	// refreshInterval is how often the limits are looked up again.
+	// This makes config changes and schedules take effect on open transfers.
+	refreshInterval = 5 * time.Second
+)
+
+// now is overwritten in tests.
+var now = time.Now
+
+// Meter measures the throughput of a stream of bytes.
+// It is safe to use from several go routines.
+type Meter struct {
+	mu      sync.Mutex
+	buckets [meterWindow]int64
+	seconds [meterWindow]int64
+	total   int64
+}
+
+// Add records that `n` bytes passed.
+func (m *Meter) Add(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sec := now().Unix()
+	idx := sec % meterWindow
+	if m.seconds[idx] != sec {
+		m.seconds[idx] = sec
+		m.buckets[idx] = 0
+	}
+
+	m.buckets[idx] += int64(n)
+	m.total += int64(n)
+}
+
+// Rate returns the average bytes per second of the last few seconds.
+func (m *Meter) Rate() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sec := now().Unix()
+	sum := int64(0)
+	for idx := range m.buckets {
+		// Only count complete seconds, the current one is still running.
+		if age := sec - m.seconds[idx]; age > 0 && age <= meterWindow {
+			sum += m.buckets[idx]
+		}
+	}
+
+	return sum / meterWindow
+}
+
+// Total returns the number of bytes that passed overall.
+func (m *Meter) Total() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.total
+}
+
+// direction is either upload or download.
+type direction int
+
+const (
+	upload direction = iota
+	download
+)
+
+func toRateLimit(rateBytes int64) rate.Limit {
+	if rateBytes <= 0 {
+		return rate.Inf
+	}
+
+	return rate.Limit(rateBytes)
+}
+
+// Limiter limits and measures traffic in both directions.
+// The limits are fetched regularly from a callback,
+// so they can change while a transfer is running.
+type Limiter struct {
+	name     string
+	limitsFn func() Limits
+
+	mu        sync.Mutex
+	limits    Limits
+	checkedAt time.Time
+
+	up, down         *rate.Limiter
+	upMeter, dnMeter Meter
+}
+
+func newLimiter(name string, limitsFn func() Limits) *Limiter {
+	lim := &Limiter{
+		name:     name,
+		limitsFn: limitsFn,
+		up:       rate.NewLimiter(rate.Inf, maxChunk),
+		down:     rate.NewLimiter(rate.Inf, maxChunk),
+	}
+
+	lim.refresh()
+	return lim
+}
+
+func (lim *Limiter) refresh() Limits {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	ts := now()
+	if !lim.checkedAt.IsZero() && ts.Sub(lim.checkedAt) < refreshInterval {
+		return lim.limits
+	}
+
+	limits := lim.limitsFn()
+	if limits != lim.limits || lim.checkedAt.IsZero() {
+		lim.up.SetLimitAt(ts, toRateLimit(limits.Upload))
+		lim.down.SetLimitAt(ts, toRateLimit(limits.Download))
+		lim.limits = limits
+	}
+
+	lim.checkedAt = ts
+	return limits
+}
+
+func (lim *Limiter) invalidate() {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.checkedAt = time.Time{}
+}
+
+// Limits returns the limits that currently apply.
+func (lim *Limiter) Limits() Limits {
+	return lim.refresh()
+}
+
+// chunkSize returns how many bytes may pass at once in `dir`.
+// If the direction is not limited, 0 is returned.
+func (lim *Limiter) chunkSize(dir direction) int {
+	limits := lim.refresh()
+	rateBytes := limits.Upload
+	if dir == download {
+		rateBytes = limits.Download
+	}
+
+	switch {
+	case rateBytes <= 0:
+		return 0
+	case rateBytes < minChunk:
+		return minChunk
+	case rateBytes < maxChunk:
+		return int(rateBytes)
+	default:
+		return maxChunk
+	}
+}
+
+func (lim *Limiter) wait(dir direction, n int) {
+	bucket := lim.up
+	if dir == download {
+		bucket = lim.down
+	}
+
+	// n is never bigger than the burst, so this can not fail
+	// with a background context.
+	bucket.WaitN(context.Background(), n)
+}
+
+func (lim *Limiter) meter(dir direction) *Meter {
+	if dir == download {
+		return &lim.dnMeter
+	}
+
+	return &lim.upMeter
+}
+
+// Stats is a snapshot of the traffic that went through a limiter.
+type Stats struct {
+	// Name is the name of the remote, or empty for the global limiter.
+	Name string
+
+	// Limits that apply right now.
+	Limits Limits
+
+	// Upload and Download are the current rates in bytes per second.
+	Upload   int64
+	Download int64
+
+	// UploadTotal and DownloadTotal are the number of bytes
+	// that were transferred overall.
+	UploadTotal   int64
+	DownloadTotal int64
+}
+
+// Stats returns the current limits and throughput.
+func (lim *Limiter) Stats() Stats {
+	return Stats{
+		Name:          lim.name,
+		Limits:        lim.Limits(),
+		Upload:        lim.upMeter.Rate(),
+		Download:      lim.dnMeter.Rate(),
+		UploadTotal:   lim.upMeter.Total(),
+		DownloadTotal: lim.dnMeter.Total(),
+	}
+}
@@ -0,0 +1,137 @@
+// Package bandwidth limits and measures the traffic of brig.
+//
+// There is one global limiter, whose limits may change during the day
+// according to a schedule. Remotes may override the global limits for
+// their own connections. Traffic of such remotes is still measured
+// by the global limiter, but not throttled by it.
+package bandwidth
+
+import (
+	"io"
+	"net"
+	"sort"
+	"sync"
+)
+
+// OverrideFunc returns the limits for the remote `name`,
+// if they differ from the global ones. Otherwise ok is false.
+type OverrideFunc func(name string) (limits Limits, ok bool)
+
+// Manager hands out limiters for the global traffic and for single remotes.
+// A nil Manager is valid and does not limit anything.
+type Manager struct {
+	global   *Limiter
+	override OverrideFunc
+
+	mu      sync.Mutex
+	remotes map[string]*Limiter
+}
+
+// NewManager returns a new Manager. `global` is called regularly
+// to get the global limits; `override` may be nil.
+func NewManager(global func() Limits, override OverrideFunc) *Manager {
+	if override == nil {
+		override = func(string) (Limits, bool) { return Limits{}, false }
+	}
+
+	return &Manager{
+		global:   newLimiter("", global),
+		override: override,
+		remotes:  make(map[string]*Limiter),
+	}
+}
+
+func (m *Manager) remote(name string) *Limiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lim, ok := m.remotes[name]
+	if !ok {
+		lim = newLimiter(name, func() Limits {
+			if limits, ok := m.override(name); ok {
+				return limits
+			}
+
+			// Not used for throttling, but good to know for the stats:
+			return m.global.Limits()
+		})
+		m.remotes[name] = lim
+	}
+
+	return lim
+}
+
+func (m *Manager) stagesFor(name string) stages {
+	if name == "" {
+		return stages{{lim: m.global, throttle: true}}
+	}
+
+	// Decide once per connection; connections do not live very long.
+	_, hasOverride := m.override(name)
+	return stages{
+		{lim: m.remote(name), throttle: hasOverride},
+		{lim: m.global, throttle: !hasOverride},
+	}
+}
+
+// Conn limits `conn` with the limits of the remote `name`.
+// Reading counts as download, writing as upload.
+// If `name` is empty, only the global limits apply.
+func (m *Manager) Conn(conn net.Conn, name string) net.Conn {
+	if m == nil {
+		return conn
+	}
+
+	return &limitedConn{Conn: conn, ss: m.stagesFor(name)}
+}
+
+// DownloadReader limits reading from `r` with the global download limit.
+func (m *Manager) DownloadReader(r io.Reader) io.Reader {
+	if m == nil {
+		return r
+	}
+
+	return &limitedReader{r: r, dir: download, ss: m.stagesFor("")}
+}
+
+// Refresh makes all limiters look up their limits again on next use.
+// Call it when the configured limits changed.
+func (m *Manager) Refresh() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.global.invalidate()
+	for _, lim := range m.remotes {
+		lim.invalidate()
+	}
+}
+
+// Stats returns the stats of the global limiter and of all
+// remotes that had traffic so far, sorted by their name.
+func (m *Manager) Stats() (Stats, []Stats) {
+	if m == nil {
+		return Stats{}, nil
+	}
+
+	m.mu.Lock()
+	remotes := make([]*Limiter, 0, len(m.remotes))
+	for _, lim := range m.remotes {
+		remotes = append(remotes, lim)
+	}
+	m.mu.Unlock()
+
+	remoteStats := make([]Stats, 0, len(remotes))
+	for _, lim := range remotes {
+		remoteStats = append(remoteStats, lim.Stats())
+	}
+
+	sort.Slice(remoteStats, func(i, j int) bool {
+		return remoteStats[i].Name < remoteStats[j].Name
+	})
+
+	return m.global.Stats(), remoteStats
+}
@@ -0,0 +1,99 @@
+package bandwidth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter(t *testing.T) {
+	defer func() { now = time.Now }()
+
+	ts := time.Unix(1000, 0)
+	now = func() time.Time { return ts }
+
+	m := &Meter{}
+	for sec := 0; sec < meterWindow; sec++ {
+		m.Add(100)
+		m.Add(100)
+		ts = ts.Add(time.Second)
+	}
+
+	require.Equal(t, int64(200), m.Rate())
+	require.Equal(t, int64(200*meterWindow), m.Total())
+
+	// Old traffic is forgotten after a while:
+	ts = ts.Add(2 * meterWindow * time.Second)
+	require.Equal(t, int64(0), m.Rate())
+	require.Equal(t, int64(200*meterWindow), m.Total())
+}
+
+func TestNilManager(t *testing.T) {
+	var m *Manager
+	r := bytes.NewReader([]byte("hello"))
+	require.Equal(t, r, m.DownloadReader(r))
+
+	global, remotes := m.Stats()
+	require.Equal(t, Stats{}, global)
+	require.Empty(t, remotes)
+}
+
+func TestDownloadReaderLimits(t *testing.T) {
+	m := NewManager(func() Limits {
+		return Limits{Download: 64 * 1024}
+	}, nil)
+
+	// The first 32K pass with the initial burst, the rest takes ~0.5s.
+	data := make([]byte, 64*1024)
+	start := time.Now()
+	read, err := ioutil.ReadAll(m.DownloadReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	require.Equal(t, data, read)
+	require.True(t, time.Since(start) > 400*time.Millisecond)
+
+	global, _ := m.Stats()
+	require.Equal(t, int64(len(data)), global.DownloadTotal)
+	require.Equal(t, int64(0), global.UploadTotal)
+	require.Equal(t, int64(64*1024), global.Limits.Download)
+}
+
+func TestConnOverride(t *testing.T) {
+	m := NewManager(func() Limits {
+		// So slow that the test would time out if it applied:
+		return Limits{Upload: 1, Download: 1}
+	}, func(name string) (Limits, bool) {
+		return Unlimited, name == "bob"
+	})
+
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	data := make([]byte, 128*1024)
+	go func() {
+		m.Conn(a, "bob").Write(data)
+	}()
+
+	read := make([]byte, len(data))
+	conn := m.Conn(b, "bob")
+	for n := 0; n < len(read); {
+		nread, err := conn.Read(read[n:])
+		require.NoError(t, err)
+		n += nread
+	}
+
+	global, remotes := m.Stats()
+	require.Len(t, remotes, 1)
+	require.Equal(t, "bob", remotes[0].Name)
+	require.Equal(t, Unlimited, remotes[0].Limits)
+	require.Equal(t, int64(len(data)), remotes[0].UploadTotal)
+	require.Equal(t, int64(len(data)), remotes[0].DownloadTotal)
+
+	// The global limiter still sees the traffic:
+	require.Equal(t, int64(len(data)), global.UploadTotal)
+	require.Equal(t, int64(len(data)), global.DownloadTotal)
+}
@@ -0,0 +1,164 @@
+package bandwidth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Limits are the allowed rates in bytes per second.
+// A value of zero (or less) means unlimited.
+type Limits struct {
+	Upload   int64
+	Download int64
+}
+
+// Unlimited does not limit anything.
+var Unlimited = Limits{}
+
+func formatRate(rate int64) string {
+	if rate <= 0 {
+		return "unlimited"
+	}
+
+	return humanize.Bytes(uint64(rate)) + "/s"
+}
+
+func (l Limits) String() string {
+	return fmt.Sprintf("up %s, down %s", formatRate(l.Upload), formatRate(l.Download))
+}
+
+// ParseRate parses a single human readable rate like »1MB«.
+// An empty string or »0« means unlimited.
+func ParseRate(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "/s")
+	if s == "" {
+		return 0, nil
+	}
+
+	rate, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(rate), nil
+}
+
+// ParseLimits parses a spec of the form »<upload>/<download>«,
+// for example »1MB/256KB«. Zero means unlimited for either side.
+func ParseLimits(spec string) (Limits, error) {
+	split := strings.Split(spec, "/")
+	if len(split) != 2 {
+		return Limits{}, fmt.Errorf("bad limits »%s«: need <upload>/<download>", spec)
+	}
+
+	up, err := ParseRate(split[0])
+	if err != nil {
+		return Limits{}, fmt.Errorf("bad upload rate: %v", err)
+	}
+
+	down, err := ParseRate(split[1])
+	if err != nil {
+		return Limits{}, fmt.Errorf("bad download rate: %v", err)
+	}
+
+	return Limits{Upload: up, Download: down}, nil
+}
+
+// Window is a time range of the day in which other limits apply.
+type Window struct {
+	// From and To are minutes since midnight.
+	// If To is before From, the window wraps around midnight.
+	// If both are equal, the window spans the whole day.
+	From, To int
+	Limits   Limits
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("bad time of day »%s«: need HH:MM", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseWindow parses a window of the form »HH:MM-HH:MM <upload>/<download>«,
+// for example »22:00-06:00 0/0« for no limits during the night.
+func ParseWindow(spec string) (Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 2 {
+		return Window{}, fmt.Errorf("bad window »%s«: need »HH:MM-HH:MM <upload>/<download>«", spec)
+	}
+
+	span := strings.Split(fields[0], "-")
+	if len(span) != 2 {
+		return Window{}, fmt.Errorf("bad time range »%s«", fields[0])
+	}
+
+	from, err := parseClock(span[0])
+	if err != nil {
+		return Window{}, err
+	}
+
+	to, err := parseClock(span[1])
+	if err != nil {
+		return Window{}, err
+	}
+
+	limits, err := ParseLimits(fields[1])
+	if err != nil {
+		return Window{}, err
+	}
+
+	return Window{From: from, To: to, Limits: limits}, nil
+}
+
+// Contains returns true if `t` (in its local time zone) is inside the window.
+func (w Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case w.From == w.To:
+		return true
+	case w.From < w.To:
+		return w.From <= minute && minute < w.To
+	default:
+		return minute >= w.From || minute < w.To
+	}
+}
+
+// Schedule is a list of windows. The first window that matches wins.
+type Schedule []Window
+
+// ParseSchedule parses every entry of `specs` with ParseWindow.
+func ParseSchedule(specs []string) (Schedule, error) {
+	schedule := Schedule{}
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+
+		window, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		schedule = append(schedule, window)
+	}
+
+	return schedule, nil
+}
+
+// At returns the limits that apply at `t`.
+// If no window matches, `base` is returned.
+func (s Schedule) At(t time.Time, base Limits) Limits {
+	for _, window := range s {
+		if window.Contains(t) {
+			return window.Limits
+		}
+	}
+
+	return base
+}
@@ -0,0 +1,76 @@
+package bandwidth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func clock(hour, minute int) time.Time {
+	return time.Date(2020, 1, 1, hour, minute, 0, 0, time.Local)
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("1MB/256KB")
+	require.NoError(t, err)
+	require.Equal(t, Limits{Upload: 1000 * 1000, Download: 256 * 1000}, limits)
+
+	limits, err = ParseLimits("0/2MiB")
+	require.NoError(t, err)
+	require.Equal(t, Limits{Upload: 0, Download: 2 * 1024 * 1024}, limits)
+
+	for _, bad := range []string{"", "1MB", "1MB/2MB/3MB", "x/1MB", "1MB/x"} {
+		_, err := ParseLimits(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	window, err := ParseWindow("22:00-06:30 0/1MB")
+	require.NoError(t, err)
+	require.Equal(t, 22*60, window.From)
+	require.Equal(t, 6*60+30, window.To)
+	require.Equal(t, Limits{Download: 1000 * 1000}, window.Limits)
+
+	for _, bad := range []string{"", "22:00-06:00", "22:00 0/0", "25:00-06:00 0/0", "22:00-06:00 0"} {
+		_, err := ParseWindow(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	day := Window{From: 8 * 60, To: 18 * 60}
+	require.False(t, day.Contains(clock(7, 59)))
+	require.True(t, day.Contains(clock(8, 0)))
+	require.True(t, day.Contains(clock(17, 59)))
+	require.False(t, day.Contains(clock(18, 0)))
+
+	night := Window{From: 22 * 60, To: 6 * 60}
+	require.True(t, night.Contains(clock(23, 0)))
+	require.True(t, night.Contains(clock(0, 0)))
+	require.True(t, night.Contains(clock(5, 59)))
+	require.False(t, night.Contains(clock(6, 0)))
+	require.False(t, night.Contains(clock(12, 0)))
+
+	always := Window{From: 0, To: 0}
+	require.True(t, always.Contains(clock(12, 0)))
+}
+
+func TestScheduleAt(t *testing.T) {
+	schedule, err := ParseSchedule([]string{
+		"08:00-18:00 1MB/2MB",
+		"",
+		"22:00-06:00 0/0",
+	})
+	require.NoError(t, err)
+	require.Len(t, schedule, 2)
+
+	base := Limits{Upload: 10, Download: 20}
+	require.Equal(t, Limits{Upload: 1000 * 1000, Download: 2000 * 1000}, schedule.At(clock(9, 0), base))
+	require.Equal(t, Unlimited, schedule.At(clock(23, 0), base))
+	require.Equal(t, base, schedule.At(clock(20, 0), base))
+
+	_, err = ParseSchedule([]string{"08:00-18:00 1MB/2MB", "nonsense"})
+	require.Error(t, err)
+}
@@ -10,6 +10,198 @@ import (
 	server "zombiezen.com/go/capnproto2/server"
 )
 
+type Lock struct{ capnp.Struct }
+
+// Lock_TypeID is the unique identifier for the type Lock.
+const Lock_TypeID = 0xfe457b470c5b9420
+
+func NewLock(s *capnp.Segment) (Lock, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 3})
+	return Lock{st}, err
+}
+
+func NewRootLock(s *capnp.Segment) (Lock, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 3})
+	return Lock{st}, err
+}
+
+func ReadRootLock(msg *capnp.Message) (Lock, error) {
+	root, err := msg.RootPtr()
+	return Lock{root.Struct()}, err
+}
+
+func (s Lock) String() string {
+	str, _ := text.Marshal(0xfe457b470c5b9420, s.Struct)
+	return str
+}
+
+func (s Lock) Path() (string, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.Text(), err
+}
+
+func (s Lock) HasPath() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s Lock) PathBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s Lock) SetPath(v string) error {
+	return s.Struct.SetText(0, v)
+}
+
+func (s Lock) Owner() (string, error) {
+	p, err := s.Struct.Ptr(1)
+	return p.Text(), err
+}
+
+func (s Lock) HasOwner() bool {
+	p, err := s.Struct.Ptr(1)
+	return p.IsValid() || err != nil
+}
+
+func (s Lock) OwnerBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(1)
+	return p.TextBytes(), err
+}
+
+func (s Lock) SetOwner(v string) error {
+	return s.Struct.SetText(1, v)
+}
+
+func (s Lock) Since() (string, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.Text(), err
+}
+
+func (s Lock) HasSince() bool {
+	p, err := s.Struct.Ptr(2)
+	return p.IsValid() || err != nil
+}
+
+func (s Lock) SinceBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.TextBytes(), err
+}
+
+func (s Lock) SetSince(v string) error {
+	return s.Struct.SetText(2, v)
+}
+
+// Lock_List is a list of Lock.
+type Lock_List struct{ capnp.List }
+
+// NewLock creates a new list of Lock.
+func NewLock_List(s *capnp.Segment, sz int32) (Lock_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 3}, sz)
+	return Lock_List{l}, err
+}
+
+func (s Lock_List) At(i int) Lock { return Lock{s.List.Struct(i)} }
+
+func (s Lock_List) Set(i int, v Lock) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s Lock_List) String() string {
+	str, _ := text.MarshalList(0xfe457b470c5b9420, s.List)
+	return str
+}
+
+// Lock_Promise is a wrapper for a Lock promised by a client call.
+type Lock_Promise struct{ *capnp.Pipeline }
+
+func (p Lock_Promise) Struct() (Lock, error) {
+	s, err := p.Pipeline.Struct()
+	return Lock{s}, err
+}
+
+type Dict struct{ capnp.Struct }
+
+// Dict_TypeID is the unique identifier for the type Dict.
+const Dict_TypeID = 0xe7ac6f8968588f61
+
+func NewDict(s *capnp.Segment) (Dict, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return Dict{st}, err
+}
+
+func NewRootDict(s *capnp.Segment) (Dict, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return Dict{st}, err
+}
+
+func ReadRootDict(msg *capnp.Message) (Dict, error) {
+	root, err := msg.RootPtr()
+	return Dict{root.Struct()}, err
+}
+
+func (s Dict) String() string {
+	str, _ := text.Marshal(0xe7ac6f8968588f61, s.Struct)
+	return str
+}
+
+func (s Dict) Folder() (string, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.Text(), err
+}
+
+func (s Dict) HasFolder() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s Dict) FolderBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s Dict) SetFolder(v string) error {
+	return s.Struct.SetText(0, v)
+}
+
+func (s Dict) Data() ([]byte, error) {
+	p, err := s.Struct.Ptr(1)
+	return []byte(p.Data()), err
+}
+
+func (s Dict) HasData() bool {
+	p, err := s.Struct.Ptr(1)
+	return p.IsValid() || err != nil
+}
+
+func (s Dict) SetData(v []byte) error {
+	return s.Struct.SetData(1, v)
+}
+
+// Dict_List is a list of Dict.
+type Dict_List struct{ capnp.List }
+
+// NewDict creates a new list of Dict.
+func NewDict_List(s *capnp.Segment, sz int32) (Dict_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2}, sz)
+	return Dict_List{l}, err
+}
+
+func (s Dict_List) At(i int) Dict { return Dict{s.List.Struct(i)} }
+
+func (s Dict_List) Set(i int, v Dict) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s Dict_List) String() string {
+	str, _ := text.MarshalList(0xe7ac6f8968588f61, s.List)
+	return str
+}
+
+// Dict_Promise is a wrapper for a Dict promised by a client call.
+type Dict_Promise struct{ *capnp.Pipeline }
+
+func (p Dict_Promise) Struct() (Dict, error) {
+	s, err := p.Pipeline.Struct()
+	return Dict{s}, err
+}
+
 type Sync struct{ Client capnp.Client }
 
 // Sync_TypeID is the unique identifier for the type Sync.
@@ -135,6 +327,46 @@ func (c Sync) FetchPatches(ctx context.Context, params func(Sync_fetchPatches_Pa
 	}
 	return Sync_fetchPatches_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
 }
+func (c Sync) FetchLocks(ctx context.Context, params func(Sync_fetchLocks_Params) error, opts ...capnp.CallOption) Sync_fetchLocks_Results_Promise {
+	if c.Client == nil {
+		return Sync_fetchLocks_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      6,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchLocks",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchLocks_Params{Struct: s}) }
+	}
+	return Sync_fetchLocks_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+func (c Sync) FetchDicts(ctx context.Context, params func(Sync_fetchDicts_Params) error, opts ...capnp.CallOption) Sync_fetchDicts_Results_Promise {
+	if c.Client == nil {
+		return Sync_fetchDicts_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      7,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchDicts",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchDicts_Params{Struct: s}) }
+	}
+	return Sync_fetchDicts_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
 
 type Sync_Server interface {
 	FetchStore(Sync_fetchStore) error
@@ -148,6 +380,10 @@ type Sync_Server interface {
 	Push(Sync_push) error
 
 	FetchPatches(Sync_fetchPatches) error
+
+	FetchLocks(Sync_fetchLocks) error
+
+	FetchDicts(Sync_fetchDicts) error
 }
 
 func Sync_ServerToClient(s Sync_Server) Sync {
@@ -157,7 +393,7 @@ func Sync_ServerToClient(s Sync_Server) Sync {
 
 func Sync_Methods(methods []server.Method, s Sync_Server) []server.Method {
 	if cap(methods) == 0 {
-		methods = make([]server.Method, 0, 6)
+		methods = make([]server.Method, 0, 8)
 	}
 
 	methods = append(methods, server.Method{
@@ -244,6 +480,34 @@ func Sync_Methods(methods []server.Method, s Sync_Server) []server.Method {
 		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
 	})
 
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      6,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchLocks",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := Sync_fetchLocks{c, opts, Sync_fetchLocks_Params{Struct: p}, Sync_fetchLocks_Results{Struct: r}}
+			return s.FetchLocks(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      7,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchDicts",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := Sync_fetchDicts{c, opts, Sync_fetchDicts_Params{Struct: p}, Sync_fetchDicts_Results{Struct: r}}
+			return s.FetchDicts(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
 	return methods
 }
 
@@ -295,6 +559,22 @@ type Sync_fetchPatches struct {
 	Results Sync_fetchPatches_Results
 }
 
+// Sync_fetchLocks holds the arguments for a server call to Sync.fetchLocks.
+type Sync_fetchLocks struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  Sync_fetchLocks_Params
+	Results Sync_fetchLocks_Results
+}
+
+// Sync_fetchDicts holds the arguments for a server call to Sync.fetchDicts.
+type Sync_fetchDicts struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  Sync_fetchDicts_Params
+	Results Sync_fetchDicts_Results
+}
+
 type Sync_fetchStore_Params struct{ capnp.Struct }
 
 // Sync_fetchStore_Params_TypeID is the unique identifier for the type Sync_fetchStore_Params.
@@ -1027,120 +1307,390 @@ func (p Sync_fetchPatches_Results_Promise) Struct() (Sync_fetchPatches_Results,
 	return Sync_fetchPatches_Results{s}, err
 }
 
-type Meta struct{ Client capnp.Client }
-
-// Meta_TypeID is the unique identifier for the type Meta.
-const Meta_TypeID = 0xb02d2ba0578cc7ff
-
-func (c Meta) Ping(ctx context.Context, params func(Meta_ping_Params) error, opts ...capnp.CallOption) Meta_ping_Results_Promise {
-	if c.Client == nil {
-		return Meta_ping_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
-	}
-	call := &capnp.Call{
-		Ctx: ctx,
-		Method: capnp.Method{
-			InterfaceID:   0xb02d2ba0578cc7ff,
-			MethodID:      0,
-			InterfaceName: "net/capnp/api.capnp:Meta",
-			MethodName:    "ping",
-		},
-		Options: capnp.NewCallOptions(opts),
-	}
-	if params != nil {
-		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
-		call.ParamsFunc = func(s capnp.Struct) error { return params(Meta_ping_Params{Struct: s}) }
-	}
-	return Meta_ping_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
-}
-
-type Meta_Server interface {
-	Ping(Meta_ping) error
-}
-
-func Meta_ServerToClient(s Meta_Server) Meta {
-	c, _ := s.(server.Closer)
-	return Meta{Client: server.New(Meta_Methods(nil, s), c)}
-}
-
-func Meta_Methods(methods []server.Method, s Meta_Server) []server.Method {
-	if cap(methods) == 0 {
-		methods = make([]server.Method, 0, 1)
-	}
-
-	methods = append(methods, server.Method{
-		Method: capnp.Method{
-			InterfaceID:   0xb02d2ba0578cc7ff,
-			MethodID:      0,
-			InterfaceName: "net/capnp/api.capnp:Meta",
-			MethodName:    "ping",
-		},
-		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
-			call := Meta_ping{c, opts, Meta_ping_Params{Struct: p}, Meta_ping_Results{Struct: r}}
-			return s.Ping(call)
-		},
-		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
-	})
-
-	return methods
-}
-
-// Meta_ping holds the arguments for a server call to Meta.ping.
-type Meta_ping struct {
-	Ctx     context.Context
-	Options capnp.CallOptions
-	Params  Meta_ping_Params
-	Results Meta_ping_Results
-}
-
-type Meta_ping_Params struct{ capnp.Struct }
+type Sync_fetchLocks_Params struct{ capnp.Struct }
 
-// Meta_ping_Params_TypeID is the unique identifier for the type Meta_ping_Params.
-const Meta_ping_Params_TypeID = 0xe1a9fd466eca248c
+// Sync_fetchLocks_Params_TypeID is the unique identifier for the type Sync_fetchLocks_Params.
+const Sync_fetchLocks_Params_TypeID = 0x8ca34b7330c3e9ed
 
-func NewMeta_ping_Params(s *capnp.Segment) (Meta_ping_Params, error) {
+func NewSync_fetchLocks_Params(s *capnp.Segment) (Sync_fetchLocks_Params, error) {
 	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
-	return Meta_ping_Params{st}, err
+	return Sync_fetchLocks_Params{st}, err
 }
 
-func NewRootMeta_ping_Params(s *capnp.Segment) (Meta_ping_Params, error) {
+func NewRootSync_fetchLocks_Params(s *capnp.Segment) (Sync_fetchLocks_Params, error) {
 	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
-	return Meta_ping_Params{st}, err
+	return Sync_fetchLocks_Params{st}, err
 }
 
-func ReadRootMeta_ping_Params(msg *capnp.Message) (Meta_ping_Params, error) {
+func ReadRootSync_fetchLocks_Params(msg *capnp.Message) (Sync_fetchLocks_Params, error) {
 	root, err := msg.RootPtr()
-	return Meta_ping_Params{root.Struct()}, err
+	return Sync_fetchLocks_Params{root.Struct()}, err
 }
 
-func (s Meta_ping_Params) String() string {
-	str, _ := text.Marshal(0xe1a9fd466eca248c, s.Struct)
+func (s Sync_fetchLocks_Params) String() string {
+	str, _ := text.Marshal(0x8ca34b7330c3e9ed, s.Struct)
 	return str
 }
 
-// Meta_ping_Params_List is a list of Meta_ping_Params.
-type Meta_ping_Params_List struct{ capnp.List }
+// Sync_fetchLocks_Params_List is a list of Sync_fetchLocks_Params.
+type Sync_fetchLocks_Params_List struct{ capnp.List }
 
-// NewMeta_ping_Params creates a new list of Meta_ping_Params.
-func NewMeta_ping_Params_List(s *capnp.Segment, sz int32) (Meta_ping_Params_List, error) {
+// NewSync_fetchLocks_Params creates a new list of Sync_fetchLocks_Params.
+func NewSync_fetchLocks_Params_List(s *capnp.Segment, sz int32) (Sync_fetchLocks_Params_List, error) {
 	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
-	return Meta_ping_Params_List{l}, err
+	return Sync_fetchLocks_Params_List{l}, err
 }
 
-func (s Meta_ping_Params_List) At(i int) Meta_ping_Params { return Meta_ping_Params{s.List.Struct(i)} }
+func (s Sync_fetchLocks_Params_List) At(i int) Sync_fetchLocks_Params {
+	return Sync_fetchLocks_Params{s.List.Struct(i)}
+}
 
-func (s Meta_ping_Params_List) Set(i int, v Meta_ping_Params) error {
+func (s Sync_fetchLocks_Params_List) Set(i int, v Sync_fetchLocks_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
-func (s Meta_ping_Params_List) String() string {
-	str, _ := text.MarshalList(0xe1a9fd466eca248c, s.List)
+func (s Sync_fetchLocks_Params_List) String() string {
+	str, _ := text.MarshalList(0x8ca34b7330c3e9ed, s.List)
 	return str
 }
 
-// Meta_ping_Params_Promise is a wrapper for a Meta_ping_Params promised by a client call.
-type Meta_ping_Params_Promise struct{ *capnp.Pipeline }
+// Sync_fetchLocks_Params_Promise is a wrapper for a Sync_fetchLocks_Params promised by a client call.
+type Sync_fetchLocks_Params_Promise struct{ *capnp.Pipeline }
 
-func (p Meta_ping_Params_Promise) Struct() (Meta_ping_Params, error) {
+func (p Sync_fetchLocks_Params_Promise) Struct() (Sync_fetchLocks_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return Sync_fetchLocks_Params{s}, err
+}
+
+type Sync_fetchLocks_Results struct{ capnp.Struct }
+
+// Sync_fetchLocks_Results_TypeID is the unique identifier for the type Sync_fetchLocks_Results.
+const Sync_fetchLocks_Results_TypeID = 0xaa32afdfcc5507cc
+
+func NewSync_fetchLocks_Results(s *capnp.Segment) (Sync_fetchLocks_Results, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Sync_fetchLocks_Results{st}, err
+}
+
+func NewRootSync_fetchLocks_Results(s *capnp.Segment) (Sync_fetchLocks_Results, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Sync_fetchLocks_Results{st}, err
+}
+
+func ReadRootSync_fetchLocks_Results(msg *capnp.Message) (Sync_fetchLocks_Results, error) {
+	root, err := msg.RootPtr()
+	return Sync_fetchLocks_Results{root.Struct()}, err
+}
+
+func (s Sync_fetchLocks_Results) String() string {
+	str, _ := text.Marshal(0xaa32afdfcc5507cc, s.Struct)
+	return str
+}
+
+func (s Sync_fetchLocks_Results) Locks() (Lock_List, error) {
+	p, err := s.Struct.Ptr(0)
+	return Lock_List{List: p.List()}, err
+}
+
+func (s Sync_fetchLocks_Results) HasLocks() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s Sync_fetchLocks_Results) SetLocks(v Lock_List) error {
+	return s.Struct.SetPtr(0, v.List.ToPtr())
+}
+
+// NewLocks sets the locks field to a newly
+// allocated Lock_List, preferring placement in s's segment.
+func (s Sync_fetchLocks_Results) NewLocks(n int32) (Lock_List, error) {
+	l, err := NewLock_List(s.Struct.Segment(), n)
+	if err != nil {
+		return Lock_List{}, err
+	}
+	err = s.Struct.SetPtr(0, l.List.ToPtr())
+	return l, err
+}
+
+// Sync_fetchLocks_Results_List is a list of Sync_fetchLocks_Results.
+type Sync_fetchLocks_Results_List struct{ capnp.List }
+
+// NewSync_fetchLocks_Results creates a new list of Sync_fetchLocks_Results.
+func NewSync_fetchLocks_Results_List(s *capnp.Segment, sz int32) (Sync_fetchLocks_Results_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return Sync_fetchLocks_Results_List{l}, err
+}
+
+func (s Sync_fetchLocks_Results_List) At(i int) Sync_fetchLocks_Results {
+	return Sync_fetchLocks_Results{s.List.Struct(i)}
+}
+
+func (s Sync_fetchLocks_Results_List) Set(i int, v Sync_fetchLocks_Results) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s Sync_fetchLocks_Results_List) String() string {
+	str, _ := text.MarshalList(0xaa32afdfcc5507cc, s.List)
+	return str
+}
+
+// Sync_fetchLocks_Results_Promise is a wrapper for a Sync_fetchLocks_Results promised by a client call.
+type Sync_fetchLocks_Results_Promise struct{ *capnp.Pipeline }
+
+func (p Sync_fetchLocks_Results_Promise) Struct() (Sync_fetchLocks_Results, error) {
+	s, err := p.Pipeline.Struct()
+	return Sync_fetchLocks_Results{s}, err
+}
+
+type Sync_fetchDicts_Params struct{ capnp.Struct }
+
+// Sync_fetchDicts_Params_TypeID is the unique identifier for the type Sync_fetchDicts_Params.
+const Sync_fetchDicts_Params_TypeID = 0xa523dde9eb30e8b4
+
+func NewSync_fetchDicts_Params(s *capnp.Segment) (Sync_fetchDicts_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return Sync_fetchDicts_Params{st}, err
+}
+
+func NewRootSync_fetchDicts_Params(s *capnp.Segment) (Sync_fetchDicts_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return Sync_fetchDicts_Params{st}, err
+}
+
+func ReadRootSync_fetchDicts_Params(msg *capnp.Message) (Sync_fetchDicts_Params, error) {
+	root, err := msg.RootPtr()
+	return Sync_fetchDicts_Params{root.Struct()}, err
+}
+
+func (s Sync_fetchDicts_Params) String() string {
+	str, _ := text.Marshal(0xa523dde9eb30e8b4, s.Struct)
+	return str
+}
+
+// Sync_fetchDicts_Params_List is a list of Sync_fetchDicts_Params.
+type Sync_fetchDicts_Params_List struct{ capnp.List }
+
+// NewSync_fetchDicts_Params creates a new list of Sync_fetchDicts_Params.
+func NewSync_fetchDicts_Params_List(s *capnp.Segment, sz int32) (Sync_fetchDicts_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	return Sync_fetchDicts_Params_List{l}, err
+}
+
+func (s Sync_fetchDicts_Params_List) At(i int) Sync_fetchDicts_Params {
+	return Sync_fetchDicts_Params{s.List.Struct(i)}
+}
+
+func (s Sync_fetchDicts_Params_List) Set(i int, v Sync_fetchDicts_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s Sync_fetchDicts_Params_List) String() string {
+	str, _ := text.MarshalList(0xa523dde9eb30e8b4, s.List)
+	return str
+}
+
+// Sync_fetchDicts_Params_Promise is a wrapper for a Sync_fetchDicts_Params promised by a client call.
+type Sync_fetchDicts_Params_Promise struct{ *capnp.Pipeline }
+
+func (p Sync_fetchDicts_Params_Promise) Struct() (Sync_fetchDicts_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return Sync_fetchDicts_Params{s}, err
+}
+
+type Sync_fetchDicts_Results struct{ capnp.Struct }
+
+// Sync_fetchDicts_Results_TypeID is the unique identifier for the type Sync_fetchDicts_Results.
+const Sync_fetchDicts_Results_TypeID = 0xfe15393095732772
+
+func NewSync_fetchDicts_Results(s *capnp.Segment) (Sync_fetchDicts_Results, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Sync_fetchDicts_Results{st}, err
+}
+
+func NewRootSync_fetchDicts_Results(s *capnp.Segment) (Sync_fetchDicts_Results, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Sync_fetchDicts_Results{st}, err
+}
+
+func ReadRootSync_fetchDicts_Results(msg *capnp.Message) (Sync_fetchDicts_Results, error) {
+	root, err := msg.RootPtr()
+	return Sync_fetchDicts_Results{root.Struct()}, err
+}
+
+func (s Sync_fetchDicts_Results) String() string {
+	str, _ := text.Marshal(0xfe15393095732772, s.Struct)
+	return str
+}
+
+func (s Sync_fetchDicts_Results) Dicts() (Dict_List, error) {
+	p, err := s.Struct.Ptr(0)
+	return Dict_List{List: p.List()}, err
+}
+
+func (s Sync_fetchDicts_Results) HasDicts() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s Sync_fetchDicts_Results) SetDicts(v Dict_List) error {
+	return s.Struct.SetPtr(0, v.List.ToPtr())
+}
+
+// NewDicts sets the dicts field to a newly
+// allocated Dict_List, preferring placement in s's segment.
+func (s Sync_fetchDicts_Results) NewDicts(n int32) (Dict_List, error) {
+	l, err := NewDict_List(s.Struct.Segment(), n)
+	if err != nil {
+		return Dict_List{}, err
+	}
+	err = s.Struct.SetPtr(0, l.List.ToPtr())
+	return l, err
+}
+
+// Sync_fetchDicts_Results_List is a list of Sync_fetchDicts_Results.
+type Sync_fetchDicts_Results_List struct{ capnp.List }
+
+// NewSync_fetchDicts_Results creates a new list of Sync_fetchDicts_Results.
+func NewSync_fetchDicts_Results_List(s *capnp.Segment, sz int32) (Sync_fetchDicts_Results_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return Sync_fetchDicts_Results_List{l}, err
+}
+
+func (s Sync_fetchDicts_Results_List) At(i int) Sync_fetchDicts_Results {
+	return Sync_fetchDicts_Results{s.List.Struct(i)}
+}
+
+func (s Sync_fetchDicts_Results_List) Set(i int, v Sync_fetchDicts_Results) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s Sync_fetchDicts_Results_List) String() string {
+	str, _ := text.MarshalList(0xfe15393095732772, s.List)
+	return str
+}
+
+// Sync_fetchDicts_Results_Promise is a wrapper for a Sync_fetchDicts_Results promised by a client call.
+type Sync_fetchDicts_Results_Promise struct{ *capnp.Pipeline }
+
+func (p Sync_fetchDicts_Results_Promise) Struct() (Sync_fetchDicts_Results, error) {
+	s, err := p.Pipeline.Struct()
+	return Sync_fetchDicts_Results{s}, err
+}
+
+type Meta struct{ Client capnp.Client }
+
+// Meta_TypeID is the unique identifier for the type Meta.
+const Meta_TypeID = 0xb02d2ba0578cc7ff
+
+func (c Meta) Ping(ctx context.Context, params func(Meta_ping_Params) error, opts ...capnp.CallOption) Meta_ping_Results_Promise {
+	if c.Client == nil {
+		return Meta_ping_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xb02d2ba0578cc7ff,
+			MethodID:      0,
+			InterfaceName: "net/capnp/api.capnp:Meta",
+			MethodName:    "ping",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Meta_ping_Params{Struct: s}) }
+	}
+	return Meta_ping_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+
+type Meta_Server interface {
+	Ping(Meta_ping) error
+}
+
+func Meta_ServerToClient(s Meta_Server) Meta {
+	c, _ := s.(server.Closer)
+	return Meta{Client: server.New(Meta_Methods(nil, s), c)}
+}
+
+func Meta_Methods(methods []server.Method, s Meta_Server) []server.Method {
+	if cap(methods) == 0 {
+		methods = make([]server.Method, 0, 1)
+	}
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xb02d2ba0578cc7ff,
+			MethodID:      0,
+			InterfaceName: "net/capnp/api.capnp:Meta",
+			MethodName:    "ping",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := Meta_ping{c, opts, Meta_ping_Params{Struct: p}, Meta_ping_Results{Struct: r}}
+			return s.Ping(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
+	return methods
+}
+
+// Meta_ping holds the arguments for a server call to Meta.ping.
+type Meta_ping struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  Meta_ping_Params
+	Results Meta_ping_Results
+}
+
+type Meta_ping_Params struct{ capnp.Struct }
+
+// Meta_ping_Params_TypeID is the unique identifier for the type Meta_ping_Params.
+const Meta_ping_Params_TypeID = 0xe1a9fd466eca248c
+
+func NewMeta_ping_Params(s *capnp.Segment) (Meta_ping_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return Meta_ping_Params{st}, err
+}
+
+func NewRootMeta_ping_Params(s *capnp.Segment) (Meta_ping_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return Meta_ping_Params{st}, err
+}
+
+func ReadRootMeta_ping_Params(msg *capnp.Message) (Meta_ping_Params, error) {
+	root, err := msg.RootPtr()
+	return Meta_ping_Params{root.Struct()}, err
+}
+
+func (s Meta_ping_Params) String() string {
+	str, _ := text.Marshal(0xe1a9fd466eca248c, s.Struct)
+	return str
+}
+
+// Meta_ping_Params_List is a list of Meta_ping_Params.
+type Meta_ping_Params_List struct{ capnp.List }
+
+// NewMeta_ping_Params creates a new list of Meta_ping_Params.
+func NewMeta_ping_Params_List(s *capnp.Segment, sz int32) (Meta_ping_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	return Meta_ping_Params_List{l}, err
+}
+
+func (s Meta_ping_Params_List) At(i int) Meta_ping_Params { return Meta_ping_Params{s.List.Struct(i)} }
+
+func (s Meta_ping_Params_List) Set(i int, v Meta_ping_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s Meta_ping_Params_List) String() string {
+	str, _ := text.MarshalList(0xe1a9fd466eca248c, s.List)
+	return str
+}
+
+// Meta_ping_Params_Promise is a wrapper for a Meta_ping_Params promised by a client call.
+type Meta_ping_Params_Promise struct{ *capnp.Pipeline }
+
+func (p Meta_ping_Params_Promise) Struct() (Meta_ping_Params, error) {
 	s, err := p.Pipeline.Struct()
 	return Meta_ping_Params{s}, err
 }
@@ -1244,49 +1794,149 @@ func (c API) Version(ctx context.Context, params func(API_version_Params) error,
 	}
 	return API_version_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
 }
-func (c API) FetchStore(ctx context.Context, params func(Sync_fetchStore_Params) error, opts ...capnp.CallOption) Sync_fetchStore_Results_Promise {
+func (c API) HashAlgorithm(ctx context.Context, params func(API_hashAlgorithm_Params) error, opts ...capnp.CallOption) API_hashAlgorithm_Results_Promise {
 	if c.Client == nil {
-		return Sync_fetchStore_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+		return API_hashAlgorithm_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
 	}
 	call := &capnp.Call{
 		Ctx: ctx,
 		Method: capnp.Method{
-			InterfaceID:   0xf5692a07c5cf7872,
-			MethodID:      0,
-			InterfaceName: "net/capnp/api.capnp:Sync",
-			MethodName:    "fetchStore",
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      1,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "hashAlgorithm",
 		},
 		Options: capnp.NewCallOptions(opts),
 	}
 	if params != nil {
 		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
-		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchStore_Params{Struct: s}) }
+		call.ParamsFunc = func(s capnp.Struct) error { return params(API_hashAlgorithm_Params{Struct: s}) }
 	}
-	return Sync_fetchStore_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+	return API_hashAlgorithm_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
 }
-func (c API) FetchPatch(ctx context.Context, params func(Sync_fetchPatch_Params) error, opts ...capnp.CallOption) Sync_fetchPatch_Results_Promise {
+func (c API) AcceptInvite(ctx context.Context, params func(API_acceptInvite_Params) error, opts ...capnp.CallOption) API_acceptInvite_Results_Promise {
 	if c.Client == nil {
-		return Sync_fetchPatch_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+		return API_acceptInvite_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
 	}
 	call := &capnp.Call{
 		Ctx: ctx,
 		Method: capnp.Method{
-			InterfaceID:   0xf5692a07c5cf7872,
-			MethodID:      1,
-			InterfaceName: "net/capnp/api.capnp:Sync",
-			MethodName:    "fetchPatch",
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      2,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "acceptInvite",
 		},
 		Options: capnp.NewCallOptions(opts),
 	}
 	if params != nil {
-		call.ParamsSize = capnp.ObjectSize{DataSize: 8, PointerCount: 0}
-		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchPatch_Params{Struct: s}) }
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 5}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(API_acceptInvite_Params{Struct: s}) }
 	}
-	return Sync_fetchPatch_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+	return API_acceptInvite_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
 }
-func (c API) IsCompleteFetchAllowed(ctx context.Context, params func(Sync_isCompleteFetchAllowed_Params) error, opts ...capnp.CallOption) Sync_isCompleteFetchAllowed_Results_Promise {
+func (c API) ExchangeRevocations(ctx context.Context, params func(API_exchangeRevocations_Params) error, opts ...capnp.CallOption) API_exchangeRevocations_Results_Promise {
 	if c.Client == nil {
-		return Sync_isCompleteFetchAllowed_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+		return API_exchangeRevocations_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      3,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "exchangeRevocations",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 1}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(API_exchangeRevocations_Params{Struct: s}) }
+	}
+	return API_exchangeRevocations_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+func (c API) Devices(ctx context.Context, params func(API_devices_Params) error, opts ...capnp.CallOption) API_devices_Results_Promise {
+	if c.Client == nil {
+		return API_devices_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      4,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "devices",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(API_devices_Params{Struct: s}) }
+	}
+	return API_devices_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+func (c API) DeviceRemotes(ctx context.Context, params func(API_deviceRemotes_Params) error, opts ...capnp.CallOption) API_deviceRemotes_Results_Promise {
+	if c.Client == nil {
+		return API_deviceRemotes_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      5,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "deviceRemotes",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(API_deviceRemotes_Params{Struct: s}) }
+	}
+	return API_deviceRemotes_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+func (c API) FetchStore(ctx context.Context, params func(Sync_fetchStore_Params) error, opts ...capnp.CallOption) Sync_fetchStore_Results_Promise {
+	if c.Client == nil {
+		return Sync_fetchStore_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      0,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchStore",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchStore_Params{Struct: s}) }
+	}
+	return Sync_fetchStore_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+func (c API) FetchPatch(ctx context.Context, params func(Sync_fetchPatch_Params) error, opts ...capnp.CallOption) Sync_fetchPatch_Results_Promise {
+	if c.Client == nil {
+		return Sync_fetchPatch_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      1,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchPatch",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 8, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchPatch_Params{Struct: s}) }
+	}
+	return Sync_fetchPatch_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+func (c API) IsCompleteFetchAllowed(ctx context.Context, params func(Sync_isCompleteFetchAllowed_Params) error, opts ...capnp.CallOption) Sync_isCompleteFetchAllowed_Results_Promise {
+	if c.Client == nil {
+		return Sync_isCompleteFetchAllowed_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
 	}
 	call := &capnp.Call{
 		Ctx: ctx,
@@ -1364,6 +2014,46 @@ func (c API) FetchPatches(ctx context.Context, params func(Sync_fetchPatches_Par
 	}
 	return Sync_fetchPatches_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
 }
+func (c API) FetchLocks(ctx context.Context, params func(Sync_fetchLocks_Params) error, opts ...capnp.CallOption) Sync_fetchLocks_Results_Promise {
+	if c.Client == nil {
+		return Sync_fetchLocks_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      6,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchLocks",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchLocks_Params{Struct: s}) }
+	}
+	return Sync_fetchLocks_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
+func (c API) FetchDicts(ctx context.Context, params func(Sync_fetchDicts_Params) error, opts ...capnp.CallOption) Sync_fetchDicts_Results_Promise {
+	if c.Client == nil {
+		return Sync_fetchDicts_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      7,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchDicts",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Sync_fetchDicts_Params{Struct: s}) }
+	}
+	return Sync_fetchDicts_Results_Promise{Pipeline: capnp.NewPipeline(c.Client.Call(call))}
+}
 func (c API) Ping(ctx context.Context, params func(Meta_ping_Params) error, opts ...capnp.CallOption) Meta_ping_Results_Promise {
 	if c.Client == nil {
 		return Meta_ping_Results_Promise{Pipeline: capnp.NewPipeline(capnp.ErrorAnswer(capnp.ErrNullClient))}
@@ -1388,6 +2078,16 @@ func (c API) Ping(ctx context.Context, params func(Meta_ping_Params) error, opts
 type API_Server interface {
 	Version(API_version) error
 
+	HashAlgorithm(API_hashAlgorithm) error
+
+	AcceptInvite(API_acceptInvite) error
+
+	ExchangeRevocations(API_exchangeRevocations) error
+
+	Devices(API_devices) error
+
+	DeviceRemotes(API_deviceRemotes) error
+
 	FetchStore(Sync_fetchStore) error
 
 	FetchPatch(Sync_fetchPatch) error
@@ -1400,6 +2100,10 @@ type API_Server interface {
 
 	FetchPatches(Sync_fetchPatches) error
 
+	FetchLocks(Sync_fetchLocks) error
+
+	FetchDicts(Sync_fetchDicts) error
+
 	Ping(Meta_ping) error
 }
 
@@ -1410,7 +2114,7 @@ func API_ServerToClient(s API_Server) API {
 
 func API_Methods(methods []server.Method, s API_Server) []server.Method {
 	if cap(methods) == 0 {
-		methods = make([]server.Method, 0, 8)
+		methods = make([]server.Method, 0, 15)
 	}
 
 	methods = append(methods, server.Method{
@@ -1427,6 +2131,76 @@ func API_Methods(methods []server.Method, s API_Server) []server.Method {
 		ResultsSize: capnp.ObjectSize{DataSize: 8, PointerCount: 0},
 	})
 
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      1,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "hashAlgorithm",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := API_hashAlgorithm{c, opts, API_hashAlgorithm_Params{Struct: p}, API_hashAlgorithm_Results{Struct: r}}
+			return s.HashAlgorithm(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      2,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "acceptInvite",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := API_acceptInvite{c, opts, API_acceptInvite_Params{Struct: p}, API_acceptInvite_Results{Struct: r}}
+			return s.AcceptInvite(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 0},
+	})
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      3,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "exchangeRevocations",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := API_exchangeRevocations{c, opts, API_exchangeRevocations_Params{Struct: p}, API_exchangeRevocations_Results{Struct: r}}
+			return s.ExchangeRevocations(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      4,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "devices",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := API_devices{c, opts, API_devices_Params{Struct: p}, API_devices_Results{Struct: r}}
+			return s.Devices(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xb74958502f92fefd,
+			MethodID:      5,
+			InterfaceName: "net/capnp/api.capnp:API",
+			MethodName:    "deviceRemotes",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := API_deviceRemotes{c, opts, API_deviceRemotes_Params{Struct: p}, API_deviceRemotes_Results{Struct: r}}
+			return s.DeviceRemotes(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
 	methods = append(methods, server.Method{
 		Method: capnp.Method{
 			InterfaceID:   0xf5692a07c5cf7872,
@@ -1511,6 +2285,34 @@ func API_Methods(methods []server.Method, s API_Server) []server.Method {
 		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
 	})
 
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      6,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchLocks",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := Sync_fetchLocks{c, opts, Sync_fetchLocks_Params{Struct: p}, Sync_fetchLocks_Results{Struct: r}}
+			return s.FetchLocks(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xf5692a07c5cf7872,
+			MethodID:      7,
+			InterfaceName: "net/capnp/api.capnp:Sync",
+			MethodName:    "fetchDicts",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := Sync_fetchDicts{c, opts, Sync_fetchDicts_Params{Struct: p}, Sync_fetchDicts_Results{Struct: r}}
+			return s.FetchDicts(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 1},
+	})
+
 	methods = append(methods, server.Method{
 		Method: capnp.Method{
 			InterfaceID:   0xb02d2ba0578cc7ff,
@@ -1536,6 +2338,46 @@ type API_version struct {
 	Results API_version_Results
 }
 
+// API_hashAlgorithm holds the arguments for a server call to API.hashAlgorithm.
+type API_hashAlgorithm struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  API_hashAlgorithm_Params
+	Results API_hashAlgorithm_Results
+}
+
+// API_acceptInvite holds the arguments for a server call to API.acceptInvite.
+type API_acceptInvite struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  API_acceptInvite_Params
+	Results API_acceptInvite_Results
+}
+
+// API_exchangeRevocations holds the arguments for a server call to API.exchangeRevocations.
+type API_exchangeRevocations struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  API_exchangeRevocations_Params
+	Results API_exchangeRevocations_Results
+}
+
+// API_devices holds the arguments for a server call to API.devices.
+type API_devices struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  API_devices_Params
+	Results API_devices_Results
+}
+
+// API_deviceRemotes holds the arguments for a server call to API.deviceRemotes.
+type API_deviceRemotes struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  API_deviceRemotes_Params
+	Results API_deviceRemotes_Results
+}
+
 type API_version_Params struct{ capnp.Struct }
 
 // API_version_Params_TypeID is the unique identifier for the type API_version_Params.
@@ -1654,86 +2496,909 @@ func (p API_version_Results_Promise) Struct() (API_version_Results, error) {
 	return API_version_Results{s}, err
 }
 
-const schema_9bcb07fb35756ee6 = "x\xda\xacU]h\x1cU\x14>\xe7\xde;\x99\x82\x89" +
-	"\xcbe\x8ad\x05M\x85\xb5\x85H\x936U\xa4\x05\xcd" +
-	"&\x9a\xd6\x05#3[P\x1b\x10\x1cwo\xbb[7" +
-	"\xbb\x93\x99Y\xed*E\xda\x12\xac\x12\xab\xd4\x9f\x87\xb6" +
-	"JU\xfa\xd0\x8a`\x0b\"\x14|\xa9H0\x05\xf5E" +
-	"|P\xc1Z\xfc\x01QPX\x8c\x84\xed\xc8\x9d\xd9\x99" +
-	"\xdc$\x8d]\x8bo\xcb\x9es\xbf\xf9\xbes\xbe\xfb\xdd" +
-	"M[H\x96l\xd6^\xee\x02\xb0\x1e\xd4\xba\x82\x1fn" +
-	":yq\xea\xd9\xe24XiD\x00\xa6\x03l\xd9H" +
-	"\xf7\"\xa0\xb1\x95\x0e\x03\x06\x1b.O\xe7/\xb5^9" +
-	"\x06<\x8d\x00\x1a\xca\x86\xc7\xe8\x90l\x10a\xc3\xab\xa7" +
-	"\x16\xd2\x1f\xbex\xfc\x9d\xa8!\x04\x98\xa6\xe7\x11X\xf0" +
-	"\xc0\xfc\xc1\x99?\x0fn>\xa3b\xd7\xe93\xf2\xe8\x81" +
-	"\xf0h0;\xf3\xc8\xc9;6~\x00|-\x0d~\xaa" +
-	"\xd6\xefZ\xd0/\x1e\x07@\xe3\x14\x9d3\xceR\x1d\xc0" +
-	"x\x9f\xee0\xbe\x92\xbf\x82\xe6'\x8f\x1f9\xe2\xa6\xce" +
-	"\xa9h\x1f\xd3\x09\x89\xf6Y\x88\xd6\xbart\xd0|4" +
-	"\xf7\xd1\x0a\xb4\xdf\xe8\x05\xa3\x19\xa2\xfdAw\x18i\xb6" +
-	"\x01 hd\x9a7\x1e#\x87gUY\x9c=!\xd1" +
-	"na\x12\xed\x8d\xf5\x7f\x9d[\xb7\xee\xcc\xe7\x8a\xac{" +
-	"\xd8\x90\x94\xc5_\xcb\xedy\x88\x15\xbeU*\xb7\xb3\x09" +
-	"Y9\xb4\xfe\xf0\xad7\xa7~W+\x9c\xb9\xb22\x93" +
-	"\x99\xabno\x9d\xbe\xa4TZ\xb4_V\xee\xe5\xf7\xf3" +
-	"\xfd\xdf\xbf\xfd\xb3*\xebGzA\x12i\x86\xb2n\xb8" +
-	"\xfb\xddo.\xa7\xbf\xfb\x15\xac\xde\xa4!\xcdFe\xc3" +
-	"m!Sw\xdf\x17\x9f\xea\xfd\xe5\xe6\x0a\xdd#l\xce" +
-	"\x18\x0f\xfbs\xecy4,M\x07h\x9d\xf8e\xd3[" +
-	"\xd9;\xe7\x15\xd9[\xb5P\xf6\x98&\xc1f\xf7?y" +
-	"\xe0a\xfb\xca\xbcBTh!\xd1\xafYc\xec\xe8\xa1" +
-	"\xcc\xdf\xea\xc4\xc65W\x1e\xdd\x15\x1ee\xa5\xa9/_" +
-	"\xca\xbf\xb7\x00\xbc7>\xda\xd0\xb6\xc9\xa3U\xe1\x0f\x16" +
-	"l\xa7\xca\x9cA\xdb)\x0f\xc8\x9f\xce\xb6\x9d\x8dja" +
-	"`\xb7\xf0\x0b%\xd3\xf6\x0b%\xe1eL;\xe5\xda\x93" +
-	"\x9e\xc5(\x03`\x08\xc0{\xf2\x00V7E\xab\x97`" +
-	"\xb0\xdb\xadM\xe6\xaaE\x01\xb8\x0f5 \xa8\x01&\xc8" +
-	"TE\x1e\x17\xbe=\xe0\x94\xab{2y\xd1\xe7\xd5+" +
-	"\xfe\x12\xc8!\x00k\x0dEk-\xc1>W8\x95\x06" +
-	"v\x03\xc1n\x05L[A\xb3\xec\xddW\x9bt*\xc2" +
-	"\x17\xdb%\xe1\x91J\xa5\xf6\xb4(f\x86M[\x12\xfe" +
-	"\x17}e\xcf\xac{I\x7f~X\xac\xa0\xa3*,{" +
-	"Q'`\x11\x11\x08\xa2B\x8a,W\x08`\"Z\x8c" +
-	"j\x00\x89\xbd0\xbe\xad\x9c\xf7\x03\xe1\x9a\x9e\x92c\xc8" +
-	"\xa2\x89\xd8\xd1\x122R\x10\xbd\xbe\x15,!8b\xe6" +
-	"\x14z\xb130\xf62\xe7\xa3!\xbd\xe7\x9e\x12\xaeW" +
-	"\xaeU\xb3h\xadA\xc5\xc9\x00\x8b\xd9\x00\xd0\x19\xf5\xbc" +
-	"\xf0\xea\xfa\xb2\xe1\xf6/\xee:U\xb4}\x1b{\x80`" +
-	"\xcfj\xbe\x09\x11\x9d\xbaWJ|s\xad/\xef\xf4k" +
-	"\xae\x88\x87\xd6\xb1\x0d\xcc\xbe\xa5\xb6Y\xc5\xbc\xed\xeb\x90" +
-	"\xb4uuj\xcb|\xe42\xb8\x1e\x9b\xb1e[\x1ch" +
-	"o\xe8\xaa\xa0\xa3\x8b\xe3\x8d7\x89\x0c\x08\xb2\xd5l!" +
-	"YG\xbe\xed\x0d\x8d\x11G)\x9e\x80v,\x9d\x9d\x00" +
-	"\xc2O\xeb\x88I\xdec\x1c\xd5\xfcMY{]G\x92" +
-	"\xbc9\x18\xa7'\x7f\xe1<\x10>\xad#MB\x18\xe3" +
-	"\xe7\x877\\ |JG\x96\xa4\x1b\xc6\xe9\xce\x85\xbc" +
-	"'\xbbt\xd4\x92\x97\x10\xe3\xa0\xe3\xe3{\x81\xf01=" +
-	"\x88W\x0d\xd4\x15Y\x0cb\xcf\x01-\x94\xb2r\x9a\xd1" +
-	"\x120\xde\xc2p\xb4\x86\xb0\x14\xad\x1d\xfa\xda\xff\xa4\xa4" +
-	"\xbbT\x88\x94\x0c\xbe\x8e\xeegd\xb5\xff\xd3\xe4\xcb\xfd" +
-	"u\x8dt\xbeZ\x96\xfe\xb7O\xabvjG\xe7?\x01" +
-	"\x00\x00\xff\xff\xb3\xd4\x83\x1a"
+type API_hashAlgorithm_Params struct{ capnp.Struct }
+
+// API_hashAlgorithm_Params_TypeID is the unique identifier for the type API_hashAlgorithm_Params.
+const API_hashAlgorithm_Params_TypeID = 0xce6ec43e6977b7f6
+
+func NewAPI_hashAlgorithm_Params(s *capnp.Segment) (API_hashAlgorithm_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_hashAlgorithm_Params{st}, err
+}
+
+func NewRootAPI_hashAlgorithm_Params(s *capnp.Segment) (API_hashAlgorithm_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_hashAlgorithm_Params{st}, err
+}
+
+func ReadRootAPI_hashAlgorithm_Params(msg *capnp.Message) (API_hashAlgorithm_Params, error) {
+	root, err := msg.RootPtr()
+	return API_hashAlgorithm_Params{root.Struct()}, err
+}
+
+func (s API_hashAlgorithm_Params) String() string {
+	str, _ := text.Marshal(0xce6ec43e6977b7f6, s.Struct)
+	return str
+}
+
+// API_hashAlgorithm_Params_List is a list of API_hashAlgorithm_Params.
+type API_hashAlgorithm_Params_List struct{ capnp.List }
+
+// NewAPI_hashAlgorithm_Params creates a new list of API_hashAlgorithm_Params.
+func NewAPI_hashAlgorithm_Params_List(s *capnp.Segment, sz int32) (API_hashAlgorithm_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	return API_hashAlgorithm_Params_List{l}, err
+}
+
+func (s API_hashAlgorithm_Params_List) At(i int) API_hashAlgorithm_Params {
+	return API_hashAlgorithm_Params{s.List.Struct(i)}
+}
+
+func (s API_hashAlgorithm_Params_List) Set(i int, v API_hashAlgorithm_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_hashAlgorithm_Params_List) String() string {
+	str, _ := text.MarshalList(0xce6ec43e6977b7f6, s.List)
+	return str
+}
+
+// API_hashAlgorithm_Params_Promise is a wrapper for a API_hashAlgorithm_Params promised by a client call.
+type API_hashAlgorithm_Params_Promise struct{ *capnp.Pipeline }
+
+func (p API_hashAlgorithm_Params_Promise) Struct() (API_hashAlgorithm_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return API_hashAlgorithm_Params{s}, err
+}
+
+type API_hashAlgorithm_Results struct{ capnp.Struct }
+
+// API_hashAlgorithm_Results_TypeID is the unique identifier for the type API_hashAlgorithm_Results.
+const API_hashAlgorithm_Results_TypeID = 0xb38b1123400c0120
+
+func NewAPI_hashAlgorithm_Results(s *capnp.Segment) (API_hashAlgorithm_Results, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_hashAlgorithm_Results{st}, err
+}
+
+func NewRootAPI_hashAlgorithm_Results(s *capnp.Segment) (API_hashAlgorithm_Results, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_hashAlgorithm_Results{st}, err
+}
+
+func ReadRootAPI_hashAlgorithm_Results(msg *capnp.Message) (API_hashAlgorithm_Results, error) {
+	root, err := msg.RootPtr()
+	return API_hashAlgorithm_Results{root.Struct()}, err
+}
+
+func (s API_hashAlgorithm_Results) String() string {
+	str, _ := text.Marshal(0xb38b1123400c0120, s.Struct)
+	return str
+}
+
+func (s API_hashAlgorithm_Results) Algorithm() (string, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.Text(), err
+}
+
+func (s API_hashAlgorithm_Results) HasAlgorithm() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s API_hashAlgorithm_Results) AlgorithmBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s API_hashAlgorithm_Results) SetAlgorithm(v string) error {
+	return s.Struct.SetText(0, v)
+}
+
+// API_hashAlgorithm_Results_List is a list of API_hashAlgorithm_Results.
+type API_hashAlgorithm_Results_List struct{ capnp.List }
+
+// NewAPI_hashAlgorithm_Results creates a new list of API_hashAlgorithm_Results.
+func NewAPI_hashAlgorithm_Results_List(s *capnp.Segment, sz int32) (API_hashAlgorithm_Results_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return API_hashAlgorithm_Results_List{l}, err
+}
+
+func (s API_hashAlgorithm_Results_List) At(i int) API_hashAlgorithm_Results {
+	return API_hashAlgorithm_Results{s.List.Struct(i)}
+}
+
+func (s API_hashAlgorithm_Results_List) Set(i int, v API_hashAlgorithm_Results) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_hashAlgorithm_Results_List) String() string {
+	str, _ := text.MarshalList(0xb38b1123400c0120, s.List)
+	return str
+}
+
+// API_hashAlgorithm_Results_Promise is a wrapper for a API_hashAlgorithm_Results promised by a client call.
+type API_hashAlgorithm_Results_Promise struct{ *capnp.Pipeline }
+
+func (p API_hashAlgorithm_Results_Promise) Struct() (API_hashAlgorithm_Results, error) {
+	s, err := p.Pipeline.Struct()
+	return API_hashAlgorithm_Results{s}, err
+}
+
+type API_acceptInvite_Params struct{ capnp.Struct }
+
+// API_acceptInvite_Params_TypeID is the unique identifier for the type API_acceptInvite_Params.
+const API_acceptInvite_Params_TypeID = 0xc66055db7d505c51
+
+func NewAPI_acceptInvite_Params(s *capnp.Segment) (API_acceptInvite_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5})
+	return API_acceptInvite_Params{st}, err
+}
+
+func NewRootAPI_acceptInvite_Params(s *capnp.Segment) (API_acceptInvite_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5})
+	return API_acceptInvite_Params{st}, err
+}
+
+func ReadRootAPI_acceptInvite_Params(msg *capnp.Message) (API_acceptInvite_Params, error) {
+	root, err := msg.RootPtr()
+	return API_acceptInvite_Params{root.Struct()}, err
+}
+
+func (s API_acceptInvite_Params) String() string {
+	str, _ := text.Marshal(0xc66055db7d505c51, s.Struct)
+	return str
+}
+
+func (s API_acceptInvite_Params) Id() (string, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.Text(), err
+}
+
+func (s API_acceptInvite_Params) HasId() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s API_acceptInvite_Params) IdBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s API_acceptInvite_Params) SetId(v string) error {
+	return s.Struct.SetText(0, v)
+}
+
+func (s API_acceptInvite_Params) Secret() (string, error) {
+	p, err := s.Struct.Ptr(1)
+	return p.Text(), err
+}
+
+func (s API_acceptInvite_Params) HasSecret() bool {
+	p, err := s.Struct.Ptr(1)
+	return p.IsValid() || err != nil
+}
+
+func (s API_acceptInvite_Params) SecretBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(1)
+	return p.TextBytes(), err
+}
+
+func (s API_acceptInvite_Params) SetSecret(v string) error {
+	return s.Struct.SetText(1, v)
+}
+
+func (s API_acceptInvite_Params) Name() (string, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.Text(), err
+}
+
+func (s API_acceptInvite_Params) HasName() bool {
+	p, err := s.Struct.Ptr(2)
+	return p.IsValid() || err != nil
+}
+
+func (s API_acceptInvite_Params) NameBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(2)
+	return p.TextBytes(), err
+}
+
+func (s API_acceptInvite_Params) SetName(v string) error {
+	return s.Struct.SetText(2, v)
+}
+
+func (s API_acceptInvite_Params) Fingerprint() (string, error) {
+	p, err := s.Struct.Ptr(3)
+	return p.Text(), err
+}
+
+func (s API_acceptInvite_Params) HasFingerprint() bool {
+	p, err := s.Struct.Ptr(3)
+	return p.IsValid() || err != nil
+}
+
+func (s API_acceptInvite_Params) FingerprintBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(3)
+	return p.TextBytes(), err
+}
+
+func (s API_acceptInvite_Params) SetFingerprint(v string) error {
+	return s.Struct.SetText(3, v)
+}
+
+func (s API_acceptInvite_Params) DeviceProof() (string, error) {
+	p, err := s.Struct.Ptr(4)
+	return p.Text(), err
+}
+
+func (s API_acceptInvite_Params) HasDeviceProof() bool {
+	p, err := s.Struct.Ptr(4)
+	return p.IsValid() || err != nil
+}
+
+func (s API_acceptInvite_Params) DeviceProofBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(4)
+	return p.TextBytes(), err
+}
+
+func (s API_acceptInvite_Params) SetDeviceProof(v string) error {
+	return s.Struct.SetText(4, v)
+}
+
+// API_acceptInvite_Params_List is a list of API_acceptInvite_Params.
+type API_acceptInvite_Params_List struct{ capnp.List }
+
+// NewAPI_acceptInvite_Params creates a new list of API_acceptInvite_Params.
+func NewAPI_acceptInvite_Params_List(s *capnp.Segment, sz int32) (API_acceptInvite_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5}, sz)
+	return API_acceptInvite_Params_List{l}, err
+}
+
+func (s API_acceptInvite_Params_List) At(i int) API_acceptInvite_Params {
+	return API_acceptInvite_Params{s.List.Struct(i)}
+}
+
+func (s API_acceptInvite_Params_List) Set(i int, v API_acceptInvite_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_acceptInvite_Params_List) String() string {
+	str, _ := text.MarshalList(0xc66055db7d505c51, s.List)
+	return str
+}
+
+// API_acceptInvite_Params_Promise is a wrapper for a API_acceptInvite_Params promised by a client call.
+type API_acceptInvite_Params_Promise struct{ *capnp.Pipeline }
+
+func (p API_acceptInvite_Params_Promise) Struct() (API_acceptInvite_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return API_acceptInvite_Params{s}, err
+}
+
+type API_acceptInvite_Results struct{ capnp.Struct }
+
+// API_acceptInvite_Results_TypeID is the unique identifier for the type API_acceptInvite_Results.
+const API_acceptInvite_Results_TypeID = 0xc2a20b731a6867e1
+
+func NewAPI_acceptInvite_Results(s *capnp.Segment) (API_acceptInvite_Results, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_acceptInvite_Results{st}, err
+}
+
+func NewRootAPI_acceptInvite_Results(s *capnp.Segment) (API_acceptInvite_Results, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_acceptInvite_Results{st}, err
+}
+
+func ReadRootAPI_acceptInvite_Results(msg *capnp.Message) (API_acceptInvite_Results, error) {
+	root, err := msg.RootPtr()
+	return API_acceptInvite_Results{root.Struct()}, err
+}
+
+func (s API_acceptInvite_Results) String() string {
+	str, _ := text.Marshal(0xc2a20b731a6867e1, s.Struct)
+	return str
+}
+
+// API_acceptInvite_Results_List is a list of API_acceptInvite_Results.
+type API_acceptInvite_Results_List struct{ capnp.List }
+
+// NewAPI_acceptInvite_Results creates a new list of API_acceptInvite_Results.
+func NewAPI_acceptInvite_Results_List(s *capnp.Segment, sz int32) (API_acceptInvite_Results_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	return API_acceptInvite_Results_List{l}, err
+}
+
+func (s API_acceptInvite_Results_List) At(i int) API_acceptInvite_Results {
+	return API_acceptInvite_Results{s.List.Struct(i)}
+}
+
+func (s API_acceptInvite_Results_List) Set(i int, v API_acceptInvite_Results) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_acceptInvite_Results_List) String() string {
+	str, _ := text.MarshalList(0xc2a20b731a6867e1, s.List)
+	return str
+}
+
+// API_acceptInvite_Results_Promise is a wrapper for a API_acceptInvite_Results promised by a client call.
+type API_acceptInvite_Results_Promise struct{ *capnp.Pipeline }
+
+func (p API_acceptInvite_Results_Promise) Struct() (API_acceptInvite_Results, error) {
+	s, err := p.Pipeline.Struct()
+	return API_acceptInvite_Results{s}, err
+}
+
+type API_exchangeRevocations_Params struct{ capnp.Struct }
+
+// API_exchangeRevocations_Params_TypeID is the unique identifier for the type API_exchangeRevocations_Params.
+const API_exchangeRevocations_Params_TypeID = 0xc2064e79e116dc74
+
+func NewAPI_exchangeRevocations_Params(s *capnp.Segment) (API_exchangeRevocations_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_exchangeRevocations_Params{st}, err
+}
+
+func NewRootAPI_exchangeRevocations_Params(s *capnp.Segment) (API_exchangeRevocations_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_exchangeRevocations_Params{st}, err
+}
+
+func ReadRootAPI_exchangeRevocations_Params(msg *capnp.Message) (API_exchangeRevocations_Params, error) {
+	root, err := msg.RootPtr()
+	return API_exchangeRevocations_Params{root.Struct()}, err
+}
+
+func (s API_exchangeRevocations_Params) String() string {
+	str, _ := text.Marshal(0xc2064e79e116dc74, s.Struct)
+	return str
+}
+
+func (s API_exchangeRevocations_Params) Revocations() (capnp.TextList, error) {
+	p, err := s.Struct.Ptr(0)
+	return capnp.TextList{List: p.List()}, err
+}
+
+func (s API_exchangeRevocations_Params) HasRevocations() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s API_exchangeRevocations_Params) SetRevocations(v capnp.TextList) error {
+	return s.Struct.SetPtr(0, v.List.ToPtr())
+}
+
+// NewRevocations sets the revocations field to a newly
+// allocated capnp.TextList, preferring placement in s's segment.
+func (s API_exchangeRevocations_Params) NewRevocations(n int32) (capnp.TextList, error) {
+	l, err := capnp.NewTextList(s.Struct.Segment(), n)
+	if err != nil {
+		return capnp.TextList{}, err
+	}
+	err = s.Struct.SetPtr(0, l.List.ToPtr())
+	return l, err
+}
+
+// API_exchangeRevocations_Params_List is a list of API_exchangeRevocations_Params.
+type API_exchangeRevocations_Params_List struct{ capnp.List }
+
+// NewAPI_exchangeRevocations_Params creates a new list of API_exchangeRevocations_Params.
+func NewAPI_exchangeRevocations_Params_List(s *capnp.Segment, sz int32) (API_exchangeRevocations_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return API_exchangeRevocations_Params_List{l}, err
+}
+
+func (s API_exchangeRevocations_Params_List) At(i int) API_exchangeRevocations_Params {
+	return API_exchangeRevocations_Params{s.List.Struct(i)}
+}
+
+func (s API_exchangeRevocations_Params_List) Set(i int, v API_exchangeRevocations_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_exchangeRevocations_Params_List) String() string {
+	str, _ := text.MarshalList(0xc2064e79e116dc74, s.List)
+	return str
+}
+
+// API_exchangeRevocations_Params_Promise is a wrapper for a API_exchangeRevocations_Params promised by a client call.
+type API_exchangeRevocations_Params_Promise struct{ *capnp.Pipeline }
+
+func (p API_exchangeRevocations_Params_Promise) Struct() (API_exchangeRevocations_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return API_exchangeRevocations_Params{s}, err
+}
+
+type API_exchangeRevocations_Results struct{ capnp.Struct }
+
+// API_exchangeRevocations_Results_TypeID is the unique identifier for the type API_exchangeRevocations_Results.
+const API_exchangeRevocations_Results_TypeID = 0xa9565a76d367ef5c
+
+func NewAPI_exchangeRevocations_Results(s *capnp.Segment) (API_exchangeRevocations_Results, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_exchangeRevocations_Results{st}, err
+}
+
+func NewRootAPI_exchangeRevocations_Results(s *capnp.Segment) (API_exchangeRevocations_Results, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_exchangeRevocations_Results{st}, err
+}
+
+func ReadRootAPI_exchangeRevocations_Results(msg *capnp.Message) (API_exchangeRevocations_Results, error) {
+	root, err := msg.RootPtr()
+	return API_exchangeRevocations_Results{root.Struct()}, err
+}
+
+func (s API_exchangeRevocations_Results) String() string {
+	str, _ := text.Marshal(0xa9565a76d367ef5c, s.Struct)
+	return str
+}
+
+func (s API_exchangeRevocations_Results) Revocations() (capnp.TextList, error) {
+	p, err := s.Struct.Ptr(0)
+	return capnp.TextList{List: p.List()}, err
+}
+
+func (s API_exchangeRevocations_Results) HasRevocations() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s API_exchangeRevocations_Results) SetRevocations(v capnp.TextList) error {
+	return s.Struct.SetPtr(0, v.List.ToPtr())
+}
+
+// NewRevocations sets the revocations field to a newly
+// allocated capnp.TextList, preferring placement in s's segment.
+func (s API_exchangeRevocations_Results) NewRevocations(n int32) (capnp.TextList, error) {
+	l, err := capnp.NewTextList(s.Struct.Segment(), n)
+	if err != nil {
+		return capnp.TextList{}, err
+	}
+	err = s.Struct.SetPtr(0, l.List.ToPtr())
+	return l, err
+}
+
+// API_exchangeRevocations_Results_List is a list of API_exchangeRevocations_Results.
+type API_exchangeRevocations_Results_List struct{ capnp.List }
+
+// NewAPI_exchangeRevocations_Results creates a new list of API_exchangeRevocations_Results.
+func NewAPI_exchangeRevocations_Results_List(s *capnp.Segment, sz int32) (API_exchangeRevocations_Results_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return API_exchangeRevocations_Results_List{l}, err
+}
+
+func (s API_exchangeRevocations_Results_List) At(i int) API_exchangeRevocations_Results {
+	return API_exchangeRevocations_Results{s.List.Struct(i)}
+}
+
+func (s API_exchangeRevocations_Results_List) Set(i int, v API_exchangeRevocations_Results) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_exchangeRevocations_Results_List) String() string {
+	str, _ := text.MarshalList(0xa9565a76d367ef5c, s.List)
+	return str
+}
+
+// API_exchangeRevocations_Results_Promise is a wrapper for a API_exchangeRevocations_Results promised by a client call.
+type API_exchangeRevocations_Results_Promise struct{ *capnp.Pipeline }
+
+func (p API_exchangeRevocations_Results_Promise) Struct() (API_exchangeRevocations_Results, error) {
+	s, err := p.Pipeline.Struct()
+	return API_exchangeRevocations_Results{s}, err
+}
+
+type API_devices_Params struct{ capnp.Struct }
+
+// API_devices_Params_TypeID is the unique identifier for the type API_devices_Params.
+const API_devices_Params_TypeID = 0xde017cd87186316b
+
+func NewAPI_devices_Params(s *capnp.Segment) (API_devices_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_devices_Params{st}, err
+}
+
+func NewRootAPI_devices_Params(s *capnp.Segment) (API_devices_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_devices_Params{st}, err
+}
+
+func ReadRootAPI_devices_Params(msg *capnp.Message) (API_devices_Params, error) {
+	root, err := msg.RootPtr()
+	return API_devices_Params{root.Struct()}, err
+}
+
+func (s API_devices_Params) String() string {
+	str, _ := text.Marshal(0xde017cd87186316b, s.Struct)
+	return str
+}
+
+// API_devices_Params_List is a list of API_devices_Params.
+type API_devices_Params_List struct{ capnp.List }
+
+// NewAPI_devices_Params creates a new list of API_devices_Params.
+func NewAPI_devices_Params_List(s *capnp.Segment, sz int32) (API_devices_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	return API_devices_Params_List{l}, err
+}
+
+func (s API_devices_Params_List) At(i int) API_devices_Params {
+	return API_devices_Params{s.List.Struct(i)}
+}
+
+func (s API_devices_Params_List) Set(i int, v API_devices_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_devices_Params_List) String() string {
+	str, _ := text.MarshalList(0xde017cd87186316b, s.List)
+	return str
+}
+
+// API_devices_Params_Promise is a wrapper for a API_devices_Params promised by a client call.
+type API_devices_Params_Promise struct{ *capnp.Pipeline }
+
+func (p API_devices_Params_Promise) Struct() (API_devices_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return API_devices_Params{s}, err
+}
+
+type API_devices_Results struct{ capnp.Struct }
+
+// API_devices_Results_TypeID is the unique identifier for the type API_devices_Results.
+const API_devices_Results_TypeID = 0xea3be35efff53064
+
+func NewAPI_devices_Results(s *capnp.Segment) (API_devices_Results, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_devices_Results{st}, err
+}
+
+func NewRootAPI_devices_Results(s *capnp.Segment) (API_devices_Results, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_devices_Results{st}, err
+}
+
+func ReadRootAPI_devices_Results(msg *capnp.Message) (API_devices_Results, error) {
+	root, err := msg.RootPtr()
+	return API_devices_Results{root.Struct()}, err
+}
+
+func (s API_devices_Results) String() string {
+	str, _ := text.Marshal(0xea3be35efff53064, s.Struct)
+	return str
+}
+
+func (s API_devices_Results) Proofs() (capnp.TextList, error) {
+	p, err := s.Struct.Ptr(0)
+	return capnp.TextList{List: p.List()}, err
+}
+
+func (s API_devices_Results) HasProofs() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s API_devices_Results) SetProofs(v capnp.TextList) error {
+	return s.Struct.SetPtr(0, v.List.ToPtr())
+}
+
+// NewProofs sets the proofs field to a newly
+// allocated capnp.TextList, preferring placement in s's segment.
+func (s API_devices_Results) NewProofs(n int32) (capnp.TextList, error) {
+	l, err := capnp.NewTextList(s.Struct.Segment(), n)
+	if err != nil {
+		return capnp.TextList{}, err
+	}
+	err = s.Struct.SetPtr(0, l.List.ToPtr())
+	return l, err
+}
+
+// API_devices_Results_List is a list of API_devices_Results.
+type API_devices_Results_List struct{ capnp.List }
+
+// NewAPI_devices_Results creates a new list of API_devices_Results.
+func NewAPI_devices_Results_List(s *capnp.Segment, sz int32) (API_devices_Results_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return API_devices_Results_List{l}, err
+}
+
+func (s API_devices_Results_List) At(i int) API_devices_Results {
+	return API_devices_Results{s.List.Struct(i)}
+}
+
+func (s API_devices_Results_List) Set(i int, v API_devices_Results) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_devices_Results_List) String() string {
+	str, _ := text.MarshalList(0xea3be35efff53064, s.List)
+	return str
+}
+
+// API_devices_Results_Promise is a wrapper for a API_devices_Results promised by a client call.
+type API_devices_Results_Promise struct{ *capnp.Pipeline }
+
+func (p API_devices_Results_Promise) Struct() (API_devices_Results, error) {
+	s, err := p.Pipeline.Struct()
+	return API_devices_Results{s}, err
+}
+
+type API_deviceRemotes_Params struct{ capnp.Struct }
+
+// API_deviceRemotes_Params_TypeID is the unique identifier for the type API_deviceRemotes_Params.
+const API_deviceRemotes_Params_TypeID = 0xdd33ec6329281548
+
+func NewAPI_deviceRemotes_Params(s *capnp.Segment) (API_deviceRemotes_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_deviceRemotes_Params{st}, err
+}
+
+func NewRootAPI_deviceRemotes_Params(s *capnp.Segment) (API_deviceRemotes_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	return API_deviceRemotes_Params{st}, err
+}
+
+func ReadRootAPI_deviceRemotes_Params(msg *capnp.Message) (API_deviceRemotes_Params, error) {
+	root, err := msg.RootPtr()
+	return API_deviceRemotes_Params{root.Struct()}, err
+}
+
+func (s API_deviceRemotes_Params) String() string {
+	str, _ := text.Marshal(0xdd33ec6329281548, s.Struct)
+	return str
+}
+
+// API_deviceRemotes_Params_List is a list of API_deviceRemotes_Params.
+type API_deviceRemotes_Params_List struct{ capnp.List }
+
+// NewAPI_deviceRemotes_Params creates a new list of API_deviceRemotes_Params.
+func NewAPI_deviceRemotes_Params_List(s *capnp.Segment, sz int32) (API_deviceRemotes_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	return API_deviceRemotes_Params_List{l}, err
+}
+
+func (s API_deviceRemotes_Params_List) At(i int) API_deviceRemotes_Params {
+	return API_deviceRemotes_Params{s.List.Struct(i)}
+}
+
+func (s API_deviceRemotes_Params_List) Set(i int, v API_deviceRemotes_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_deviceRemotes_Params_List) String() string {
+	str, _ := text.MarshalList(0xdd33ec6329281548, s.List)
+	return str
+}
+
+// API_deviceRemotes_Params_Promise is a wrapper for a API_deviceRemotes_Params promised by a client call.
+type API_deviceRemotes_Params_Promise struct{ *capnp.Pipeline }
+
+func (p API_deviceRemotes_Params_Promise) Struct() (API_deviceRemotes_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return API_deviceRemotes_Params{s}, err
+}
+
+type API_deviceRemotes_Results struct{ capnp.Struct }
+
+// API_deviceRemotes_Results_TypeID is the unique identifier for the type API_deviceRemotes_Results.
+const API_deviceRemotes_Results_TypeID = 0xd129147b9e251ae6
+
+func NewAPI_deviceRemotes_Results(s *capnp.Segment) (API_deviceRemotes_Results, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_deviceRemotes_Results{st}, err
+}
+
+func NewRootAPI_deviceRemotes_Results(s *capnp.Segment) (API_deviceRemotes_Results, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return API_deviceRemotes_Results{st}, err
+}
+
+func ReadRootAPI_deviceRemotes_Results(msg *capnp.Message) (API_deviceRemotes_Results, error) {
+	root, err := msg.RootPtr()
+	return API_deviceRemotes_Results{root.Struct()}, err
+}
+
+func (s API_deviceRemotes_Results) String() string {
+	str, _ := text.Marshal(0xd129147b9e251ae6, s.Struct)
+	return str
+}
+
+func (s API_deviceRemotes_Results) Data() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return []byte(p.Data()), err
+}
+
+func (s API_deviceRemotes_Results) HasData() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s API_deviceRemotes_Results) SetData(v []byte) error {
+	return s.Struct.SetData(0, v)
+}
+
+// API_deviceRemotes_Results_List is a list of API_deviceRemotes_Results.
+type API_deviceRemotes_Results_List struct{ capnp.List }
+
+// NewAPI_deviceRemotes_Results creates a new list of API_deviceRemotes_Results.
+func NewAPI_deviceRemotes_Results_List(s *capnp.Segment, sz int32) (API_deviceRemotes_Results_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return API_deviceRemotes_Results_List{l}, err
+}
+
+func (s API_deviceRemotes_Results_List) At(i int) API_deviceRemotes_Results {
+	return API_deviceRemotes_Results{s.List.Struct(i)}
+}
+
+func (s API_deviceRemotes_Results_List) Set(i int, v API_deviceRemotes_Results) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s API_deviceRemotes_Results_List) String() string {
+	str, _ := text.MarshalList(0xd129147b9e251ae6, s.List)
+	return str
+}
+
+// API_deviceRemotes_Results_Promise is a wrapper for a API_deviceRemotes_Results promised by a client call.
+type API_deviceRemotes_Results_Promise struct{ *capnp.Pipeline }
+
+func (p API_deviceRemotes_Results_Promise) Struct() (API_deviceRemotes_Results, error) {
+	s, err := p.Pipeline.Struct()
+	return API_deviceRemotes_Results{s}, err
+}
+
+const schema_9bcb07fb35756ee6 = "x\xda\xa4Wo\x8c\x14\xe5\x19\x7f\x9e\x99\xd9\x9d\xdd\xdb" +
+	"\x83\xed\x9b]\x0dGb\xf7\x8c[\x81k\x84\x03$\x8d" +
+	"4z\x0b\x05\xe5R0;k\xb1\x96j\xe38;w" +
+	";p;\xb373wp\xc5\x8bE{-\xd7\x1eV" +
+	",6\x11\xa9Q\xcc}\x806Ql\x89\xd1\xc4/X" +
+	"J<\x8d\xadiL\x9b\x08m\xaf$*\xb5\xb4i\x93" +
+	"k1\xe4n\x9agv\xdf\xd9\xb9\xdb\xfb\x07~\x9by" +
+	"\xdf\xe7\xfd\xbd\xcf\xff\xdf\xf3\xb6\xbf\"\xe5\x84\xb5\x91d" +
+	"3\x802\x12\x89z\x7f\xbb\xf1\x85wz\xf7\x17\x87@" +
+	"iA\x04\x90d\x80\xf5\x97#\xbb\x110\xf5Y\xa4\x03" +
+	"\xd0\xbb|\xe9\xadv\xe7\xeb/\x8d\x00k\xe1\xfb7E" +
+	"w!H\xde\x8a\x8bC\x85\xf1\xc9\xa7\x8eVw\"H" +
+	"[\xf1\xe8::\xca\xa2t\xf4\xa7\xa3W[N\xff\xe8" +
+	"\xb9\xe3\xa1\xa3k\xa3o\xd0\xd1_\x7f\xd2\xfe\xe9\xa5\x0b" +
+	"\xb7\x8c\x86vn\xae\x82>\xf8\xaf\xee?\xf4\xef\xba\xff" +
+	"\x04\xb0e\x01\xe8\x92\xe8q\x02\xbd\xc9\x07\xddv\xe5\xf1" +
+	"\x91\xff<\xbe\xf6dXa%\xfa]\x12x\xc8\x17x" +
+	"W\xde\xf9\xee_^^w2\xac\xd6\x81\xe8#$p" +
+	"\xc8\x17\xf0\xce\x8d|\xf3\x85/\xdf\xf6\x0a\xb0\xb4\xe8}" +
+	"d\xf6m\xb8*\xbf\xf3\x1c\x00\xa6\xde\x8c\x8e\xa5\xde\x8e" +
+	"\xca\x00\xa9\xb3\xd1{R\x97\xe9\xcb\x9bx\xeb\xe1'\x9f" +
+	"\xb4\x93\xaf\x86\xaf\xfb\x80T\xc5\xd4\xb8\x8f\xd6\x8a\xcd\xb9" +
+	"[\xd8\x8f\x7f\x15V8\"\xdb\xbe\x17d\x12\x98\x9cz" +
+	"zM\xfe\x81\xce\xd7\x1a\xae\xdb \x9fI\xdd)\x93\xfc" +
+	"\x1d\xf2\x0f1ugl\x05\x80\xe7\x9e\xbfq|\xe0\xde" +
+	"\xe8\x990\xdc\x86\xd8Q\x82\xdb\x1a#\xb8\xf1\xee\xd2r" +
+	"'q\xbc&\xe0\xeb3\x10\xdbM\xaeS\x1e\xcc\x0f~" +
+	"\xb8\xf3\xe1\xdf\xd6\x8eFhK\x8f\x91\xe1\xeb{c\x19" +
+	"\x04\xf4\x06\xb2\x13K\x8f\x0a\x07\xcf\x85]3\x1c\xf7]" +
+	"\xf3L\x9c\xc0\xff\xfb\xda^\xe3\xae\xdf\x98\xef\x85\xc0O" +
+	"\xc7}\xf0\x9f\xdd\xfa\xbfW[[O\xbe\x17\x8a\xd8\x8b" +
+	"\xf1u\xb4\xf3\xd1\xf2/=\xbf?\xbd\xea\xfd\xb0\xc6\xc3" +
+	"q\xbb\x0e\xca\x8etv\xdf+i\xe7CGO\xc7\xfd" +
+	"`?q\xeb\xc1/.O\xfe\xf3\xfc4P\x9bv\xb6" +
+	"\xdd\xb0r\x95\xf6\x8f\xf5\x17B\x8a\x1c\xaa*\xb2g\xed" +
+	"\x0fz\xff\xf4(\xfe9l\x7f|#\xed\x8cd\xc7\xcc" +
+	"\xbb'O\x8c\x87\xd0\xd4x\x1b\xed\xdc\xc5\xb6\xb0\xc1\xbf" +
+	"\xbe\xf8q8\x88\x9d\xf13\xa4\xe2\xb7|\x15\xd5\x9f<" +
+	"P\x1a\xb6~\xf91\xb04\xd6c\x14\x11(\x17\x06\xe3" +
+	"c\xa9\xe18}\x0d\xc5\xf7\x02z\xc5\xf6\x09\xef;\x17" +
+	"\xbf\xfa\xf7\xb0\xbd\xe3\xf1\xcd\x04v\xc9\x07K|\xe5\xa5" +
+	"\x0f/\xb6\\\xf8\x14\x94e\xc1m-M\xbe\xc0\xcdM" +
+	"$`\xef\xfb\xddY\xb9\xcd\x98h\xc8\x88MMc\xa9" +
+	"\x1dM\xbevM\xb2\x90:\x91\x90\x01&\x8f}\xd2\xfe" +
+	"|\xee\xf6+\xa1\x90\x1dN\xf8!\xfby\x82\xc0\xce\x0d" +
+	"\xee9p\xbf:u%d\xf5\x9b\x09\xdf\xea?J\x03" +
+	"[\x9f~\"\xfbY8\xda\xa3\x09?0\xa7\xfc\xa3R" +
+	"\xa9\xf7\xf7\x87\x0a\xbf\xb8\x1ar\xe5\xfb\x09\xdf\x95\xf6\x0a" +
+	"\xe7\x99\xf6;n\x98\x0a\x1f}\xbdz\xebY\xffh\xeb" +
+	"\x91o7\xdf\xb3\x7f\xeb\xd4\x0c\x87\x89\xe4\xa6\x89\xc4X" +
+	"\x0a\x9b\xe9k2\xf12\xa0g\xea\xee\x1aM\xad\x98R" +
+	"e\x8dZ1V\xd3ge\xe3}\x03\xa6\xb6\xbaKw" +
+	"\xb5R^u\xb5\x92\xeed\xf3j\xd2V\xcb\x8e\"\x89" +
+	"\x12\x80\x84\x00lI\x01@i\x16QY&\xa0\xd7e" +
+	"[\xe5N\xb3\xa8\x03\xee\xc3\x08\x08\x18Y\x18y\xbb\xa5" +
+	"\xed!\\[\x15\xcbN ,\x86\x85w\xe8\xae\xba\xba" +
+	"b\x98\xdd\xd9\x82\x9eq\xfaz\xdci\xf7\xaf\x03Pb" +
+	"\"*i\x013\xb6^\xe9\x19\xc0f\x10\xb09ts" +
+	"\xa4\xe1f\xc3\xf9\x9aU\xae\xf4\xe8\xae~7\xe9\xb0\xa9" +
+	"\xa7\xc7\xda\xab\x17\xb3\x1d\xa4F\xd9YH\xe5-\x86\xe6" +
+	"6\xaa<\xed\x96M\xf9\xce\xd5\xfa>\xad\xa4\x9a\xddz" +
+	"A\xef\xb74\xd55,\xd3\xc9\x16t\xa7O\x9ea\xc0" +
+	"#5\x07\xae\x14\xd0\xb3k\xb2 [\xa6\x83K\x01\xf3" +
+	"\"\xfa\xf6,\x9d\xd7\x93\x86\x93\xefs\x023\x0a\x1dz" +
+	"\x83\x97\xc2Q2\x9c\xaa$`\x11\x11\x04\xc4\xc5Fi" +
+	"6\xed\xb9\xfb\xb3\x02fzH\x8ak\xfd\x85z\xfe\x01" +
+	"N\xd3_\x98\x19\\\x80<\xa2\"\x89\x11\x80\xa0A " +
+	"g.\xc6\xda@`\x119I\x19\x90\xc3<..Y" +
+	"y|>w\xaaR(K*y\xb7\xdb\xb2\x0d\xb7T" +
+	"\x9e5\x0b\xc3\xd0jM\x12\xb0\xdc\x90\x8b\xc2\x0ch\xdf" +
+	"\xf2e\xbe\xe5\xbc\xd2\x91\xf7&vj3\x08lT\xc6" +
+	"z\xcfG\xced\xecY\x1b\x04vXF!\xa0\x14\xe4" +
+	"\xac\xc3\x86v\x83\xc0\x06e\x14\x03\xa6BN\xd9\xac\xf7" +
+	"8\x08\xac,c\xbdI#o\x96L\xa5\xfbv\xca\x18" +
+	"\x09Z;r\xe2`\x9dt\xdf&\xf9\xb1~\xddv\x0c" +
+	"\xcb\xcc\xa1\xc7\x9d\x02\x19\xdf-9\xf4TM\xd3+n" +
+	"\xa7\x09\xc9~\xc3\xd5s\xe8\xf1\x12@^\x03\x94\xd69" +
+	"|\xac\xa8\xf7\x1b\x9a\xee\xe4\xd0\xab~\x15t\xc8\x94-" +
+	"\x97V\x94\x18\x86\x9a/@}\x12\x00\xb8\xb6Z\x9b%" +
+	"\x01>_\xa9\xd1E\xdcD2\x90\xea!I\x89\xb0H" +
+	"\xf1\xbcj\xcb\xd4<\xd3\x81B\x83\xcb\x01\x94}\"*" +
+	"\xdf\x17\x90!\xa6\x91\x16\x0fl\x04P\x1e\x15Q9(" +
+	" \x13\x844\x0a\x00l\xa8\x0d@\xf9\x9e\x88\xca\x88\x80" +
+	"L\x14\xd3(\x02\xb0a\xb2\xe7\xa0\x88\xca\x11\x01\x99$" +
+	"\xa5Q\x02`\x87i\xf1)\x11\x95c\x02\x8aF\x91g" +
+	"`\x87\xa3k\xb6\xee\xf2\xdf\xa4\xa9\x96u\xfe\xe3u\x19" +
+	"f\xb7nWl\x90\x0d3\x10\xa9\xc5&o\x83lY" +
+	"]\x0d\x99<o\xf1\xcd\xd6)\xda\xea\x8d:YT]" +
+	"\x15\x97\x80\x80K\x16_v5\xee\x99\x9d#|\x05*" +
+	"}N)\xa8\xce\xb9ay\xce\xf9\x197k5_\x9b" +
+	"\xaau\xe3\xefs-[o`\x86\x85\xfau>3\x0f" +
+	"\xed4*<\xaf\x1f\xea\xe2N\x03\x9d\xcdA\xaa3\xf1" +
+	"\xa2\x8b\xa5\xcbB\x95f\xe0zxfZ\x1f$B\xad" +
+	"r@,@ZEU\x90\x15Qi\x0f\x95\xc6m\x14" +
+	"\x98\x95\"*\xb7\x0b\xd8\xd1e\xf5\x14u;H\xe8\xc5" +
+	"\xa5\x14\xf7\xcdl\xbao\xacSYG\xc5\xb6\xac\xae\xc5" +
+	"\xb7\x85ZW\x9c\x15vs=\x99x\xf7D\x09\x04\x94" +
+	"\xe6r\x07\xf9\xbc\xea\x8eV\x9f\x18\xf8l\x8e\xc7\xa06" +
+	"f\xfe{\x17\x08\xec\x12\x11\x03\x7f\xfa \x7f6\xb0\x0b" +
+	"\xb4\xf7\x01\x11\x03\x7f\xda!\x1f\xad\xd9\xdbo\x80\xc0\xce" +
+	"\x121\xf0\xa9\x1e\xf9S\x8d\xbdN\x0d\xfe\x14\x11\x03\x9f" +
+	"V\x91\xbf'\xd8(Q\xf0\xb3D\x0c\xfc)\x8a|p" +
+	"e\x87\x88l\x86d\x8c\x06\xafP\xe4\xaf;6@\xba" +
+	"\xf4\xca(\x07\x8fI\xe4S+\xd3i\xef!\xd9\xe3\x85" +
+	"\x03\xa2M\x94\xc1\x9b\x08\x88Z)\x87\x1eO=\xe4\xb9" +
+	"\xd7QM>\x7f\xabZD\x90\xa9\xad$\xa9\xfe\xc3\x10" +
+	"I\x9aY\xf9\xc2vK\x03qO\xf0\xbb\xc5\xd0@t" +
+	"\x9dE\x0d\x14\xd5\xb2\xbe\xae\x9e6G\x93\x9aYs\x0b" +
+	"\x8c\xdd\xd7\xd5\xa3\xc4\xb9\x92\xf4\x9a\xc6\xdc\x85f\xbe\"" +
+	"I\xd5g\xbe\xe0\x916\xdf\xccG\xd3d5\xc1\x9b\x03" +
+	"\xd0\xaddONDe{\xa8\xde;\xe9\xa6-\"*" +
+	"\xf9\x10\x15\xee\xa0\xc5m\"*\xdf\x100YQ\xdd\x12" +
+	"o\x01\x19k\xafYo\x08\x19\xc70\xb5\x80\xe2\xfe?" +
+	"\x00u\xc8\xe2S"
 
 func init() {
 	schemas.Register(schema_9bcb07fb35756ee6,
 		0x85647b71cba016e2,
+		0x8ca34b7330c3e9ed,
 		0x9a90fde15285e327,
 		0xa29b8ab519fba593,
+		0xa523dde9eb30e8b4,
+		0xa9565a76d367ef5c,
 		0xaa3182f28c82f848,
+		0xaa32afdfcc5507cc,
 		0xb02d2ba0578cc7ff,
 		0xb20f728e8e60c3f5,
+		0xb38b1123400c0120,
 		0xb74958502f92fefd,
+		0xc2064e79e116dc74,
+		0xc2a20b731a6867e1,
+		0xc66055db7d505c51,
 		0xc788029a0ef52479,
+		0xce6ec43e6977b7f6,
 		0xceaa2020b2f72696,
+		0xd129147b9e251ae6,
 		0xdc63044e67499411,
 		0xdcee0f1a1e882683,
+		0xdd33ec6329281548,
+		0xde017cd87186316b,
 		0xe1a9fd466eca248c,
 		0xe7a1e07d1144113e,
+		0xe7ac6f8968588f61,
+		0xea3be35efff53064,
 		0xebdd19e3dba3370b,
 		0xf5692a07c5cf7872,
 		0xf834409e30e8009c,
 		0xf8fe6156816b7dc7,
 		0xf9248392457904d7,
-		0xfbab528dd0716804)
+		0xfbab528dd0716804,
+		0xfe15393095732772,
+		0xfe457b470c5b9420)
 }
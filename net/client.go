@@ -6,13 +6,19 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"time"
 
 	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/mio/compress"
 	netBackend "github.com/sahib/brig/net/backend"
 	"github.com/sahib/brig/net/capnp"
 	"github.com/sahib/brig/net/peer"
 	"github.com/sahib/brig/repo"
+	h "github.com/sahib/brig/util/hashlib"
 	log "github.com/sirupsen/logrus"
+	capnplib "zombiezen.com/go/capnproto2"
 	"zombiezen.com/go/capnproto2/rpc"
 )
 
@@ -35,13 +41,19 @@ func Dial(ctx context.Context, name string, rp *repo.Repository, bk netBackend.B
 		return nil, err
 	}
 
-	addr := remote.Fingerprint.Addr()
-	ctl, err := DialByAddr(ctx, addr, remote.Fingerprint, rp, bk, pingMap)
-	if err != nil {
-		return nil, e.Wrapf(err, "by-addr")
+	// Try all devices of the remote in order, until one answers:
+	var lastErr error
+	for _, fp := range remote.Fingerprints() {
+		ctl, err := DialByAddr(ctx, fp.Addr(), fp, rp, bk, pingMap)
+		if err == nil {
+			return ctl, nil
+		}
+
+		log.Debugf("failed to dial device %s of %s: %v", fp, name, err)
+		lastErr = err
 	}
 
-	return ctl, nil
+	return nil, e.Wrapf(lastErr, "by-addr")
 }
 
 // DialByAddr is like Dial but does not get its info from the remote list.
@@ -85,6 +97,8 @@ func DialByAddr(
 		return nil
 	})
 
+	authConn.SetRevocationChecker(rp.Revocations)
+
 	// Trigger the authentication:
 	// (otherwise it would be triggered on the first read/write)
 	if err := authConn.Trigger(); err != nil {
@@ -94,18 +108,101 @@ func DialByAddr(
 
 	pingMap.hintNetAttempt(addr, true)
 
+	// Limit the connection like all other traffic with this remote.
+	// (Peers we only have an invite of are not known yet)
+	remoteName := ""
+	if remote, err := rp.Remotes.RemoteByPubKeyID(fingerprint.PubKeyID()); err == nil {
+		remoteName = remote.Name
+	}
+
+	rawConn = rp.Bandwidth.Conn(rawConn, remoteName)
+
 	// Setup capnp-rpc:
 	transport := rpc.StreamTransport(rawConn)
 	clientConn := rpc.NewConn(transport, rpc.ConnLog(nil))
 	api := capnp.API{Client: clientConn.Bootstrap(ctx)}
 
-	return &Client{
+	cl := &Client{
 		ctx:      ctx,
 		authConn: authConn,
 		conn:     clientConn,
 		rawConn:  rawConn,
 		api:      api,
-	}, nil
+	}
+
+	// Syncing with a remote that uses other hashes would produce
+	// nothing but conflicts; refuse to talk to it in the first place.
+	if err := cl.checkHashAlgorithm(rp.Immutables.HashAlgorithm()); err != nil {
+		cl.Close()
+		return nil, err
+	}
+
+	return cl, nil
+}
+
+// AcceptInviteToken contacts the peer that created `token` and accepts its
+// invite. The signature of the token is checked with the public key that
+// the inviter authenticated with. It is up to the caller to add the
+// inviter to its own remotes afterwards.
+func AcceptInviteToken(
+	ctx context.Context,
+	token *repo.InviteToken,
+	rp *repo.Repository,
+	bk netBackend.Backend,
+	pingMap *PingMap,
+) error {
+	if token.IsExpired() {
+		return fmt.Errorf("invite of %s expired at %s", token.Name, token.Expires.Format(time.RFC3339))
+	}
+
+	owner := peer.Name(rp.Immutables.Owner())
+	if token.Device && !owner.SameUser(peer.Name(token.Name)) {
+		return fmt.Errorf("»%s« is not a device of our user (%s)", token.Name, owner)
+	}
+
+	kr, err := rp.Keyring()
+	if err != nil {
+		return err
+	}
+
+	ownPubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return err
+	}
+
+	self, err := bk.Identity()
+	if err != nil {
+		return err
+	}
+
+	ctl, err := DialByAddr(ctx, token.Fingerprint.Addr(), token.Fingerprint, rp, bk, pingMap)
+	if err != nil {
+		return e.Wrapf(err, "by-addr")
+	}
+
+	defer ctl.Close()
+
+	if err := token.Verify(ctl.RemotePubKey()); err != nil {
+		return err
+	}
+
+	ownFp := peer.BuildFingerprint(self.Addr, ownPubKey)
+
+	// The inviter passes our proof on to other peers, which only
+	// trust the devices of a user that proved to be one:
+	deviceProof := ""
+	if token.Device {
+		proof, err := rp.OwnDeviceProof(ownFp)
+		if err != nil {
+			return err
+		}
+
+		if deviceProof, err = proof.Encode(); err != nil {
+			return err
+		}
+	}
+
+	return ctl.AcceptInvite(token.ID, token.Secret, rp.Immutables.Owner(), ownFp, deviceProof)
 }
 
 // PeekRemotePubkey connects to `addr` and tries to read the public key they claim.
@@ -165,6 +262,86 @@ func (cl *Client) Ping() error {
 	return err
 }
 
+// HashAlgorithm returns the hash algorithm of the remote.
+// Remotes that are too old to tell use the default algorithm.
+func (cl *Client) HashAlgorithm() (h.Algorithm, error) {
+	call := cl.api.HashAlgorithm(cl.ctx, func(p capnp.API_hashAlgorithm_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		if isUnimplemented(err) {
+			return h.DefaultAlgorithm, nil
+		}
+
+		return 0, err
+	}
+
+	name, err := result.Algorithm()
+	if err != nil {
+		return 0, err
+	}
+
+	return h.ParseAlgorithm(name)
+}
+
+func (cl *Client) checkHashAlgorithm(own h.Algorithm) error {
+	remote, err := cl.HashAlgorithm()
+	if err != nil {
+		return e.Wrapf(err, "failed to get hash algorithm of remote")
+	}
+
+	if remote != own {
+		return fmt.Errorf(
+			"remote uses the hash algorithm %s, but we use %s (see »brig repo rehash«)",
+			remote, own,
+		)
+	}
+
+	return nil
+}
+
+// RemotePubKey returns the public key the remote authenticated with.
+func (cl *Client) RemotePubKey() []byte {
+	return cl.authConn.RemotePubKey()
+}
+
+// AcceptInvite accepts the invite with `id` and `secret` that the remote
+// gave us. The remote will add us as `name` with `fingerprint`.
+// `deviceProof` is the encoded DeviceProof for device invites.
+func (cl *Client) AcceptInvite(id, secret, name string, fingerprint peer.Fingerprint, deviceProof string) error {
+	call := cl.api.AcceptInvite(cl.ctx, func(p capnp.API_acceptInvite_Params) error {
+		if err := p.SetId(id); err != nil {
+			return err
+		}
+
+		if err := p.SetSecret(secret); err != nil {
+			return err
+		}
+
+		if err := p.SetName(name); err != nil {
+			return err
+		}
+
+		if err := p.SetDeviceProof(deviceProof); err != nil {
+			return err
+		}
+
+		return p.SetFingerprint(string(fingerprint))
+	})
+
+	_, err := call.Struct()
+	return err
+}
+
+// isUnimplemented checks if `err` was caused by calling a method that
+// the remote does not know yet. The error is transferred as text only.
+func isUnimplemented(err error) bool {
+	return capnplib.IsUnimplemented(err) ||
+		strings.Contains(err.Error(), capnplib.ErrUnimplemented.Error())
+}
+
 // FetchStore tries to fetch all store data from the remote.
 // This will only work when the other store allowed us to access all folders.
 // (See IsCompleteFetchAllowed)
@@ -218,6 +395,110 @@ func (cl *Client) FetchPatches(fromIndex int64) ([]byte, error) {
 	return result.Data()
 }
 
+// RemoteDict is a compression dictionary of the remote.
+type RemoteDict struct {
+	// Folder is the folder the dictionary was trained for.
+	Folder string
+
+	// Dict is the dictionary itself.
+	Dict *compress.Dict
+}
+
+// FetchDicts returns the compression dictionaries of the remote
+// that are used in folders we are allowed to see.
+func (cl *Client) FetchDicts() ([]RemoteDict, error) {
+	call := cl.api.FetchDicts(cl.ctx, func(p capnp.Sync_fetchDicts_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capDicts, err := result.Dicts()
+	if err != nil {
+		return nil, err
+	}
+
+	dicts := []RemoteDict{}
+	for idx := 0; idx < capDicts.Len(); idx++ {
+		capDict := capDicts.At(idx)
+		folder, err := capDict.Folder()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := capDict.Data()
+		if err != nil {
+			return nil, err
+		}
+
+		// NOTE: capnp data is only valid as long as the message lives.
+		dict, err := compress.NewDict(append([]byte{}, data...))
+		if err != nil {
+			return nil, err
+		}
+
+		dicts = append(dicts, RemoteDict{
+			Folder: folder,
+			Dict:   dict,
+		})
+	}
+
+	return dicts, nil
+}
+
+// FetchLocks returns the locks that the remote holds in the folders we
+// are allowed to see.
+func (cl *Client) FetchLocks() ([]catfs.Lock, error) {
+	call := cl.api.FetchLocks(cl.ctx, func(p capnp.Sync_fetchLocks_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capLocks, err := result.Locks()
+	if err != nil {
+		return nil, err
+	}
+
+	locks := []catfs.Lock{}
+	for idx := 0; idx < capLocks.Len(); idx++ {
+		capLock := capLocks.At(idx)
+		path, err := capLock.Path()
+		if err != nil {
+			return nil, err
+		}
+
+		owner, err := capLock.Owner()
+		if err != nil {
+			return nil, err
+		}
+
+		sinceStr, err := capLock.Since()
+		if err != nil {
+			return nil, err
+		}
+
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			return nil, err
+		}
+
+		locks = append(locks, catfs.Lock{
+			Path:  path,
+			Owner: owner,
+			Since: since,
+		})
+	}
+
+	return locks, nil
+}
+
 // IsCompleteFetchAllowed asks the remote if we can use FetchStore.
 func (cl *Client) IsCompleteFetchAllowed() (bool, error) {
 	call := cl.api.IsCompleteFetchAllowed(cl.ctx, func(p capnp.Sync_isCompleteFetchAllowed_Params) error {
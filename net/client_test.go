@@ -12,6 +12,7 @@ import (
 	"github.com/sahib/brig/backend"
 	"github.com/sahib/brig/catfs"
 	ie "github.com/sahib/brig/catfs/errors"
+	"github.com/sahib/brig/catfs/mio/compress"
 	"github.com/sahib/brig/net/peer"
 	"github.com/sahib/brig/repo"
 	"github.com/stretchr/testify/require"
@@ -26,6 +27,10 @@ type testUnit struct {
 }
 
 func withNetServer(t *testing.T, name string, basePath string, fn func(u testUnit)) {
+	withNetServerAlgo(t, name, basePath, "", fn)
+}
+
+func withNetServerAlgo(t *testing.T, name string, basePath string, hashAlgo string, fn func(u testUnit)) {
 	basePath, err := ioutil.TempDir("", "brig-ctl-test")
 	require.Nil(t, err)
 
@@ -46,10 +51,11 @@ func withNetServer(t *testing.T, name string, basePath string, fn func(u testUni
 	require.Nil(t, err)
 
 	err = repo.Init(repo.InitOptions{
-		BaseFolder:  basePath,
-		Owner:       name,
-		BackendName: "mock",
-		DaemonURL:   "not-relevant-here",
+		BaseFolder:    basePath,
+		Owner:         name,
+		BackendName:   "mock",
+		DaemonURL:     "not-relevant-here",
+		HashAlgorithm: hashAlgo,
 	})
 	require.NoError(t, err)
 
@@ -298,3 +304,299 @@ func TestClientCompleteFetchAllowed(t *testing.T) {
 		require.True(t, isAllowed)
 	})
 }
+
+func TestClientFetchLocks(t *testing.T) {
+	withNetPair(t, func(a, b testUnit) {
+		require.NoError(t, a.fs.Stage("/public/x", bytes.NewReader([]byte{1})))
+		require.NoError(t, a.fs.Stage("/private/y", bytes.NewReader([]byte{2})))
+		require.NoError(t, a.fs.Lock("/public/x"))
+		require.NoError(t, a.fs.Lock("/private"))
+
+		locks, err := b.ctl.FetchLocks()
+		require.NoError(t, err)
+		require.Len(t, locks, 2)
+
+		// bob should only see locks in the folders he has access to:
+		require.NoError(t, a.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+			Name:        "bob",
+			Fingerprint: buildFingerprint(t, b),
+			Folders:     []repo.Folder{{Folder: "/public"}},
+		}))
+
+		locks, err = b.ctl.FetchLocks()
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+		require.Equal(t, "/public/x", locks[0].Path)
+		require.Equal(t, "alice", locks[0].Owner)
+	})
+}
+
+func TestClientFetchDicts(t *testing.T) {
+	withNetPair(t, func(a, b testUnit) {
+		samples := [][]byte{}
+		for idx := 0; idx < 50; idx++ {
+			samples = append(samples, []byte(fmt.Sprintf("GET /index.html?page=%d HTTP/1.1 200", idx)))
+		}
+
+		dict, err := compress.TrainDict(samples, compress.DefaultDictSize)
+		require.NoError(t, err)
+		require.NoError(t, a.rp.Dicts.Add("/private/logs", dict))
+
+		dicts, err := b.ctl.FetchDicts()
+		require.NoError(t, err)
+		require.Len(t, dicts, 1)
+		require.Equal(t, "/private/logs", dicts[0].Folder)
+		require.Equal(t, dict.ID, dicts[0].Dict.ID)
+
+		// bob may not see files in /private, so he does not need the dict:
+		require.NoError(t, a.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+			Name:        "bob",
+			Fingerprint: buildFingerprint(t, b),
+			Folders:     []repo.Folder{{Folder: "/public"}},
+		}))
+
+		dicts, err = b.ctl.FetchDicts()
+		require.NoError(t, err)
+		require.Len(t, dicts, 0)
+	})
+}
+
+func TestClientHashAlgorithmMismatch(t *testing.T) {
+	withNetServer(t, "alice", "", func(a testUnit) {
+		withNetServerAlgo(t, "bob", "", "sha2-256", func(b testUnit) {
+			require.Nil(t, a.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+				Name:        "bob",
+				Fingerprint: buildFingerprint(t, b),
+			}))
+			require.Nil(t, b.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+				Name:        "alice",
+				Fingerprint: buildFingerprint(t, a),
+			}))
+
+			_, err := Dial(context.Background(), "bob", a.rp, a.bk, nil)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "sha2-256")
+		})
+	})
+}
+
+func TestClientAcceptInvite(t *testing.T) {
+	withNetServer(t, "alice", "", func(a testUnit) {
+		withNetServer(t, "bob", "", func(b testUnit) {
+			ctx := context.Background()
+			aliceKr, err := a.rp.Keyring()
+			require.NoError(t, err)
+
+			folders := []repo.Folder{{Folder: "/shared"}}
+			invite, err := a.rp.Invites.Create(folders, true, time.Hour)
+			require.NoError(t, err)
+
+			encoded, err := repo.NewInviteToken("alice", buildFingerprint(t, a), invite).Encode(aliceKr)
+			require.NoError(t, err)
+
+			token, err := repo.DecodeInviteToken(encoded)
+			require.NoError(t, err)
+
+			// Guests may not use anything else:
+			ctl, err := DialByAddr(ctx, token.Fingerprint.Addr(), token.Fingerprint, b.rp, b.bk, nil)
+			require.NoError(t, err)
+			_, err = ctl.FetchStore()
+			require.Error(t, err)
+			_, err = ctl.ExchangeRevocations(b.rp)
+			require.Error(t, err)
+
+			// Wrong secrets do not work:
+			err = ctl.AcceptInvite(token.ID, "wrong", "bob", buildFingerprint(t, b), "")
+			require.Error(t, err)
+			require.NoError(t, ctl.Close())
+
+			require.NoError(t, AcceptInviteToken(ctx, token, b.rp, b.bk, nil))
+
+			remote, err := a.rp.Remotes.Remote("bob")
+			require.NoError(t, err)
+			require.Equal(t, buildFingerprint(t, b), remote.Fingerprint)
+			require.Equal(t, folders, remote.Folders)
+			require.True(t, remote.AcceptPush)
+
+			// Invites are only valid once:
+			require.Error(t, AcceptInviteToken(ctx, token, b.rp, b.bk, nil))
+
+			// Without pending invites, unknown peers are not let in:
+			require.NoError(t, a.rp.Remotes.RmRemote("bob"))
+			_, err = DialByAddr(ctx, token.Fingerprint.Addr(), token.Fingerprint, b.rp, b.bk, nil)
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestClientExchangeRevocations(t *testing.T) {
+	withNetPair(t, func(a, b testUnit) {
+		aliceFp, bobFp := buildFingerprint(t, a), buildFingerprint(t, b)
+
+		_, charliePubKey := createKeyPair(t, 1024)
+		charlieFp := peer.BuildFingerprint("QmCharlie", charliePubKey)
+		for _, u := range []testUnit{a, b} {
+			require.NoError(t, u.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+				Name:        "charlie",
+				Fingerprint: charlieFp,
+			}))
+		}
+
+		// Alice tells everyone that charlie lost his device:
+		aliceKr, err := a.rp.Keyring()
+		require.NoError(t, err)
+
+		rv := &repo.Revocation{Revoked: charlieFp, Issuer: aliceFp, Created: time.Now().UTC()}
+		require.NoError(t, rv.Sign(aliceKr))
+		_, err = a.rp.AddRevocations([]*repo.Revocation{rv})
+		require.NoError(t, err)
+
+		added, err := b.ctl.ExchangeRevocations(b.rp)
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+
+		_, err = b.rp.Remotes.Remote("charlie")
+		require.Equal(t, repo.ErrNoSuchRemote, err)
+
+		// Bob rotates his key and tells alice about it:
+		bobKr, err := b.rp.Keyring()
+		require.NoError(t, err)
+		require.NoError(t, bobKr.CreateNextKey(1024))
+		nextPubKey, err := bobKr.NextPubKey()
+		require.NoError(t, err)
+
+		newBobFp := peer.BuildFingerprint(bobFp.Addr(), nextPubKey)
+		rotation := &repo.Revocation{
+			Revoked:   bobFp,
+			Successor: newBobFp,
+			Issuer:    bobFp,
+			Created:   time.Now().UTC(),
+		}
+
+		require.NoError(t, rotation.Sign(bobKr))
+		_, err = b.rp.AddRevocations([]*repo.Revocation{rotation})
+		require.NoError(t, err)
+
+		added, err = b.ctl.ExchangeRevocations(b.rp)
+		require.NoError(t, err)
+		require.Len(t, added, 0)
+
+		bobRemote, err := a.rp.Remotes.Remote("bob")
+		require.NoError(t, err)
+		require.Equal(t, newBobFp, bobRemote.Fingerprint)
+
+		// With the new key, alice can talk to bob again:
+		require.NoError(t, bobKr.SwitchToNextKey())
+		ctl, err := Dial(context.Background(), "bob", a.rp, a.bk, nil)
+		require.NoError(t, err)
+		require.NoError(t, ctl.Ping())
+		require.NoError(t, ctl.Close())
+
+		// The old fingerprint does not work anymore:
+		_, err = DialByAddr(context.Background(), bobFp.Addr(), bobFp, a.rp, a.bk, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestClientDevices(t *testing.T) {
+	withNetServer(t, "ali/laptop", "", func(laptop testUnit) {
+		withNetServer(t, "ali/desktop", "", func(desktop testUnit) {
+			withNetServer(t, "carol", "", func(carol testUnit) {
+				ctx := context.Background()
+				laptopFp, desktopFp := buildFingerprint(t, laptop), buildFingerprint(t, desktop)
+				carolFp := buildFingerprint(t, carol)
+
+				laptopKr, err := laptop.rp.Keyring()
+				require.NoError(t, err)
+
+				invite, err := laptop.rp.Invites.CreateForDevice(time.Hour)
+				require.NoError(t, err)
+
+				encoded, err := repo.NewInviteToken("ali/laptop", laptopFp, invite).Encode(laptopKr)
+				require.NoError(t, err)
+
+				token, err := repo.DecodeInviteToken(encoded)
+				require.NoError(t, err)
+
+				// Carol is a different user and can't become a device of ali:
+				require.Error(t, AcceptInviteToken(ctx, token, carol.rp, carol.bk, nil))
+
+				// A bad device proof does not use up the invite:
+				guestCtl, err := DialByAddr(ctx, laptopFp.Addr(), laptopFp, desktop.rp, desktop.bk, nil)
+				require.NoError(t, err)
+				err = guestCtl.AcceptInvite(token.ID, token.Secret, "ali/desktop", desktopFp, "bogus")
+				require.Error(t, err)
+				require.NoError(t, guestCtl.Close())
+
+				require.NoError(t, AcceptInviteToken(ctx, token, desktop.rp, desktop.bk, nil))
+
+				remote, err := laptop.rp.Remotes.Remote("ali/desktop")
+				require.NoError(t, err)
+				require.True(t, remote.Device)
+				require.True(t, remote.AcceptPush)
+				require.NoError(t, laptop.rp.CheckDeviceProof(remote.DeviceProof, desktopFp))
+
+				require.NoError(t, desktop.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+					Name:        "ali/laptop",
+					Fingerprint: laptopFp,
+					Device:      true,
+				}))
+
+				// Carol knows ali only by the laptop:
+				require.NoError(t, carol.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+					Name:        "ali",
+					Fingerprint: laptopFp,
+				}))
+				require.NoError(t, laptop.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+					Name:        "carol",
+					Fingerprint: carolFp,
+				}))
+
+				ctl, err := Dial(ctx, "ali", carol.rp, carol.bk, nil)
+				require.NoError(t, err)
+
+				proofs, err := ctl.Devices()
+				require.NoError(t, err)
+				require.Len(t, proofs, 2)
+				require.Equal(t, laptopFp, proofs[0].Fingerprint)
+				require.Equal(t, desktopFp, proofs[1].Fingerprint)
+
+				self, devices, err := carol.rp.VerifyDevices("ali", ctl.RemotePubKey(), proofs)
+				require.NoError(t, err)
+				require.Equal(t, "ali/laptop", self.Name)
+				require.Equal(t, []peer.Fingerprint{desktopFp}, devices)
+
+				// Only devices may see the remote list:
+				_, err = ctl.DeviceRemotes()
+				require.Error(t, err)
+				require.NoError(t, ctl.Close())
+
+				ctl, err = Dial(ctx, "ali/laptop", desktop.rp, desktop.bk, nil)
+				require.NoError(t, err)
+
+				remotes, err := ctl.DeviceRemotes()
+				require.NoError(t, err)
+				require.NoError(t, ctl.Close())
+
+				for _, remote := range remotes {
+					if remote.Name == "carol" {
+						require.NoError(t, desktop.rp.Remotes.AddOrUpdateRemote(remote))
+					}
+				}
+
+				// If the laptop is not reachable, carol tries the desktop:
+				_, unknownPubKey := createKeyPair(t, 1024)
+				require.NoError(t, carol.rp.Remotes.AddOrUpdateRemote(repo.Remote{
+					Name:        "ali",
+					Fingerprint: peer.BuildFingerprint("QmOffline", unknownPubKey),
+					Devices:     []peer.Fingerprint{desktopFp},
+				}))
+
+				ctl, err = Dial(ctx, "ali", carol.rp, carol.bk, nil)
+				require.NoError(t, err)
+				require.True(t, desktopFp.PubKeyMatches(ctl.RemotePubKey()))
+				require.NoError(t, ctl.Close())
+			})
+		})
+	})
+}
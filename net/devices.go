@@ -0,0 +1,168 @@
+package net
+
+import (
+	"fmt"
+
+	"github.com/sahib/brig/net/capnp"
+	"github.com/sahib/brig/net/peer"
+	"github.com/sahib/brig/repo"
+	log "github.com/sirupsen/logrus"
+	yml "gopkg.in/yaml.v2"
+)
+
+// ownDeviceProofs returns the proof of our own key, followed by
+// the ones of the other devices of our user that we got a proof of.
+func ownDeviceProofs(rp *repo.Repository, ownFp peer.Fingerprint) ([]string, error) {
+	ownProof, err := rp.OwnDeviceProof(ownFp)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := ownProof.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes, err := rp.Remotes.ListRemotes()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{ownFp.PubKeyID(): true}
+	proofs := []string{encoded}
+	for _, remote := range remotes {
+		if !remote.Device || remote.DeviceProof == "" || seen[remote.Fingerprint.PubKeyID()] {
+			continue
+		}
+
+		seen[remote.Fingerprint.PubKeyID()] = true
+		proofs = append(proofs, remote.DeviceProof)
+	}
+
+	return proofs, nil
+}
+
+func (hdl *requestHandler) Devices(call capnp.API_devices) error {
+	if hdl.currRemoteName == "" {
+		return fmt.Errorf("only remotes may ask for our devices")
+	}
+
+	kr, err := hdl.rp.Keyring()
+	if err != nil {
+		return err
+	}
+
+	ownPubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return err
+	}
+
+	self, err := hdl.bk.Identity()
+	if err != nil {
+		return err
+	}
+
+	proofs, err := ownDeviceProofs(hdl.rp, peer.BuildFingerprint(self.Addr, ownPubKey))
+	if err != nil {
+		return err
+	}
+
+	capProofs, err := call.Results.NewProofs(int32(len(proofs)))
+	if err != nil {
+		return err
+	}
+
+	for idx, proof := range proofs {
+		if err := capProofs.Set(idx, proof); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (hdl *requestHandler) DeviceRemotes(call capnp.API_deviceRemotes) error {
+	currRemote, err := hdl.rp.Remotes.Remote(hdl.currRemoteName)
+	if err != nil {
+		return err
+	}
+
+	if !currRemote.Device {
+		return fmt.Errorf("only our own devices may read our remote list")
+	}
+
+	remotes, err := hdl.rp.Remotes.ListRemotes()
+	if err != nil {
+		return err
+	}
+
+	data, err := yml.Marshal(remotes)
+	if err != nil {
+		return err
+	}
+
+	return call.Results.SetData(data)
+}
+
+// Devices returns the proofs of all devices of the remote's user,
+// starting with the one we are connected to. Proofs with a bad
+// signature are left out; use Repository.VerifyDevices to check
+// that the rest really belongs to the remote.
+func (cl *Client) Devices() ([]*repo.DeviceProof, error) {
+	call := cl.api.Devices(cl.ctx, func(p capnp.API_devices_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capProofs, err := result.Proofs()
+	if err != nil {
+		return nil, err
+	}
+
+	proofs := []*repo.DeviceProof{}
+	for idx := 0; idx < capProofs.Len(); idx++ {
+		capProof, err := capProofs.At(idx)
+		if err != nil {
+			return nil, err
+		}
+
+		proof, err := repo.DecodeDeviceProof(capProof)
+		if err != nil {
+			log.Warningf("ignoring bad device proof: %v", err)
+			continue
+		}
+
+		proofs = append(proofs, proof)
+	}
+
+	return proofs, nil
+}
+
+// DeviceRemotes returns the remote list of the remote.
+// This only works if we are one of its devices.
+func (cl *Client) DeviceRemotes() ([]repo.Remote, error) {
+	call := cl.api.DeviceRemotes(cl.ctx, func(p capnp.API_deviceRemotes_Params) error {
+		return nil
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := result.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := []repo.Remote{}
+	if err := yml.Unmarshal(data, &remotes); err != nil {
+		return nil, err
+	}
+
+	return remotes, nil
+}
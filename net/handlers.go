@@ -5,10 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/sahib/brig/backend"
+	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/mio/compress"
 	"github.com/sahib/brig/gateway/remotesapi"
 	"github.com/sahib/brig/net/capnp"
+	"github.com/sahib/brig/net/peer"
 	"github.com/sahib/brig/repo"
 	log "github.com/sirupsen/logrus"
 )
@@ -19,6 +23,14 @@ type requestHandler struct {
 	ctx            context.Context
 	rapi           remotesapi.RemotesAPI
 	currRemoteName string
+
+	// guestPubKey is set if the other side is not a remote of us,
+	// but was let in to accept an invite.
+	guestPubKey []byte
+
+	// onNewRevocations is called when the remote told us about
+	// revocations that we did not know yet.
+	onNewRevocations func(from string)
 }
 
 func completeExportAllowed(folders []repo.Folder) bool {
@@ -121,6 +133,117 @@ func (hdl *requestHandler) FetchPatches(call capnp.Sync_fetchPatches) error {
 	return nil
 }
 
+func (hdl *requestHandler) FetchLocks(call capnp.Sync_fetchLocks) error {
+	currRemote, err := hdl.rp.Remotes.Remote(hdl.currRemoteName)
+	if err != nil {
+		return err
+	}
+
+	fs, err := hdl.rp.FS(hdl.rp.Immutables.Owner(), hdl.bk)
+	if err != nil {
+		return err
+	}
+
+	ownLocks, err := fs.OwnLocks()
+	if err != nil {
+		return err
+	}
+
+	locks := []catfs.Lock{}
+	for _, lock := range ownLocks {
+		if currRemote.CanSee(lock.Path) {
+			locks = append(locks, lock)
+		}
+	}
+
+	seg := call.Results.Segment()
+	capLocks, err := capnp.NewLock_List(seg, int32(len(locks)))
+	if err != nil {
+		return err
+	}
+
+	for idx, lock := range locks {
+		capLock, err := capnp.NewLock(seg)
+		if err != nil {
+			return err
+		}
+
+		if err := capLock.SetPath(lock.Path); err != nil {
+			return err
+		}
+
+		if err := capLock.SetOwner(lock.Owner); err != nil {
+			return err
+		}
+
+		if err := capLock.SetSince(lock.Since.Format(time.RFC3339)); err != nil {
+			return err
+		}
+
+		if err := capLocks.Set(idx, capLock); err != nil {
+			return err
+		}
+	}
+
+	return call.Results.SetLocks(capLocks)
+}
+
+func (hdl *requestHandler) FetchDicts(call capnp.Sync_fetchDicts) error {
+	currRemote, err := hdl.rp.Remotes.Remote(hdl.currRemoteName)
+	if err != nil {
+		return err
+	}
+
+	// The remote gets a dictionary if it sees files in its folder:
+	dicts := []*compress.Dict{}
+	folders := []string{}
+	for _, info := range hdl.rp.Dicts.List() {
+		if !currRemote.CanSee(info.Folder) {
+			continue
+		}
+
+		id, err := compress.ParseDictID(info.ID)
+		if err != nil {
+			return err
+		}
+
+		dict, err := hdl.rp.Dicts.Dict(id)
+		if err != nil {
+			return err
+		}
+
+		dicts = append(dicts, dict)
+		folders = append(folders, info.Folder)
+	}
+
+	seg := call.Results.Segment()
+	capDicts, err := capnp.NewDict_List(seg, int32(len(dicts)))
+	if err != nil {
+		return err
+	}
+
+	for idx, dict := range dicts {
+		capDict, err := capnp.NewDict(seg)
+		if err != nil {
+			return err
+		}
+
+		if err := capDict.SetFolder(folders[idx]); err != nil {
+			return err
+		}
+
+		if err := capDict.SetData(dict.Data); err != nil {
+			return err
+		}
+
+		if err := capDicts.Set(idx, capDict); err != nil {
+			return err
+		}
+	}
+
+	return call.Results.SetDicts(capDicts)
+}
+
 func (hdl *requestHandler) IsCompleteFetchAllowed(call capnp.Sync_isCompleteFetchAllowed) error {
 	currRemote, err := hdl.rp.Remotes.Remote(hdl.currRemoteName)
 	if err != nil {
@@ -141,6 +264,10 @@ func (hdl *requestHandler) Version(call capnp.API_version) error {
 	return nil
 }
 
+func (hdl *requestHandler) HashAlgorithm(call capnp.API_hashAlgorithm) error {
+	return call.Results.SetAlgorithm(hdl.rp.Immutables.HashAlgorithm().String())
+}
+
 func (hdl *requestHandler) IsPushAllowed(call capnp.Sync_isPushAllowed) error {
 	currRemote, err := hdl.rp.Remotes.Remote(hdl.currRemoteName)
 	if err != nil {
@@ -166,3 +293,143 @@ func (hdl *requestHandler) Push(call capnp.Sync_push) error {
 	log.Infof("Syncing with »%s« because he asked us to via a push.", currRemote.Name)
 	return hdl.rapi.Sync(currRemote.Name)
 }
+
+func (hdl *requestHandler) AcceptInvite(call capnp.API_acceptInvite) error {
+	if hdl.guestPubKey == nil {
+		return fmt.Errorf("only peers that are not a remote yet can accept invites")
+	}
+
+	id, err := call.Params.Id()
+	if err != nil {
+		return err
+	}
+
+	secret, err := call.Params.Secret()
+	if err != nil {
+		return err
+	}
+
+	name, err := call.Params.Name()
+	if err != nil {
+		return err
+	}
+
+	if _, err := peer.CastName(name); err != nil {
+		return err
+	}
+
+	capFp, err := call.Params.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	fp, err := peer.CastFingerprint(capFp)
+	if err != nil {
+		return err
+	}
+
+	// The fingerprint must be the one of the key that authenticated:
+	if !fp.PubKeyMatches(hdl.guestPubKey) {
+		return fmt.Errorf("fingerprint does not match your public key")
+	}
+
+	if name == hdl.rp.Immutables.Owner() {
+		return fmt.Errorf("remote name »%s« is our own name", name)
+	}
+
+	if _, err := hdl.rp.Remotes.Remote(name); err == nil {
+		return fmt.Errorf("there is already a remote named »%s«", name)
+	}
+
+	deviceProof, err := call.Params.DeviceProof()
+	if err != nil {
+		return err
+	}
+
+	// Check devices before the invite is used up,
+	// so a bad name or proof does not burn it:
+	invite, err := hdl.rp.Invites.Take(id, secret, func(invite repo.Invite) error {
+		if !invite.Device {
+			return nil
+		}
+
+		owner := peer.Name(hdl.rp.Immutables.Owner())
+		if !owner.SameUser(peer.Name(name)) {
+			return fmt.Errorf("»%s« is not a device of our user (%s)", name, owner)
+		}
+
+		if err := hdl.rp.CheckDeviceProof(deviceProof, fp); err != nil {
+			return fmt.Errorf("device proof: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Warningf("failed attempt to accept invite %s by »%s«: %v", id, name, err)
+		return err
+	}
+
+	remote := repo.Remote{
+		Name:        name,
+		Fingerprint: fp,
+		Folders:     invite.Folders,
+		AcceptPush:  invite.AcceptPush,
+	}
+
+	if invite.Device {
+		remote.Device = true
+		remote.DeviceProof = deviceProof
+		remote.AcceptAutoUpdates = true
+	}
+
+	log.Infof("»%s« accepted invite %s; adding it as remote", name, id)
+
+	if err := hdl.addRemote(remote); err != nil {
+		return err
+	}
+
+	// The connection belongs to a proper remote from now on:
+	hdl.currRemoteName = name
+	hdl.guestPubKey = nil
+	return nil
+}
+
+func (hdl *requestHandler) addRemote(remote repo.Remote) error {
+	if hdl.rapi == nil {
+		return hdl.rp.Remotes.AddOrUpdateRemote(remote)
+	}
+
+	// Go over the remotes api, so the daemon starts watching the remote.
+	folders := []remotesapi.Folder{}
+	for _, folder := range remote.Folders {
+		folders = append(folders, remotesapi.Folder{
+			Folder:           folder.Folder,
+			ReadOnly:         folder.ReadOnly,
+			ConflictStrategy: folder.ConflictStrategy,
+			LockPolicy:       folder.LockPolicy,
+		})
+	}
+
+	err := hdl.rapi.Set(remotesapi.Remote{
+		Name:              remote.Name,
+		Fingerprint:       string(remote.Fingerprint),
+		Folders:           folders,
+		AcceptPush:        remote.AcceptPush,
+		AcceptAutoUpdates: remote.AcceptAutoUpdates,
+		Device:            remote.Device,
+	})
+
+	if err != nil || remote.DeviceProof == "" {
+		return err
+	}
+
+	// The api does not know about device proofs:
+	added, err := hdl.rp.Remotes.Remote(remote.Name)
+	if err != nil {
+		return err
+	}
+
+	added.DeviceProof = remote.DeviceProof
+	return hdl.rp.Remotes.AddOrUpdateRemote(added)
+}
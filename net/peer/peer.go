@@ -103,6 +103,21 @@ func (name Name) WithoutResource() string {
 	return name.User()
 }
 
+// SameUser returns true if `other` is the same user as `name`,
+// possibly on a different resource (i.e. device).
+func (name Name) SameUser(other Name) bool {
+	withoutResource := func(n Name) string {
+		idx := strings.LastIndexByte(string(n), '/')
+		if idx < 0 {
+			return string(n)
+		}
+
+		return string(n)[:idx]
+	}
+
+	return withoutResource(name) == withoutResource(other)
+}
+
 // AsPath converts a peer name to a path that can be used for storage.
 func (name Name) AsPath() string {
 	path := name.User()
@@ -161,8 +176,10 @@ func CastFingerprint(s string) (Fingerprint, error) {
 }
 
 // BuildFingerprint builds a fingerprint from `addr` and a public key.
+// Fingerprints are handed out to other users, so they do not change
+// with the hash algorithm of the repository.
 func BuildFingerprint(addr string, pubKeyData []byte) Fingerprint {
-	s := fmt.Sprintf("%s:%s", addr, h.Sum(pubKeyData).B58String())
+	s := fmt.Sprintf("%s:%s", addr, h.SumWith(h.DefaultAlgorithm, pubKeyData).B58String())
 	return Fingerprint(s)
 }
 
@@ -192,13 +209,13 @@ func (fp Fingerprint) PubKeyID() string {
 // PubKeyMatches checks if the supplied public key matches with the
 // hashed version in the fingerprint.
 func (fp Fingerprint) PubKeyMatches(pubKeyData []byte) bool {
-	own := fp.PubKeyID()
-	if own == "" {
+	own, err := h.FromB58String(fp.PubKeyID())
+	if err != nil {
 		return false
 	}
 
-	remote := h.Sum(pubKeyData).B58String()
-	return own == remote
+	matches, err := own.Verify(pubKeyData)
+	return err == nil && matches
 }
 
 ///////////////////////
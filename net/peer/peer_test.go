@@ -64,3 +64,23 @@ func TestValidity(t *testing.T) {
 		}
 	}
 }
+
+func TestSameUser(t *testing.T) {
+	tcs := []struct {
+		a, b string
+		same bool
+	}{
+		{"alice@nullcat.de/laptop", "alice@nullcat.de/desktop", true},
+		{"alice@nullcat.de/laptop", "alice@nullcat.de", true},
+		{"alice/laptop", "alice/desktop", true},
+		{"alice@nullcat.de/laptop", "bob@nullcat.de/laptop", false},
+		{"alice@nullcat.de/laptop", "alice@example.org/laptop", false},
+		{"alice", "bob", false},
+	}
+
+	for _, tc := range tcs {
+		if same := Name(tc.a).SameUser(Name(tc.b)); same != tc.same {
+			t.Errorf("SameUser(%s, %s) was %t, should be %t", tc.a, tc.b, same, tc.same)
+		}
+	}
+}
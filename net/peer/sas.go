@@ -0,0 +1,75 @@
+package peer
+
+import (
+	"crypto/sha256"
+	"sort"
+	"strings"
+)
+
+// SASSymbol is a single symbol of a short authentication string.
+type SASSymbol struct {
+	Emoji string
+	Word  string
+}
+
+// sasSymbols are the same 64 emojis (and their names) that other
+// messengers use for their short authentication strings. They were
+// chosen to be easy to recognize and to tell apart when read out loud.
+var sasSymbols = [64]SASSymbol{
+	{"🐶", "dog"}, {"🐱", "cat"}, {"🦁", "lion"}, {"🐎", "horse"},
+	{"🦄", "unicorn"}, {"🐷", "pig"}, {"🐘", "elephant"}, {"🐰", "rabbit"},
+	{"🐼", "panda"}, {"🐓", "rooster"}, {"🐧", "penguin"}, {"🐢", "turtle"},
+	{"🐟", "fish"}, {"🐙", "octopus"}, {"🦋", "butterfly"}, {"🌷", "flower"},
+	{"🌳", "tree"}, {"🌵", "cactus"}, {"🍄", "mushroom"}, {"🌏", "globe"},
+	{"🌙", "moon"}, {"☁️", "cloud"}, {"🔥", "fire"}, {"🍌", "banana"},
+	{"🍎", "apple"}, {"🍓", "strawberry"}, {"🌽", "corn"}, {"🍕", "pizza"},
+	{"🎂", "cake"}, {"❤️", "heart"}, {"😀", "smiley"}, {"🤖", "robot"},
+	{"🎩", "hat"}, {"👓", "glasses"}, {"🔧", "spanner"}, {"🎅", "santa"},
+	{"👍", "thumbs up"}, {"☂️", "umbrella"}, {"⌛", "hourglass"}, {"⏰", "clock"},
+	{"🎁", "gift"}, {"💡", "light bulb"}, {"📕", "book"}, {"✏️", "pencil"},
+	{"📎", "paperclip"}, {"✂️", "scissors"}, {"🔒", "lock"}, {"🔑", "key"},
+	{"🔨", "hammer"}, {"☎️", "telephone"}, {"🏁", "flag"}, {"🚂", "train"},
+	{"🚲", "bicycle"}, {"✈️", "aeroplane"}, {"🚀", "rocket"}, {"🏆", "trophy"},
+	{"⚽", "ball"}, {"🎸", "guitar"}, {"🎺", "trumpet"}, {"🔔", "bell"},
+	{"⚓", "anchor"}, {"🎧", "headphones"}, {"📁", "folder"}, {"📌", "pin"},
+}
+
+// SASLength is the number of symbols returned by ShortAuthString.
+// Each symbol carries 6 bits, so the string has 144 bits in total.
+//
+// The string is derived from the public fingerprints alone; there is no
+// per-session nonce an attacker has to guess. Someone who can swap the
+// fingerprints on their way can therefore search for matching keys offline,
+// for as long as they want. Since they get to choose the key shown to
+// either side, a collision of both strings is enough, which needs about
+// 2^(bits/2) tries. 144 bits keep this at 2^72 tries, which is out of reach.
+const SASLength = 24
+
+// SASGroupSize is the number of symbols that should be shown on one line.
+// Comparing them group by group is easier than all at once.
+const SASGroupSize = 6
+
+// ShortAuthString derives a short authentication string from two
+// fingerprints. The order of `a` and `b` does not matter, so both peers
+// get the same symbols if (and only if) they know the right fingerprint
+// of each other. It is meant to be compared in person or over the phone,
+// and only helps if all symbols are compared, not just the first few.
+//
+// The hash algorithm is fixed and does not depend on the one of the repository.
+func ShortAuthString(a, b Fingerprint) []SASSymbol {
+	fps := []string{string(a), string(b)}
+	sort.Strings(fps)
+
+	digest := sha256.Sum256([]byte("brig-sas\x00" + strings.Join(fps, "\x00")))
+
+	symbols := make([]SASSymbol, 0, SASLength)
+	for idx := 0; idx < SASLength; idx++ {
+		// Take 6 bits at a time:
+		bitOff := idx * 6
+		word := uint(digest[bitOff/8])<<8 | uint(digest[bitOff/8+1])
+		shift := uint(16 - 6 - bitOff%8)
+		symbols = append(symbols, sasSymbols[(word>>shift)&0x3F])
+	}
+
+	return symbols
+}
@@ -0,0 +1,47 @@
+package peer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestShortAuthString(t *testing.T) {
+	alice := BuildFingerprint("QmAlice", []byte("alice's public key"))
+	bob := BuildFingerprint("QmBob", []byte("bob's public key"))
+	mallory := BuildFingerprint("QmBob", []byte("mallory's public key"))
+
+	aliceSAS := ShortAuthString(alice, bob)
+	if len(aliceSAS) != SASLength {
+		t.Fatalf("expected %d symbols, got %d", SASLength, len(aliceSAS))
+	}
+
+	// Both sides see the same symbols:
+	if bobSAS := ShortAuthString(bob, alice); !reflect.DeepEqual(aliceSAS, bobSAS) {
+		t.Fatalf("order of fingerprints matters: %v != %v", aliceSAS, bobSAS)
+	}
+
+	// The string has to be long enough to make offline grinding of
+	// keys infeasible, even when looking for collisions of both sides:
+	if SASLength*6/2 < 64 {
+		t.Fatalf("short authentication string is too short: %d bits", SASLength*6)
+	}
+
+	// All symbols should be drawn, also from the end of the digest:
+	seen := make(map[SASSymbol]bool)
+	for idx := 0; idx < 1000; idx++ {
+		other := BuildFingerprint("QmBob", []byte(fmt.Sprintf("key %d", idx)))
+		for _, symbol := range ShortAuthString(alice, other)[SASLength-1:] {
+			seen[symbol] = true
+		}
+	}
+
+	if len(seen) != len(sasSymbols) {
+		t.Fatalf("only %d of %d symbols are used", len(seen), len(sasSymbols))
+	}
+
+	// ...but not if one of them was tricked into using another key:
+	if malSAS := ShortAuthString(alice, mallory); reflect.DeepEqual(aliceSAS, malSAS) {
+		t.Fatalf("different fingerprints lead to the same symbols: %v", malSAS)
+	}
+}
@@ -0,0 +1,107 @@
+package net
+
+import (
+	"fmt"
+
+	"github.com/sahib/brig/net/capnp"
+	"github.com/sahib/brig/repo"
+	log "github.com/sirupsen/logrus"
+	capnplib "zombiezen.com/go/capnproto2"
+)
+
+func revocationsToCap(revs []*repo.Revocation, seg *capnplib.Segment) (capnplib.TextList, error) {
+	capRevs, err := capnplib.NewTextList(seg, int32(len(revs)))
+	if err != nil {
+		return capRevs, err
+	}
+
+	for idx, rv := range revs {
+		enc, err := rv.Encode()
+		if err != nil {
+			return capRevs, err
+		}
+
+		if err := capRevs.Set(idx, enc); err != nil {
+			return capRevs, err
+		}
+	}
+
+	return capRevs, nil
+}
+
+// capToRevocations decodes all revocations with a valid signature.
+// Invalid ones are skipped, since one bad peer should not
+// stop the others from spreading revocations.
+func capToRevocations(capRevs capnplib.TextList) []*repo.Revocation {
+	revs := []*repo.Revocation{}
+	for idx := 0; idx < capRevs.Len(); idx++ {
+		enc, err := capRevs.At(idx)
+		if err != nil {
+			log.Warningf("failed to read revocation: %v", err)
+			continue
+		}
+
+		rv, err := repo.DecodeRevocation(enc)
+		if err != nil {
+			log.Warningf("skipping invalid revocation: %v", err)
+			continue
+		}
+
+		revs = append(revs, rv)
+	}
+
+	return revs
+}
+
+func (hdl *requestHandler) ExchangeRevocations(call capnp.API_exchangeRevocations) error {
+	if hdl.currRemoteName == "" {
+		return fmt.Errorf("only remotes may exchange revocations")
+	}
+
+	capRevs, err := call.Params.Revocations()
+	if err != nil {
+		return err
+	}
+
+	added, err := hdl.rp.AddRevocations(capToRevocations(capRevs))
+	if err != nil {
+		return err
+	}
+
+	if len(added) > 0 && hdl.onNewRevocations != nil {
+		hdl.onNewRevocations(hdl.currRemoteName)
+	}
+
+	ownRevs, err := revocationsToCap(hdl.rp.Revocations.List(), call.Results.Segment())
+	if err != nil {
+		return err
+	}
+
+	return call.Results.SetRevocations(ownRevs)
+}
+
+// ExchangeRevocations sends all revocations we know of to the remote
+// and adds those of the remote that we trust. The revocations that
+// were new to us are returned.
+func (cl *Client) ExchangeRevocations(rp *repo.Repository) ([]*repo.Revocation, error) {
+	call := cl.api.ExchangeRevocations(cl.ctx, func(p capnp.API_exchangeRevocations_Params) error {
+		capRevs, err := revocationsToCap(rp.Revocations.List(), p.Segment())
+		if err != nil {
+			return err
+		}
+
+		return p.SetRevocations(capRevs)
+	})
+
+	result, err := call.Struct()
+	if err != nil {
+		return nil, err
+	}
+
+	capRevs, err := result.Revocations()
+	if err != nil {
+		return nil, err
+	}
+
+	return rp.AddRevocations(capToRevocations(capRevs))
+}
@@ -27,6 +27,13 @@ type Server struct {
 	pingMap    *PingMap
 }
 
+// OnNewRevocations registers `fn` to be called when a remote told us about
+// revocations that we did not know yet. `from` is the name of the remote,
+// or empty if it is not a remote of us (yet). It should be called before Serve.
+func (sv *Server) OnNewRevocations(fn func(from string)) {
+	sv.hdl.onNewRevocations = fn
+}
+
 // Serve blocks and serves request until quit was called.
 func (sv *Server) Serve() error {
 	return e.Wrapf(sv.baseServer.Serve(), "serve")
@@ -298,6 +305,8 @@ type connHandler struct {
 	rp      *repo.Repository
 	rapi    remotesapi.RemotesAPI
 	pingMap *PingMap
+
+	onNewRevocations func(from string)
 }
 
 // Handle is called whenever we receive a new connection from another brig peer.
@@ -327,6 +336,8 @@ func (hdl *connHandler) Handle(ctx context.Context, conn net.Conn) {
 		rp:   hdl.rp,
 		ctx:  reqCtx,
 		rapi: hdl.rapi,
+
+		onNewRevocations: hdl.onNewRevocations,
 	}
 
 	// This func will be called during the authentication process.
@@ -345,11 +356,15 @@ func (hdl *connHandler) Handle(ctx context.Context, conn net.Conn) {
 			return fmt.Errorf("cannot dial self")
 		}
 
-		// Linear scan over all remotes.
+		// Linear scan over all remotes (and their devices).
 		// If this proves to be a performance problem, we can fix it later.
 		for _, remote := range remotes {
-			if remote.Fingerprint.PubKeyID() == remoteFp.PubKeyID() {
-				addr := remote.Fingerprint.Addr()
+			for _, fp := range remote.Fingerprints() {
+				if fp.PubKeyID() != remoteFp.PubKeyID() {
+					continue
+				}
+
+				addr := fp.Addr()
 				log.Infof("starting connection with addr `%s`", addr)
 				hdl.pingMap.hintNetAttempt(addr, true)
 				reqHdl.currRemoteName = remote.Name
@@ -357,6 +372,15 @@ func (hdl *connHandler) Handle(ctx context.Context, conn net.Conn) {
 			}
 		}
 
+		// Peers we invited are not known yet. Let them in as guest,
+		// so they can accept their invite. They may not call anything
+		// that needs a remote, since they do not have one yet.
+		if hdl.rp.Invites.HasPending() {
+			log.Infof("letting unknown peer in as guest, since we have pending invites")
+			reqHdl.guestPubKey = pubKey
+			return nil
+		}
+
 		netAddr := conn.RemoteAddr()
 		if netAddr != nil {
 			hdl.pingMap.hintNetAttempt(netAddr.String(), false)
@@ -375,6 +399,8 @@ func (hdl *connHandler) Handle(ctx context.Context, conn net.Conn) {
 		authChecker,
 	)
 
+	authConn.SetRevocationChecker(hdl.rp.Revocations)
+
 	// Trigger the authentication. This is not strictly necessary and would
 	// happen anyways on the first read/write on the connection. But doing it
 	// here catches errors early.
@@ -385,6 +411,9 @@ func (hdl *connHandler) Handle(ctx context.Context, conn net.Conn) {
 	}
 
 	// The connection is considered authenticated at this point.
+	// Guests are limited by the global limits only.
+	conn = hdl.rp.Bandwidth.Conn(conn, reqHdl.currRemoteName)
+
 	// Initialize the capnp rpc protocol over it.
 	transport := rpc.StreamTransport(conn)
 	srv := capnp.API_ServerToClient(reqHdl)
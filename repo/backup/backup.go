@@ -0,0 +1,767 @@
+// Package backup implements incremental, encrypted backups of the metadata
+// and configuration of a repository.
+//
+// Every backup is a single file in the backup directory. A full backup
+// contains all keys of all metadata stores, an incremental one only those
+// that were added, changed or removed since the previous backup. Small files
+// like the config or the keyring are always included completely.
+//
+// The file layout is:
+//
+//     8 Byte: Magic number ("brigbkp\n")
+//     4 Byte: Length of the JSON header (little endian)
+//     n Byte: JSON header (see Info), not encrypted
+//     ...   : Gob encoded, gzipped and encrypted body.
+//
+// The encryption key is derived from a password and the salt in the header.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/db"
+	"github.com/sahib/brig/catfs/mio/encrypt"
+	"github.com/sahib/brig/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	magicNumber   = "brigbkp\n"
+	formatVersion = 1
+	fileSuffix    = ".backup"
+	keySeparator  = "\x00"
+)
+
+var (
+	// ErrNoBackups is returned when a backup directory contains no backups.
+	ErrNoBackups = errors.New("no backups found")
+
+	// Files and directories (relative to the repository) that are
+	// always included completely in every backup.
+	repoFiles = []string{
+		"config.yml",
+		"immutable.yml",
+		"remotes.yml",
+		"hints.yml",
+		"invites.yml",
+		"revocations.yml",
+		"README.md",
+		"keyring",
+		"dicts",
+	}
+)
+
+// Info is the unencrypted header of each backup.
+type Info struct {
+	// Path is the location of the backup file.
+	Path string `json:"-"`
+	// Size is the size of the backup file in bytes.
+	Size int64 `json:"-"`
+
+	Version   int       `json:"version"`
+	Index     int       `json:"index"`
+	Full      bool      `json:"full"`
+	CreatedAt time.Time `json:"created_at"`
+	Changed   int       `json:"changed"`
+	Removed   int       `json:"removed"`
+	Salt      []byte    `json:"salt"`
+}
+
+// databaseDelta contains the changes of a single metadata store.
+type databaseDelta struct {
+	Type    string
+	Put     map[string][]byte
+	Removed []string
+}
+
+type body struct {
+	// Parent is the checksum of the previous backup file.
+	// It is empty for full backups.
+	Parent []byte
+
+	// Files maps the path relative to the repository to the file content.
+	Files map[string][]byte
+
+	// Databases maps the owner of a store to its changes.
+	Databases map[string]*databaseDelta
+
+	// State maps the owner of a store to the checksum of each key's value
+	// after this backup was applied. It is used to find out what changed
+	// for the next backup and to verify the backups.
+	State map[string]map[string][]byte
+}
+
+func keyFromPassword(password string, salt []byte) []byte {
+	return util.DeriveKey([]byte(password), salt, 32)
+}
+
+func checksum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func fileChecksum(path string) ([]byte, error) {
+	fd, err := os.Open(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, fd); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+func readInfo(r io.Reader) (*Info, error) {
+	magic := make([]byte, len(magicNumber))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+
+	if string(magic) != magicNumber {
+		return nil, fmt.Errorf("not a brig backup")
+	}
+
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	info := &Info{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+
+	if info.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported backup version: %d", info.Version)
+	}
+
+	return info, nil
+}
+
+func writeInfo(w io.Writer, info *Info) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte(magicNumber)); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(data))); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func readBody(info *Info, password string) (*body, error) {
+	fd, err := os.Open(info.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+
+	if _, err := readInfo(fd); err != nil {
+		return nil, err
+	}
+
+	encR, err := encrypt.NewReader(fd, keyFromPassword(password, info.Salt))
+	if err != nil {
+		return nil, err
+	}
+
+	zipR, err := gzip.NewReader(encR)
+	if err != nil {
+		return nil, e.Wrapf(err, "failed to decrypt (wrong password?)")
+	}
+
+	defer zipR.Close()
+
+	bd := &body{}
+	if err := gob.NewDecoder(zipR).Decode(bd); err != nil {
+		return nil, e.Wrapf(err, "failed to decode backup")
+	}
+
+	return bd, nil
+}
+
+func writeBackup(path string, info *Info, bd *body, password string) error {
+	tmpPath := path + ".tmp"
+	fd, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	// Make sure we do not leave half written backups around:
+	success := false
+	defer func() {
+		if !success {
+			fd.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if err := writeInfo(fd, info); err != nil {
+		return err
+	}
+
+	encW, err := encrypt.NewWriter(
+		fd,
+		keyFromPassword(password, info.Salt),
+		encrypt.FlagEncryptAES256GCM|encrypt.FlagCompressedInside,
+	)
+	if err != nil {
+		return err
+	}
+
+	zipW := gzip.NewWriter(encW)
+	if err := gob.NewEncoder(zipW).Encode(bd); err != nil {
+		return err
+	}
+
+	if err := zipW.Close(); err != nil {
+		return err
+	}
+
+	if err := encW.Close(); err != nil {
+		return err
+	}
+
+	if err := fd.Close(); err != nil {
+		return err
+	}
+
+	success = true
+	return os.Rename(tmpPath, path)
+}
+
+// List returns all backups in `backupDir`, sorted by their index.
+func List(backupDir string) ([]*Info, error) {
+	paths, err := filepath.Glob(filepath.Join(backupDir, "*"+fileSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []*Info{}
+	for _, path := range paths {
+		fd, err := os.Open(path) // #nosec
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := readInfo(fd)
+		fd.Close()
+
+		if err != nil {
+			log.Warnf("skipping %s: %v", path, err)
+			continue
+		}
+
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		info.Path = path
+		info.Size = stat.Size()
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Index < infos[j].Index
+	})
+
+	return infos, nil
+}
+
+func readRepoFiles(repoFolder string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, name := range repoFiles {
+		root := filepath.Join(repoFolder, name)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			data, err := ioutil.ReadFile(path) // #nosec
+			if err != nil {
+				return err
+			}
+
+			relPath, err := filepath.Rel(repoFolder, path)
+			if err != nil {
+				return err
+			}
+
+			files[filepath.ToSlash(relPath)] = data
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// walkDatabase calls `fn` for each key in the metadata store at `path`.
+// The key is passed in its canonical form, so it does not depend on the
+// type of the database.
+func walkDatabase(path string, fn func(key string, val []byte) error) (string, error) {
+	dbType, err := db.DetectType(path)
+	if err != nil {
+		return "", err
+	}
+
+	kv, err := db.Open(dbType, path)
+	if err != nil {
+		return "", err
+	}
+
+	defer kv.Close()
+
+	keys, err := kv.Keys()
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range keys {
+		val, err := kv.Get(key...)
+		if err != nil {
+			return "", e.Wrapf(err, "failed to read key %v", key)
+		}
+
+		canonicalKey := catfs.CanonicalKey(dbType, key)
+		if err := fn(strings.Join(canonicalKey, keySeparator), val); err != nil {
+			return "", err
+		}
+	}
+
+	return dbType, nil
+}
+
+// Create writes a new backup of the repository at `repoFolder` to
+// `backupDir`. If there is a previous backup, only the changes since
+// then are written, unless `full` is true. The daemon may not run while
+// creating the backup. `password` is used to encrypt the backup and must be
+// the same for all backups in the same directory.
+func Create(repoFolder, backupDir, password string, full bool) (*Info, error) {
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return nil, err
+	}
+
+	infos, err := List(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := 1
+	prevState := make(map[string]map[string][]byte)
+
+	bd := &body{
+		Databases: make(map[string]*databaseDelta),
+		State:     make(map[string]map[string][]byte),
+	}
+
+	if len(infos) > 0 {
+		prevInfo := infos[len(infos)-1]
+		index = prevInfo.Index + 1
+
+		if !full {
+			prevBody, err := readBody(prevInfo, password)
+			if err != nil {
+				return nil, e.Wrapf(err, "failed to read previous backup %s", prevInfo.Path)
+			}
+
+			prevState = prevBody.State
+			bd.Parent, err = fileChecksum(prevInfo.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Version:   formatVersion,
+		Index:     index,
+		Full:      bd.Parent == nil,
+		CreatedAt: time.Now(),
+		Salt:      salt,
+	}
+
+	bd.Files, err = readRepoFiles(repoFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataDir := filepath.Join(repoFolder, "metadata")
+	entries, err := ioutil.ReadDir(metadataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		owner := entry.Name()
+		oldState := prevState[owner]
+		newState := make(map[string][]byte)
+		delta := &databaseDelta{
+			Put: make(map[string][]byte),
+		}
+
+		delta.Type, err = walkDatabase(
+			filepath.Join(metadataDir, owner),
+			func(key string, val []byte) error {
+				sum := checksum(val)
+				newState[key] = sum
+				if !bytes.Equal(oldState[key], sum) {
+					delta.Put[key] = val
+				}
+
+				return nil
+			},
+		)
+
+		if err != nil {
+			return nil, e.Wrapf(err, "failed to read metadata of %s", owner)
+		}
+
+		for key := range oldState {
+			if _, ok := newState[key]; !ok {
+				delta.Removed = append(delta.Removed, key)
+			}
+		}
+
+		info.Changed += len(delta.Put)
+		info.Removed += len(delta.Removed)
+		bd.Databases[owner] = delta
+		bd.State[owner] = newState
+	}
+
+	path := filepath.Join(backupDir, fmt.Sprintf("%06d%s", index, fileSuffix))
+	if err := writeBackup(path, info, bd, password); err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Path = path
+	info.Size = stat.Size()
+	return info, nil
+}
+
+// VerifyResult is the result of verifying a single backup.
+type VerifyResult struct {
+	Info *Info
+	Err  error
+}
+
+func compareState(expected, actual map[string]map[string][]byte) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("number of stores differ: %d != %d", len(expected), len(actual))
+	}
+
+	for owner, expectedKeys := range expected {
+		actualKeys, ok := actual[owner]
+		if !ok {
+			return fmt.Errorf("missing store of %s", owner)
+		}
+
+		if len(expectedKeys) != len(actualKeys) {
+			return fmt.Errorf(
+				"number of keys differ for %s: %d != %d",
+				owner, len(expectedKeys), len(actualKeys),
+			)
+		}
+
+		for key, sum := range expectedKeys {
+			if !bytes.Equal(sum, actualKeys[key]) {
+				return fmt.Errorf("checksum differs for %s: %q", owner, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// replay applies the changes of each backup in `infos` on top of each other.
+// The first backup must be a full backup. `fn` is called after each step with
+// the replayed values. If `fn` returns an error, replay stops.
+func replay(infos []*Info, password string, fn func(info *Info, bd *body, values map[string]map[string][]byte, err error) error) error {
+	var values map[string]map[string][]byte
+	var prevInfo *Info
+
+	for _, info := range infos {
+		bd, err := readBody(info, password)
+		if err == nil {
+			err = applyBody(info, prevInfo, bd, &values)
+		}
+
+		if err != nil {
+			// Following incremental backups cannot be applied anymore.
+			values = nil
+		}
+
+		if err := fn(info, bd, values, err); err != nil {
+			return err
+		}
+
+		prevInfo = info
+	}
+
+	return nil
+}
+
+func applyBody(info, prevInfo *Info, bd *body, values *map[string]map[string][]byte) error {
+	if info.Full {
+		*values = make(map[string]map[string][]byte)
+	} else {
+		if prevInfo == nil || prevInfo.Index != info.Index-1 {
+			return fmt.Errorf("previous backup %d is missing", info.Index-1)
+		}
+
+		if *values == nil {
+			return fmt.Errorf("previous backup %d is broken", prevInfo.Index)
+		}
+
+		prevSum, err := fileChecksum(prevInfo.Path)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(prevSum, bd.Parent) {
+			return fmt.Errorf("previous backup %d was modified", prevInfo.Index)
+		}
+	}
+
+	// Stores that are not mentioned anymore were removed:
+	for owner := range *values {
+		if _, ok := bd.Databases[owner]; !ok {
+			delete(*values, owner)
+		}
+	}
+
+	for owner, delta := range bd.Databases {
+		ownerValues, ok := (*values)[owner]
+		if !ok {
+			ownerValues = make(map[string][]byte)
+			(*values)[owner] = ownerValues
+		}
+
+		for _, key := range delta.Removed {
+			delete(ownerValues, key)
+		}
+
+		for key, val := range delta.Put {
+			ownerValues[key] = val
+		}
+	}
+
+	return nil
+}
+
+func stateFromValues(values map[string]map[string][]byte) map[string]map[string][]byte {
+	state := make(map[string]map[string][]byte)
+	for owner, ownerValues := range values {
+		ownerState := make(map[string][]byte)
+		for key, val := range ownerValues {
+			ownerState[key] = checksum(val)
+		}
+
+		state[owner] = ownerState
+	}
+
+	return state
+}
+
+// Verify checks that all backups in `backupDir` can be decrypted and
+// that replaying them results in exactly the recorded state.
+func Verify(backupDir, password string) ([]VerifyResult, error) {
+	infos, err := List(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(infos) == 0 {
+		return nil, ErrNoBackups
+	}
+
+	results := []VerifyResult{}
+	return results, replay(infos, password, func(info *Info, bd *body, values map[string]map[string][]byte, err error) error {
+		if err == nil {
+			err = compareState(bd.State, stateFromValues(values))
+		}
+
+		results = append(results, VerifyResult{Info: info, Err: err})
+		return nil
+	})
+}
+
+// Restore creates a new repository at `repoFolder` from the backup with
+// `index` in `backupDir`. If `index` is zero or less, the latest backup is
+// used. `repoFolder` may not exist yet or must be empty.
+func Restore(backupDir, repoFolder, password string, index int) error {
+	children, err := ioutil.ReadDir(repoFolder)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(children) > 0 {
+		return fmt.Errorf("%s is not empty, refusing to overwrite", repoFolder)
+	}
+
+	infos, err := List(backupDir)
+	if err != nil {
+		return err
+	}
+
+	if len(infos) == 0 {
+		return ErrNoBackups
+	}
+
+	if index <= 0 {
+		index = infos[len(infos)-1].Index
+	}
+
+	// Find the last full backup before `index`, we only need to replay from there.
+	start, end := -1, -1
+	for idx, info := range infos {
+		if info.Index > index {
+			break
+		}
+
+		if info.Full {
+			start = idx
+		}
+
+		if info.Index == index {
+			end = idx
+		}
+	}
+
+	if end < 0 {
+		return fmt.Errorf("no backup with index %d", index)
+	}
+
+	if start < 0 {
+		return fmt.Errorf("no full backup before index %d", index)
+	}
+
+	var target *body
+	var targetValues map[string]map[string][]byte
+	err = replay(infos[start:end+1], password, func(info *Info, bd *body, values map[string]map[string][]byte, err error) error {
+		if err != nil {
+			return e.Wrapf(err, "backup %d", info.Index)
+		}
+
+		target, targetValues = bd, values
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := compareState(target.State, stateFromValues(targetValues)); err != nil {
+		return e.Wrapf(err, "restored state differs from backup")
+	}
+
+	return restoreRepo(repoFolder, target, targetValues)
+}
+
+func restoreRepo(repoFolder string, bd *body, values map[string]map[string][]byte) error {
+	for _, dir := range []string{"metadata", "keyring"} {
+		if err := os.MkdirAll(filepath.Join(repoFolder, dir), 0700); err != nil {
+			return err
+		}
+	}
+
+	for relPath, data := range bd.Files {
+		path := filepath.Join(repoFolder, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			return err
+		}
+	}
+
+	for owner, ownerValues := range values {
+		dbType := db.TypeBadger
+		if delta, ok := bd.Databases[owner]; ok && delta.Type != "" {
+			dbType = delta.Type
+		}
+
+		kv, err := db.Open(dbType, filepath.Join(repoFolder, "metadata", owner))
+		if err != nil {
+			return err
+		}
+
+		batch := kv.Batch()
+		for key, val := range ownerValues {
+			batch.Put(val, strings.Split(key, keySeparator)...)
+		}
+
+		if err := batch.Flush(); err != nil {
+			kv.Close()
+			return err
+		}
+
+		if err := kv.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sahib/brig/backend/mock"
+	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/mio/compress"
+	"github.com/sahib/brig/net/peer"
+	"github.com/sahib/brig/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func withRepoFS(t *testing.T, repoFolder string, fn func(fs *catfs.FS)) {
+	rp, err := repo.Open(repoFolder)
+	require.NoError(t, err)
+
+	fs, err := rp.FS(rp.CurrentUser(), mock.NewMockBackend("", ""))
+	require.NoError(t, err)
+
+	fn(fs)
+
+	require.NoError(t, fs.Close())
+	require.NoError(t, rp.Close())
+}
+
+func addRevocation(t *testing.T, repoFolder string) {
+	rp, err := repo.Open(repoFolder)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	kr, err := rp.Keyring()
+	require.NoError(t, err)
+
+	ownPubKey, err := kr.OwnPubKey()
+	require.NoError(t, err)
+
+	rv := &repo.Revocation{
+		Revoked: peer.BuildFingerprint("QmBob", []byte("bob's key")),
+		Reason:  "lost",
+		Created: time.Now().UTC(),
+		Issuer:  peer.BuildFingerprint("QmAlice", ownPubKey),
+	}
+
+	require.NoError(t, rv.Sign(kr))
+	added, err := rp.Revocations.Add(rv)
+	require.NoError(t, err)
+	require.True(t, added)
+}
+
+func addDict(t *testing.T, repoFolder string) {
+	rp, err := repo.Open(repoFolder)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	samples := [][]byte{}
+	for idx := 0; idx < 100; idx++ {
+		samples = append(samples, []byte(fmt.Sprintf("user=%d action=login result=ok", idx)))
+	}
+
+	dict, err := compress.TrainDict(samples, compress.DefaultDictSize)
+	require.NoError(t, err)
+	require.NoError(t, rp.Dicts.Add("/logs", dict))
+}
+
+func requireDicts(t *testing.T, repoFolder string, count int) {
+	rp, err := repo.Open(repoFolder)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	infos := rp.Dicts.List()
+	require.Len(t, infos, count)
+	for _, info := range infos {
+		id, err := compress.ParseDictID(info.ID)
+		require.NoError(t, err)
+
+		// Files compressed with it need the dictionary itself, not only the index:
+		_, err = rp.Dicts.Dict(id)
+		require.NoError(t, err)
+	}
+}
+
+func addInvite(t *testing.T, repoFolder string) {
+	rp, err := repo.Open(repoFolder)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	_, err = rp.Invites.Create([]repo.Folder{{Folder: "/x"}}, false, time.Hour)
+	require.NoError(t, err)
+}
+
+func requireInvites(t *testing.T, repoFolder string, count int) {
+	rp, err := repo.Open(repoFolder)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	require.Len(t, rp.Invites.List(), count)
+}
+
+func requireRevocations(t *testing.T, repoFolder string, count int) {
+	rp, err := repo.Open(repoFolder)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	require.Len(t, rp.Revocations.List(), count)
+}
+
+func TestBackupCreateAndRestore(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "brig-backup-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	repoFolder := filepath.Join(testDir, "repo")
+	backupDir := filepath.Join(testDir, "backups")
+
+	require.NoError(t, repo.Init(repo.InitOptions{
+		BaseFolder:  repoFolder,
+		Owner:       "alice",
+		BackendName: "mock",
+		DaemonURL:   "yadda-yadda",
+	}))
+
+	withRepoFS(t, repoFolder, func(fs *catfs.FS) {
+		require.NoError(t, fs.Stage("/x", bytes.NewReader([]byte{1, 2, 3})))
+		require.NoError(t, fs.MakeCommit("add x"))
+	})
+
+	full, err := Create(repoFolder, backupDir, "secret", false)
+	require.NoError(t, err)
+	require.True(t, full.Full)
+	require.Equal(t, 1, full.Index)
+	require.True(t, full.Changed > 0)
+
+	withRepoFS(t, repoFolder, func(fs *catfs.FS) {
+		require.NoError(t, fs.Stage("/y", bytes.NewReader([]byte{4, 5, 6})))
+		require.NoError(t, fs.MakeCommit("add y"))
+	})
+
+	// Revoked peers should stay revoked after a restore:
+	addRevocation(t, repoFolder)
+
+	// Same for dictionaries; files compressed with them are unreadable otherwise:
+	addDict(t, repoFolder)
+
+	// Pending invites should still work after a restore:
+	addInvite(t, repoFolder)
+
+	incr, err := Create(repoFolder, backupDir, "secret", false)
+	require.NoError(t, err)
+	require.False(t, incr.Full)
+	require.Equal(t, 2, incr.Index)
+	require.True(t, incr.Changed > 0)
+	require.True(t, incr.Changed < full.Changed)
+
+	// Nothing changed, so nothing should be in the next backup:
+	empty, err := Create(repoFolder, backupDir, "secret", false)
+	require.NoError(t, err)
+	require.Equal(t, 0, empty.Changed)
+	require.Equal(t, 0, empty.Removed)
+
+	infos, err := List(backupDir)
+	require.NoError(t, err)
+	require.Len(t, infos, 3)
+
+	results, err := Verify(backupDir, "secret")
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		require.NoError(t, result.Err)
+	}
+
+	results, err = Verify(backupDir, "wrong")
+	require.NoError(t, err)
+	for _, result := range results {
+		require.Error(t, result.Err)
+	}
+
+	// Restore the latest state:
+	latestFolder := filepath.Join(testDir, "latest")
+	require.NoError(t, Restore(backupDir, latestFolder, "secret", 0))
+	withRepoFS(t, latestFolder, func(fs *catfs.FS) {
+		_, err := fs.Stat("/x")
+		require.NoError(t, err)
+		_, err = fs.Stat("/y")
+		require.NoError(t, err)
+	})
+	requireRevocations(t, latestFolder, 1)
+	requireDicts(t, latestFolder, 1)
+	requireInvites(t, latestFolder, 1)
+
+	// Restore the state of the first backup:
+	firstFolder := filepath.Join(testDir, "first")
+	require.NoError(t, Restore(backupDir, firstFolder, "secret", 1))
+	withRepoFS(t, firstFolder, func(fs *catfs.FS) {
+		_, err := fs.Stat("/x")
+		require.NoError(t, err)
+		_, err = fs.Stat("/y")
+		require.Error(t, err)
+	})
+	requireRevocations(t, firstFolder, 0)
+	requireDicts(t, firstFolder, 0)
+	requireInvites(t, firstFolder, 0)
+
+	// Do not overwrite existing repositories:
+	require.Error(t, Restore(backupDir, firstFolder, "secret", 1))
+}
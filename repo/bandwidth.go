@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/sahib/brig/net/bandwidth"
+	"github.com/sahib/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// globalBandwidth returns the limits of the net.bandwidth config section
+// that apply at `now`. Broken values are logged and taken as unlimited.
+func globalBandwidth(cfg *config.Config, now time.Time) bandwidth.Limits {
+	up, err := bandwidth.ParseRate(cfg.String("net.bandwidth.upload"))
+	if err != nil {
+		log.Warnf("bad net.bandwidth.upload: %v", err)
+	}
+
+	down, err := bandwidth.ParseRate(cfg.String("net.bandwidth.download"))
+	if err != nil {
+		log.Warnf("bad net.bandwidth.download: %v", err)
+	}
+
+	base := bandwidth.Limits{Upload: up, Download: down}
+	schedule, err := bandwidth.ParseSchedule(cfg.Strings("net.bandwidth.schedule"))
+	if err != nil {
+		log.Warnf("bad net.bandwidth.schedule: %v", err)
+		return base
+	}
+
+	return schedule.At(now, base)
+}
+
+// BandwidthLimits returns the limits for connections with this remote,
+// if it overrides them. A side that is not set takes the value of `global`.
+func (r Remote) BandwidthLimits(global bandwidth.Limits) (bandwidth.Limits, bool) {
+	if r.UploadLimit == "" && r.DownloadLimit == "" {
+		return global, false
+	}
+
+	limits := global
+	if r.UploadLimit != "" {
+		// Errors were checked when the remote was added.
+		limits.Upload, _ = bandwidth.ParseRate(r.UploadLimit)
+	}
+
+	if r.DownloadLimit != "" {
+		limits.Download, _ = bandwidth.ParseRate(r.DownloadLimit)
+	}
+
+	return limits, true
+}
+
+func newBandwidthManager(cfg *config.Config, remotes *RemoteList) *bandwidth.Manager {
+	global := func() bandwidth.Limits {
+		return globalBandwidth(cfg, time.Now())
+	}
+
+	bw := bandwidth.NewManager(global, func(name string) (bandwidth.Limits, bool) {
+		remote, err := remotes.Remote(name)
+		if err != nil {
+			return bandwidth.Limits{}, false
+		}
+
+		return remote.BandwidthLimits(global())
+	})
+
+	// Changes should not wait until the next regular refresh:
+	refresh := func(string) { bw.Refresh() }
+	for _, key := range []string{"upload", "download", "schedule"} {
+		cfg.AddEvent("net.bandwidth."+key, refresh)
+	}
+
+	remotes.OnChange(func() { bw.Refresh() })
+	return bw
+}
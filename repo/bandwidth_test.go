@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sahib/brig/defaults"
+	"github.com/sahib/brig/net/bandwidth"
+	"github.com/sahib/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobalBandwidth(t *testing.T) {
+	cfg, err := config.Open(nil, defaults.Defaults, config.StrictnessPanic)
+	require.NoError(t, err)
+
+	noon := time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+	require.Equal(t, bandwidth.Unlimited, globalBandwidth(cfg, noon))
+
+	require.NoError(t, cfg.SetString("net.bandwidth.upload", "1MB"))
+	require.NoError(t, cfg.SetString("net.bandwidth.download", "2MB"))
+	require.NoError(t, cfg.SetStrings("net.bandwidth.schedule", []string{"22:00-06:00 0/0"}))
+	require.Equal(t, bandwidth.Limits{Upload: 1000 * 1000, Download: 2000 * 1000}, globalBandwidth(cfg, noon))
+
+	night := time.Date(2020, 1, 1, 23, 0, 0, 0, time.Local)
+	require.Equal(t, bandwidth.Unlimited, globalBandwidth(cfg, night))
+
+	require.Error(t, cfg.SetStrings("net.bandwidth.schedule", []string{"tonight"}))
+	require.Error(t, cfg.SetString("net.bandwidth.upload", "fast"))
+}
+
+func TestRemoteBandwidthLimits(t *testing.T) {
+	fd, err := ioutil.TempFile("", "brig-test-remotes")
+	require.NoError(t, err)
+	defer os.Remove(fd.Name())
+
+	rl, err := NewRemotes(fd.Name())
+	require.NoError(t, err)
+
+	bob := bobRemote
+	bob.UploadLimit = "bogus"
+	require.Error(t, rl.AddOrUpdateRemote(bob))
+
+	global := bandwidth.Limits{Upload: 10, Download: 20}
+	limits, ok := bobRemote.BandwidthLimits(global)
+	require.False(t, ok)
+	require.Equal(t, global, limits)
+
+	bob.UploadLimit = "1KB"
+	require.NoError(t, rl.AddOrUpdateRemote(bob))
+
+	stored, err := rl.Remote(bob.Name)
+	require.NoError(t, err)
+
+	limits, ok = stored.BandwidthLimits(global)
+	require.True(t, ok)
+	require.Equal(t, bandwidth.Limits{Upload: 1000, Download: 20}, limits)
+}
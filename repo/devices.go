@@ -0,0 +1,222 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/net/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeviceProof is a statement, signed by the key of a device,
+// that the device belongs to the user in Name. Peers only accept
+// a fingerprint as device of a remote if they got a valid proof for it;
+// otherwise every remote could claim the keys of other peers.
+type DeviceProof struct {
+	// Name is the full name of the device (e.g. »ali/laptop«).
+	// Its user part (»ali«) is what the proof is about.
+	Name string `json:"name"`
+
+	// Fingerprint is the fingerprint of the device.
+	Fingerprint peer.Fingerprint `json:"fingerprint"`
+
+	// PubKey is the public key of the device. It is part of the proof,
+	// so peers that never met the device can check it.
+	PubKey []byte `json:"pubkey"`
+
+	// Created is the time the proof was made.
+	Created time.Time `json:"created"`
+
+	// payload and signature are set once signed or decoded.
+	payload   []byte
+	signature []byte
+}
+
+// NewDeviceProof creates a proof that the owner of `kr`, reachable
+// under `fingerprint`, is a device of the user in `name`.
+func NewDeviceProof(kr *Keyring, name string, fingerprint peer.Fingerprint) (*DeviceProof, error) {
+	pubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if !fingerprint.PubKeyMatches(pubKey) {
+		return nil, fmt.Errorf("fingerprint of device proof is not the one of the keyring")
+	}
+
+	dp := &DeviceProof{
+		Name:        name,
+		Fingerprint: fingerprint,
+		PubKey:      pubKey,
+		Created:     time.Now().UTC(),
+	}
+
+	payload, err := json.Marshal(dp)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := kr.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	dp.payload = payload
+	dp.signature = signature
+	return dp, nil
+}
+
+// Encode returns the signed proof as text.
+func (dp *DeviceProof) Encode() (string, error) {
+	if dp.payload == nil {
+		return "", fmt.Errorf("device proof is not signed")
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(dp.payload) + "." + enc.EncodeToString(dp.signature), nil
+}
+
+// DecodeDeviceProof parses and checks a proof produced by Encode.
+// An error is returned if the signature is not valid.
+func DecodeDeviceProof(data string) (*DeviceProof, error) {
+	parts := strings.Split(data, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("bad device proof: expected two parts, got %d", len(parts))
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, e.Wrap(err, "bad device proof")
+	}
+
+	signature, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, e.Wrap(err, "bad device proof signature")
+	}
+
+	dp := &DeviceProof{}
+	if err := json.Unmarshal(payload, dp); err != nil {
+		return nil, e.Wrap(err, "bad device proof")
+	}
+
+	if _, err := peer.CastName(dp.Name); err != nil {
+		return nil, err
+	}
+
+	if _, err := peer.CastFingerprint(string(dp.Fingerprint)); err != nil {
+		return nil, err
+	}
+
+	if !dp.Fingerprint.PubKeyMatches(dp.PubKey) {
+		return nil, fmt.Errorf("public key of device proof does not match its fingerprint")
+	}
+
+	if err := VerifySignature(payload, signature, dp.PubKey); err != nil {
+		return nil, e.Wrap(err, "bad signature of device proof")
+	}
+
+	dp.payload = payload
+	dp.signature = signature
+	return dp, nil
+}
+
+// OwnDeviceProof returns a proof that we are a device of our owner.
+func (rp *Repository) OwnDeviceProof(ownFp peer.Fingerprint) (*DeviceProof, error) {
+	kr, err := rp.Keyring()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceProof(kr, rp.Immutables.Owner(), ownFp)
+}
+
+// CheckDeviceProof checks that `encoded` is a valid proof for a device
+// of our own user with `fingerprint`.
+func (rp *Repository) CheckDeviceProof(encoded string, fingerprint peer.Fingerprint) error {
+	dp, err := DecodeDeviceProof(encoded)
+	if err != nil {
+		return err
+	}
+
+	if dp.Fingerprint.PubKeyID() != fingerprint.PubKeyID() {
+		return fmt.Errorf("device proof is for another key")
+	}
+
+	owner := peer.Name(rp.Immutables.Owner())
+	if !owner.SameUser(peer.Name(dp.Name)) {
+		return fmt.Errorf("»%s« is not a device of our user (%s)", dp.Name, owner)
+	}
+
+	return nil
+}
+
+// VerifyDevices checks the device proofs that the remote `who`
+// sent us. `remotePubKey` is the key the remote authenticated with;
+// one of the proofs has to be the remote's own. It is returned,
+// together with the fingerprints of all other devices that proved
+// to belong to the same user. Devices that use our own key, the key
+// of another remote or a revoked key are left out.
+func (rp *Repository) VerifyDevices(who string, remotePubKey []byte, proofs []*DeviceProof) (*DeviceProof, []peer.Fingerprint, error) {
+	var self *DeviceProof
+	for _, dp := range proofs {
+		if dp.Fingerprint.PubKeyMatches(remotePubKey) && bytes.Equal(dp.PubKey, remotePubKey) {
+			self = dp
+			break
+		}
+	}
+
+	if self == nil {
+		return nil, nil, fmt.Errorf("%s sent no device proof for its own key", who)
+	}
+
+	kr, err := rp.Keyring()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ownPubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user := peer.Name(self.Name)
+	seen := map[string]bool{self.Fingerprint.PubKeyID(): true}
+	devices := []peer.Fingerprint{}
+	for _, dp := range proofs {
+		id := dp.Fingerprint.PubKeyID()
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		if !user.SameUser(peer.Name(dp.Name)) {
+			log.Warningf("%s claims that %s (user of %s) is one of its devices; ignoring", who, dp.Name, self.Name)
+			continue
+		}
+
+		if bytes.Equal(dp.PubKey, ownPubKey) {
+			log.Warningf("%s claims that our key is one of its devices; ignoring", who)
+			continue
+		}
+
+		if other, err := rp.Remotes.RemoteByPubKeyID(id); err == nil && other.Name != who {
+			log.Warningf("%s claims that the key of %s is one of its devices; ignoring", who, other.Name)
+			continue
+		}
+
+		if _, revoked := rp.Revocations.Resolve(dp.Fingerprint); revoked {
+			continue
+		}
+
+		devices = append(devices, dp.Fingerprint)
+	}
+
+	return self, devices, nil
+}
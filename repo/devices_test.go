@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sahib/brig/net/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDeviceProof(t *testing.T, key testKey, name string) *DeviceProof {
+	dp, err := NewDeviceProof(key.kr, name, key.fp)
+	require.NoError(t, err)
+
+	enc, err := dp.Encode()
+	require.NoError(t, err)
+
+	decoded, err := DecodeDeviceProof(enc)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestDeviceProofEncoding(t *testing.T) {
+	withTestKeys(t, []string{"laptop", "mallory"}, func(keys map[string]testKey) {
+		laptop, mallory := keys["laptop"], keys["mallory"]
+
+		dp := mustDeviceProof(t, laptop, "ali/laptop")
+		require.Equal(t, "ali/laptop", dp.Name)
+		require.Equal(t, laptop.fp, dp.Fingerprint)
+
+		// Nobody can make a proof for the key of somebody else:
+		_, err := NewDeviceProof(mallory.kr, "ali/evil", laptop.fp)
+		require.Error(t, err)
+
+		// Changing the proof breaks the signature:
+		enc, err := dp.Encode()
+		require.NoError(t, err)
+
+		malloryProof := mustDeviceProof(t, mallory, "ali/evil")
+		malloryEnc, err := malloryProof.Encode()
+		require.NoError(t, err)
+
+		forged := strings.Split(malloryEnc, ".")[0] + "." + strings.Split(enc, ".")[1]
+		_, err = DecodeDeviceProof(forged)
+		require.Error(t, err)
+	})
+}
+
+func TestRepoVerifyDevices(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "brig-test-repo-devices")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	require.NoError(t, Init(InitOptions{
+		BaseFolder:  testDir,
+		Owner:       "carol",
+		BackendName: "mock",
+		DaemonURL:   "yadda-yadda",
+	}))
+
+	rp, err := Open(testDir)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	kr, err := rp.Keyring()
+	require.NoError(t, err)
+
+	ownPubKey, err := kr.OwnPubKey()
+	require.NoError(t, err)
+
+	ownFp := peer.BuildFingerprint("QmCarol", ownPubKey)
+	ownProof, err := rp.OwnDeviceProof(ownFp)
+	require.NoError(t, err)
+
+	names := []string{"laptop", "desktop", "phone", "bob", "eve"}
+	withTestKeys(t, names, func(keys map[string]testKey) {
+		laptop, desktop, bob := keys["laptop"], keys["desktop"], keys["bob"]
+		for _, name := range []string{"laptop", "bob"} {
+			require.NoError(t, rp.Remotes.AddOrUpdateRemote(Remote{
+				Name:        name,
+				Fingerprint: keys[name].fp,
+			}))
+		}
+
+		proofs := []*DeviceProof{
+			mustDeviceProof(t, laptop, "ali/laptop"),
+			mustDeviceProof(t, desktop, "ali/desktop"),
+			// A device of another user:
+			mustDeviceProof(t, keys["eve"], "eve/phone"),
+			// Keys of other remotes and our own key:
+			mustDeviceProof(t, bob, "ali/bob"),
+			ownProof,
+		}
+
+		self, devices, err := rp.VerifyDevices("laptop", mustPubKey(t, laptop), proofs)
+		require.NoError(t, err)
+		require.Equal(t, laptop.fp, self.Fingerprint)
+		require.Equal(t, []peer.Fingerprint{desktop.fp}, devices)
+
+		// Bob can not claim ali's devices without his own proof:
+		_, _, err = rp.VerifyDevices("bob", mustPubKey(t, bob), proofs[1:3])
+		require.Error(t, err)
+
+		// ...and even with it, they are not his:
+		proofs = []*DeviceProof{mustDeviceProof(t, bob, "bob"), proofs[1]}
+		_, devices, err = rp.VerifyDevices("bob", mustPubKey(t, bob), proofs)
+		require.NoError(t, err)
+		require.Empty(t, devices)
+
+		// Only proofs of our own user are accepted for our devices:
+		enc, err := mustDeviceProof(t, keys["phone"], "carol/phone").Encode()
+		require.NoError(t, err)
+		require.NoError(t, rp.CheckDeviceProof(enc, keys["phone"].fp))
+		require.Error(t, rp.CheckDeviceProof(enc, desktop.fp))
+
+		enc, err = proofs[1].Encode()
+		require.NoError(t, err)
+		require.Error(t, rp.CheckDeviceProof(enc, desktop.fp))
+	})
+}
+
+func mustPubKey(t *testing.T, key testKey) []byte {
+	pubKey, err := key.kr.OwnPubKey()
+	require.NoError(t, err)
+	return pubKey
+}
@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs/mio/compress"
+	yml "gopkg.in/yaml.v2"
+)
+
+// DictInfo describes a single stored compression dictionary.
+type DictInfo struct {
+	// ID is the hex encoded id of the dictionary.
+	ID string
+
+	// Folder is the folder the dictionary was trained for.
+	Folder string
+
+	// Size of the dictionary in bytes.
+	Size int64
+}
+
+// DictStore remembers zstd dictionaries, trained for folders with many
+// small, similar files. Dictionaries are stored content addressed in the
+// dicts/ folder of the repository. They never change once stored, since
+// they are needed to decode all files that were compressed with them.
+type DictStore struct {
+	mu     sync.Mutex
+	dir    string
+	infos  map[string]DictInfo
+	loaded map[compress.DictID]*compress.Dict
+}
+
+// NewDictStore opens (or creates) the dictionary store at `dir`.
+func NewDictStore(dir string) (*DictStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	ds := &DictStore{
+		dir:    dir,
+		infos:  make(map[string]DictInfo),
+		loaded: make(map[compress.DictID]*compress.Dict),
+	}
+
+	data, err := ioutil.ReadFile(ds.indexPath()) // #nosec
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	infos := []DictInfo{}
+	if err := yml.Unmarshal(data, &infos); err != nil {
+		return nil, e.Wrap(err, "failed to parse dictionary index")
+	}
+
+	for _, info := range infos {
+		ds.infos[info.ID] = info
+	}
+
+	return ds, nil
+}
+
+func (ds *DictStore) indexPath() string {
+	return filepath.Join(ds.dir, "index.yml")
+}
+
+func (ds *DictStore) dictPath(id compress.DictID) string {
+	return filepath.Join(ds.dir, id.String()+".dict")
+}
+
+func (ds *DictStore) saveIndex() error {
+	data, err := yml.Marshal(ds.list())
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(ds.indexPath(), data, 0600)
+}
+
+// Add stores `dict` as dictionary for `folder`.
+// Adding the same dictionary twice only updates its folder.
+func (ds *DictStore) Add(folder string, dict *compress.Dict) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ioutil.WriteFile(ds.dictPath(dict.ID), dict.Data, 0600); err != nil {
+		return err
+	}
+
+	ds.loaded[dict.ID] = dict
+	ds.infos[dict.ID.String()] = DictInfo{
+		ID:     dict.ID.String(),
+		Folder: folder,
+		Size:   int64(len(dict.Data)),
+	}
+
+	return ds.saveIndex()
+}
+
+// Dict returns the dictionary with `id`. If there is no such dictionary,
+// an error wrapping compress.ErrDictNotFound is returned.
+func (ds *DictStore) Dict(id compress.DictID) (*compress.Dict, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if dict, ok := ds.loaded[id]; ok {
+		return dict, nil
+	}
+
+	data, err := ioutil.ReadFile(ds.dictPath(id)) // #nosec
+	if os.IsNotExist(err) {
+		return nil, e.Wrapf(compress.ErrDictNotFound, "%s", id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	dict, err := compress.NewDict(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if dict.ID != id {
+		return nil, e.Errorf("dictionary %s is corrupted", id)
+	}
+
+	ds.loaded[id] = dict
+	return dict, nil
+}
+
+func (ds *DictStore) list() []DictInfo {
+	infos := []DictInfo{}
+	for _, info := range ds.infos {
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Folder != infos[j].Folder {
+			return infos[i].Folder < infos[j].Folder
+		}
+
+		return infos[i].ID < infos[j].ID
+	})
+
+	return infos
+}
+
+// List returns all stored dictionaries, sorted by their folder.
+func (ds *DictStore) List() []DictInfo {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	return ds.list()
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/sahib/brig/catfs/mio/compress"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDictStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brig-test-dicts")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	samples := [][]byte{}
+	for idx := 0; idx < 100; idx++ {
+		samples = append(samples, []byte(fmt.Sprintf("user=%d action=login result=ok", idx)))
+	}
+
+	dict, err := compress.TrainDict(samples, compress.DefaultDictSize)
+	require.NoError(t, err)
+
+	ds, err := NewDictStore(dir)
+	require.NoError(t, err)
+
+	_, err = ds.Dict(dict.ID)
+	require.True(t, compress.IsDictNotFoundError(err))
+
+	require.NoError(t, ds.Add("/logs", dict))
+
+	// A freshly loaded store should know about the dict:
+	newDs, err := NewDictStore(dir)
+	require.NoError(t, err)
+
+	loaded, err := newDs.Dict(dict.ID)
+	require.NoError(t, err)
+	require.Equal(t, dict.Data, loaded.Data)
+	require.Equal(t, []DictInfo{{
+		ID:     dict.ID.String(),
+		Folder: "/logs",
+		Size:   int64(len(dict.Data)),
+	}}, newDs.List())
+}
@@ -171,6 +171,15 @@ type Hint struct {
 
 	// EncryptionAlgo must be a valid encryption algorithm.
 	EncryptionAlgo EncryptionHint
+
+	// ZstdDict is the hex encoded id of a zstd dictionary that is used
+	// to compress the files. Only used with zstd compression; may be empty.
+	ZstdDict string
+
+	// KeyDomain is the name of the key domain the files belong to.
+	// All folders of a domain are re-keyed together (see »brig rekey«).
+	// May be empty if the folder is not part of any domain.
+	KeyDomain string
 }
 
 // Small heuristic to decide if we should use ChaCha20
@@ -201,6 +210,16 @@ func Default() Hint {
 
 // IsValid checks if all fields of the hint are valid.
 func (h Hint) IsValid() bool {
+	if h.ZstdDict != "" {
+		if _, err := compress.ParseDictID(h.ZstdDict); err != nil {
+			return false
+		}
+	}
+
+	if !IsValidKeyDomain(h.KeyDomain) {
+		return false
+	}
+
 	return h.EncryptionAlgo.IsValid() && h.CompressionAlgo.IsValid()
 }
 
@@ -221,7 +240,26 @@ func (h Hint) IsRaw() bool {
 }
 
 func (h Hint) String() string {
-	return fmt.Sprintf("enc:%s-zip:%s", h.EncryptionAlgo, h.CompressionAlgo)
+	s := fmt.Sprintf("enc:%s-zip:%s", h.EncryptionAlgo, h.CompressionAlgo)
+	if h.ZstdDict != "" {
+		s += "-dict:" + h.ZstdDict
+	}
+
+	if h.KeyDomain != "" {
+		s += "-domain:" + h.KeyDomain
+	}
+
+	return s
+}
+
+// KeyDomainNone can be passed instead of an empty domain name
+// to remove a folder from its key domain.
+const KeyDomainNone = "none"
+
+// IsValidKeyDomain checks if `domain` can be used as name of a key domain.
+// The empty string is valid and means "no domain".
+func IsValidKeyDomain(domain string) bool {
+	return !strings.ContainsAny(domain, "/ \t\n")
 }
 
 // Less returns false if `o` should be sorted before `h`.
@@ -308,6 +346,16 @@ var (
 					Docs:         "Which encryption algorithm to use.",
 					Validator:    config.EnumValidator(ValidEncryptionHints()...),
 				},
+				"zstd_dict": config.DefaultEntry{
+					Default:      "",
+					NeedsRestart: false,
+					Docs:         "Id of the zstd dictionary to compress with (see »brig hints train-dict«).",
+				},
+				"key_domain": config.DefaultEntry{
+					Default:      "",
+					NeedsRestart: false,
+					Docs:         "Name of the key domain; all its folders are re-keyed together (see »brig rekey«).",
+				},
 			},
 		},
 	}
@@ -363,6 +411,8 @@ func NewManager(yamlReader io.Reader) (*HintManager, error) {
 		hint := Hint{
 			CompressionAlgo: CompressionHint(hintMapping.String(prefixKey + ".compression_algo")),
 			EncryptionAlgo:  EncryptionHint(hintMapping.String(prefixKey + ".encryption_algo")),
+			ZstdDict:        hintMapping.String(prefixKey + ".zstd_dict"),
+			KeyDomain:       hintMapping.String(prefixKey + ".key_domain"),
 		}
 
 		// Fill up a trie with each hint:
@@ -448,6 +498,27 @@ func (hm *HintManager) list() map[string]Hint {
 	return hints
 }
 
+// KeyDomainFolders returns the sorted paths of all hints that are part of
+// the key domain `domain`. An empty domain has no folders.
+func (hm *HintManager) KeyDomainFolders(domain string) []string {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	folders := []string{}
+	if domain == "" {
+		return folders
+	}
+
+	for path, hint := range hm.list() {
+		if hint.KeyDomain == domain {
+			folders = append(folders, path)
+		}
+	}
+
+	sort.Strings(folders)
+	return folders
+}
+
 // Save writes a YAML representation of the hints to `w`.
 func (hm *HintManager) Save(w io.Writer) error {
 	hm.mu.Lock()
@@ -463,6 +534,8 @@ func (hm *HintManager) Save(w io.Writer) error {
 		hintMapping.SetString(path+".path", path)
 		hintMapping.SetString(path+".compression_algo", string(hint.CompressionAlgo))
 		hintMapping.SetString(path+".encryption_algo", string(hint.EncryptionAlgo))
+		hintMapping.SetString(path+".zstd_dict", hint.ZstdDict)
+		hintMapping.SetString(path+".key_domain", hint.KeyDomain)
 	}
 
 	return emptyCfg.Save(config.NewYamlEncoder(w))
@@ -44,3 +44,31 @@ func TestHintManager(t *testing.T) {
 	require.NoError(t, newMgr.Remove("/a/b/c"))
 	require.Equal(t, Default(), newMgr.Lookup("/a/b/c/d"))
 }
+
+func TestHintKeyDomains(t *testing.T) {
+	mgr, err := NewManager(nil)
+	require.NoError(t, err)
+
+	team := Default()
+	team.KeyDomain = "team"
+
+	require.NoError(t, mgr.Set("/team", team))
+	require.NoError(t, mgr.Set("/shared/team", team))
+	require.NoError(t, mgr.Set("/private", Default()))
+
+	invalid := Default()
+	invalid.KeyDomain = "a/b"
+	require.Equal(t, ErrInvalidHint, mgr.Set("/x", invalid))
+
+	require.Equal(t, []string{"/shared/team", "/team"}, mgr.KeyDomainFolders("team"))
+	require.Empty(t, mgr.KeyDomainFolders("other"))
+	require.Empty(t, mgr.KeyDomainFolders(""))
+
+	yamlBuf := bytes.NewBuffer(nil)
+	require.NoError(t, mgr.Save(yamlBuf))
+
+	newMgr, err := NewManager(yamlBuf)
+	require.NoError(t, err)
+	require.Equal(t, "team", newMgr.Lookup("/team/a").KeyDomain)
+	require.Equal(t, "", newMgr.Lookup("/private/a").KeyDomain)
+}
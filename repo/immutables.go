@@ -4,6 +4,7 @@ import (
 	"os"
 
 	e "github.com/pkg/errors"
+	h "github.com/sahib/brig/util/hashlib"
 	"github.com/sahib/config"
 )
 
@@ -31,6 +32,12 @@ var immutableDefaultsV0 = config.DefaultMapping{
 		NeedsRestart: true,
 		Docs:         "Hash of the first commit",
 	},
+	"hash_algorithm": config.DefaultEntry{
+		Default:      h.DefaultAlgorithm.String(),
+		NeedsRestart: true,
+		Docs:         "Algorithm of the content and tree hashes (see »brig repo rehash«)",
+		Validator:    config.EnumValidator(h.Algorithms()...),
+	},
 	"version": config.DefaultEntry{
 		Default:      currentImmutablesVersion,
 		NeedsRestart: true,
@@ -60,7 +67,8 @@ func openMigratedImmutables(path string) (*config.Config, error) {
 // Immutables gives access to different values that can not be changed
 // by the user and were determined during the init of the repository.
 type Immutables struct {
-	cfg *config.Config
+	cfg  *config.Config
+	path string
 }
 
 // NewImmutables loads the immutable.yml at `path`
@@ -70,7 +78,7 @@ func NewImmutables(path string) (*Immutables, error) {
 		return nil, err
 	}
 
-	return &Immutables{cfg: cfg}, nil
+	return &Immutables{cfg: cfg, path: path}, nil
 }
 
 // Owner returns the owner of the repository.
@@ -82,3 +90,24 @@ func (i *Immutables) Owner() string {
 func (i *Immutables) Backend() string {
 	return i.cfg.String("backend")
 }
+
+// HashAlgorithm returns the algorithm of the content and tree hashes.
+func (i *Immutables) HashAlgorithm() h.Algorithm {
+	algo, err := h.ParseAlgorithm(i.cfg.String("hash_algorithm"))
+	if err != nil {
+		// The validator makes sure this does not happen.
+		return h.DefaultAlgorithm
+	}
+
+	return algo
+}
+
+// setHashAlgorithm changes the hash algorithm and saves the immutables.
+// This may only be done together with rehashing all metadata.
+func (i *Immutables) setHashAlgorithm(algo h.Algorithm) error {
+	if err := i.cfg.SetString("hash_algorithm", algo.String()); err != nil {
+		return err
+	}
+
+	return config.ToYamlFile(i.path, i.cfg)
+}
@@ -9,6 +9,7 @@ import (
 	e "github.com/pkg/errors"
 	"github.com/sahib/brig/defaults"
 	"github.com/sahib/brig/util"
+	h "github.com/sahib/brig/util/hashlib"
 	"github.com/sahib/config"
 	log "github.com/sirupsen/logrus"
 )
@@ -26,6 +27,10 @@ type InitOptions struct {
 
 	// DaemonURL is the URL that will be used for the brig daemon.
 	DaemonURL string
+
+	// HashAlgorithm is the name of the algorithm for content and tree hashes.
+	// If empty, the default algorithm is used.
+	HashAlgorithm string
 }
 
 // IsValidBackendName tells you if `name` is a valid backend name.
@@ -48,6 +53,12 @@ func (opts InitOptions) Validate() error {
 		return fmt.Errorf("owner may not be empty")
 	}
 
+	if opts.HashAlgorithm != "" {
+		if _, err := h.ParseAlgorithm(opts.HashAlgorithm); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -112,6 +123,12 @@ func Init(opts InitOptions) error {
 		return err
 	}
 
+	if opts.HashAlgorithm != "" {
+		if err := immutables.SetString("hash_algorithm", opts.HashAlgorithm); err != nil {
+			return err
+		}
+	}
+
 	immutablePath := filepath.Join(opts.BaseFolder, "immutable.yml")
 	if err := config.ToYamlFile(immutablePath, immutables); err != nil {
 		return e.Wrap(err, "failed to setup immutables config")
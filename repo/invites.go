@@ -0,0 +1,333 @@
+package repo
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/net/peer"
+	yml "gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrNoSuchInvite is returned when an invite was not found,
+	// was already used or is expired.
+	ErrNoSuchInvite = errors.New("No such invite (or it expired)")
+)
+
+// Invite is an offer we made to a peer that we do not know yet.
+// Whoever can present the secret of the invite before it expires
+// is added as remote with the folders of the invite.
+type Invite struct {
+	// ID identifies the invite.
+	ID string
+
+	// Secret is the hex encoded secret of the invite.
+	Secret string
+
+	// Folders are the folders that the new remote will be able to see.
+	Folders []Folder
+
+	// AcceptPush is taken over to the new remote.
+	AcceptPush bool
+
+	// Device is true if the invite links another device of our own user.
+	// Folders and AcceptPush do not matter then; devices get everything.
+	Device bool
+
+	// Expires is the time after which the invite can not be used anymore.
+	Expires time.Time
+}
+
+// InviteStore remembers the invites that were not accepted yet.
+// Invites can be accepted only once.
+type InviteStore struct {
+	mu      sync.Mutex
+	path    string
+	invites map[string]*Invite
+}
+
+// NewInviteStore opens (or creates) the invite store at `path`.
+func NewInviteStore(path string) (*InviteStore, error) {
+	data, err := ioutil.ReadFile(path) // #nosec
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	invites := make(map[string]*Invite)
+	if err := yml.Unmarshal(data, invites); err != nil {
+		return nil, e.Wrap(err, "failed to parse invites")
+	}
+
+	return &InviteStore{
+		path:    path,
+		invites: invites,
+	}, nil
+}
+
+func (is *InviteStore) save() error {
+	// Expired invites are of no use to anyone:
+	now := time.Now()
+	for id, invite := range is.invites {
+		if now.After(invite.Expires) {
+			delete(is.invites, id)
+		}
+	}
+
+	data, err := yml.Marshal(is.invites)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(is.path, data, 0600)
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// Create makes a new invite, valid for `validFor`, and stores it.
+func (is *InviteStore) Create(folders []Folder, acceptPush bool, validFor time.Duration) (Invite, error) {
+	return is.create(&Invite{
+		Folders:    dedupeFolders(folders),
+		AcceptPush: acceptPush,
+	}, validFor)
+}
+
+// CreateForDevice makes a new invite for another device of our user.
+func (is *InviteStore) CreateForDevice(validFor time.Duration) (Invite, error) {
+	return is.create(&Invite{Device: true}, validFor)
+}
+
+func (is *InviteStore) create(invite *Invite, validFor time.Duration) (Invite, error) {
+	if validFor <= 0 {
+		return Invite{}, fmt.Errorf("invites need a positive expiry time")
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return Invite{}, err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return Invite{}, err
+	}
+
+	invite.ID = id
+	invite.Secret = secret
+	invite.Expires = time.Now().Add(validFor).Round(time.Second)
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	is.invites[id] = invite
+	return *invite, is.save()
+}
+
+// List returns all invites that were not used and did not expire yet.
+func (is *InviteStore) List() []Invite {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	now := time.Now()
+	invites := []Invite{}
+	for _, invite := range is.invites {
+		if now.Before(invite.Expires) {
+			invites = append(invites, *invite)
+		}
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].Expires.Before(invites[j].Expires)
+	})
+
+	return invites
+}
+
+// HasPending returns true if there is at least one usable invite.
+func (is *InviteStore) HasPending() bool {
+	return len(is.List()) > 0
+}
+
+// Take returns the invite with `id` if `secret` matches and removes it,
+// so that it can not be used a second time. If `check` is not nil, it is
+// called with the invite before; if it fails, the invite is kept and the
+// error is returned.
+func (is *InviteStore) Take(id, secret string, check func(invite Invite) error) (Invite, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	invite, ok := is.invites[id]
+	if !ok || time.Now().After(invite.Expires) {
+		return Invite{}, ErrNoSuchInvite
+	}
+
+	if subtle.ConstantTimeCompare([]byte(invite.Secret), []byte(secret)) != 1 {
+		return Invite{}, ErrNoSuchInvite
+	}
+
+	if check != nil {
+		if err := check(*invite); err != nil {
+			return Invite{}, err
+		}
+	}
+
+	delete(is.invites, id)
+	return *invite, is.save()
+}
+
+// Remove deletes the invite with `id` without using it.
+func (is *InviteStore) Remove(id string) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if _, ok := is.invites[id]; !ok {
+		return ErrNoSuchInvite
+	}
+
+	delete(is.invites, id)
+	return is.save()
+}
+
+// InviteFolder is a folder offered by an invite.
+type InviteFolder struct {
+	Folder string `json:"folder"`
+
+	// ReadOnly is true if the inviter will not take changes to this folder.
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// InviteToken is what the inviter passes to the invited peer.
+// It has everything needed to contact the inviter and to prove
+// that we were invited. It is signed by the inviter.
+type InviteToken struct {
+	Name        string           `json:"name"`
+	Fingerprint peer.Fingerprint `json:"fingerprint"`
+	Folders     []InviteFolder   `json:"folders"`
+	Expires     time.Time        `json:"expires"`
+	ID          string           `json:"id"`
+	Secret      string           `json:"secret"`
+
+	// Device is true if the token links another device of the inviter's user.
+	Device bool `json:"device,omitempty"`
+
+	// payload and signature are only set for decoded tokens.
+	payload   []byte
+	signature []byte
+}
+
+// NewInviteToken builds the token for `invite`, made by `name` with `fingerprint`.
+func NewInviteToken(name string, fingerprint peer.Fingerprint, invite Invite) *InviteToken {
+	folders := []InviteFolder{}
+	for _, folder := range invite.Folders {
+		folders = append(folders, InviteFolder{
+			Folder:   folder.Folder,
+			ReadOnly: folder.ReadOnly,
+		})
+	}
+
+	return &InviteToken{
+		Name:        name,
+		Fingerprint: fingerprint,
+		Folders:     folders,
+		Expires:     invite.Expires,
+		ID:          invite.ID,
+		Secret:      invite.Secret,
+		Device:      invite.Device,
+	}
+}
+
+// Encode signs the token with our key in `kr` and returns it
+// in a form that can be easily copied around.
+func (it *InviteToken) Encode(kr *Keyring) (string, error) {
+	payload, err := json.Marshal(it)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := kr.Sign(payload)
+	if err != nil {
+		return "", e.Wrap(err, "failed to sign invite")
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(signature), nil
+}
+
+// DecodeInviteToken parses a token produced by Encode.
+// The signature is not checked here, since the public key of the inviter
+// is only known after connecting to it. Use Verify for that.
+func DecodeInviteToken(token string) (*InviteToken, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("bad invite token: expected two parts, got %d", len(parts))
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, e.Wrap(err, "bad invite token")
+	}
+
+	signature, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, e.Wrap(err, "bad invite token signature")
+	}
+
+	it := &InviteToken{}
+	if err := json.Unmarshal(payload, it); err != nil {
+		return nil, e.Wrap(err, "bad invite token")
+	}
+
+	if _, err := peer.CastFingerprint(string(it.Fingerprint)); err != nil {
+		return nil, err
+	}
+
+	if _, err := peer.CastName(it.Name); err != nil {
+		return nil, err
+	}
+
+	it.payload = payload
+	it.signature = signature
+	return it, nil
+}
+
+// IsExpired returns true if the inviter will not accept the token anymore.
+func (it *InviteToken) IsExpired() bool {
+	return time.Now().After(it.Expires)
+}
+
+// Verify checks that the token was signed by the owner of `pubKey`
+// and that `pubKey` belongs to the fingerprint in the token.
+func (it *InviteToken) Verify(pubKey []byte) error {
+	if !it.Fingerprint.PubKeyMatches(pubKey) {
+		return fmt.Errorf("public key of inviter does not match the fingerprint in the token")
+	}
+
+	if it.payload == nil {
+		return fmt.Errorf("only decoded invite tokens can be verified")
+	}
+
+	if err := VerifySignature(it.payload, it.signature, pubKey); err != nil {
+		return e.Wrap(err, "bad signature of invite token")
+	}
+
+	return nil
+}
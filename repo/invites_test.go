@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sahib/brig/net/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brig-test-invites")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "invites.yml")
+	is, err := NewInviteStore(path)
+	require.NoError(t, err)
+	require.False(t, is.HasPending())
+
+	_, err = is.Create(nil, false, 0)
+	require.Error(t, err)
+
+	folders := []Folder{{Folder: "/shared"}, {Folder: "/public", ReadOnly: true}}
+	invite, err := is.Create(folders, true, time.Hour)
+	require.NoError(t, err)
+	require.True(t, is.HasPending())
+
+	// Survives a reload:
+	is, err = NewInviteStore(path)
+	require.NoError(t, err)
+	invites := is.List()
+	require.Len(t, invites, 1)
+	require.Equal(t, invite.ID, invites[0].ID)
+	require.Equal(t, invite.Secret, invites[0].Secret)
+	require.True(t, invite.Expires.Equal(invites[0].Expires))
+
+	_, err = is.Take(invite.ID, "wrong secret", nil)
+	require.Equal(t, ErrNoSuchInvite, err)
+
+	taken, err := is.Take(invite.ID, invite.Secret, nil)
+	require.NoError(t, err)
+	require.Equal(t, folders, taken.Folders)
+	require.True(t, taken.AcceptPush)
+
+	// Invites can be used only once:
+	_, err = is.Take(invite.ID, invite.Secret, nil)
+	require.Equal(t, ErrNoSuchInvite, err)
+	require.False(t, is.HasPending())
+
+	// Expired invites can not be taken:
+	expired, err := is.Create(folders, false, time.Hour)
+	require.NoError(t, err)
+	is.invites[expired.ID].Expires = time.Now().Add(-time.Minute)
+	require.False(t, is.HasPending())
+
+	_, err = is.Take(expired.ID, expired.Secret, nil)
+	require.Equal(t, ErrNoSuchInvite, err)
+
+	require.Equal(t, ErrNoSuchInvite, is.Remove("nope"))
+
+	device, err := is.CreateForDevice(time.Hour)
+	require.NoError(t, err)
+	require.True(t, device.Device)
+
+	// A failing check keeps the invite:
+	_, err = is.Take(device.ID, device.Secret, func(invite Invite) error {
+		require.True(t, invite.Device)
+		return errors.New("bad device proof")
+	})
+	require.EqualError(t, err, "bad device proof")
+	require.True(t, is.HasPending())
+
+	taken, err = is.Take(device.ID, device.Secret, nil)
+	require.NoError(t, err)
+	require.True(t, taken.Device)
+	require.Empty(t, taken.Folders)
+}
+
+func TestInviteToken(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "brig-test-invite-token")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	require.Nil(t, createKeyPair("alice", testDir, 1024))
+	require.Nil(t, createKeyPair("bob", testDir, 1024))
+
+	alice := newKeyringHandle(testDir, "alice")
+	alicePubKey, err := alice.OwnPubKey()
+	require.NoError(t, err)
+
+	bob := newKeyringHandle(testDir, "bob")
+	bobPubKey, err := bob.OwnPubKey()
+	require.NoError(t, err)
+
+	invite := Invite{
+		ID:      "1234",
+		Secret:  "secret",
+		Folders: []Folder{{Folder: "/shared", ReadOnly: true}},
+		Expires: time.Now().Add(time.Hour).Round(time.Second),
+	}
+
+	fp := peer.BuildFingerprint("QmAliceAddr", alicePubKey)
+	token, err := NewInviteToken("alice", fp, invite).Encode(alice)
+	require.NoError(t, err)
+
+	decoded, err := DecodeInviteToken(token)
+	require.NoError(t, err)
+	require.Equal(t, "alice", decoded.Name)
+	require.Equal(t, fp, decoded.Fingerprint)
+	require.Equal(t, []InviteFolder{{Folder: "/shared", ReadOnly: true}}, decoded.Folders)
+	require.Equal(t, "1234", decoded.ID)
+	require.Equal(t, "secret", decoded.Secret)
+	require.True(t, invite.Expires.Equal(decoded.Expires))
+	require.False(t, decoded.IsExpired())
+	require.False(t, decoded.Device)
+
+	require.NoError(t, decoded.Verify(alicePubKey))
+	require.Error(t, decoded.Verify(bobPubKey))
+
+	// A token signed by somebody else than the fingerprint's owner:
+	forged, err := NewInviteToken("alice", fp, invite).Encode(bob)
+	require.NoError(t, err)
+
+	decoded, err = DecodeInviteToken(forged)
+	require.NoError(t, err)
+	require.Error(t, decoded.Verify(alicePubKey))
+
+	// Modified payload with the old signature:
+	parts := strings.Split(token, ".")
+	otherFp := peer.BuildFingerprint("QmMalloryAddr", alicePubKey)
+	tampered, err := NewInviteToken("alice", otherFp, invite).Encode(bob)
+	require.NoError(t, err)
+
+	decoded, err = DecodeInviteToken(strings.Split(tampered, ".")[0] + "." + parts[1])
+	require.NoError(t, err)
+	require.Error(t, decoded.Verify(alicePubKey))
+
+	_, err = DecodeInviteToken("garbage")
+	require.Error(t, err)
+}
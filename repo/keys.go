@@ -150,3 +150,87 @@ func (kp *Keyring) SavePubKey(name string, pubKey []byte) error {
 	pubKeyPath := filepath.Join(base, filepath.Clean(name))
 	return ioutil.WriteFile(pubKeyPath, pubKey, 0600)
 }
+
+// Sign creates a detached signature of `data` with our private key.
+func (kp *Keyring) Sign(data []byte) ([]byte, error) {
+	prvPath := filepath.Join(kp.folder, kp.owner, "key.prv")
+	fd, err := os.Open(prvPath) // #nosec
+	if err != nil {
+		return nil, err
+	}
+
+	defer util.Closer(fd)
+
+	ents, err := openpgp.ReadKeyRing(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ents) == 0 {
+		return nil, errors.New("no private key in keyring")
+	}
+
+	sigBuf := &bytes.Buffer{}
+	if err := openpgp.DetachSign(sigBuf, ents[0], bytes.NewReader(data), nil); err != nil {
+		return nil, err
+	}
+
+	return sigBuf.Bytes(), nil
+}
+
+// VerifySignature checks that `sig` is a signature of `data`,
+// made by the private key belonging to `pubKey`.
+func VerifySignature(data, sig, pubKey []byte) error {
+	ents, err := openpgp.ReadKeyRing(bytes.NewReader(pubKey))
+	if err != nil {
+		return err
+	}
+
+	_, err = openpgp.CheckDetachedSignature(ents, bytes.NewReader(data), bytes.NewReader(sig))
+	return err
+}
+
+func (kp *Keyring) nextFolder() string {
+	return filepath.Join(kp.folder, kp.owner, "next")
+}
+
+// CreateNextKey generates the key pair that will replace our current one
+// once SwitchToNextKey is called. Until then, the current key stays in use.
+func (kp *Keyring) CreateNextKey(bits int) error {
+	if err := os.RemoveAll(kp.nextFolder()); err != nil {
+		return err
+	}
+
+	return createKeyPair(kp.owner, kp.nextFolder(), bits)
+}
+
+// NextPubKey returns the public key created by CreateNextKey.
+func (kp *Keyring) NextPubKey() ([]byte, error) {
+	pubPath := filepath.Join(kp.nextFolder(), kp.owner, "key.pub")
+	return ioutil.ReadFile(pubPath) // #nosec
+}
+
+// SwitchToNextKey makes the key created by CreateNextKey our own key.
+// The old key pair is kept with the suffix ».old«.
+func (kp *Keyring) SwitchToNextKey() error {
+	ownFolder := filepath.Join(kp.folder, kp.owner)
+	nextFolder := filepath.Join(kp.nextFolder(), kp.owner)
+	for _, name := range []string{"key.pub", "key.prv"} {
+		if _, err := os.Stat(filepath.Join(nextFolder, name)); err != nil {
+			return fmt.Errorf("no next key: %v", err)
+		}
+	}
+
+	for _, name := range []string{"key.pub", "key.prv"} {
+		curr := filepath.Join(ownFolder, name)
+		if err := os.Rename(curr, curr+".old"); err != nil {
+			return err
+		}
+
+		if err := os.Rename(filepath.Join(nextFolder, name), curr); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(kp.nextFolder())
+}
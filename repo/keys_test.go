@@ -40,3 +40,65 @@ func TestKeyring(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, remotePubKey, []byte{1})
 }
+
+func TestKeyringSign(t *testing.T) {
+	testDir := filepath.Join(os.TempDir(), "brig-repo-sign-test")
+	require.Nil(t, os.MkdirAll(testDir, 0755))
+	defer os.RemoveAll(testDir)
+
+	require.Nil(t, createKeyPair("alice", testDir, 1024))
+	require.Nil(t, createKeyPair("bob", testDir, 1024))
+
+	alice := newKeyringHandle(testDir, "alice")
+	alicePubKey, err := alice.OwnPubKey()
+	require.NoError(t, err)
+
+	bob := newKeyringHandle(testDir, "bob")
+	bobPubKey, err := bob.OwnPubKey()
+	require.NoError(t, err)
+
+	data := []byte("Hello!")
+	sig, err := alice.Sign(data)
+	require.NoError(t, err)
+
+	require.NoError(t, VerifySignature(data, sig, alicePubKey))
+	require.Error(t, VerifySignature([]byte("Hello?"), sig, alicePubKey))
+	require.Error(t, VerifySignature(data, sig, bobPubKey))
+}
+
+func TestKeyringSwitchToNextKey(t *testing.T) {
+	testDir := filepath.Join(os.TempDir(), "brig-repo-next-key-test")
+	require.Nil(t, os.MkdirAll(testDir, 0755))
+	defer os.RemoveAll(testDir)
+
+	require.Nil(t, createKeyPair("alice", testDir, 1024))
+
+	kr := newKeyringHandle(testDir, "alice")
+	require.Error(t, kr.SwitchToNextKey())
+
+	oldPubKey, err := kr.OwnPubKey()
+	require.NoError(t, err)
+
+	require.NoError(t, kr.CreateNextKey(1024))
+	nextPubKey, err := kr.NextPubKey()
+	require.NoError(t, err)
+	require.NotEqual(t, oldPubKey, nextPubKey)
+
+	// The current key is still in use:
+	currPubKey, err := kr.OwnPubKey()
+	require.NoError(t, err)
+	require.Equal(t, oldPubKey, currPubKey)
+
+	require.NoError(t, kr.SwitchToNextKey())
+	currPubKey, err = kr.OwnPubKey()
+	require.NoError(t, err)
+	require.Equal(t, nextPubKey, currPubKey)
+
+	// The private key was switched too:
+	sig, err := kr.Sign([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, VerifySignature([]byte("hello"), sig, nextPubKey))
+
+	_, err = kr.NextPubKey()
+	require.Error(t, err)
+}
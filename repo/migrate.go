@@ -0,0 +1,247 @@
+package repo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs"
+	h "github.com/sahib/brig/util/hashlib"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	metadataDir         = "metadata"
+	metadataMigrateDir  = "metadata.migrate"
+	metadataRollbackDir = "metadata.rollback"
+
+	// immutablesRollbackName is the immutable.yml before Rehash().
+	immutablesRollbackName = "immutable.yml.rollback"
+)
+
+// HaveDatabaseRollback returns true when a previous call to
+// MigrateDatabases() was neither confirmed nor rolled back yet.
+func HaveDatabaseRollback(baseFolder string) (bool, error) {
+	_, err := os.Stat(filepath.Join(baseFolder, metadataRollbackDir))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// MigrateDatabases converts the metadata databases of all filesystems of the
+// repository at `baseFolder` to the database type `dbType`. The daemon may
+// not run while doing this. The key count and checksum of each database is
+// verified after copying. The old metadata is kept as rollback until either
+// ConfirmDatabaseMigration() or RollbackDatabaseMigration() is called.
+// The result maps the owner of each filesystem to its migration stats.
+func MigrateDatabases(baseFolder, dbType string) (map[string]*catfs.MigrateStats, error) {
+	haveRollback, err := HaveDatabaseRollback(baseFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	if haveRollback {
+		return nil, fmt.Errorf("previous migration was not confirmed or rolled back yet")
+	}
+
+	srcDir := filepath.Join(baseFolder, metadataDir)
+	dstDir := filepath.Join(baseFolder, metadataMigrateDir)
+
+	// Left over from a previous, failed migration:
+	if err := os.RemoveAll(dstDir); err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	allStats := make(map[string]*catfs.MigrateStats)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		owner := entry.Name()
+		log.Infof("migrating metadata of »%s« to %s", owner, dbType)
+		stats, err := catfs.MigrateDatabase(
+			filepath.Join(srcDir, owner),
+			filepath.Join(dstDir, owner),
+			dbType,
+		)
+
+		if err != nil {
+			if rmErr := os.RemoveAll(dstDir); rmErr != nil {
+				log.Warnf("failed to clean up %s: %v", dstDir, rmErr)
+			}
+
+			return nil, e.Wrapf(err, "failed to migrate metadata of %s", owner)
+		}
+
+		allStats[owner] = stats
+	}
+
+	if err := swapMetadata(baseFolder); err != nil {
+		return nil, err
+	}
+
+	return allStats, nil
+}
+
+// swapMetadata makes the migrated metadata the current one
+// and keeps the current one as rollback.
+func swapMetadata(baseFolder string) error {
+	srcDir := filepath.Join(baseFolder, metadataDir)
+	dstDir := filepath.Join(baseFolder, metadataMigrateDir)
+	rollbackDir := filepath.Join(baseFolder, metadataRollbackDir)
+
+	if err := os.Rename(srcDir, rollbackDir); err != nil {
+		return err
+	}
+
+	if err := os.Rename(dstDir, srcDir); err != nil {
+		// Try to bring the old state back at least:
+		if rbErr := os.Rename(rollbackDir, srcDir); rbErr != nil {
+			log.Warnf("failed to restore %s: %v", srcDir, rbErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmDatabaseMigration deletes the metadata kept by MigrateDatabases()
+// or Rehash().
+func ConfirmDatabaseMigration(baseFolder string) error {
+	haveRollback, err := HaveDatabaseRollback(baseFolder)
+	if err != nil {
+		return err
+	}
+
+	if !haveRollback {
+		return fmt.Errorf("no migration to confirm")
+	}
+
+	immutablesRollbackPath := filepath.Join(baseFolder, immutablesRollbackName)
+	if err := os.RemoveAll(immutablesRollbackPath); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(baseFolder, metadataRollbackDir))
+}
+
+// RollbackDatabaseMigration restores the metadata as it was before
+// MigrateDatabases() or Rehash() was called. Changes made since then are lost.
+func RollbackDatabaseMigration(baseFolder string) error {
+	haveRollback, err := HaveDatabaseRollback(baseFolder)
+	if err != nil {
+		return err
+	}
+
+	if !haveRollback {
+		return fmt.Errorf("no migration to roll back")
+	}
+
+	// Rehash() also changed the hash algorithm in the immutables:
+	immutablesRollbackPath := filepath.Join(baseFolder, immutablesRollbackName)
+	if _, err := os.Stat(immutablesRollbackPath); err == nil {
+		immutablesPath := filepath.Join(baseFolder, "immutable.yml")
+		if err := os.Rename(immutablesRollbackPath, immutablesPath); err != nil {
+			return err
+		}
+	}
+
+	srcDir := filepath.Join(baseFolder, metadataDir)
+	if err := os.RemoveAll(srcDir); err != nil {
+		return err
+	}
+
+	return os.Rename(filepath.Join(baseFolder, metadataRollbackDir), srcDir)
+}
+
+// Rehash rewrites the metadata of the own filesystem, so that all content,
+// tree and commit hashes are computed with `algo`, and records `algo` in the
+// immutables. The content of all files is read from `bk` for this. The
+// metadata of other owners (mirrors of remotes) is dropped; it is fetched
+// again on the next sync. Like with MigrateDatabases() the daemon may not
+// run and the old state is kept until either ConfirmDatabaseMigration() or
+// RollbackDatabaseMigration() is called. `rp` is closed afterwards.
+func (rp *Repository) Rehash(bk catfs.FsBackend, algo h.Algorithm) (*catfs.RehashStats, error) {
+	baseFolder := rp.BaseFolder
+	haveRollback, err := HaveDatabaseRollback(baseFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	if haveRollback {
+		return nil, fmt.Errorf("previous migration was not confirmed or rolled back yet")
+	}
+
+	oldAlgo := rp.Immutables.HashAlgorithm()
+	if oldAlgo == algo {
+		return nil, fmt.Errorf("repository already uses %s", algo)
+	}
+
+	// Make sure we read the metadata with the right algorithm:
+	if err := h.SetInternalAlgorithm(oldAlgo); err != nil {
+		return nil, err
+	}
+
+	dstDir := filepath.Join(baseFolder, metadataMigrateDir)
+
+	// Left over from a previous, failed migration:
+	if err := os.RemoveAll(dstDir); err != nil {
+		return nil, err
+	}
+
+	owner := rp.Immutables.Owner()
+	fs, err := rp.FS(owner, bk)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("rehashing metadata of »%s« from %s to %s", owner, oldAlgo, algo)
+	stats, err := fs.Rehash(filepath.Join(dstDir, owner), algo)
+	if closeErr := rp.Close(); closeErr != nil {
+		log.Warnf("failed to close repository: %v", closeErr)
+	}
+
+	if err != nil {
+		if rmErr := os.RemoveAll(dstDir); rmErr != nil {
+			log.Warnf("failed to clean up %s: %v", dstDir, rmErr)
+		}
+
+		return nil, e.Wrapf(err, "failed to rehash metadata of %s", owner)
+	}
+
+	immutablesPath := filepath.Join(baseFolder, "immutable.yml")
+	immutablesData, err := ioutil.ReadFile(immutablesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	immutablesRollbackPath := filepath.Join(baseFolder, immutablesRollbackName)
+	if err := ioutil.WriteFile(immutablesRollbackPath, immutablesData, 0600); err != nil {
+		return nil, err
+	}
+
+	if err := swapMetadata(baseFolder); err != nil {
+		return nil, err
+	}
+
+	if err := rp.Immutables.setHashAlgorithm(algo); err != nil {
+		if rbErr := RollbackDatabaseMigration(baseFolder); rbErr != nil {
+			log.Warnf("failed to roll back: %v", rbErr)
+		}
+
+		return nil, err
+	}
+
+	return stats, h.SetInternalAlgorithm(algo)
+}
@@ -8,8 +8,10 @@ import (
 	"io/ioutil"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/sahib/brig/catfs/vcs"
+	"github.com/sahib/brig/net/bandwidth"
 	"github.com/sahib/brig/net/peer"
 
 	yml "gopkg.in/yaml.v2"
@@ -31,6 +33,11 @@ type Folder struct {
 	// ConflictStrategy for this folder.
 	// This overwrites the strategy per remote.
 	ConflictStrategy string
+
+	// LockPolicy decides what happens to changes of this remote to paths
+	// that are locked by somebody else: "warn", "refuse" or "ignore".
+	// If empty, the config value fs.sync.lock_policy is taken.
+	LockPolicy string
 }
 
 func (f Folder) String() string {
@@ -62,6 +69,54 @@ type Remote struct {
 
 	// AcceptPush will allow this remote to push data to us if true.
 	AcceptPush bool
+
+	// Device is true if the remote is another device of our own user.
+	// Devices can see all folders and may always push to us.
+	Device bool
+
+	// Devices are the fingerprints of other devices of this remote's user.
+	// They are tried in order when Fingerprint can not be reached.
+	Devices []peer.Fingerprint
+
+	// DeviceProof is the encoded DeviceProof of a remote that is one
+	// of our devices. We pass it on to other peers, so they can check
+	// that the device really belongs to our user.
+	DeviceProof string
+
+	// UploadLimit and DownloadLimit override net.bandwidth.upload and
+	// net.bandwidth.download for connections with this remote (e.g. »1MB«,
+	// »0« is unlimited). If both are empty, the global limits apply.
+	UploadLimit   string
+	DownloadLimit string
+}
+
+// Fingerprints returns the fingerprint of the remote,
+// followed by the ones of its other devices.
+func (r Remote) Fingerprints() []peer.Fingerprint {
+	return append([]peer.Fingerprint{r.Fingerprint}, r.Devices...)
+}
+
+// HasPubKeyID returns true if one of the remote's fingerprints
+// has the public key id `id`.
+func (r Remote) HasPubKeyID(id string) bool {
+	for _, fp := range r.Fingerprints() {
+		if fp.PubKeyID() == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasAddr returns true if one of the remote's fingerprints has `addr`.
+func (r Remote) HasAddr(addr string) bool {
+	for _, fp := range r.Fingerprints() {
+		if fp.Addr() == addr {
+			return true
+		}
+	}
+
+	return false
 }
 
 // ReadOnlyFolders returns the folders that are set to read only
@@ -77,6 +132,27 @@ func (r Remote) ReadOnlyFolders() []string {
 	return folders
 }
 
+// CanSee returns true if the remote receives metadata of anything at or
+// below `path`, i.e. if one of its folders overlaps with `path`.
+// A remote without folders sees everything.
+func (r Remote) CanSee(path string) bool {
+	if len(r.Folders) == 0 || path == "/" {
+		return true
+	}
+
+	for _, folder := range r.Folders {
+		if folder.Folder == "/" || folder.Folder == path {
+			return true
+		}
+
+		if strings.HasPrefix(path, folder.Folder+"/") || strings.HasPrefix(folder.Folder, path+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ConflictStrategyPerFolder returns a map of folders (as key)
 // which have a dedicated conflict strategy (as value).
 func (r Remote) ConflictStrategyPerFolder() map[string]string {
@@ -91,6 +167,20 @@ func (r Remote) ConflictStrategyPerFolder() map[string]string {
 	return cspf
 }
 
+// LockPolicyPerFolder returns a map of folders (as key)
+// which have a dedicated lock policy (as value).
+func (r Remote) LockPolicyPerFolder() map[string]string {
+	lppf := make(map[string]string)
+
+	for _, folder := range r.Folders {
+		if folder.LockPolicy != "" {
+			lppf[folder.Folder] = folder.LockPolicy
+		}
+	}
+
+	return lppf
+}
+
 // RemoteList is a helper that parses the remote access yml file
 // and makes it easily accessible from the Go side.
 type RemoteList struct {
@@ -169,6 +259,22 @@ func dedupeFolders(folders []Folder) []Folder {
 	return newFolders
 }
 
+func dedupeDevices(primary peer.Fingerprint, devices []peer.Fingerprint) []peer.Fingerprint {
+	seen := map[string]bool{primary.PubKeyID(): true}
+	var newDevices []peer.Fingerprint
+
+	for _, device := range devices {
+		if seen[device.PubKeyID()] {
+			continue
+		}
+
+		seen[device.PubKeyID()] = true
+		newDevices = append(newDevices, device)
+	}
+
+	return newDevices
+}
+
 // AddOrUpdateRemote will add/update a remote.
 func (rl *RemoteList) AddOrUpdateRemote(remote Remote) error {
 	if remote.ConflictStrategy != "" {
@@ -178,7 +284,35 @@ func (rl *RemoteList) AddOrUpdateRemote(remote Remote) error {
 		}
 	}
 
+	for _, folder := range remote.Folders {
+		if folder.LockPolicy == "" {
+			continue
+		}
+
+		if vcs.LockPolicyFromString(folder.LockPolicy) == vcs.LockPolicyUnknown {
+			return fmt.Errorf("unknown lock policy: %s", folder.LockPolicy)
+		}
+	}
+
+	for _, limit := range []string{remote.UploadLimit, remote.DownloadLimit} {
+		if _, err := bandwidth.ParseRate(limit); err != nil {
+			return fmt.Errorf("bad bandwidth limit »%s«: %v", limit, err)
+		}
+	}
+
+	if remote.Device {
+		// Our own devices always get everything:
+		remote.Folders = nil
+		remote.AcceptPush = true
+	}
+
+	// The proof is not something users edit; do not lose it on updates.
+	if old, ok := rl.remotes[remote.Name]; ok && remote.DeviceProof == "" && old.Fingerprint == remote.Fingerprint {
+		remote.DeviceProof = old.DeviceProof
+	}
+
 	remote.Folders = dedupeFolders(remote.Folders)
+	remote.Devices = dedupeDevices(remote.Fingerprint, remote.Devices)
 	rl.remotes[remote.Name] = &remote
 	return rl.save()
 }
@@ -232,9 +366,14 @@ func (rl *RemoteList) SaveList(remotes []Remote) error {
 	rl.remotes = make(map[string]*Remote)
 	for _, remote := range remotes {
 		rl.remotes[remote.Name] = &Remote{
-			Name:        remote.Name,
-			Fingerprint: remote.Fingerprint,
-			Folders:     remote.Folders,
+			Name:          remote.Name,
+			Fingerprint:   remote.Fingerprint,
+			Folders:       remote.Folders,
+			Device:        remote.Device,
+			Devices:       remote.Devices,
+			DeviceProof:   remote.DeviceProof,
+			UploadLimit:   remote.UploadLimit,
+			DownloadLimit: remote.DownloadLimit,
 		}
 	}
 
@@ -247,11 +386,23 @@ func (rl *RemoteList) SaveList(remotes []Remote) error {
 	return rl.save()
 }
 
-// RemoteByAddr will return a remote with `addr` in its fingerprint, if any.
+// RemoteByAddr will return a remote with `addr` in one of its fingerprints, if any.
 // If none are found ErrNoSuchRemote will be returned as error.
 func (rl *RemoteList) RemoteByAddr(addr string) (Remote, error) {
 	for _, remote := range rl.remotes {
-		if addr == remote.Fingerprint.Addr() {
+		if remote.HasAddr(addr) {
+			return *remote, nil
+		}
+	}
+
+	return Remote{}, ErrNoSuchRemote
+}
+
+// RemoteByPubKeyID returns the remote that has a fingerprint
+// with the public key id `id`, or ErrNoSuchRemote.
+func (rl *RemoteList) RemoteByPubKeyID(id string) (Remote, error) {
+	for _, remote := range rl.remotes {
+		if remote.HasPubKeyID(id) {
 			return *remote, nil
 		}
 	}
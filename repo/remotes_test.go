@@ -100,3 +100,89 @@ func TestRemoteOps(t *testing.T) {
 	require.Equal(t, remotes[0], bobRemote)
 	require.Equal(t, remotes[1], charlieRemote)
 }
+
+func TestRemoteFolderLockPolicy(t *testing.T) {
+	fd, err := ioutil.TempFile("", "brig-test-remotes")
+	require.Nil(t, err)
+
+	defer require.Nil(t, os.Remove(fd.Name()))
+	defer require.Nil(t, fd.Close())
+
+	rl, err := NewRemotes(fd.Name())
+	require.Nil(t, err)
+
+	rmt := Remote{
+		Name:        "dave",
+		Fingerprint: peer.Fingerprint("davesfp"),
+		Folders: []Folder{
+			{Folder: "/design", LockPolicy: "refuse"},
+			{Folder: "/docs"},
+		},
+	}
+
+	require.Nil(t, rl.AddOrUpdateRemote(rmt))
+	require.Equal(t, map[string]string{"/design": "refuse"}, rmt.LockPolicyPerFolder())
+
+	rmt.Folders[1].LockPolicy = "panic"
+	require.NotNil(t, rl.AddOrUpdateRemote(rmt))
+}
+
+func TestRemoteCanSee(t *testing.T) {
+	rmt := Remote{Name: "bob"}
+	require.True(t, rmt.CanSee("/team"))
+
+	rmt.Folders = []Folder{{Folder: "/team/sub"}, {Folder: "/public"}}
+	require.True(t, rmt.CanSee("/"))
+	require.True(t, rmt.CanSee("/team"))
+	require.True(t, rmt.CanSee("/team/sub/x"))
+	require.True(t, rmt.CanSee("/public"))
+	require.False(t, rmt.CanSee("/team/other"))
+	require.False(t, rmt.CanSee("/publicity"))
+	require.False(t, rmt.CanSee("/private"))
+}
+
+func TestRemoteDevices(t *testing.T) {
+	fd, err := ioutil.TempFile("", "brig-test-remotes")
+	require.Nil(t, err)
+
+	defer require.Nil(t, os.Remove(fd.Name()))
+	defer require.Nil(t, fd.Close())
+
+	rl, err := NewRemotes(fd.Name())
+	require.Nil(t, err)
+
+	laptop := peer.Fingerprint("QmLaptop:W1laptop")
+	desktop := peer.Fingerprint("QmDesktop:W1desktop")
+	require.Nil(t, rl.AddOrUpdateRemote(Remote{
+		Name:        "ali",
+		Fingerprint: laptop,
+		Devices:     []peer.Fingerprint{desktop, laptop, desktop},
+	}))
+
+	ali, err := rl.Remote("ali")
+	require.Nil(t, err)
+	require.Equal(t, []peer.Fingerprint{laptop, desktop}, ali.Fingerprints())
+	require.True(t, ali.HasAddr("QmDesktop"))
+	require.False(t, ali.HasAddr("QmPhone"))
+
+	byID, err := rl.RemoteByPubKeyID(desktop.PubKeyID())
+	require.Nil(t, err)
+	require.Equal(t, "ali", byID.Name)
+
+	byAddr, err := rl.RemoteByAddr("QmDesktop")
+	require.Nil(t, err)
+	require.Equal(t, "ali", byAddr.Name)
+
+	// Own devices always see everything and may push:
+	require.Nil(t, rl.AddOrUpdateRemote(Remote{
+		Name:        "ali/desktop",
+		Fingerprint: desktop,
+		Device:      true,
+		Folders:     []Folder{{Folder: "/private"}},
+	}))
+
+	device, err := rl.Remote("ali/desktop")
+	require.Nil(t, err)
+	require.Empty(t, device.Folders)
+	require.True(t, device.AcceptPush)
+}
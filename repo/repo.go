@@ -11,6 +11,7 @@ import (
 	fserr "github.com/sahib/brig/catfs/errors"
 	"github.com/sahib/brig/catfs/mio/pagecache/mdcache"
 	"github.com/sahib/brig/defaults"
+	"github.com/sahib/brig/net/bandwidth"
 	"github.com/sahib/brig/repo/hints"
 	"github.com/sahib/config"
 	log "github.com/sirupsen/logrus"
@@ -23,6 +24,8 @@ import (
 // config.yml
 // immutables.yml
 // remotes.yml
+// invites.yml
+// revocations.yml
 // keyring/
 //    <remote_name>
 //        key.prv
@@ -30,8 +33,13 @@ import (
 // metadata/
 //    <remote_name>
 //        (fs-backend specific)
+// metadata.rollback/
+//    (old metadata after »brig repo migrate-db«, until confirmed)
 // gateway/
 //    (gateway specific)
+// dicts/
+//    index.yml
+//    <dict_id>.dict
 type Repository struct {
 	mu sync.Mutex
 
@@ -51,9 +59,21 @@ type Repository struct {
 	// Remotes gives access to all known remotes
 	Remotes *RemoteList
 
+	// Invites are the pending invites for not yet known remotes.
+	Invites *InviteStore
+
+	// Revocations are the keys we do not talk to anymore.
+	Revocations *RevocationStore
+
 	// Hints are streaming settings
 	Hints *hints.HintManager
 
+	// Dicts are the zstd dictionaries trained for folders.
+	Dicts *DictStore
+
+	// Bandwidth limits and measures content transfers and sync connections.
+	Bandwidth *bandwidth.Manager
+
 	// channel to control the auto gc loop
 	autoGCControl chan bool
 }
@@ -96,17 +116,36 @@ func Open(baseFolder string) (*Repository, error) {
 		return nil, err
 	}
 
+	invites, err := NewInviteStore(filepath.Join(baseFolder, "invites.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	revocations, err := NewRevocationStore(filepath.Join(baseFolder, "revocations.yml"))
+	if err != nil {
+		return nil, err
+	}
+
 	hintsMgr, err := loadHintManager(filepath.Join(baseFolder, "hints.yml"))
 	if err != nil {
 		return nil, err
 	}
 
+	dicts, err := NewDictStore(filepath.Join(baseFolder, "dicts"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Repository{
 		BaseFolder:    baseFolder,
 		Immutables:    immutables,
 		Config:        cfg,
 		Remotes:       remotes,
+		Invites:       invites,
+		Revocations:   revocations,
 		Hints:         hintsMgr,
+		Dicts:         dicts,
+		Bandwidth:     newBandwidthManager(cfg, remotes),
 		fsMap:         make(map[string]*catfs.FS),
 		autoGCControl: make(chan bool, 1),
 	}, nil
@@ -136,6 +175,33 @@ func (rp *Repository) HaveFS(owner string) bool {
 	return true
 }
 
+// ResetFS deletes all metadata we have of `owner`, so that it will be
+// fetched from scratch. Our own metadata can not be reset.
+func (rp *Repository) ResetFS(owner string) error {
+	if owner == rp.Immutables.Owner() {
+		return e.New("refusing to reset own metadata")
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if fs, ok := rp.fsMap[owner]; ok {
+		if err := fs.Close(); err != nil {
+			return err
+		}
+
+		delete(rp.fsMap, owner)
+	}
+
+	for _, dir := range []string{"metadata", "pages"} {
+		if err := os.RemoveAll(filepath.Join(rp.BaseFolder, dir, owner)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // FS returns a filesystem for `owner`. If there is none yet,
 // it will create own associated to the respective owner.
 func (rp *Repository) FS(owner string, bk catfs.FsBackend) (*catfs.FS, error) {
@@ -186,6 +252,7 @@ func (rp *Repository) FS(owner string, bk catfs.FsBackend) (*catfs.FS, error) {
 		isReadOnly,
 		fsCfg,
 		rp.Hints,
+		rp.Dicts,
 		pageCache,
 	)
 
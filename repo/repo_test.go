@@ -8,6 +8,9 @@ import (
 	"testing"
 
 	"github.com/sahib/brig/backend/mock"
+	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/db"
+	h "github.com/sahib/brig/util/hashlib"
 	"github.com/stretchr/testify/require"
 )
 
@@ -67,3 +70,188 @@ func dirSize(t *testing.T, path string) int64 {
 
 	return size
 }
+
+func TestRepoMigrateDatabases(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "brig-repo-migrate-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	require.NoError(t, Init(InitOptions{
+		BaseFolder:  testDir,
+		Owner:       "alice",
+		BackendName: "mock",
+		DaemonURL:   "yadda-yadda",
+	}))
+
+	bk := mock.NewMockBackend("", "")
+	withFS := func(fn func(fs *catfs.FS)) {
+		rp, err := Open(testDir)
+		require.NoError(t, err)
+
+		fs, err := rp.FS(rp.CurrentUser(), bk)
+		require.NoError(t, err)
+
+		fn(fs)
+
+		require.NoError(t, fs.Close())
+		require.NoError(t, rp.Close())
+	}
+
+	withFS(func(fs *catfs.FS) {
+		require.NoError(t, fs.Stage("/x", bytes.NewReader([]byte{1, 2, 3})))
+	})
+
+	stats, err := MigrateDatabases(testDir, db.TypeDisk)
+	require.NoError(t, err)
+	require.Equal(t, db.TypeBadger, stats["alice"].SrcType)
+	require.Equal(t, db.TypeDisk, stats["alice"].DstType)
+
+	// A second migration has to wait until the first is confirmed:
+	_, err = MigrateDatabases(testDir, db.TypeBadger)
+	require.Error(t, err)
+
+	withFS(func(fs *catfs.FS) {
+		_, err := fs.Stat("/x")
+		require.NoError(t, err)
+		require.NoError(t, fs.Stage("/y", bytes.NewReader([]byte{4, 5, 6})))
+	})
+
+	// Rolling back should lose the change above:
+	require.NoError(t, RollbackDatabaseMigration(testDir))
+	withFS(func(fs *catfs.FS) {
+		_, err := fs.Stat("/y")
+		require.Error(t, err)
+	})
+
+	_, err = MigrateDatabases(testDir, db.TypeDisk)
+	require.NoError(t, err)
+	require.NoError(t, ConfirmDatabaseMigration(testDir))
+
+	haveRollback, err := HaveDatabaseRollback(testDir)
+	require.NoError(t, err)
+	require.False(t, haveRollback)
+
+	withFS(func(fs *catfs.FS) {
+		_, err := fs.Stat("/x")
+		require.NoError(t, err)
+	})
+}
+
+func TestRepoRehash(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "brig-repo-rehash-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	defer h.SetInternalAlgorithm(h.DefaultAlgorithm)
+
+	require.NoError(t, Init(InitOptions{
+		BaseFolder:  testDir,
+		Owner:       "alice",
+		BackendName: "mock",
+		DaemonURL:   "yadda-yadda",
+	}))
+
+	bk := mock.NewMockBackend("", "")
+	withRepo := func(fn func(rp *Repository, fs *catfs.FS)) {
+		rp, err := Open(testDir)
+		require.NoError(t, err)
+		require.NoError(t, h.SetInternalAlgorithm(rp.Immutables.HashAlgorithm()))
+
+		fs, err := rp.FS(rp.CurrentUser(), bk)
+		require.NoError(t, err)
+
+		fn(rp, fs)
+
+		require.NoError(t, fs.Close())
+		require.NoError(t, rp.Close())
+	}
+
+	var oldContent h.Hash
+	withRepo(func(rp *Repository, fs *catfs.FS) {
+		require.Equal(t, h.DefaultAlgorithm, rp.Immutables.HashAlgorithm())
+		require.NoError(t, fs.Stage("/x", bytes.NewReader([]byte{1, 2, 3})))
+		require.NoError(t, fs.MakeCommit("add x"))
+
+		info, err := fs.Stat("/x")
+		require.NoError(t, err)
+		oldContent = info.ContentHash
+	})
+
+	rp, err := Open(testDir)
+	require.NoError(t, err)
+
+	// Rehashing to the same algorithm makes no sense:
+	_, err = rp.Rehash(bk, h.DefaultAlgorithm)
+	require.Error(t, err)
+
+	stats, err := rp.Rehash(bk, h.AlgoSHA3)
+	require.NoError(t, err)
+	require.Equal(t, "sha3-256", stats.DstAlgorithm)
+
+	withRepo(func(rp *Repository, fs *catfs.FS) {
+		require.Equal(t, h.AlgoSHA3, rp.Immutables.HashAlgorithm())
+
+		info, err := fs.Stat("/x")
+		require.NoError(t, err)
+		require.Equal(t, h.SumWith(h.AlgoSHA3, []byte{1, 2, 3}), info.ContentHash)
+	})
+
+	// Rolling back restores the old algorithm as well:
+	require.NoError(t, RollbackDatabaseMigration(testDir))
+	withRepo(func(rp *Repository, fs *catfs.FS) {
+		require.Equal(t, h.DefaultAlgorithm, rp.Immutables.HashAlgorithm())
+
+		info, err := fs.Stat("/x")
+		require.NoError(t, err)
+		require.Equal(t, oldContent, info.ContentHash)
+	})
+
+	rp, err = Open(testDir)
+	require.NoError(t, err)
+
+	_, err = rp.Rehash(bk, h.AlgoSHA3)
+	require.NoError(t, err)
+	require.NoError(t, ConfirmDatabaseMigration(testDir))
+
+	_, err = os.Stat(filepath.Join(testDir, immutablesRollbackName))
+	require.True(t, os.IsNotExist(err))
+
+	withRepo(func(rp *Repository, fs *catfs.FS) {
+		require.Equal(t, h.AlgoSHA3, rp.Immutables.HashAlgorithm())
+	})
+}
+
+func TestRepoResetFS(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "brig-repo-reset-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	require.NoError(t, Init(InitOptions{
+		BaseFolder:  testDir,
+		Owner:       "alice",
+		BackendName: "mock",
+		DaemonURL:   "yadda-yadda",
+	}))
+
+	rp, err := Open(testDir)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	bk := mock.NewMockBackend("", "")
+	fs, err := rp.FS("bob", bk)
+	require.NoError(t, err)
+	require.NoError(t, fs.SetFetchSource("W1laptop"))
+	require.True(t, rp.HaveFS("bob"))
+
+	require.NoError(t, rp.ResetFS("bob"))
+	require.False(t, rp.HaveFS("bob"))
+
+	// A new, empty one is created on the next access:
+	fs, err = rp.FS("bob", bk)
+	require.NoError(t, err)
+	source, err := fs.FetchSource()
+	require.NoError(t, err)
+	require.Equal(t, "", source)
+
+	require.Error(t, rp.ResetFS("alice"))
+}
@@ -0,0 +1,431 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/net/peer"
+	log "github.com/sirupsen/logrus"
+	yml "gopkg.in/yaml.v2"
+)
+
+// Revocation is a signed statement that the key behind a fingerprint
+// may not be used anymore. If Successor is set, the key was rotated
+// and the successor replaces it. Rotations are only valid if they were
+// signed by the rotated key itself.
+type Revocation struct {
+	// Revoked is the fingerprint that was revoked.
+	Revoked peer.Fingerprint `json:"revoked"`
+
+	// Successor is the new fingerprint of a rotated key.
+	Successor peer.Fingerprint `json:"successor,omitempty"`
+
+	// Reason is a human readable explanation.
+	Reason string `json:"reason,omitempty"`
+
+	// Created is the time the revocation was made.
+	Created time.Time `json:"created"`
+
+	// Issuer is the fingerprint of who made the revocation.
+	Issuer peer.Fingerprint `json:"issuer"`
+
+	// IssuerPubKey is the public key of the issuer. It is part of the
+	// revocation, so peers that never met the issuer can check it.
+	IssuerPubKey []byte `json:"issuer_pubkey"`
+
+	// payload and signature are set once signed or decoded.
+	payload   []byte
+	signature []byte
+}
+
+// Sign signs the revocation with our own key in `kr`.
+// The issuer's public key is set to ours.
+func (rv *Revocation) Sign(kr *Keyring) error {
+	pubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return err
+	}
+
+	if !rv.Issuer.PubKeyMatches(pubKey) {
+		return fmt.Errorf("issuer of revocation is not the owner of the keyring")
+	}
+
+	rv.IssuerPubKey = pubKey
+	payload, err := json.Marshal(rv)
+	if err != nil {
+		return err
+	}
+
+	signature, err := kr.Sign(payload)
+	if err != nil {
+		return err
+	}
+
+	rv.payload = payload
+	rv.signature = signature
+	return nil
+}
+
+// Encode returns the signed revocation as text, suitable for storing
+// and sending it to other peers.
+func (rv *Revocation) Encode() (string, error) {
+	if rv.payload == nil {
+		return "", fmt.Errorf("revocation is not signed")
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(rv.payload) + "." + enc.EncodeToString(rv.signature), nil
+}
+
+// DecodeRevocation parses and checks a revocation produced by Encode.
+// An error is returned if the signature is not valid.
+func DecodeRevocation(data string) (*Revocation, error) {
+	parts := strings.Split(data, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("bad revocation: expected two parts, got %d", len(parts))
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, e.Wrap(err, "bad revocation")
+	}
+
+	signature, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, e.Wrap(err, "bad revocation signature")
+	}
+
+	rv := &Revocation{}
+	if err := json.Unmarshal(payload, rv); err != nil {
+		return nil, e.Wrap(err, "bad revocation")
+	}
+
+	if _, err := peer.CastFingerprint(string(rv.Revoked)); err != nil {
+		return nil, err
+	}
+
+	if _, err := peer.CastFingerprint(string(rv.Issuer)); err != nil {
+		return nil, err
+	}
+
+	if rv.Successor != "" {
+		if _, err := peer.CastFingerprint(string(rv.Successor)); err != nil {
+			return nil, err
+		}
+
+		if !rv.IsSelfSigned() {
+			return nil, fmt.Errorf("only the owner of a key may rotate it")
+		}
+	}
+
+	if !rv.Issuer.PubKeyMatches(rv.IssuerPubKey) {
+		return nil, fmt.Errorf("public key of revocation does not match its issuer")
+	}
+
+	if err := VerifySignature(payload, signature, rv.IssuerPubKey); err != nil {
+		return nil, e.Wrap(err, "bad signature of revocation")
+	}
+
+	rv.payload = payload
+	rv.signature = signature
+	return rv, nil
+}
+
+// IsSelfSigned returns true if the revoked key revoked itself.
+func (rv *Revocation) IsSelfSigned() bool {
+	return rv.Issuer.PubKeyID() == rv.Revoked.PubKeyID()
+}
+
+// IsRotation returns true if the revoked key has a successor.
+func (rv *Revocation) IsRotation() bool {
+	return rv.Successor != ""
+}
+
+// RevocationStore remembers all revocations that we trust.
+// Revocations are never removed again.
+type RevocationStore struct {
+	mu      sync.Mutex
+	path    string
+	encoded map[string]bool
+	revs    []*Revocation
+}
+
+// NewRevocationStore opens (or creates) the revocation store at `path`.
+func NewRevocationStore(path string) (*RevocationStore, error) {
+	data, err := ioutil.ReadFile(path) // #nosec
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	encoded := []string{}
+	if err := yml.Unmarshal(data, &encoded); err != nil {
+		return nil, e.Wrap(err, "failed to parse revocations")
+	}
+
+	rs := &RevocationStore{
+		path:    path,
+		encoded: make(map[string]bool),
+	}
+
+	for _, enc := range encoded {
+		rv, err := DecodeRevocation(enc)
+		if err != nil {
+			// Somebody messed with the file; do not trust this one.
+			log.Warningf("ignoring stored revocation: %v", err)
+			continue
+		}
+
+		rs.encoded[enc] = true
+		rs.revs = append(rs.revs, rv)
+	}
+
+	return rs, nil
+}
+
+func (rs *RevocationStore) save() error {
+	encoded := []string{}
+	for _, rv := range rs.revs {
+		enc, err := rv.Encode()
+		if err != nil {
+			return err
+		}
+
+		encoded = append(encoded, enc)
+	}
+
+	data, err := yml.Marshal(encoded)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(rs.path, data, 0600)
+}
+
+// Add stores `rv`. It returns false if we knew it already.
+// Use Repository.AddRevocations to check if `rv` should be trusted.
+func (rs *RevocationStore) Add(rv *Revocation) (bool, error) {
+	enc, err := rv.Encode()
+	if err != nil {
+		return false, err
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.encoded[enc] {
+		return false, nil
+	}
+
+	rs.encoded[enc] = true
+	rs.revs = append(rs.revs, rv)
+	return true, rs.save()
+}
+
+// List returns all stored revocations, oldest first.
+func (rs *RevocationStore) List() []*Revocation {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return append([]*Revocation{}, rs.revs...)
+}
+
+// IsRevoked returns true if the key `pubKey` was revoked or rotated.
+func (rs *RevocationStore) IsRevoked(pubKey []byte) bool {
+	fp := peer.BuildFingerprint("", pubKey)
+	_, revoked := rs.Resolve(fp)
+	return revoked
+}
+
+func (rs *RevocationStore) resolveOnce(fp peer.Fingerprint) (peer.Fingerprint, bool) {
+	revoked := false
+	successors := make(map[peer.Fingerprint]bool)
+	for _, rv := range rs.revs {
+		if rv.Revoked.PubKeyID() != fp.PubKeyID() {
+			continue
+		}
+
+		revoked = true
+		if !rv.IsRotation() {
+			// A plain revocation always wins over rotations:
+			return "", true
+		}
+
+		successors[rv.Successor] = true
+	}
+
+	if len(successors) != 1 {
+		// Several successors mean that somebody else had the key
+		// and rotated it too. We can't tell who's right.
+		return "", revoked
+	}
+
+	for successor := range successors {
+		return successor, true
+	}
+
+	return "", revoked
+}
+
+// Resolve checks if `fp` was revoked. If it was rotated (maybe several
+// times) to a key that is still valid, the latest successor is returned.
+func (rs *RevocationStore) Resolve(fp peer.Fingerprint) (peer.Fingerprint, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	successor, revoked := rs.resolveOnce(fp)
+	if !revoked {
+		return "", false
+	}
+
+	// Follow the chain of rotations. It can't be longer than the
+	// number of revocations, so this also protects against cycles.
+	for idx := 0; successor != "" && idx < len(rs.revs); idx++ {
+		next, nextRevoked := rs.resolveOnce(successor)
+		if !nextRevoked {
+			return successor, true
+		}
+
+		successor = next
+	}
+
+	return "", true
+}
+
+// AddRevocations stores the revocations of `revs` that we trust
+// and updates the remote list: remotes with a revoked key are removed,
+// remotes with a rotated key get the key's successor.
+//
+// Revocations are trusted when they were made by the revoked key itself,
+// by us, or by one of our remotes (if net.accept_remote_revocations is set).
+// The revocations that were new to us are returned.
+func (rp *Repository) AddRevocations(revs []*Revocation) ([]*Revocation, error) {
+	kr, err := rp.Keyring()
+	if err != nil {
+		return nil, err
+	}
+
+	ownPubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	ownID := peer.BuildFingerprint("", ownPubKey).PubKeyID()
+	acceptFromRemotes := rp.Config.Bool("net.accept_remote_revocations")
+
+	added := []*Revocation{}
+	for _, rv := range revs {
+		if !rp.isTrustedRevocation(rv, ownID, acceptFromRemotes) {
+			log.Warningf("ignoring revocation of %s by %s: issuer is not trusted", rv.Revoked, rv.Issuer)
+			continue
+		}
+
+		isNew, err := rp.Revocations.Add(rv)
+		if err != nil {
+			return nil, err
+		}
+
+		if isNew {
+			log.Infof("key of %s was revoked by %s (reason: %s)", rv.Revoked, rv.Issuer, rv.Reason)
+			added = append(added, rv)
+		}
+	}
+
+	if len(added) == 0 {
+		return added, nil
+	}
+
+	return added, rp.applyRevocations()
+}
+
+func (rp *Repository) isTrustedRevocation(rv *Revocation, ownID string, acceptFromRemotes bool) bool {
+	if rv.IsSelfSigned() || rv.Issuer.PubKeyID() == ownID {
+		return true
+	}
+
+	// Nobody else can revoke our own key for us:
+	if rv.Revoked.PubKeyID() == ownID || !acceptFromRemotes {
+		return false
+	}
+
+	if _, revoked := rp.Revocations.Resolve(rv.Issuer); revoked {
+		return false
+	}
+
+	remotes, err := rp.Remotes.ListRemotes()
+	if err != nil {
+		return false
+	}
+
+	for _, remote := range remotes {
+		if remote.HasPubKeyID(rv.Issuer.PubKeyID()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResolveFingerprints replaces rotated fingerprints in `fps` by their
+// successors and drops revoked ones. It returns true if anything changed.
+func (rp *Repository) ResolveFingerprints(fps []peer.Fingerprint) ([]peer.Fingerprint, bool) {
+	changed := false
+	resolved := []peer.Fingerprint{}
+	for _, fp := range fps {
+		successor, revoked := rp.Revocations.Resolve(fp)
+		if !revoked {
+			resolved = append(resolved, fp)
+			continue
+		}
+
+		changed = true
+		if successor != "" {
+			resolved = append(resolved, successor)
+		}
+	}
+
+	return resolved, changed
+}
+
+func (rp *Repository) applyRevocations() error {
+	remotes, err := rp.Remotes.ListRemotes()
+	if err != nil {
+		return err
+	}
+
+	for _, remote := range remotes {
+		fps, changed := rp.ResolveFingerprints(remote.Fingerprints())
+		if !changed {
+			continue
+		}
+
+		if len(fps) == 0 {
+			log.Warningf("removing remote %s, since its key was revoked", remote.Name)
+			if err := rp.Remotes.RmRemote(remote.Name); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// If the first device was revoked, the next one takes its place:
+		if fps[0] != remote.Fingerprint {
+			log.Infof("remote %s changed its key; new fingerprint is %s", remote.Name, fps[0])
+		}
+
+		remote.Fingerprint = fps[0]
+		remote.Devices = fps[1:]
+		if err := rp.Remotes.AddOrUpdateRemote(remote); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package repo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sahib/brig/net/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type testKey struct {
+	kr *Keyring
+	fp peer.Fingerprint
+}
+
+func withTestKeys(t *testing.T, names []string, fn func(keys map[string]testKey)) {
+	testDir, err := ioutil.TempDir("", "brig-test-revocations")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	keys := make(map[string]testKey)
+	for _, name := range names {
+		require.NoError(t, createKeyPair(name, testDir, 1024))
+		kr := newKeyringHandle(testDir, name)
+		pubKey, err := kr.OwnPubKey()
+		require.NoError(t, err)
+
+		keys[name] = testKey{kr: kr, fp: peer.BuildFingerprint("Qm"+name, pubKey)}
+	}
+
+	fn(keys)
+}
+
+func mustRevocation(t *testing.T, issuer testKey, revoked, successor peer.Fingerprint) *Revocation {
+	rv := &Revocation{
+		Revoked:   revoked,
+		Successor: successor,
+		Reason:    "test",
+		Created:   time.Now().UTC(),
+		Issuer:    issuer.fp,
+	}
+
+	require.NoError(t, rv.Sign(issuer.kr))
+
+	enc, err := rv.Encode()
+	require.NoError(t, err)
+
+	decoded, err := DecodeRevocation(enc)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestRevocationEncoding(t *testing.T) {
+	withTestKeys(t, []string{"ali", "bob"}, func(keys map[string]testKey) {
+		ali, bob := keys["ali"], keys["bob"]
+
+		rv := mustRevocation(t, ali, bob.fp, "")
+		require.Equal(t, bob.fp, rv.Revoked)
+		require.Equal(t, ali.fp, rv.Issuer)
+		require.False(t, rv.IsSelfSigned())
+		require.False(t, rv.IsRotation())
+
+		// Can't sign in the name of somebody else:
+		forged := &Revocation{Revoked: bob.fp, Issuer: bob.fp}
+		require.Error(t, forged.Sign(ali.kr))
+
+		// Unsigned revocations can't be encoded:
+		_, err := forged.Encode()
+		require.Error(t, err)
+
+		// Tampered payloads are detected:
+		enc, err := rv.Encode()
+		require.NoError(t, err)
+		other, err := mustRevocation(t, ali, ali.fp, "").Encode()
+		require.NoError(t, err)
+
+		_, err = DecodeRevocation(strings.Split(other, ".")[0] + "." + strings.Split(enc, ".")[1])
+		require.Error(t, err)
+
+		// Only the owner of a key may rotate it:
+		rotation := &Revocation{Revoked: bob.fp, Successor: ali.fp, Issuer: ali.fp}
+		require.NoError(t, rotation.Sign(ali.kr))
+		enc, err = rotation.Encode()
+		require.NoError(t, err)
+		_, err = DecodeRevocation(enc)
+		require.Error(t, err)
+	})
+}
+
+func TestRevocationStoreResolve(t *testing.T) {
+	withTestKeys(t, []string{"a", "b", "c", "d"}, func(keys map[string]testKey) {
+		dir, err := ioutil.TempDir("", "brig-test-revocation-store")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "revocations.yml")
+		rs, err := NewRevocationStore(path)
+		require.NoError(t, err)
+
+		a, b, c, d := keys["a"], keys["b"], keys["c"], keys["d"]
+		_, revoked := rs.Resolve(a.fp)
+		require.False(t, revoked)
+
+		// a -> b -> c:
+		for _, rv := range []*Revocation{
+			mustRevocation(t, a, a.fp, b.fp),
+			mustRevocation(t, b, b.fp, c.fp),
+		} {
+			isNew, err := rs.Add(rv)
+			require.NoError(t, err)
+			require.True(t, isNew)
+		}
+
+		isNew, err := rs.Add(rs.List()[0])
+		require.NoError(t, err)
+		require.False(t, isNew)
+
+		successor, revoked := rs.Resolve(a.fp)
+		require.True(t, revoked)
+		require.Equal(t, c.fp, successor)
+
+		aPubKey, err := a.kr.OwnPubKey()
+		require.NoError(t, err)
+		require.True(t, rs.IsRevoked(aPubKey))
+
+		cPubKey, err := c.kr.OwnPubKey()
+		require.NoError(t, err)
+		require.False(t, rs.IsRevoked(cPubKey))
+
+		// Survives a reload:
+		rs, err = NewRevocationStore(path)
+		require.NoError(t, err)
+		require.Len(t, rs.List(), 2)
+
+		// A second rotation of b by somebody that also has b's key:
+		_, err = rs.Add(mustRevocation(t, b, b.fp, d.fp))
+		require.NoError(t, err)
+		successor, revoked = rs.Resolve(a.fp)
+		require.True(t, revoked)
+		require.Equal(t, peer.Fingerprint(""), successor)
+
+		// Plain revocations win over rotations:
+		_, err = rs.Add(mustRevocation(t, d, c.fp, ""))
+		require.NoError(t, err)
+		successor, revoked = rs.Resolve(c.fp)
+		require.True(t, revoked)
+		require.Equal(t, peer.Fingerprint(""), successor)
+	})
+}
+
+func TestRepoAddRevocations(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "brig-test-repo-revocations")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	require.NoError(t, Init(InitOptions{
+		BaseFolder:  testDir,
+		Owner:       "ali",
+		BackendName: "mock",
+		DaemonURL:   "yadda-yadda",
+	}))
+
+	rp, err := Open(testDir)
+	require.NoError(t, err)
+	defer rp.Close()
+
+	withTestKeys(t, []string{"bob", "bob-new", "charlie", "mallory"}, func(keys map[string]testKey) {
+		bob, bobNew, charlie, mallory := keys["bob"], keys["bob-new"], keys["charlie"], keys["mallory"]
+		for _, name := range []string{"bob", "charlie"} {
+			require.NoError(t, rp.Remotes.AddOrUpdateRemote(Remote{
+				Name:        name,
+				Fingerprint: keys[name].fp,
+				Folders:     []Folder{{Folder: "/shared"}},
+			}))
+		}
+
+		// Mallory is not a remote of us, so we don't trust her:
+		added, err := rp.AddRevocations([]*Revocation{mustRevocation(t, mallory, charlie.fp, "")})
+		require.NoError(t, err)
+		require.Len(t, added, 0)
+
+		// Bob rotates his key:
+		added, err = rp.AddRevocations([]*Revocation{mustRevocation(t, bob, bob.fp, bobNew.fp)})
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+
+		bobRemote, err := rp.Remotes.Remote("bob")
+		require.NoError(t, err)
+		require.Equal(t, bobNew.fp, bobRemote.Fingerprint)
+		require.Equal(t, []Folder{{Folder: "/shared"}}, bobRemote.Folders)
+
+		// Nobody can revoke our own key, except us:
+		kr, err := rp.Keyring()
+		require.NoError(t, err)
+		ownPubKey, err := kr.OwnPubKey()
+		require.NoError(t, err)
+
+		ownFp := peer.BuildFingerprint("QmAli", ownPubKey)
+		added, err = rp.AddRevocations([]*Revocation{mustRevocation(t, bobNew, ownFp, "")})
+		require.NoError(t, err)
+		require.Len(t, added, 0)
+
+		// Bob (with his new key) tells us that charlie lost his device:
+		added, err = rp.AddRevocations([]*Revocation{mustRevocation(t, bobNew, charlie.fp, "")})
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+
+		_, err = rp.Remotes.Remote("charlie")
+		require.Equal(t, ErrNoSuchRemote, err)
+
+		// Revoking one device of a remote keeps the others:
+		require.NoError(t, rp.Remotes.AddOrUpdateRemote(Remote{
+			Name:        "dave",
+			Fingerprint: keys["mallory"].fp,
+			Devices:     []peer.Fingerprint{bob.fp},
+		}))
+
+		added, err = rp.AddRevocations([]*Revocation{mustRevocation(t, mallory, mallory.fp, "")})
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+
+		daveRemote, err := rp.Remotes.Remote("dave")
+		require.NoError(t, err)
+		require.Equal(t, bobNew.fp, daveRemote.Fingerprint)
+		require.Empty(t, daveRemote.Devices)
+
+		// Known revocations are not reported twice:
+		added, err = rp.AddRevocations(rp.Revocations.List())
+		require.NoError(t, err)
+		require.Len(t, added, 0)
+	})
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/sahib/brig/server/capnp"
+	"github.com/sahib/config"
+	log "github.com/sirupsen/logrus"
+	capnplib "zombiezen.com/go/capnproto2"
+	capnpserver "zombiezen.com/go/capnproto2/server"
+)
+
+// accessClass decides which API methods a client may call.
+type accessClass int
+
+const (
+	// accessDenied clients may not talk to the daemon at all.
+	accessDenied accessClass = iota
+	// accessReadOnly clients may only call methods in readOnlyMethods.
+	accessReadOnly
+	// accessFull clients may call every method.
+	accessFull
+)
+
+func (ac accessClass) String() string {
+	switch ac {
+	case accessReadOnly:
+		return "read-only"
+	case accessFull:
+		return "full"
+	default:
+		return "denied"
+	}
+}
+
+var errPeerCredUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// unknownUID is the uid of clients whose user can not be told, like
+// clients connecting over tcp. Everyone that can connect has full access then.
+const unknownUID = -1
+
+// readOnlyMethods are all methods that clients with read-only access may
+// call. They may not modify the repository, the daemon or its config.
+var readOnlyMethods = map[string]bool{
+	"FS.list":             true,
+	"FS.cat":              true,
+	"FS.stat":             true,
+	"FS.exists":           true,
+	"FS.tar":              true,
+	"FS.deletedNodes":     true,
+	"FS.isCached":         true,
+	"FS.prefetchStatus":   true,
+	"FS.locks":            true,
+	"FS.getAttr":          true,
+	"FS.compressionStats": true,
+	"FS.rekeyStatus":      true,
+
+	"VCS.log":        true,
+	"VCS.history":    true,
+	"VCS.makeDiff":   true,
+	"VCS.commitInfo": true,
+
+	"Repo.ping":            true,
+	"Repo.configGet":       true,
+	"Repo.configAll":       true,
+	"Repo.configDoc":       true,
+	"Repo.fstabList":       true,
+	"Repo.version":         true,
+	"Repo.gatewayUserList": true,
+	"Repo.hintList":        true,
+	"Repo.selectRepo":      true,
+	"Repo.daemonRepos":     true,
+	"Repo.jobList":         true,
+
+	"Net.remoteLs":         true,
+	"Net.remotePing":       true,
+	"Net.netLocate":        true,
+	"Net.netLocateNext":    true,
+	"Net.whoami":           true,
+	"Net.remoteOnlineList": true,
+	"Net.remoteByName":     true,
+	"Net.revocationList":   true,
+	"Net.bandwidthStats":   true,
+}
+
+func methodKey(method capnplib.Method) string {
+	ifaceName := method.InterfaceName
+	if idx := strings.LastIndex(ifaceName, ":"); idx >= 0 {
+		ifaceName = ifaceName[idx+1:]
+	}
+
+	return ifaceName + "." + method.MethodName
+}
+
+// restrictMethods replaces the implementation of all methods
+// that `access` may not call by one that returns an error.
+func restrictMethods(methods []capnpserver.Method, access accessClass) []capnpserver.Method {
+	if access == accessFull {
+		return methods
+	}
+
+	for idx := range methods {
+		method := &methods[idx]
+		key := methodKey(method.Method)
+		if access == accessReadOnly && readOnlyMethods[key] {
+			continue
+		}
+
+		method.Impl = func(ctx context.Context, opts capnplib.CallOptions, params, results capnplib.Struct) error {
+			return fmt.Errorf("permission denied: %s needs full access to the daemon", key)
+		}
+	}
+
+	return methods
+}
+
+// newAPIClient returns the API of `b` that clients with `access` may use.
+// `uid` is the user of the client; it is needed when it selects another repo.
+func newAPIClient(b *base, uid int, access accessClass) capnp.API {
+	handler := newAPIHandler(b, uid, access)
+	methods := restrictMethods(capnp.API_Methods(nil, handler), access)
+	return capnp.API{Client: capnpserver.New(methods, nil)}
+}
+
+func containsUID(uids []int64, uid int) bool {
+	for _, allowedUID := range uids {
+		if allowedUID == int64(uid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// accessForUID decides what the user with `uid` may do, given the daemon
+// config section `cfg` of the repository it wants to use. The user that
+// runs the daemon and root may do all.
+func accessForUID(uid int, cfg *config.Config) accessClass {
+	if uid == unknownUID || uid == os.Getuid() || uid == 0 {
+		return accessFull
+	}
+
+	if containsUID(cfg.Ints("allowed_uids"), uid) {
+		return accessFull
+	}
+
+	if containsUID(cfg.Ints("readonly_uids"), uid) {
+		return accessReadOnly
+	}
+
+	return accessDenied
+}
+
+// accessForConn checks the credentials of the process on the other side of
+// `conn` and returns its uid together with its access. This is only possible
+// for unix sockets; over tcp we cannot tell who is connecting and everyone
+// gets full access.
+func accessForConn(conn net.Conn, cfg *config.Config) (int, accessClass) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return unknownUID, accessFull
+	}
+
+	uid, err := peerUID(unixConn)
+	if err == errPeerCredUnsupported {
+		return unknownUID, accessFull
+	}
+
+	if err != nil {
+		log.Warningf("failed to get credentials of local client: %v", err)
+		return unknownUID, accessDenied
+	}
+
+	access := accessForUID(uid, cfg)
+	log.Debugf("local client with uid %d has %s access", uid, access)
+	return uid, access
+}
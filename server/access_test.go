@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sahib/brig/defaults"
+	"github.com/sahib/brig/server/capnp"
+	"github.com/sahib/config"
+	"github.com/stretchr/testify/require"
+	capnplib "zombiezen.com/go/capnproto2"
+)
+
+func TestAccessForUID(t *testing.T) {
+	cfg, err := config.Open(nil, defaults.Defaults, config.StrictnessPanic)
+	require.NoError(t, err)
+
+	daemonCfg := cfg.Section("daemon")
+	require.Equal(t, accessFull, accessForUID(os.Getuid(), daemonCfg))
+	require.Equal(t, accessFull, accessForUID(0, daemonCfg))
+	require.Equal(t, accessDenied, accessForUID(4242, daemonCfg))
+
+	// Clients over tcp can not be told apart:
+	require.Equal(t, accessFull, accessForUID(unknownUID, daemonCfg))
+
+	require.NoError(t, daemonCfg.SetInts("allowed_uids", []int64{4242}))
+	require.NoError(t, daemonCfg.SetInts("readonly_uids", []int64{2323}))
+	require.Equal(t, accessFull, accessForUID(4242, daemonCfg))
+	require.Equal(t, accessReadOnly, accessForUID(2323, daemonCfg))
+	require.Equal(t, accessDenied, accessForUID(1111, daemonCfg))
+}
+
+func TestAccessRestrictMethods(t *testing.T) {
+	handler := newAPIHandler(nil, unknownUID, accessReadOnly)
+	methods := restrictMethods(capnp.API_Methods(nil, handler), accessReadOnly)
+
+	seen := map[string]bool{}
+	for _, method := range methods {
+		key := methodKey(method.Method)
+		seen[key] = true
+		if readOnlyMethods[key] {
+			continue
+		}
+
+		// Those would crash without a base if they were not replaced:
+		err := method.Impl(
+			context.Background(),
+			capnplib.CallOptions{},
+			capnplib.Struct{},
+			capnplib.Struct{},
+		)
+		require.Error(t, err, key)
+	}
+
+	// Make sure there are no typos in readOnlyMethods:
+	for key := range readOnlyMethods {
+		require.True(t, seen[key], key)
+	}
+
+	require.False(t, readOnlyMethods["Repo.configSet"])
+	require.False(t, readOnlyMethods["FS.stage"])
+}
+
+func TestProtectSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brig-socket-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "brig.socket")
+	lst, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer lst.Close()
+
+	cfg, err := config.Open(nil, defaults.Defaults, config.StrictnessPanic)
+	require.NoError(t, err)
+
+	require.NoError(t, protectSocket("unix:"+socketPath, cfg.Section("daemon")))
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Abstract sockets have no file:
+	require.NoError(t, protectSocket("unix:/tmp/brig-test.socket?abstract=true", cfg.Section("daemon")))
+}
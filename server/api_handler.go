@@ -7,9 +7,9 @@ type apiHandler struct {
 	netHandler
 }
 
-func newAPIHandler(base *base) *apiHandler {
+func newAPIHandler(base *base, uid int, access accessClass) *apiHandler {
 	return &apiHandler{
-		repoHandler: repoHandler{base},
+		repoHandler: repoHandler{base: base, uid: uid, access: access},
 		netHandler:  netHandler{base},
 		vcsHandler:  vcsHandler{base},
 		fsHandler:   fsHandler{base},
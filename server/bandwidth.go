@@ -0,0 +1,91 @@
+package server
+
+import (
+	"io"
+
+	"github.com/sahib/brig/backend"
+	"github.com/sahib/brig/catfs/mio"
+	"github.com/sahib/brig/net/bandwidth"
+	"github.com/sahib/brig/server/capnp"
+	h "github.com/sahib/brig/util/hashlib"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// limitedBackend applies the global download limit to content that is not
+// cached yet, since getting it means fetching it from other peers.
+// Adding content only stores it locally and is not limited. Content that
+// other peers fetch from us is served by IPFS directly and does not pass
+// through here; the upload limit only covers the sync connections.
+type limitedBackend struct {
+	backend.Backend
+	bw *bandwidth.Manager
+}
+
+type limitedStream struct {
+	mio.Stream
+	r io.Reader
+}
+
+func (ls *limitedStream) Read(buf []byte) (int, error) {
+	return ls.r.Read(buf)
+}
+
+func (ls *limitedStream) WriteTo(w io.Writer) (int64, error) {
+	// Hide our WriteTo from io.Copy, it would recurse otherwise.
+	return io.Copy(w, struct{ io.Reader }{ls.r})
+}
+
+func (lb *limitedBackend) Cat(hash h.Hash) (mio.Stream, error) {
+	stream, err := lb.Backend.Cat(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Local content does not cross the network; do not slow it down.
+	if isCached, err := lb.Backend.IsCached(hash); err == nil && isCached {
+		return stream, nil
+	}
+
+	return &limitedStream{Stream: stream, r: lb.bw.DownloadReader(stream)}, nil
+}
+
+func statsToCapStats(stats bandwidth.Stats, capStats capnp.BandwidthStats) error {
+	if err := capStats.SetName(stats.Name); err != nil {
+		return err
+	}
+
+	capStats.SetUploadLimit(stats.Limits.Upload)
+	capStats.SetDownloadLimit(stats.Limits.Download)
+	capStats.SetUpload(stats.Upload)
+	capStats.SetDownload(stats.Download)
+	capStats.SetUploadTotal(stats.UploadTotal)
+	capStats.SetDownloadTotal(stats.DownloadTotal)
+	return nil
+}
+
+func (nh *netHandler) BandwidthStats(call capnp.Net_bandwidthStats) error {
+	server.Ack(call.Options)
+
+	global, remotes := nh.base.repo.Bandwidth.Stats()
+	capGlobal, err := call.Results.NewGlobal()
+	if err != nil {
+		return err
+	}
+
+	if err := statsToCapStats(global, capGlobal); err != nil {
+		return err
+	}
+
+	capRemotes, err := call.Results.NewRemotes(int32(len(remotes)))
+	if err != nil {
+		return err
+	}
+
+	for idx, stats := range remotes {
+		if err := statsToCapStats(stats, capRemotes.At(idx)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -12,8 +12,6 @@ import (
 	"sync"
 	"time"
 
-	"zombiezen.com/go/capnproto2/rpc"
-
 	// For loadProfileServer
 	_ "net/http/pprof"
 
@@ -27,8 +25,8 @@ import (
 	p2pnet "github.com/sahib/brig/net"
 	"github.com/sahib/brig/net/peer"
 	"github.com/sahib/brig/repo"
-	"github.com/sahib/brig/server/capnp"
 	"github.com/sahib/brig/util/conductor"
+	h "github.com/sahib/brig/util/hashlib"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -38,6 +36,12 @@ type base struct {
 	// base path to the repository (i.e. BRIG_PATH)
 	basePath string
 
+	// name is used by clients to select this repository
+	name string
+
+	// repos are all repositories served by the daemon (including this one)
+	repos *repoSet
+
 	ctx context.Context
 
 	repo       *repo.Repository
@@ -64,6 +68,18 @@ type base struct {
 
 	// pprofPort is the port pprof can acquire profiling from
 	pprofPort int
+
+	// rekeyState is the progress of the current (or last) rekey
+	rekeyState rekeyState
+
+	// jobs are the long running operations that are currently in progress
+	jobs jobTracker
+
+	// stopJobEvents stops sending job updates to the gateway
+	stopJobEvents func()
+
+	// keyMu is held while our own key is rotated
+	keyMu sync.Mutex
 }
 
 func repoIsInitialized(path string) error {
@@ -79,31 +95,6 @@ func repoIsInitialized(path string) error {
 	return nil
 }
 
-// Handle is being called by the base server implementation
-// for every local request that is being served to the brig daemon.
-func (b *base) Handle(ctx context.Context, conn net.Conn) {
-	transport := rpc.StreamTransport(conn)
-	srv := capnp.API_ServerToClient(newAPIHandler(b))
-	rpcConn := rpc.NewConn(
-		transport,
-		rpc.MainInterface(srv.Client),
-		rpc.ConnLog(nil),
-		rpc.SendBufferSize(128),
-	)
-
-	if err := rpcConn.Wait(); err != nil {
-		log.Warnf("serving rpc failed: %v", err)
-	}
-
-	if err := rpcConn.Close(); err != nil {
-		// Close seems to be complaining that the conn was
-		// already closed, but be safe and expect this.
-		if err != rpc.ErrConnClosed {
-			log.Warnf("failed to close rpc conn: %v", err)
-		}
-	}
-}
-
 /////////
 
 func (b *base) loadRepo() error {
@@ -118,6 +109,20 @@ func (b *base) loadRepo() error {
 
 	b.repo = rp
 
+	// Hashes are computed by package level functions; therefore all
+	// repositories of a daemon need to use the same algorithm.
+	hashAlgo := rp.Immutables.HashAlgorithm()
+	if b.repos.isDefault(b) {
+		if err := h.SetInternalAlgorithm(hashAlgo); err != nil {
+			return err
+		}
+	} else if internalAlgo := h.InternalAlgorithm(); hashAlgo != internalAlgo {
+		return fmt.Errorf(
+			"repository `%s` uses the hash algorithm %s, but the daemon uses %s",
+			b.name, hashAlgo, internalAlgo,
+		)
+	}
+
 	// Adjust the backend's logging output here, since this should be done
 	// before actually loading the backend (which might produce logs already)
 	backendName := rp.Immutables.Backend()
@@ -190,7 +195,11 @@ func (b *base) loadBackend() error {
 		return err
 	}
 
-	b.backend = realBackend
+	b.backend = &limitedBackend{
+		Backend: realBackend,
+		bw:      b.repo.Bandwidth,
+	}
+
 	b.repo.StartAutoGCLoop(realBackend)
 	return nil
 }
@@ -204,6 +213,10 @@ func (b *base) loadPeerServer() error {
 		return err
 	}
 
+	srv.OnNewRevocations(func(from string) {
+		go b.onNewRevocations(from)
+	})
+
 	go func() {
 		if err := srv.Serve(); err != nil {
 			log.Warningf("PeerServer.Serve() returned with error: %v", err)
@@ -240,6 +253,7 @@ func (b *base) loadPeerServer() error {
 	)
 
 	b.evListener.RegisterEventHandler(events.FsEvent, false, b.handleFsEvent)
+	b.evListener.RegisterEventHandler(events.LockEvent, false, b.handleLockEvent)
 	if err := b.evListener.SetupListeners(b.evListenerCtx, addrs); err != nil {
 		log.Warningf("failed to setup event listeners: %v", err)
 	}
@@ -252,6 +266,14 @@ func (b *base) loadPeerServer() error {
 		log.Warningf("initial sync failed with one or more peers: %v", err)
 	}
 
+	// Tell other users when we take or release a lock:
+	ownFs, err := b.repo.FS(b.repo.Immutables.Owner(), b.backend)
+	if err != nil {
+		return err
+	}
+
+	ownFs.OnLockChange(b.notifyLockChangeEvent)
+
 	// Now that we boooted up, we should tell other users that our fs changed.
 	// It may or may not have, but other remotes judge that.
 	b.notifyFsChangeEvent()
@@ -279,6 +301,7 @@ func (b *base) loadGateway() error {
 
 		b.gateway = gateway
 		b.gateway.Start()
+		b.stopJobEvents = b.jobs.OnChange(b.notifyJobs)
 		return nil
 	})
 }
@@ -385,6 +408,10 @@ func (b *base) withNetClient(who string, fn func(ctl *p2pnet.Client) error) erro
 func (b *base) Quit() (err error) {
 	log.Info("shutting down brigd due to QUIT command")
 
+	if b.stopJobEvents != nil {
+		b.stopJobEvents()
+	}
+
 	if err := b.gateway.Stop(); err != nil {
 		log.Warningf("could not close gateway: %v", err)
 	}
@@ -424,11 +451,15 @@ func (b *base) Quit() (err error) {
 func newBase(
 	ctx context.Context,
 	basePath string,
+	name string,
+	repos *repoSet,
 	quitCh chan struct{},
 ) *base {
 	return &base{
 		ctx:       ctx,
 		basePath:  basePath,
+		name:      name,
+		repos:     repos,
 		quitCh:    quitCh,
 		conductor: conductor.New(5*time.Minute, 100),
 	}
@@ -442,38 +473,85 @@ func (b *base) doFetch(who string) error {
 	}
 
 	return b.withNetClient(who, func(ctl *p2pnet.Client) error {
-		return b.withRemoteFs(who, func(remoteFs *catfs.FS) error {
-			// Not all remotes might allow doing a full fetch.
-			// This is only possible when having full access to all folders.
-			if isAllowed, err := ctl.IsCompleteFetchAllowed(); isAllowed && err != nil {
-				log.Debugf("fetch: doing complete fetch for %s", who)
-				storeBuf, err := ctl.FetchStore()
-				if err != nil {
-					return e.Wrapf(err, "fetch-store")
-				}
-
-				return e.Wrapf(remoteFs.Import(storeBuf), "import")
-			}
+		// The remote might have answered from another device than last time:
+		source := fetchSource(ctl)
+		remoteFs, err := b.remoteFsForSource(who, source)
+		if err != nil {
+			return err
+		}
 
-			// Ask our local copy of the remote what the last patch index was.
-			fromIndex, err := remoteFs.LastPatchIndex()
-			if err != nil {
-				return err
-			}
+		// Learn about other devices of the remote (or its remotes if it's ours):
+		b.doFetchIdentity(ctl, who)
 
-			// Get the missing changes since then:
-			log.Infof("fetch: doing partial fetch for %s starting at %d", who, fromIndex)
-			patches, err := ctl.FetchPatches(fromIndex)
-			if err != nil {
-				return err
-			}
+		if err := b.doFetchMetadata(ctl, who, remoteFs); err != nil {
+			return err
+		}
 
-			return remoteFs.ApplyPatches(patches)
-		})
+		return remoteFs.SetFetchSource(source)
 	})
 }
 
+func (b *base) doFetchMetadata(ctl *p2pnet.Client, who string, remoteFs *catfs.FS) error {
+	// Not all remotes might allow doing a full fetch.
+	// This is only possible when having full access to all folders.
+	if isAllowed, err := ctl.IsCompleteFetchAllowed(); isAllowed && err != nil {
+		log.Debugf("fetch: doing complete fetch for %s", who)
+		storeBuf, err := ctl.FetchStore()
+		if err != nil {
+			return e.Wrapf(err, "fetch-store")
+		}
+
+		if err := b.doFetchLocks(ctl, who); err != nil {
+			log.Warningf("fetch: failed to fetch locks of %s: %v", who, err)
+		}
+
+		if err := b.doFetchDicts(ctl); err != nil {
+			log.Warningf("fetch: failed to fetch dictionaries of %s: %v", who, err)
+		}
+
+		if err := b.doExchangeRevocations(ctl, who); err != nil {
+			log.Warningf("fetch: failed to exchange revocations with %s: %v", who, err)
+		}
+
+		return e.Wrapf(remoteFs.Import(storeBuf), "import")
+	}
+
+	// Locks are not part of the metadata, so get them separately:
+	if err := b.doFetchLocks(ctl, who); err != nil {
+		// Might be an older version that does not know about locks.
+		log.Warningf("fetch: failed to fetch locks of %s: %v", who, err)
+	}
+
+	// Without the dictionaries we could not read some files of the remote:
+	if err := b.doFetchDicts(ctl); err != nil {
+		log.Warningf("fetch: failed to fetch dictionaries of %s: %v", who, err)
+	}
+
+	// Every fetch also spreads revocations a bit further:
+	if err := b.doExchangeRevocations(ctl, who); err != nil {
+		log.Warningf("fetch: failed to exchange revocations with %s: %v", who, err)
+	}
+
+	// Ask our local copy of the remote what the last patch index was.
+	fromIndex, err := remoteFs.LastPatchIndex()
+	if err != nil {
+		return err
+	}
+
+	// Get the missing changes since then:
+	log.Infof("fetch: doing partial fetch for %s starting at %d", who, fromIndex)
+	patches, err := ctl.FetchPatches(fromIndex)
+	if err != nil {
+		return err
+	}
+
+	return remoteFs.ApplyPatches(patches)
+}
+
 func (b *base) doSync(withWhom string, needFetch bool, msg string) (*catfs.Diff, error) {
+	job := b.jobs.start("sync", withWhom)
+	defer job.finish()
+
 	if needFetch {
 		if err := b.doFetch(withWhom); err != nil {
 			return nil, e.Wrapf(err, "fetch")
@@ -509,6 +587,14 @@ func (b *base) doSync(withWhom string, needFetch bool, msg string) (*catfs.Diff,
 				catfs.SyncOptConflictStrategy(rmt.ConflictStrategy),
 				catfs.SyncOptReadOnlyFolders(rmt.ReadOnlyFolders()),
 				catfs.SyncOptConflictgStrategyPerFolder(rmt.ConflictStrategyPerFolder()),
+				catfs.SyncOptLockPolicy("", rmt.LockPolicyPerFolder()),
+				catfs.SyncOptProgress(func(nodePath string, done, total int) {
+					job.update(func(job *Job) {
+						job.TotalFiles = int64(total)
+						job.DoneFiles = int64(done)
+						job.Current = nodePath
+					})
+				}),
 			)
 
 			if err != nil {
@@ -547,6 +633,74 @@ func (b *base) handleFsEvent(ev *events.Event) {
 	}
 }
 
+// doFetchLocks stores the locks of `who` in our own filesystem,
+// so they can be shown to the user and checked during sync.
+func (b *base) doFetchLocks(ctl *p2pnet.Client, who string) error {
+	locks, err := ctl.FetchLocks()
+	if err != nil {
+		return err
+	}
+
+	ownFs, err := b.repo.FS(b.repo.Immutables.Owner(), b.backend)
+	if err != nil {
+		return err
+	}
+
+	return ownFs.SetRemoteLocks(who, locks)
+}
+
+// doFetchDicts stores all compression dictionaries of the remote
+// that we do not know yet.
+func (b *base) doFetchDicts(ctl *p2pnet.Client) error {
+	dicts, err := ctl.FetchDicts()
+	if err != nil {
+		return err
+	}
+
+	for _, remoteDict := range dicts {
+		if _, err := b.repo.Dicts.Dict(remoteDict.Dict.ID); err == nil {
+			continue
+		}
+
+		if err := b.repo.Dicts.Add(remoteDict.Folder, remoteDict.Dict); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *base) handleLockEvent(ev *events.Event) {
+	rmt, err := b.repo.Remotes.RemoteByAddr(ev.Source)
+	if err != nil {
+		log.Debugf("failed to resolve '%s' to a known remote name: %v", ev.Source, err)
+		return
+	}
+
+	log.Debugf("fetching locks of »%s« since we received a lock notification.", rmt.Name)
+	err = b.withNetClient(rmt.Name, func(ctl *p2pnet.Client) error {
+		return b.doFetchLocks(ctl, rmt.Name)
+	})
+
+	if err != nil {
+		log.Warningf("failed to fetch locks of %s: %v", rmt.Name, err)
+	}
+}
+
+func (b *base) notifyLockChangeEvent() {
+	if b.evListener == nil {
+		return
+	}
+
+	ev := events.Event{
+		Type: events.LockEvent,
+	}
+
+	if err := b.evListener.PublishEvent(ev); err != nil {
+		log.Warningf("failed to publish lock change event: %v", err)
+	}
+}
+
 func (b *base) notifyFsChangeEvent() {
 	if b.evListener == nil {
 		return
@@ -595,7 +749,9 @@ func (b *base) syncRemoteStates() error {
 	}
 
 	for _, remote := range remotes {
-		addrs = append(addrs, remote.Fingerprint.Addr())
+		for _, fp := range remote.Fingerprints() {
+			addrs = append(addrs, fp.Addr())
+		}
 	}
 
 	pmap := b.peerServer.PingMap()
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"time"
+
+	"github.com/sahib/brig/catfs"
+	p2pnet "github.com/sahib/brig/net"
+	"github.com/sahib/brig/net/peer"
+	"github.com/sahib/brig/repo"
+	"github.com/sahib/brig/server/capnp"
+	log "github.com/sirupsen/logrus"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// fetchSource returns the id of the device behind `ctl`.
+func fetchSource(ctl *p2pnet.Client) string {
+	return peer.BuildFingerprint("", ctl.RemotePubKey()).PubKeyID()
+}
+
+// remoteFsForSource returns our copy of the metadata of `who`.
+// If it was fetched from another device of `who` before, it is reset,
+// since patch indices of different devices have nothing in common.
+func (b *base) remoteFsForSource(who, source string) (*catfs.FS, error) {
+	remoteFs, err := b.repo.FS(who, b.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	lastSource, err := remoteFs.FetchSource()
+	if err != nil {
+		return nil, err
+	}
+
+	if lastSource == "" || lastSource == source {
+		return remoteFs, nil
+	}
+
+	log.Infof("fetch: %s answered from another device; fetching from scratch", who)
+	if err := b.repo.ResetFS(who); err != nil {
+		return nil, err
+	}
+
+	return b.repo.FS(who, b.backend)
+}
+
+// doFetchDevices updates the devices of `who`, so we can
+// try them when the device we know is not reachable.
+// Only devices that proved to belong to the user of `who` are kept.
+func (b *base) doFetchDevices(ctl *p2pnet.Client, who string) error {
+	proofs, err := ctl.Devices()
+	if err != nil {
+		return err
+	}
+
+	remote, err := b.repo.Remotes.Remote(who)
+	if err != nil {
+		return err
+	}
+
+	self, devices, err := b.repo.VerifyDevices(who, ctl.RemotePubKey(), proofs)
+	if err != nil {
+		return err
+	}
+
+	// Remember the proof of our own devices, so we can pass it on:
+	selfProof := remote.DeviceProof
+	if remote.Device && remote.Fingerprint.PubKeyMatches(self.PubKey) {
+		if selfProof, err = self.Encode(); err != nil {
+			return err
+		}
+	}
+
+	if fingerprintsEqual(remote.Devices, devices) && selfProof == remote.DeviceProof {
+		return nil
+	}
+
+	log.Infof("fetch: devices of %s changed to %v", who, devices)
+	remote.Devices = devices
+	remote.DeviceProof = selfProof
+	if err := b.repo.Remotes.AddOrUpdateRemote(remote); err != nil {
+		return err
+	}
+
+	return b.syncRemoteStates()
+}
+
+func fingerprintsEqual(a, b []peer.Fingerprint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// doFetchDeviceRemotes adds the remotes of our device `who` that we
+// do not know yet. This way, all devices of a user know the same peers.
+// Removed remotes are not synced; use revocations for that.
+func (b *base) doFetchDeviceRemotes(ctl *p2pnet.Client, who string) error {
+	remotes, err := ctl.DeviceRemotes()
+	if err != nil {
+		return err
+	}
+
+	ownFp, err := b.ownFingerprint()
+	if err != nil {
+		return err
+	}
+
+	owner := peer.Name(b.repo.Immutables.Owner())
+	added := 0
+	for _, remote := range remotes {
+		if remote.HasPubKeyID(ownFp.PubKeyID()) || string(owner) == remote.Name {
+			continue
+		}
+
+		if remote.Device && !owner.SameUser(peer.Name(remote.Name)) {
+			log.Warningf("%s claims that %s is one of our devices; ignoring", who, remote.Name)
+			continue
+		}
+
+		if remote.Device {
+			if err := b.repo.CheckDeviceProof(remote.DeviceProof, remote.Fingerprint); err != nil {
+				log.Debugf("not adding device %s yet: %v", remote.Name, err)
+				continue
+			}
+		}
+
+		if _, err := b.repo.Remotes.RemoteByPubKeyID(remote.Fingerprint.PubKeyID()); err == nil {
+			continue
+		}
+
+		if _, err := b.repo.Remotes.Remote(remote.Name); err == nil {
+			log.Warningf("%s knows another remote named %s; not adding it", who, remote.Name)
+			continue
+		}
+
+		if _, revoked := b.repo.Revocations.Resolve(remote.Fingerprint); revoked {
+			continue
+		}
+
+		log.Infof("adding remote %s, since our device %s knows it", remote.Name, who)
+		if err := b.repo.Remotes.AddOrUpdateRemote(remote); err != nil {
+			return err
+		}
+
+		added++
+	}
+
+	if added == 0 {
+		return nil
+	}
+
+	return b.syncRemoteStates()
+}
+
+// doFetchIdentity updates what we know about the user behind `who`.
+func (b *base) doFetchIdentity(ctl *p2pnet.Client, who string) {
+	if err := b.doFetchDevices(ctl, who); err != nil {
+		log.Warningf("fetch: failed to fetch devices of %s: %v", who, err)
+	}
+
+	remote, err := b.repo.Remotes.Remote(who)
+	if err != nil || !remote.Device {
+		return
+	}
+
+	if err := b.doFetchDeviceRemotes(ctl, who); err != nil {
+		log.Warningf("fetch: failed to fetch remotes of our device %s: %v", who, err)
+	}
+}
+
+func (nh *netHandler) DeviceInvite(call capnp.Net_deviceInvite) error {
+	server.Ack(call.Options)
+
+	fingerprint, err := nh.base.ownFingerprint()
+	if err != nil {
+		return err
+	}
+
+	rp := nh.base.repo
+	kr, err := rp.Keyring()
+	if err != nil {
+		return err
+	}
+
+	validFor := time.Duration(call.Params.ValidSec()) * time.Second
+	invite, err := rp.Invites.CreateForDevice(validFor)
+	if err != nil {
+		return err
+	}
+
+	token, err := repo.NewInviteToken(rp.Immutables.Owner(), fingerprint, invite).Encode(kr)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("created device invite %s (valid until %s)", invite.ID, invite.Expires)
+	return call.Results.SetToken(token)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/mio/compress"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Files bigger than this are not worth a dictionary and only
+	// their beginning is used as sample.
+	maxDictSampleSize = 128 * 1024
+
+	// zstd recommends about 100 times the dictionary size as samples.
+	dictSamplesFactor = 100
+)
+
+// collectDictSamples reads the content of the files below `root`,
+// which are used to train a dictionary of `maxSize` bytes.
+func collectDictSamples(fs *catfs.FS, root string, maxSize int) ([][]byte, error) {
+	infos, err := fs.List(root, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := [][]byte{}
+	totalSize, maxTotalSize := 0, dictSamplesFactor*maxSize
+
+	for _, info := range infos {
+		if info.IsDir || info.Size == 0 {
+			continue
+		}
+
+		if totalSize >= maxTotalSize {
+			break
+		}
+
+		stream, err := fs.Cat(info.Path)
+		if err != nil {
+			// Files that are not available right now are just skipped.
+			log.Warnf("failed to read %s for dictionary: %v", info.Path, err)
+			continue
+		}
+
+		data, err := ioutil.ReadAll(io.LimitReader(stream, maxDictSampleSize))
+		stream.Close()
+
+		if err != nil {
+			log.Warnf("failed to read %s for dictionary: %v", info.Path, err)
+			continue
+		}
+
+		samples = append(samples, data)
+		totalSize += len(data)
+	}
+
+	return samples, nil
+}
+
+// trainDict trains a zstd dictionary on the files below `root`
+// and stores it in the repository.
+func (b *base) trainDict(fs *catfs.FS, root string, maxSize int) (*compress.Dict, error) {
+	samples, err := collectDictSamples(fs, root, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(samples) == 0 {
+		return nil, e.Errorf("no files to train a dictionary below %s", root)
+	}
+
+	dict, err := compress.TrainDict(samples, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.repo.Dicts.Add(root, dict); err != nil {
+		return nil, e.Wrap(err, "failed to store dictionary")
+	}
+
+	log.Infof(
+		"trained dictionary %s (%d bytes) on %d files below %s",
+		dict.ID, len(dict.Data), len(samples), root,
+	)
+
+	return dict, nil
+}
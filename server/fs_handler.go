@@ -5,7 +5,8 @@ import (
 	"io"
 	"net"
 	"os"
-	"runtime"
+	"sort"
+	"sync"
 	"time"
 
 	e "github.com/pkg/errors"
@@ -70,6 +71,14 @@ func statToCapnp(fs *catfs.FS, info *catfs.StatInfo, seg *capnplib.Segment) (*ca
 		return nil, err
 	}
 
+	if err := attrsToCapnp(seg, capInfo, info.Attrs); err != nil {
+		return nil, err
+	}
+
+	if err := capInfo.SetCompression(info.Compression); err != nil {
+		return nil, err
+	}
+
 	capInfo.SetSize(info.Size)
 	capInfo.SetCachedSize(info.CachedSize)
 	capInfo.SetInode(info.Inode)
@@ -81,6 +90,37 @@ func statToCapnp(fs *catfs.FS, info *catfs.StatInfo, seg *capnplib.Segment) (*ca
 	return &capInfo, nil
 }
 
+func attrsToCapnp(seg *capnplib.Segment, capInfo capnp.StatInfo, attrs map[string][]byte) error {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	capAttrs, err := capInfo.NewAttrs(int32(len(keys)))
+	if err != nil {
+		return err
+	}
+
+	for idx, key := range keys {
+		capAttr := capAttrs.At(idx)
+		if err := capAttr.SetKey(key); err != nil {
+			return err
+		}
+
+		if err := capAttr.SetValue(attrs[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 ////////////////////////////////////
 // ACTUAL HANDLER IMPLEMENTATIONS //
 ////////////////////////////////////
@@ -156,6 +196,96 @@ func (fh *fsHandler) Stage(call capnp.FS_stage) error {
 	})
 }
 
+func (fh *fsHandler) StageBatch(call capnp.FS_stageBatch) error {
+	server.Ack(call.Options)
+
+	capEntries, err := call.Params.Entries()
+	if err != nil {
+		return err
+	}
+
+	if capEntries.Len() == 0 {
+		return nil
+	}
+
+	firstRepoPath := ""
+	firstURL := &URL{}
+	entries := []catfs.StageEntry{}
+	for idx := 0; idx < capEntries.Len(); idx++ {
+		capEntry := capEntries.At(idx)
+		repoPath, err := capEntry.RepoPath()
+		if err != nil {
+			return err
+		}
+
+		localPath, err := capEntry.LocalPath()
+		if err != nil {
+			return err
+		}
+
+		url, err := parsePath(repoPath)
+		if err != nil {
+			return err
+		}
+
+		if idx == 0 {
+			firstRepoPath, firstURL = repoPath, url
+		} else if url.User != firstURL.User {
+			return fmt.Errorf("all files of a batch need to be staged for the same user")
+		}
+
+		entries = append(entries, catfs.StageEntry{
+			Path: url.Path,
+			Open: func() (io.ReadCloser, error) {
+				return os.Open(localPath) // #nosec
+			},
+		})
+	}
+
+	return fh.base.withFsFromPath(firstRepoPath, func(url *URL, fs *catfs.FS) error {
+		mu := sync.Mutex{}
+		failures := map[string]string{}
+		err := fs.StageBatch(entries, func(path string, err error) {
+			if err == nil {
+				return
+			}
+
+			mu.Lock()
+			failures[path] = err.Error()
+			mu.Unlock()
+		})
+
+		// Failures of single files are reported to the client:
+		if err != nil && e.Cause(err) != catfs.ErrStageIncomplete {
+			return err
+		}
+
+		fh.base.notifyFsChangeEvent()
+
+		seg := call.Results.Segment()
+		capFailures, err := capnp.NewStageFailure_List(seg, int32(len(failures)))
+		if err != nil {
+			return err
+		}
+
+		idx := 0
+		for path, msg := range failures {
+			capFailure := capFailures.At(idx)
+			if err := capFailure.SetRepoPath(path); err != nil {
+				return err
+			}
+
+			if err := capFailure.SetError(msg); err != nil {
+				return err
+			}
+
+			idx++
+		}
+
+		return call.Results.SetFailures(capFailures)
+	})
+}
+
 ///////////////
 
 func (fh *fsHandler) StageFromStream(call capnp.FS_stageFromStream) error {
@@ -206,8 +336,25 @@ func (fh *fsHandler) Cat(call capnp.FS_cat) error {
 			defer stream.Close()
 			localAddr := conn.LocalAddr().String()
 
+			// Only cats that take a while (usually because the content
+			// needs to be fetched) are worth showing as job:
+			job := fh.base.jobs.startLazy("cat", path, func(job *Job) {
+				info, err := fs.Stat(url.Path)
+				if err != nil {
+					log.Debugf("failed to stat %s for cat job: %v", path, err)
+					return
+				}
+
+				job.TotalFiles = 1
+				job.TotalBytes = info.Size
+				job.Current = url.Path
+			})
+			defer job.finish()
+
+			r := &jobReader{r: stream, jh: job}
+
 			start := time.Now()
-			n, err := io.Copy(conn, stream)
+			n, err := io.Copy(conn, r)
 			if err != nil {
 				log.Warningf("I/O failed for path %s on %s: %v", path, localAddr, err)
 				return
@@ -373,7 +520,18 @@ func (fh *fsHandler) Pin(call capnp.FS_pin) error {
 	}
 
 	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
-		return fs.Pin(url.Path, "curr", true)
+		job := fh.base.jobs.start("pin", path)
+		defer job.finish()
+
+		return fs.PinWithProgress(url.Path, "curr", true, func(status catfs.PinProgress) {
+			job.update(func(job *Job) {
+				job.TotalFiles = status.TotalFiles
+				job.DoneFiles = status.DoneFiles
+				job.TotalBytes = status.TotalBytes
+				job.DoneBytes = status.DoneBytes
+				job.Current = status.Current
+			})
+		})
 	})
 }
 
@@ -575,6 +733,45 @@ func (fh *fsHandler) DeletedNodes(call capnp.FS_deletedNodes) error {
 	})
 }
 
+func (fh *fsHandler) PurgeTrash(call capnp.FS_purgeTrash) error {
+	server.Ack(call.Options)
+
+	root, err := call.Params.Root()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withCurrFs(func(fs *catfs.FS) error {
+		olderThan := time.Duration(call.Params.OlderThanNs())
+		nodes, err := fs.PurgeTrash(root, olderThan)
+		if err != nil {
+			return err
+		}
+
+		lst, err := capnp.NewStatInfo_List(
+			call.Results.Segment(),
+			int32(len(nodes)),
+		)
+
+		if err != nil {
+			return err
+		}
+
+		for idx, node := range nodes {
+			capEntry, err := statToCapnp(fs, node, call.Results.Segment())
+			if err != nil {
+				return err
+			}
+
+			if err := lst.Set(idx, *capEntry); err != nil {
+				return err
+			}
+		}
+
+		return call.Results.SetNodes(lst)
+	})
+}
+
 func (fh *fsHandler) IsCached(call capnp.FS_isCached) error {
 	server.Ack(call.Options)
 
@@ -594,7 +791,9 @@ func (fh *fsHandler) IsCached(call capnp.FS_isCached) error {
 	})
 }
 
-func recodeStream(fs *catfs.FS, path string) error {
+// recodeStream stages the file at `path` again with the current hints.
+// If `rekey` is true, a new encryption key is used for the file.
+func recodeStream(fs *catfs.FS, path string, rekey bool) error {
 	stream, err := fs.Cat(path)
 	if err != nil {
 		return err
@@ -602,6 +801,10 @@ func recodeStream(fs *catfs.FS, path string) error {
 
 	defer stream.Close()
 
+	if rekey {
+		return fs.StageWithNewKey(path, stream)
+	}
+
 	return fs.Stage(path, stream)
 }
 
@@ -620,40 +823,358 @@ func (fh *fsHandler) RecodeStream(call capnp.FS_recodeStream) error {
 			return e.Wrap(err, "failed to list")
 		}
 
-		// channel serves as token giver to limit parallel workload:
-		sem := make(chan error, runtime.NumCPU()*2)
-		for idx := 0; idx < cap(sem); idx++ {
-			sem <- nil
+		paths := []string{}
+		for _, child := range children {
+			if !child.IsDir {
+				paths = append(paths, child.Path)
+			}
 		}
 
-		for _, child := range children {
-			if child.IsDir {
-				continue
+		return recodeFiles(fs, paths, false, func(path string, err error) error {
+			if err != nil {
+				log.WithError(err).Warnf("failed to recode %s", path)
+			}
+
+			return err
+		})
+	})
+}
+
+func rekeyStatusToCapnp(seg *capnplib.Segment, status RekeyStatus) (*capnp.RekeyStatus, error) {
+	capStatus, err := capnp.NewRekeyStatus(seg)
+	if err != nil {
+		return nil, err
+	}
+
+	capStatus.SetRunning(status.Running)
+	capStatus.SetTotalFiles(status.TotalFiles)
+	capStatus.SetDoneFiles(status.DoneFiles)
+	capStatus.SetFailedFiles(status.FailedFiles)
+
+	if err := capStatus.SetCurrent(status.Current); err != nil {
+		return nil, err
+	}
+
+	if err := capStatus.SetLastError(status.LastError); err != nil {
+		return nil, err
+	}
+
+	capFolders, err := capnplib.NewTextList(seg, int32(len(status.Folders)))
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, folder := range status.Folders {
+		if err := capFolders.Set(idx, folder); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := capStatus.SetFolders(capFolders); err != nil {
+		return nil, err
+	}
+
+	capRemotes, err := capnplib.NewTextList(seg, int32(len(status.Remotes)))
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, remote := range status.Remotes {
+		if err := capRemotes.Set(idx, remote); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := capStatus.SetRemotes(capRemotes); err != nil {
+		return nil, err
+	}
+
+	return &capStatus, nil
+}
+
+func (fh *fsHandler) Rekey(call capnp.FS_rekey) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withCurrFs(func(fs *catfs.FS) error {
+		if err := fh.base.rekey(fs, path); err != nil {
+			return err
+		}
+
+		capStatus, err := rekeyStatusToCapnp(call.Results.Segment(), fh.base.rekeyState.Status())
+		if err != nil {
+			return err
+		}
+
+		return call.Results.SetStatus(*capStatus)
+	})
+}
+
+func (fh *fsHandler) RekeyStatus(call capnp.FS_rekeyStatus) error {
+	server.Ack(call.Options)
+
+	capStatus, err := rekeyStatusToCapnp(call.Results.Segment(), fh.base.rekeyState.Status())
+	if err != nil {
+		return err
+	}
+
+	return call.Results.SetStatus(*capStatus)
+}
+
+func (fh *fsHandler) CompressionStats(call capnp.FS_compressionStats) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
+		stats, err := fs.CompressionStats(url.Path)
+		if err != nil {
+			return err
+		}
+
+		seg := call.Results.Segment()
+		capStats, err := capnp.NewCompressionStats(seg)
+		if err != nil {
+			return err
+		}
+
+		capStats.SetFiles(int64(stats.Files))
+		capStats.SetSize(stats.Size)
+		capStats.SetStoredSize(stats.StoredSize)
+
+		compressions := []string{}
+		for compression := range stats.ByCompression {
+			compressions = append(compressions, compression)
+		}
+
+		sort.Strings(compressions)
+
+		capCounts, err := capStats.NewByCompression(int32(len(compressions)))
+		if err != nil {
+			return err
+		}
+
+		for idx, compression := range compressions {
+			capCount := capCounts.At(idx)
+			if err := capCount.SetCompression(compression); err != nil {
+				return err
 			}
 
-			// Get next token:
-			if err := <-sem; err != nil {
+			capCount.SetFiles(int64(stats.ByCompression[compression]))
+		}
+
+		return call.Results.SetStats(capStats)
+	})
+}
+
+func (fh *fsHandler) SetOffline(call capnp.FS_setOffline) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	enable := call.Params.Enable()
+	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
+		return fs.SetOffline(url.Path, enable)
+	})
+}
+
+func (fh *fsHandler) PrefetchStatus(call capnp.FS_prefetchStatus) error {
+	server.Ack(call.Options)
+
+	return fh.base.withCurrFs(func(fs *catfs.FS) error {
+		paths, err := fs.OfflinePaths()
+		if err != nil {
+			return err
+		}
+
+		seg := call.Results.Segment()
+		capStatus, err := capnp.NewPrefetchStatus(seg)
+		if err != nil {
+			return err
+		}
+
+		status := fs.PrefetchStatus()
+		capStatus.SetRunning(status.Running)
+		capStatus.SetTotalFiles(status.TotalFiles)
+		capStatus.SetDoneFiles(status.DoneFiles)
+		capStatus.SetTotalBytes(status.TotalBytes)
+		capStatus.SetDoneBytes(status.DoneBytes)
+
+		if err := capStatus.SetCurrent(status.Current); err != nil {
+			return err
+		}
+
+		if err := capStatus.SetLastError(status.LastError); err != nil {
+			return err
+		}
+
+		capPaths, err := capnplib.NewTextList(seg, int32(len(paths)))
+		if err != nil {
+			return err
+		}
+
+		for idx, path := range paths {
+			if err := capPaths.Set(idx, path); err != nil {
 				return err
 			}
+		}
 
-			go func(path string) {
-				err := recodeStream(fs, path)
-				if err != nil {
-					log.WithError(err).Warnf("failed to recode %s", err)
-				}
-				sem <- err
-			}(child.Path)
+		if err := capStatus.SetPaths(capPaths); err != nil {
+			return err
+		}
+
+		return call.Results.SetStatus(capStatus)
+	})
+}
+
+func (fh *fsHandler) Lock(call capnp.FS_lock) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
+		return fs.Lock(url.Path)
+	})
+}
+
+func (fh *fsHandler) Unlock(call capnp.FS_unlock) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
+		return fs.Unlock(url.Path)
+	})
+}
+
+func (fh *fsHandler) Locks(call capnp.FS_locks) error {
+	server.Ack(call.Options)
+
+	return fh.base.withCurrFs(func(fs *catfs.FS) error {
+		locks, err := fs.Locks()
+		if err != nil {
+			return err
+		}
+
+		seg := call.Results.Segment()
+		capLocks, err := capnp.NewLock_List(seg, int32(len(locks)))
+		if err != nil {
+			return err
 		}
 
-		// drain any errors possibly still left in the channel.
-		// This also make sure to wait until completion.
-		// Note we don't cancel running request.
-		for idx := 0; idx < cap(sem); idx++ {
-			if err := <-sem; err != nil {
+		for idx, lock := range locks {
+			capLock, err := capnp.NewLock(seg)
+			if err != nil {
+				return err
+			}
+
+			if err := capLock.SetPath(lock.Path); err != nil {
+				return err
+			}
+
+			if err := capLock.SetOwner(lock.Owner); err != nil {
+				return err
+			}
+
+			if err := capLock.SetSince(lock.Since.Format(time.RFC3339)); err != nil {
+				return err
+			}
+
+			if err := capLocks.Set(idx, capLock); err != nil {
 				return err
 			}
 		}
 
+		return call.Results.SetLocks(capLocks)
+	})
+}
+
+func (fh *fsHandler) SetAttr(call capnp.FS_setAttr) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	key, err := call.Params.Key()
+	if err != nil {
+		return err
+	}
+
+	value, err := call.Params.Value()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
+		if err := fs.SetAttr(url.Path, key, value); err != nil {
+			return err
+		}
+
+		fh.base.notifyFsChangeEvent()
+		return nil
+	})
+}
+
+func (fh *fsHandler) GetAttr(call capnp.FS_getAttr) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	key, err := call.Params.Key()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
+		value, err := fs.GetAttr(url.Path, key)
+		if err != nil {
+			return err
+		}
+
+		return call.Results.SetValue(value)
+	})
+}
+
+func (fh *fsHandler) RemoveAttr(call capnp.FS_removeAttr) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	key, err := call.Params.Key()
+	if err != nil {
+		return err
+	}
+
+	return fh.base.withFsFromPath(path, func(url *URL, fs *catfs.FS) error {
+		if err := fs.RemoveAttr(url.Path, key); err != nil {
+			return err
+		}
+
+		fh.base.notifyFsChangeEvent()
 		return nil
 	})
 }
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sahib/brig/server/capnp"
+	log "github.com/sirupsen/logrus"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// Job is an operation of the daemon that takes a while,
+// like a sync, pinning a directory or a cat of uncached content.
+type Job struct {
+	ID         uint64    `json:"id"`
+	Kind       string    `json:"kind"`
+	Subject    string    `json:"subject"`
+	Started    time.Time `json:"started"`
+	TotalFiles int64     `json:"total_files"`
+	DoneFiles  int64     `json:"done_files"`
+	TotalBytes uint64    `json:"total_bytes"`
+	DoneBytes  uint64    `json:"done_bytes"`
+	Current    string    `json:"current"`
+}
+
+// ETA estimates how long the job still needs from its progress so far.
+// Bytes are preferred over files, since files might differ in size.
+// If nothing can be said yet, a negative duration is returned.
+func (jb Job) ETA() time.Duration {
+	done, total := float64(jb.DoneBytes), float64(jb.TotalBytes)
+	if total == 0 {
+		done, total = float64(jb.DoneFiles), float64(jb.TotalFiles)
+	}
+
+	if done == 0 || total == 0 || done > total {
+		return -1
+	}
+
+	took := time.Since(jb.Started)
+	return time.Duration(float64(took) * (total - done) / done)
+}
+
+// jobNotifyInterval is the minimum time between two change notifications.
+const jobNotifyInterval = 250 * time.Millisecond
+
+// jobTracker keeps track of all running jobs.
+// The zero value is ready to use.
+type jobTracker struct {
+	mu     sync.Mutex
+	lastID uint64
+	jobs   map[uint64]*Job
+
+	// changed gets a value when jobs changed and somebody listens.
+	changed chan struct{}
+}
+
+// List returns a copy of all running jobs, oldest first.
+func (jt *jobTracker) List() []Job {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	jobs := make([]Job, 0, len(jt.jobs))
+	for _, job := range jt.jobs {
+		jobs = append(jobs, *job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+
+	return jobs
+}
+
+// OnChange calls `fn` with the current jobs every time they changed,
+// but not more often than jobNotifyInterval. It returns a function
+// that stops calling `fn`. Only one listener is supported.
+func (jt *jobTracker) OnChange(fn func(jobs []Job)) func() {
+	changed := make(chan struct{}, 1)
+	jt.mu.Lock()
+	jt.changed = changed
+	jt.mu.Unlock()
+
+	quit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-quit:
+				return
+			case <-changed:
+				fn(jt.List())
+				time.Sleep(jobNotifyInterval)
+			}
+		}
+	}()
+
+	return func() {
+		jt.mu.Lock()
+		jt.changed = nil
+		jt.mu.Unlock()
+		close(quit)
+	}
+}
+
+// notify needs jt.mu to be held.
+func (jt *jobTracker) notify() {
+	if jt.changed == nil {
+		return
+	}
+
+	select {
+	case jt.changed <- struct{}{}:
+	default:
+		// A notification is pending already;
+		// it will see the latest state.
+	}
+}
+
+// start registers a new job and returns a handle to update it.
+func (jt *jobTracker) start(kind, subject string) *jobHandle {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	if jt.jobs == nil {
+		jt.jobs = make(map[uint64]*Job)
+	}
+
+	jt.lastID++
+	jt.jobs[jt.lastID] = &Job{
+		ID:      jt.lastID,
+		Kind:    kind,
+		Subject: subject,
+		Started: time.Now(),
+	}
+
+	jt.notify()
+	return &jobHandle{jt: jt, id: jt.lastID}
+}
+
+// jobHandle is used by the code doing the job to report its progress.
+type jobHandle struct {
+	jt *jobTracker
+	id uint64
+}
+
+func (jh *jobHandle) update(fn func(job *Job)) {
+	jh.jt.mu.Lock()
+	defer jh.jt.mu.Unlock()
+
+	if job, ok := jh.jt.jobs[jh.id]; ok {
+		fn(job)
+		jh.jt.notify()
+	}
+}
+
+// finish removes the job; it is not shown anymore afterwards.
+func (jh *jobHandle) finish() {
+	jh.jt.mu.Lock()
+	defer jh.jt.mu.Unlock()
+
+	delete(jh.jt.jobs, jh.id)
+	jh.jt.notify()
+}
+
+// lazyJobDelay is how long an operation started with startLazy
+// has to run before it is registered as job.
+const lazyJobDelay = 500 * time.Millisecond
+
+// lazyJob is a job that is only registered once it runs longer than
+// lazyJobDelay. Progress updates before that are kept aside.
+type lazyJob struct {
+	mu            sync.Mutex
+	jt            *jobTracker
+	kind, subject string
+	describe      func(job *Job)
+	pending       Job
+	timer         *time.Timer
+	jh            *jobHandle
+	isFinished    bool
+}
+
+// startLazy is like start, but does not register the job right away.
+// Most operations are over quickly, so `describe` is only called when
+// the job is actually registered. It should set the totals of the job,
+// which might need some lookups that quick operations can skip.
+func (jt *jobTracker) startLazy(kind, subject string, describe func(job *Job)) *lazyJob {
+	lj := &lazyJob{
+		jt:       jt,
+		kind:     kind,
+		subject:  subject,
+		describe: describe,
+		pending:  Job{Started: time.Now()},
+	}
+
+	lj.timer = time.AfterFunc(lazyJobDelay, lj.register)
+	return lj
+}
+
+func (lj *lazyJob) register() {
+	lj.mu.Lock()
+	defer lj.mu.Unlock()
+
+	if lj.isFinished {
+		return
+	}
+
+	lj.describe(&lj.pending)
+	pending := lj.pending
+
+	lj.jh = lj.jt.start(lj.kind, lj.subject)
+	lj.jh.update(func(job *Job) {
+		// Keep the actual start, the progress so far counts for the ETA:
+		job.Started = pending.Started
+		job.TotalFiles, job.DoneFiles = pending.TotalFiles, pending.DoneFiles
+		job.TotalBytes, job.DoneBytes = pending.TotalBytes, pending.DoneBytes
+		job.Current = pending.Current
+	})
+}
+
+func (lj *lazyJob) update(fn func(job *Job)) {
+	lj.mu.Lock()
+	defer lj.mu.Unlock()
+
+	if lj.jh != nil {
+		lj.jh.update(fn)
+		return
+	}
+
+	fn(&lj.pending)
+}
+
+// finish removes the job, if it was registered at all.
+func (lj *lazyJob) finish() {
+	lj.mu.Lock()
+	defer lj.mu.Unlock()
+
+	lj.isFinished = true
+	lj.timer.Stop()
+	if lj.jh != nil {
+		lj.jh.finish()
+	}
+}
+
+// jobUpdater is implemented by jobHandle and lazyJob.
+type jobUpdater interface {
+	update(fn func(job *Job))
+}
+
+// jobReader counts the bytes read from `r` as progress of `jh`.
+type jobReader struct {
+	r  io.Reader
+	jh jobUpdater
+}
+
+func (jr *jobReader) Read(buf []byte) (int, error) {
+	n, err := jr.r.Read(buf)
+	if n > 0 {
+		jr.jh.update(func(job *Job) {
+			job.DoneBytes += uint64(n)
+		})
+	}
+
+	return n, err
+}
+
+// jobsEvent is the message that is sent over the gateway's /events websocket.
+type jobsEvent struct {
+	Type string     `json:"type"`
+	Jobs []jobEvent `json:"jobs"`
+}
+
+type jobEvent struct {
+	Job
+	ETASec float64 `json:"eta_sec"`
+}
+
+// notifyJobs tells the clients of the gateway about `jobs`.
+func (b *base) notifyJobs(jobs []Job) {
+	if b.gateway == nil {
+		return
+	}
+
+	ev := jobsEvent{Type: "jobs", Jobs: []jobEvent{}}
+	for _, job := range jobs {
+		ev.Jobs = append(ev.Jobs, jobEvent{Job: job, ETASec: job.ETA().Seconds()})
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Warningf("failed to encode jobs event: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if err := b.gateway.Notify(ctx, string(data)); err != nil {
+		log.Debugf("failed to send jobs event: %v", err)
+	}
+}
+
+func (rh *repoHandler) JobList(call capnp.Repo_jobList) error {
+	server.Ack(call.Options)
+
+	jobs := rh.base.jobs.List()
+	capJobs, err := call.Results.NewJobs(int32(len(jobs)))
+	if err != nil {
+		return err
+	}
+
+	for idx, job := range jobs {
+		capJob := capJobs.At(idx)
+		capJob.SetId(job.ID)
+		if err := capJob.SetKind(job.Kind); err != nil {
+			return err
+		}
+
+		if err := capJob.SetSubject(job.Subject); err != nil {
+			return err
+		}
+
+		if err := capJob.SetStarted(job.Started.Format(time.RFC3339)); err != nil {
+			return err
+		}
+
+		if err := capJob.SetCurrent(job.Current); err != nil {
+			return err
+		}
+
+		capJob.SetTotalFiles(job.TotalFiles)
+		capJob.SetDoneFiles(job.DoneFiles)
+		capJob.SetTotalBytes(job.TotalBytes)
+		capJob.SetDoneBytes(job.DoneBytes)
+		capJob.SetEtaSec(job.ETA().Seconds())
+	}
+
+	return nil
+}
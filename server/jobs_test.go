@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobTracker(t *testing.T) {
+	jt := &jobTracker{}
+	require.Empty(t, jt.List())
+
+	sync := jt.start("sync", "bob")
+	pin := jt.start("pin", "/photos")
+
+	pin.update(func(job *Job) {
+		job.TotalFiles = 10
+		job.DoneFiles = 5
+		job.Current = "/photos/cat.png"
+	})
+
+	jobs := jt.List()
+	require.Len(t, jobs, 2)
+	require.Equal(t, "sync", jobs[0].Kind)
+	require.Equal(t, "bob", jobs[0].Subject)
+	require.Equal(t, "pin", jobs[1].Kind)
+	require.Equal(t, int64(5), jobs[1].DoneFiles)
+	require.Equal(t, "/photos/cat.png", jobs[1].Current)
+
+	sync.finish()
+	jobs = jt.List()
+	require.Len(t, jobs, 1)
+	require.Equal(t, "pin", jobs[0].Kind)
+
+	// Updating a finished job does nothing:
+	sync.update(func(job *Job) { job.DoneFiles = 1 })
+	require.Len(t, jt.List(), 1)
+
+	pin.finish()
+	require.Empty(t, jt.List())
+}
+
+func TestJobETA(t *testing.T) {
+	job := Job{Started: time.Now().Add(-10 * time.Second)}
+	require.True(t, job.ETA() < 0)
+
+	// Files are used if there are no bytes:
+	job.TotalFiles, job.DoneFiles = 4, 1
+	require.InDelta(t, 30, job.ETA().Seconds(), 1)
+
+	job.TotalBytes, job.DoneBytes = 100, 50
+	require.InDelta(t, 10, job.ETA().Seconds(), 1)
+}
+
+func TestJobReaderAndOnChange(t *testing.T) {
+	jt := &jobTracker{}
+	changes := make(chan []Job, 10)
+	stop := jt.OnChange(func(jobs []Job) {
+		changes <- jobs
+	})
+	defer stop()
+
+	job := jt.start("cat", "/big")
+	data, err := ioutil.ReadAll(&jobReader{r: bytes.NewReader(make([]byte, 1024)), jh: job})
+	require.NoError(t, err)
+	require.Len(t, data, 1024)
+
+	jobs := jt.List()
+	require.Len(t, jobs, 1)
+	require.Equal(t, uint64(1024), jobs[0].DoneBytes)
+
+	job.finish()
+
+	// Changes are coalesced, but the last one has to show no jobs:
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case jobs := <-changes:
+			if len(jobs) == 0 {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("did not get notified about the finished job")
+		}
+	}
+}
+
+func TestLazyJob(t *testing.T) {
+	jt := &jobTracker{}
+	described := 0
+	describe := func(job *Job) {
+		described++
+		job.TotalFiles = 1
+		job.TotalBytes = 2048
+	}
+
+	// Quick jobs are never registered and not described:
+	quick := jt.startLazy("cat", "/small", describe)
+	data, err := ioutil.ReadAll(&jobReader{r: bytes.NewReader(make([]byte, 16)), jh: quick})
+	require.NoError(t, err)
+	require.Len(t, data, 16)
+	quick.finish()
+
+	time.Sleep(2 * lazyJobDelay)
+	require.Empty(t, jt.List())
+	require.Equal(t, 0, described)
+
+	// Slow ones show up with the progress they made before:
+	slow := jt.startLazy("cat", "/big", describe)
+	_, err = ioutil.ReadAll(&jobReader{r: bytes.NewReader(make([]byte, 1024)), jh: slow})
+	require.NoError(t, err)
+
+	time.Sleep(2 * lazyJobDelay)
+	jobs := jt.List()
+	require.Len(t, jobs, 1)
+	require.Equal(t, 1, described)
+	require.Equal(t, "/big", jobs[0].Subject)
+	require.Equal(t, uint64(2048), jobs[0].TotalBytes)
+	require.Equal(t, uint64(1024), jobs[0].DoneBytes)
+
+	slow.update(func(job *Job) { job.DoneBytes += 1024 })
+	require.Equal(t, uint64(2048), jt.List()[0].DoneBytes)
+
+	slow.finish()
+	require.Empty(t, jt.List())
+}
@@ -185,6 +185,56 @@ func capRemoteToRemote(remote capnp.Remote) (*repo.Remote, error) {
 		return nil, err
 	}
 
+	folders, err := capFoldersToFolders(remoteFolders)
+	if err != nil {
+		return nil, err
+	}
+
+	capDevices, err := remote.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []peer.Fingerprint
+	for idx := 0; idx < capDevices.Len(); idx++ {
+		capDevice, err := capDevices.At(idx)
+		if err != nil {
+			return nil, err
+		}
+
+		device, err := peer.CastFingerprint(capDevice)
+		if err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, device)
+	}
+
+	uploadLimit, err := remote.UploadLimit()
+	if err != nil {
+		return nil, err
+	}
+
+	downloadLimit, err := remote.DownloadLimit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &repo.Remote{
+		Name:              remoteName,
+		Fingerprint:       peer.Fingerprint(fingerprint),
+		Folders:           folders,
+		AcceptAutoUpdates: remote.AcceptAutoUpdates(),
+		AcceptPush:        remote.AcceptPush(),
+		ConflictStrategy:  conflictStrategy,
+		Device:            remote.Device(),
+		Devices:           devices,
+		UploadLimit:       uploadLimit,
+		DownloadLimit:     downloadLimit,
+	}, nil
+}
+
+func capFoldersToFolders(remoteFolders capnp.RemoteFolder_List) ([]repo.Folder, error) {
 	folders := []repo.Folder{}
 	for idx := 0; idx < remoteFolders.Len(); idx++ {
 		capFolder := remoteFolders.At(idx)
@@ -198,21 +248,20 @@ func capRemoteToRemote(remote capnp.Remote) (*repo.Remote, error) {
 			return nil, err
 		}
 
+		lp, err := capFolder.LockPolicy()
+		if err != nil {
+			return nil, err
+		}
+
 		folders = append(folders, repo.Folder{
 			Folder:           folderName,
 			ReadOnly:         capFolder.ReadOnly(),
 			ConflictStrategy: cs,
+			LockPolicy:       lp,
 		})
 	}
 
-	return &repo.Remote{
-		Name:              remoteName,
-		Fingerprint:       peer.Fingerprint(fingerprint),
-		Folders:           folders,
-		AcceptAutoUpdates: remote.AcceptAutoUpdates(),
-		AcceptPush:        remote.AcceptPush(),
-		ConflictStrategy:  conflictStrategy,
-	}, nil
+	return folders, nil
 }
 
 func remoteToCapRemote(remote repo.Remote, seg *capnplib.Segment) (*capnp.Remote, error) {
@@ -253,6 +302,10 @@ func remoteToCapRemote(remote repo.Remote, seg *capnplib.Segment) (*capnp.Remote
 			return nil, err
 		}
 
+		if err := capFolder.SetLockPolicy(folder.LockPolicy); err != nil {
+			return nil, err
+		}
+
 		if err := capFolders.Set(idx, capFolder); err != nil {
 			return nil, err
 		}
@@ -262,8 +315,32 @@ func remoteToCapRemote(remote repo.Remote, seg *capnplib.Segment) (*capnp.Remote
 		return nil, err
 	}
 
+	capDevices, err := capnplib.NewTextList(seg, int32(len(remote.Devices)))
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, device := range remote.Devices {
+		if err := capDevices.Set(idx, string(device)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := capRemote.SetDevices(capDevices); err != nil {
+		return nil, err
+	}
+
+	if err := capRemote.SetUploadLimit(remote.UploadLimit); err != nil {
+		return nil, err
+	}
+
+	if err := capRemote.SetDownloadLimit(remote.DownloadLimit); err != nil {
+		return nil, err
+	}
+
 	capRemote.SetAcceptAutoUpdates(remote.AcceptAutoUpdates)
 	capRemote.SetAcceptPush(remote.AcceptPush)
+	capRemote.SetDevice(remote.Device)
 	return &capRemote, nil
 }
 
@@ -597,3 +674,135 @@ func (nh *netHandler) Push(call capnp.Net_push) error {
 		return ctl.Push()
 	})
 }
+
+func (nh *netHandler) RemoteInvite(call capnp.Net_remoteInvite) error {
+	server.Ack(call.Options)
+
+	capFolders, err := call.Params.Folders()
+	if err != nil {
+		return err
+	}
+
+	folders, err := capFoldersToFolders(capFolders)
+	if err != nil {
+		return err
+	}
+
+	fingerprint, err := nh.base.ownFingerprint()
+	if err != nil {
+		return err
+	}
+
+	rp := nh.base.repo
+	kr, err := rp.Keyring()
+	if err != nil {
+		return err
+	}
+
+	validFor := time.Duration(call.Params.ValidSec()) * time.Second
+	invite, err := rp.Invites.Create(folders, call.Params.AcceptPush(), validFor)
+	if err != nil {
+		return err
+	}
+
+	token, err := repo.NewInviteToken(rp.Immutables.Owner(), fingerprint, invite).Encode(kr)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("created invite %s (valid until %s)", invite.ID, invite.Expires)
+	return call.Results.SetToken(token)
+}
+
+func (nh *netHandler) RemoteAcceptInvite(call capnp.Net_remoteAcceptInvite) error {
+	server.Ack(call.Options)
+
+	capToken, err := call.Params.Token()
+	if err != nil {
+		return err
+	}
+
+	token, err := repo.DecodeInviteToken(capToken)
+	if err != nil {
+		return err
+	}
+
+	name, err := call.Params.Name()
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = token.Name
+	}
+
+	rp := nh.base.repo
+	if name == rp.Immutables.Owner() {
+		return fmt.Errorf("refusing to add a remote with the same as the repo owner")
+	}
+
+	owner := peer.Name(rp.Immutables.Owner())
+	if token.Device && !owner.SameUser(peer.Name(name)) {
+		return fmt.Errorf("devices need the same user name as we have (%s)", owner.WithoutResource())
+	}
+
+	if _, err := rp.Remotes.Remote(name); err == nil {
+		return fmt.Errorf("there is already a remote named »%s«", name)
+	}
+
+	subCtx, cancel := context.WithCancel(nh.base.ctx)
+	defer cancel()
+
+	pingMap := nh.base.peerServer.PingMap()
+	if err := p2pnet.AcceptInviteToken(subCtx, token, rp, nh.base.backend, pingMap); err != nil {
+		return err
+	}
+
+	// The inviter decides what it takes from us. We share
+	// the same folders, but take everything it sends us.
+	folders := []repo.Folder{}
+	for _, folder := range token.Folders {
+		folders = append(folders, repo.Folder{Folder: folder.Folder})
+	}
+
+	remote := repo.Remote{
+		Name:        name,
+		Fingerprint: token.Fingerprint,
+		Folders:     folders,
+	}
+
+	if token.Device {
+		remote.Device = true
+		remote.AcceptAutoUpdates = true
+	}
+
+	if err := rp.Remotes.AddOrUpdateRemote(remote); err != nil {
+		return err
+	}
+
+	if err := nh.base.syncRemoteStates(); err != nil {
+		return err
+	}
+
+	if remote.Device {
+		// Get to know the peers of our new device right away:
+		go func() {
+			if err := nh.base.doFetch(name); err != nil {
+				log.Warningf("failed to fetch from new device %s: %v", name, err)
+			}
+		}()
+	}
+
+	// Return the remote as it was stored:
+	remote, err = rp.Remotes.Remote(name)
+	if err != nil {
+		return err
+	}
+
+	capRemote, err := remoteToCapRemote(remote, call.Results.Segment())
+	if err != nil {
+		return err
+	}
+
+	return call.Results.SetRemote(*capRemote)
+}
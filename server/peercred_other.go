@@ -0,0 +1,13 @@
+// +build !linux
+
+package server
+
+import (
+	"net"
+)
+
+// peerUID returns the uid of the process on the other side of `conn`.
+func peerUID(conn *net.UnixConn) (int, error) {
+	// Not supported yet for non-linux systems.
+	return -1, errPeerCredUnsupported
+}
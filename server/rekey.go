@@ -0,0 +1,216 @@
+package server
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/catfs"
+	fserrs "github.com/sahib/brig/catfs/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RekeyStatus describes the progress of a running (or the last) rekey.
+type RekeyStatus struct {
+	Running     bool
+	Folders     []string
+	TotalFiles  int64
+	DoneFiles   int64
+	FailedFiles int64
+	Current     string
+	LastError   string
+
+	// Remotes are the remotes that will receive the new keys.
+	Remotes []string
+}
+
+// rekeyState is shared between the rekey call and status requests,
+// which are usually issued from a different connection.
+type rekeyState struct {
+	mu     sync.Mutex
+	status RekeyStatus
+}
+
+func (rs *rekeyState) Status() RekeyStatus {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.status
+}
+
+func (rs *rekeyState) start(folders, remotes []string, totalFiles int) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.status.Running {
+		return e.Errorf("already re-keying %s", strings.Join(rs.status.Folders, ", "))
+	}
+
+	rs.status = RekeyStatus{
+		Running:    true,
+		Folders:    folders,
+		TotalFiles: int64(totalFiles),
+		Remotes:    remotes,
+	}
+
+	return nil
+}
+
+func (rs *rekeyState) progress(path string, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.status.Current = path
+	if err != nil {
+		rs.status.FailedFiles++
+		rs.status.LastError = err.Error()
+		return
+	}
+
+	rs.status.DoneFiles++
+}
+
+func (rs *rekeyState) stop() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.status.Running = false
+	rs.status.Current = ""
+}
+
+// rekeyFolders returns the folders that have to be re-keyed together
+// with `path`: all folders of its key domain, or just `path` itself
+// if it is not part of any domain.
+func (b *base) rekeyFolders(path string) []string {
+	domain := b.repo.Hints.Lookup(path).KeyDomain
+	folders := b.repo.Hints.KeyDomainFolders(domain)
+	if len(folders) == 0 {
+		return []string{path}
+	}
+
+	return folders
+}
+
+// authorizedRemotes returns the names of all remotes that may
+// receive the metadata (and therefore the keys) of `folders`.
+func (b *base) authorizedRemotes(folders []string) ([]string, error) {
+	rmts, err := b.repo.Remotes.ListRemotes()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, rmt := range rmts {
+		for _, folder := range folders {
+			if rmt.CanSee(folder) {
+				names = append(names, rmt.Name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// rekey re-encrypts the current version of all files in the key domain
+// of `path` with new keys. The new keys are part of the metadata and
+// therefore only reach remotes that are still allowed to see the folders.
+func (b *base) rekey(fs *catfs.FS, path string) error {
+	folders := b.rekeyFolders(path)
+
+	paths := []string{}
+	seen := make(map[string]bool)
+	for _, folder := range folders {
+		// If it's a file the list will be just the file itself.
+		children, err := fs.List(folder, -1)
+		if err != nil {
+			return e.Wrapf(err, "failed to list %s", folder)
+		}
+
+		for _, child := range children {
+			if child.IsDir || seen[child.Path] {
+				continue
+			}
+
+			seen[child.Path] = true
+			paths = append(paths, child.Path)
+		}
+	}
+
+	remotes, err := b.authorizedRemotes(folders)
+	if err != nil {
+		return err
+	}
+
+	if err := b.rekeyState.start(folders, remotes, len(paths)); err != nil {
+		return err
+	}
+
+	defer b.rekeyState.stop()
+
+	err = recodeFiles(fs, paths, true, func(path string, err error) error {
+		if err != nil {
+			log.WithError(err).Warnf("failed to rekey %s", path)
+		}
+
+		b.rekeyState.progress(path, err)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("rekey %s", strings.Join(folders, ", "))
+	if err := fs.MakeCommit(msg); err != nil && err != fserrs.ErrNoChange {
+		return err
+	}
+
+	b.notifyFsChangeEvent()
+	return nil
+}
+
+// recodeFiles runs recodeStream on all `paths` in parallel.
+// `done` is called after each file; if it returns an error,
+// no further files are started and the error is returned.
+func recodeFiles(fs *catfs.FS, paths []string, rekey bool, done func(path string, err error) error) error {
+	var doneMu sync.Mutex
+
+	// channel serves as token giver to limit parallel workload:
+	sem := make(chan error, runtime.NumCPU()*2)
+	for idx := 0; idx < cap(sem); idx++ {
+		sem <- nil
+	}
+
+	for _, path := range paths {
+		// Get next token:
+		if err := <-sem; err != nil {
+			return err
+		}
+
+		go func(path string) {
+			err := recodeStream(fs, path, rekey)
+
+			doneMu.Lock()
+			err = done(path, err)
+			doneMu.Unlock()
+
+			sem <- err
+		}(path)
+	}
+
+	// drain any errors possibly still left in the channel.
+	// This also make sure to wait until completion.
+	// Note we don't cancel running request.
+	for idx := 0; idx < cap(sem); idx++ {
+		if err := <-sem; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -60,16 +60,27 @@ func (a *RemotesAPI) get(name string) (*remotesapi.Remote, error) {
 	extRmt.AcceptAutoUpdates = rmt.AcceptAutoUpdates
 	extRmt.AcceptPush = rmt.AcceptPush
 	extRmt.ConflictStrategy = rmt.ConflictStrategy
+	extRmt.Device = rmt.Device
 
 	for _, folder := range rmt.Folders {
 		extRmt.Folders = append(extRmt.Folders, remotesapi.Folder{
-			Folder:   folder.Folder,
-			ReadOnly: folder.ReadOnly,
+			Folder:           folder.Folder,
+			ReadOnly:         folder.ReadOnly,
+			ConflictStrategy: folder.ConflictStrategy,
+			LockPolicy:       folder.LockPolicy,
 		})
 	}
 
-	addr := rmt.Fingerprint.Addr()
+	// Report the first device that is online:
 	psrv := a.base.peerServer
+	addr := rmt.Fingerprint.Addr()
+	for _, fp := range rmt.Fingerprints() {
+		if pinger, err := psrv.PingMap().For(fp.Addr()); err == nil && pinger.Roundtrip() > 0 {
+			addr = fp.Addr()
+			break
+		}
+	}
+
 	pinger, err := psrv.PingMap().For(addr)
 	if err != nil {
 		// early exit: peer is not online.
@@ -93,11 +104,28 @@ func (a *RemotesAPI) Set(rm remotesapi.Remote) error {
 	folders := []repo.Folder{}
 	for _, folder := range rm.Folders {
 		folders = append(folders, repo.Folder{
-			Folder:   folder.Folder,
-			ReadOnly: folder.ReadOnly,
+			Folder:           folder.Folder,
+			ReadOnly:         folder.ReadOnly,
+			ConflictStrategy: folder.ConflictStrategy,
+			LockPolicy:       folder.LockPolicy,
 		})
 	}
 
+	// Devices are not editable over the api; keep what we know.
+	// Devices are unlinked by removing them.
+	device := rm.Device
+	var devices []peer.Fingerprint
+	if old, err := a.base.repo.Remotes.Remote(rm.Name); err == nil && old.Fingerprint == fp {
+		device = device || old.Device
+		devices = old.Devices
+	}
+
+	// Same for the bandwidth limits, but those stay even with a new fingerprint.
+	var uploadLimit, downloadLimit string
+	if old, err := a.base.repo.Remotes.Remote(rm.Name); err == nil {
+		uploadLimit, downloadLimit = old.UploadLimit, old.DownloadLimit
+	}
+
 	err = a.base.repo.Remotes.AddOrUpdateRemote(repo.Remote{
 		Name:              rm.Name,
 		Fingerprint:       fp,
@@ -105,6 +133,10 @@ func (a *RemotesAPI) Set(rm remotesapi.Remote) error {
 		AcceptAutoUpdates: rm.AcceptAutoUpdates,
 		AcceptPush:        rm.AcceptPush,
 		ConflictStrategy:  rm.ConflictStrategy,
+		Device:            device,
+		Devices:           devices,
+		UploadLimit:       uploadLimit,
+		DownloadLimit:     downloadLimit,
 	})
 
 	if err != nil {
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/sahib/brig/backend"
+	"github.com/sahib/brig/catfs"
+	"github.com/sahib/brig/catfs/mio/compress"
 	"github.com/sahib/brig/fuse"
 	gwdb "github.com/sahib/brig/gateway/db"
 	gwcapnp "github.com/sahib/brig/gateway/db/capnp"
@@ -18,6 +20,12 @@ import (
 
 type repoHandler struct {
 	base *base
+
+	// uid is the user of the client, or unknownUID.
+	uid int
+
+	// access is what the client may do with this repository.
+	access accessClass
 }
 
 func (rh *repoHandler) Quit(call capnp.Repo_quit) error {
@@ -66,15 +74,17 @@ func (rh *repoHandler) Unmount(call capnp.Repo_unmount) error {
 func capMountOptionsToMountOptions(capOpts capnp.MountOptions) (fuse.MountOptions, error) {
 	readOnly := capOpts.ReadOnly()
 	offline := capOpts.Offline()
+	flagUnavailable := capOpts.FlagUnavailable()
 	rootPath, err := capOpts.RootPath()
 	if err != nil {
 		return fuse.MountOptions{}, err
 	}
 
 	return fuse.MountOptions{
-		ReadOnly: readOnly,
-		Root:     rootPath,
-		Offline:  offline,
+		ReadOnly:        readOnly,
+		Root:            rootPath,
+		Offline:         offline,
+		FlagUnavailable: flagUnavailable,
 	}, nil
 }
 
@@ -145,6 +155,7 @@ func fsTabEntryToCap(entry fuse.FsTabEntry, seg *capnplib.Segment) (*capnp.FsTab
 
 	capEntry.SetReadOnly(entry.ReadOnly)
 	capEntry.SetOffline(entry.Offline)
+	capEntry.SetFlagUnavailable(entry.FlagUnavailable)
 	capEntry.SetActive(entry.Active)
 
 	if err := capEntry.SetPath(entry.Path); err != nil {
@@ -494,6 +505,33 @@ func (rh *repoHandler) HintSet(call capnp.Repo_hintSet) error {
 		newHint.EncryptionAlgo = hints.EncryptionHint(encryptionAlgo)
 	}
 
+	if capHint.HasZstdDict() {
+		zstdDict, err := capHint.ZstdDict()
+		if err != nil {
+			return err
+		}
+
+		newHint.ZstdDict = zstdDict
+	}
+
+	if capHint.HasKeyDomain() {
+		keyDomain, err := capHint.KeyDomain()
+		if err != nil {
+			return err
+		}
+
+		if keyDomain == hints.KeyDomainNone {
+			keyDomain = ""
+		}
+
+		newHint.KeyDomain = keyDomain
+	}
+
+	// A dictionary is useless without zstd; do not keep it around.
+	if newHint.CompressionAlgo != hints.CompressionZstd {
+		newHint.ZstdDict = ""
+	}
+
 	if err := rh.base.repo.Hints.Set(path, newHint); err != nil {
 		return err
 	}
@@ -532,6 +570,14 @@ func hintToCapnp(seg *capnplib.Segment, path string, hint hints.Hint) (*capnp.Hi
 		return nil, err
 	}
 
+	if err := capHint.SetZstdDict(hint.ZstdDict); err != nil {
+		return nil, err
+	}
+
+	if err := capHint.SetKeyDomain(hint.KeyDomain); err != nil {
+		return nil, err
+	}
+
 	return &capHint, nil
 }
 
@@ -563,3 +609,103 @@ func (rh *repoHandler) HintList(call capnp.Repo_hintList) error {
 
 	return call.Results.SetHints(capnpHints)
 }
+
+func (rh *repoHandler) HintTrainDict(call capnp.Repo_hintTrainDict) error {
+	server.Ack(call.Options)
+
+	path, err := call.Params.Path()
+	if err != nil {
+		return err
+	}
+
+	maxSize := int(call.Params.MaxSize())
+	if maxSize <= 0 {
+		maxSize = compress.DefaultDictSize
+	}
+
+	return rh.base.withCurrFs(func(fs *catfs.FS) error {
+		dict, err := rh.base.trainDict(fs, path, maxSize)
+		if err != nil {
+			return err
+		}
+
+		// Keep the encryption settings of the folder, but switch to zstd:
+		newHint := rh.base.repo.Hints.Lookup(path)
+		newHint.CompressionAlgo = hints.CompressionZstd
+		newHint.ZstdDict = dict.ID.String()
+		if err := rh.base.repo.Hints.Set(path, newHint); err != nil {
+			return err
+		}
+
+		if err := rh.base.repo.SaveHints(); err != nil {
+			return err
+		}
+
+		if err := call.Results.SetId(dict.ID.String()); err != nil {
+			return err
+		}
+
+		call.Results.SetSize(int64(len(dict.Data)))
+		return nil
+	})
+}
+
+func (rh *repoHandler) SelectRepo(call capnp.Repo_selectRepo) error {
+	server.Ack(call.Options)
+
+	repoName, err := call.Params.Repo()
+	if err != nil {
+		return err
+	}
+
+	b, err := rh.base.repos.lookup(repoName)
+	if err != nil {
+		return err
+	}
+
+	// Every repository decides on its own who may use it:
+	access := accessForUID(rh.uid, b.repo.Config.Section("daemon"))
+	if access == accessDenied {
+		return fmt.Errorf("permission denied: no access to repository %s", repoName)
+	}
+
+	return call.Results.SetApi(newAPIClient(b, rh.uid, access))
+}
+
+func (rh *repoHandler) DaemonRepos(call capnp.Repo_daemonRepos) error {
+	server.Ack(call.Options)
+
+	bases := rh.base.repos.all()
+
+	seg := call.Results.Segment()
+	capRepos, err := capnp.NewDaemonRepo_List(seg, int32(len(bases)))
+	if err != nil {
+		return err
+	}
+
+	for idx, b := range bases {
+		capRepo, err := capnp.NewDaemonRepo(seg)
+		if err != nil {
+			return err
+		}
+
+		if err := capRepo.SetName(b.name); err != nil {
+			return err
+		}
+
+		if err := capRepo.SetPath(b.basePath); err != nil {
+			return err
+		}
+
+		if err := capRepo.SetOwner(b.repo.Immutables.Owner()); err != nil {
+			return err
+		}
+
+		capRepo.SetIsDefault(rh.base.repos.isDefault(b))
+		if err := capRepos.Set(idx, capRepo); err != nil {
+			return err
+		}
+	}
+
+	return call.Results.SetRepos(capRepos)
+}
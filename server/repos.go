@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"zombiezen.com/go/capnproto2/rpc"
+)
+
+// RepoSpec describes an additional repository that should be
+// served by the daemon next to the default one.
+type RepoSpec struct {
+	// Name is used to select the repository (e.g. via »brig --repo <name>«).
+	// If empty, the name of the repository folder is used.
+	Name string
+
+	// Path is the folder of the repository.
+	Path string
+}
+
+// repoSet holds all repositories that are served by a single daemon.
+// Every repository has its own base, i.e. its own backend, event listener,
+// peer server and gateway. Incoming connections operate on the default
+// repository until they select another one.
+type repoSet struct {
+	mu          sync.Mutex
+	bases       map[string]*base
+	defaultName string
+}
+
+func newRepoSet() *repoSet {
+	return &repoSet{
+		bases: make(map[string]*base),
+	}
+}
+
+// normalizeRepoPath makes `path` comparable to other paths.
+func normalizeRepoPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		return realPath
+	}
+
+	return absPath
+}
+
+func (rs *repoSet) add(b *base, isDefault bool) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if _, ok := rs.bases[b.name]; ok {
+		return fmt.Errorf("a repository named `%s` is already served", b.name)
+	}
+
+	for _, other := range rs.bases {
+		if normalizeRepoPath(other.basePath) == normalizeRepoPath(b.basePath) {
+			return fmt.Errorf("repository at %s is already served as `%s`", b.basePath, other.name)
+		}
+	}
+
+	rs.bases[b.name] = b
+	if isDefault {
+		rs.defaultName = b.name
+	}
+
+	return nil
+}
+
+func (rs *repoSet) defaultBase() *base {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.bases[rs.defaultName]
+}
+
+// lookup returns the base of the repository with the name or path `repo`.
+func (rs *repoSet) lookup(repo string) (*base, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if b, ok := rs.bases[repo]; ok {
+		return b, nil
+	}
+
+	repoPath := normalizeRepoPath(repo)
+	for _, b := range rs.bases {
+		if normalizeRepoPath(b.basePath) == repoPath {
+			return b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no such repository served by this daemon: %s", repo)
+}
+
+// all returns all bases, sorted by their name.
+func (rs *repoSet) all() []*base {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	bases := []*base{}
+	for _, b := range rs.bases {
+		bases = append(bases, b)
+	}
+
+	sort.Slice(bases, func(i, j int) bool {
+		return bases[i].name < bases[j].name
+	})
+
+	return bases
+}
+
+func (rs *repoSet) isDefault(b *base) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.defaultName == b.name
+}
+
+// Quit shuts down all served repositories.
+func (rs *repoSet) Quit() error {
+	var firstErr error
+	for _, b := range rs.all() {
+		if err := b.Quit(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Handle is being called by the base server implementation
+// for every local request that is being served to the brig daemon.
+func (rs *repoSet) Handle(ctx context.Context, conn net.Conn) {
+	b := rs.defaultBase()
+	uid, access := accessForConn(conn, b.repo.Config.Section("daemon"))
+	if access == accessDenied {
+		log.Warnf("denied access to local client at %v", conn.RemoteAddr())
+		if err := conn.Close(); err != nil {
+			log.Warnf("failed to close denied conn: %v", err)
+		}
+
+		return
+	}
+
+	transport := rpc.StreamTransport(conn)
+	srv := newAPIClient(b, uid, access)
+	rpcConn := rpc.NewConn(
+		transport,
+		rpc.MainInterface(srv.Client),
+		rpc.ConnLog(nil),
+		rpc.SendBufferSize(128),
+	)
+
+	if err := rpcConn.Wait(); err != nil {
+		log.Warnf("serving rpc failed: %v", err)
+	}
+
+	if err := rpcConn.Close(); err != nil {
+		// Close seems to be complaining that the conn was
+		// already closed, but be safe and expect this.
+		if err != rpc.ErrConnClosed {
+			log.Warnf("failed to close rpc conn: %v", err)
+		}
+	}
+}
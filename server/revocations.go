@@ -0,0 +1,273 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	p2pnet "github.com/sahib/brig/net"
+	"github.com/sahib/brig/net/peer"
+	"github.com/sahib/brig/repo"
+	"github.com/sahib/brig/server/capnp"
+	log "github.com/sirupsen/logrus"
+	capnplib "zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// rotatedKeyBits is the size of keys made by »brig whoami --rotate-key«.
+// It's the same as for the key made during init.
+const rotatedKeyBits = 2048
+
+func (b *base) ownFingerprint() (peer.Fingerprint, error) {
+	kr, err := b.repo.Keyring()
+	if err != nil {
+		return "", err
+	}
+
+	ownPubKey, err := kr.OwnPubKey()
+	if err != nil {
+		return "", err
+	}
+
+	self, err := b.peerServer.Identity()
+	if err != nil {
+		return "", err
+	}
+
+	return peer.BuildFingerprint(self.Addr, ownPubKey), nil
+}
+
+// doExchangeRevocations swaps revocations with the remote behind `ctl`.
+// If it told us something new, we pass it on to our other remotes.
+func (b *base) doExchangeRevocations(ctl *p2pnet.Client, who string) error {
+	added, err := ctl.ExchangeRevocations(b.repo)
+	if err != nil {
+		return err
+	}
+
+	if len(added) > 0 {
+		go b.onNewRevocations(who)
+	}
+
+	return nil
+}
+
+// onNewRevocations is called once we learned about new revocations from `from`.
+func (b *base) onNewRevocations(from string) {
+	// Revocations might have removed remotes:
+	if err := b.syncRemoteStates(); err != nil {
+		log.Warningf("failed to sync remote states after revocation: %v", err)
+	}
+
+	if notReached := b.spreadRevocations(from); len(notReached) > 0 {
+		log.Infof("revocations will reach %v later", notReached)
+	}
+}
+
+// spreadRevocations exchanges revocations with all remotes except `except`.
+// The names of the remotes that could not be reached are returned.
+func (b *base) spreadRevocations(except string) []string {
+	remotes, err := b.repo.Remotes.ListRemotes()
+	if err != nil {
+		log.Warningf("failed to list remotes: %v", err)
+		return nil
+	}
+
+	notReached := []string{}
+	for _, remote := range remotes {
+		if remote.Name == except {
+			continue
+		}
+
+		err := b.withNetClient(remote.Name, func(ctl *p2pnet.Client) error {
+			return b.doExchangeRevocations(ctl, remote.Name)
+		})
+
+		if err != nil {
+			log.Warningf("failed to exchange revocations with %s: %v", remote.Name, err)
+			notReached = append(notReached, remote.Name)
+		}
+	}
+
+	return notReached
+}
+
+func (b *base) revokeRemote(name, reason string) ([]string, error) {
+	remote, err := b.repo.Remotes.Remote(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ownFp, err := b.ownFingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	kr, err := b.repo.Keyring()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := &repo.Revocation{
+		Revoked: remote.Fingerprint,
+		Reason:  reason,
+		Created: time.Now().UTC(),
+		Issuer:  ownFp,
+	}
+
+	if err := rv.Sign(kr); err != nil {
+		return nil, err
+	}
+
+	// This also removes the remote:
+	if _, err := b.repo.AddRevocations([]*repo.Revocation{rv}); err != nil {
+		return nil, err
+	}
+
+	if err := b.syncRemoteStates(); err != nil {
+		return nil, err
+	}
+
+	return b.spreadRevocations(name), nil
+}
+
+// rotateKey replaces our key by a new one. The rotation is signed with the
+// old key and sent to all remotes before switching, since they would not
+// let us in with the new key otherwise. Remotes that are not reached now
+// will hear about it from other remotes that know us both.
+func (b *base) rotateKey() (peer.Fingerprint, []string, error) {
+	b.keyMu.Lock()
+	defer b.keyMu.Unlock()
+
+	kr, err := b.repo.Keyring()
+	if err != nil {
+		return "", nil, err
+	}
+
+	oldFp, err := b.ownFingerprint()
+	if err != nil {
+		return "", nil, err
+	}
+
+	log.Infof("creating a new key with %d bits", rotatedKeyBits)
+	if err := kr.CreateNextKey(rotatedKeyBits); err != nil {
+		return "", nil, err
+	}
+
+	nextPubKey, err := kr.NextPubKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	newFp := peer.BuildFingerprint(oldFp.Addr(), nextPubKey)
+	rv := &repo.Revocation{
+		Revoked:   oldFp,
+		Successor: newFp,
+		Reason:    "key rotation",
+		Created:   time.Now().UTC(),
+		Issuer:    oldFp,
+	}
+
+	if err := rv.Sign(kr); err != nil {
+		return "", nil, err
+	}
+
+	if _, err := b.repo.AddRevocations([]*repo.Revocation{rv}); err != nil {
+		return "", nil, err
+	}
+
+	notReached := b.spreadRevocations("")
+	if err := kr.SwitchToNextKey(); err != nil {
+		return "", nil, fmt.Errorf("failed to switch to new key: %v", err)
+	}
+
+	log.Infof("rotated our key; new fingerprint is %s", newFp)
+	return newFp, notReached, nil
+}
+
+func stringsToCapTextList(strs []string, newList func(n int32) (capnplib.TextList, error)) error {
+	list, err := newList(int32(len(strs)))
+	if err != nil {
+		return err
+	}
+
+	for idx, str := range strs {
+		if err := list.Set(idx, str); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (nh *netHandler) RemoteRevoke(call capnp.Net_remoteRevoke) error {
+	server.Ack(call.Options)
+
+	name, err := call.Params.Name()
+	if err != nil {
+		return err
+	}
+
+	reason, err := call.Params.Reason()
+	if err != nil {
+		return err
+	}
+
+	notReached, err := nh.base.revokeRemote(name, reason)
+	if err != nil {
+		return err
+	}
+
+	return stringsToCapTextList(notReached, call.Results.NewNotReached)
+}
+
+func (nh *netHandler) RotateKey(call capnp.Net_rotateKey) error {
+	server.Ack(call.Options)
+
+	fingerprint, notReached, err := nh.base.rotateKey()
+	if err != nil {
+		return err
+	}
+
+	if err := call.Results.SetFingerprint(string(fingerprint)); err != nil {
+		return err
+	}
+
+	return stringsToCapTextList(notReached, call.Results.NewNotReached)
+}
+
+func (nh *netHandler) RevocationList(call capnp.Net_revocationList) error {
+	server.Ack(call.Options)
+
+	revs := nh.base.repo.Revocations.List()
+	capRevs, err := call.Results.NewRevocations(int32(len(revs)))
+	if err != nil {
+		return err
+	}
+
+	for idx, rv := range revs {
+		capRv := capRevs.At(idx)
+		if err := capRv.SetRevoked(string(rv.Revoked)); err != nil {
+			return err
+		}
+
+		if err := capRv.SetSuccessor(string(rv.Successor)); err != nil {
+			return err
+		}
+
+		if err := capRv.SetReason(rv.Reason); err != nil {
+			return err
+		}
+
+		if err := capRv.SetCreated(rv.Created.Format(time.RFC3339)); err != nil {
+			return err
+		}
+
+		if err := capRv.SetIssuer(string(rv.Issuer)); err != nil {
+			return err
+		}
+
+		capRv.SetSelfSigned(rv.IsSelfSigned())
+	}
+
+	return nil
+}
@@ -3,11 +3,18 @@ package server
 import (
 	"context"
 	"net"
+	"os"
+	"path/filepath"
 	"runtime/debug"
+	"strconv"
+	"strings"
 
+	e "github.com/pkg/errors"
+	"github.com/sahib/brig/defaults"
 	"github.com/sahib/brig/fuse"
 	"github.com/sahib/brig/util"
 	"github.com/sahib/brig/util/server"
+	"github.com/sahib/config"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -39,6 +46,26 @@ func listenerFromServerURL(s string) (net.Listener, error) {
 	return net.Listen(scheme, addr)
 }
 
+// protectSocket sets the permissions of the socket file at `serverURL`, if
+// there is one. Abstract unix sockets and tcp sockets have no file.
+func protectSocket(serverURL string, cfg *config.Config) error {
+	scheme, addr, err := util.URLToSchemeAndAddr(serverURL)
+	if err != nil {
+		return err
+	}
+
+	if scheme != "unix" || strings.HasPrefix(addr, "\x00") {
+		return nil
+	}
+
+	mode, err := strconv.ParseUint(cfg.String("socket_mode"), 8, 32)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(addr, os.FileMode(mode))
+}
+
 func applyFstabInitially(base *base) error {
 	return fuse.FsTabApply(base.repo.Config.Section("mounts"), base.mounts)
 }
@@ -48,13 +75,29 @@ func (sv *Server) RepoPath() string {
 	return sv.base.basePath
 }
 
+// RepoPaths returns the paths of all repositories served by the daemon.
+func (sv *Server) RepoPaths() []string {
+	paths := []string{}
+	for _, b := range sv.base.repos.all() {
+		paths = append(paths, b.basePath)
+	}
+
+	return paths
+}
+
 // DaemonURL returns the url that can be used used to access the server.
 func (sv *Server) DaemonURL() string {
 	return sv.daemonURL
 }
 
-// BootServer will boot up the local server.
-func BootServer(basePath string, serverURL string) (*Server, error) {
+func repoNameFromPath(path string) string {
+	return filepath.Base(normalizeRepoPath(path))
+}
+
+// BootServer will boot up the local server. The repository at `basePath` is
+// served by default; `extraRepos` are served as well and can be selected by
+// clients by their name or path.
+func BootServer(basePath string, serverURL string, extraRepos ...RepoSpec) (*Server, error) {
 	defer func() {
 		// If anything in the daemon goes fatally wrong and it blows up, we
 		// want to log the panic at least. Otherwise we'll have a hard time
@@ -73,13 +116,45 @@ func BootServer(basePath string, serverURL string) (*Server, error) {
 
 	ctx := context.Background()
 	quitCh := make(chan struct{})
-	base := newBase(ctx, basePath, quitCh)
+	repos := newRepoSet()
+	mainBase := newBase(ctx, basePath, repoNameFromPath(basePath), repos, quitCh)
+	if err := repos.add(mainBase, true); err != nil {
+		return nil, err
+	}
+
+	extraBases := []*base{}
+	for _, spec := range extraRepos {
+		name := spec.Name
+		if name == "" {
+			name = repoNameFromPath(spec.Path)
+		}
+
+		extraBase := newBase(ctx, spec.Path, name, repos, quitCh)
+		if err := repos.add(extraBase, false); err != nil {
+			return nil, err
+		}
+
+		extraBases = append(extraBases, extraBase)
+	}
+
+	// The repository is not loaded yet, but the socket should
+	// not be accessible with umask permissions in the meantime.
+	cfg, err := defaults.OpenMigratedConfig(filepath.Join(basePath, "config.yml"))
+	if err != nil {
+		return nil, err
+	}
+
 	lst, err := listenerFromServerURL(serverURL)
 	if err != nil {
 		return nil, err
 	}
 
-	baseServer, err := server.NewServer(ctx, lst, base)
+	if err := protectSocket(serverURL, cfg.Section("daemon")); err != nil {
+		lst.Close()
+		return nil, e.Wrapf(err, "socket permissions")
+	}
+
+	baseServer, err := server.NewServer(ctx, lst, repos)
 	if err != nil {
 		return nil, err
 	}
@@ -93,17 +168,28 @@ func BootServer(basePath string, serverURL string) (*Server, error) {
 		}
 	}()
 
-	if err := base.loadAll(); err != nil {
+	if err := mainBase.loadAll(); err != nil {
 		return nil, err
 	}
 
-	if err := applyFstabInitially(base); err != nil {
+	if err := applyFstabInitially(mainBase); err != nil {
 		log.Warnf("could not mount fstab mounts: %v", err)
 	}
 
+	for _, extraBase := range extraBases {
+		log.Infof("also serving repository `%s` at %s", extraBase.name, extraBase.basePath)
+		if err := extraBase.loadAll(); err != nil {
+			return nil, e.Wrapf(err, "repo %s", extraBase.name)
+		}
+
+		if err := applyFstabInitially(extraBase); err != nil {
+			log.Warnf("could not mount fstab mounts of %s: %v", extraBase.name, err)
+		}
+	}
+
 	return &Server{
 		daemonURL:  serverURL,
 		baseServer: baseServer,
-		base:       base,
+		base:       mainBase,
 	}, nil
 }
@@ -1,11 +1,19 @@
 package server
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	e "github.com/pkg/errors"
 	"github.com/sahib/brig/catfs"
 	"github.com/sahib/brig/server/capnp"
+	log "github.com/sirupsen/logrus"
 	cplib "zombiezen.com/go/capnproto2"
 	"zombiezen.com/go/capnproto2/server"
 )
@@ -494,3 +502,122 @@ func (vcs *vcsHandler) CommitInfo(call capnp.VCS_commitInfo) error {
 		return nil
 	})
 }
+
+func (vcs *vcsHandler) ImportGit(call capnp.VCS_importGit) error {
+	server.Ack(call.Options)
+
+	gitPath, err := call.Params.GitPath()
+	if err != nil {
+		return err
+	}
+
+	rev, err := call.Params.Rev()
+	if err != nil {
+		return err
+	}
+
+	prefix, err := call.Params.Prefix()
+	if err != nil {
+		return err
+	}
+
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	// git would take it as option otherwise (like --export-marks=<path>):
+	if strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("invalid git revision: %s", rev)
+	}
+
+	// Merges cannot be represented in brig, so follow only the mainline.
+	// The »--« makes sure the revision is not mistaken for a path either.
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("git", "-C", gitPath, "fast-export", "--first-parent", "--signed-tags=strip", rev, "--") // #nosec
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	return vcs.base.withCurrFs(func(fs *catfs.FS) error {
+		if err := cmd.Start(); err != nil {
+			return e.Wrapf(err, "failed to run git")
+		}
+
+		stats, importErr := fs.ImportGit(stdout, prefix)
+
+		// Make sure git does not block on a full pipe:
+		if _, err := io.Copy(ioutil.Discard, stdout); err != nil {
+			log.Debugf("failed to drain git output: %v", err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("git fast-export failed: %v: %s", err, stderr.String())
+		}
+
+		if stats != nil && stats.Commits > 0 {
+			vcs.base.notifyFsChangeEvent()
+		}
+
+		if importErr != nil {
+			return importErr
+		}
+
+		call.Results.SetCommits(int32(stats.Commits))
+		call.Results.SetSkipped(int32(stats.Skipped))
+		return nil
+	})
+}
+
+func (vcs *vcsHandler) ExportGit(call capnp.VCS_exportGit) error {
+	server.Ack(call.Options)
+
+	gitPath, err := call.Params.GitPath()
+	if err != nil {
+		return err
+	}
+
+	branch, err := call.Params.Branch()
+	if err != nil {
+		return err
+	}
+
+	if branch == "" {
+		branch = "master"
+	}
+
+	if _, err := os.Stat(filepath.Join(gitPath, ".git")); os.IsNotExist(err) {
+		out, err := exec.Command("git", "init", "-q", gitPath).CombinedOutput() // #nosec
+		if err != nil {
+			return fmt.Errorf("git init failed: %v: %s", err, out)
+		}
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("git", "-C", gitPath, "fast-import", "--quiet") // #nosec
+	cmd.Stderr = stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	return vcs.base.withCurrFs(func(fs *catfs.FS) error {
+		if err := cmd.Start(); err != nil {
+			return e.Wrapf(err, "failed to run git")
+		}
+
+		exportErr := fs.ExportGit(stdin, branch)
+		if err := stdin.Close(); err != nil {
+			log.Debugf("failed to close git input: %v", err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("git fast-import failed: %v: %s", err, stderr.String())
+		}
+
+		return exportErr
+	})
+}
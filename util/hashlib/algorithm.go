@@ -0,0 +1,173 @@
+package hashlib
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sort"
+	"sync/atomic"
+
+	"github.com/multiformats/go-multihash"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/sha3"
+)
+
+// Algorithm is a hash algorithm that can be used for content and tree
+// hashes. Its value is the multihash code that is written into the hashes.
+type Algorithm uint64
+
+const (
+	// AlgoBlake2s is BLAKE2s with 32 bytes. It is the default and was the
+	// only algorithm in older versions. For historic reasons, its multihash
+	// code claims a length of 5 bytes; the digest is 32 bytes nevertheless.
+	AlgoBlake2s = Algorithm(multihash.BLAKE2S_MIN + 4)
+
+	// AlgoBlake2b is BLAKE2b with 32 bytes.
+	AlgoBlake2b = Algorithm(multihash.BLAKE2B_MIN + 31)
+
+	// AlgoSHA256 is SHA-256; useful if a standardized algorithm is required.
+	AlgoSHA256 = Algorithm(multihash.SHA2_256)
+
+	// AlgoSHA3 is SHA3-256.
+	AlgoSHA3 = Algorithm(multihash.SHA3_256)
+
+	// DefaultAlgorithm is used by repositories that did not choose one.
+	DefaultAlgorithm = AlgoBlake2s
+)
+
+type algorithmInfo struct {
+	name    string
+	newHash func() hash.Hash
+}
+
+var algorithms = map[Algorithm]algorithmInfo{
+	AlgoBlake2s: {
+		name: "blake2s",
+		newHash: func() hash.Hash {
+			hsh, _ := blake2s.New256(nil)
+			return hsh
+		},
+	},
+	AlgoBlake2b: {
+		name: "blake2b-256",
+		newHash: func() hash.Hash {
+			hsh, _ := blake2b.New256(nil)
+			return hsh
+		},
+	},
+	AlgoSHA256: {
+		name:    "sha2-256",
+		newHash: sha256.New,
+	},
+	AlgoSHA3: {
+		name:    "sha3-256",
+		newHash: sha3.New256,
+	},
+}
+
+// String returns the name of the algorithm.
+func (a Algorithm) String() string {
+	if info, ok := algorithms[a]; ok {
+		return info.name
+	}
+
+	return fmt.Sprintf("unknown-%x", uint64(a))
+}
+
+// IsValid returns true if `a` is a supported algorithm.
+func (a Algorithm) IsValid() bool {
+	_, ok := algorithms[a]
+	return ok
+}
+
+func (a Algorithm) newHash() (hash.Hash, error) {
+	info, ok := algorithms[a]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", a)
+	}
+
+	return info.newHash(), nil
+}
+
+// ParseAlgorithm returns the algorithm called `name`.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for algo, info := range algorithms {
+		if info.name == name {
+			return algo, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown hash algorithm: %s", name)
+}
+
+// Algorithms returns the names of all supported algorithms.
+func Algorithms() []string {
+	names := []string{}
+	for _, info := range algorithms {
+		names = append(names, info.name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// internalState bundles everything Sum() needs about the internal algorithm,
+// so that it can be swapped atomically and read without locking.
+type internalState struct {
+	algo    Algorithm
+	newHash func() hash.Hash
+	empty   Hash
+}
+
+// internal holds the current *internalState.
+var internal atomic.Value
+
+func init() {
+	internal.Store(newInternalState(DefaultAlgorithm))
+}
+
+func newInternalState(algo Algorithm) *internalState {
+	return &internalState{
+		algo:    algo,
+		newHash: algorithms[algo].newHash,
+		empty:   emptyHash(algo),
+	}
+}
+
+func loadInternalState() *internalState {
+	return internal.Load().(*internalState)
+}
+
+// SetInternalAlgorithm changes the algorithm that is used by Sum(),
+// NewHashWriter() and EmptyInternalHash(). It should be only called on
+// startup or during an offline rehash, before any hash was computed.
+func SetInternalAlgorithm(algo Algorithm) error {
+	if !algo.IsValid() {
+		return fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+
+	internal.Store(newInternalState(algo))
+	return nil
+}
+
+// InternalAlgorithm returns the algorithm that is used by Sum().
+func InternalAlgorithm() Algorithm {
+	return loadInternalState().algo
+}
+
+// EmptyInternalHash returns a hash containing only zeros,
+// using the internal algorithm. Clone it before modifying it.
+func EmptyInternalHash() Hash {
+	return loadInternalState().empty
+}
+
+func emptyHash(algo Algorithm) Hash {
+	data := make([]byte, internalHashLength)
+	hash, err := multihash.Encode(data, uint64(algo))
+	if err != nil {
+		panic(fmt.Sprintf("Unable to create empty content hash: %v", err))
+	}
+
+	return Hash(hash)
+}
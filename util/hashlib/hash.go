@@ -9,22 +9,16 @@ import (
 
 	goipfsutil "github.com/ipfs/go-ipfs-util"
 	"github.com/multiformats/go-multihash"
-	"golang.org/x/crypto/blake2s"
 )
 
 const (
-	// The + 4 is for making sure 32 byte length is encoded (4 * 8 = 32).
-	// This is a weird convention by multihash apparently.
-	internalHashAlgo   = multihash.BLAKE2S_MIN + 4
+	// All supported algorithms produce 32 byte digests.
 	internalHashLength = 32
 )
 
 var (
 	// EmptyBackendHash is a hash containing only zeros, using IPFS's default hash.
 	EmptyBackendHash Hash
-
-	// EmptyInternalHash is a hash containing only zeros, using brig's default hash.
-	EmptyInternalHash Hash
 )
 
 func init() {
@@ -35,14 +29,6 @@ func init() {
 	}
 
 	EmptyBackendHash = Hash(hash)
-
-	data = make([]byte, internalHashLength)
-	hash, err = multihash.Encode(data, internalHashAlgo)
-	if err != nil {
-		panic(fmt.Sprintf("Unable to create empty content hash: %v", err))
-	}
-
-	EmptyInternalHash = Hash(hash)
 }
 
 // Hash is like multihash.Multihash but also supports serializing to json.
@@ -148,15 +134,37 @@ func (h Hash) Mix(o Hash) Hash {
 	return Sum(buf)
 }
 
+// Algorithm returns the algorithm that was used to produce `h`.
+func (h Hash) Algorithm() (Algorithm, error) {
+	decoded, err := multihash.Decode(h)
+	if err != nil {
+		return 0, err
+	}
+
+	algo := Algorithm(decoded.Code)
+	if !algo.IsValid() {
+		return 0, fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+
+	return algo, nil
+}
+
 // Sum hashes `data` with the internal hashing algorithm.
 func Sum(data []byte) Hash {
-	d := blake2s.Sum256(data)
-	mh, err := multihash.Encode(d[:], internalHashAlgo)
+	hw := NewHashWriter()
+	hw.Write(data)
+	return hw.Finalize()
+}
+
+// SumWith hashes `data` with `algo`.
+func SumWith(algo Algorithm, data []byte) Hash {
+	hw, err := NewHashWriterWith(algo)
 	if err != nil {
 		panic(err)
 	}
 
-	return Hash(mh)
+	hw.Write(data)
+	return hw.Finalize()
 }
 
 // SumWithBackendHash creates a hash with the same algorithm the backend uses.
@@ -195,7 +203,7 @@ func TestDummy(t *testing.T, seed byte) Hash {
 		data[idx] = seed
 	}
 
-	hash, err := multihash.Encode(data, internalHashAlgo)
+	hash, err := multihash.Encode(data, uint64(InternalAlgorithm()))
 	if err != nil {
 		t.Fatalf("Failed to create dummy hash: %v", err)
 		return nil
@@ -207,19 +215,52 @@ func TestDummy(t *testing.T, seed byte) Hash {
 // HashWriter is a io.Writer that supports being written to.
 type HashWriter struct {
 	hash hash.Hash
+	algo Algorithm
 }
 
-// NewHashWriter returns a new HashWriter.
-// Currently it is always sha3-256.
+// NewHashWriter returns a new HashWriter that uses the internal algorithm.
 func NewHashWriter() *HashWriter {
-	b, _ := blake2s.New256(nil)
-	return &HashWriter{hash: b}
+	state := loadInternalState()
+	return &HashWriter{hash: state.newHash(), algo: state.algo}
+}
+
+// NewHashWriterWith returns a new HashWriter that uses `algo`.
+func NewHashWriterWith(algo Algorithm) (*HashWriter, error) {
+	hsh, err := algo.newHash()
+	if err != nil {
+		return nil, err
+	}
+
+	return &HashWriter{hash: hsh, algo: algo}, nil
+}
+
+// NewHashWriterLike returns a HashWriter that uses the same algorithm as
+// `h`. This can be used to verify data against `h`, no matter with what
+// algorithm it was produced.
+func NewHashWriterLike(h Hash) (*HashWriter, error) {
+	algo, err := h.Algorithm()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHashWriterWith(algo)
+}
+
+// Verify checks if `data` hashes to `h`.
+func (h Hash) Verify(data []byte) (bool, error) {
+	hw, err := NewHashWriterLike(h)
+	if err != nil {
+		return false, err
+	}
+
+	hw.Write(data)
+	return hw.Finalize().Equal(h), nil
 }
 
 // Finalize returns the final hash of the written data.
 func (hw *HashWriter) Finalize() Hash {
 	sum := hw.hash.Sum(nil)
-	hash, err := multihash.Encode(sum, internalHashAlgo)
+	hash, err := multihash.Encode(sum, uint64(hw.algo))
 	if err != nil {
 		// If this does not work, there's something serious wrong.
 		panic(fmt.Sprintf("failed to encode final hash: %v", err))
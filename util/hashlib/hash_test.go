@@ -22,3 +22,69 @@ func TestHashWriter(t *testing.T) {
 		t.Fatalf("hashes differ due to different feed order")
 	}
 }
+
+func TestAlgorithms(t *testing.T) {
+	data := []byte("hello world")
+	defaultSum := Sum(data)
+
+	for _, name := range Algorithms() {
+		algo, err := ParseAlgorithm(name)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", name, err)
+		}
+
+		if algo.String() != name {
+			t.Fatalf("name of %s does not roundtrip: %s", name, algo)
+		}
+
+		hash := SumWith(algo, data)
+		hashAlgo, err := hash.Algorithm()
+		if err != nil || hashAlgo != algo {
+			t.Fatalf("wrong algorithm in %s hash: %s (%v)", name, hashAlgo, err)
+		}
+
+		if algo != DefaultAlgorithm && hash.Equal(defaultSum) {
+			t.Fatalf("%s produces the same hash as the default", name)
+		}
+
+		// Hashes of all algorithms can be verified:
+		if ok, err := hash.Verify(data); !ok || err != nil {
+			t.Fatalf("failed to verify %s hash: %v", name, err)
+		}
+
+		if ok, _ := hash.Verify([]byte("hello World")); ok {
+			t.Fatalf("%s hash verified wrong data", name)
+		}
+	}
+
+	if _, err := ParseAlgorithm("md5"); err == nil {
+		t.Fatalf("md5 should not be supported")
+	}
+}
+
+func TestSetInternalAlgorithm(t *testing.T) {
+	data := []byte("hello world")
+	defer SetInternalAlgorithm(DefaultAlgorithm)
+
+	if err := SetInternalAlgorithm(AlgoSHA256); err != nil {
+		t.Fatalf("failed to set algorithm: %v", err)
+	}
+
+	if !Sum(data).Equal(SumWith(AlgoSHA256, data)) {
+		t.Fatalf("Sum() does not use the internal algorithm")
+	}
+
+	if algo, _ := EmptyInternalHash().Algorithm(); algo != AlgoSHA256 {
+		t.Fatalf("empty hash uses the wrong algorithm: %s", algo)
+	}
+
+	hw := NewHashWriter()
+	hw.Write(data)
+	if !hw.Finalize().Equal(SumWith(AlgoSHA256, data)) {
+		t.Fatalf("hash writer does not use the internal algorithm")
+	}
+
+	if err := SetInternalAlgorithm(Algorithm(0x4711)); err == nil {
+		t.Fatalf("invalid algorithms should be refused")
+	}
+}
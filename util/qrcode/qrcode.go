@@ -0,0 +1,547 @@
+// Package qrcode implements a small QR code encoder.
+//
+// Only what brig needs is supported: the byte mode and versions 1 to 10,
+// which is enough for up to 271 bytes of data (with level L).
+// The encoder follows ISO/IEC 18004; the data is split into blocks,
+// protected with Reed-Solomon codes, interleaved and placed into the
+// symbol. Out of the eight mask patterns the one with the lowest
+// penalty is chosen.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Level is the error correction level.
+type Level int
+
+const (
+	// LevelL recovers about 7% of the data.
+	LevelL = Level(iota)
+	// LevelM recovers about 15% of the data.
+	LevelM
+	// LevelQ recovers about 25% of the data.
+	LevelQ
+	// LevelH recovers about 30% of the data.
+	LevelH
+)
+
+const maxVersion = 10
+
+var (
+	// ErrTooLong is returned when the data does not fit into a supported version.
+	ErrTooLong = errors.New("data is too long for a qr code")
+)
+
+// formatBits are the bits of each level in the format information.
+var formatBits = [...]int{LevelL: 1, LevelM: 0, LevelQ: 3, LevelH: 2}
+
+// blockSpec describes how the codewords of a version and level are split.
+type blockSpec struct {
+	// ecPerBlock is the number of error correction codewords of each block.
+	ecPerBlock int
+
+	// Number of blocks and data codewords per block of both groups.
+	// The blocks of the second group have one data codeword more.
+	groups [2]struct{ blocks, data int }
+}
+
+func spec(ec, b1, d1, b2, d2 int) blockSpec {
+	bs := blockSpec{ecPerBlock: ec}
+	bs.groups[0].blocks, bs.groups[0].data = b1, d1
+	bs.groups[1].blocks, bs.groups[1].data = b2, d2
+	return bs
+}
+
+// blockSpecs is indexed by version-1 and level.
+var blockSpecs = [maxVersion][4]blockSpec{
+	{spec(7, 1, 19, 0, 0), spec(10, 1, 16, 0, 0), spec(13, 1, 13, 0, 0), spec(17, 1, 9, 0, 0)},
+	{spec(10, 1, 34, 0, 0), spec(16, 1, 28, 0, 0), spec(22, 1, 22, 0, 0), spec(28, 1, 16, 0, 0)},
+	{spec(15, 1, 55, 0, 0), spec(26, 1, 44, 0, 0), spec(18, 2, 17, 0, 0), spec(22, 2, 13, 0, 0)},
+	{spec(20, 1, 80, 0, 0), spec(18, 2, 32, 0, 0), spec(26, 2, 24, 0, 0), spec(16, 4, 9, 0, 0)},
+	{spec(26, 1, 108, 0, 0), spec(24, 2, 43, 0, 0), spec(18, 2, 15, 2, 16), spec(22, 2, 11, 2, 12)},
+	{spec(18, 2, 68, 0, 0), spec(16, 4, 27, 0, 0), spec(24, 4, 19, 0, 0), spec(28, 4, 15, 0, 0)},
+	{spec(20, 2, 78, 0, 0), spec(18, 4, 31, 0, 0), spec(18, 2, 14, 4, 15), spec(26, 4, 13, 1, 14)},
+	{spec(24, 2, 97, 0, 0), spec(22, 2, 38, 2, 39), spec(22, 4, 18, 2, 19), spec(26, 4, 14, 2, 15)},
+	{spec(30, 2, 116, 0, 0), spec(22, 3, 36, 2, 37), spec(20, 4, 16, 4, 17), spec(24, 4, 12, 4, 13)},
+	{spec(18, 2, 68, 2, 69), spec(26, 4, 43, 1, 44), spec(24, 6, 19, 2, 20), spec(28, 6, 15, 2, 16)},
+}
+
+// alignmentPositions is indexed by version-1.
+var alignmentPositions = [maxVersion][]int{
+	nil,
+	{6, 18},
+	{6, 22},
+	{6, 26},
+	{6, 30},
+	{6, 34},
+	{6, 22, 38},
+	{6, 24, 42},
+	{6, 26, 46},
+	{6, 28, 50},
+}
+
+func (bs blockSpec) dataCodewords() int {
+	return bs.groups[0].blocks*bs.groups[0].data + bs.groups[1].blocks*bs.groups[1].data
+}
+
+func (bs blockSpec) totalCodewords() int {
+	blocks := bs.groups[0].blocks + bs.groups[1].blocks
+	return bs.dataCodewords() + blocks*bs.ecPerBlock
+}
+
+// Code is an encoded QR code.
+type Code struct {
+	// Version of the code (1-10).
+	Version int
+	// Level is the used error correction level.
+	Level Level
+	// Size is the number of modules per side.
+	Size int
+
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Black returns true if the module at column `x` and row `y` is dark.
+// Coordinates outside of the symbol are light (the quiet zone).
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+
+	return c.modules[y][x]
+}
+
+// Encode encodes `data` with the smallest version
+// that can hold it with the error correction level `level`.
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < LevelL || level > LevelH {
+		return nil, fmt.Errorf("invalid error correction level: %d", level)
+	}
+
+	for version := 1; version <= maxVersion; version++ {
+		bs := blockSpecs[version-1][level]
+		if dataBits(version, len(data)) <= bs.dataCodewords()*8 {
+			return encode(data, version, level), nil
+		}
+	}
+
+	return nil, ErrTooLong
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+
+	return 16
+}
+
+func dataBits(version, size int) int {
+	return 4 + charCountBits(version) + 8*size
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (bb *bitBuffer) append(val, n int) {
+	for idx := n - 1; idx >= 0; idx-- {
+		bb.bits = append(bb.bits, (val>>uint(idx))&1 == 1)
+	}
+}
+
+func (bb *bitBuffer) bytes() []byte {
+	out := make([]byte, (len(bb.bits)+7)/8)
+	for idx, bit := range bb.bits {
+		if bit {
+			out[idx/8] |= 1 << uint(7-idx%8)
+		}
+	}
+
+	return out
+}
+
+// dataCodewords builds the padded data codewords for `data`.
+func dataCodewords(data []byte, version int, bs blockSpec) []byte {
+	capacity := bs.dataCodewords() * 8
+
+	bb := &bitBuffer{}
+	bb.append(0x4, 4) // byte mode
+	bb.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+
+	// Terminator, then pad to a full byte:
+	terminator := capacity - len(bb.bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+
+	bb.append(0, terminator)
+	bb.append(0, (8-len(bb.bits)%8)%8)
+
+	for pad := 0xEC; len(bb.bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	return bb.bytes()
+}
+
+// interleave splits `data` into blocks, adds the error correction
+// codewords and interleaves everything into the final sequence.
+func interleave(data []byte, bs blockSpec) []byte {
+	dataBlocks := [][]byte{}
+	ecBlocks := [][]byte{}
+	gen := rsGenerator(bs.ecPerBlock)
+
+	offset := 0
+	for _, group := range bs.groups {
+		for idx := 0; idx < group.blocks; idx++ {
+			block := data[offset : offset+group.data]
+			offset += group.data
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, gen))
+		}
+	}
+
+	out := make([]byte, 0, bs.totalCodewords())
+	for col := 0; ; col++ {
+		added := false
+		for _, block := range dataBlocks {
+			if col < len(block) {
+				out = append(out, block[col])
+				added = true
+			}
+		}
+
+		if !added {
+			break
+		}
+	}
+
+	for col := 0; col < bs.ecPerBlock; col++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[col])
+		}
+	}
+
+	return out
+}
+
+func encode(data []byte, version int, level Level) *Code {
+	bs := blockSpecs[version-1][level]
+	codewords := interleave(dataCodewords(data, version, bs), bs)
+
+	size := 4*version + 17
+	c := &Code{
+		Version: version,
+		Level:   level,
+		Size:    size,
+	}
+
+	c.modules = make([][]bool, size)
+	c.isFunction = make([][]bool, size)
+	for y := 0; y < size; y++ {
+		c.modules[y] = make([]bool, size)
+		c.isFunction[y] = make([]bool, size)
+	}
+
+	c.drawFunctionPatterns()
+	c.drawCodewords(codewords)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+
+		// Masking is its own inverse:
+		c.applyMask(mask)
+	}
+
+	c.applyMask(bestMask)
+	c.drawFormatBits(bestMask)
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	// Timing patterns:
+	for idx := 0; idx < c.Size; idx++ {
+		c.setFunction(6, idx, idx%2 == 0)
+		c.setFunction(idx, 6, idx%2 == 0)
+	}
+
+	// Finder patterns (including the separators):
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions[c.Version-1]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// Skip the corners with finder patterns:
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format bits; they are drawn after masking.
+	c.drawFormatBits(0)
+	c.drawVersionBits()
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+
+			dist := maxInt(absInt(dx), absInt(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, maxInt(absInt(dx), absInt(dy)) != 1)
+		}
+	}
+}
+
+// formatInfo returns the 15 bit format information for `level` and `mask`.
+func formatInfo(level Level, mask int) int {
+	data := formatBits[level]<<3 | mask
+	rem := data
+	for idx := 0; idx < 10; idx++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionInfo returns the 18 bit version information (only used from version 7).
+func versionInfo(version int) int {
+	rem := version
+	for idx := 0; idx < 12; idx++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+
+	return version<<12 | rem
+}
+
+func bitAt(val, idx int) bool {
+	return (val>>uint(idx))&1 == 1
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatInfo(c.Level, mask)
+
+	// First copy, around the top left finder:
+	for idx := 0; idx <= 5; idx++ {
+		c.setFunction(8, idx, bitAt(bits, idx))
+	}
+
+	c.setFunction(8, 7, bitAt(bits, 6))
+	c.setFunction(8, 8, bitAt(bits, 7))
+	c.setFunction(7, 8, bitAt(bits, 8))
+	for idx := 9; idx < 15; idx++ {
+		c.setFunction(14-idx, 8, bitAt(bits, idx))
+	}
+
+	// Second copy, split between the other two finders:
+	for idx := 0; idx < 8; idx++ {
+		c.setFunction(c.Size-1-idx, 8, bitAt(bits, idx))
+	}
+
+	for idx := 8; idx < 15; idx++ {
+		c.setFunction(8, c.Size-15+idx, bitAt(bits, idx))
+	}
+
+	// The dark module:
+	c.setFunction(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersionBits() {
+	if c.Version < 7 {
+		return
+	}
+
+	bits := versionInfo(c.Version)
+	for idx := 0; idx < 18; idx++ {
+		a, b := c.Size-11+idx%3, idx/3
+		c.setFunction(a, b, bitAt(bits, idx))
+		c.setFunction(b, a, bitAt(bits, idx))
+	}
+}
+
+// drawCodewords places the codewords in the zig-zag order
+// into all modules that are not part of a function pattern.
+func (c *Code) drawCodewords(codewords []byte) {
+	bitIdx := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// Skip the vertical timing pattern.
+			right = 5
+		}
+
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.isFunction[y][x] || bitIdx >= len(codewords)*8 {
+					// Remainder bits stay light.
+					continue
+				}
+
+				c.modules[y][x] = (codewords[bitIdx/8]>>uint(7-bitIdx%8))&1 == 1
+				bitIdx++
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.isFunction[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to read. Lower is better.
+func (c *Code) penalty() int {
+	penalty := 0
+	dark := 0
+
+	// Runs of the same color and finder-like patterns in rows and columns:
+	for _, horizontal := range []bool{true, false} {
+		for a := 0; a < c.Size; a++ {
+			get := func(b int) bool {
+				if horizontal {
+					return c.Black(b, a)
+				}
+
+				return c.Black(a, b)
+			}
+
+			run := 1
+			for b := 1; b < c.Size; b++ {
+				if get(b) == get(b-1) {
+					run++
+					continue
+				}
+
+				if run >= 5 {
+					penalty += run - 2
+				}
+
+				run = 1
+			}
+
+			if run >= 5 {
+				penalty += run - 2
+			}
+
+			// 1:1:3:1:1 with four light modules on one side.
+			// Black() returns light outside of the symbol.
+			for b := -4; b < c.Size; b++ {
+				core := get(b+4) && !get(b+5) && get(b+6) && get(b+7) &&
+					get(b+8) && !get(b+9) && get(b+10)
+				if !core {
+					continue
+				}
+
+				lightBefore := !get(b) && !get(b+1) && !get(b+2) && !get(b+3)
+				lightAfter := !get(b+11) && !get(b+12) && !get(b+13) && !get(b+14)
+				if lightBefore || lightAfter {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+
+			if x+1 < c.Size && y+1 < c.Size {
+				color := c.modules[y][x]
+				if c.modules[y][x+1] == color &&
+					c.modules[y+1][x] == color &&
+					c.modules[y+1][x+1] == color {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	total := c.Size * c.Size
+	deviation := absInt(dark*20-total*10) / total
+	penalty += deviation * 10
+	return penalty
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+
+	return a
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
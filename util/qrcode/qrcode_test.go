@@ -0,0 +1,220 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" as 1-M code, taken from the well known tutorial
+	// at thonky.com, which uses the alphanumeric mode.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expect := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	require.Equal(t, expect, rsRemainder(data, rsGenerator(len(expect))))
+}
+
+func TestFormatAndVersionInfo(t *testing.T) {
+	// Values from the tables of the standard:
+	require.Equal(t, 0x5412, formatInfo(LevelM, 0)) // 101010000010010
+	require.Equal(t, 0x662F, formatInfo(LevelL, 4)) // 110011000101111
+	require.Equal(t, 0x07C94, versionInfo(7))       // 000111110010010100
+}
+
+func TestBlockSpecs(t *testing.T) {
+	remainderBits := []int{0, 7, 7, 7, 7, 7, 0, 0, 0, 0}
+	for version := 1; version <= maxVersion; version++ {
+		var total int
+		for level := LevelL; level <= LevelH; level++ {
+			bs := blockSpecs[version-1][level]
+			if level == LevelL {
+				total = bs.totalCodewords()
+			}
+
+			require.Equal(t, total, bs.totalCodewords(), "version %d", version)
+		}
+
+		// The number of data modules must match the codewords:
+		c := encode([]byte("x"), version, LevelM)
+		free := 0
+		for y := 0; y < c.Size; y++ {
+			for x := 0; x < c.Size; x++ {
+				if !c.isFunction[y][x] {
+					free++
+				}
+			}
+		}
+
+		require.Equal(t, total*8+remainderBits[version-1], free, "version %d", version)
+	}
+}
+
+// decode is a minimal decoder for error free codes produced by Encode.
+func decode(t *testing.T, c *Code) []byte {
+	// Read the first copy of the format information:
+	bits := 0
+	positions := [][2]int{}
+	for idx := 0; idx <= 5; idx++ {
+		positions = append(positions, [2]int{8, idx})
+	}
+
+	positions = append(positions, [2]int{8, 7}, [2]int{8, 8}, [2]int{7, 8})
+	for idx := 9; idx < 15; idx++ {
+		positions = append(positions, [2]int{14 - idx, 8})
+	}
+
+	for idx, pos := range positions {
+		if c.Black(pos[0], pos[1]) {
+			bits |= 1 << uint(idx)
+		}
+	}
+
+	level, mask := Level(-1), -1
+	for l := LevelL; l <= LevelH; l++ {
+		for m := 0; m < 8; m++ {
+			if formatInfo(l, m) == bits {
+				level, mask = l, m
+			}
+		}
+	}
+
+	require.Equal(t, c.Level, level)
+	require.True(t, mask >= 0)
+
+	// Rebuild the function patterns and read the codewords:
+	version := (c.Size - 17) / 4
+	ref := encode([]byte{}, version, level)
+	bs := blockSpecs[version-1][level]
+	codewords := make([]byte, bs.totalCodewords())
+
+	bitIdx := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if ref.isFunction[y][x] || bitIdx >= len(codewords)*8 {
+					continue
+				}
+
+				if c.Black(x, y) != maskBit(mask, x, y) {
+					codewords[bitIdx/8] |= 1 << uint(7-bitIdx%8)
+				}
+
+				bitIdx++
+			}
+		}
+	}
+
+	// De-interleave the data codewords:
+	blocks := [][]byte{}
+	for _, group := range bs.groups {
+		for idx := 0; idx < group.blocks; idx++ {
+			blocks = append(blocks, make([]byte, 0, group.data))
+		}
+	}
+
+	pos := 0
+	for col := 0; pos < bs.dataCodewords(); col++ {
+		for idx := range blocks {
+			if col < cap(blocks[idx]) {
+				blocks[idx] = append(blocks[idx], codewords[pos])
+				pos++
+			}
+		}
+	}
+
+	data := []byte{}
+	for idx, block := range blocks {
+		// Check the error correction of each block as well:
+		ecStart := bs.dataCodewords() + idx
+		ec := []byte{}
+		for col := 0; col < bs.ecPerBlock; col++ {
+			ec = append(ec, codewords[ecStart+col*len(blocks)])
+		}
+
+		require.Equal(t, rsRemainder(block, rsGenerator(bs.ecPerBlock)), ec)
+		data = append(data, block...)
+	}
+
+	// Parse the byte mode segment:
+	bb := &bitBuffer{}
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+
+	read := func(n int) int {
+		val := 0
+		for idx := 0; idx < n; idx++ {
+			val <<= 1
+			if bb.bits[idx] {
+				val |= 1
+			}
+		}
+
+		bb.bits = bb.bits[n:]
+		return val
+	}
+
+	require.Equal(t, 0x4, read(4))
+	size := read(charCountBits(version))
+	out := []byte{}
+	for idx := 0; idx < size; idx++ {
+		out = append(out, byte(read(8)))
+	}
+
+	return out
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, size := range []int{0, 1, 17, 42, 110, 200, 271} {
+		for level := LevelL; level <= LevelH; level++ {
+			data := []byte(strings.Repeat("brig:", size)[:size])
+			c, err := Encode(data, level)
+			if err == ErrTooLong {
+				continue
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, 4*c.Version+17, c.Size)
+			require.Equal(t, data, decode(t, c), fmt.Sprintf("size=%d level=%d", size, level))
+		}
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	_, err := Encode(make([]byte, 272), LevelL)
+	require.Equal(t, ErrTooLong, err)
+
+	c, err := Encode(make([]byte, 271), LevelL)
+	require.NoError(t, err)
+	require.Equal(t, 10, c.Version)
+}
+
+func TestRender(t *testing.T) {
+	c, err := Encode([]byte("alice QmFingerprint:W1Key"), LevelM)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimRight(c.Terminal(false), "\n"), "\n")
+	require.Len(t, lines, (c.Size+2*terminalQuietZone+1)/2)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, c.WritePNG(buf, 3))
+
+	img, err := png.Decode(buf)
+	require.NoError(t, err)
+	require.Equal(t, (c.Size+2*quietZone)*3, img.Bounds().Dx())
+}
@@ -0,0 +1,73 @@
+package qrcode
+
+// Arithmetic in GF(2^8) with the primitive polynomial x^8+x^4+x^3+x^2+1.
+var gfExp, gfLog = buildGaloisTables()
+
+func buildGaloisTables() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+
+	x := 1
+	for idx := 0; idx < 255; idx++ {
+		exp[idx] = byte(x)
+		log[x] = byte(idx)
+
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+
+	// Doubling the table saves a modulo in gfMul:
+	for idx := 255; idx < 512; idx++ {
+		exp[idx] = exp[idx-255]
+	}
+
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// rsGenerator returns the coefficients of the generator polynomial
+// (x - a^0)(x - a^1)...(x - a^(degree-1)), highest power first,
+// without the leading coefficient (which is always 1).
+func rsGenerator(degree int) []byte {
+	gen := make([]byte, degree)
+	gen[degree-1] = 1
+
+	root := byte(1)
+	for idx := 0; idx < degree; idx++ {
+		// Multiply with (x - root):
+		for j := 0; j < degree; j++ {
+			gen[j] = gfMul(gen[j], root)
+			if j+1 < degree {
+				gen[j] ^= gen[j+1]
+			}
+		}
+
+		root = gfMul(root, 2)
+	}
+
+	return gen
+}
+
+// rsRemainder returns the error correction codewords of `data`.
+func rsRemainder(data, gen []byte) []byte {
+	rem := make([]byte, len(gen))
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[len(rem)-1] = 0
+		for idx, coef := range gen {
+			rem[idx] ^= gfMul(coef, factor)
+		}
+	}
+
+	return rem
+}
@@ -0,0 +1,76 @@
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+const (
+	// quietZone is the number of light modules around the symbol.
+	quietZone = 4
+
+	// terminalQuietZone is smaller, since terminal space is precious.
+	terminalQuietZone = 2
+)
+
+// Terminal renders the code with unicode half blocks, so that two rows of
+// modules fit into one line. By default the light modules are drawn, which
+// is what most terminals with a dark background need to show a readable
+// code. If `invert` is true, the dark modules are drawn instead.
+func (c *Code) Terminal(invert bool) string {
+	drawn := func(x, y int) bool {
+		return c.Black(x, y) == invert
+	}
+
+	buf := &strings.Builder{}
+	lo, hi := -terminalQuietZone, c.Size+terminalQuietZone
+	for y := lo; y < hi; y += 2 {
+		for x := lo; x < hi; x++ {
+			upper, lower := drawn(x, y), drawn(x, y+1) && y+1 < hi
+			switch {
+			case upper && lower:
+				buf.WriteString("█")
+			case upper:
+				buf.WriteString("▀")
+			case lower:
+				buf.WriteString("▄")
+			default:
+				buf.WriteString(" ")
+			}
+		}
+
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
+
+// Image renders the code with `scale` pixels per module.
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+
+	side := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for py := 0; py < side; py++ {
+		for px := 0; px < side; px++ {
+			col := color.Gray{Y: 0xFF}
+			if c.Black(px/scale-quietZone, py/scale-quietZone) {
+				col = color.Gray{Y: 0x00}
+			}
+
+			img.SetGray(px, py, col)
+		}
+	}
+
+	return img
+}
+
+// WritePNG writes the code as PNG image with `scale` pixels per module to `w`.
+func (c *Code) WritePNG(w io.Writer, scale int) error {
+	return png.Encode(w, c.Image(scale))
+}